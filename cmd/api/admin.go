@@ -0,0 +1,485 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/aggregator"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/bluesky"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/crawler"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/database"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/features"
+)
+
+// adminAuthMiddleware requires the configured admin bearer token.
+// Admin endpoints are disabled entirely when no token is configured.
+func (s *Server) adminAuthMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.config.Server.AdminToken == "" {
+			http.Error(w, "Admin endpoints are disabled (set ADMIN_TOKEN)", http.StatusForbidden)
+			return
+		}
+		if !s.isAdmin(r) {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// isAdmin reports whether a request carries the configured admin token. In
+// public mode the token only counts on the admin listener.
+func (s *Server) isAdmin(r *http.Request) bool {
+	if s.config.Server.PublicMode && r.Context().Value(adminListenerKey{}) == nil {
+		return false
+	}
+	token := s.config.Server.AdminToken
+	provided, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return token != "" && ok && subtle.ConstantTimeCompare([]byte(provided), []byte(token)) == 1
+}
+
+// newAuditLogger returns the audit log, appending to path when set
+func newAuditLogger(path string) (*log.Logger, error) {
+	if path == "" {
+		return log.New(log.Writer(), "[AUDIT] ", log.LstdFlags), nil
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	return log.New(f, "[AUDIT] ", log.LstdFlags), nil
+}
+
+// auditf records a security event in the audit log (public mode only)
+func (s *Server) auditf(format string, args ...interface{}) {
+	if s.audit != nil {
+		s.audit.Printf(format, args...)
+	}
+}
+
+type adminListenerKey struct{}
+
+// auditMiddleware records every request to the admin listener with its
+// outcome, and marks requests as arriving there
+func (s *Server) auditMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		r = r.WithContext(context.WithValue(r.Context(), adminListenerKey{}, true))
+		next.ServeHTTP(ww, r)
+		s.auditf("admin method=%s path=%s status=%d ip=%s request_id=%s",
+			r.Method, r.URL.RequestURI(), ww.Status(), s.clientIP(r), middleware.GetReqID(r.Context()))
+	})
+}
+
+// LinkMetadataResponse is the admin view of a link's metadata sources
+type LinkMetadataResponse struct {
+	LinkID     int                           `json:"link_id"`
+	Source     string                        `json:"source"`     // Source currently displayed
+	Preference string                        `json:"preference"` // Pinned source, or "auto"
+	Sources    []database.LinkMetadataSource `json:"sources"`    // Highest score first
+}
+
+// LinkMetadataRequest pins a link's metadata source
+type LinkMetadataRequest struct {
+	Preference string `json:"preference"` // "bluesky", "scraped" or "auto"
+}
+
+func (s *Server) handleGetLinkMetadata(w http.ResponseWriter, r *http.Request) {
+	linkID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid link ID", http.StatusBadRequest)
+		return
+	}
+
+	link, err := s.db.GetLinkByID(linkID)
+	if err != nil {
+		log.Printf("Error getting link %d: %v", linkID, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if link == nil {
+		http.Error(w, "Link not found", http.StatusNotFound)
+		return
+	}
+
+	sources, err := s.db.GetLinkMetadataSources(linkID)
+	if err != nil {
+		log.Printf("Error getting metadata sources for link %d: %v", linkID, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if sources == nil {
+		sources = []database.LinkMetadataSource{}
+	}
+
+	preference := stringOrEmpty(link.MetadataPref)
+	if preference == "" {
+		preference = "auto"
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(LinkMetadataResponse{
+		LinkID:     link.ID,
+		Source:     stringOrEmpty(link.MetadataSource),
+		Preference: preference,
+		Sources:    sources,
+	})
+}
+
+func (s *Server) handleSetLinkMetadataPreference(w http.ResponseWriter, r *http.Request) {
+	linkID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid link ID", http.StatusBadRequest)
+		return
+	}
+
+	var req LinkMetadataRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+		return
+	}
+
+	preference := req.Preference
+	if preference == "auto" {
+		preference = ""
+	} else if !database.IsMetadataSource(preference) {
+		http.Error(w, "preference must be bluesky, scraped or auto", http.StatusBadRequest)
+		return
+	}
+
+	found, err := s.db.SetLinkMetadataPreference(linkID, preference)
+	if err != nil {
+		log.Printf("Error setting metadata preference for link %d: %v", linkID, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if !found {
+		http.Error(w, "Link not found", http.StatusNotFound)
+		return
+	}
+
+	s.handleGetLinkMetadata(w, r)
+}
+
+// handleCoordinatedLinks lists links shared with copied text for review.
+// Accepts ?hours= (default 24) and ?min_copies= (default the configured
+// coordination threshold).
+func (s *Server) handleCoordinatedLinks(w http.ResponseWriter, r *http.Request) {
+	hours := 24
+	if h := r.URL.Query().Get("hours"); h != "" {
+		parsed, err := strconv.Atoi(h)
+		if err != nil || parsed < 1 || parsed > 720 {
+			http.Error(w, "Invalid hours parameter (1-720)", http.StatusBadRequest)
+			return
+		}
+		hours = parsed
+	}
+
+	minCopies := s.config.Trending.CoordinationThreshold
+	if minCopies < 1 {
+		minCopies = 1
+	}
+	if m := r.URL.Query().Get("min_copies"); m != "" {
+		parsed, err := strconv.Atoi(m)
+		if err != nil || parsed < 1 {
+			http.Error(w, "Invalid min_copies parameter (at least 1)", http.StatusBadRequest)
+			return
+		}
+		minCopies = parsed
+	}
+
+	since := time.Now().Add(-time.Duration(hours) * time.Hour)
+	links, err := s.db.GetCoordinatedLinks(since, minCopies, 100)
+	if err != nil {
+		log.Printf("Error getting coordinated links: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if links == nil {
+		links = []database.CoordinatedLink{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(links)
+}
+
+// handleDomainStats reports scrape success rate and latency per domain.
+// Accepts ?hours= (default 24, up to the week of kept attempts) and
+// ?min_attempts= (default 1).
+func (s *Server) handleDomainStats(w http.ResponseWriter, r *http.Request) {
+	maxHours := int(database.ScrapeStatsRetention / time.Hour)
+	hours := 24
+	if h := r.URL.Query().Get("hours"); h != "" {
+		parsed, err := strconv.Atoi(h)
+		if err != nil || parsed < 1 || parsed > maxHours {
+			http.Error(w, fmt.Sprintf("Invalid hours parameter (1-%d)", maxHours), http.StatusBadRequest)
+			return
+		}
+		hours = parsed
+	}
+
+	minAttempts := 1
+	if m := r.URL.Query().Get("min_attempts"); m != "" {
+		parsed, err := strconv.Atoi(m)
+		if err != nil || parsed < 1 {
+			http.Error(w, "Invalid min_attempts parameter (at least 1)", http.StatusBadRequest)
+			return
+		}
+		minAttempts = parsed
+	}
+
+	since := time.Now().Add(-time.Duration(hours) * time.Hour)
+	stats, err := s.db.GetDomainStats(since, minAttempts, 500)
+	if err != nil {
+		log.Printf("Error getting domain stats: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if stats == nil {
+		stats = []database.DomainStats{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(stats)
+}
+
+// FollowStatsResponse is the admin view of how much signal an account
+// contributes
+type FollowStatsResponse struct {
+	DID           string     `json:"did"`
+	Handle        string     `json:"handle,omitempty"`
+	Degree        int        `json:"degree"` // 0 when the account isn't in the network
+	PostsSeen     int64      `json:"posts_seen"`
+	URLsExtracted int64      `json:"urls_extracted"`
+	TrendingLinks int64      `json:"trending_links"`
+	LinkRate      float64    `json:"link_rate"`     // URLs per post
+	TrendingRate  float64    `json:"trending_rate"` // Trending links per URL
+	FirstPostAt   *time.Time `json:"first_post_at,omitempty"`
+	LastPostAt    *time.Time `json:"last_post_at,omitempty"`
+}
+
+func (s *Server) handleFollowStats(w http.ResponseWriter, r *http.Request) {
+	did := chi.URLParam(r, "did")
+
+	account, err := s.db.GetNetworkAccount(did)
+	if err != nil {
+		log.Printf("Error getting network account %s: %v", did, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	stats, err := s.db.GetAccountStats(did)
+	if err != nil {
+		log.Printf("Error getting stats for %s: %v", did, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if account == nil && stats == nil {
+		http.Error(w, "Account not found", http.StatusNotFound)
+		return
+	}
+
+	response := FollowStatsResponse{DID: did}
+	if account != nil {
+		response.Handle = account.Handle
+		response.Degree = account.Degree
+	}
+	if stats != nil {
+		response.PostsSeen = stats.PostsSeen
+		response.URLsExtracted = stats.URLsExtracted
+		response.TrendingLinks = stats.TrendingLinks
+		response.FirstPostAt = stats.FirstPostAt
+		response.LastPostAt = stats.LastPostAt
+		if stats.PostsSeen > 0 {
+			response.LinkRate = float64(stats.URLsExtracted) / float64(stats.PostsSeen)
+		}
+		if stats.URLsExtracted > 0 {
+			response.TrendingRate = float64(stats.TrendingLinks) / float64(stats.URLsExtracted)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// maxImportBodyBytes caps an uploaded follow list
+const maxImportBodyBytes = 1 << 20
+
+// importProgressLine is one NDJSON line of an import's progress
+type importProgressLine struct {
+	crawler.ImportProgress
+	Done  bool   `json:"done"`
+	Error string `json:"error,omitempty"`
+}
+
+// handleImportFollows adds the accounts in a CSV or newline-separated list
+// of handles (or DIDs) as 1st-degree follows. Progress is streamed as
+// NDJSON, one line per resolved batch, ending with a "done" line.
+func (s *Server) handleImportFollows(w http.ResponseWriter, r *http.Request) {
+	accounts, err := crawler.ParseAccountList(http.MaxBytesReader(w, r.Body, maxImportBodyBytes))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid account list: %v", err), http.StatusBadRequest)
+		return
+	}
+	if len(accounts) == 0 {
+		http.Error(w, "No accounts listed", http.StatusBadRequest)
+		return
+	}
+
+	if s.config.Bluesky.Handle == "" || s.config.Bluesky.Password == "" {
+		http.Error(w, "Bluesky credentials not configured", http.StatusServiceUnavailable)
+		return
+	}
+	client, err := bluesky.NewClient(s.config.Bluesky.Handle, s.config.Bluesky.Password)
+	if err != nil {
+		log.Printf("Error authenticating for follow import: %v", err)
+		http.Error(w, "Failed to authenticate with Bluesky", http.StatusBadGateway)
+		return
+	}
+	c := crawler.NewCrawler(s.db, client, client.GetDID(), &crawler.Config{RequestsPerSecond: 5})
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Cache-Control", "no-cache")
+	encoder := json.NewEncoder(w)
+	flusher, _ := w.(http.Flusher)
+	result, err := c.ImportAccounts(r.Context(), accounts, func(progress crawler.ImportProgress) {
+		encoder.Encode(importProgressLine{ImportProgress: progress})
+		if flusher != nil {
+			flusher.Flush()
+		}
+	})
+
+	line := importProgressLine{ImportProgress: result, Done: true}
+	if err != nil {
+		log.Printf("Follow import stopped after %d/%d accounts: %v", result.Processed, result.Total, err)
+		line.Error = err.Error()
+	} else {
+		log.Printf("Imported %d/%d follows (%d not found, %d failed)",
+			result.Imported, result.Total, len(result.NotFound), len(result.Failed))
+	}
+	encoder.Encode(line)
+}
+
+// AccountCurationRequest edits an account's curation; omitted fields are
+// left as they are
+type AccountCurationRequest struct {
+	Notes       *string `json:"notes"` // "" clears them
+	TrustLevel  *string `json:"trust_level"`
+	Pinned      *bool   `json:"pinned"`
+	AccountType *string `json:"account_type"` // "" returns the account to inferred typing
+}
+
+func (s *Server) handleListCuratedAccounts(w http.ResponseWriter, r *http.Request) {
+	accounts, err := s.db.GetCuratedAccounts()
+	if err != nil {
+		log.Printf("Error getting curated accounts: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if accounts == nil {
+		accounts = []database.AccountCuration{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(accounts)
+}
+
+func (s *Server) handleGetAccountCuration(w http.ResponseWriter, r *http.Request) {
+	did := chi.URLParam(r, "did")
+
+	account, err := s.db.GetAccountCuration(did)
+	if err != nil {
+		log.Printf("Error getting curation for %s: %v", did, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if account == nil {
+		http.Error(w, "Account not in the network", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(account)
+}
+
+func (s *Server) handleUpdateAccountCuration(w http.ResponseWriter, r *http.Request) {
+	did := chi.URLParam(r, "did")
+
+	var req AccountCurationRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+		return
+	}
+	if req.TrustLevel != nil && !database.IsTrustLevel(*req.TrustLevel) {
+		http.Error(w, "trust_level must be low, normal or high", http.StatusBadRequest)
+		return
+	}
+	if req.AccountType != nil && *req.AccountType != "" && !database.IsAccountType(*req.AccountType) {
+		http.Error(w, "account_type must be news_org, journalist or individual", http.StatusBadRequest)
+		return
+	}
+
+	account, err := s.db.UpdateAccountCuration(did, req.Notes, req.TrustLevel, req.Pinned, req.AccountType)
+	if err != nil {
+		log.Printf("Error updating curation for %s: %v", did, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if account == nil {
+		http.Error(w, "Account not in the network", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(account)
+}
+
+// recordRankingComparison logs and stores a shadow ranking comparison
+func recordRankingComparison(db *database.DB, c aggregator.RankingComparison) {
+	log.Printf("[SHADOW] %s vs %s: kendall tau %.3f, top-10 overlap %.0f%% (%d links)",
+		c.Shadow, c.Primary, c.KendallTau, c.TopOverlap*100, c.Links)
+	if err := db.SaveRankingComparison(c.Primary, c.Shadow, c.Links, c.KendallTau, c.TopOverlap, c.PrimaryIDs, c.ShadowIDs); err != nil {
+		log.Printf("Error saving ranking comparison: %v", err)
+	}
+}
+
+// handleRankingComparisons summarizes shadow ranking comparisons over the
+// last ?hours= (default 24, up to the 30 days they are kept)
+func (s *Server) handleRankingComparisons(w http.ResponseWriter, r *http.Request) {
+	hours := 24
+	if h := r.URL.Query().Get("hours"); h != "" {
+		parsed, err := strconv.Atoi(h)
+		if err != nil || parsed < 1 || parsed > int(database.RankingComparisonRetention.Hours()) {
+			http.Error(w, fmt.Sprintf("Invalid hours parameter (1-%d)", int(database.RankingComparisonRetention.Hours())), http.StatusBadRequest)
+			return
+		}
+		hours = parsed
+	}
+
+	summaries, err := s.db.GetRankingComparisonSummaries(time.Now().Add(-time.Duration(hours) * time.Hour))
+	if err != nil {
+		log.Printf("Error getting ranking comparisons: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(summaries)
+}
+
+// handleListFeatures lists the optional subsystems and whether this
+// deployment turned them on
+func (s *Server) handleListFeatures(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(features.All())
+}
@@ -0,0 +1,449 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/database"
+)
+
+// API key scopes: the read-only areas of the API a public key may call
+const (
+	ScopeTrending = "trending" // Rankings, feeds, topics, events and GraphQL
+	ScopeLinks    = "links"    // Link details and the posts sharing them
+	ScopeAccounts = "accounts" // Sharer profiles, leaderboards, cohorts and communities
+)
+
+// apiScopes lists every scope, in the order keys get them by default
+var apiScopes = []string{ScopeTrending, ScopeLinks, ScopeAccounts}
+
+// apiKeyScopes maps path prefixes to the scope needed to call them with a
+// key. Every public route that isn't keyless is listed here or in
+// scopeFreePaths; admin routes take the admin token, never a key.
+var apiKeyScopes = []struct {
+	prefix string
+	scope  string
+}{
+	{"/api/trending", ScopeTrending}, // Including stream, as-of and delta
+	{"/feeds/trending.xml", ScopeTrending},
+	{"/api/topics", ScopeTrending},
+	{"/feeds/topics", ScopeTrending},
+	{"/api/domains", ScopeTrending},
+	{"/api/events", ScopeTrending},
+	{"/api/events.ics", ScopeTrending},
+	{"/api/retrospective", ScopeTrending},
+	{"/api/on-this-day", ScopeTrending},
+	{"/graphql", ScopeTrending},
+	{"/topics", ScopeTrending}, // HTML pages
+	{"/snapshots", ScopeTrending},
+	{"/digest", ScopeTrending},
+	{"/retrospective", ScopeTrending},
+	{"/on-this-day", ScopeTrending},
+	{"/api/links", ScopeLinks},
+	{"/links", ScopeLinks},
+	{"/api/users", ScopeAccounts},
+	{"/users", ScopeAccounts},
+	{"/api/sharers", ScopeAccounts},
+	{"/api/leaderboard", ScopeAccounts},
+	{"/api/recommendations", ScopeAccounts},
+	{"/api/cohorts", ScopeAccounts},
+	{"/api/communities", ScopeAccounts},
+}
+
+// scopeFreePaths can be called with any valid key, whatever its scopes:
+// signup, capabilities, server status and the key's own usage
+var scopeFreePaths = []string{"/api/keys", "/api/keys/me", "/api/capabilities", "/api/status"}
+
+// apiKeyHeader carries a public API key. Keys may also be sent as
+// "Authorization: Bearer <key>"; their prefix tells them from the admin token.
+const apiKeyHeader = "X-API-Key"
+
+// apiKeyPrefix starts every issued key
+const apiKeyPrefix = "bna_"
+
+// keyFreePaths can be called without a key even when keys are required:
+// requesting a key and discovering what the server offers
+var keyFreePaths = []string{"/api/keys", "/api/capabilities"}
+
+// keylessPaths and keylessPrefixes are never gated by RequireAPIKey,
+// either because they serve no aggregated data or because their callers
+// can't send a key
+var keylessPaths = []string{
+	"/",       // Page shell; its script loads data from the gated API
+	"/health", // Load balancer and uptime probes
+	"/.well-known/did.json",
+	"/xrpc/app.bsky.feed.describeFeedGenerator", // Feed generator identity and feed list, no posts
+	// Called by the Bluesky app view for app users, who have no key; leave
+	// feedgen.publisher_did empty to close it
+	"/xrpc/app.bsky.feed.getFeedSkeleton",
+}
+
+var keylessPrefixes = []string{
+	"/static/", // Stylesheets, scripts and images
+	// click_url redirects, followed by readers of keyed clients; each one
+	// reveals a single link's URL and is rate limited per IP
+	"/out/",
+}
+
+// keyless reports whether path is served without a key when keys are required
+func keyless(path string) bool {
+	if slices.Contains(keyFreePaths, path) || slices.Contains(keylessPaths, path) {
+		return true
+	}
+	for _, prefix := range keylessPrefixes {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// providedAPIKey returns the API key a request carries, if any
+func providedAPIKey(r *http.Request) string {
+	if key := r.Header.Get(apiKeyHeader); key != "" {
+		return key
+	}
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok && strings.HasPrefix(token, apiKeyPrefix) {
+		return token
+	}
+	return ""
+}
+
+// requiresAPIKey reports whether an unkeyed request must be refused: with
+// RequireAPIKey on, everything but keyless paths (the JSON API, GraphQL,
+// feeds and HTML pages) needs a key or the admin token
+func (s *Server) requiresAPIKey(r *http.Request) bool {
+	if !s.config.Server.RequireAPIKey || keyless(r.URL.Path) {
+		return false
+	}
+	return !s.isAdmin(r)
+}
+
+type apiKeyContextKey struct{}
+
+// apiKeyFromContext returns the API key a request was made with, if any
+func apiKeyFromContext(r *http.Request) *database.APIKey {
+	key, _ := r.Context().Value(apiKeyContextKey{}).(*database.APIKey)
+	return key
+}
+
+// hashAPIKey returns the stored form of a key. Keys are random, so an
+// unsalted hash is enough to make a leaked table useless.
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// scopeForPath returns the scope a key needs for path, or "" if keys can't
+// call it
+func scopeForPath(path string) string {
+	for _, s := range apiKeyScopes {
+		if path == s.prefix || strings.HasPrefix(path, s.prefix+"/") {
+			return s.scope
+		}
+	}
+	return ""
+}
+
+// apiKeyMiddleware authenticates requests carrying an API key and enforces
+// its scopes and quotas. Keyed requests skip the per-IP limit; requests
+// without a key pass through untouched unless keys are required. Owners
+// can reach /api/keys/me even while their key is pending, within its
+// per-minute limit.
+func (s *Server) apiKeyMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		provided := providedAPIKey(r)
+		if provided == "" {
+			if s.requiresAPIKey(r) {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="api"`)
+				http.Error(w, "API key required", http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		key, err := s.db.GetAPIKeyByHash(hashAPIKey(provided))
+		if err != nil {
+			log.Printf("Error looking up API key: %v", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if key == nil || key.Status == database.APIKeyRevoked {
+			http.Error(w, "Invalid API key", http.StatusUnauthorized)
+			return
+		}
+		ctx := context.WithValue(r.Context(), apiKeyContextKey{}, key)
+
+		if r.URL.Path == "/api/keys/me" {
+			// Not recorded as usage, so checking usage doesn't spend the quota
+			if !s.allowAPIKey(r, "apikey:"+strconv.Itoa(key.ID), key.RatePerMinute, time.Minute) {
+				w.Header().Set("Retry-After", "60")
+				http.Error(w, "API key rate limit exceeded", http.StatusTooManyRequests)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(ctx))
+			return
+		}
+		if key.Status != database.APIKeyActive {
+			http.Error(w, "API key is awaiting approval", http.StatusForbidden)
+			return
+		}
+
+		status, msg := http.StatusOK, ""
+		scope := scopeForPath(r.URL.Path)
+		if !slices.Contains(scopeFreePaths, r.URL.Path) && (scope == "" || !slices.Contains(key.Scopes, scope)) {
+			status, msg = http.StatusForbidden, "API key not allowed for this endpoint"
+		} else if !s.allowAPIKey(r, "apikey:"+strconv.Itoa(key.ID), key.RatePerMinute, time.Minute) {
+			w.Header().Set("Retry-After", "60")
+			status, msg = http.StatusTooManyRequests, "API key rate limit exceeded"
+		} else {
+			// Daily quotas follow UTC days, matching usage accounting
+			now := time.Now().UTC()
+			day := now.Format("2006-01-02")
+			if !s.allowAPIKey(r, "apikey-day:"+strconv.Itoa(key.ID)+":"+day, key.QuotaPerDay, 24*time.Hour) {
+				reset := now.Truncate(24 * time.Hour).Add(24 * time.Hour)
+				w.Header().Set("Retry-After", strconv.Itoa(int(reset.Sub(now).Seconds())+1))
+				status, msg = http.StatusTooManyRequests, "API key daily quota exceeded"
+			}
+		}
+
+		if err := s.db.RecordAPIKeyUsage(key.ID, status != http.StatusOK); err != nil {
+			log.Printf("Error recording API key usage: %v", err)
+		}
+		if status != http.StatusOK {
+			http.Error(w, msg, status)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// allowAPIKey counts a request against a key's limit, failing open like the
+// IP rate limiter
+func (s *Server) allowAPIKey(r *http.Request, counter string, limit int, window time.Duration) bool {
+	allowed, err := s.cache.RateLimiter.Allow(r.Context(), counter, limit, window)
+	if err != nil {
+		log.Printf("Rate limiter error: %v", err)
+		return true
+	}
+	return allowed
+}
+
+// APIKeyRequest asks for a public API key
+type APIKeyRequest struct {
+	Name    string   `json:"name"`    // What the key is for
+	Contact string   `json:"contact"` // How to reach the owner
+	Scopes  []string `json:"scopes"`  // Defaults to all read scopes
+}
+
+// APIKeyIssuedResponse is a new key: the only time the key itself is shown
+type APIKeyIssuedResponse struct {
+	Key string `json:"key"`
+	*database.APIKey
+}
+
+// handleRequestAPIKey issues a pending key to anyone, when signup is on.
+// The key works once an admin approves it.
+func (s *Server) handleRequestAPIKey(w http.ResponseWriter, r *http.Request) {
+	if !s.config.Server.APIKeySignup {
+		http.Error(w, "API key signup is disabled", http.StatusForbidden)
+		return
+	}
+
+	var req APIKeyRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<16)).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	req.Contact = strings.TrimSpace(req.Contact)
+	if req.Name == "" || req.Contact == "" || len(req.Name) > 200 || len(req.Contact) > 200 {
+		http.Error(w, "name and contact are required (up to 200 characters)", http.StatusBadRequest)
+		return
+	}
+	scopes, ok := parseScopes(req.Scopes)
+	if !ok {
+		http.Error(w, "Invalid scopes (trending, links, accounts)", http.StatusBadRequest)
+		return
+	}
+
+	secret := make([]byte, 24)
+	if _, err := rand.Read(secret); err != nil {
+		log.Printf("Error generating API key: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	plaintext := apiKeyPrefix + hex.EncodeToString(secret)
+
+	key, err := s.db.CreateAPIKey(hashAPIKey(plaintext), plaintext[:12], req.Name, req.Contact, scopes,
+		s.config.Server.APIKeyRPM, s.config.Server.APIKeyDailyQuota)
+	if err != nil {
+		log.Printf("Error creating API key: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("API key %d (%s) requested by %s: pending approval", key.ID, key.KeyPrefix, key.Contact)
+	s.auditf("api_key_requested id=%d ip=%s", key.ID, s.clientIP(r))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(APIKeyIssuedResponse{Key: plaintext, APIKey: key})
+}
+
+// parseScopes validates requested scopes, defaulting to all of them
+func parseScopes(requested []string) ([]string, bool) {
+	if len(requested) == 0 {
+		return slices.Clone(apiScopes), true
+	}
+	var scopes []string
+	for _, scope := range requested {
+		if !slices.Contains(apiScopes, scope) {
+			return nil, false
+		}
+		if !slices.Contains(scopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+	return scopes, true
+}
+
+// APIKeyUsageResponse shows a key's owner its limits and recent usage
+type APIKeyUsageResponse struct {
+	*database.APIKey
+	RemainingToday int64                  `json:"remaining_today"`
+	Usage          []database.APIKeyUsage `json:"usage"` // Last 30 UTC days, newest first
+}
+
+// handleAPIKeyUsage reports the calling key's status, limits and usage
+func (s *Server) handleAPIKeyUsage(w http.ResponseWriter, r *http.Request) {
+	key := apiKeyFromContext(r)
+	if key == nil {
+		http.Error(w, "Send your key in the "+apiKeyHeader+" or Authorization: Bearer header", http.StatusUnauthorized)
+		return
+	}
+
+	usage, err := s.db.GetAPIKeyUsage(key.ID, 30)
+	if err != nil {
+		log.Printf("Error getting usage for API key %d: %v", key.ID, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if usage == nil {
+		usage = []database.APIKeyUsage{}
+	}
+
+	// Rejected requests don't use up the quota
+	remaining := int64(key.QuotaPerDay)
+	today := time.Now().UTC().Format("2006-01-02")
+	if len(usage) > 0 && usage[0].Day.Format("2006-01-02") == today {
+		remaining -= usage[0].Requests - usage[0].Rejected
+	}
+	if remaining < 0 {
+		remaining = 0
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(APIKeyUsageResponse{APIKey: key, RemainingToday: remaining, Usage: usage})
+}
+
+// handleListAPIKeys lists keys for admins; ?status=pending shows requests
+// waiting for approval
+func (s *Server) handleListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+	switch status {
+	case "", database.APIKeyPending, database.APIKeyActive, database.APIKeyRevoked:
+	default:
+		http.Error(w, "Invalid status parameter (pending, active or revoked)", http.StatusBadRequest)
+		return
+	}
+
+	keys, err := s.db.GetAPIKeys(status)
+	if err != nil {
+		log.Printf("Error listing API keys: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if keys == nil {
+		keys = []database.APIKey{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"keys": keys})
+}
+
+// APIKeyApproval activates a key, optionally changing what was requested
+type APIKeyApproval struct {
+	Scopes        []string `json:"scopes,omitempty"`
+	RatePerMinute int      `json:"rate_per_minute,omitempty"`
+	QuotaPerDay   int      `json:"quota_per_day,omitempty"`
+}
+
+func (s *Server) handleApproveAPIKey(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid key ID", http.StatusBadRequest)
+		return
+	}
+
+	var req APIKeyApproval
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<16)).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+			return
+		}
+	}
+	var scopes []string
+	if req.Scopes != nil {
+		var ok bool
+		if scopes, ok = parseScopes(req.Scopes); !ok {
+			http.Error(w, "Invalid scopes (trending, links, accounts)", http.StatusBadRequest)
+			return
+		}
+	}
+
+	key, err := s.db.ApproveAPIKey(id, scopes, req.RatePerMinute, req.QuotaPerDay)
+	if err != nil {
+		log.Printf("Error approving API key %d: %v", id, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if key == nil {
+		http.Error(w, "API key not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(key)
+}
+
+func (s *Server) handleRevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid key ID", http.StatusBadRequest)
+		return
+	}
+
+	revoked, err := s.db.RevokeAPIKey(id)
+	if err != nil {
+		log.Printf("Error revoking API key %d: %v", id, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if !revoked {
+		http.Error(w, "API key not found", http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
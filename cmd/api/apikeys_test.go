@@ -0,0 +1,150 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/config"
+)
+
+// TestAPIKeyScopesCoverRoutes checks every public route that isn't keyless
+// either needs a scope or is explicitly scope-free, so keys can't be locked
+// out of (or silently granted) a route added later
+func TestAPIKeyScopesCoverRoutes(t *testing.T) {
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("config.Load: %v", err)
+	}
+	cfg.Server.ClickTracking = true
+	server := &Server{router: chi.NewRouter(), config: cfg}
+	server.setupRoutes()
+
+	admin := chi.NewRouter()
+	server.adminRoutes(admin)
+	adminRoutes := make(map[string]bool)
+	chi.Walk(admin, func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		adminRoutes[method+" "+route] = true
+		return nil
+	})
+
+	chi.Walk(server.router, func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		if adminRoutes[method+" "+route] {
+			return nil
+		}
+		// A concrete path for the route
+		path := strings.NewReplacer("{id}", "1", "{handle}", "alice.test", "{name}", "news", "{slug}", "climate", "{slug}.xml", "climate.xml", "{date}", "2024-05-14").Replace(route)
+		if keyless(path) {
+			return nil
+		}
+		if scopeForPath(path) == "" && !slices.Contains(scopeFreePaths, path) {
+			t.Errorf("%s %s has no API key scope and isn't scope-free", method, route)
+		}
+		return nil
+	})
+}
+
+// TestRequireAPIKey checks which paths refuse unkeyed requests when keys
+// are required
+func TestRequireAPIKey(t *testing.T) {
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("config.Load: %v", err)
+	}
+	cfg.Server.RequireAPIKey = true
+	cfg.Server.AdminToken = "admin-secret"
+	server := &Server{config: cfg}
+	handler := server.apiKeyMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	tests := []struct {
+		path string
+		want int
+	}{
+		{"/api/trending", http.StatusUnauthorized},
+		{"/api/status", http.StatusUnauthorized},
+		{"/api/keys/me", http.StatusUnauthorized},
+		{"/feeds/trending.xml", http.StatusUnauthorized},
+		{"/graphql", http.StatusUnauthorized},
+		{"/links/1", http.StatusUnauthorized},
+		{"/users/alice.test", http.StatusUnauthorized},
+		{"/topics/climate", http.StatusUnauthorized},
+		{"/snapshots/1", http.StatusUnauthorized},
+		{"/digest/2024-05-14", http.StatusUnauthorized},
+		{"/retrospective", http.StatusUnauthorized},
+		{"/on-this-day", http.StatusUnauthorized},
+		{"/nonexistent", http.StatusUnauthorized},
+		{"/api/keys", http.StatusOK},
+		{"/api/capabilities", http.StatusOK},
+		{"/", http.StatusOK},
+		{"/static/style.css", http.StatusOK},
+		{"/health", http.StatusOK},
+		{"/.well-known/did.json", http.StatusOK},
+		{"/xrpc/app.bsky.feed.describeFeedGenerator", http.StatusOK},
+		{"/xrpc/app.bsky.feed.getFeedSkeleton", http.StatusOK},
+		{"/out/1", http.StatusOK},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if rec.Code != tt.want {
+			t.Errorf("GET %s without a key = %d, want %d", tt.path, rec.Code, tt.want)
+		}
+	}
+
+	// The admin token stands in for a key
+	req := httptest.NewRequest(http.MethodGet, "/links/1", nil)
+	req.Header.Set("Authorization", "Bearer admin-secret")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Errorf("GET /links/1 with the admin token = %d, want 200", rec.Code)
+	}
+
+	// Nothing is gated with keys optional
+	cfg.Server.RequireAPIKey = false
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/links/1", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("GET /links/1 with keys optional = %d, want 200", rec.Code)
+	}
+}
+
+func TestScopeForPath(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/api/trending", ScopeTrending},
+		{"/api/trending/stream", ScopeTrending},
+		{"/api/events.ics", ScopeTrending},
+		{"/feeds/topics/climate.xml", ScopeTrending},
+		{"/api/links/42/posts", ScopeLinks},
+		{"/api/users/alice.test", ScopeAccounts},
+		{"/users/alice.test", ScopeAccounts},
+		{"/links/42", ScopeLinks},
+		{"/digest/2024-05-14", ScopeTrending},
+		{"/api/trendingx", ""},
+		{"/api/admin/keys", ""},
+		{"/api/stories", ""},
+	}
+	for _, tt := range tests {
+		if got := scopeForPath(tt.path); got != tt.want {
+			t.Errorf("scopeForPath(%s) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestParseScopes(t *testing.T) {
+	if got, ok := parseScopes(nil); !ok || !slices.Equal(got, apiScopes) {
+		t.Errorf("parseScopes(nil) = %v, %v; want every scope", got, ok)
+	}
+	if got, ok := parseScopes([]string{ScopeLinks, ScopeLinks, ScopeTrending}); !ok || !slices.Equal(got, []string{ScopeLinks, ScopeTrending}) {
+		t.Errorf("parseScopes = %v, %v; want links, trending", got, ok)
+	}
+	if _, ok := parseScopes([]string{"stories"}); ok {
+		t.Error("parseScopes accepted a scope that doesn't exist")
+	}
+}
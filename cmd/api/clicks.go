@@ -0,0 +1,89 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/calendar"
+)
+
+// botUserAgentPattern matches crawlers, link preview fetchers and HTTP
+// libraries, whose requests aren't reader clicks
+var botUserAgentPattern = regexp.MustCompile(`(?i)bot|crawl|spider|slurp|preview|fetch|facebookexternalhit|embedly|whatsapp|curl|wget|python|go-http-client|httpclient|headless`)
+
+// isBotRequest reports whether a click should not be counted: HEAD requests,
+// prefetches and bot user agents
+func isBotRequest(r *http.Request) bool {
+	if r.Method != http.MethodGet {
+		return true
+	}
+	if strings.Contains(r.Header.Get("Sec-Purpose"), "prefetch") || r.Header.Get("Purpose") == "prefetch" {
+		return true
+	}
+	ua := r.Header.Get("User-Agent")
+	return ua == "" || botUserAgentPattern.MatchString(ua)
+}
+
+// clickURL returns the counting redirect for a link, or "" when click
+// tracking is off
+func (s *Server) clickURL(linkID int) string {
+	if !s.config.Server.ClickTracking {
+		return ""
+	}
+	return fmt.Sprintf("/out/%d", linkID)
+}
+
+// handleOutboundClick redirects to a link's URL, counting the click. Each
+// reader counts once per link per day: readers are told apart by a salted
+// hash of their IP that only lives in the rate limiter for a day, and only
+// counts are stored.
+func (s *Server) handleOutboundClick(w http.ResponseWriter, r *http.Request) {
+	linkID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid link ID", http.StatusBadRequest)
+		return
+	}
+
+	link, err := s.db.GetLinkByID(linkID)
+	if err != nil {
+		log.Printf("Error getting link %d: %v", linkID, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if link == nil {
+		http.Error(w, "Link not found", http.StatusNotFound)
+		return
+	}
+
+	if !isBotRequest(r) {
+		// The same address rate limits use, so a varied X-Forwarded-For
+		// can't count one reader many times
+		day := calendar.Date(time.Now(), s.config.Timezone)
+
+		mac := hmac.New(sha256.New, s.clickSalt)
+		fmt.Fprintf(mac, "%s|%d|%s", s.clientIP(r), linkID, day.Format("2006-01-02"))
+		reader := hex.EncodeToString(mac.Sum(nil)[:16])
+
+		first, err := s.cache.RateLimiter.Allow(r.Context(), "click:"+reader, 1, 24*time.Hour)
+		if err != nil {
+			log.Printf("Click de-duplication error: %v", err)
+		} else if first {
+			if err := s.db.RecordClick(linkID, day); err != nil {
+				log.Printf("Error recording click on link %d: %v", linkID, err)
+			}
+		}
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	http.Redirect(w, r, link.NormalizedURL, http.StatusFound)
+}
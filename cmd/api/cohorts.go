@@ -0,0 +1,149 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/database"
+)
+
+// CohortRequest is the body of PUT /api/cohorts/{name}
+type CohortRequest struct {
+	Description *string  `json:"description"`
+	Members     []string `json:"members"` // DIDs or handles of network accounts
+}
+
+func (s *Server) handleListCohorts(w http.ResponseWriter, r *http.Request) {
+	cohorts, err := s.db.GetCohorts()
+	if err != nil {
+		log.Printf("Error listing cohorts: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if cohorts == nil {
+		cohorts = []database.Cohort{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"cohorts": cohorts})
+}
+
+func (s *Server) handleGetCohort(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+	cohort, err := s.db.GetCohortByName(name)
+	if err != nil {
+		log.Printf("Error getting cohort %s: %v", name, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if cohort == nil {
+		http.Error(w, "Cohort not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(cohort)
+}
+
+// handleSaveCohort creates a cohort or replaces its description and members
+func (s *Server) handleSaveCohort(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+
+	var req CohortRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+		return
+	}
+
+	dids := make([]string, 0, len(req.Members))
+	for _, member := range req.Members {
+		did, err := s.db.ResolveAccountDID(member)
+		if err != nil {
+			log.Printf("Error resolving %s: %v", member, err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if did == "" {
+			http.Error(w, fmt.Sprintf("Unknown member %q (use a DID for accounts outside the network)", member), http.StatusBadRequest)
+			return
+		}
+		dids = append(dids, did)
+	}
+
+	if err := s.db.SaveCohort(name, req.Description, dids); err != nil {
+		log.Printf("Error saving cohort %s: %v", name, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	s.handleGetCohort(w, r)
+}
+
+func (s *Server) handleDeleteCohort(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+	deleted, err := s.db.DeleteCohort(name)
+	if err != nil {
+		log.Printf("Error deleting cohort %s: %v", name, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if !deleted {
+		http.Error(w, "Cohort not found", http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleListCommunities(w http.ResponseWriter, r *http.Request) {
+	communities, err := s.db.GetCommunities()
+	if err != nil {
+		log.Printf("Error listing communities: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if communities == nil {
+		communities = []database.Community{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"communities": communities})
+}
+
+// CommunityRequest is the body of PUT /api/admin/communities/{id}
+type CommunityRequest struct {
+	Name string `json:"name"` // "" goes back to the generated label
+}
+
+// handleRenameCommunity names a community, e.g. "the ML crowd"
+func (s *Server) handleRenameCommunity(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid community ID", http.StatusBadRequest)
+		return
+	}
+
+	var req CommunityRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<16)).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+		return
+	}
+
+	community, err := s.db.RenameCommunity(id, strings.TrimSpace(req.Name))
+	if err != nil {
+		log.Printf("Error renaming community %d: %v", id, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if community == nil {
+		http.Error(w, "Community not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(community)
+}
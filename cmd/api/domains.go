@@ -0,0 +1,71 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/aggregator"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/database"
+)
+
+// DomainsResponse ranks the hosts of links shared in a time window
+type DomainsResponse struct {
+	Hours   int                  `json:"hours"`
+	Degree  int                  `json:"degree"`
+	Domains []database.TopDomain `json:"domains"`
+}
+
+// handleDomains groups shares by link host, showing which outlets dominate
+// the network. Accepts ?hours= (default 24), ?degree= (as in trending) and
+// ?limit= (default 25).
+func (s *Server) handleDomains(w http.ResponseWriter, r *http.Request) {
+	hoursStr := r.URL.Query().Get("hours")
+	if hoursStr == "" {
+		hoursStr = strconv.Itoa(aggregator.DefaultTrendingHours)
+	}
+	hours, err := strconv.Atoi(hoursStr)
+	if err != nil || hours < 1 || hours > aggregator.MaxTrendingHours {
+		http.Error(w, fmt.Sprintf("Invalid hours parameter (1-%d)", aggregator.MaxTrendingHours), http.StatusBadRequest)
+		return
+	}
+
+	degreeStr := r.URL.Query().Get("degree")
+	if degreeStr == "" {
+		degreeStr = "0"
+	}
+	degree, err := strconv.Atoi(degreeStr)
+	if err != nil || degree < 0 || degree > 2 {
+		http.Error(w, "Invalid degree parameter (0=all, 1=1st-degree, 2=2nd-degree)", http.StatusBadRequest)
+		return
+	}
+
+	limitStr := r.URL.Query().Get("limit")
+	if limitStr == "" {
+		limitStr = "25"
+	}
+	limit, err := strconv.Atoi(limitStr)
+	if err != nil || limit < 1 || limit > 100 {
+		http.Error(w, "Invalid limit parameter (1-100)", http.StatusBadRequest)
+		return
+	}
+
+	domains, err := s.db.GetTopDomains(hours, degree, limit, 3)
+	if err != nil {
+		log.Printf("Error getting top domains: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if domains == nil {
+		domains = []database.TopDomain{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(DomainsResponse{
+		Hours:   hours,
+		Degree:  degree,
+		Domains: domains,
+	})
+}
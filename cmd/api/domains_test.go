@@ -0,0 +1,74 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/testutil"
+)
+
+func TestHandleDomainsValidation(t *testing.T) {
+	server := &Server{} // Invalid parameters are refused before any query
+
+	for _, query := range []string{"hours=0", "hours=721", "hours=x", "degree=3", "degree=-1", "limit=0", "limit=101"} {
+		rec := httptest.NewRecorder()
+		server.handleDomains(rec, httptest.NewRequest(http.MethodGet, "/api/domains?"+query, nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status %d, want 400", query, rec.Code)
+		}
+	}
+}
+
+func TestHandleDomains(t *testing.T) {
+	if testing.Short() {
+		t.Skip("integration test")
+	}
+	db := testutil.NewTestDB(t) // Skips without TEST_DATABASE_URL or docker
+
+	now := time.Now().Add(-time.Minute)
+	testutil.AddShare(t, db, "did:plc:a", 1, "https://www.example.com/story", now)
+	testutil.AddShare(t, db, "did:plc:b", 2, "https://example.com/story", now)
+	testutil.AddShare(t, db, "did:plc:c", 2, "https://example.org/other", now)
+
+	server := &Server{db: db}
+	get := func(query string) DomainsResponse {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		server.handleDomains(rec, httptest.NewRequest(http.MethodGet, "/api/domains?"+query, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: status %d %s", query, rec.Code, rec.Body.String())
+		}
+		var response DomainsResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+			t.Fatalf("%s: decoding response: %v", query, err)
+		}
+		return response
+	}
+
+	all := get("")
+	if all.Hours != 24 || all.Degree != 0 || len(all.Domains) != 2 {
+		t.Fatalf("default response = %+v, want 24 hours, all degrees, 2 domains", all)
+	}
+	if d := all.Domains[0]; d.Domain != "example.com" || d.Shares != 2 || len(d.TopLinks) != 1 || d.TopLinks[0].ShareCount != 2 {
+		t.Errorf("first domain = %+v, want example.com with one link shared twice", d)
+	}
+
+	first := get("hours=1&degree=1&limit=5")
+	if first.Hours != 1 || first.Degree != 1 || len(first.Domains) != 1 || first.Domains[0].Domain != "example.com" {
+		t.Errorf("1st-degree response = %+v, want example.com alone", first)
+	}
+
+	// An empty window is an empty list, not null
+	if _, err := db.Exec(`DELETE FROM post_links`); err != nil {
+		t.Fatal(err)
+	}
+	rec := httptest.NewRecorder()
+	server.handleDomains(rec, httptest.NewRequest(http.MethodGet, "/api/domains", nil))
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(rec.Body.Bytes(), &raw); err != nil || string(raw["domains"]) != "[]" {
+		t.Errorf("empty response = %s, want domains: []", rec.Body.String())
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/calendar"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/database"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/events"
+)
+
+// upcomingEvents loads future events mentioned by trending links, parsing
+// ?hours= (trending window, default 72), ?min_shares= (default 3) and ?limit=
+func (s *Server) upcomingEvents(w http.ResponseWriter, r *http.Request) ([]database.UpcomingEvent, bool) {
+	hoursStr := r.URL.Query().Get("hours")
+	if hoursStr == "" {
+		hoursStr = "72"
+	}
+	hours, err := strconv.Atoi(hoursStr)
+	if err != nil || hours < 1 || hours > 720 {
+		http.Error(w, "Invalid hours parameter (1-720)", http.StatusBadRequest)
+		return nil, false
+	}
+
+	minSharesStr := r.URL.Query().Get("min_shares")
+	if minSharesStr == "" {
+		minSharesStr = "3"
+	}
+	minShares, err := strconv.Atoi(minSharesStr)
+	if err != nil || minShares < 1 {
+		http.Error(w, "Invalid min_shares parameter (1 or more)", http.StatusBadRequest)
+		return nil, false
+	}
+
+	limitStr := r.URL.Query().Get("limit")
+	if limitStr == "" {
+		limitStr = "100"
+	}
+	limit, err := strconv.Atoi(limitStr)
+	if err != nil || limit < 1 || limit > 500 {
+		http.Error(w, "Invalid limit parameter (1-500)", http.StatusBadRequest)
+		return nil, false
+	}
+
+	// Start of today in the configured timezone, so all-day events happening
+	// today are included
+	today := calendar.Date(time.Now(), s.config.Timezone)
+
+	upcoming, err := s.db.GetUpcomingEvents(today, hours, minShares, limit)
+	if err != nil {
+		log.Printf("Error getting upcoming events: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return nil, false
+	}
+	if upcoming == nil {
+		upcoming = []database.UpcomingEvent{}
+	}
+	return upcoming, true
+}
+
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	upcoming, ok := s.upcomingEvents(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"events": upcoming})
+}
+
+func (s *Server) handleEventsICS(w http.ResponseWriter, r *http.Request) {
+	upcoming, ok := s.upcomingEvents(w, r)
+	if !ok {
+		return
+	}
+
+	entries := make([]events.CalendarEntry, len(upcoming))
+	for i, e := range upcoming {
+		description := e.URL
+		if title := stringOrEmpty(e.Title); title != "" {
+			description = fmt.Sprintf("%s\n%s\nShared by %d accounts", title, e.URL, e.ShareCount)
+		}
+		entries[i] = events.CalendarEntry{
+			UID:         fmt.Sprintf("link-event-%d@bluesky-news-aggregator", e.ID),
+			Summary:     e.Name,
+			Description: description,
+			URL:         e.URL,
+			Location:    stringOrEmpty(e.Location),
+			StartAt:     e.StartAt,
+			EndAt:       e.EndAt,
+			AllDay:      e.AllDay,
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	if err := events.WriteICS(w, "Events trending in my Bluesky network", entries); err != nil {
+		log.Printf("Error writing calendar: %v", err)
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/database"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/feedgen"
+)
+
+// handleDIDDocument serves the did:web document pointing Bluesky at this
+// feed generator
+func (s *Server) handleDIDDocument(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.feedgen.DIDDocument())
+}
+
+func (s *Server) handleDescribeFeedGenerator(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.feedgen.Describe())
+}
+
+// handleFeedSkeleton serves the Bluesky feed: a post sharing each trending
+// link, in trending order. Sensitive links are left out, since the app
+// shows their previews unblurred.
+func (s *Server) handleFeedSkeleton(w http.ResponseWriter, r *http.Request) {
+	req, err := s.feedgen.ParseSkeletonRequest(r.URL.Query())
+	if err != nil {
+		writeXRPCError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	// The App View asks on behalf of every reader, so share the cache
+	// with /api/trending
+	cacheTTL := time.Duration(s.config.Server.TrendingCacheSeconds) * time.Second
+	cacheKey := fmt.Sprintf("feedgen:%d:%s", req.Limit, req.Cursor)
+	if cacheTTL > 0 {
+		if cached, ok, err := s.cache.Store.Get(r.Context(), cacheKey); err != nil {
+			log.Printf("Error reading feed skeleton cache: %v", err)
+		} else if ok {
+			w.Header().Set("Content-Type", "application/json")
+			w.Write(cached)
+			return
+		}
+	}
+
+	query := s.feedQuery(req.Limit)
+	if req.Cursor != "" {
+		if query.Options.After, err = database.ParseTrendingCursor(req.Cursor); err != nil {
+			writeXRPCError(w, http.StatusBadRequest, &feedgen.Error{Name: "InvalidRequest", Message: "Invalid cursor"})
+			return
+		}
+	}
+
+	links, err := s.aggregator.Trending(query)
+	if err != nil {
+		log.Printf("Error getting trending links for feed skeleton: %v", err)
+		writeXRPCError(w, http.StatusInternalServerError, &feedgen.Error{Name: "InternalServerError", Message: "Internal server error"})
+		return
+	}
+
+	linkIDs := make([]int, 0, len(links))
+	for _, link := range links {
+		if !link.Sensitive {
+			linkIDs = append(linkIDs, link.ID)
+		}
+	}
+	since := time.Now().Add(-time.Duration(query.Hours) * time.Hour)
+	posts, err := s.db.GetFeedPosts(linkIDs, since)
+	if err != nil {
+		log.Printf("Error getting feed posts: %v", err)
+		writeXRPCError(w, http.StatusInternalServerError, &feedgen.Error{Name: "InternalServerError", Message: "Internal server error"})
+		return
+	}
+
+	skeleton := feedgen.Skeleton{Cursor: query.NextCursor(links), Feed: []feedgen.SkeletonPost{}}
+	for _, id := range linkIDs {
+		if uri, ok := posts[id]; ok {
+			skeleton.Feed = append(skeleton.Feed, feedgen.SkeletonPost{Post: uri})
+		}
+	}
+
+	body, err := json.Marshal(skeleton)
+	if err != nil {
+		log.Printf("Error encoding feed skeleton: %v", err)
+		writeXRPCError(w, http.StatusInternalServerError, &feedgen.Error{Name: "InternalServerError", Message: "Internal server error"})
+		return
+	}
+	if cacheTTL > 0 {
+		if err := s.cache.Store.Set(r.Context(), cacheKey, body, cacheTTL); err != nil {
+			log.Printf("Error writing feed skeleton cache: %v", err)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+}
+
+// writeXRPCError writes an error in the XRPC format Bluesky expects
+func writeXRPCError(w http.ResponseWriter, status int, err error) {
+	xrpcErr, ok := err.(*feedgen.Error)
+	if !ok {
+		xrpcErr = &feedgen.Error{Name: "InvalidRequest", Message: err.Error()}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(xrpcErr)
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/aggregator"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/database"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/feed"
+)
+
+// handleTrendingFeed serves trending links as an RSS 2.0 feed, or Atom with
+// ?format=atom. Other parameters filter as in /api/trending.
+func (s *Server) handleTrendingFeed(w http.ResponseWriter, r *http.Request) {
+	values := r.URL.Query()
+	format, ok := feedFormat(w, values)
+	if !ok {
+		return
+	}
+
+	query := aggregator.NewTrendingQuery(s.config.Trending)
+	query.Location = s.config.Timezone
+	if err := query.Parse(values); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !s.resolveCohort(w, &query) {
+		return
+	}
+
+	links, err := s.aggregator.Trending(query)
+	if err != nil {
+		log.Printf("Error getting trending links for feed: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	loc := s.localizer(r)
+	setLanguageHeaders(w, loc)
+	s.writeLinksFeed(w, r, format, feed.Feed{
+		Title:       loc.T("feed_title"),
+		Description: loc.T("feed_description"),
+		Link:        s.absoluteURL(r, "/"),
+	}, database.SnapshotLinks(links), query.IncludeSensitive)
+}
+
+// feedFormat returns the ?format= of a feed request (RSS by default),
+// removing it from values, or writes the error response and returns false
+func feedFormat(w http.ResponseWriter, values url.Values) (string, bool) {
+	format := values.Get("format")
+	switch format {
+	case "":
+		format = feed.FormatRSS
+	case feed.FormatRSS, feed.FormatAtom:
+	default:
+		http.Error(w, "Invalid format parameter (rss, atom)", http.StatusBadRequest)
+		return "", false
+	}
+	values.Del("format")
+	return format, true
+}
+
+// writeLinksFeed writes links as the items of out, linking to them through
+// the click redirect when it is on
+func (s *Server) writeLinksFeed(w http.ResponseWriter, r *http.Request, format string, out feed.Feed, links []database.SnapshotLink, includeSensitive bool) {
+	loc := s.localizer(r)
+	out.SelfURL = s.absoluteURL(r, r.URL.RequestURI())
+	out.Updated = time.Now()
+	for _, link := range links {
+		title := link.Title
+		if title == "" {
+			title = link.URL
+		}
+		description := loc.N("shared_by_accounts", link.ShareCount)
+		if link.Description != "" {
+			description = link.Description + "\n\n" + description
+		}
+		target := s.clickURL(link.ID)
+		if target == "" {
+			target = link.URL
+		}
+
+		item := feed.Item{
+			ID:          s.absoluteURL(r, fmt.Sprintf("/links/%d", link.ID)),
+			Title:       title,
+			Description: description,
+			Link:        s.absoluteURL(r, target),
+			Published:   link.LastSharedAt,
+		}
+		imageURL := link.ImageURL
+		if link.Sensitive && !includeSensitive && imageURL != "" {
+			imageURL = sensitivePlaceholderImage
+		}
+		if imageURL != "" {
+			item.ImageURL = s.absoluteURL(r, imageURL)
+		}
+		out.Items = append(out.Items, item)
+	}
+
+	w.Header().Set("Content-Type", feed.ContentType(format))
+	if err := feed.Write(w, format, out); err != nil {
+		log.Printf("Error writing feed: %v", err)
+	}
+}
+
+// feedQuery returns the trending query behind the Bluesky feed
+func (s *Server) feedQuery(limit int) aggregator.TrendingQuery {
+	query := aggregator.NewTrendingQuery(s.config.Trending)
+	query.Location = s.config.Timezone
+	query.Hours = s.config.FeedGen.Hours
+	query.Limit = limit
+	return query
+}
@@ -0,0 +1,304 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/aggregator"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/atutil"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/database"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/graphql"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/i18n"
+)
+
+// maxGraphQLBodyBytes caps a GraphQL request body
+const maxGraphQLBodyBytes = 64 << 10
+
+// handleGraphQL runs a GraphQL query: POSTed as JSON ({"query",
+// "variables", "operationName"}) or passed as GET parameters
+func (s *Server) handleGraphQL(w http.ResponseWriter, r *http.Request) {
+	var req graphql.Request
+	if r.Method == http.MethodPost {
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxGraphQLBodyBytes)).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+			return
+		}
+	} else {
+		req.Query = r.URL.Query().Get("query")
+		req.OperationName = r.URL.Query().Get("operationName")
+		if v := r.URL.Query().Get("variables"); v != "" {
+			if err := json.Unmarshal([]byte(v), &req.Variables); err != nil {
+				http.Error(w, "Invalid variables parameter", http.StatusBadRequest)
+				return
+			}
+		}
+	}
+	if req.Query == "" {
+		http.Error(w, "Missing query", http.StatusBadRequest)
+		return
+	}
+
+	ctx := context.WithValue(r.Context(), localizerContextKey{}, s.localizer(r))
+	response := s.graphql.Execute(ctx, req)
+
+	w.Header().Set("Content-Type", "application/json")
+	if response.Data == nil {
+		w.WriteHeader(http.StatusBadRequest)
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+type localizerContextKey struct{}
+
+// graphqlLink is a Link in GraphQL results
+type graphqlLink struct {
+	link             database.TrendingLink
+	includeSensitive bool
+}
+
+// graphqlTrendingPage is a TrendingPage in GraphQL results
+type graphqlTrendingPage struct {
+	links      []graphqlLink
+	nextCursor string
+}
+
+// graphqlArgValues turns GraphQL arguments into the query parameters of the
+// matching REST endpoint, so both validate them the same way
+func graphqlArgValues(args graphql.Args) url.Values {
+	values := url.Values{}
+	for name, value := range args {
+		switch v := value.(type) {
+		case nil:
+		case []interface{}:
+			items := make([]string, len(v))
+			for i, item := range v {
+				items[i] = fmt.Sprint(item)
+			}
+			values.Set(name, strings.Join(items, ","))
+		default:
+			values.Set(name, fmt.Sprint(v))
+		}
+	}
+	return values
+}
+
+// newGraphQLSchema builds the /graphql schema over links, the posts sharing
+// them and their sharers. Field names match the REST API's JSON.
+func (s *Server) newGraphQLSchema() *graphql.Schema {
+	sharer := &graphql.Object{Name: "Sharer", Fields: map[string]*graphql.Field{
+		"did": {Resolve: func(_ context.Context, src interface{}, _ graphql.Args) (interface{}, error) {
+			return src.(database.SharerAvatar).DID, nil
+		}},
+		"handle": {Resolve: func(_ context.Context, src interface{}, _ graphql.Args) (interface{}, error) {
+			return src.(database.SharerAvatar).Handle, nil
+		}},
+		"display_name": {Resolve: func(_ context.Context, src interface{}, _ graphql.Args) (interface{}, error) {
+			return src.(database.SharerAvatar).DisplayName, nil
+		}},
+		"avatar_url": {Resolve: func(_ context.Context, src interface{}, _ graphql.Args) (interface{}, error) {
+			return src.(database.SharerAvatar).AvatarURL, nil
+		}},
+		"pinned": {Resolve: func(_ context.Context, src interface{}, _ graphql.Args) (interface{}, error) {
+			return src.(database.SharerAvatar).Pinned, nil
+		}},
+		"account_type": {Resolve: func(_ context.Context, src interface{}, _ graphql.Args) (interface{}, error) {
+			// Post authors are loaded without their type
+			if t := src.(database.SharerAvatar).AccountType; t != "" {
+				return t, nil
+			}
+			return nil, nil
+		}},
+	}}
+
+	post := &graphql.Object{Name: "Post", Fields: map[string]*graphql.Field{
+		"id": {Resolve: func(_ context.Context, src interface{}, _ graphql.Args) (interface{}, error) {
+			return src.(database.LinkPost).ID, nil
+		}},
+		"content": {Resolve: func(_ context.Context, src interface{}, _ graphql.Args) (interface{}, error) {
+			return src.(database.LinkPost).Content, nil
+		}},
+		"created_at": {Resolve: func(_ context.Context, src interface{}, _ graphql.Args) (interface{}, error) {
+			return s.formatTime(src.(database.LinkPost).CreatedAt), nil
+		}},
+		"url": {Resolve: func(_ context.Context, src interface{}, _ graphql.Args) (interface{}, error) {
+			p := src.(database.LinkPost)
+			if postURL := atutil.PostURL(p.ID, p.Handle); postURL != "" {
+				return postURL, nil
+			}
+			return nil, nil
+		}},
+		"author": {Type: sharer, Resolve: func(_ context.Context, src interface{}, _ graphql.Args) (interface{}, error) {
+			p := src.(database.LinkPost)
+			return database.SharerAvatar{Handle: p.Handle, DisplayName: p.DisplayName, AvatarURL: p.AvatarURL, DID: p.DID}, nil
+		}},
+	}}
+
+	link := &graphql.Object{Name: "Link", Fields: map[string]*graphql.Field{
+		"id": {Resolve: func(_ context.Context, src interface{}, _ graphql.Args) (interface{}, error) {
+			return src.(graphqlLink).link.ID, nil
+		}},
+		"url": {Resolve: func(_ context.Context, src interface{}, _ graphql.Args) (interface{}, error) {
+			return src.(graphqlLink).link.NormalizedURL, nil
+		}},
+		"title": {Resolve: func(_ context.Context, src interface{}, _ graphql.Args) (interface{}, error) {
+			return stringOrEmpty(src.(graphqlLink).link.Title), nil
+		}},
+		"description": {Resolve: func(_ context.Context, src interface{}, _ graphql.Args) (interface{}, error) {
+			return stringOrEmpty(src.(graphqlLink).link.Description), nil
+		}},
+		"image_url": {Resolve: func(_ context.Context, src interface{}, _ graphql.Args) (interface{}, error) {
+			l := src.(graphqlLink)
+			imageURL := stringOrEmpty(l.link.OGImageURL)
+			if l.link.Sensitive && !l.includeSensitive && imageURL != "" {
+				imageURL = sensitivePlaceholderImage
+			}
+			return imageURL, nil
+		}},
+		"share_count": {Resolve: func(_ context.Context, src interface{}, _ graphql.Args) (interface{}, error) {
+			return src.(graphqlLink).link.ShareCount, nil
+		}},
+		"last_shared_at": {Resolve: func(_ context.Context, src interface{}, _ graphql.Args) (interface{}, error) {
+			t := src.(graphqlLink).link.LastSharedAt
+			if t.IsZero() {
+				return nil, nil
+			}
+			return s.formatTime(t), nil
+		}},
+		"last_shared_ago": {Resolve: func(ctx context.Context, src interface{}, _ graphql.Args) (interface{}, error) {
+			t := src.(graphqlLink).link.LastSharedAt
+			if t.IsZero() {
+				return nil, nil
+			}
+			return ctx.Value(localizerContextKey{}).(*i18n.Localizer).Ago(t.In(s.config.Timezone), time.Now()), nil
+		}},
+		"first_shared_at": {Resolve: func(_ context.Context, src interface{}, _ graphql.Args) (interface{}, error) {
+			t := src.(graphqlLink).link.FirstSharedAt
+			if t == nil {
+				return nil, nil
+			}
+			return s.formatTime(*t), nil
+		}},
+		"sensitive": {Resolve: func(_ context.Context, src interface{}, _ graphql.Args) (interface{}, error) {
+			return src.(graphqlLink).link.Sensitive, nil
+		}},
+		"dead": {Resolve: func(_ context.Context, src interface{}, _ graphql.Args) (interface{}, error) {
+			return src.(graphqlLink).link.DeadAt != nil, nil
+		}},
+		"click_url": {Resolve: func(_ context.Context, src interface{}, _ graphql.Args) (interface{}, error) {
+			return s.clickURL(src.(graphqlLink).link.ID), nil
+		}},
+		"sharers": {Type: sharer, Resolve: func(_ context.Context, src interface{}, _ graphql.Args) (interface{}, error) {
+			sharers, err := s.db.GetLinkSharers(src.(graphqlLink).link.ID)
+			if err != nil {
+				log.Printf("Error getting sharers for link %d: %v", src.(graphqlLink).link.ID, err)
+				return nil, errors.New("failed to get sharers")
+			}
+			return sharers, nil
+		}},
+		"posts": {Type: post, Resolve: func(_ context.Context, src interface{}, args graphql.Args) (interface{}, error) {
+			limit, err := args.Int("limit", 50)
+			if err != nil || limit < 1 || limit > 50 {
+				return nil, errors.New("limit must be 1-50")
+			}
+			posts, err := s.db.GetLinkPosts(src.(graphqlLink).link.ID)
+			if err != nil {
+				log.Printf("Error getting posts for link %d: %v", src.(graphqlLink).link.ID, err)
+				return nil, errors.New("failed to get posts")
+			}
+			if len(posts) > limit {
+				posts = posts[:limit]
+			}
+			return posts, nil
+		}},
+	}}
+
+	trendingPage := &graphql.Object{Name: "TrendingPage", Fields: map[string]*graphql.Field{
+		"links": {Type: link, Resolve: func(_ context.Context, src interface{}, _ graphql.Args) (interface{}, error) {
+			return src.(graphqlTrendingPage).links, nil
+		}},
+		"next_cursor": {Resolve: func(_ context.Context, src interface{}, _ graphql.Args) (interface{}, error) {
+			if next := src.(graphqlTrendingPage).nextCursor; next != "" {
+				return next, nil
+			}
+			return nil, nil
+		}},
+	}}
+
+	query := &graphql.Object{Name: "Query", Fields: map[string]*graphql.Field{
+		"trending": {Type: trendingPage, Resolve: func(_ context.Context, _ interface{}, args graphql.Args) (interface{}, error) {
+			q := aggregator.NewTrendingQuery(s.config.Trending)
+			q.Location = s.config.Timezone
+			if err := q.Parse(graphqlArgValues(args)); err != nil {
+				return nil, err
+			}
+			if q.Cohort != "" {
+				cohort, err := s.db.GetCohortByName(q.Cohort)
+				if err != nil {
+					log.Printf("Error getting cohort %s: %v", q.Cohort, err)
+					return nil, errors.New("failed to get cohort")
+				}
+				if cohort == nil {
+					return nil, errors.New("unknown cohort")
+				}
+				q.Options.CohortID = cohort.ID
+			}
+			links, err := s.aggregator.Trending(q)
+			if err != nil {
+				log.Printf("Error getting trending links: %v", err)
+				return nil, errors.New("failed to get trending links")
+			}
+			results := make([]graphqlLink, len(links))
+			for i, l := range links {
+				results[i] = graphqlLink{link: l, includeSensitive: q.IncludeSensitive}
+			}
+			return graphqlTrendingPage{links: results, nextCursor: q.NextCursor(links)}, nil
+		}},
+		"link": {Type: link, Resolve: func(_ context.Context, _ interface{}, args graphql.Args) (interface{}, error) {
+			id, err := args.Int("id", 0)
+			if err != nil || id < 1 {
+				return nil, errors.New("id must be a link ID")
+			}
+			l, err := s.db.GetLinkByID(id)
+			if err != nil {
+				log.Printf("Error getting link %d: %v", id, err)
+				return nil, errors.New("failed to get link")
+			}
+			if l == nil {
+				return nil, nil
+			}
+			breakdown, err := s.db.GetLinkBreakdown(id)
+			if err != nil {
+				log.Printf("Error getting breakdown for link %d: %v", id, err)
+				return nil, errors.New("failed to get link")
+			}
+			result := database.TrendingLink{
+				ID:            l.ID,
+				NormalizedURL: l.NormalizedURL,
+				OriginalURL:   l.OriginalURL,
+				Title:         l.Title,
+				Description:   l.Description,
+				OGImageURL:    l.OGImageURL,
+				ShareCount:    breakdown.UniqueAuthors,
+				FirstSharedAt: l.FirstSharedAt,
+				Sensitive:     l.Sensitive,
+				ClickCount:    l.ClickCount,
+				DeadAt:        l.DeadAt,
+				ImageMissing:  l.ImageMissing,
+			}
+			if breakdown.LastSharedAt != nil {
+				result.LastSharedAt = *breakdown.LastSharedAt
+			}
+			includeSensitive := args["include_sensitive"] == true
+			return graphqlLink{link: result, includeSensitive: includeSensitive}, nil
+		}},
+	}}
+
+	return &graphql.Schema{Query: query}
+}
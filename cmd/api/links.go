@@ -0,0 +1,255 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/atutil"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/database"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/urlutil"
+)
+
+// LinkDetailResponse is the API response for a single link with its share breakdown
+type LinkDetailResponse struct {
+	ID             int                         `json:"id"`
+	URL            string                      `json:"url"`
+	Title          string                      `json:"title"`
+	Description    string                      `json:"description"`
+	ImageURL       string                      `json:"image_url"`
+	Sensitive      bool                        `json:"sensitive,omitempty"`
+	MetadataSource string                      `json:"metadata_source,omitempty"` // bluesky or scraped
+	HTTPStatus     *int                        `json:"http_status,omitempty"`     // Status seen by the last dead-link check (0 = unreachable)
+	DeadSince      *time.Time                  `json:"dead_since,omitempty"`      // When the page was found gone
+	ImageMissing   bool                        `json:"image_missing,omitempty"`   // No usable preview image: show a placeholder for the domain
+	Breakdown      *database.LinkBreakdown     `json:"breakdown"`
+	EarliestSharer *database.LinkContributor   `json:"earliest_sharer"`
+	Contributors   []database.LinkContributor  `json:"contributors"`
+	History        []database.MetadataRevision `json:"metadata_history"` // Replaced metadata, newest first
+}
+
+func (s *Server) handleLink(w http.ResponseWriter, r *http.Request) {
+	linkID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid link ID", http.StatusBadRequest)
+		return
+	}
+
+	link, err := s.db.GetLinkByID(linkID)
+	if err != nil {
+		log.Printf("Error getting link %d: %v", linkID, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if link == nil {
+		http.Error(w, "Link not found", http.StatusNotFound)
+		return
+	}
+
+	breakdown, err := s.db.GetLinkBreakdown(linkID)
+	if err != nil {
+		log.Printf("Error getting breakdown for link %d: %v", linkID, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	contributors, err := s.db.GetLinkContributors(linkID, 100)
+	if err != nil {
+		log.Printf("Error getting contributors for link %d: %v", linkID, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	history, err := s.db.GetLinkMetadataHistory(linkID)
+	if err != nil {
+		log.Printf("Error getting metadata history for link %d: %v", linkID, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if history == nil {
+		history = []database.MetadataRevision{}
+	}
+
+	includeSensitive := r.URL.Query().Get("include_sensitive") == "true"
+	imageURL := stringOrEmpty(link.OGImageURL)
+	if link.Sensitive && !includeSensitive && imageURL != "" {
+		imageURL = sensitivePlaceholderImage
+	}
+	if link.Sensitive && !includeSensitive {
+		for i := range history {
+			if history[i].OGImageURL != nil && *history[i].OGImageURL != "" {
+				placeholder := sensitivePlaceholderImage
+				history[i].OGImageURL = &placeholder
+			}
+		}
+	}
+
+	response := LinkDetailResponse{
+		ID:             link.ID,
+		URL:            link.NormalizedURL,
+		Title:          stringOrEmpty(link.Title),
+		Description:    stringOrEmpty(link.Description),
+		ImageURL:       imageURL,
+		Sensitive:      link.Sensitive,
+		MetadataSource: stringOrEmpty(link.MetadataSource),
+		HTTPStatus:     link.HTTPStatus,
+		DeadSince:      link.DeadAt,
+		ImageMissing:   link.ImageMissing && imageURL == "",
+		Breakdown:      breakdown,
+		Contributors:   contributors,
+		History:        history,
+	}
+	if len(contributors) > 0 {
+		response.EarliestSharer = &contributors[0] // Ordered by first share
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// handleLinkPage renders a link's permalink page, with OpenGraph and Twitter
+// card tags so it unfurls when shared on Bluesky, Slack and the like
+func (s *Server) handleLinkPage(w http.ResponseWriter, r *http.Request) {
+	linkID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid link ID", http.StatusBadRequest)
+		return
+	}
+
+	link, err := s.db.GetLinkByID(linkID)
+	if err != nil {
+		log.Printf("Error getting link %d: %v", linkID, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if link == nil {
+		http.Error(w, "Link not found", http.StatusNotFound)
+		return
+	}
+
+	breakdown, err := s.db.GetLinkBreakdown(linkID)
+	if err != nil {
+		log.Printf("Error getting breakdown for link %d: %v", linkID, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	contributors, err := s.db.GetLinkContributors(linkID, 20)
+	if err != nil {
+		log.Printf("Error getting contributors for link %d: %v", linkID, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	title := stringOrEmpty(link.Title)
+	if title == "" {
+		title = link.NormalizedURL
+	}
+	description := stringOrEmpty(link.Description)
+	if description == "" {
+		description = fmt.Sprintf("Shared by %d accounts in my Bluesky network", breakdown.UniqueAuthors)
+	}
+	imageURL := stringOrEmpty(link.OGImageURL)
+	if link.Sensitive && imageURL != "" {
+		imageURL = sensitivePlaceholderImage
+	}
+	targetURL := s.clickURL(link.ID)
+	if targetURL == "" {
+		targetURL = link.NormalizedURL
+	}
+
+	data := struct {
+		Title        string
+		Description  string
+		Domain       string
+		Permalink    string
+		ImageURL     string // Absolute, since unfurlers fetch it off-site
+		TargetURL    string
+		Link         *database.Link
+		Breakdown    *database.LinkBreakdown
+		Contributors []database.LinkContributor
+		Timezone     *time.Location
+	}{
+		Title:        title,
+		Description:  description,
+		Domain:       urlutil.Domain(link.NormalizedURL),
+		Permalink:    s.absoluteURL(r, fmt.Sprintf("/links/%d", link.ID)),
+		TargetURL:    targetURL,
+		Link:         link,
+		Breakdown:    breakdown,
+		Contributors: contributors,
+		Timezone:     s.config.Timezone,
+	}
+	if imageURL != "" {
+		data.ImageURL = s.absoluteURL(r, imageURL)
+	}
+
+	s.render(w, r, "link.html", data)
+}
+
+// absoluteURL resolves a site path against PUBLIC_URL, or the host the
+// request was made to when it isn't set. Absolute URLs are returned as is.
+func (s *Server) absoluteURL(r *http.Request, ref string) string {
+	if strings.HasPrefix(ref, "http://") || strings.HasPrefix(ref, "https://") {
+		return ref
+	}
+	base := s.config.Server.PublicURL
+	if base == "" {
+		scheme := "http"
+		if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+			scheme = "https"
+		}
+		base = scheme + "://" + r.Host
+	}
+	return strings.TrimSuffix(base, "/") + ref
+}
+
+func (s *Server) handleLinkPosts(w http.ResponseWriter, r *http.Request) {
+	// Get link ID from URL parameter
+	linkIDStr := chi.URLParam(r, "id")
+	linkID, err := strconv.Atoi(linkIDStr)
+	if err != nil {
+		http.Error(w, "Invalid link ID", http.StatusBadRequest)
+		return
+	}
+
+	// Get posts for this link
+	posts, err := s.db.GetLinkPosts(linkID)
+	if err != nil {
+		log.Printf("Error getting link posts: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	response := make([]LinkPostResponse, len(posts))
+	for i, post := range posts {
+		response[i] = newLinkPostResponse(post)
+	}
+
+	// Return posts as JSON
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"link_id": linkID,
+		"posts":   response,
+	})
+}
+
+// LinkPostResponse is a post sharing a link, with its bsky.app URLs
+type LinkPostResponse struct {
+	database.LinkPost
+	URL        string `json:"url,omitempty"` // The post on bsky.app (empty = not a post URI)
+	ProfileURL string `json:"profile_url"`   // The author on bsky.app
+}
+
+func newLinkPostResponse(post database.LinkPost) LinkPostResponse {
+	return LinkPostResponse{
+		LinkPost:   post,
+		URL:        atutil.PostURL(post.ID, post.Handle),
+		ProfileURL: atutil.ProfileURL(post.Handle, post.DID),
+	}
+}
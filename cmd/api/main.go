@@ -1,20 +1,26 @@
 package main
 
 import (
-	"encoding/json"
+	"context"
+	"crypto/rand"
 	"fmt"
 	"html/template"
 	"log"
+	"net"
 	"net/http"
-	"strconv"
-	"sync"
+	"strings"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/aggregator"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/cache"
 	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/config"
 	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/database"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/features"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/feedgen"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/graphql"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/i18n"
 )
 
 var templates *template.Template
@@ -25,24 +31,17 @@ type Server struct {
 	aggregator *aggregator.Aggregator
 	router     *chi.Mux
 	config     *config.Config
-}
-
-// TrendingResponse is the API response for trending links
-type TrendingResponse struct {
-	Links []LinkResponse `json:"links"`
-}
-
-// LinkResponse is a single link in the API response
-type LinkResponse struct {
-	ID            int                     `json:"id"`
-	URL           string                  `json:"url"`
-	Title         string                  `json:"title"`
-	Description   string                  `json:"description"`
-	ImageURL      string                  `json:"image_url"`
-	ShareCount    int                     `json:"share_count"`
-	LastSharedAt  string                  `json:"last_shared_at"`
-	Sharers       []string                `json:"sharers"`
-	SharerAvatars []database.SharerAvatar `json:"sharer_avatars"`
+	cache      *cache.Backend // Shared across replicas when Redis is configured
+	clickSalt  []byte         // Random per process; hashes readers for click de-duplication
+	stream     *trendingHub   // Wakes live trending streams (nil = stream disabled)
+	i18n       *i18n.Bundle   // Message catalogs for pages and API text
+	graphql    *graphql.Schema
+	feedgen    *feedgen.Generator // Bluesky custom feed (nil = not served)
+
+	// Public mode (see ServerConfig.PublicMode)
+	admin   *chi.Mux     // Admin routes on their own listener (nil = not served separately)
+	audit   *log.Logger  // Audit events (nil = not in public mode)
+	blocked []*net.IPNet // Configured blocklist
 }
 
 func main() {
@@ -52,8 +51,21 @@ func main() {
 		log.Fatalf("Failed to load config: %v", err)
 	}
 
-	// Load templates
-	templates = template.Must(template.ParseGlob("cmd/api/templates/*.html"))
+	// Optional subsystems turned off in this deployment
+	if err := features.Configure(cfg.Features); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+
+	// Load message catalogs and templates; pages are rendered from a copy
+	// of the templates bound to the request's locale (see render)
+	bundle, err := i18n.New(cfg.Server.DefaultLocale)
+	if err != nil {
+		log.Fatalf("Invalid server.default_locale: %v", err)
+	}
+	templates = template.Must(template.New("").Funcs(template.FuncMap{
+		"join":  strings.Join,
+		"nonce": func() string { return "" }, // Replaced per request (see render)
+	}).Funcs(localeFuncs(bundle.Localizer(cfg.Server.DefaultLocale))).ParseGlob("cmd/api/templates/*.html"))
 
 	// Initialize database (log safe connection string without password)
 	log.Printf("Connecting to database: %s", cfg.Database.DatabaseConnStringSafe())
@@ -63,8 +75,47 @@ func main() {
 	}
 	defer db.Close()
 
-	// Create aggregator with default ranking
-	agg := aggregator.NewAggregator(db, &aggregator.ShareCountRanking{})
+	// Bring the schema up to date before anything reads it
+	if cfg.MigrateOnStart {
+		log.Printf("Running migrations...")
+		if err := db.Migrate(context.Background(), database.MigrationsDir, cfg.Database.Schema, nil); err != nil {
+			log.Fatalf("Failed to run migrations: %v", err)
+		}
+	}
+
+	// Create aggregator with the configured ranking
+	ranker, err := aggregator.NewRanking(cfg.Trending.Ranking, cfg.Trending.ClickWeight)
+	if err != nil {
+		log.Fatalf("Invalid trending.ranking: %v", err)
+	}
+	agg := aggregator.NewAggregator(db, ranker)
+
+	// Compare a candidate strategy against the served one on sampled requests
+	if cfg.Trending.ShadowRanking != "" {
+		shadow, err := aggregator.NewRanking(cfg.Trending.ShadowRanking, cfg.Trending.ClickWeight)
+		if err != nil {
+			log.Fatalf("Invalid trending.shadow_ranking: %v", err)
+		}
+		agg.SetShadow(&aggregator.ShadowConfig{
+			Primary:    cfg.Trending.Ranking,
+			Shadow:     cfg.Trending.ShadowRanking,
+			Ranker:     shadow,
+			SampleRate: cfg.Trending.ShadowSampleRate,
+			Record:     func(c aggregator.RankingComparison) { go recordRankingComparison(db, c) },
+		})
+		log.Printf("Shadow-ranking %.0f%% of trending requests with %s", cfg.Trending.ShadowSampleRate*100, cfg.Trending.ShadowRanking)
+	}
+
+	// Rate limiter and response cache: Redis when configured, else in-memory
+	backend, err := cache.NewWithConfig(&cache.Config{
+		RedisURL:  cfg.Redis.URL,
+		KeyPrefix: cfg.Redis.KeyPrefix,
+	})
+	if err != nil {
+		log.Fatalf("Failed to initialize cache: %v", err)
+	}
+	defer backend.Close()
+	log.Printf("Using %s cache backend", backend.Name)
 
 	// Create server
 	server := &Server{
@@ -72,12 +123,68 @@ func main() {
 		aggregator: agg,
 		router:     chi.NewRouter(),
 		config:     cfg,
+		cache:      backend,
+		clickSalt:  make([]byte, 32),
+		i18n:       bundle,
+	}
+	server.graphql = server.newGraphQLSchema()
+	if server.feedgen, err = feedgen.New(cfg.FeedGen, cfg.Server.PublicURL); err != nil {
+		log.Fatalf("Invalid feed generator settings: %v", err)
+	}
+	if server.feedgen != nil {
+		query := server.feedQuery(feedgen.MaxLimit)
+		if err := query.Validate(); err != nil {
+			log.Fatalf("Invalid feed generator settings: %v", err)
+		}
+		log.Printf("Serving Bluesky feed %s as %s", server.feedgen.FeedURI(), server.feedgen.ServiceDID)
+	}
+	if _, err := rand.Read(server.clickSalt); err != nil {
+		log.Fatalf("Failed to generate click salt: %v", err)
+	}
+
+	// Live trending stream, woken by Postgres notifications of new shares
+	if cfg.Server.StreamIntervalSeconds > 0 {
+		shared, err := db.ListenLinkShares(context.Background(), cfg.Database.DatabaseConnString())
+		if err != nil {
+			log.Fatalf("Failed to listen for link shares: %v", err)
+		}
+		server.stream = newTrendingHub()
+		go server.stream.run(shared, time.Duration(cfg.Server.StreamIntervalSeconds)*time.Second)
+	}
+
+	if cfg.Server.PublicMode {
+		if server.audit, err = newAuditLogger(cfg.Server.AuditLogFile); err != nil {
+			log.Fatalf("Failed to open audit log: %v", err)
+		}
+		if server.blocked, err = parseIPNets(cfg.Server.BlockedIPs); err != nil {
+			log.Fatalf("Invalid blocked_ips: %v", err)
+		}
+		if cfg.Server.AdminListen == "" {
+			log.Printf("Public mode on: admin routes disabled (set ADMIN_LISTEN to serve them)")
+		} else {
+			log.Printf("Public mode on: admin routes served on %s only", cfg.Server.AdminListen)
+		}
 	}
 
 	server.setupRoutes()
 
 	addr := fmt.Sprintf("%s:%d", cfg.Server.Host, cfg.Server.Port)
 
+	// In public mode admin routes get their own listener, which should only
+	// be reachable from a private network
+	if server.admin != nil {
+		go func() {
+			log.Printf("Starting admin server on %s", cfg.Server.AdminListen)
+			var err error
+			if cfg.Server.IsTLSEnabled() {
+				err = http.ListenAndServeTLS(cfg.Server.AdminListen, cfg.Server.TLSCertFile, cfg.Server.TLSKeyFile, server.admin)
+			} else {
+				err = http.ListenAndServe(cfg.Server.AdminListen, server.admin)
+			}
+			log.Fatalf("Admin server failed: %v", err)
+		}()
+	}
+
 	// Start server with or without TLS
 	if cfg.Server.IsTLSEnabled() {
 		log.Printf("Starting HTTPS server on %s", addr)
@@ -102,7 +209,15 @@ func (s *Server) setupRoutes() {
 	// Security middleware
 	s.router.Use(s.securityHeadersMiddleware)
 	s.router.Use(s.corsMiddleware)
+	if s.config.Server.PublicMode {
+		s.router.Use(s.blocklistMiddleware)
+		s.router.Use(s.bodyLimitMiddleware)
+	}
+	s.router.Use(s.apiKeyMiddleware)
 	s.router.Use(s.rateLimitMiddleware)
+	if s.config.Server.PublicMode {
+		s.router.Use(s.routeRateLimitMiddleware)
+	}
 
 	// Static files
 	fileServer := http.FileServer(http.Dir("cmd/api/static"))
@@ -111,262 +226,158 @@ func (s *Server) setupRoutes() {
 	// Routes
 	s.router.Get("/", s.handleRoot)
 	s.router.Get("/api/trending", s.handleTrending)
+	s.router.Get("/feeds/trending.xml", s.handleTrendingFeed)
+	if s.stream != nil {
+		s.router.Get("/api/trending/stream", s.handleTrendingStream)
+	}
+	s.router.Get("/api/links/{id}", s.handleLink)
 	s.router.Get("/api/links/{id}/posts", s.handleLinkPosts)
+	s.router.Get("/api/users/{handle}", s.handleUserProfile)
+	s.router.Get("/api/users/{handle}/discoveries", s.handleDiscoveries)
+	s.router.Get("/api/sharers/{handle}/links", s.handleSharerLinks)
+	s.router.Get("/api/leaderboard", s.handleLeaderboard)
+	s.router.Get("/api/domains", s.handleDomains)
+	s.router.Get("/api/recommendations/follows", s.handleFollowRecommendations)
+	s.router.Get("/api/events", s.handleEvents)
+	s.router.Get("/api/events.ics", s.handleEventsICS)
+	s.router.Get("/api/cohorts", s.handleListCohorts)
+	s.router.Get("/api/cohorts/{name}", s.handleGetCohort)
+	s.router.Get("/api/communities", s.handleListCommunities)
+	s.router.Get("/api/topics", s.handleListTopics)
+	s.router.Get("/api/topics/{slug}", s.handleGetTopic)
+	s.router.Get("/graphql", s.handleGraphQL)
+	s.router.Post("/graphql", s.handleGraphQL)
+	if s.feedgen != nil {
+		if s.feedgen.ServesDIDDocument() {
+			s.router.Get("/.well-known/did.json", s.handleDIDDocument)
+		}
+		s.router.Get("/xrpc/app.bsky.feed.describeFeedGenerator", s.handleDescribeFeedGenerator)
+		s.router.Get("/xrpc/app.bsky.feed.getFeedSkeleton", s.handleFeedSkeleton)
+	}
+	if !s.config.Server.PublicMode {
+		s.router.Group(func(r chi.Router) {
+			r.Use(s.adminAuthMiddleware)
+			s.adminRoutes(r)
+		})
+	} else if s.config.Server.AdminListen != "" {
+		s.admin = chi.NewRouter()
+		s.admin.Use(middleware.RequestID)
+		s.admin.Use(middleware.RealIP)
+		s.admin.Use(middleware.Logger)
+		s.admin.Use(middleware.Recoverer)
+		s.admin.Use(s.securityHeadersMiddleware)
+		s.admin.Use(s.auditMiddleware)
+		s.admin.Use(s.adminAuthMiddleware)
+		s.adminRoutes(s.admin)
+	}
+	s.router.Get("/users/{handle}", s.handleUserPage)
+	s.router.Get("/links/{id}", s.handleLinkPage)
+	s.router.Get("/topics/{slug}", s.handleTopicPage)
+	s.router.Get("/feeds/topics/{slug}.xml", s.handleTopicFeed)
 	s.router.Get("/health", s.handleHealth)
+	s.router.Get("/api/status", s.handleStatus)
+	s.router.Get("/api/capabilities", s.handleCapabilities)
+	s.router.Post("/api/keys", s.handleRequestAPIKey)
+	s.router.Get("/api/keys/me", s.handleAPIKeyUsage)
+	if s.config.Server.ClickTracking {
+		s.router.Get("/out/{id}", s.handleOutboundClick)
+		s.router.Head("/out/{id}", s.handleOutboundClick)
+	}
+	if features.Enabled(features.Digests) {
+		s.router.Get("/api/trending/as-of", s.handleTrendingAsOf)
+		s.router.Get("/api/trending/delta", s.handleTrendingDelta)
+		s.router.Get("/snapshots/{id}", s.handleSnapshotPage)
+		s.router.Get("/digest/{date}", s.handleDigestPage)
+	}
+	if features.Enabled(features.Retrospectives) {
+		s.router.Get("/api/retrospective", s.handleRetrospective)
+		s.router.Get("/api/on-this-day", s.handleOnThisDay)
+		s.router.Get("/retrospective", s.handleRetrospectivePage)
+		s.router.Get("/on-this-day", s.handleOnThisDayPage)
+	}
+}
+
+// adminRoutes registers the endpoints that need the admin token: on the
+// main router, or on the admin listener in public mode
+func (s *Server) adminRoutes(r chi.Router) {
+	r.Put("/api/cohorts/{name}", s.handleSaveCohort)
+	r.Delete("/api/cohorts/{name}", s.handleDeleteCohort)
+	r.Put("/api/admin/communities/{id}", s.handleRenameCommunity)
+	r.Put("/api/topics/{slug}", s.handleSaveTopic)
+	r.Delete("/api/topics/{slug}", s.handleDeleteTopic)
+	r.Get("/api/links/{id}/metadata", s.handleGetLinkMetadata)
+	r.Put("/api/links/{id}/metadata", s.handleSetLinkMetadataPreference)
+	r.Get("/api/admin/coordinated", s.handleCoordinatedLinks)
+	r.Get("/api/admin/follows/{did}/stats", s.handleFollowStats)
+	r.Post("/api/admin/follows/import", s.handleImportFollows)
+	r.Get("/api/admin/accounts", s.handleListCuratedAccounts)
+	r.Get("/api/admin/accounts/{did}", s.handleGetAccountCuration)
+	r.Put("/api/admin/accounts/{did}", s.handleUpdateAccountCuration)
+	r.Get("/api/admin/domain-stats", s.handleDomainStats)
+	r.Get("/api/admin/scrape/jobs", s.handleScrapeJobs)
+	r.Get("/api/admin/scrape/failures", s.handleScrapeFailures)
+	r.Get("/api/admin/scrape/links/{id}", s.handleScrapeLink)
+	r.Post("/api/admin/scrape/links/{id}/retry", s.handleRetryScrape)
+	r.Get("/api/admin/scrape/paused-domains", s.handlePausedScrapeDomains)
+	r.Put("/api/admin/scrape/paused-domains/{domain}", s.handlePauseScrapeDomain)
+	r.Delete("/api/admin/scrape/paused-domains/{domain}", s.handleResumeScrapeDomain)
+	r.Get("/api/admin/features", s.handleListFeatures)
+	r.Get("/api/admin/ranking-comparisons", s.handleRankingComparisons)
+	r.Get("/api/admin/keys", s.handleListAPIKeys)
+	r.Post("/api/admin/keys/{id}/approve", s.handleApproveAPIKey)
+	r.Post("/api/admin/keys/{id}/revoke", s.handleRevokeAPIKey)
 }
 
 func (s *Server) handleRoot(w http.ResponseWriter, r *http.Request) {
 	data := struct {
 		Title string
 	}{
-		Title: "Bluesky News Aggregator",
+		Title: s.localizer(r).T("site_title"),
 	}
 
-	if err := templates.ExecuteTemplate(w, "index.html", data); err != nil {
-		log.Printf("Template error: %v", err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
-	}
+	s.render(w, r, "index.html", data)
 }
 
-func (s *Server) handleTrending(w http.ResponseWriter, r *http.Request) {
-	// Parse query parameters
-	hoursStr := r.URL.Query().Get("hours")
-	if hoursStr == "" {
-		hoursStr = "24"
-	}
-	hours, err := strconv.Atoi(hoursStr)
-	if err != nil || hours < 1 || hours > 720 {
-		http.Error(w, "Invalid hours parameter (1-720)", http.StatusBadRequest)
-		return
-	}
-
-	limitStr := r.URL.Query().Get("limit")
-	if limitStr == "" {
-		limitStr = "50"
-	}
-	limit, err := strconv.Atoi(limitStr)
-	if err != nil || limit < 1 || limit > 100 {
-		http.Error(w, "Invalid limit parameter (1-100)", http.StatusBadRequest)
-		return
-	}
-
-	// Parse degree filter: 0 = all, 1 = 1st-degree only, 2 = 2nd-degree only
-	degreeStr := r.URL.Query().Get("degree")
-	degree := 0 // Default: all posts
-	if degreeStr != "" {
-		degree, err = strconv.Atoi(degreeStr)
-		if err != nil || degree < 0 || degree > 2 {
-			http.Error(w, "Invalid degree parameter (0=all, 1=1st-degree, 2=2nd-degree)", http.StatusBadRequest)
-			return
-		}
-	}
-
-	// Get trending links (filtered by degree if specified)
-	var links []database.TrendingLink
-	if degree == 0 {
-		links, err = s.aggregator.GetTrendingLinks(hours, limit)
-	} else {
-		links, err = s.aggregator.GetTrendingLinksByDegree(hours, limit, degree)
-	}
-	if err != nil {
-		log.Printf("Error getting trending links: %v", err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
-		return
-	}
-
-	// Convert to response format
-	response := TrendingResponse{
-		Links: make([]LinkResponse, len(links)),
-	}
-
-	for i, link := range links {
-		// Fetch sharer avatars for this link
-		sharers, err := s.db.GetLinkSharers(link.ID)
-		if err != nil {
-			log.Printf("Error getting sharers for link %d: %v", link.ID, err)
-			sharers = []database.SharerAvatar{} // Empty on error
-		}
-
-		response.Links[i] = LinkResponse{
-			ID:            link.ID,
-			URL:           link.NormalizedURL,
-			Title:         stringOrEmpty(link.Title),
-			Description:   stringOrEmpty(link.Description),
-			ImageURL:      stringOrEmpty(link.OGImageURL),
-			ShareCount:    link.ShareCount,
-			LastSharedAt:  link.LastSharedAt.Format("2006-01-02T15:04:05Z"),
-			Sharers:       []string(link.Sharers),
-			SharerAvatars: sharers,
-		}
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+// localizer picks the request's locale: ?locale= if given, else the best
+// match for Accept-Language, else the configured default
+func (s *Server) localizer(r *http.Request) *i18n.Localizer {
+	return s.i18n.Match(r.URL.Query().Get("locale"), r.Header.Get("Accept-Language"))
 }
 
-func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+// setLanguageHeaders marks a response as localized for loc
+func setLanguageHeaders(w http.ResponseWriter, loc *i18n.Localizer) {
+	w.Header().Set("Content-Language", loc.Locale())
+	w.Header().Add("Vary", "Accept-Language")
 }
 
-func (s *Server) handleLinkPosts(w http.ResponseWriter, r *http.Request) {
-	// Get link ID from URL parameter
-	linkIDStr := chi.URLParam(r, "id")
-	linkID, err := strconv.Atoi(linkIDStr)
-	if err != nil {
-		http.Error(w, "Invalid link ID", http.StatusBadRequest)
-		return
+// localeFuncs are the template functions that translate into loc
+func localeFuncs(loc *i18n.Localizer) template.FuncMap {
+	return template.FuncMap{
+		"t":        loc.T,
+		"plural":   loc.N,
+		"locale":   loc.Locale,
+		"messages": loc.JSMessages,
 	}
+}
 
-	// Get posts for this link
-	posts, err := s.db.GetLinkPosts(linkID)
+// render executes a page template in the request's locale
+func (s *Server) render(w http.ResponseWriter, r *http.Request, name string, data interface{}) {
+	loc := s.localizer(r)
+	// The shared set is never executed itself, so it can always be cloned
+	tmpl, err := templates.Clone()
 	if err != nil {
-		log.Printf("Error getting link posts: %v", err)
+		log.Printf("Template error: %v", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
+	tmpl.Funcs(localeFuncs(loc))
+	tmpl.Funcs(template.FuncMap{"nonce": func() string { return cspNonce(r) }})
 
-	// Return posts as JSON
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"link_id": linkID,
-		"posts":   posts,
-	})
-}
-
-// securityHeadersMiddleware adds security headers to all responses
-func (s *Server) securityHeadersMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Prevent MIME type sniffing
-		w.Header().Set("X-Content-Type-Options", "nosniff")
-
-		// Prevent clickjacking
-		w.Header().Set("X-Frame-Options", "DENY")
-
-		// XSS protection (legacy but still useful)
-		w.Header().Set("X-XSS-Protection", "1; mode=block")
-
-		// Referrer policy
-		w.Header().Set("Referrer-Policy", "strict-origin-when-cross-origin")
-
-		// Content Security Policy (adjust as needed for your frontend)
-		w.Header().Set("Content-Security-Policy", "default-src 'self'; script-src 'self'; style-src 'self' 'unsafe-inline'; img-src 'self' https: data:; connect-src 'self'")
-
-		// HSTS (only if TLS is enabled)
-		if s.config.Server.IsTLSEnabled() {
-			w.Header().Set("Strict-Transport-Security", "max-age=31536000; includeSubDomains")
-		}
-
-		next.ServeHTTP(w, r)
-	})
-}
-
-// corsMiddleware handles CORS with configurable allowed origins
-func (s *Server) corsMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		origin := s.config.Server.CORSAllowOrigin
-
-		// If specific origin is configured, validate it
-		if origin != "*" {
-			// Check if request origin matches allowed origin
-			requestOrigin := r.Header.Get("Origin")
-			if requestOrigin != "" && requestOrigin != origin {
-				// Origin not allowed - don't set CORS headers
-				next.ServeHTTP(w, r)
-				return
-			}
-		}
-
-		w.Header().Set("Access-Control-Allow-Origin", origin)
-		w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
-		w.Header().Set("Access-Control-Max-Age", "86400") // 24 hours
-
-		if r.Method == "OPTIONS" {
-			w.WriteHeader(http.StatusOK)
-			return
-		}
-
-		next.ServeHTTP(w, r)
-	})
-}
-
-// rateLimitMiddleware implements simple IP-based rate limiting
-func (s *Server) rateLimitMiddleware(next http.Handler) http.Handler {
-	// Simple in-memory rate limiter
-	type visitor struct {
-		count    int
-		lastSeen time.Time
-	}
-
-	var (
-		visitors = make(map[string]*visitor)
-		mu       sync.Mutex
-	)
-
-	// Cleanup old entries periodically
-	go func() {
-		for {
-			time.Sleep(time.Minute)
-			mu.Lock()
-			for ip, v := range visitors {
-				if time.Since(v.lastSeen) > time.Minute {
-					delete(visitors, ip)
-				}
-			}
-			mu.Unlock()
-		}
-	}()
-
-	limitPerMinute := s.config.Server.RateLimitRPM
-	if limitPerMinute == 0 {
-		limitPerMinute = 100 // Default
+	setLanguageHeaders(w, loc)
+	if err := tmpl.ExecuteTemplate(w, name, data); err != nil {
+		log.Printf("Template error: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
 	}
-
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Skip rate limiting for health checks
-		if r.URL.Path == "/health" {
-			next.ServeHTTP(w, r)
-			return
-		}
-
-		ip := r.RemoteAddr
-		// Use X-Forwarded-For if behind proxy
-		if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
-			ip = xff
-		}
-
-		mu.Lock()
-		v, exists := visitors[ip]
-		if !exists {
-			visitors[ip] = &visitor{count: 1, lastSeen: time.Now()}
-			mu.Unlock()
-			next.ServeHTTP(w, r)
-			return
-		}
-
-		// Reset count if more than a minute has passed
-		if time.Since(v.lastSeen) > time.Minute {
-			v.count = 1
-			v.lastSeen = time.Now()
-			mu.Unlock()
-			next.ServeHTTP(w, r)
-			return
-		}
-
-		v.count++
-		v.lastSeen = time.Now()
-
-		if v.count > limitPerMinute {
-			mu.Unlock()
-			w.Header().Set("Retry-After", "60")
-			http.Error(w, "Rate limit exceeded", http.StatusTooManyRequests)
-			return
-		}
-		mu.Unlock()
-
-		next.ServeHTTP(w, r)
-	})
 }
 
 func stringOrEmpty(s *string) string {
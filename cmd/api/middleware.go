@@ -0,0 +1,303 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// securityHeadersMiddleware adds security headers to all responses
+func (s *Server) securityHeadersMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Prevent MIME type sniffing
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+
+		// Prevent clickjacking
+		w.Header().Set("X-Frame-Options", "DENY")
+
+		// XSS protection (legacy but still useful)
+		w.Header().Set("X-XSS-Protection", "1; mode=block")
+
+		// Referrer policy
+		w.Header().Set("Referrer-Policy", "strict-origin-when-cross-origin")
+
+		// Content Security Policy (adjust as needed for your frontend).
+		// Public mode only runs scripts carrying this response's nonce.
+		if s.config.Server.PublicMode {
+			nonce, err := newCSPNonce()
+			if err != nil {
+				log.Printf("Error generating CSP nonce: %v", err)
+				http.Error(w, "Internal server error", http.StatusInternalServerError)
+				return
+			}
+			r = r.WithContext(context.WithValue(r.Context(), cspNonceKey{}, nonce))
+			w.Header().Set("Content-Security-Policy", fmt.Sprintf("default-src 'none'; script-src 'nonce-%s'; style-src 'self'; img-src 'self' https: data:; connect-src 'self'; base-uri 'none'; form-action 'self'; frame-ancestors 'none'", nonce))
+		} else {
+			w.Header().Set("Content-Security-Policy", "default-src 'self'; script-src 'self'; style-src 'self' 'unsafe-inline'; img-src 'self' https: data:; connect-src 'self'")
+		}
+
+		// HSTS (only if TLS is enabled)
+		if s.config.Server.IsTLSEnabled() {
+			w.Header().Set("Strict-Transport-Security", "max-age=31536000; includeSubDomains")
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// corsMiddleware handles CORS with configurable allowed origins
+func (s *Server) corsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := s.config.Server.CORSAllowOrigin
+
+		// If specific origin is configured, validate it
+		if origin != "*" {
+			// Check if request origin matches allowed origin
+			requestOrigin := r.Header.Get("Origin")
+			if requestOrigin != "" && requestOrigin != origin {
+				// Origin not allowed - don't set CORS headers
+				next.ServeHTTP(w, r)
+				return
+			}
+		}
+
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-API-Key")
+		w.Header().Set("Access-Control-Max-Age", "86400") // 24 hours
+
+		if r.Method == "OPTIONS" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// rateLimitMiddleware implements IP-based rate limiting, shared across
+// replicas when the cache backend is Redis
+func (s *Server) rateLimitMiddleware(next http.Handler) http.Handler {
+	limitPerMinute := s.config.Server.RateLimitRPM
+	if limitPerMinute == 0 {
+		limitPerMinute = 100 // Default
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Skip rate limiting for health checks and keyed requests, which
+		// have their own limits
+		if r.URL.Path == "/health" || apiKeyFromContext(r) != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ip := s.clientIP(r)
+		allowed, err := s.cache.RateLimiter.Allow(r.Context(), "ip:"+ip, limitPerMinute, time.Minute)
+		if err != nil {
+			// Fail open: a cache outage shouldn't take the API down
+			log.Printf("Rate limiter error: %v", err)
+			allowed = true
+		}
+		if !allowed {
+			s.strike(r.Context(), ip)
+			w.Header().Set("Retry-After", "60")
+			http.Error(w, "Rate limit exceeded", http.StatusTooManyRequests)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// clientIP returns the address rate limits and blocks apply to. Public mode
+// uses the address middleware.RealIP settled on rather than the whole
+// X-Forwarded-For header, which a client can vary freely.
+func (s *Server) clientIP(r *http.Request) string {
+	if !s.config.Server.PublicMode {
+		// Use X-Forwarded-For if behind proxy
+		if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
+			return xff
+		}
+		return r.RemoteAddr
+	}
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
+
+type cspNonceKey struct{}
+
+// newCSPNonce returns a random nonce for a response's script-src
+func newCSPNonce() (string, error) {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(nonce), nil
+}
+
+// cspNonce returns the nonce scripts on a page must carry ("" outside
+// public mode)
+func cspNonce(r *http.Request) string {
+	nonce, _ := r.Context().Value(cspNonceKey{}).(string)
+	return nonce
+}
+
+// publicRouteLimits are per-IP limits (requests per minute) on costly or
+// abusable routes in public mode, on top of the global limit. The first
+// matching prefix applies.
+var publicRouteLimits = []struct {
+	prefix string
+	rpm    int
+}{
+	{"/api/keys", 5}, // Key signups
+	{"/api/trending/stream", 10},
+	{"/api/trending/as-of", 30},
+	{"/api/retrospective", 30},
+	{"/api/on-this-day", 30},
+	{"/graphql", 30},
+	{"/feeds", 30},
+	{"/out", 60},
+}
+
+// routeRateLimitMiddleware applies publicRouteLimits. Keyed requests have
+// their own limits and skip it.
+func (s *Server) routeRateLimitMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if apiKeyFromContext(r) != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		for _, route := range publicRouteLimits {
+			if r.URL.Path != route.prefix && !strings.HasPrefix(r.URL.Path, route.prefix+"/") {
+				continue
+			}
+			ip := s.clientIP(r)
+			allowed, err := s.cache.RateLimiter.Allow(r.Context(), "route:"+route.prefix+":"+ip, route.rpm, time.Minute)
+			if err != nil {
+				log.Printf("Rate limiter error: %v", err)
+				allowed = true
+			}
+			if !allowed {
+				s.strike(r.Context(), ip)
+				w.Header().Set("Retry-After", "60")
+				http.Error(w, "Rate limit exceeded", http.StatusTooManyRequests)
+				return
+			}
+			break
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// strikeWindow is the period rate-limited requests are counted over before
+// an IP is blocked
+const strikeWindow = 10 * time.Minute
+
+// strike records a rate-limited request from ip in public mode, blocking
+// the IP once it exceeds the configured strikes in strikeWindow. Blocks are
+// kept in the shared cache, so they apply on every replica.
+func (s *Server) strike(ctx context.Context, ip string) {
+	strikes := s.config.Server.BlockAfterStrikes
+	if !s.config.Server.PublicMode || strikes < 0 {
+		return
+	}
+	within, err := s.cache.RateLimiter.Allow(ctx, "strikes:"+ip, strikes, strikeWindow)
+	if err != nil {
+		log.Printf("Rate limiter error: %v", err)
+		return
+	}
+	if within {
+		return
+	}
+
+	duration := time.Duration(s.config.Server.BlockMinutes) * time.Minute
+	if err := s.cache.Store.Set(ctx, "blocked:"+ip, []byte("1"), duration); err != nil {
+		log.Printf("Error blocking %s: %v", ip, err)
+		return
+	}
+	s.auditf("blocked ip=%s duration=%v reason=rate_limit strikes=%d", ip, duration, strikes+1)
+}
+
+// blocklistMiddleware refuses requests from configured or automatically
+// blocked IPs
+func (s *Server) blocklistMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.isBlocked(r.Context(), s.clientIP(r)) {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// isBlocked reports whether ip is on the configured blocklist or was
+// blocked for abuse
+func (s *Server) isBlocked(ctx context.Context, ip string) bool {
+	if parsed := net.ParseIP(ip); parsed != nil {
+		for _, n := range s.blocked {
+			if n.Contains(parsed) {
+				return true
+			}
+		}
+	}
+	_, blocked, err := s.cache.Store.Get(ctx, "blocked:"+ip)
+	if err != nil {
+		// Fail open, as the rate limiter does
+		log.Printf("Error checking blocklist: %v", err)
+		return false
+	}
+	return blocked
+}
+
+// parseIPNets parses IPs and CIDRs; a bare IP is a single-address network
+func parseIPNets(values []string) ([]*net.IPNet, error) {
+	var nets []*net.IPNet
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		if !strings.Contains(value, "/") {
+			ip := net.ParseIP(value)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid IP %q", value)
+			}
+			bits := 8 * len(ip.To16())
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 32
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, n, err := net.ParseCIDR(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR %q", value)
+		}
+		nets = append(nets, n)
+	}
+	return nets, nil
+}
+
+// bodyLimitMiddleware refuses request bodies larger than the configured cap
+func (s *Server) bodyLimitMiddleware(next http.Handler) http.Handler {
+	limit := int64(s.config.Server.MaxBodyBytes)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ContentLength > limit {
+			s.auditf("body_refused ip=%s path=%s bytes=%d", s.clientIP(r), r.URL.Path, r.ContentLength)
+			http.Error(w, "Request body too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, limit)
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/calendar"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/database"
+)
+
+// RetrospectiveResponse is the top links of a span of past days
+type RetrospectiveResponse struct {
+	Title string                       `json:"title"`
+	From  string                       `json:"from"` // First day, YYYY-MM-DD in the configured timezone
+	To    string                       `json:"to"`   // Last day
+	Links []database.RetrospectiveLink `json:"links"`
+}
+
+func (s *Server) handleRetrospective(w http.ResponseWriter, r *http.Request) {
+	retro, status, msg := s.loadRetrospective(r)
+	if status != http.StatusOK {
+		http.Error(w, msg, status)
+		return
+	}
+
+	setLanguageHeaders(w, s.localizer(r))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(retro)
+}
+
+func (s *Server) handleOnThisDay(w http.ResponseWriter, r *http.Request) {
+	retro, status, msg := s.loadOnThisDay(r)
+	if status != http.StatusOK {
+		http.Error(w, msg, status)
+		return
+	}
+
+	setLanguageHeaders(w, s.localizer(r))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(retro)
+}
+
+// handleRetrospectivePage renders /api/retrospective as HTML
+func (s *Server) handleRetrospectivePage(w http.ResponseWriter, r *http.Request) {
+	retro, status, msg := s.loadRetrospective(r)
+	if status != http.StatusOK {
+		http.Error(w, msg, status)
+		return
+	}
+	s.renderRetrospective(w, r, retro)
+}
+
+// handleOnThisDayPage renders /api/on-this-day as HTML
+func (s *Server) handleOnThisDayPage(w http.ResponseWriter, r *http.Request) {
+	retro, status, msg := s.loadOnThisDay(r)
+	if status != http.StatusOK {
+		http.Error(w, msg, status)
+		return
+	}
+	s.renderRetrospective(w, r, retro)
+}
+
+func (s *Server) renderRetrospective(w http.ResponseWriter, r *http.Request, retro *RetrospectiveResponse) {
+	s.render(w, r, "retrospective.html", retro)
+}
+
+// loadRetrospective returns the top links of the past period (week, the
+// last 7 days, or month, the last 30; today included). Query parameters:
+// period, limit (1-100, default 20) and include_sensitive.
+func (s *Server) loadRetrospective(r *http.Request) (*RetrospectiveResponse, int, string) {
+	loc := s.localizer(r)
+	var days int
+	var title string
+	switch r.URL.Query().Get("period") {
+	case "", "week":
+		days, title = 7, loc.T("retrospective_week")
+	case "month":
+		days, title = 30, loc.T("retrospective_month")
+	default:
+		return nil, http.StatusBadRequest, "Invalid period parameter (week, month)"
+	}
+
+	last := calendar.Date(time.Now(), s.config.Timezone)
+	return s.loadRetrospectiveDays(r, title, last.AddDate(0, 0, 1-days), last)
+}
+
+// loadOnThisDay returns the top links of the same day a month or a year
+// ago (?ago=, default year) before ?date= (YYYY-MM-DD, default today).
+// Query parameters limit and include_sensitive are as for retrospectives.
+func (s *Server) loadOnThisDay(r *http.Request) (*RetrospectiveResponse, int, string) {
+	anchor := calendar.Date(time.Now(), s.config.Timezone)
+	if v := r.URL.Query().Get("date"); v != "" {
+		parsed, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			return nil, http.StatusBadRequest, "Invalid date parameter (YYYY-MM-DD)"
+		}
+		anchor = parsed
+	}
+
+	loc := s.localizer(r)
+	var day time.Time
+	var title string
+	switch r.URL.Query().Get("ago") {
+	case "", "year":
+		day, title = monthsBefore(anchor, 12), loc.T("on_this_day_year")
+	case "month":
+		day, title = monthsBefore(anchor, 1), loc.T("on_this_day_month")
+	default:
+		return nil, http.StatusBadRequest, "Invalid ago parameter (month, year)"
+	}
+
+	return s.loadRetrospectiveDays(r, title, day, day)
+}
+
+// loadRetrospectiveDays loads the top links of days first through last
+// (dates at midnight UTC), hiding sensitive previews unless requested
+func (s *Server) loadRetrospectiveDays(r *http.Request, title string, first, last time.Time) (*RetrospectiveResponse, int, string) {
+	limitStr := r.URL.Query().Get("limit")
+	if limitStr == "" {
+		limitStr = "20"
+	}
+	limit, err := strconv.Atoi(limitStr)
+	if err != nil || limit < 1 || limit > 100 {
+		return nil, http.StatusBadRequest, "Invalid limit parameter (1-100)"
+	}
+
+	links, err := s.db.GetRetrospective(first, last, limit)
+	if err != nil {
+		log.Printf("Error getting retrospective for %s to %s: %v", first.Format("2006-01-02"), last.Format("2006-01-02"), err)
+		return nil, http.StatusInternalServerError, "Internal server error"
+	}
+	if links == nil {
+		links = []database.RetrospectiveLink{}
+	}
+
+	includeSensitive := r.URL.Query().Get("include_sensitive") == "true"
+	placeholder := sensitivePlaceholderImage
+	for i := range links {
+		if links[i].Sensitive && links[i].OGImageURL != nil && !includeSensitive {
+			links[i].OGImageURL = &placeholder
+		}
+	}
+
+	return &RetrospectiveResponse{
+		Title: title,
+		From:  first.Format("2006-01-02"),
+		To:    last.Format("2006-01-02"),
+		Links: links,
+	}, http.StatusOK, ""
+}
+
+// monthsBefore returns the same date months earlier, clamped to the end of
+// a shorter month (March 31 a month back is the last day of February)
+func monthsBefore(day time.Time, months int) time.Time {
+	shifted := day.AddDate(0, -months, 0)
+	if shifted.Day() != day.Day() {
+		shifted = shifted.AddDate(0, 0, -shifted.Day())
+	}
+	return shifted
+}
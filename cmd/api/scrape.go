@@ -0,0 +1,285 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/database"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/scrapequeue"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/urlutil"
+)
+
+// ScrapeJobResponse is a metadata fetch waiting in the scrape backlog
+type ScrapeJobResponse struct {
+	LinkID     int       `json:"link_id"`
+	URL        string    `json:"url"`
+	Priority   int       `json:"priority"` // Higher is fetched first
+	EnqueuedAt time.Time `json:"enqueued_at"`
+}
+
+func newScrapeJobResponse(job database.ScrapeJob) ScrapeJobResponse {
+	return ScrapeJobResponse{LinkID: job.LinkID, URL: job.URL, Priority: job.Priority, EnqueuedAt: job.EnqueuedAt}
+}
+
+// ScrapeJobsResponse lists the scrape backlog. Jobs already taken into the
+// firehose's in-memory queue aren't visible here.
+type ScrapeJobsResponse struct {
+	Backlog int                 `json:"backlog"` // Jobs parked in the database
+	Jobs    []ScrapeJobResponse `json:"jobs"`    // The first of them, in the order they will be served
+}
+
+func (s *Server) handleScrapeJobs(w http.ResponseWriter, r *http.Request) {
+	limit := 100
+	if l := r.URL.Query().Get("limit"); l != "" {
+		parsed, err := strconv.Atoi(l)
+		if err != nil || parsed < 1 || parsed > 1000 {
+			http.Error(w, "Invalid limit parameter (1-1000)", http.StatusBadRequest)
+			return
+		}
+		limit = parsed
+	}
+
+	backlog, err := s.db.CountScrapeJobs()
+	if err != nil {
+		log.Printf("Error counting scrape jobs: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	jobs, err := s.db.GetScrapeJobs(limit)
+	if err != nil {
+		log.Printf("Error getting scrape jobs: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	resp := ScrapeJobsResponse{Backlog: backlog, Jobs: make([]ScrapeJobResponse, len(jobs))}
+	for i, job := range jobs {
+		resp.Jobs[i] = newScrapeJobResponse(job)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+func (s *Server) handleScrapeFailures(w http.ResponseWriter, r *http.Request) {
+	domain := ""
+	if d := r.URL.Query().Get("domain"); d != "" {
+		var ok bool
+		if domain, ok = normalizeScrapeDomain(d); !ok {
+			http.Error(w, "Invalid domain parameter (a host name, e.g. example.com)", http.StatusBadRequest)
+			return
+		}
+	}
+
+	limit := 100
+	if l := r.URL.Query().Get("limit"); l != "" {
+		parsed, err := strconv.Atoi(l)
+		if err != nil || parsed < 1 || parsed > 1000 {
+			http.Error(w, "Invalid limit parameter (1-1000)", http.StatusBadRequest)
+			return
+		}
+		limit = parsed
+	}
+
+	failures, err := s.db.GetScrapeFailures(domain, limit)
+	if err != nil {
+		log.Printf("Error getting scrape failures: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if failures == nil {
+		failures = []database.ScrapeFailure{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(failures)
+}
+
+// ScrapeLinkResponse is the admin view of a link's scraping
+type ScrapeLinkResponse struct {
+	LinkID        int                     `json:"link_id"`
+	URL           string                  `json:"url"`
+	LastFetchedAt *time.Time              `json:"last_fetched_at"`      // null = not fetched yet, or retry pending
+	Queued        *ScrapeJobResponse      `json:"queued,omitempty"`     // Set while parked in the backlog
+	LastError     *database.ScrapeFailure `json:"last_error,omitempty"` // Set when the last scrape failed
+	DomainPaused  bool                    `json:"domain_paused"`
+}
+
+// scrapeLinkStatus gathers a link's scrape job, last error and pause state
+func (s *Server) scrapeLinkStatus(link *database.Link) (*ScrapeLinkResponse, error) {
+	resp := &ScrapeLinkResponse{LinkID: link.ID, URL: link.NormalizedURL, LastFetchedAt: link.LastFetchedAt}
+
+	job, err := s.db.GetScrapeJob(link.ID)
+	if err != nil {
+		return nil, err
+	}
+	if job != nil {
+		queued := newScrapeJobResponse(*job)
+		resp.Queued = &queued
+	}
+
+	if resp.LastError, err = s.db.GetScrapeError(link.ID); err != nil {
+		return nil, err
+	}
+	if resp.DomainPaused, err = s.db.IsScrapePaused(urlutil.Domain(link.NormalizedURL)); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// scrapeLink looks up the link named by the {id} URL parameter, writing the
+// error response and returning nil if there is none
+func (s *Server) scrapeLink(w http.ResponseWriter, r *http.Request) *database.Link {
+	linkID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid link ID", http.StatusBadRequest)
+		return nil
+	}
+
+	link, err := s.db.GetLinkByID(linkID)
+	if err != nil {
+		log.Printf("Error getting link %d: %v", linkID, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return nil
+	}
+	if link == nil {
+		http.Error(w, "Link not found", http.StatusNotFound)
+		return nil
+	}
+	return link
+}
+
+func (s *Server) handleScrapeLink(w http.ResponseWriter, r *http.Request) {
+	link := s.scrapeLink(w, r)
+	if link == nil {
+		return
+	}
+
+	status, err := s.scrapeLinkStatus(link)
+	if err != nil {
+		log.Printf("Error getting scrape status of link %d: %v", link.ID, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
+}
+
+// handleRetryScrape queues a link for scraping ahead of share-driven jobs.
+// The firehose's scrape queue picks it up on its next backlog refill, within
+// seconds; without the queue, the next cmd/metadata-fetcher run does.
+func (s *Server) handleRetryScrape(w http.ResponseWriter, r *http.Request) {
+	link := s.scrapeLink(w, r)
+	if link == nil {
+		return
+	}
+
+	paused, err := s.db.IsScrapePaused(urlutil.Domain(link.NormalizedURL))
+	if err != nil {
+		log.Printf("Error checking scrape pause for link %d: %v", link.ID, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if paused {
+		http.Error(w, "Scraping is paused for this link's domain (resume it first)", http.StatusConflict)
+		return
+	}
+
+	if err := s.db.RetryScrape(link.ID, link.NormalizedURL, scrapequeue.PriorityTrending); err != nil {
+		log.Printf("Error queueing scrape retry of link %d: %v", link.ID, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	link.LastFetchedAt = nil
+
+	status, err := s.scrapeLinkStatus(link)
+	if err != nil {
+		log.Printf("Error getting scrape status of link %d: %v", link.ID, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(status)
+}
+
+// normalizeScrapeDomain lowercases a host name and strips a leading www.,
+// matching how link domains are compared. Reports false if it isn't a bare
+// host name.
+func normalizeScrapeDomain(raw string) (string, bool) {
+	domain := strings.TrimPrefix(strings.ToLower(strings.TrimSpace(raw)), "www.")
+	if domain == "" || strings.ContainsAny(domain, "/:?#@ ") || !strings.Contains(domain, ".") {
+		return "", false
+	}
+	return domain, true
+}
+
+func (s *Server) handlePausedScrapeDomains(w http.ResponseWriter, r *http.Request) {
+	domains, err := s.db.GetPausedScrapeDomains()
+	if err != nil {
+		log.Printf("Error getting paused domains: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if domains == nil {
+		domains = []database.PausedDomain{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(domains)
+}
+
+// PauseDomainRequest is the optional body of PUT /api/admin/scrape/paused-domains/{domain}
+type PauseDomainRequest struct {
+	Reason string `json:"reason"`
+}
+
+func (s *Server) handlePauseScrapeDomain(w http.ResponseWriter, r *http.Request) {
+	domain, ok := normalizeScrapeDomain(chi.URLParam(r, "domain"))
+	if !ok {
+		http.Error(w, "Invalid domain (a host name, e.g. example.com)", http.StatusBadRequest)
+		return
+	}
+
+	var req PauseDomainRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<16)).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+			return
+		}
+	}
+
+	if err := s.db.PauseScrapeDomain(domain, strings.TrimSpace(req.Reason)); err != nil {
+		log.Printf("Error pausing scraping of %s: %v", domain, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleResumeScrapeDomain(w http.ResponseWriter, r *http.Request) {
+	domain, ok := normalizeScrapeDomain(chi.URLParam(r, "domain"))
+	if !ok {
+		http.Error(w, "Invalid domain (a host name, e.g. example.com)", http.StatusBadRequest)
+		return
+	}
+
+	resumed, err := s.db.ResumeScrapeDomain(domain)
+	if err != nil {
+		log.Printf("Error resuming scraping of %s: %v", domain, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if !resumed {
+		http.Error(w, "Domain not paused", http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
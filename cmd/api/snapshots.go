@@ -0,0 +1,218 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/calendar"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/database"
+)
+
+// SnapshotResponse is the API response for a stored trending snapshot
+type SnapshotResponse struct {
+	*database.TrendingSnapshot
+	Permalink string `json:"permalink"`
+}
+
+// handleTrendingAsOf returns the latest trending snapshot taken at or before
+// ?timestamp= (RFC 3339 or Unix seconds), for ?lang= when given
+func (s *Server) handleTrendingAsOf(w http.ResponseWriter, r *http.Request) {
+	timestamp := r.URL.Query().Get("timestamp")
+	if timestamp == "" {
+		http.Error(w, "Missing timestamp parameter", http.StatusBadRequest)
+		return
+	}
+	asOf, err := parseTimestamp(timestamp)
+	if err != nil {
+		http.Error(w, "Invalid timestamp parameter (RFC 3339 or Unix seconds)", http.StatusBadRequest)
+		return
+	}
+
+	snapshot, err := s.db.GetTrendingSnapshotAsOf(asOf, snapshotLang(r))
+	if err != nil {
+		log.Printf("Error getting snapshot as of %v: %v", asOf, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if snapshot == nil {
+		http.Error(w, "No snapshot at or before timestamp", http.StatusNotFound)
+		return
+	}
+
+	hideSensitiveSnapshotImages(r, snapshot)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(SnapshotResponse{
+		TrendingSnapshot: snapshot,
+		Permalink:        fmt.Sprintf("/snapshots/%d", snapshot.ID),
+	})
+}
+
+// hideSensitiveSnapshotImages replaces the preview images of sensitive
+// links with the placeholder, unless ?include_sensitive=true
+func hideSensitiveSnapshotImages(r *http.Request, snapshot *database.TrendingSnapshot) {
+	if r.URL.Query().Get("include_sensitive") == "true" {
+		return
+	}
+	for i := range snapshot.Links {
+		if snapshot.Links[i].Sensitive && snapshot.Links[i].ImageURL != "" {
+			snapshot.Links[i].ImageURL = sensitivePlaceholderImage
+		}
+	}
+}
+
+// TrendingDeltaResponse is how trending changed since a client last looked
+type TrendingDeltaResponse struct {
+	Since     string                        `json:"since"`
+	From      string                        `json:"from,omitempty"` // Snapshot compared against (empty = none that old)
+	To        string                        `json:"to"`             // Latest snapshot
+	NewCount  int                           `json:"new_count"`
+	New       []database.RankedSnapshotLink `json:"new"`      // Links that appeared since
+	MovedUp   []database.RankedSnapshotLink `json:"moved_up"` // Links ranked higher than then
+	Permalink string                        `json:"permalink"`
+}
+
+// handleTrendingDelta compares the latest trending snapshot with the one
+// current at ?since= (RFC 3339 or Unix seconds), for ?lang= when given, so
+// clients can offer "N new stories" instead of reloading the list
+func (s *Server) handleTrendingDelta(w http.ResponseWriter, r *http.Request) {
+	sinceParam := r.URL.Query().Get("since")
+	if sinceParam == "" {
+		http.Error(w, "Missing since parameter", http.StatusBadRequest)
+		return
+	}
+	since, err := parseTimestamp(sinceParam)
+	if err != nil {
+		http.Error(w, "Invalid since parameter (RFC 3339 or Unix seconds)", http.StatusBadRequest)
+		return
+	}
+
+	lang := snapshotLang(r)
+	latest, err := s.db.GetTrendingSnapshotAsOf(time.Now().UTC(), lang)
+	if err != nil {
+		log.Printf("Error getting latest snapshot: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if latest == nil {
+		http.Error(w, "No snapshots yet", http.StatusNotFound)
+		return
+	}
+
+	previous := latest
+	if since.Before(latest.TakenAt) {
+		if previous, err = s.db.GetTrendingSnapshotAsOf(since, lang); err != nil {
+			log.Printf("Error getting snapshot as of %v: %v", since, err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	hideSensitiveSnapshotImages(r, latest)
+	appeared, movedUp := database.CompareSnapshots(previous, latest)
+	response := TrendingDeltaResponse{
+		Since:     s.formatTime(since),
+		To:        s.formatTime(latest.TakenAt),
+		NewCount:  len(appeared),
+		New:       appeared,
+		MovedUp:   movedUp,
+		Permalink: fmt.Sprintf("/snapshots/%d", latest.ID),
+	}
+	if previous != nil {
+		response.From = s.formatTime(previous.TakenAt)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// handleSnapshotPage renders a stored snapshot as a stable, shareable page
+func (s *Server) handleSnapshotPage(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid snapshot ID", http.StatusBadRequest)
+		return
+	}
+
+	snapshot, err := s.db.GetTrendingSnapshotByID(id)
+	if err != nil {
+		log.Printf("Error getting snapshot %d: %v", id, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if snapshot == nil {
+		http.Error(w, "Snapshot not found", http.StatusNotFound)
+		return
+	}
+
+	s.renderSnapshot(w, r, snapshot)
+}
+
+// handleDigestPage renders the daily digest for a date (YYYY-MM-DD, in the
+// configured timezone): the last snapshot taken that day, for ?lang= when
+// given
+func (s *Server) handleDigestPage(w http.ResponseWriter, r *http.Request) {
+	date := chi.URLParam(r, "date")
+	start, end, err := calendar.ParseDay(date, s.config.Timezone)
+	if err != nil {
+		http.Error(w, "Invalid date (YYYY-MM-DD)", http.StatusBadRequest)
+		return
+	}
+
+	snapshot, err := s.db.GetTrendingSnapshotAsOf(end.Add(-time.Second), snapshotLang(r))
+	if err != nil {
+		log.Printf("Error getting digest for %s: %v", date, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if snapshot == nil || snapshot.TakenAt.Before(start) {
+		http.Error(w, "No digest for this date", http.StatusNotFound)
+		return
+	}
+
+	s.renderSnapshot(w, r, snapshot)
+}
+
+func (s *Server) renderSnapshot(w http.ResponseWriter, r *http.Request, snapshot *database.TrendingSnapshot) {
+	takenAt := snapshot.TakenAt.In(s.config.Timezone)
+	data := struct {
+		Title          string
+		Snapshot       *database.TrendingSnapshot
+		TakenAt        time.Time // In the configured timezone
+		Permalink      string
+		SensitiveImage string
+	}{
+		Title:          s.localizer(r).T("snapshot_title", takenAt.Format("Jan 2, 2006 15:04 MST")),
+		Snapshot:       snapshot,
+		TakenAt:        takenAt,
+		Permalink:      fmt.Sprintf("/snapshots/%d", snapshot.ID),
+		SensitiveImage: sensitivePlaceholderImage,
+	}
+
+	s.render(w, r, "snapshot.html", data)
+}
+
+// snapshotLang returns the ?lang= snapshots are requested for ("" = all
+// posts). Only languages in snapshot.langs have snapshots of their own.
+func snapshotLang(r *http.Request) string {
+	langs := database.NormalizeLangs([]string{r.URL.Query().Get("lang")})
+	if len(langs) == 0 {
+		return ""
+	}
+	return langs[0]
+}
+
+// parseTimestamp accepts RFC 3339 or Unix seconds, returning UTC to match
+// the UTC taken_at stored on snapshots
+func parseTimestamp(value string) (time.Time, error) {
+	if unix, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(unix, 0).UTC(), nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	return t.UTC(), err
+}
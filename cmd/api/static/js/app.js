@@ -1,3 +1,8 @@
+// Interface text in the page's language, set by the server in index.html
+function t(key) {
+  return (window.messages && window.messages[key]) || key;
+}
+
 function extractDomain(url) {
   try {
     const urlObj = new URL(url);
@@ -7,6 +12,39 @@ function extractDomain(url) {
   }
 }
 
+// Hide previews whose image fails to load. Listening here rather than with
+// onerror attributes keeps the page working under a nonce-only CSP.
+document.addEventListener(
+  "error",
+  (event) => {
+    const target = event.target;
+    if (target.tagName === "IMG" && target.parentElement.classList.contains("link-image")) {
+      target.parentElement.style.display = "none";
+    }
+  },
+  true
+);
+
+function renderImage(link, domain) {
+  if (link.image_url) {
+    return `
+                        <div class="link-image">
+                            <img src="${link.image_url}" alt="${
+      link.title || t("link_preview")
+    }">
+                        </div>
+                    `;
+  }
+  if (link.image_missing && domain) {
+    return `
+                        <div class="link-image link-image-placeholder">
+                            <span>${domain}</span>
+                        </div>
+                    `;
+  }
+  return "";
+}
+
 function renderAvatarStack(sharers, maxVisible = 5) {
   if (!sharers || sharers.length === 0) {
     return "";
@@ -26,8 +64,8 @@ function renderAvatarStack(sharers, maxVisible = 5) {
     html += `<img
             src="${avatarUrl}"
             alt="${displayName}"
-            title="${displayName} (@${sharer.handle})"
-            class="avatar"
+            title="${displayName} (@${sharer.handle})${sharer.pinned ? " · pinned" : ""}"
+            class="avatar${sharer.pinned ? " avatar-pinned" : ""}"
         />`;
   });
 
@@ -46,74 +84,86 @@ function loadTrending() {
   const limit = document.getElementById("limit").value;
   const container = document.getElementById("links");
 
-  container.innerHTML = '<div class="loading">Loading trending links...</div>';
+  container.innerHTML = `<div class="loading">${t("loading_links")}</div>`;
 
-  fetch(`/api/trending?hours=${hours}&limit=${limit}&degree=2`)
+  const params = `hours=${hours}&limit=${limit}&degree=2`;
+  fetch(`/api/trending?${params}`)
     .then((res) => {
-      if (!res.ok) throw new Error("Failed to fetch trending links");
+      if (!res.ok) throw new Error(t("links_failed"));
       return res.json();
     })
     .then((data) => {
-      if (!data.links || data.links.length === 0) {
-        container.innerHTML =
-          '<div class="loading">No trending links found. The poller may still be collecting data.</div>';
-        return;
-      }
+      renderLinks(data, container);
+      streamTrending(params, container);
+    })
+    .catch((err) => {
+      container.innerHTML = `<div class="error">${t("error")} ${err.message}</div>`;
+    });
+}
+
+// Live updates: re-render whenever the server streams a new ranking.
+// The stream is optional; if the server has it disabled the EventSource
+// simply closes.
+let trendingStream = null;
 
-      container.innerHTML = "";
-      data.links.forEach((link) => {
-        const card = document.createElement("div");
-        card.className = "link-card";
+function streamTrending(params, container) {
+  if (trendingStream) trendingStream.close();
+  if (!window.EventSource) return;
 
-        const domain = extractDomain(link.url);
+  trendingStream = new EventSource(`/api/trending/stream?${params}`);
+  trendingStream.addEventListener("trending", (e) => {
+    // Don't pull the page out from under a reader browsing posts
+    if (container.querySelector(".posts-container.expanded")) return;
+    renderLinks(JSON.parse(e.data), container);
+  });
+}
+
+function renderLinks(data, container) {
+  if (!data.links || data.links.length === 0) {
+    container.innerHTML = `<div class="loading">${t("no_links")}</div>`;
+    return;
+  }
 
-        card.innerHTML = `
+  container.innerHTML = "";
+  data.links.forEach((link) => {
+    const card = document.createElement("div");
+    card.className = "link-card";
+
+    const domain = extractDomain(link.url);
+
+    card.innerHTML = `
+                ${renderImage(link, domain)}
+                <div class="link-content">
+                    <h3><a href="${
+                      link.url
+                    }" target="_blank" rel="noopener noreferrer">${
+      link.title || link.url
+    }</a></h3>
                     ${
-                      link.image_url
-                        ? `
-                        <div class="link-image">
-                            <img src="${link.image_url}" alt="${
-                            link.title || "Link preview"
-                          }" onerror="this.parentElement.style.display='none'">
-                        </div>
-                    `
+                      domain
+                        ? `<div class="link-domain">${domain}</div>`
                         : ""
                     }
-                    <div class="link-content">
-                        <h3><a href="${
-                          link.url
-                        }" target="_blank" rel="noopener noreferrer">${
-          link.title || link.url
-        }</a></h3>
-                        ${
-                          domain
-                            ? `<div class="link-domain">${domain}</div>`
-                            : ""
-                        }
-                        ${
-                          link.description
-                            ? `<p class="link-description">${link.description}</p>`
-                            : ""
-                        }
-                        <div class="link-meta">
-                            <span class="share-count">★ ${
-                              link.share_count
-                            } share${link.share_count !== 1 ? "s" : ""}</span>
-                        </div>
-                        ${renderAvatarStack(link.sharer_avatars)}
-                        <button class="posts-toggle" data-link-id="${
-                          link.id
-                        }">Show Posts ▼</button>
-                        <div class="posts-container" id="posts-${link.id}"></div>
+                    ${
+                      link.description
+                        ? `<p class="link-description">${link.description}</p>`
+                        : ""
+                    }
+                    <div class="link-meta">
+                        <span class="share-count">★ ${
+                          link.share_count
+                        } ${link.share_count !== 1 ? t("shares") : t("share")}</span>
                     </div>
-                `;
+                    ${renderAvatarStack(link.sharer_avatars)}
+                    <button class="posts-toggle" data-link-id="${
+                      link.id
+                    }">${t("show_posts")}</button>
+                    <div class="posts-container" id="posts-${link.id}"></div>
+                </div>
+            `;
 
-        container.appendChild(card);
-      });
-    })
-    .catch((err) => {
-      container.innerHTML = `<div class="error">Error: ${err.message}</div>`;
-    });
+    container.appendChild(card);
+  });
 }
 
 function togglePosts(button, linkId) {
@@ -121,10 +171,10 @@ function togglePosts(button, linkId) {
 
   if (container.classList.contains("expanded")) {
     container.classList.remove("expanded");
-    button.textContent = "Show Posts ▼";
+    button.textContent = t("show_posts");
   } else {
     container.classList.add("expanded");
-    button.textContent = "Hide Posts ▲";
+    button.textContent = t("hide_posts");
 
     // Load posts if not already loaded
     if (!container.dataset.loaded) {
@@ -134,11 +184,11 @@ function togglePosts(button, linkId) {
 }
 
 function loadPosts(linkId, container) {
-  container.innerHTML = '<div class="loading">Loading posts...</div>';
+  container.innerHTML = `<div class="loading">${t("loading_posts")}</div>`;
 
   fetch(`/api/links/${linkId}/posts`)
     .then((res) => {
-      if (!res.ok) throw new Error("Failed to fetch posts");
+      if (!res.ok) throw new Error(t("posts_failed"));
       return res.json();
     })
     .then((data) => {
@@ -146,13 +196,13 @@ function loadPosts(linkId, container) {
       renderPosts(data.posts, container);
     })
     .catch((err) => {
-      container.innerHTML = `<div class="error">Error loading posts: ${err.message}</div>`;
+      container.innerHTML = `<div class="error">${t("posts_error")} ${err.message}</div>`;
     });
 }
 
 function renderPosts(posts, container) {
   if (!posts || posts.length === 0) {
-    container.innerHTML = '<div class="loading">No posts found for this link.</div>';
+    container.innerHTML = `<div class="loading">${t("no_posts")}</div>`;
     return;
   }
 
@@ -160,16 +210,15 @@ function renderPosts(posts, container) {
   posts.forEach((post) => {
     const displayName = post.display_name || post.handle;
     const avatarUrl = post.avatar_url || "/static/img/default-avatar.svg";
-    const postDate = new Date(post.created_at).toLocaleDateString("en-US", {
+    const postDate = new Date(post.created_at).toLocaleDateString(document.documentElement.lang, {
       month: "short",
       day: "numeric",
       year: "numeric",
     });
 
-    // Extract rkey from post ID (format: at://did/app.bsky.feed.post/rkey)
-    const rkey = post.id.split('/').pop();
-    const postUrl = `https://bsky.app/profile/${post.handle}/post/${rkey}`;
-    const profileUrl = `https://bsky.app/profile/${post.handle}`;
+    // bsky.app URLs resolved by the API (url is absent for non-post URIs)
+    const postUrl = post.url || post.profile_url;
+    const profileUrl = post.profile_url;
 
     html += `
       <div class="post-item">
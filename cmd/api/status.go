@@ -0,0 +1,185 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/aggregator"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/database"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/features"
+)
+
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+}
+
+// Bootstrap states reported by /api/status
+const (
+	StatusEmpty     = "empty"     // No follows loaded yet
+	StatusIngesting = "ingesting" // Ingesting, but nothing is trending yet
+	StatusReady     = "ready"     // Trending has links
+)
+
+// statusCacheTTL bounds how often /api/status counts the tables (and
+// /api/capabilities checks freshness)
+const statusCacheTTL = 30 * time.Second
+
+// StatusResponse reports ingestion progress, so a frontend on a fresh
+// deployment can explain an empty trending list
+type StatusResponse struct {
+	State string `json:"state"` // See Status*
+	*database.IngestStatus
+	TrendingAvailable bool `json:"trending_available"` // The default trending query returns links
+}
+
+// handleStatus reports whether the deployment is still bootstrapping
+func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	const cacheKey = "status"
+	if cached, ok, err := s.cache.Store.Get(r.Context(), cacheKey); err != nil {
+		log.Printf("Error reading status cache: %v", err)
+	} else if ok {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(cached)
+		return
+	}
+
+	ingest, err := s.db.GetIngestStatus()
+	if err != nil {
+		log.Printf("Error getting ingest status: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	query := aggregator.NewTrendingQuery(s.config.Trending)
+	query.Limit = 1
+	trending, err := s.aggregator.Trending(query)
+	if err != nil {
+		log.Printf("Error checking trending for status: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	response := StatusResponse{IngestStatus: ingest, TrendingAvailable: len(trending) > 0}
+	switch {
+	case response.TrendingAvailable:
+		response.State = StatusReady
+	case ingest.Follows == 0:
+		response.State = StatusEmpty
+	default:
+		response.State = StatusIngesting
+	}
+
+	body, err := json.Marshal(response)
+	if err != nil {
+		log.Printf("Error encoding status response: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if err := s.cache.Store.Set(r.Context(), cacheKey, body, statusCacheTTL); err != nil {
+		log.Printf("Error writing status cache: %v", err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+}
+
+// CapabilitiesResponse describes what this deployment serves, so a frontend
+// can hide features that are turned off. Features include every flag in
+// internal/features.
+type CapabilitiesResponse struct {
+	Features  map[string]bool     `json:"features"`
+	Enrichers []string            `json:"enrichers"` // Enrichment stages links go through, in order
+	Locales   []string            `json:"locales"`   // Languages of pages and API text (see ?locale=)
+	Limits    CapabilityLimits    `json:"limits"`
+	Freshness *database.Freshness `json:"freshness"`
+}
+
+// CapabilityLimits are the bounds and defaults of trending queries
+type CapabilityLimits struct {
+	MaxHours       int `json:"max_hours"`
+	MaxLimit       int `json:"max_limit"`
+	DefaultHours   int `json:"default_hours"`
+	DefaultLimit   int `json:"default_limit"`
+	MinShares      int `json:"min_shares"`
+	RetentionHours int `json:"retention_hours"` // Posts older than this are gone; longer windows rely on rollups
+	RateLimitRPM   int `json:"rate_limit_rpm"`
+}
+
+// handleCapabilities reports enabled features, query limits and how fresh
+// the data is
+func (s *Server) handleCapabilities(w http.ResponseWriter, r *http.Request) {
+	const cacheKey = "capabilities"
+	if cached, ok, err := s.cache.Store.Get(r.Context(), cacheKey); err != nil {
+		log.Printf("Error reading capabilities cache: %v", err)
+	} else if ok {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(cached)
+		return
+	}
+
+	freshness, err := s.db.GetFreshness()
+	if err != nil {
+		log.Printf("Error getting data freshness: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	cfg := s.config
+	response := CapabilitiesResponse{
+		Features: map[string]bool{
+			"degree_filter":    true,
+			"cohorts":          true,
+			"events":           true,
+			"click_tracking":   cfg.Server.ClickTracking,
+			"click_ranking":    cfg.Trending.Ranking == aggregator.RankingClicks,
+			"admin":            cfg.Server.AdminToken != "" && !cfg.Server.PublicMode,
+			"public_mode":      cfg.Server.PublicMode,
+			"api_key_signup":   cfg.Server.APIKeySignup,
+			"api_key_required": cfg.Server.RequireAPIKey,
+			"shared_cache":     cfg.Redis.URL != "",
+			"live_updates":     s.stream != nil,
+			"pagination":       true,
+			"sharer_types":     true,
+			"graphql":          true,
+			"topics":           true,
+			"communities":      cfg.Communities.IntervalHours > 0,
+			"domains":          true,
+			"trending_delta":   features.Enabled(features.Digests),
+			"feed_generator":   s.feedgen != nil,
+		},
+		Enrichers: cfg.Scrape.Enrichers,
+		Locales:   s.i18n.Locales(),
+		Limits: CapabilityLimits{
+			MaxHours:       aggregator.MaxTrendingHours,
+			MaxLimit:       aggregator.MaxTrendingLimit,
+			DefaultHours:   aggregator.DefaultTrendingHours,
+			DefaultLimit:   aggregator.DefaultTrendingLimit,
+			MinShares:      cfg.Trending.MinShares,
+			RetentionHours: cfg.Cleanup.RetentionHours,
+			RateLimitRPM:   cfg.Server.RateLimitRPM,
+		},
+		Freshness: freshness,
+	}
+	for _, feature := range features.All() {
+		response.Features[feature.Name] = feature.Enabled
+	}
+	if response.Enrichers == nil {
+		response.Enrichers = []string{}
+	}
+
+	body, err := json.Marshal(response)
+	if err != nil {
+		log.Printf("Error encoding capabilities response: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if err := s.cache.Store.Set(r.Context(), cacheKey, body, statusCacheTTL); err != nil {
+		log.Printf("Error writing capabilities cache: %v", err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/aggregator"
+)
+
+// streamHeartbeat is how often an idle trending stream sends a comment to
+// keep proxies from closing the connection
+const streamHeartbeat = 30 * time.Second
+
+// trendingHub wakes subscribed trending streams when links are shared
+type trendingHub struct {
+	mu          sync.Mutex
+	subscribers map[chan struct{}]struct{}
+}
+
+func newTrendingHub() *trendingHub {
+	return &trendingHub{subscribers: make(map[chan struct{}]struct{})}
+}
+
+// run wakes subscribers for each share signal, at most once per interval.
+// Shares arriving during the wait coalesce into a single signal.
+func (h *trendingHub) run(shared <-chan struct{}, interval time.Duration) {
+	for range shared {
+		h.mu.Lock()
+		for ch := range h.subscribers {
+			select {
+			case ch <- struct{}{}:
+			default: // Already pending
+			}
+		}
+		h.mu.Unlock()
+		time.Sleep(interval)
+	}
+}
+
+// subscribe returns a channel woken after shares and a function that
+// unsubscribes
+func (h *trendingHub) subscribe() (<-chan struct{}, func()) {
+	ch := make(chan struct{}, 1)
+	h.mu.Lock()
+	h.subscribers[ch] = struct{}{}
+	h.mu.Unlock()
+	return ch, func() {
+		h.mu.Lock()
+		delete(h.subscribers, ch)
+		h.mu.Unlock()
+	}
+}
+
+// handleTrendingStream streams /api/trending as Server-Sent Events: a
+// "trending" event with the full response on connect, then again whenever
+// new shares change the ranking or counts. Parameters filter as in
+// /api/trending.
+func (s *Server) handleTrendingStream(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	query := aggregator.NewTrendingQuery(s.config.Trending)
+	query.Location = s.config.Timezone
+	if err := query.Parse(r.URL.Query()); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !s.resolveCohort(w, &query) {
+		return
+	}
+	fields, err := parseLinkFields(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	loc := s.localizer(r)
+	wake, unsubscribe := s.stream.subscribe()
+	defer unsubscribe()
+
+	setLanguageHeaders(w, loc)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no") // Disable nginx response buffering
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+
+	var last []byte
+	for {
+		response, err := s.trendingResponse(query, fields, loc)
+		if err != nil {
+			log.Printf("Error getting trending links for stream: %v", err)
+		} else if body, err := json.Marshal(response); err != nil {
+			log.Printf("Error encoding trending stream event: %v", err)
+		} else if !bytes.Equal(body, last) {
+			if _, err := fmt.Fprintf(w, "event: trending\ndata: %s\n\n", body); err != nil {
+				return
+			}
+			flusher.Flush()
+			last = body
+		}
+
+		// Wait for new shares, pinging while idle
+	wait:
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case <-wake:
+				break wait
+			case <-heartbeat.C:
+				if _, err := io.WriteString(w, ": ping\n\n"); err != nil {
+					return
+				}
+				flusher.Flush()
+			}
+		}
+	}
+}
@@ -0,0 +1,340 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/aggregator"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/database"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/feed"
+)
+
+// TopicRequest is the body of PUT /api/topics/{slug}
+type TopicRequest struct {
+	Title       string   `json:"title"`
+	Description *string  `json:"description"`
+	Keywords    []string `json:"keywords"`     // Posts count when their text or link title contains one (case-insensitive)
+	Cohort      string   `json:"cohort"`       // Cohort whose shares are boosted ("" = none)
+	BoostWeight *float64 `json:"boost_weight"` // Weight of a cohort member's share (default 2)
+	StartsAt    string   `json:"starts_at"`    // RFC 3339 or Unix seconds
+	EndsAt      string   `json:"ends_at"`
+}
+
+// TopicResponse is a topic with its trending links: ranked live from its
+// start until the firehose archives its final list, then the archive
+type TopicResponse struct {
+	*database.Topic
+	Status    string `json:"status"`    // One of the database.Topic* states
+	Permalink string `json:"permalink"` // Topic page
+	*TrendingResponse
+	ArchivedLinks []database.SnapshotLink `json:"archived_links,omitempty"` // Final list once archived
+}
+
+// defaultTopicBoost is the weight of a boosted cohort member's share when
+// a topic doesn't set one
+const defaultTopicBoost = 2.0
+
+// topicSlugPattern is the form of topic slugs, which appear in page URLs
+var topicSlugPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+func (s *Server) handleListTopics(w http.ResponseWriter, r *http.Request) {
+	topics, err := s.db.GetTopics()
+	if err != nil {
+		log.Printf("Error listing topics: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	now := time.Now()
+	response := make([]TopicResponse, len(topics))
+	for i := range topics {
+		response[i] = TopicResponse{
+			Topic:     &topics[i],
+			Status:    topics[i].Status(now),
+			Permalink: "/topics/" + topics[i].Slug,
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"topics": response})
+}
+
+// handleGetTopic returns a topic and its links. Parameters filter as in
+// /api/trending, except hours and window: the window is the topic's run.
+func (s *Server) handleGetTopic(w http.ResponseWriter, r *http.Request) {
+	topic, ok := s.lookupTopic(w, r)
+	if !ok {
+		return
+	}
+	query, ok := s.topicQuery(w, r.URL.Query(), topic)
+	if !ok {
+		return
+	}
+
+	loc := s.localizer(r)
+	setLanguageHeaders(w, loc)
+	response := TopicResponse{
+		Topic:     topic,
+		Status:    topic.Status(time.Now()),
+		Permalink: "/topics/" + topic.Slug,
+	}
+	switch response.Status {
+	case database.TopicScheduled:
+		response.TrendingResponse = &TrendingResponse{Links: []LinkResponse{}}
+	case database.TopicArchived:
+		links, err := s.db.GetArchivedTopicLinks(topic.ID)
+		if err != nil {
+			log.Printf("Error getting archive of topic %s: %v", topic.Slug, err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if !query.IncludeSensitive {
+			for i := range links {
+				if links[i].Sensitive && links[i].ImageURL != "" {
+					links[i].ImageURL = sensitivePlaceholderImage
+				}
+			}
+		}
+		response.ArchivedLinks = links
+	default:
+		trending, err := s.trendingResponse(query, nil, loc)
+		if err != nil {
+			log.Printf("Error getting trending links for topic %s: %v", topic.Slug, err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		response.TrendingResponse = trending
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// handleSaveTopic creates a topic or replaces its settings
+func (s *Server) handleSaveTopic(w http.ResponseWriter, r *http.Request) {
+	slug := chi.URLParam(r, "slug")
+	if !topicSlugPattern.MatchString(slug) || len(slug) > 64 {
+		http.Error(w, "Invalid topic slug (lowercase letters, digits and dashes)", http.StatusBadRequest)
+		return
+	}
+
+	var req TopicRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+		return
+	}
+
+	topic := database.Topic{
+		Slug:        slug,
+		Title:       strings.TrimSpace(req.Title),
+		Description: req.Description,
+		BoostWeight: defaultTopicBoost,
+	}
+	if topic.Title == "" {
+		http.Error(w, "Missing title", http.StatusBadRequest)
+		return
+	}
+	for _, keyword := range req.Keywords {
+		if keyword = strings.TrimSpace(keyword); keyword != "" {
+			topic.Keywords = append(topic.Keywords, keyword)
+		}
+	}
+	if len(topic.Keywords) == 0 {
+		http.Error(w, "Missing keywords", http.StatusBadRequest)
+		return
+	}
+
+	var err error
+	if topic.StartsAt, err = parseTimestamp(req.StartsAt); err != nil {
+		http.Error(w, "Invalid starts_at (RFC 3339 or Unix seconds)", http.StatusBadRequest)
+		return
+	}
+	if topic.EndsAt, err = parseTimestamp(req.EndsAt); err != nil {
+		http.Error(w, "Invalid ends_at (RFC 3339 or Unix seconds)", http.StatusBadRequest)
+		return
+	}
+	if !topic.EndsAt.After(topic.StartsAt) {
+		http.Error(w, "ends_at must be after starts_at", http.StatusBadRequest)
+		return
+	}
+
+	if req.Cohort != "" {
+		cohort, err := s.db.GetCohortByName(req.Cohort)
+		if err != nil {
+			log.Printf("Error getting cohort %s: %v", req.Cohort, err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if cohort == nil {
+			http.Error(w, "Unknown cohort", http.StatusBadRequest)
+			return
+		}
+		topic.CohortID = &cohort.ID
+	}
+	if req.BoostWeight != nil {
+		if *req.BoostWeight < 1 {
+			http.Error(w, "Invalid boost_weight (1 or more)", http.StatusBadRequest)
+			return
+		}
+		topic.BoostWeight = *req.BoostWeight
+	}
+
+	if err := s.db.SaveTopic(topic); err != nil {
+		log.Printf("Error saving topic %s: %v", slug, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	s.handleGetTopic(w, r)
+}
+
+func (s *Server) handleDeleteTopic(w http.ResponseWriter, r *http.Request) {
+	slug := chi.URLParam(r, "slug")
+	deleted, err := s.db.DeleteTopic(slug)
+	if err != nil {
+		log.Printf("Error deleting topic %s: %v", slug, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if !deleted {
+		http.Error(w, "Topic not found", http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleTopicPage renders a topic's links: live while it runs, the
+// archived final list after it ends
+func (s *Server) handleTopicPage(w http.ResponseWriter, r *http.Request) {
+	topic, ok := s.lookupTopic(w, r)
+	if !ok {
+		return
+	}
+	query, ok := s.topicQuery(w, r.URL.Query(), topic)
+	if !ok {
+		return
+	}
+
+	links, err := s.topicLinks(topic, query)
+	if err != nil {
+		log.Printf("Error getting links for topic %s: %v", topic.Slug, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	data := struct {
+		Title          string
+		Topic          *database.Topic
+		Status         string
+		StartsAt       time.Time // In the configured timezone
+		EndsAt         time.Time
+		Links          []database.SnapshotLink
+		Permalink      string
+		FeedURL        string
+		SensitiveImage string
+	}{
+		Title:          topic.Title,
+		Topic:          topic,
+		Status:         topic.Status(time.Now()),
+		StartsAt:       topic.StartsAt.In(s.config.Timezone),
+		EndsAt:         topic.EndsAt.In(s.config.Timezone),
+		Links:          links,
+		Permalink:      "/topics/" + topic.Slug,
+		FeedURL:        "/feeds/topics/" + topic.Slug + ".xml",
+		SensitiveImage: sensitivePlaceholderImage,
+	}
+	s.render(w, r, "topic.html", data)
+}
+
+// handleTopicFeed serves a topic's links as an RSS 2.0 feed, or Atom with
+// ?format=atom. Other parameters filter as in /api/topics/{slug}.
+func (s *Server) handleTopicFeed(w http.ResponseWriter, r *http.Request) {
+	values := r.URL.Query()
+	format, ok := feedFormat(w, values)
+	if !ok {
+		return
+	}
+	topic, ok := s.lookupTopic(w, r)
+	if !ok {
+		return
+	}
+	query, ok := s.topicQuery(w, values, topic)
+	if !ok {
+		return
+	}
+
+	links, err := s.topicLinks(topic, query)
+	if err != nil {
+		log.Printf("Error getting links for topic %s feed: %v", topic.Slug, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	loc := s.localizer(r)
+	setLanguageHeaders(w, loc)
+	s.writeLinksFeed(w, r, format, feed.Feed{
+		Title:       topic.Title,
+		Description: loc.T("topic_feed_description", topic.Title),
+		Link:        s.absoluteURL(r, "/topics/"+topic.Slug),
+	}, links, query.IncludeSensitive)
+}
+
+// lookupTopic gets the topic named by the {slug} URL parameter, writing the
+// error response and returning false if it can't
+func (s *Server) lookupTopic(w http.ResponseWriter, r *http.Request) (*database.Topic, bool) {
+	slug := chi.URLParam(r, "slug")
+	topic, err := s.db.GetTopicBySlug(slug)
+	if err != nil {
+		log.Printf("Error getting topic %s: %v", slug, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return nil, false
+	}
+	if topic == nil {
+		http.Error(w, "Topic not found", http.StatusNotFound)
+		return nil, false
+	}
+	return topic, true
+}
+
+// topicQuery parses trending parameters (as in /api/trending, except hours
+// and window) into a query restricted to topic, writing the error response
+// and returning false if they're invalid
+func (s *Server) topicQuery(w http.ResponseWriter, values url.Values, topic *database.Topic) (aggregator.TrendingQuery, bool) {
+	query := aggregator.NewTrendingQuery(s.config.Trending)
+	query.Location = s.config.Timezone
+	if values.Get("hours") != "" {
+		http.Error(w, (&aggregator.QueryError{Param: "hours", Hint: "not available for topics"}).Error(), http.StatusBadRequest)
+		return query, false
+	}
+	err := query.Parse(values)
+	if err == nil {
+		err = query.ForTopic(topic, time.Now())
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return query, false
+	}
+	return query, s.resolveCohort(w, &query)
+}
+
+// topicLinks returns a topic's links for pages and feeds: none before it
+// starts, ranked live until it is archived, then the archived list
+func (s *Server) topicLinks(topic *database.Topic, query aggregator.TrendingQuery) ([]database.SnapshotLink, error) {
+	switch topic.Status(time.Now()) {
+	case database.TopicScheduled:
+		return nil, nil
+	case database.TopicArchived:
+		return s.db.GetArchivedTopicLinks(topic.ID)
+	}
+	links, err := s.aggregator.Trending(query)
+	if err != nil {
+		return nil, err
+	}
+	return database.SnapshotLinks(links), nil
+}
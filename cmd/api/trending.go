@@ -0,0 +1,342 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/aggregator"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/database"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/i18n"
+)
+
+// TrendingResponse is the API response for trending links
+type TrendingResponse struct {
+	Links      []LinkResponse `json:"links"`
+	Cursor     string         `json:"cursor,omitempty"`      // Position this page starts after
+	NextCursor string         `json:"next_cursor,omitempty"` // Pass as ?cursor= for the next page (empty = last page)
+}
+
+// LinkResponse is a single link in the API response
+type LinkResponse struct {
+	ID             int                      `json:"id"`
+	URL            string                   `json:"url"`
+	Title          string                   `json:"title"`
+	Description    string                   `json:"description"`
+	ImageURL       string                   `json:"image_url"`
+	ShareCount     int                      `json:"share_count"`
+	LastSharedAt   string                   `json:"last_shared_at"`            // RFC 3339 in the configured timezone
+	LastSharedAgo  string                   `json:"last_shared_ago"`           // Localized relative time
+	FirstSharedAt  string                   `json:"first_shared_at,omitempty"` // First share ever (empty = unknown)
+	FirstSharedAgo string                   `json:"first_shared_ago,omitempty"`
+	Sharers        []string                 `json:"sharers"`
+	SharerAvatars  []database.SharerAvatar  `json:"sharer_avatars"`
+	Flagged        bool                     `json:"flagged,omitempty"`        // Predominantly shared by labeled posts/accounts
+	CopiedShares   int                      `json:"copied_shares,omitempty"`  // Shares repeating another account's text
+	Coordinated    bool                     `json:"coordinated,omitempty"`    // Enough copied shares to suggest a campaign
+	Sensitive      bool                     `json:"sensitive,omitempty"`      // Preview may contain adult/graphic content
+	PreviousTitle  string                   `json:"previous_title,omitempty"` // Set when the headline has changed
+	ClickURL       string                   `json:"click_url,omitempty"`      // Counting redirect, when click tracking is on
+	Dead           bool                     `json:"dead,omitempty"`           // A dead-link check found the page gone
+	ImageMissing   bool                     `json:"image_missing,omitempty"`  // No usable preview image: show a placeholder for the domain
+	Communities    []database.LinkCommunity `json:"communities,omitempty"`    // Communities of the network sharing it, most sharers first
+
+	fields linkFields // Fields to encode (nil = all)
+}
+
+// linkFields is a selection of LinkResponse fields by JSON name (nil = all)
+type linkFields map[string]bool
+
+// has reports whether a field is selected
+func (f linkFields) has(name string) bool {
+	return f == nil || f[name]
+}
+
+// compactLinkFields is ?view=compact: everything but the description,
+// sharers and previous headline, which list views rarely show
+var compactLinkFields = linkFields{}
+
+// linkResponseFields are LinkResponse's encoded fields, in order
+var linkResponseFields []linkResponseField
+
+type linkResponseField struct {
+	name      string
+	index     int
+	omitEmpty bool
+}
+
+func init() {
+	t := reflect.TypeOf(LinkResponse{})
+	for i := 0; i < t.NumField(); i++ {
+		tag := t.Field(i).Tag.Get("json")
+		if tag == "" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		linkResponseFields = append(linkResponseFields, linkResponseField{name, i, opts == "omitempty"})
+		switch name {
+		case "description", "sharers", "sharer_avatars", "previous_title":
+		default:
+			compactLinkFields[name] = true
+		}
+	}
+}
+
+// parseLinkFields reads the link fields a trending request selects with
+// ?fields= (comma-separated names) or ?view= (full or compact)
+func parseLinkFields(values url.Values) (linkFields, error) {
+	view := values.Get("view")
+	list := values.Get("fields")
+	if list == "" {
+		switch view {
+		case "", "full":
+			return nil, nil
+		case "compact":
+			return compactLinkFields, nil
+		default:
+			return nil, &aggregator.QueryError{Param: "view", Hint: "full, compact"}
+		}
+	}
+	if view != "" {
+		return nil, &aggregator.QueryError{Param: "fields", Hint: "not available with view"}
+	}
+
+	fields := linkFields{}
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		known := false
+		for _, f := range linkResponseFields {
+			known = known || f.name == name
+		}
+		if !known {
+			return nil, &aggregator.QueryError{Param: "fields", Hint: "comma-separated link fields, e.g. id,url,title,share_count"}
+		}
+		fields[name] = true
+	}
+	return fields, nil
+}
+
+// MarshalJSON encodes the selected fields, in declaration order
+func (l LinkResponse) MarshalJSON() ([]byte, error) {
+	type plain LinkResponse // Without this method
+	if l.fields == nil {
+		return json.Marshal(plain(l))
+	}
+
+	v := reflect.ValueOf(l)
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for _, f := range linkResponseFields {
+		value := v.Field(f.index)
+		if !l.fields[f.name] || f.omitEmpty && value.IsZero() {
+			continue
+		}
+		data, err := json.Marshal(value.Interface())
+		if err != nil {
+			return nil, err
+		}
+		if buf.Len() > 1 {
+			buf.WriteByte(',')
+		}
+		fmt.Fprintf(&buf, "%q:", f.name)
+		buf.Write(data)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// sensitivePlaceholderImage replaces preview images of sensitive links
+const sensitivePlaceholderImage = "/static/img/sensitive-placeholder.svg"
+
+func (s *Server) handleTrending(w http.ResponseWriter, r *http.Request) {
+	// Serve from the shared cache (keyed by the canonical query string)
+	cacheTTL := time.Duration(s.config.Server.TrendingCacheSeconds) * time.Second
+	loc := s.localizer(r)
+	setLanguageHeaders(w, loc)
+	cacheKey := "trending:" + loc.Locale() + ":" + r.URL.Query().Encode()
+	if cacheTTL > 0 {
+		if cached, ok, err := s.cache.Store.Get(r.Context(), cacheKey); err != nil {
+			log.Printf("Error reading trending cache: %v", err)
+		} else if ok {
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("X-Cache", "HIT")
+			w.Write(cached)
+			return
+		}
+	}
+
+	// Parse and validate filters (defaults from config)
+	query := aggregator.NewTrendingQuery(s.config.Trending)
+	query.Location = s.config.Timezone
+	if err := query.Parse(r.URL.Query()); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Restrict to posts by members of a named cohort
+	if !s.resolveCohort(w, &query) {
+		return
+	}
+
+	// Leave out fields list views don't need, and the queries behind them
+	fields, err := parseLinkFields(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	response, err := s.trendingResponse(query, fields, loc)
+	if err != nil {
+		log.Printf("Error getting trending links: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	body, err := json.Marshal(response)
+	if err != nil {
+		log.Printf("Error encoding trending response: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if cacheTTL > 0 {
+		if err := s.cache.Store.Set(r.Context(), cacheKey, body, cacheTTL); err != nil {
+			log.Printf("Error writing trending cache: %v", err)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Cache", "MISS")
+	w.Write(body)
+}
+
+// trendingResponse runs a parsed trending query and converts the links to
+// the API response, with sharer avatars and previous headlines and relative
+// times in loc's language. Only the selected fields are looked up and
+// encoded.
+func (s *Server) trendingResponse(query aggregator.TrendingQuery, fields linkFields, loc *i18n.Localizer) (*TrendingResponse, error) {
+	query.Options.OmitSharers = !fields.has("sharers")
+	links, err := s.aggregator.Trending(query)
+	if err != nil {
+		return nil, err
+	}
+
+	linkIDs := make([]int, len(links))
+	for i, link := range links {
+		linkIDs[i] = link.ID
+	}
+
+	// Headlines changed by metadata refreshes
+	previousTitles := map[int]string{}
+	if fields.has("previous_title") {
+		if previousTitles, err = s.db.GetPreviousTitles(linkIDs); err != nil {
+			log.Printf("Error getting previous titles: %v", err)
+			previousTitles = map[int]string{} // Omit on error
+		}
+	}
+
+	// Which communities of the network are sharing each link
+	communities := map[int][]database.LinkCommunity{}
+	if fields.has("communities") {
+		if communities, err = s.db.GetLinkCommunities(linkIDs, s.config.Communities.MinSharers); err != nil {
+			log.Printf("Error getting link communities: %v", err)
+			communities = map[int][]database.LinkCommunity{} // Omit on error
+		}
+	}
+
+	// Convert to response format
+	response := &TrendingResponse{
+		Links:      make([]LinkResponse, len(links)),
+		NextCursor: query.NextCursor(links),
+	}
+	if query.Options.After != nil {
+		response.Cursor = query.Options.After.Encode()
+	}
+
+	now := time.Now()
+	for i, link := range links {
+		// Fetch sharer avatars for this link
+		var sharers []database.SharerAvatar
+		if fields.has("sharer_avatars") {
+			if sharers, err = s.db.GetLinkSharers(link.ID); err != nil {
+				log.Printf("Error getting sharers for link %d: %v", link.ID, err)
+				sharers = []database.SharerAvatar{} // Empty on error
+			}
+		}
+
+		imageURL := stringOrEmpty(link.OGImageURL)
+		if link.Sensitive && !query.IncludeSensitive && imageURL != "" {
+			imageURL = sensitivePlaceholderImage
+		}
+
+		response.Links[i] = LinkResponse{
+			ID:            link.ID,
+			URL:           link.NormalizedURL,
+			Title:         stringOrEmpty(link.Title),
+			Description:   stringOrEmpty(link.Description),
+			ImageURL:      imageURL,
+			ShareCount:    link.ShareCount,
+			LastSharedAt:  s.formatTime(link.LastSharedAt),
+			LastSharedAgo: loc.Ago(link.LastSharedAt.In(s.config.Timezone), now),
+			Sharers:       []string(link.Sharers),
+			SharerAvatars: sharers,
+			Flagged:       query.Flagged(link),
+			CopiedShares:  link.CopiedShares,
+			Coordinated:   query.Coordinated(link),
+			Sensitive:     link.Sensitive,
+			PreviousTitle: previousTitles[link.ID],
+			Communities:   topCommunities(communities[link.ID]),
+			ClickURL:      s.clickURL(link.ID),
+			Dead:          link.DeadAt != nil,
+			ImageMissing:  link.ImageMissing && imageURL == "",
+			fields:        fields,
+		}
+		if link.FirstSharedAt != nil {
+			response.Links[i].FirstSharedAt = s.formatTime(*link.FirstSharedAt)
+			response.Links[i].FirstSharedAgo = loc.Ago(link.FirstSharedAt.In(s.config.Timezone), now)
+		}
+	}
+	return response, nil
+}
+
+// linkCommunitiesShown is how many communities a link lists
+const linkCommunitiesShown = 3
+
+// topCommunities trims a link's communities to those it lists
+func topCommunities(communities []database.LinkCommunity) []database.LinkCommunity {
+	if len(communities) > linkCommunitiesShown {
+		return communities[:linkCommunitiesShown]
+	}
+	return communities
+}
+
+// formatTime formats a timestamp for API responses: RFC 3339 in the
+// configured timezone, so clients see both the instant and the local time
+func (s *Server) formatTime(t time.Time) string {
+	return t.In(s.config.Timezone).Format(time.RFC3339)
+}
+
+// resolveCohort looks up a query's named cohort into Options.CohortID,
+// writing the error response and returning false if it can't
+func (s *Server) resolveCohort(w http.ResponseWriter, query *aggregator.TrendingQuery) bool {
+	if query.Cohort == "" {
+		return true
+	}
+	cohort, err := s.db.GetCohortByName(query.Cohort)
+	if err != nil {
+		log.Printf("Error getting cohort %s: %v", query.Cohort, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return false
+	}
+	if cohort == nil {
+		http.Error(w, "Unknown cohort", http.StatusNotFound)
+		return false
+	}
+	query.Options.CohortID = cohort.ID
+	return true
+}
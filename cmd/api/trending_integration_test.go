@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bluesky-social/jetstream/pkg/models"
+	"github.com/go-chi/chi/v5"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/aggregator"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/cache"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/config"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/database"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/i18n"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/jetstream"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/processor"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/testutil"
+)
+
+// firstDegree places every author in the 1st degree
+type firstDegree struct{}
+
+func (firstDegree) GetDegree(did string) int { return 1 }
+
+// sharePost builds a Jetstream event for a post sharing url as an external
+// embed, so no scrape is needed for its title
+func sharePost(did, rkey, url, title string, createdAt time.Time) models.Event {
+	return testutil.PostEvent(did, rkey, createdAt.UnixMicro(), map[string]interface{}{
+		"$type":     "app.bsky.feed.post",
+		"text":      "Worth a read",
+		"createdAt": createdAt.Format(time.RFC3339),
+		"embed": map[string]interface{}{
+			"$type": "app.bsky.embed.external",
+			"external": map[string]interface{}{
+				"uri":         url,
+				"title":       title,
+				"description": "",
+			},
+		},
+	})
+}
+
+// TestTrendingFromFirehose replays posts from a fake Jetstream through the
+// firehose processor into Postgres and reads them back from /api/trending
+func TestTrendingFromFirehose(t *testing.T) {
+	if testing.Short() {
+		t.Skip("integration test")
+	}
+	db := testutil.NewTestDB(t) // Skips without TEST_DATABASE_URL or docker
+
+	now := time.Now().Add(-time.Minute).Truncate(time.Second)
+	events := []models.Event{
+		sharePost("did:plc:alice", "1", "https://example.com/story?utm_source=bsky", "Story", now),
+		sharePost("did:plc:bob", "1", "https://example.com/story", "Story", now.Add(time.Second)),
+		sharePost("did:plc:carol", "1", "https://EXAMPLE.com/story/#comments", "Story", now.Add(2*time.Second)),
+		sharePost("did:plc:alice", "2", "https://example.org/other", "Other", now.Add(3*time.Second)),
+		sharePost("did:plc:alice", "3", "https://example.org/other", "Other", now.Add(4*time.Second)),
+	}
+	jetstreamServer := testutil.NewFakeJetstream(t, events)
+
+	// Ingest like cmd/firehose, stopping once every event is processed
+	proc := processor.NewProcessorWithConfig(db, firstDegree{}, &processor.Config{
+		SkipMetadataFetch: true,
+		Source:            database.IngestSourceFirehose,
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	var processed atomic.Int32
+	client, err := jetstream.NewClient(&jetstream.Config{
+		WebsocketURL:      jetstreamServer.URL(),
+		WantedCollections: []string{"app.bsky.feed.post"},
+	}, func(ctx context.Context, event *models.Event) error {
+		if err := proc.ProcessEvent(event); err != nil {
+			t.Errorf("ProcessEvent: %v", err)
+		}
+		if int(processed.Add(1)) == len(events) {
+			cancel()
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	client.Connect(ctx, nil) // Returns once cancelled
+	if n := int(processed.Load()); n != len(events) {
+		t.Fatalf("processed %d of %d events", n, len(events))
+	}
+
+	// Serve /api/trending from the same database
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("config.Load: %v", err)
+	}
+	cfg.Server.RequireAPIKey = false
+	cfg.Server.TrendingCacheSeconds = 0
+	bundle, err := i18n.New(cfg.Server.DefaultLocale)
+	if err != nil {
+		t.Fatalf("i18n.New: %v", err)
+	}
+	server := &Server{
+		db:         db,
+		aggregator: aggregator.NewAggregator(db, nil),
+		router:     chi.NewRouter(),
+		config:     cfg,
+		cache:      cache.NewMemory(),
+		i18n:       bundle,
+	}
+	server.graphql = server.newGraphQLSchema()
+	server.setupRoutes()
+
+	rec := httptest.NewRecorder()
+	server.router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/trending?hours=1", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /api/trending: %d %s", rec.Code, rec.Body.String())
+	}
+
+	var response TrendingResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+		t.Fatalf("decoding response: %v", err)
+	}
+	got := make([]struct {
+		url    string
+		shares int
+	}, len(response.Links))
+	for i, link := range response.Links {
+		got[i].url, got[i].shares = link.URL, link.ShareCount
+	}
+	if len(got) != 2 ||
+		got[0].url != "https://example.com/story" || got[0].shares != 3 ||
+		got[1].url != "https://example.org/other" || got[1].shares != 1 {
+		t.Fatalf("trending = %+v, want example.com/story (3 sharers) then example.org/other (1)", got)
+	}
+	if title := response.Links[0].Title; title != "Story" {
+		t.Errorf("title = %q, want the embed's title", title)
+	}
+}
@@ -0,0 +1,423 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/aggregator"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/atutil"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/database"
+)
+
+// DiscoveriesResponse lists links an account was first in the network to share
+type DiscoveriesResponse struct {
+	DID         string               `json:"did"`
+	Discoveries []database.Discovery `json:"discoveries"`
+}
+
+func (s *Server) handleDiscoveries(w http.ResponseWriter, r *http.Request) {
+	handle := chi.URLParam(r, "handle")
+
+	limitStr := r.URL.Query().Get("limit")
+	if limitStr == "" {
+		limitStr = "50"
+	}
+	limit, err := strconv.Atoi(limitStr)
+	if err != nil || limit < 1 || limit > 100 {
+		http.Error(w, "Invalid limit parameter (1-100)", http.StatusBadRequest)
+		return
+	}
+
+	did, err := s.db.ResolveAccountDID(handle)
+	if err != nil {
+		log.Printf("Error resolving %s: %v", handle, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if did == "" {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	discoveries, err := s.db.GetDiscoveries(did, limit)
+	if err != nil {
+		log.Printf("Error getting discoveries for %s: %v", did, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if discoveries == nil {
+		discoveries = []database.Discovery{}
+	}
+
+	// Hide sensitive previews unless requested, as in trending
+	if r.URL.Query().Get("include_sensitive") != "true" {
+		placeholder := sensitivePlaceholderImage
+		for i := range discoveries {
+			if discoveries[i].Sensitive && discoveries[i].OGImageURL != nil {
+				discoveries[i].OGImageURL = &placeholder
+			}
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(DiscoveriesResponse{DID: did, Discoveries: discoveries})
+}
+
+// SharerLinksResponse is what a followed account shared in a time window
+type SharerLinksResponse struct {
+	DID    string              `json:"did"`
+	Handle string              `json:"handle"`
+	Hours  int                 `json:"hours"`
+	Links  []UserShareResponse `json:"links"`
+}
+
+// handleSharerLinks lists the links a followed account shared in the last
+// ?hours= (default 24), most recent first. Accounts outside the follows get
+// 404; see /api/users/{handle} for any account's recent shares.
+func (s *Server) handleSharerLinks(w http.ResponseWriter, r *http.Request) {
+	hoursStr := r.URL.Query().Get("hours")
+	if hoursStr == "" {
+		hoursStr = strconv.Itoa(aggregator.DefaultTrendingHours)
+	}
+	hours, err := strconv.Atoi(hoursStr)
+	if err != nil || hours < 1 || hours > aggregator.MaxTrendingHours {
+		http.Error(w, fmt.Sprintf("Invalid hours parameter (1-%d)", aggregator.MaxTrendingHours), http.StatusBadRequest)
+		return
+	}
+
+	limitStr := r.URL.Query().Get("limit")
+	if limitStr == "" {
+		limitStr = "50"
+	}
+	limit, err := strconv.Atoi(limitStr)
+	if err != nil || limit < 1 || limit > 100 {
+		http.Error(w, "Invalid limit parameter (1-100)", http.StatusBadRequest)
+		return
+	}
+
+	handle := chi.URLParam(r, "handle")
+	did, err := s.db.ResolveAccountDID(handle)
+	if err != nil {
+		log.Printf("Error resolving %s: %v", handle, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	var profile *database.UserProfile
+	if did != "" {
+		if profile, err = s.db.GetUserProfile(did); err != nil {
+			log.Printf("Error getting profile of %s: %v", did, err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+	if profile == nil || !profile.Followed {
+		http.Error(w, "Not a followed account", http.StatusNotFound)
+		return
+	}
+
+	shares, err := s.db.GetFollowShares(did, hours, limit)
+	if err != nil {
+		log.Printf("Error getting shares for %s: %v", did, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	// Hide sensitive previews unless requested, as in trending
+	includeSensitive := r.URL.Query().Get("include_sensitive") == "true"
+	placeholder := sensitivePlaceholderImage
+	links := make([]UserShareResponse, len(shares))
+	for i, share := range shares {
+		if share.Sensitive && share.OGImageURL != nil && !includeSensitive {
+			share.OGImageURL = &placeholder
+		}
+		links[i] = UserShareResponse{UserShare: share, PostURL: atutil.PostURL(share.PostID, profile.Handle)}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(SharerLinksResponse{
+		DID:    did,
+		Handle: profile.Handle,
+		Hours:  hours,
+		Links:  links,
+	})
+}
+
+// UserProfileResponse is an account's page: who it is, what it shared
+// recently and how its discoveries did
+type UserProfileResponse struct {
+	*database.UserProfile
+	ProfileURL   string                  `json:"profile_url"` // On Bluesky
+	MinShares    int                     `json:"min_shares"`
+	Stats        database.DiscoveryStats `json:"discovery_stats"`
+	TopDomains   []database.DomainCount  `json:"top_domains"`
+	RecentShares []UserShareResponse     `json:"recent_shares"`
+}
+
+// UserShareResponse is a shared link with a web link to the sharing post
+type UserShareResponse struct {
+	database.UserShare
+	PostURL string `json:"post_url,omitempty"`
+}
+
+func (s *Server) handleUserProfile(w http.ResponseWriter, r *http.Request) {
+	profile, status, msg := s.loadUserProfile(r)
+	if status != http.StatusOK {
+		http.Error(w, msg, status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(profile)
+}
+
+// handleUserPage renders an account's profile as HTML
+func (s *Server) handleUserPage(w http.ResponseWriter, r *http.Request) {
+	profile, status, msg := s.loadUserProfile(r)
+	if status != http.StatusOK {
+		http.Error(w, msg, status)
+		return
+	}
+
+	title := "@" + profile.Handle
+	if profile.DisplayName != nil && *profile.DisplayName != "" {
+		title = *profile.DisplayName + " (@" + profile.Handle + ")"
+	}
+	loc := s.localizer(r)
+	network := loc.T("network_outside")
+	switch {
+	case profile.Followed:
+		network = loc.T("network_followed")
+	case profile.Degree != nil && *profile.Degree == 1:
+		network = loc.T("network_first_degree")
+	case profile.Degree != nil:
+		network = loc.T("network_second_degree")
+	}
+	data := struct {
+		Title    string
+		Profile  *UserProfileResponse
+		Network  string
+		HitRate  string
+		Timezone *time.Location
+	}{
+		Title:    title,
+		Profile:  profile,
+		Network:  network,
+		HitRate:  fmt.Sprintf("%.0f%%", profile.Stats.HitRate*100),
+		Timezone: s.config.Timezone,
+	}
+
+	s.render(w, r, "user.html", data)
+}
+
+// loadUserProfile builds the profile of the {handle} account (handle or DID).
+// Query parameters: limit (recent shares, 1-100, default 25), min_shares
+// (hit threshold, default 5) and include_sensitive. Returns the HTTP status
+// and message to fail with, or StatusOK.
+func (s *Server) loadUserProfile(r *http.Request) (*UserProfileResponse, int, string) {
+	handle := chi.URLParam(r, "handle")
+
+	limitStr := r.URL.Query().Get("limit")
+	if limitStr == "" {
+		limitStr = "25"
+	}
+	limit, err := strconv.Atoi(limitStr)
+	if err != nil || limit < 1 || limit > 100 {
+		return nil, http.StatusBadRequest, "Invalid limit parameter (1-100)"
+	}
+
+	minSharesStr := r.URL.Query().Get("min_shares")
+	if minSharesStr == "" {
+		minSharesStr = "5"
+	}
+	minShares, err := strconv.Atoi(minSharesStr)
+	if err != nil || minShares < 2 {
+		return nil, http.StatusBadRequest, "Invalid min_shares parameter (2 or more)"
+	}
+
+	did, err := s.db.ResolveAccountDID(handle)
+	if err != nil {
+		log.Printf("Error resolving %s: %v", handle, err)
+		return nil, http.StatusInternalServerError, "Internal server error"
+	}
+	if did == "" {
+		return nil, http.StatusNotFound, "User not found"
+	}
+
+	profile, err := s.db.GetUserProfile(did)
+	if err != nil {
+		log.Printf("Error getting profile for %s: %v", did, err)
+		return nil, http.StatusInternalServerError, "Internal server error"
+	}
+	if profile == nil {
+		return nil, http.StatusNotFound, "User not found"
+	}
+
+	stats, err := s.db.GetDiscoveryStats(did, minShares)
+	if err != nil {
+		log.Printf("Error getting discovery stats for %s: %v", did, err)
+		return nil, http.StatusInternalServerError, "Internal server error"
+	}
+
+	domains, err := s.db.GetUserTopDomains(did, 10)
+	if err != nil {
+		log.Printf("Error getting top domains for %s: %v", did, err)
+		return nil, http.StatusInternalServerError, "Internal server error"
+	}
+	if domains == nil {
+		domains = []database.DomainCount{}
+	}
+
+	shares, err := s.db.GetUserShares(did, limit)
+	if err != nil {
+		log.Printf("Error getting shares for %s: %v", did, err)
+		return nil, http.StatusInternalServerError, "Internal server error"
+	}
+
+	// Hide sensitive previews unless requested, as in trending
+	includeSensitive := r.URL.Query().Get("include_sensitive") == "true"
+	placeholder := sensitivePlaceholderImage
+	recent := make([]UserShareResponse, len(shares))
+	for i, share := range shares {
+		if share.Sensitive && share.OGImageURL != nil && !includeSensitive {
+			share.OGImageURL = &placeholder
+		}
+		recent[i] = UserShareResponse{UserShare: share, PostURL: atutil.PostURL(share.PostID, profile.Handle)}
+	}
+
+	return &UserProfileResponse{
+		UserProfile:  profile,
+		ProfileURL:   atutil.ProfileURL("", profile.DID),
+		MinShares:    minShares,
+		Stats:        *stats,
+		TopDomains:   domains,
+		RecentShares: recent,
+	}, http.StatusOK, ""
+}
+
+// LeaderboardResponse ranks accounts whose discoveries went on to trend
+type LeaderboardResponse struct {
+	Hours     int                `json:"hours"`
+	MinShares int                `json:"min_shares"`
+	Curators  []database.Curator `json:"curators"`
+}
+
+func (s *Server) handleLeaderboard(w http.ResponseWriter, r *http.Request) {
+	hoursStr := r.URL.Query().Get("hours")
+	if hoursStr == "" {
+		hoursStr = "168"
+	}
+	hours, err := strconv.Atoi(hoursStr)
+	if err != nil || hours < 1 || hours > 720 {
+		http.Error(w, "Invalid hours parameter (1-720)", http.StatusBadRequest)
+		return
+	}
+
+	// A discovery counts as a hit once this many distinct accounts shared it
+	minSharesStr := r.URL.Query().Get("min_shares")
+	if minSharesStr == "" {
+		minSharesStr = "5"
+	}
+	minShares, err := strconv.Atoi(minSharesStr)
+	if err != nil || minShares < 2 {
+		http.Error(w, "Invalid min_shares parameter (2 or more)", http.StatusBadRequest)
+		return
+	}
+
+	limitStr := r.URL.Query().Get("limit")
+	if limitStr == "" {
+		limitStr = "25"
+	}
+	limit, err := strconv.Atoi(limitStr)
+	if err != nil || limit < 1 || limit > 100 {
+		http.Error(w, "Invalid limit parameter (1-100)", http.StatusBadRequest)
+		return
+	}
+
+	curators, err := s.db.GetCuratorLeaderboard(hours, minShares, limit, 3)
+	if err != nil {
+		log.Printf("Error getting leaderboard: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if curators == nil {
+		curators = []database.Curator{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(LeaderboardResponse{
+		Hours:     hours,
+		MinShares: minShares,
+		Curators:  curators,
+	})
+}
+
+// FollowRecommendationsResponse suggests 2nd-degree accounts to follow
+type FollowRecommendationsResponse struct {
+	Hours           int                             `json:"hours"`
+	MinShares       int                             `json:"min_shares"`
+	Recommendations []database.FollowRecommendation `json:"recommendations"`
+}
+
+func (s *Server) handleFollowRecommendations(w http.ResponseWriter, r *http.Request) {
+	hoursStr := r.URL.Query().Get("hours")
+	if hoursStr == "" {
+		hoursStr = "168"
+	}
+	hours, err := strconv.Atoi(hoursStr)
+	if err != nil || hours < 1 || hours > 720 {
+		http.Error(w, "Invalid hours parameter (1-720)", http.StatusBadRequest)
+		return
+	}
+
+	minSharesStr := r.URL.Query().Get("min_shares")
+	if minSharesStr == "" {
+		minSharesStr = "5"
+	}
+	minShares, err := strconv.Atoi(minSharesStr)
+	if err != nil || minShares < 2 {
+		http.Error(w, "Invalid min_shares parameter (2 or more)", http.StatusBadRequest)
+		return
+	}
+
+	minHitsStr := r.URL.Query().Get("min_hits")
+	if minHitsStr == "" {
+		minHitsStr = "2"
+	}
+	minHits, err := strconv.Atoi(minHitsStr)
+	if err != nil || minHits < 1 {
+		http.Error(w, "Invalid min_hits parameter (1 or more)", http.StatusBadRequest)
+		return
+	}
+
+	limitStr := r.URL.Query().Get("limit")
+	if limitStr == "" {
+		limitStr = "25"
+	}
+	limit, err := strconv.Atoi(limitStr)
+	if err != nil || limit < 1 || limit > 100 {
+		http.Error(w, "Invalid limit parameter (1-100)", http.StatusBadRequest)
+		return
+	}
+
+	recommendations, err := s.db.GetFollowRecommendations(hours, minShares, minHits, limit)
+	if err != nil {
+		log.Printf("Error getting follow recommendations: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if recommendations == nil {
+		recommendations = []database.FollowRecommendation{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(FollowRecommendationsResponse{
+		Hours:           hours,
+		MinShares:       minShares,
+		Recommendations: recommendations,
+	})
+}
@@ -1,6 +1,8 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
 	"log"
 	"sync"
@@ -8,56 +10,118 @@ import (
 
 	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/bluesky"
 	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/config"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/crawler"
 	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/database"
 	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/didmanager"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/features"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/moderation"
 	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/processor"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/runreport"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/scrapequeue"
 	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/urlutil"
 )
 
+// backfillDegree is the network degree of backfilled accounts (direct follows)
+const backfillDegree = 1
+
 // Backfiller handles backfilling historical posts for followed accounts
 type Backfiller struct {
 	db         *database.DB
 	bskyClient *bluesky.Client
 	processor  *processor.Processor
 	config     *config.Config
+	report     *runreport.Report
+
+	// API calls from all workers share one token bucket, and the whole
+	// backfill pauses when the quota reported by the API runs low
+	limiter     *crawler.RateLimiter
+	pauseMu     sync.Mutex
+	pausedUntil time.Time
 }
 
 func main() {
+	reportJSON := flag.String("report-json", "", "Write a JSON run summary to this file (- for stdout)")
+	flag.Parse()
+
+	// Written last, after queued scrapes finish
+	report := runreport.New("backfill", *reportJSON)
+	defer report.Finish()
+
 	// Load configuration (supports env vars)
 	cfg, err := config.Load()
 	if err != nil {
-		log.Fatalf("Failed to load config: %v", err)
+		report.Fatalf("Failed to load config: %v", err)
+	}
+
+	// Optional subsystems turned off in this deployment
+	if err := features.Configure(cfg.Features); err != nil {
+		log.Printf("[WARN] %v", err)
 	}
 
 	// Initialize database (log safe connection string without password)
 	log.Printf("[INFO] Connecting to database: %s", cfg.Database.DatabaseConnStringSafe())
 	db, err := database.NewDB(cfg.Database.DatabaseConnString())
 	if err != nil {
-		log.Fatalf("Failed to connect to database: %v", err)
+		report.Fatalf("Failed to connect to database: %v", err)
 	}
 	defer db.Close()
 
 	// Initialize Bluesky client (for API-based backfill)
 	bskyClient, err := bluesky.NewClient(cfg.Bluesky.Handle, cfg.Bluesky.Password)
 	if err != nil {
-		log.Fatalf("Failed to create Bluesky client: %v", err)
+		report.Fatalf("Failed to create Bluesky client: %v", err)
 	}
 
 	// Create DID manager and load network accounts
 	didManager := didmanager.NewManagerWithConfig(db, &didmanager.Config{
-		Include2ndDegree: true,
+		Include2ndDegree: features.Enabled(features.SecondDegree),
 		MinSourceCount:   2,
 	})
 	if err := didManager.LoadFromDatabase(); err != nil {
-		log.Fatalf("Failed to load DID manager: %v", err)
+		report.Fatalf("Failed to load DID manager: %v", err)
+	}
+
+	// Metadata fetches go through a bounded queue; jobs left when the
+	// backfill finishes are persisted for the firehose to serve
+	var scrapeQueue *scrapequeue.Queue
+	if cfg.Scrape.QueueCapacity > 0 {
+		scrapeQueue = scrapequeue.NewWithConfig(db, &scrapequeue.Config{
+			Capacity:     cfg.Scrape.QueueCapacity,
+			Workers:      cfg.Scrape.Workers,
+			ShedBacklog:  cfg.Scrape.ShedBacklog,
+			ShedPriority: cfg.Scrape.ShedPriority,
+		})
 	}
 
 	// Create backfiller
 	backfiller := &Backfiller{
 		db:         db,
 		bskyClient: bskyClient,
-		processor:  processor.NewProcessor(db, didManager),
-		config:     cfg,
+		processor: processor.NewProcessorWithConfig(db, didManager, &processor.Config{
+			ExcludeReplies:    cfg.Ingest.ExcludeReplies,
+			StoreRawRecord:    cfg.Ingest.StoreRawRecord,
+			RawRecordMaxBytes: cfg.Ingest.RawRecordMaxBytes,
+			Sensitive: moderation.NewDetectorWithConfig(&moderation.Config{
+				Labels:             cfg.Moderation.SensitiveLabels,
+				Domains:            cfg.Moderation.SensitiveDomains,
+				ImageClassifierURL: classifierURL(cfg),
+			}),
+			ScrapeQueue: scrapeQueue,
+			Enrichers:   cfg.Scrape.Enrichers,
+			Source:      database.IngestSourceBackfill,
+		}),
+		config:  cfg,
+		report:  report,
+		limiter: crawler.NewRateLimiter(requestsPerSecond(cfg.Polling.RateLimitMs)),
+	}
+	defer backfiller.limiter.Close()
+	if scrapeQueue != nil {
+		ctx, cancel := context.WithCancel(context.Background())
+		scrapeQueue.Start(ctx, backfiller.processor.FetchMetadata)
+		defer func() {
+			cancel()
+			scrapeQueue.Wait()
+		}()
 	}
 
 	log.Printf("[INFO] Starting backfill for accounts without completed backfill...")
@@ -65,7 +129,7 @@ func main() {
 	// Get all follows that need backfilling
 	follows, err := db.GetAllFollows()
 	if err != nil {
-		log.Fatalf("Failed to get follows: %v", err)
+		report.Fatalf("Failed to get follows: %v", err)
 	}
 
 	// Filter to only those needing backfill
@@ -77,14 +141,18 @@ func main() {
 	}
 
 	log.Printf("[INFO] Found %d accounts needing backfill (out of %d total)", len(needsBackfill), len(follows))
+	report.Add("accounts_pending", int64(len(needsBackfill)))
 
 	if len(needsBackfill) == 0 {
 		log.Printf("[INFO] No accounts need backfilling. Exiting.")
+		report.SetStatus(runreport.StatusNothing)
 		return
 	}
 
 	// Backfill concurrently
+	done := report.Phase("backfill")
 	backfiller.backfillAccounts(needsBackfill)
+	done()
 
 	log.Printf("[INFO] Backfill complete!")
 }
@@ -117,15 +185,14 @@ func (b *Backfiller) backfillAccounts(follows []database.Follow) {
 				successCount++
 			}
 			mu.Unlock()
-
-			// Rate limiting
-			time.Sleep(time.Duration(b.config.Polling.RateLimitMs) * time.Millisecond)
 		}(follow)
 	}
 
 	wg.Wait()
 
 	log.Printf("[INFO] Backfill results: %d succeeded, %d failed", successCount, failureCount)
+	b.report.Add("accounts_succeeded", int64(successCount))
+	b.report.Add("accounts_failed", int64(failureCount))
 }
 
 // backfillAccount backfills posts for a single account
@@ -147,6 +214,7 @@ func (b *Backfiller) backfillAccount(follow database.Follow) error {
 		feed, err := b.fetchWithRetry(follow.Handle, cursor, 50)
 		if err != nil {
 			log.Printf("[BACKFILL] %s: Failed after retries on page %d: %v", follow.Handle, pageCount, err)
+			b.report.Fail("fetch_feed")
 			return err
 		}
 
@@ -158,7 +226,11 @@ func (b *Backfiller) backfillAccount(follow database.Follow) error {
 		// Process posts
 		urlsInBatch := 0
 		for _, item := range feed.Feed {
-			urlsInBatch += b.processPost(&item.Post, follow.DID)
+			relation := database.RelationOriginal
+			if item.Reason != nil && item.Reason.Type == "app.bsky.feed.defs#reasonRepost" {
+				relation = database.RelationRepost
+			}
+			urlsInBatch += b.processPost(&item.Post, follow.DID, relation)
 		}
 		totalPosts += len(feed.Feed)
 		totalURLs += urlsInBatch
@@ -175,17 +247,18 @@ func (b *Backfiller) backfillAccount(follow database.Follow) error {
 		}
 
 		cursor = feed.Cursor
-
-		// Rate limiting between pages
-		time.Sleep(time.Duration(b.config.Polling.RateLimitMs) * time.Millisecond)
 	}
 
 	// Mark backfill as completed
 	if err := b.db.MarkBackfillCompleted(follow.DID); err != nil {
+		b.report.Fail("mark_completed")
 		return fmt.Errorf("failed to mark backfill complete: %w", err)
 	}
 
 	log.Printf("[BACKFILL] %s: Complete - %d posts, %d URLs (%d pages)", follow.Handle, totalPosts, totalURLs, pageCount)
+	b.report.Add("posts", int64(totalPosts))
+	b.report.Add("urls", int64(totalURLs))
+	b.report.Add("pages", int64(pageCount))
 	return nil
 }
 
@@ -197,7 +270,11 @@ func (b *Backfiller) fetchWithRetry(handle, cursor string, limit int) (*bluesky.
 	backoff := time.Duration(b.config.Polling.RetryBackoffMs) * time.Millisecond
 
 	for attempt := 0; attempt <= b.config.Polling.MaxRetries; attempt++ {
+		if err := b.waitForQuota(context.Background()); err != nil {
+			return nil, err
+		}
 		feed, err = b.bskyClient.GetAuthorFeed(handle, cursor, limit)
+		b.checkQuota()
 
 		if err == nil {
 			return feed, nil
@@ -213,18 +290,81 @@ func (b *Backfiller) fetchWithRetry(handle, cursor string, limit int) (*bluesky.
 	return nil, fmt.Errorf("failed after %d retries: %w", b.config.Polling.MaxRetries, err)
 }
 
-// processPost processes a single post from the API and stores it
-func (b *Backfiller) processPost(post *bluesky.Post, did string) int {
+// requestsPerSecond converts the configured delay between API calls into
+// the shared limiter's rate
+func requestsPerSecond(rateLimitMs int) int {
+	if rateLimitMs <= 0 || rateLimitMs >= 1000 {
+		return 1
+	}
+	return 1000 / rateLimitMs
+}
+
+// waitForQuota blocks while the backfill is paused for quota, then until
+// the shared limiter allows another call
+func (b *Backfiller) waitForQuota(ctx context.Context) error {
+	for {
+		b.pauseMu.Lock()
+		wait := time.Until(b.pausedUntil)
+		b.pauseMu.Unlock()
+		if wait <= 0 {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+	return b.limiter.Wait(ctx)
+}
+
+// checkQuota pauses every worker until the rate limit window resets when
+// the last response left no more than the reserve of requests
+func (b *Backfiller) checkQuota() {
+	reserve := b.config.Polling.QuotaReserve
+	if reserve < 0 {
+		return
+	}
+	quota, ok := b.bskyClient.RateLimit()
+	if !ok || quota.Remaining > reserve || !quota.Reset.After(time.Now()) {
+		return
+	}
+
+	b.pauseMu.Lock()
+	defer b.pauseMu.Unlock()
+	if quota.Reset.After(b.pausedUntil) {
+		b.pausedUntil = quota.Reset
+		log.Printf("[BACKFILL] Quota low (%d of %d left), pausing until %s",
+			quota.Remaining, quota.Limit, quota.Reset.Format(time.RFC3339))
+	}
+}
+
+// processPost processes a single post from the API and stores it.
+// relation is RelationRepost when the account reposted the post.
+func (b *Backfiller) processPost(post *bluesky.Post, did string, relation string) int {
 	// Store post in database
 	dbPost := &database.Post{
 		ID:           post.URI,
 		AuthorHandle: did, // Use DID for consistency with firehose
 		Content:      post.Record.Text,
+		IsReply:      post.Record.Reply != nil,
+		RawRecord:    b.processor.RawRecordForStorage(post.Record.Raw),
+		Labels:       bluesky.LabelValues(post.Labels),
+		Fingerprint:  moderation.TextFingerprint(post.Record.Text),
+		Langs:        database.NormalizeLangs(post.Record.Langs),
+		Source:       database.IngestSourceBackfill,
 		CreatedAt:    post.Record.CreatedAt,
 	}
 
-	if err := b.db.InsertPost(dbPost); err != nil {
+	if _, err := b.db.InsertPost(dbPost); err != nil {
 		log.Printf("[WARN] Error inserting post %s: %v", post.URI, err)
+		b.report.Fail("insert_post")
+		return 0
+	}
+
+	// Optionally keep reply links out of aggregation entirely
+	if dbPost.IsReply && b.config.Ingest.ExcludeReplies {
 		return 0
 	}
 
@@ -232,18 +372,22 @@ func (b *Backfiller) processPost(post *bluesky.Post, did string) int {
 
 	// Extract URLs from post text
 	urls := extractURLsFromText(post.Record.Text)
-	urlCount += b.processURLs(post.URI, urls)
+	urlCount += b.processURLs(post.URI, urls, relation)
 
 	// Extract URLs from embeds
 	if post.Embed != nil {
-		urlCount += b.processEmbed(post.URI, post.Embed)
+		urlCount += b.processEmbed(post.URI, post.Author.DID, post.Embed, relation)
+	}
+
+	if urlCount > 0 {
+		b.processor.MarkLabeledLinksSensitive(post.URI, dbPost.Labels)
 	}
 
 	return urlCount
 }
 
 // processURLs processes a list of URLs and links them to a post
-func (b *Backfiller) processURLs(postURI string, urls []string) int {
+func (b *Backfiller) processURLs(postURI string, urls []string, relation string) int {
 	urlCount := 0
 
 	for _, rawURL := range urls {
@@ -252,12 +396,14 @@ func (b *Backfiller) processURLs(postURI string, urls []string) int {
 		link, err := b.db.GetOrCreateLink(rawURL, normalizedURL)
 		if err != nil {
 			log.Printf("[WARN] Error with link %s: %v", rawURL, err)
+			b.report.Fail("store_link")
 			continue
 		}
 
 		// Link post to link
-		if err := b.db.LinkPostToLink(postURI, link.ID); err != nil {
+		if err := b.db.LinkPostToLinkWithAttribution(postURI, link.ID, relation, backfillDegree); err != nil {
 			log.Printf("[WARN] Error linking post to link: %v", err)
+			b.report.Fail("store_link")
 			continue
 		}
 
@@ -268,7 +414,7 @@ func (b *Backfiller) processURLs(postURI string, urls []string) int {
 }
 
 // processExternalWithMetadata processes an external link with pre-fetched metadata from Bluesky
-func (b *Backfiller) processExternalWithMetadata(postURI, rawURL, title, description, imageURL string) int {
+func (b *Backfiller) processExternalWithMetadata(postURI, rawURL, title, description, imageURL, relation string) int {
 	// Normalize URL
 	normalizedURL := normalizeURL(rawURL)
 
@@ -276,18 +422,20 @@ func (b *Backfiller) processExternalWithMetadata(postURI, rawURL, title, descrip
 	link, err := b.db.GetOrCreateLink(rawURL, normalizedURL)
 	if err != nil {
 		log.Printf("[WARN] Error with link %s: %v", rawURL, err)
+		b.report.Fail("store_link")
 		return 0
 	}
 
 	// Link post to link
-	if err := b.db.LinkPostToLink(postURI, link.ID); err != nil {
+	if err := b.db.LinkPostToLinkWithAttribution(postURI, link.ID, relation, backfillDegree); err != nil {
 		log.Printf("[WARN] Error linking post to link: %v", err)
+		b.report.Fail("store_link")
 		return 0
 	}
 
 	// Store Bluesky's metadata if we don't have any yet
 	if link.Title == nil {
-		if err := b.db.UpdateLinkMetadata(link.ID, title, description, imageURL); err != nil {
+		if _, err := b.db.SaveLinkMetadata(link.ID, database.MetadataSourceBluesky, title, description, imageURL); err != nil {
 			log.Printf("[WARN] Error updating link metadata: %v", err)
 		}
 	}
@@ -295,25 +443,28 @@ func (b *Backfiller) processExternalWithMetadata(postURI, rawURL, title, descrip
 	return 1
 }
 
-// processEmbed extracts URLs and metadata from embeds
-func (b *Backfiller) processEmbed(postURI string, embed *bluesky.Embed) int {
+// processEmbed extracts URLs and metadata from embeds. authorDID owns any
+// thumbnail blobs in the embed.
+func (b *Backfiller) processEmbed(postURI, authorDID string, embed *bluesky.Embed, relation string) int {
 	urlCount := 0
 
 	// Handle external link embeds with metadata
 	if embed.External != nil {
-		// Use Bluesky's pre-fetched metadata if available
-		if embed.External.Title != "" {
+		// Use Bluesky's pre-fetched metadata, even partial, if available
+		thumb := bluesky.ThumbURL(embed.External.Thumb, authorDID)
+		if embed.External.Title != "" || embed.External.Description != "" || thumb != "" {
 			urlCount += b.processExternalWithMetadata(
 				postURI,
 				embed.External.URI,
 				embed.External.Title,
 				embed.External.Description,
-				embed.External.Thumb,
+				thumb,
+				relation,
 			)
 		} else {
 			// Fallback: just store URL without metadata
 			urls := []string{embed.External.URI}
-			urlCount += b.processURLs(postURI, urls)
+			urlCount += b.processURLs(postURI, urls, relation)
 		}
 	}
 
@@ -321,13 +472,19 @@ func (b *Backfiller) processEmbed(postURI string, embed *bluesky.Embed) int {
 	if embed.Record != nil && embed.Record.Record != nil {
 		quotedPost := embed.Record.Record
 
+		// Links in a quoted post count as quotes (unless the whole post was reposted)
+		quoteRelation := database.RelationQuote
+		if relation == database.RelationRepost {
+			quoteRelation = relation
+		}
+
 		// Extract URLs from quoted post text
 		urls := extractURLsFromText(quotedPost.Record.Text)
-		urlCount += b.processURLs(postURI, urls)
+		urlCount += b.processURLs(postURI, urls, quoteRelation)
 
 		// Recursively process embeds in the quoted post
 		if quotedPost.Embed != nil {
-			urlCount += b.processEmbed(postURI, quotedPost.Embed)
+			urlCount += b.processEmbed(postURI, quotedPost.Author.DID, quotedPost.Embed, quoteRelation)
 		}
 	}
 
@@ -347,3 +504,12 @@ func normalizeURL(url string) string {
 	}
 	return normalized
 }
+
+// classifierURL is the configured image classifier, or none when the
+// classification feature is off
+func classifierURL(cfg *config.Config) string {
+	if !features.Enabled(features.Classification) {
+		return ""
+	}
+	return cfg.Moderation.ImageClassifierURL
+}
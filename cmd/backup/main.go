@@ -0,0 +1,61 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/backup"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/config"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/database"
+)
+
+func main() {
+	// Parse flags
+	output := flag.String("o", "", "Archive to write (default backup-<timestamp>.tar.gz)")
+	flag.Parse()
+
+	if *output == "" {
+		*output = fmt.Sprintf("backup-%s.tar.gz", time.Now().UTC().Format("20060102-150405"))
+	}
+
+	// Load configuration
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	// Connect to database
+	log.Printf("[INFO] Connecting to database: %s", cfg.Database.DatabaseConnStringSafe())
+	db, err := database.NewDB(cfg.Database.DatabaseConnString())
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	// Write to a temporary file so a failed backup never replaces a good one
+	tmp := *output + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		log.Fatalf("Failed to create %s: %v", tmp, err)
+	}
+
+	manifest, err := backup.Write(db, f)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		log.Fatalf("Backup failed: %v", err)
+	}
+	if err := os.Rename(tmp, *output); err != nil {
+		log.Fatalf("Failed to write %s: %v", *output, err)
+	}
+
+	for _, table := range database.BackupTables {
+		log.Printf("[INFO]   %s: %d rows", table.Name, manifest.Tables[table.Name])
+	}
+	log.Printf("[INFO] Backup written to %s", *output)
+}
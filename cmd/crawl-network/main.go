@@ -8,11 +8,13 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/bluesky"
 	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/config"
 	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/crawler"
 	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/database"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/maintenance"
 )
 
 func main() {
@@ -20,6 +22,7 @@ func main() {
 	degree := flag.Int("degree", 2, "Network degree to crawl (2 = 2nd-degree)")
 	threshold := flag.Int("threshold", 2, "Minimum source count for 2nd-degree accounts")
 	statsOnly := flag.Bool("stats", false, "Only show network statistics")
+	dormantLimit := flag.Int("dormant-limit", 500, "Dormant accounts to check for new posts (0 = skip)")
 	flag.Parse()
 
 	// Load configuration
@@ -88,7 +91,31 @@ func main() {
 		}
 	}
 
-	// Step 3: Show stats
+	// Step 3: Bring back dormant accounts that have started posting again
+	if *dormantLimit > 0 && cfg.Firehose.DormantDays > 0 {
+		log.Printf("[INFO] ========== Checking dormant accounts ==========")
+		activeSince := time.Now().Add(-time.Duration(cfg.Firehose.DormantDays) * 24 * time.Hour)
+		n, err := c.CheckDormant(ctx, activeSince, *dormantLimit)
+		if err != nil {
+			log.Fatalf("Failed to check dormant accounts: %v", err)
+		}
+		log.Printf("[INFO] Reactivated %d dormant accounts", n)
+	}
+
+	// Step 4: Regroup communities now that the follow graph has changed
+	if cfg.Communities.IntervalHours > 0 {
+		log.Printf("[INFO] ========== Detecting communities ==========")
+		n, err := maintenance.DetectCommunities(db, maintenance.CommunityConfig{
+			IntervalHours: cfg.Communities.IntervalHours,
+			MinSize:       cfg.Communities.MinSize,
+		})
+		if err != nil {
+			log.Fatalf("Failed to detect communities: %v", err)
+		}
+		log.Printf("[INFO] Detected %d communities", n)
+	}
+
+	// Step 5: Show stats
 	log.Printf("[INFO] ========== Network Statistics ==========")
 	printStats(db)
 
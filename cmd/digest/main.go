@@ -0,0 +1,122 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/aggregator"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/calendar"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/config"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/database"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/digest"
+)
+
+func main() {
+	// Parse flags
+	once := flag.Bool("once", false, "Send one digest now and exit (e.g. from cron)")
+	dryRun := flag.Bool("dry-run", false, "Print the digest's HTML instead of sending it, then exit")
+	flag.Parse()
+
+	// Load configuration
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+	if !*dryRun {
+		if len(cfg.Digest.Recipients) == 0 || cfg.Digest.From == "" || cfg.Digest.SMTPHost == "" {
+			log.Fatalf("DIGEST_RECIPIENTS, DIGEST_FROM and SMTP_HOST must be set to send digests")
+		}
+	}
+
+	// Check the trending query before waiting a day to run it
+	query := aggregator.NewTrendingQuery(cfg.Trending)
+	query.Location = cfg.Timezone
+	query.Hours = cfg.Digest.Hours
+	query.Limit = cfg.Digest.Limit
+	if err := query.Validate(); err != nil {
+		log.Fatalf("Invalid digest settings: %v", err)
+	}
+
+	// Connect to database
+	log.Printf("[INFO] Connecting to database: %s", cfg.Database.DatabaseConnStringSafe())
+	db, err := database.NewDB(cfg.Database.DatabaseConnString())
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	ranker, err := aggregator.NewRanking(cfg.Trending.Ranking, cfg.Trending.ClickWeight)
+	if err != nil {
+		log.Fatalf("Invalid trending.ranking: %v", err)
+	}
+	agg := aggregator.NewAggregator(db, ranker)
+
+	if *dryRun {
+		msg, err := build(agg, query, cfg)
+		if err != nil {
+			log.Fatalf("Failed to build digest: %v", err)
+		}
+		os.Stdout.Write(msg.HTML)
+		return
+	}
+	if *once {
+		if err := send(agg, query, cfg); err != nil {
+			log.Fatalf("%v", err)
+		}
+		return
+	}
+
+	// Send daily at DIGEST_SEND_AT; run a single instance, or readers get
+	// one digest per instance
+	for {
+		next, err := digest.NextSend(time.Now(), cfg.Digest.SendAt, cfg.Timezone)
+		if err != nil {
+			log.Fatalf("Invalid digest.send_at: %v", err)
+		}
+		log.Printf("[DIGEST] Next digest at %s", next.Format(time.RFC3339))
+		time.Sleep(time.Until(next))
+
+		// A failed send is retried the next day rather than spamming later
+		if err := send(agg, query, cfg); err != nil {
+			log.Printf("[ERROR] %v", err)
+		}
+	}
+}
+
+// build renders a digest of the current trending list
+func build(agg *aggregator.Aggregator, query aggregator.TrendingQuery, cfg *config.Config) (*digest.Message, error) {
+	links, err := agg.Trending(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get trending links: %w", err)
+	}
+
+	return digest.Render(digest.Digest{
+		Date:    calendar.Date(time.Now(), cfg.Timezone),
+		Hours:   query.Hours,
+		SiteURL: cfg.Server.PublicURL,
+		Links:   database.SnapshotLinks(links),
+	})
+}
+
+// send builds a digest and emails it to every recipient
+func send(agg *aggregator.Aggregator, query aggregator.TrendingQuery, cfg *config.Config) error {
+	msg, err := build(agg, query, cfg)
+	if err != nil {
+		return fmt.Errorf("failed to build digest: %w", err)
+	}
+
+	server := digest.SMTPConfig{
+		Host:     cfg.Digest.SMTPHost,
+		Port:     cfg.Digest.SMTPPort,
+		Username: cfg.Digest.SMTPUsername,
+		Password: cfg.Digest.SMTPPassword,
+	}
+	if err := digest.Send(server, cfg.Digest.From, cfg.Digest.Recipients, msg); err != nil {
+		return err
+	}
+	log.Printf("[DIGEST] Sent %q to %d recipients", msg.Subject, len(cfg.Digest.Recipients))
+	return nil
+}
@@ -11,14 +11,36 @@ import (
 	"time"
 
 	"github.com/bluesky-social/jetstream/pkg/models"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/cache"
 	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/config"
 	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/database"
 	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/didmanager"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/eventarchive"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/features"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/heartbeat"
 	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/jetstream"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/ledger"
 	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/maintenance"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/moderation"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/outbox"
 	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/processor"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/retryqueue"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/scheduler"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/scrapequeue"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/sharding"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/warehouse"
 )
 
+// maxHeldEvents bounds the failed events kept in memory while the retry
+// queue can't take them; past it the rest are left for replay on restart
+const maxHeldEvents = 10000
+
+// heldEvent is a failed event waiting for the retry queue
+type heldEvent struct {
+	event models.Event
+	cause error
+}
+
 func main() {
 	// Load configuration (supports env vars)
 	cfg, err := config.Load()
@@ -26,6 +48,11 @@ func main() {
 		log.Fatalf("Failed to load config: %v", err)
 	}
 
+	// Optional subsystems turned off in this deployment
+	if err := features.Configure(cfg.Features); err != nil {
+		log.Printf("[WARN] %v", err)
+	}
+
 	// Connect to database (log safe connection string without password)
 	log.Printf("[INFO] Connecting to database: %s", cfg.Database.DatabaseConnStringSafe())
 	db, err := database.NewDB(cfg.Database.DatabaseConnString())
@@ -34,6 +61,14 @@ func main() {
 	}
 	defer db.Close()
 
+	// Bring the schema up to date before anything reads it
+	if cfg.MigrateOnStart {
+		log.Printf("[INFO] Running migrations...")
+		if err := db.Migrate(context.Background(), database.MigrationsDir, cfg.Database.Schema, nil); err != nil {
+			log.Fatalf("Failed to run migrations: %v", err)
+		}
+	}
+
 	log.Printf("[INFO] Starting Jetstream firehose consumer...")
 
 	// Load cleanup configuration
@@ -42,6 +77,12 @@ func main() {
 		TrendingThreshold:    cfg.Cleanup.TrendingThreshold,
 		CleanupIntervalMin:   cfg.Cleanup.CleanupIntervalMin,
 		CursorUpdateInterval: cfg.Cleanup.CursorUpdateSeconds,
+		MaxPosts:             cfg.Cleanup.MaxPosts,
+		MaxLinks:             cfg.Cleanup.MaxLinks,
+		WarnDatabaseMB:       cfg.Cleanup.WarnDatabaseMB,
+		VacuumMinDeleted:     cfg.Cleanup.VacuumMinDeleted,
+		PartitionAheadDays:   cfg.Cleanup.PartitionAheadDays,
+		LinkExemptTables:     cfg.Cleanup.LinkExemptTables,
 	}
 
 	// PHASE 1: Startup cleanup
@@ -50,9 +91,9 @@ func main() {
 	}
 
 	// Create DID manager and load follows
-	// Enable 2nd-degree filtering with minimum 2 sources
+	// 2nd-degree filtering with minimum 2 sources, unless the feature is off
 	didManager := didmanager.NewManagerWithConfig(db, &didmanager.Config{
-		Include2ndDegree: true,
+		Include2ndDegree: features.Enabled(features.SecondDegree),
 		MinSourceCount:   2,
 	})
 	if err := didManager.LoadFromDatabase(); err != nil {
@@ -63,35 +104,329 @@ func main() {
 	log.Printf("[INFO] Filtering to %d DIDs (%d 1st-degree, %d 2nd-degree)",
 		didManager.Count(), counts[1], counts[2])
 
+	// Create context with cancellation
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
 	// Load last cursor for crash recovery
 	savedCursor, err := db.GetJetstreamCursor()
 	if err != nil {
 		log.Fatalf("Failed to get last cursor: %v", err)
 	}
 
+	// Shard mode: lease a DID hash range and resume from its own cursor
+	// (a new shard starts from the global cursor)
+	var lease *sharding.Lease
+	saveCursor := db.UpdateJetstreamCursor
+	if cfg.Firehose.ShardCount > 1 {
+		lease, err = sharding.Claim(ctx, db, sharding.Config{
+			Count:    cfg.Firehose.ShardCount,
+			Owner:    cfg.Firehose.InstanceName,
+			LeaseTTL: time.Duration(cfg.Firehose.ShardLeaseSeconds) * time.Second,
+		})
+		if err != nil {
+			log.Fatalf("Failed to claim shard: %v", err)
+		}
+		defer lease.Release()
+		log.Printf("[INFO] Shard mode: %s holds shard %d of %d", lease.Owner(), lease.ShardID, lease.Count())
+
+		// Stop consuming if the lease is lost so another instance can take over
+		lease.KeepAlive(ctx, cancel)
+
+		if lease.Cursor != nil {
+			savedCursor = lease.Cursor
+		}
+		saveCursor = lease.SaveCursor
+	}
+
 	if savedCursor != nil {
 		log.Printf("[INFO] Resuming from cursor: %d", *savedCursor)
 	} else {
 		log.Printf("[INFO] Starting from current time (no previous cursor)")
 	}
 
-	// PHASE 3: Start periodic cleanup ticker
-	maintenance.StartCleanupTicker(db, cleanupConfig)
+	// PHASE 3: Schedule periodic jobs. They run only on the instance holding
+	// the scheduler's leader lock, so replicas and shards don't double-run them.
+	// Instances in other schemas of the same database elect their own leader.
+	sched := scheduler.NewWithConfig(db, &scheduler.Config{LockName: schedulerLockName(cfg.Database.Schema)})
+	maintenance.ScheduleCleanup(sched, db, cleanupConfig)
+
+	// Trending settings used by jobs that read the trending list
+	trendingOpts := database.TrendingOptions{
+		ReplyMode:      cfg.Trending.ReplyMode,
+		ReplyWeight:    cfg.Trending.ReplyWeight,
+		LabelMode:      cfg.Trending.LabelMode,
+		FlaggedLabels:  cfg.Trending.FlaggedLabels,
+		LabelThreshold: cfg.Trending.LabelThreshold,
+
+		SelfPromoMode:   cfg.Trending.SelfPromoMode,
+		SelfPromoWeight: cfg.Trending.SelfPromoWeight,
+		PersonalDomains: cfg.Trending.PersonalDomains,
+
+		CollapseCopies: cfg.Trending.CollapseCopies,
+		HideDead:       cfg.Trending.HideDead,
+	}
+
+	// Snapshot trending so historical states survive cleanup
+	if features.Enabled(features.Digests) {
+		maintenance.ScheduleSnapshots(sched, db, maintenance.SnapshotConfig{
+			IntervalMin: cfg.Snapshot.IntervalMin,
+			Hours:       cfg.Snapshot.Hours,
+			Limit:       cfg.Snapshot.Limit,
+			Options:     trendingOpts,
+			Langs:       database.NormalizeLangs(cfg.Snapshot.Langs),
+		})
+	} else {
+		log.Println("[SNAPSHOT] Trending snapshots disabled (digests feature off)")
+	}
+
+	// Store the final list of time-boxed topics when they end, ranked as
+	// the API ranks them while they run
+	topicOpts := trendingOpts
+	topicOpts.MinShares = cfg.Trending.MinShares
+	topicOpts.PinnedWeight = cfg.Trending.PinnedWeight
+	maintenance.ScheduleTopicArchival(sched, db, maintenance.TopicConfig{
+		IntervalMin: cfg.Topics.ArchiveIntervalMin,
+		Limit:       cfg.Topics.ArchiveLimit,
+		Options:     topicOpts,
+	})
+
+	// Stream outbox events (link_created, link_trending) to downstream sinks
+	var sinks []outbox.Sink
+	for _, url := range cfg.Outbox.WebhookURLs {
+		sinks = append(sinks, outbox.NewWebhookSink(url, cfg.Outbox.WebhookSecret))
+	}
+	if cfg.Outbox.PublishEvents {
+		if cfg.Redis.URL == "" {
+			log.Printf("[WARN] OUTBOX_PUBLISH_EVENTS requires REDIS_URL; not publishing events")
+		} else {
+			backend, err := cache.NewRedis(cfg.Redis.URL, cfg.Redis.KeyPrefix)
+			if err != nil {
+				log.Fatalf("Failed to connect to Redis: %v", err)
+			}
+			defer backend.Close()
+			sinks = append(sinks, outbox.NewPubSubSink(backend.PubSub))
+		}
+	}
+	dispatcher := outbox.NewDispatcherWithConfig(db, sinks, &outbox.Config{
+		TrendingTop:     cfg.Outbox.TrendingTop,
+		TrendingHours:   cfg.Snapshot.Hours,
+		TrendingOptions: trendingOpts,
+	})
+	if len(sinks) > 0 {
+		log.Printf("[OUTBOX] Streaming events to %d sink(s)", len(sinks))
+		sched.Every("outbox-dispatch", time.Duration(cfg.Outbox.DispatchIntervalSec)*time.Second, true, dispatcher.Dispatch)
+	}
+	if cfg.Outbox.TrendingTop > 0 {
+		sched.Every("outbox-trending", time.Duration(cfg.Outbox.TrendingIntervalMin)*time.Minute, true, dispatcher.RecordTrending)
+	}
+
+	// Metadata fetches go through a bounded queue so a burst of new links
+	// can't stall ingestion or grow memory without limit
+	var scrapeQueue *scrapequeue.Queue
+	if cfg.Scrape.QueueCapacity > 0 {
+		scrapeQueue = scrapequeue.NewWithConfig(db, &scrapequeue.Config{
+			Capacity:     cfg.Scrape.QueueCapacity,
+			Workers:      cfg.Scrape.Workers,
+			ShedBacklog:  cfg.Scrape.ShedBacklog,
+			ShedPriority: cfg.Scrape.ShedPriority,
+		})
+	}
 
 	// Create processor for handling events (with DID manager for degree lookup)
-	proc := processor.NewProcessor(db, didManager)
+	proc := processor.NewProcessorWithConfig(db, didManager, &processor.Config{
+		ExcludeReplies:    cfg.Ingest.ExcludeReplies,
+		StoreRawRecord:    cfg.Ingest.StoreRawRecord,
+		RawRecordMaxBytes: cfg.Ingest.RawRecordMaxBytes,
+		Sensitive: moderation.NewDetectorWithConfig(&moderation.Config{
+			Labels:             cfg.Moderation.SensitiveLabels,
+			Domains:            cfg.Moderation.SensitiveDomains,
+			ImageClassifierURL: classifierURL(cfg),
+		}),
+		ScrapeQueue: scrapeQueue,
+		Enrichers:   cfg.Scrape.Enrichers,
+		Source:      database.IngestSourceFirehose,
+	})
+	if scrapeQueue != nil {
+		scrapeQueue.Start(ctx, proc.FetchMetadata)
+		defer func() {
+			cancel()
+			scrapeQueue.Wait() // Persist queued jobs before the database closes
+		}()
+	}
+
+	// Keep metadata fresh for the links people actually see
+	pushScrape := func(job scrapequeue.Job) { proc.FetchMetadata(job) }
+	if scrapeQueue != nil {
+		pushScrape = scrapeQueue.Push
+	}
+	maintenance.ScheduleMetadataRecheck(sched, db, pushScrape, maintenance.RecheckConfig{
+		IntervalMin: cfg.Scrape.RecheckIntervalMin,
+		Top:         cfg.Scrape.RecheckTop,
+		Hours:       cfg.Snapshot.Hours,
+		StaleAfter:  time.Duration(cfg.Scrape.RecheckStaleHours) * time.Hour,
+		Options:     trendingOpts,
+	})
+	maintenance.ScheduleMetadataRefresh(sched, db, proc.RefreshMetadata, maintenance.RefreshConfig{
+		MinShares: cfg.Scrape.RefreshMinShares,
+		Every:     time.Duration(cfg.Scrape.RefreshHours) * time.Hour,
+		MaxAge:    time.Duration(cfg.Scrape.RefreshMaxAgeHours) * time.Hour,
+	})
+
+	maintenance.ScheduleDeadLinkChecks(sched, db, maintenance.DeadLinkConfig{
+		IntervalMin:  cfg.Scrape.DeadCheckIntervalMin,
+		Top:          cfg.Scrape.DeadCheckTop,
+		Sample:       cfg.Scrape.DeadCheckSample,
+		Hours:        cfg.Snapshot.Hours,
+		RecheckAfter: time.Duration(cfg.Scrape.DeadCheckHours) * time.Hour,
+		Options:      trendingOpts,
+	})
+
+	// Roll up share counts per local calendar day
+	maintenance.ScheduleDailyRollups(sched, db, cfg.Timezone)
+
+	// Credit sharers in their account stats when their links trend
+	maintenance.ScheduleTrendingCredit(sched, db, cleanupConfig.TrendingThreshold)
+
+	// Move long-idle 2nd-degree accounts out of the live filter
+	maintenance.ScheduleDormantPruning(sched, db, didManager.LoadFromDatabase, maintenance.DormantConfig{
+		Days: cfg.Firehose.DormantDays,
+	})
+
+	// Group network accounts into communities from the follow graph
+	maintenance.ScheduleCommunityDetection(sched, db, maintenance.CommunityConfig{
+		IntervalHours: cfg.Communities.IntervalHours,
+		MinSize:       cfg.Communities.MinSize,
+	})
+
+	// Export each finished day's aggregates for long-term analysis
+	var warehouseSink warehouse.Sink
+	switch {
+	case cfg.Warehouse.S3Bucket != "":
+		warehouseSink = warehouse.NewS3Sink(warehouse.S3Config{
+			Bucket:    cfg.Warehouse.S3Bucket,
+			Prefix:    cfg.Warehouse.S3Prefix,
+			Region:    cfg.Warehouse.S3Region,
+			Endpoint:  cfg.Warehouse.S3Endpoint,
+			AccessKey: cfg.Warehouse.S3AccessKey,
+			SecretKey: cfg.Warehouse.S3SecretKey,
+		})
+	case cfg.Warehouse.Dir != "":
+		warehouseSink = &warehouse.DirSink{Dir: cfg.Warehouse.Dir}
+	}
+	maintenance.ScheduleWarehouseExport(sched, db, maintenance.WarehouseConfig{
+		Sink:         warehouseSink,
+		Timezone:     cfg.Timezone,
+		BackfillDays: cfg.Warehouse.BackfillDays,
+	})
+	sched.Start(ctx)
+
+	// Durable retry queue: failed events are persisted before the cursor moves past them
+	retryConfig := retryqueue.Config{
+		MaxAttempts:  cfg.Firehose.RetryMaxAttempts,
+		PollInterval: time.Duration(cfg.Firehose.RetryIntervalSeconds) * time.Second,
+	}
+	if lease != nil {
+		// Only retry events from our shard
+		retryConfig.Shard, retryConfig.ShardCount = lease.ShardID, lease.Count()
+	}
+	retryQueue := retryqueue.NewQueue(db, proc.ProcessEvent, retryConfig)
+
+	// Ingest ledger: events already processed per DID, so replays are skipped
+	ingestLedger, err := ledger.Load(db)
+	if err != nil {
+		log.Fatalf("Failed to load ingest ledger: %v", err)
+	}
+
+	// Optional archive of sampled and failed raw events, for reproducing
+	// parsing bugs offline
+	var archiver *eventarchive.Archiver
+	if cfg.Firehose.ArchiveDir != "" {
+		archiver, err = eventarchive.New(&eventarchive.Config{
+			Dir:        cfg.Firehose.ArchiveDir,
+			SampleRate: cfg.Firehose.ArchiveSampleRate,
+			Errors:     cfg.Firehose.ArchiveErrors,
+			RotateMB:   cfg.Firehose.ArchiveRotateMB,
+			MaxFiles:   cfg.Firehose.ArchiveMaxFiles,
+		})
+		if err != nil {
+			log.Fatalf("Failed to open event archive: %v", err)
+		}
+		defer archiver.Close()
+		log.Printf("[ARCHIVE] Archiving %.1f%% of matched events (errors: %v) to %s",
+			cfg.Firehose.ArchiveSampleRate*100, cfg.Firehose.ArchiveErrors, cfg.Firehose.ArchiveDir)
+	}
 
 	// Cursor batching variables
 	var (
 		currentCursor    int64
 		lastCursorUpdate time.Time
 		cursorMutex      sync.Mutex
+
+		// Failed events the retry queue couldn't take. While any are held the
+		// saved cursor stays before them, so a restart replays them.
+		heldEvents   []heldEvent
+		heldOverflow bool  // More failed than maxHeldEvents: hold until restart
+		heldSince    int64 // time_us of the first event of the current hold
+		heldRetryAt  time.Time
+		heldMutex    sync.Mutex
 	)
 
+	// heldFrom returns the time_us of the oldest event a restart must replay
+	// (0 if none is held), so the ledger isn't flushed past it
+	heldFrom := func() int64 {
+		heldMutex.Lock()
+		defer heldMutex.Unlock()
+
+		if heldOverflow {
+			return heldSince // Overflowed events aren't kept; hold from the start
+		}
+		if len(heldEvents) > 0 {
+			return heldEvents[0].event.TimeUS
+		}
+		return 0
+	}
+
+	// queueHeld hands held events to the retry queue, at most once per retry
+	// interval, and reports whether any are still held
+	queueHeld := func() bool {
+		heldMutex.Lock()
+		defer heldMutex.Unlock()
+
+		if len(heldEvents) == 0 || time.Now().Before(heldRetryAt) {
+			return len(heldEvents) > 0 || heldOverflow
+		}
+		heldRetryAt = time.Now().Add(retryConfig.PollInterval)
+
+		queued := 0
+		for _, held := range heldEvents {
+			if err := retryQueue.Enqueue(&held.event, held.cause); err != nil {
+				break // Queue still unavailable; keep this and the rest
+			}
+			ingestLedger.Record(held.event.Did, held.event.TimeUS)
+			queued++
+		}
+		heldEvents = heldEvents[queued:]
+		if len(heldEvents) == 0 && !heldOverflow {
+			log.Printf("[INFO] Held events queued - cursor advancing again")
+		}
+		return len(heldEvents) > 0 || heldOverflow
+	}
+
 	cursorUpdateInterval := time.Duration(cleanupConfig.CursorUpdateInterval) * time.Second
 
+	// Liveness: every event from Jetstream, followed or not, counts as
+	// progress, so quiet follows don't make a healthy connection look stale
+	liveness := heartbeat.New("firehose", heartbeat.Config{
+		File:       cfg.Firehose.Heartbeat.File,
+		Port:       cfg.Firehose.Heartbeat.Port,
+		StaleAfter: time.Duration(cfg.Firehose.Heartbeat.StaleSeconds) * time.Second,
+	})
+
 	// Event handler that processes filtered events
 	handler := func(ctx context.Context, event *models.Event) error {
+		liveness.Beat()
+
 		// Only process commit events for posts
 		if event.Kind == "commit" && event.Commit != nil {
 			if event.Commit.Operation == "create" && event.Commit.Collection == "app.bsky.feed.post" {
@@ -101,22 +436,64 @@ func main() {
 					return nil // Skip posts from accounts we don't follow
 				}
 
+				// In shard mode, other instances handle DIDs outside our range
+				if lease != nil && !lease.Owns(event.Did) {
+					return nil
+				}
+
+				// Skip events replayed from an older cursor after a restart
+				if ingestLedger.Seen(event.Did, event.TimeUS) {
+					return nil
+				}
+
 				// Update last_seen_at for this DID
 				if err := db.UpdateFollowLastSeen(event.Did); err != nil {
 					log.Printf("[WARN] Failed to update last_seen for %s: %v", event.Did, err)
 				}
+				if err := db.TouchNetworkAccount(event.Did, time.Now()); err != nil {
+					log.Printf("[WARN] Failed to update network last_seen for %s: %v", event.Did, err)
+				}
 
 				// Process the post (extract URLs, store in DB, fetch metadata)
-				if err := proc.ProcessEvent(event); err != nil {
+				processErr := proc.ProcessEvent(event)
+				if err := archiver.Archive(event, processErr); err != nil {
+					log.Printf("[WARN] %v", err)
+				}
+				if err := processErr; err != nil {
 					log.Printf("[ERROR] Failed to process event: %v", err)
-					return err
+
+					// Persist the failure so the cursor can safely move past it
+					if qerr := retryQueue.Enqueue(event, err); qerr != nil {
+						// Couldn't persist it either: hold the saved cursor before it
+						// and queue it later, so it survives a restart either way
+						heldMutex.Lock()
+						if len(heldEvents) == 0 && !heldOverflow {
+							log.Printf("[ERROR] %v - holding cursor until it can be queued", qerr)
+							heldRetryAt = time.Now().Add(retryConfig.PollInterval)
+							heldSince = event.TimeUS
+						}
+						if len(heldEvents) < maxHeldEvents {
+							heldEvents = append(heldEvents, heldEvent{*event, err})
+						} else if !heldOverflow {
+							log.Printf("[ERROR] More than %d events held - holding cursor until restart", maxHeldEvents)
+							heldOverflow = true
+						}
+						heldMutex.Unlock()
+						return err
+					}
 				}
+
+				// Processed or owned by the retry queue: a replay must not redo it
+				ingestLedger.Record(event.Did, event.TimeUS)
 			}
 		}
 
 		// Update cursor in memory (batched writes to database)
+		held := queueHeld()
 		cursorMutex.Lock()
-		currentCursor = event.TimeUS
+		if !held {
+			currentCursor = event.TimeUS
+		}
 		cursorMutex.Unlock()
 
 		// Periodically flush cursor to database (every N seconds instead of every event)
@@ -129,7 +506,11 @@ func main() {
 			cursor := currentCursor
 			cursorMutex.Unlock()
 
-			if err := db.UpdateJetstreamCursor(cursor); err != nil {
+			// The ledger is written in the same batches as the cursor
+			if err := ingestLedger.Flush(heldFrom()); err != nil {
+				log.Printf("[WARN] %v", err)
+			}
+			if err := saveCursor(cursor); err != nil {
 				log.Printf("[WARN] Failed to update cursor: %v", err)
 			} else {
 				cursorMutex.Lock()
@@ -143,7 +524,7 @@ func main() {
 
 	// Create Jetstream client (filtering is done client-side to avoid URL length limits)
 	client, err := jetstream.NewClient(&jetstream.Config{
-		WebsocketURL:      "wss://jetstream2.us-west.bsky.network/subscribe",
+		WebsocketURL:      cfg.Firehose.WebsocketURL,
 		Compress:          true,
 		WantedCollections: []string{"app.bsky.feed.post"},
 		// Note: WantedDIDs removed - 300+ DIDs exceeds WebSocket URL length limit
@@ -153,10 +534,6 @@ func main() {
 		log.Fatalf("Failed to create Jetstream client: %v", err)
 	}
 
-	// Create context with cancellation
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
-
 	// Handle shutdown signals
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
@@ -167,14 +544,25 @@ func main() {
 		cancel()
 	}()
 
-	// Flush final cursor on shutdown
+	// Retry previously failed events in the background
+	retryQueue.Start(ctx)
+
+	if err := liveness.Start(ctx); err != nil {
+		log.Fatalf("Failed to start heartbeat: %v", err)
+	}
+
+	// Flush final cursor and ledger on shutdown
 	defer func() {
+		if err := ingestLedger.Flush(heldFrom()); err != nil {
+			log.Printf("[ERROR] %v", err)
+		}
+
 		cursorMutex.Lock()
 		cursor := currentCursor
 		cursorMutex.Unlock()
 
 		if cursor > 0 {
-			if err := db.UpdateJetstreamCursor(cursor); err != nil {
+			if err := saveCursor(cursor); err != nil {
 				log.Printf("[ERROR] Failed to save final cursor: %v", err)
 			} else {
 				log.Printf("[INFO] Final cursor saved: %d", cursor)
@@ -182,6 +570,26 @@ func main() {
 		}
 	}()
 
+	// Reload the DID filter so every instance, not just the scheduler
+	// leader, drops dormant accounts and picks up reactivated ones
+	if cfg.Firehose.DormantDays > 0 && cfg.Firehose.FilterReloadMin > 0 {
+		go func() {
+			ticker := time.NewTicker(time.Duration(cfg.Firehose.FilterReloadMin) * time.Minute)
+			defer ticker.Stop()
+
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+					if err := didManager.LoadFromDatabase(); err != nil {
+						log.Printf("[WARN] Failed to reload DID filter: %v", err)
+					}
+				}
+			}
+		}()
+	}
+
 	// Start stats reporter
 	go func() {
 		ticker := time.NewTicker(30 * time.Second)
@@ -193,7 +601,12 @@ func main() {
 				return
 			case <-ticker.C:
 				bytes, events := client.Stats()
-				log.Printf("[STATS] Events: %d, Bytes: %s", events, formatBytes(bytes))
+				log.Printf("[STATS] Events: %d, Bytes: %s, Replayed duplicates: %d", events, formatBytes(bytes), ingestLedger.Duplicates())
+				if scrapeQueue != nil {
+					st := scrapeQueue.Stats()
+					log.Printf("[STATS] Scrape queue: depth=%d backlog=%d enqueued=%d bumped=%d overflowed=%d fetched=%d failed=%d shed=%d",
+						st.Depth, st.Backlog, st.Enqueued, st.Bumped, st.Overflowed, st.Fetched, st.Failed, st.Shed)
+				}
 			}
 		}
 	}()
@@ -218,3 +631,21 @@ func formatBytes(bytes int64) string {
 	}
 	return fmt.Sprintf("%.1f %cB", float64(bytes)/float64(div), "KMGTPE"[exp])
 }
+
+// schedulerLockName scopes the leader lock to the instance's schema, since
+// advisory locks are shared by the whole database
+func schedulerLockName(schema string) string {
+	if schema == "" {
+		return scheduler.DefaultLockName
+	}
+	return scheduler.DefaultLockName + ":" + schema
+}
+
+// classifierURL is the configured image classifier, or none when the
+// classification feature is off
+func classifierURL(cfg *config.Config) string {
+	if !features.Enabled(features.Classification) {
+		return ""
+	}
+	return cfg.Moderation.ImageClassifierURL
+}
@@ -1,12 +1,15 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"log"
 	"time"
 
 	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/config"
 	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/database"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/maintenance"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/runreport"
 )
 
 // JanitorConfig holds janitor-specific configuration
@@ -14,20 +17,28 @@ type JanitorConfig struct {
 	PostRetentionDays int
 	LinkRetentionDays int
 	DryRun            bool
+	VacuumMinDeleted  int      // Rows deleted before vacuuming (< 0 = never)
+	LinkExemptTables  []string // Tables whose link_id references keep a link
 }
 
 func main() {
+	reportJSON := flag.String("report-json", "", "Write a JSON run summary to this file (- for stdout)")
+	flag.Parse()
+
+	report := runreport.New("janitor", *reportJSON)
+	defer report.Finish()
+
 	// Load configuration (supports env vars)
 	cfg, err := config.Load()
 	if err != nil {
-		log.Fatalf("Failed to load config: %v", err)
+		report.Fatalf("Failed to load config: %v", err)
 	}
 
 	// Initialize database (log safe connection string without password)
 	log.Printf("[INFO] Connecting to database: %s", cfg.Database.DatabaseConnStringSafe())
 	db, err := database.NewDB(cfg.Database.DatabaseConnString())
 	if err != nil {
-		log.Fatalf("Failed to connect to database: %v", err)
+		report.Fatalf("Failed to connect to database: %v", err)
 	}
 	defer db.Close()
 
@@ -36,6 +47,8 @@ func main() {
 		PostRetentionDays: 30,
 		LinkRetentionDays: 90,
 		DryRun:            false,
+		VacuumMinDeleted:  cfg.Cleanup.VacuumMinDeleted,
+		LinkExemptTables:  cfg.Cleanup.LinkExemptTables,
 	}
 
 	log.Printf("[INFO] Starting database cleanup...")
@@ -44,25 +57,53 @@ func main() {
 	}
 
 	// Clean up old posts
-	if err := cleanupOldPosts(db, janitorCfg); err != nil {
-		log.Fatalf("Failed to clean up posts: %v", err)
+	done := report.Phase("old_posts")
+	postsDeleted, err := cleanupOldPosts(db, janitorCfg)
+	done()
+	if err != nil {
+		report.Fail("old_posts")
+		report.Fatalf("Failed to clean up posts: %v", err)
 	}
+	report.Add("old_post_rows_deleted", postsDeleted)
 
 	// Clean up orphaned links (links with no post_links references)
-	if err := cleanupOrphanedLinks(db, janitorCfg); err != nil {
-		log.Fatalf("Failed to clean up orphaned links: %v", err)
+	done = report.Phase("orphaned_links")
+	orphansDeleted, err := cleanupOrphanedLinks(db, janitorCfg)
+	done()
+	if err != nil {
+		report.Fail("orphaned_links")
+		report.Fatalf("Failed to clean up orphaned links: %v", err)
 	}
+	report.Add("orphaned_link_rows_deleted", orphansDeleted)
 
 	// Clean up old links (based on last shared date)
-	if err := cleanupOldLinks(db, janitorCfg); err != nil {
-		log.Fatalf("Failed to clean up old links: %v", err)
+	done = report.Phase("old_links")
+	linksDeleted, err := cleanupOldLinks(db, janitorCfg)
+	done()
+	if err != nil {
+		report.Fail("old_links")
+		report.Fatalf("Failed to clean up old links: %v", err)
+	}
+	report.Add("old_link_rows_deleted", linksDeleted)
+
+	// Reclaim space and refresh statistics after large deletes
+	if !janitorCfg.DryRun {
+		deleted := int(postsDeleted + orphansDeleted + linksDeleted)
+		done = report.Phase("vacuum")
+		err := maintenance.VacuumAfterCleanup(db, janitorCfg.VacuumMinDeleted, deleted)
+		done()
+		if err != nil {
+			report.Fail("vacuum")
+			report.Fatalf("Failed to vacuum: %v", err)
+		}
 	}
 
 	log.Printf("[INFO] Database cleanup complete!")
 }
 
-// cleanupOldPosts removes posts older than the retention period
-func cleanupOldPosts(db *database.DB, cfg *JanitorConfig) error {
+// cleanupOldPosts removes posts older than the retention period, returning
+// the rows deleted
+func cleanupOldPosts(db *database.DB, cfg *JanitorConfig) (int64, error) {
 	cutoff := time.Now().AddDate(0, 0, -cfg.PostRetentionDays)
 
 	log.Printf("[INFO] Cleaning up posts older than %d days (before %s)...", cfg.PostRetentionDays, cutoff.Format("2006-01-02"))
@@ -71,19 +112,19 @@ func cleanupOldPosts(db *database.DB, cfg *JanitorConfig) error {
 	var count int
 	countQuery := `SELECT COUNT(*) FROM posts WHERE created_at < $1`
 	if err := db.Get(&count, countQuery, cutoff); err != nil {
-		return fmt.Errorf("failed to count old posts: %w", err)
+		return 0, fmt.Errorf("failed to count old posts: %w", err)
 	}
 
 	log.Printf("[INFO] Found %d posts to delete", count)
 
 	if count == 0 {
 		log.Printf("[INFO] No old posts to clean up")
-		return nil
+		return 0, nil
 	}
 
 	if cfg.DryRun {
 		log.Printf("[DRY RUN] Would delete %d posts", count)
-		return nil
+		return 0, nil
 	}
 
 	// Delete post_links references first
@@ -95,7 +136,7 @@ func cleanupOldPosts(db *database.DB, cfg *JanitorConfig) error {
 	`
 	result, err := db.Exec(deletePostLinksQuery, cutoff)
 	if err != nil {
-		return fmt.Errorf("failed to delete post_links: %w", err)
+		return 0, fmt.Errorf("failed to delete post_links: %w", err)
 	}
 
 	postLinksDeleted, _ := result.RowsAffected()
@@ -105,19 +146,25 @@ func cleanupOldPosts(db *database.DB, cfg *JanitorConfig) error {
 	deletePostsQuery := `DELETE FROM posts WHERE created_at < $1`
 	result, err = db.Exec(deletePostsQuery, cutoff)
 	if err != nil {
-		return fmt.Errorf("failed to delete posts: %w", err)
+		return 0, fmt.Errorf("failed to delete posts: %w", err)
 	}
 
 	postsDeleted, _ := result.RowsAffected()
 	log.Printf("[INFO] Deleted %d posts", postsDeleted)
 
-	return nil
+	return postLinksDeleted + postsDeleted, nil
 }
 
-// cleanupOrphanedLinks removes links that are no longer referenced by any posts
-func cleanupOrphanedLinks(db *database.DB, cfg *JanitorConfig) error {
+// cleanupOrphanedLinks removes links that are no longer referenced by any
+// posts (or exempt tables), returning the rows deleted
+func cleanupOrphanedLinks(db *database.DB, cfg *JanitorConfig) (int64, error) {
 	log.Printf("[INFO] Cleaning up orphaned links (no post references)...")
 
+	exempt, err := db.LinkExemptFilter(cfg.LinkExemptTables)
+	if err != nil {
+		return 0, fmt.Errorf("failed to check link exempt tables: %w", err)
+	}
+
 	// Count orphaned links
 	var count int
 	countQuery := `
@@ -126,45 +173,51 @@ func cleanupOrphanedLinks(db *database.DB, cfg *JanitorConfig) error {
 		WHERE NOT EXISTS (
 			SELECT 1 FROM post_links pl WHERE pl.link_id = l.id
 		)
-	`
+		AND ` + exempt
 	if err := db.Get(&count, countQuery); err != nil {
-		return fmt.Errorf("failed to count orphaned links: %w", err)
+		return 0, fmt.Errorf("failed to count orphaned links: %w", err)
 	}
 
 	log.Printf("[INFO] Found %d orphaned links", count)
 
 	if count == 0 {
 		log.Printf("[INFO] No orphaned links to clean up")
-		return nil
+		return 0, nil
 	}
 
 	if cfg.DryRun {
 		log.Printf("[DRY RUN] Would delete %d orphaned links", count)
-		return nil
+		return 0, nil
 	}
 
 	// Delete orphaned links
 	deleteQuery := `
-		DELETE FROM links
+		DELETE FROM links l
 		WHERE NOT EXISTS (
-			SELECT 1 FROM post_links pl WHERE pl.link_id = links.id
+			SELECT 1 FROM post_links pl WHERE pl.link_id = l.id
 		)
-	`
+		AND ` + exempt
 	result, err := db.Exec(deleteQuery)
 	if err != nil {
-		return fmt.Errorf("failed to delete orphaned links: %w", err)
+		return 0, fmt.Errorf("failed to delete orphaned links: %w", err)
 	}
 
 	deleted, _ := result.RowsAffected()
 	log.Printf("[INFO] Deleted %d orphaned links", deleted)
 
-	return nil
+	return deleted, nil
 }
 
-// cleanupOldLinks removes links that haven't been shared recently
-func cleanupOldLinks(db *database.DB, cfg *JanitorConfig) error {
+// cleanupOldLinks removes links that haven't been shared recently (unless an
+// exempt table references them), returning the rows deleted
+func cleanupOldLinks(db *database.DB, cfg *JanitorConfig) (int64, error) {
 	cutoff := time.Now().AddDate(0, 0, -cfg.LinkRetentionDays)
 
+	exempt, err := db.LinkExemptFilter(cfg.LinkExemptTables)
+	if err != nil {
+		return 0, fmt.Errorf("failed to check link exempt tables: %w", err)
+	}
+
 	log.Printf("[INFO] Cleaning up links not shared since %d days ago (before %s)...", cfg.LinkRetentionDays, cutoff.Format("2006-01-02"))
 
 	// Count old links (links where the most recent post is older than cutoff)
@@ -186,12 +239,12 @@ func cleanupOldLinks(db *database.DB, cfg *JanitorConfig) error {
 
 	if count == 0 {
 		log.Printf("[INFO] No old links to clean up")
-		return nil
+		return 0, nil
 	}
 
 	if cfg.DryRun {
 		log.Printf("[DRY RUN] Would delete %d old links and their post_links", count)
-		return nil
+		return 0, nil
 	}
 
 	// Delete post_links for old links
@@ -208,7 +261,7 @@ func cleanupOldLinks(db *database.DB, cfg *JanitorConfig) error {
 	`
 	result, err := db.Exec(deletePostLinksQuery, cutoff)
 	if err != nil {
-		return fmt.Errorf("failed to delete post_links for old links: %w", err)
+		return 0, fmt.Errorf("failed to delete post_links for old links: %w", err)
 	}
 
 	postLinksDeleted, _ := result.RowsAffected()
@@ -222,17 +275,18 @@ func cleanupOldLinks(db *database.DB, cfg *JanitorConfig) error {
 			FROM links l
 			LEFT JOIN post_links pl ON l.id = pl.link_id
 			LEFT JOIN posts p ON pl.post_id = p.id
+			WHERE ` + exempt + `
 			GROUP BY l.id
 			HAVING MAX(p.created_at) < $1 OR MAX(p.created_at) IS NULL
 		)
 	`
 	result, err = db.Exec(deleteLinksQuery, cutoff)
 	if err != nil {
-		return fmt.Errorf("failed to delete old links: %w", err)
+		return 0, fmt.Errorf("failed to delete old links: %w", err)
 	}
 
 	linksDeleted, _ := result.RowsAffected()
 	log.Printf("[INFO] Deleted %d old links", linksDeleted)
 
-	return nil
+	return postLinksDeleted + linksDeleted, nil
 }
@@ -1,12 +1,18 @@
 package main
 
 import (
+	"errors"
+	"flag"
 	"fmt"
 	"log"
 	"time"
 
 	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/database"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/enrich"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/events"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/runreport"
 	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/scraper"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/urlutil"
 	"github.com/spf13/viper"
 )
 
@@ -20,16 +26,22 @@ type Config struct {
 }
 
 func main() {
+	reportJSON := flag.String("report-json", "", "Write a JSON run summary to this file (- for stdout)")
+	flag.Parse()
+
+	report := runreport.New("metadata-fetcher", *reportJSON)
+	defer report.Finish()
+
 	// Load configuration
 	config, err := loadConfig()
 	if err != nil {
-		log.Fatalf("Failed to load config: %v", err)
+		report.Fatalf("Failed to load config: %v", err)
 	}
 
 	// Initialize database
 	db, err := database.NewDB(config.DatabaseURL)
 	if err != nil {
-		log.Fatalf("Failed to connect to database: %v", err)
+		report.Fatalf("Failed to connect to database: %v", err)
 	}
 	defer db.Close()
 
@@ -40,19 +52,23 @@ func main() {
 
 	// Create scraper
 	sc := scraper.NewScraper()
+	enrich.RecordScrapes(sc, db)
 
 	// Get links that need metadata
 	links, err := getLinksNeedingMetadata(db)
 	if err != nil {
-		log.Fatalf("Failed to get links: %v", err)
+		report.Fatalf("Failed to get links: %v", err)
 	}
 
 	log.Printf("[INFO] Found %d links without metadata", len(links))
+	report.Add("links_pending", int64(len(links)))
 
 	if len(links) == 0 {
 		log.Printf("[INFO] No links need metadata fetching. Exiting.")
+		report.SetStatus(runreport.StatusNothing)
 		return
 	}
+	defer report.Phase("fetch")()
 
 	// Process links
 	successCount := 0
@@ -65,6 +81,16 @@ func main() {
 		// Skip if dry run
 		if config.DryRun {
 			skippedCount++
+			report.Add("skipped_dry_run", 1)
+			continue
+		}
+
+		// Leave links on paused domains unfetched until they are resumed
+		if paused, err := db.IsScrapePaused(urlutil.Domain(link.NormalizedURL)); err != nil {
+			log.Printf("[WARN] Failed to check scrape pause for %s: %v", link.NormalizedURL, err)
+		} else if paused {
+			skippedCount++
+			report.Add("skipped_paused", 1)
 			continue
 		}
 
@@ -73,21 +99,44 @@ func main() {
 		if err != nil {
 			log.Printf("[WARN] Failed to fetch metadata for %s: %v", link.NormalizedURL, err)
 			failureCount++
+			if errors.Is(err, scraper.ErrBlocked) {
+				report.Fail("blocked")
+			} else {
+				report.Fail("fetch")
+			}
 
 			// Mark as fetched even on failure to avoid retry storms
 			if err := db.MarkLinkFetched(link.ID); err != nil {
 				log.Printf("[ERROR] Failed to mark link as fetched: %v", err)
 			}
+			if recordErr := db.RecordScrapeError(link.ID, err.Error()); recordErr != nil {
+				log.Printf("[WARN] Failed to record scrape error: %v", recordErr)
+			}
 			continue
 		}
 
+		if err := db.ClearScrapeError(link.ID); err != nil {
+			log.Printf("[WARN] Failed to clear scrape error: %v", err)
+		}
+
+		if err := db.SetLinkImageMissing(link.ID, ogData.ImageMissing); err != nil {
+			log.Printf("[WARN] Failed to mark missing image for %s: %v", link.NormalizedURL, err)
+		}
+
 		// Update metadata
-		if err := db.UpdateLinkMetadata(link.ID, ogData.Title, ogData.Description, ogData.ImageURL); err != nil {
+		if _, err := db.SaveLinkMetadata(link.ID, database.MetadataSourceScraped, ogData.Title, ogData.Description, ogData.ImageURL); err != nil {
 			log.Printf("[ERROR] Failed to update metadata for %s: %v", link.NormalizedURL, err)
 			failureCount++
+			report.Fail("save")
 			continue
 		}
 
+		if n, err := events.Store(db, link.ID, ogData); err != nil {
+			log.Printf("[WARN] Failed to store events for %s: %v", link.NormalizedURL, err)
+		} else if n > 0 {
+			log.Printf("[INFO] Detected %d event(s) in %s", n, link.NormalizedURL)
+		}
+
 		successCount++
 		log.Printf("[SUCCESS] Updated metadata for %s (title: %q)", link.NormalizedURL, ogData.Title)
 
@@ -97,6 +146,9 @@ func main() {
 
 	log.Printf("[INFO] Metadata fetching complete!")
 	log.Printf("[INFO] Results: %d succeeded, %d failed, %d skipped", successCount, failureCount, skippedCount)
+	report.Add("succeeded", int64(successCount))
+	report.Add("failed", int64(failureCount))
+	report.Add("skipped", int64(skippedCount))
 }
 
 func loadConfig() (*Config, error) {
@@ -132,6 +184,9 @@ func loadConfig() (*Config, error) {
 			viper.GetString("database.sslmode"),
 		)
 	}
+	if schema := viper.GetString("database.schema"); schema != "" {
+		dbURL += " search_path=" + schema
+	}
 
 	return &Config{
 		DatabaseURL:   dbURL,
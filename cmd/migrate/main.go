@@ -1,16 +1,19 @@
 package main
 
 import (
-	"database/sql"
+	"context"
+	"flag"
 	"log"
-	"os"
-	"path/filepath"
+	"time"
 
-	_ "github.com/lib/pq"
 	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/config"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/database"
 )
 
 func main() {
+	partitionPosts := flag.Bool("partition-posts", false, "Convert posts and post_links to daily partitions after migrating")
+	flag.Parse()
+
 	// Load configuration (supports env vars)
 	cfg, err := config.Load()
 	if err != nil {
@@ -19,36 +22,68 @@ func main() {
 
 	// Connect to database (log safe connection string without password)
 	log.Printf("Connecting to database: %s", cfg.Database.DatabaseConnStringSafe())
-	db, err := sql.Open("postgres", cfg.Database.DatabaseConnString())
+	db, err := database.NewDB(cfg.Database.DatabaseConnString())
 	if err != nil {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
 	defer db.Close()
 
-	if err := db.Ping(); err != nil {
-		log.Fatalf("Failed to ping database: %v", err)
+	if schema := cfg.Database.Schema; schema != "" {
+		log.Printf("Using schema: %s", schema)
 	}
 
 	// Run migrations
 	log.Println("Running migrations...")
+	runMigrations(db.Migrate, cfg.Database.Schema)
+	log.Println("Migrations completed successfully!")
 
-	migrations, err := filepath.Glob("migrations/*.sql")
+	if *partitionPosts {
+		convertPosts(db, cfg)
+
+		// The old tables' indexes and views went with them; the migrations
+		// are idempotent, so running them all again recreates both
+		log.Println("Recreating indexes and views...")
+		runMigrations(db.RerunMigrations, cfg.Database.Schema)
+		log.Println("Partitioning completed successfully!")
+	}
+}
+
+// runMigrations executes migration files in order with migrate (Migrate
+// for pending ones, RerunMigrations for all). Tables of an instance with its
+// own schema are created there through the connection's search_path; both
+// create the schema first.
+func runMigrations(migrate func(ctx context.Context, dir, schema string, before func(name string)) error, schema string) {
+	err := migrate(context.Background(), database.MigrationsDir, schema, func(name string) {
+		log.Printf("Running migration: %s", name)
+	})
 	if err != nil {
-		log.Fatalf("Failed to find migrations: %v", err)
+		log.Fatalf("Migration failed: %v", err)
 	}
+}
 
-	for _, migration := range migrations {
-		log.Printf("Running migration: %s", filepath.Base(migration))
+// convertPosts partitions posts and post_links by day, covering the
+// retention window and the days cleanup would create ahead. Older posts go
+// to the default partition and are deleted row by row as before.
+func convertPosts(db *database.DB, cfg *config.Config) {
+	partitioned, err := db.IsPostsPartitioned()
+	if err != nil {
+		log.Fatalf("Failed to check posts partitioning: %v", err)
+	}
+	if partitioned {
+		log.Println("Posts are already partitioned")
+		return
+	}
 
-		content, err := os.ReadFile(migration)
-		if err != nil {
-			log.Fatalf("Failed to read migration %s: %v", migration, err)
-		}
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	retentionDays := (cfg.Cleanup.RetentionHours + 23) / 24
+	firstDay := today.AddDate(0, 0, -retentionDays)
+	lastDay := today.AddDate(0, 0, cfg.Cleanup.PartitionAheadDays)
 
-		if _, err := db.Exec(string(content)); err != nil {
-			log.Fatalf("Failed to execute migration %s: %v", migration, err)
-		}
+	log.Printf("Partitioning posts by day (%s to %s); writers block until the copy commits...",
+		firstDay.Format("2006-01-02"), lastDay.Format("2006-01-02"))
+	posts, postLinks, err := db.PartitionPosts(firstDay, lastDay)
+	if err != nil {
+		log.Fatalf("Failed to partition posts: %v", err)
 	}
-
-	log.Println("Migrations completed successfully!")
+	log.Printf("Copied %d posts and %d post_links into partitions", posts, postLinks)
 }
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"strings"
@@ -10,6 +11,9 @@ import (
 	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/bluesky"
 	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/config"
 	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/database"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/enrich"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/heartbeat"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/moderation"
 	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/scraper"
 	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/urlutil"
 )
@@ -18,9 +22,10 @@ import (
 type Poller struct {
 	db         *database.DB
 	bskyClient *bluesky.Client
-	scraper    *scraper.Scraper
+	enrich     *enrich.Pipeline
 	userHandle string
 	config     *config.Config
+	liveness   *heartbeat.Monitor
 }
 
 func main() {
@@ -44,13 +49,28 @@ func main() {
 		log.Fatalf("Failed to create Bluesky client: %v", err)
 	}
 
+	sc := scraper.NewScraper()
+	enrich.RecordScrapes(sc, db)
+	pipeline, err := enrich.Build(cfg.Scrape.Enrichers, enrich.Deps{DB: db, Scraper: sc})
+	if err != nil {
+		log.Printf("[WARN] %v; running %v", err, pipeline.Names())
+	}
+
 	// Create poller
 	poller := &Poller{
 		db:         db,
 		bskyClient: bskyClient,
-		scraper:    scraper.NewScraper(),
+		enrich:     pipeline,
 		userHandle: cfg.Bluesky.Handle,
 		config:     cfg,
+		liveness: heartbeat.New("poller", heartbeat.Config{
+			File:       cfg.Polling.Heartbeat.File,
+			Port:       cfg.Polling.Heartbeat.Port,
+			StaleAfter: time.Duration(cfg.Polling.Heartbeat.StaleSeconds) * time.Second,
+		}),
+	}
+	if err := poller.liveness.Start(context.Background()); err != nil {
+		log.Fatalf("Failed to start heartbeat: %v", err)
 	}
 
 	log.Printf("Starting poller for %s", cfg.Bluesky.Handle)
@@ -96,6 +116,7 @@ func (p *Poller) Poll() {
 			defer func() { <-semaphore }() // Release
 
 			p.pollAccount(h)
+			p.liveness.Beat()
 
 			// Rate limiting
 			time.Sleep(time.Duration(p.config.Polling.RateLimitMs) * time.Millisecond)
@@ -106,6 +127,7 @@ func (p *Poller) Poll() {
 
 	duration := time.Since(startTime)
 	log.Printf("Poll complete in %v", duration)
+	p.liveness.Beat()
 }
 
 // pollAccount fetches posts from a single account
@@ -312,14 +334,23 @@ func (p *Poller) processPost(post *bluesky.Post) int {
 		ID:           post.URI,
 		AuthorHandle: post.Author.Handle,
 		Content:      post.Record.Text,
+		IsReply:      post.Record.Reply != nil,
+		Fingerprint:  moderation.TextFingerprint(post.Record.Text),
+		Langs:        database.NormalizeLangs(post.Record.Langs),
+		Source:       database.IngestSourcePoller,
 		CreatedAt:    post.Record.CreatedAt,
 	}
 
-	if err := p.db.InsertPost(dbPost); err != nil {
+	if _, err := p.db.InsertPost(dbPost); err != nil {
 		log.Printf("Error inserting post %s: %v", post.URI, err)
 		return 0
 	}
 
+	// Optionally keep reply links out of aggregation entirely
+	if dbPost.IsReply && p.config.Ingest.ExcludeReplies {
+		return 0
+	}
+
 	urlCount := 0
 
 	// Extract URLs from post text
@@ -328,7 +359,7 @@ func (p *Poller) processPost(post *bluesky.Post) int {
 
 	// Extract URLs from embeds (quote posts, external links)
 	if post.Embed != nil {
-		urlCount += p.processEmbed(post.URI, post.Embed)
+		urlCount += p.processEmbed(post.URI, post.Author.DID, post.Embed)
 	}
 
 	return urlCount
@@ -370,20 +401,22 @@ func (p *Poller) processURLs(postURI string, urls []string) int {
 	return urlCount
 }
 
-// processEmbed extracts URLs from embeds (quote posts, external links, etc.)
-func (p *Poller) processEmbed(postURI string, embed *bluesky.Embed) int {
+// processEmbed extracts URLs from embeds (quote posts, external links, etc.).
+// authorDID owns any thumbnail blobs in the embed.
+func (p *Poller) processEmbed(postURI, authorDID string, embed *bluesky.Embed) int {
 	urlCount := 0
 
 	// Handle external link embeds
 	if embed.External != nil {
-		// Use Bluesky's pre-fetched metadata if available
-		if embed.External.Title != "" {
+		// Use Bluesky's pre-fetched metadata, even partial, if available
+		thumb := bluesky.ThumbURL(embed.External.Thumb, authorDID)
+		if embed.External.Title != "" || embed.External.Description != "" || thumb != "" {
 			urlCount += p.processExternalWithMetadata(
 				postURI,
 				embed.External.URI,
 				embed.External.Title,
 				embed.External.Description,
-				embed.External.Thumb,
+				thumb,
 			)
 		} else {
 			// Fallback: scrape if Bluesky didn't fetch metadata
@@ -402,7 +435,7 @@ func (p *Poller) processEmbed(postURI string, embed *bluesky.Embed) int {
 
 		// Recursively process embeds in the quoted post
 		if quotedPost.Embed != nil {
-			urlCount += p.processEmbed(postURI, quotedPost.Embed)
+			urlCount += p.processEmbed(postURI, quotedPost.Author.DID, quotedPost.Embed)
 		}
 	}
 
@@ -433,7 +466,7 @@ func (p *Poller) processExternalWithMetadata(postURI, rawURL, title, description
 
 	// Store Bluesky's metadata if we don't have any yet
 	if link.Title == nil {
-		if err := p.db.UpdateLinkMetadata(link.ID, title, description, imageURL); err != nil {
+		if _, err := p.db.SaveLinkMetadata(link.ID, database.MetadataSourceBluesky, title, description, imageURL); err != nil {
 			log.Printf("Error updating link metadata: %v", err)
 		}
 	}
@@ -441,16 +474,7 @@ func (p *Poller) processExternalWithMetadata(postURI, rawURL, title, description
 	return 1
 }
 
-// fetchOGDataAsync fetches OpenGraph data in the background
+// fetchOGDataAsync enriches a link in the background
 func (p *Poller) fetchOGDataAsync(linkID int, url string) {
-	ogData, err := p.scraper.FetchOGData(url)
-	if err != nil {
-		log.Printf("Error fetching OG data for %s: %v", url, err)
-		return
-	}
-
-	// Update link with OG data
-	if err := p.db.UpdateLinkMetadata(linkID, ogData.Title, ogData.Description, ogData.ImageURL); err != nil {
-		log.Printf("Error updating link metadata: %v", err)
-	}
+	p.enrich.Run(&enrich.Item{Link: &database.Link{ID: linkID, NormalizedURL: url}})
 }
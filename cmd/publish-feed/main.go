@@ -0,0 +1,60 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/bluesky"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/config"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/feedgen"
+)
+
+func main() {
+	// Parse flags
+	dryRun := flag.Bool("dry-run", false, "Print the feed record instead of publishing it")
+	flag.Parse()
+
+	// Load configuration
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	gen, err := feedgen.New(cfg.FeedGen, cfg.Server.PublicURL)
+	if err != nil {
+		log.Fatalf("Invalid feed generator settings: %v", err)
+	}
+	if gen == nil {
+		log.Fatalf("FEEDGEN_PUBLISHER_DID must be set to publish a feed")
+	}
+
+	record := gen.Record(time.Now())
+	if *dryRun {
+		data, err := json.MarshalIndent(record, "", "  ")
+		if err != nil {
+			log.Fatalf("Failed to encode feed record: %v", err)
+		}
+		fmt.Printf("%s\n%s\n", gen.FeedURI(), data)
+		return
+	}
+
+	// The record goes in the publisher's own repo, so log in as them
+	log.Printf("[INFO] Authenticating with Bluesky as %s", cfg.Bluesky.Handle)
+	bskyClient, err := bluesky.NewClient(cfg.Bluesky.Handle, cfg.Bluesky.Password)
+	if err != nil {
+		log.Fatalf("Failed to create Bluesky client: %v", err)
+	}
+	if bskyClient.GetDID() != gen.PublisherDID {
+		log.Fatalf("Logged in as %s, but FEEDGEN_PUBLISHER_DID is %s", bskyClient.GetDID(), gen.PublisherDID)
+	}
+
+	uri, err := bskyClient.PutRecord(feedgen.GeneratorCollection, gen.RecordName, record)
+	if err != nil {
+		log.Fatalf("Failed to publish feed record: %v", err)
+	}
+	log.Printf("[INFO] Published %s (service %s)", uri, gen.ServiceDID)
+	log.Printf("[INFO] Subscribe at %s", gen.WebURL())
+}
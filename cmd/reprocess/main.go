@@ -0,0 +1,106 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/config"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/database"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/didmanager"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/processor"
+)
+
+func main() {
+	// Parse flags
+	since := flag.String("since", "24h", "Reprocess posts created since this duration ago (e.g. 72h) or date (2006-01-02)")
+	dryRun := flag.Bool("dry-run", false, "Report link changes without writing")
+	batchSize := flag.Int("batch", 500, "Posts loaded per batch")
+	verbose := flag.Bool("verbose", false, "Log every changed post")
+	flag.Parse()
+
+	sinceTime, err := parseSince(*since)
+	if err != nil {
+		log.Fatalf("Invalid -since %q: %v", *since, err)
+	}
+
+	// Load configuration
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	// Connect to database
+	log.Printf("[INFO] Connecting to database: %s", cfg.Database.DatabaseConnStringSafe())
+	db, err := database.NewDB(cfg.Database.DatabaseConnString())
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	// Metadata for new links is left to cmd/metadata-fetcher
+	proc := processor.NewProcessorWithConfig(db, didmanager.NewManager(db), &processor.Config{
+		ExcludeReplies:    cfg.Ingest.ExcludeReplies,
+		SkipMetadataFetch: true,
+	})
+
+	mode := "LIVE"
+	if *dryRun {
+		mode = "DRY RUN"
+	}
+	log.Printf("[INFO] Reprocessing posts since %s (%s)", sinceTime.Format(time.RFC3339), mode)
+
+	start := time.Now()
+	total, changed, failed, fromText := 0, 0, 0, 0
+	afterID := ""
+
+	for {
+		posts, err := db.GetPostsSince(sinceTime, afterID, *batchSize)
+		if err != nil {
+			log.Fatalf("Failed to load posts: %v", err)
+		}
+		if len(posts) == 0 {
+			break
+		}
+
+		for i := range posts {
+			post := &posts[i]
+			total++
+			if len(post.RawRecord) == 0 {
+				fromText++
+			}
+
+			result, err := proc.ReprocessPost(post, *dryRun)
+			if err != nil {
+				log.Printf("[WARN] %s: %v", post.ID, err)
+				failed++
+				continue
+			}
+			if result.Changed {
+				changed++
+				if *verbose {
+					log.Printf("[CHANGED] %s: [%s] -> [%s]", post.ID,
+						strings.Join(result.Before, ", "), strings.Join(result.After, ", "))
+				}
+			}
+		}
+
+		afterID = posts[len(posts)-1].ID
+		log.Printf("[PROGRESS] %d posts processed, %d changed", total, changed)
+	}
+
+	log.Printf("[INFO] Reprocess complete in %v (%s): %d posts, %d changed, %d failed, %d without raw record (text only)",
+		time.Since(start).Round(time.Second), mode, total, changed, failed, fromText)
+}
+
+// parseSince accepts either a duration ago or an absolute date/time
+func parseSince(value string) (time.Time, error) {
+	if d, err := time.ParseDuration(value); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", value)
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/anonymize"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/config"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/database"
+)
+
+func main() {
+	// Parse flags
+	output := flag.String("o", "", "Dataset to write (default research-<timestamp>.tar.gz)")
+	hours := flag.Int("hours", 168, "Export shares posted in the last N hours")
+	resolution := flag.Duration("resolution", time.Minute, "Precision of exported timestamps")
+	keyFile := flag.String("key-file", "", "Hashing key to reuse across exports (created if missing; default a one-off key)")
+	flag.Parse()
+
+	if *output == "" {
+		*output = fmt.Sprintf("research-%s.tar.gz", time.Now().UTC().Format("20060102-150405"))
+	}
+
+	anonymizer, err := loadAnonymizer(*keyFile, *resolution)
+	if err != nil {
+		log.Fatalf("Failed to set up anonymization: %v", err)
+	}
+
+	// Load configuration
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	// Connect to database
+	log.Printf("[INFO] Connecting to database: %s", cfg.Database.DatabaseConnStringSafe())
+	db, err := database.NewDB(cfg.Database.DatabaseConnString())
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	// Write to a temporary file so a failed export leaves nothing behind
+	tmp := *output + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		log.Fatalf("Failed to create %s: %v", tmp, err)
+	}
+
+	since := time.Now().Add(-time.Duration(*hours) * time.Hour)
+	manifest, err := anonymize.Export(db, f, anonymizer, since)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		log.Fatalf("Export failed: %v", err)
+	}
+	if err := os.Rename(tmp, *output); err != nil {
+		log.Fatalf("Failed to write %s: %v", *output, err)
+	}
+
+	for name, count := range manifest.Files {
+		log.Printf("[INFO]   %s: %d records", name, count)
+	}
+	log.Printf("[INFO] Anonymized dataset written to %s", *output)
+}
+
+// loadAnonymizer reads the hex key in path, creating it when missing. An
+// empty path uses a one-off key, so the export can't be joined with others.
+func loadAnonymizer(path string, resolution time.Duration) (*anonymize.Anonymizer, error) {
+	if path == "" {
+		return anonymize.NewRandom(resolution)
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		a, err := anonymize.NewRandom(resolution)
+		if err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(path, []byte(hex.EncodeToString(a.Key())+"\n"), 0600); err != nil {
+			return nil, fmt.Errorf("failed to write key: %w", err)
+		}
+		log.Printf("[INFO] Created hashing key %s; keep it private", path)
+		return a, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key: %w", err)
+	}
+
+	key, err := hex.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil || len(key) < anonymize.KeySize {
+		return nil, fmt.Errorf("%s is not a %d-byte hex key", path, anonymize.KeySize)
+	}
+	return anonymize.New(key, resolution), nil
+}
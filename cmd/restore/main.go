@@ -0,0 +1,53 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/backup"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/config"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/database"
+)
+
+func main() {
+	// Parse flags
+	input := flag.String("i", "", "Archive written by cmd/backup (required)")
+	merge := flag.Bool("merge", false, "Restore into a non-empty database, skipping rows that already exist")
+	flag.Parse()
+
+	if *input == "" {
+		log.Fatalf("Usage: restore -i backup.tar.gz [-merge]")
+	}
+
+	// Load configuration
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	// Connect to database
+	log.Printf("[INFO] Connecting to database: %s", cfg.Database.DatabaseConnStringSafe())
+	db, err := database.NewDB(cfg.Database.DatabaseConnString())
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	f, err := os.Open(*input)
+	if err != nil {
+		log.Fatalf("Failed to open %s: %v", *input, err)
+	}
+	defer f.Close()
+
+	result, err := backup.Restore(db, f, backup.RestoreOptions{Merge: *merge})
+	if err != nil {
+		log.Fatalf("Restore failed: %v", err)
+	}
+
+	log.Printf("[INFO] Restored backup taken %s", result.Manifest.CreatedAt.Format("2006-01-02 15:04:05 MST"))
+	for _, table := range database.BackupTables {
+		log.Printf("[INFO]   %s: %d restored, %d already present", table.Name, result.Inserted[table.Name], result.Skipped[table.Name])
+	}
+	log.Printf("[INFO] Restore complete")
+}
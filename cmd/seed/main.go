@@ -0,0 +1,64 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"time"
+
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/config"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/database"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/seed"
+)
+
+func main() {
+	defaults := seed.DefaultDemoConfig()
+
+	// Parse flags
+	runID := flag.String("run-id", defaults.RunID, "Tag for generated rows")
+	remove := flag.Bool("remove", false, "Remove the data for -run-id and exit")
+	randSeed := flag.Int64("seed", defaults.RandSeed, "Random seed (the same seed generates the same data)")
+	follows := flag.Int("follows", defaults.Follows, "Followed (1st-degree) accounts")
+	accounts := flag.Int("accounts", defaults.Accounts, "All accounts, the rest 2nd-degree")
+	links := flag.Int("links", defaults.Links, "Distinct links")
+	posts := flag.Int("posts", defaults.Posts, "Posts")
+	hours := flag.Int("hours", int(defaults.Window/time.Hour), "Posts are spread over this many hours up to now")
+	flag.Parse()
+
+	// Load configuration
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	// Connect to database
+	log.Printf("[INFO] Connecting to database: %s", cfg.Database.DatabaseConnStringSafe())
+	db, err := database.NewDB(cfg.Database.DatabaseConnString())
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	if *remove {
+		if err := seed.Remove(db, *runID); err != nil {
+			log.Fatalf("Failed to remove demo data: %v", err)
+		}
+		log.Printf("[INFO] Removed demo data for run %s", *runID)
+		return
+	}
+
+	result, err := seed.Demo(db, seed.DemoConfig{
+		RunID:    *runID,
+		Follows:  *follows,
+		Accounts: *accounts,
+		Links:    *links,
+		Posts:    *posts,
+		Window:   time.Duration(*hours) * time.Hour,
+		RandSeed: *randSeed,
+	})
+	if err != nil {
+		log.Fatalf("Failed to generate demo data: %v", err)
+	}
+	log.Printf("[INFO] Seeded run %s in %v: %d accounts, %d links, %d posts, %d post_links",
+		result.RunID, result.Duration.Round(time.Millisecond), result.Accounts, result.Links, result.Posts, result.PostLinks)
+	log.Printf("[INFO] Remove it with: go run cmd/seed/main.go -remove -run-id %s", result.RunID)
+}
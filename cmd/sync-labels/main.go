@@ -0,0 +1,94 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"time"
+
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/bluesky"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/config"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/database"
+)
+
+func main() {
+	// Parse flags
+	stale := flag.Duration("stale", 24*time.Hour, "Refresh labels fetched longer ago than this")
+	maxAccounts := flag.Int("max", 0, "Maximum accounts to refresh (0 = all stale accounts)")
+	rateLimitMs := flag.Int("rate-limit-ms", 200, "Delay between getProfiles requests")
+	flag.Parse()
+
+	// Load configuration
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	// Connect to database
+	log.Printf("[INFO] Connecting to database: %s", cfg.Database.DatabaseConnStringSafe())
+	db, err := database.NewDB(cfg.Database.DatabaseConnString())
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	// Create Bluesky client
+	log.Printf("[INFO] Authenticating with Bluesky as %s", cfg.Bluesky.Handle)
+	bskyClient, err := bluesky.NewClient(cfg.Bluesky.Handle, cfg.Bluesky.Password)
+	if err != nil {
+		log.Fatalf("Failed to create Bluesky client: %v", err)
+	}
+
+	staleBefore := time.Now().Add(-*stale)
+	refreshed, labeled, failed := 0, 0, 0
+
+	for *maxAccounts == 0 || refreshed < *maxAccounts {
+		dids, err := db.GetAccountsNeedingLabelRefresh(staleBefore, bluesky.MaxProfilesPerRequest)
+		if err != nil {
+			log.Fatalf("Failed to load accounts: %v", err)
+		}
+		if len(dids) == 0 {
+			break
+		}
+
+		profiles, err := bskyClient.GetProfiles(dids)
+		if err != nil {
+			// Stop rather than overwrite existing labels; stale accounts are retried next run
+			log.Printf("[ERROR] getProfiles failed, stopping: %v", err)
+			failed += len(dids)
+			break
+		}
+
+		// Accounts missing from the response (deleted, suspended) get no labels
+		labelsByDID := make(map[string][]string, len(profiles))
+		for _, profile := range profiles {
+			labelsByDID[profile.DID] = bluesky.LabelValues(profile.Labels)
+		}
+
+		for _, did := range dids {
+			labels := labelsByDID[did]
+			if err := db.UpdateAccountLabels(did, labels); err != nil {
+				log.Fatalf("Failed to update labels for %s: %v", did, err)
+			}
+			if len(labels) > 0 {
+				labeled++
+				log.Printf("[LABELS] %s: %v", did, labels)
+			}
+			refreshed++
+		}
+
+		time.Sleep(time.Duration(*rateLimitMs) * time.Millisecond)
+	}
+
+	log.Printf("[INFO] Label sync complete: %d accounts refreshed, %d labeled, %d failed lookups", refreshed, labeled, failed)
+
+	// Re-type accounts now their labels are current
+	retyped, err := db.InferAccountTypes(database.AccountTypeRules{
+		NewsDomains:      cfg.Trending.NewsDomains,
+		NewsOrgLabels:    cfg.Trending.NewsOrgLabels,
+		JournalistLabels: cfg.Trending.JournalistLabels,
+	})
+	if err != nil {
+		log.Fatalf("Failed to infer account types: %v", err)
+	}
+	log.Printf("[INFO] Account types inferred: %d accounts changed type", retyped)
+}
@@ -7,6 +7,24 @@ function extractDomain(url) {
     }
 }
 
+function renderImage(link, domain) {
+    if (link.image_url) {
+        return `
+            <div class="link-image">
+                <img src="${link.image_url}" alt="${link.title || 'Link preview'}" onerror="this.parentElement.style.display='none'">
+            </div>
+        `;
+    }
+    if (link.image_missing && domain) {
+        return `
+            <div class="link-image link-image-placeholder">
+                <span>${domain}</span>
+            </div>
+        `;
+    }
+    return '';
+}
+
 function renderAvatarStack(sharers, maxVisible = 5) {
     if (!sharers || sharers.length === 0) {
         return '';
@@ -26,8 +44,8 @@ function renderAvatarStack(sharers, maxVisible = 5) {
         html += `<img
             src="${avatarUrl}"
             alt="${displayName}"
-            title="${displayName} (@${sharer.handle})"
-            class="avatar"
+            title="${displayName} (@${sharer.handle})${sharer.pinned ? ' · pinned' : ''}"
+            class="avatar${sharer.pinned ? ' avatar-pinned' : ''}"
             onerror="this.src='img/default-avatar.svg'"
         />`;
     });
@@ -69,11 +87,7 @@ function loadTrending() {
                 const domain = extractDomain(link.url);
 
                 card.innerHTML = `
-                    ${link.image_url ? `
-                        <div class="link-image">
-                            <img src="${link.image_url}" alt="${link.title || 'Link preview'}" onerror="this.parentElement.style.display='none'">
-                        </div>
-                    ` : ''}
+                    ${renderImage(link, domain)}
                     <div class="link-content">
                         <h3><a href="${link.url}" target="_blank" rel="noopener noreferrer">${link.title || link.url}</a></h3>
                         ${domain ? `<div class="link-domain">${domain}</div>` : ''}
@@ -1,7 +1,12 @@
 package aggregator
 
 import (
+	"math"
+	"sort"
+	"time"
+
 	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/database"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/urlutil"
 )
 
 // RankingStrategy defines how links should be ranked
@@ -9,6 +14,12 @@ type RankingStrategy interface {
 	Rank(links []database.TrendingLink) []database.TrendingLink
 }
 
+// scorer is a ranking strategy that orders links by a per-link score, so
+// the undiscovered ranking can apply its penalty on top of it
+type scorer interface {
+	scoreFunc() func(database.TrendingLink) float64
+}
+
 // ShareCountRanking ranks links by share count (default)
 type ShareCountRanking struct{}
 
@@ -19,34 +30,264 @@ func (r *ShareCountRanking) Rank(links []database.TrendingLink) []database.Trend
 	return links
 }
 
-// RecencyWeightedRanking ranks links with a recency boost
-// TODO: Implement this in the future
-type RecencyWeightedRanking struct{}
+func (r *ShareCountRanking) scoreFunc() func(database.TrendingLink) float64 {
+	return baseScore
+}
+
+// DefaultHalfLifeHours is how long RecencyWeightedRanking takes to halve a
+// link's score when no half-life is set
+const DefaultHalfLifeHours = 6
 
+// RecencyWeightedRanking favors links shared recently: score =
+// base score × 2^(-hours since the last share / HalfLifeHours), so a link
+// last shared one half-life ago needs twice the shares of a fresh one.
+type RecencyWeightedRanking struct {
+	HalfLifeHours float64          // <= 0 = DefaultHalfLifeHours
+	Now           func() time.Time // Clock (nil = time.Now)
+}
+
+// Rank re-sorts links by recency-weighted score, keeping SQL order for ties
 func (r *RecencyWeightedRanking) Rank(links []database.TrendingLink) []database.TrendingLink {
-	// TODO: Implement recency-weighted ranking
-	// Formula: score = share_count * (1 + recency_factor)
-	return links
+	return sortByScore(links, r.scoreFunc())
 }
 
-// VelocityRanking ranks links by how quickly they're gaining shares
-// TODO: Implement this in the future
-type VelocityRanking struct{}
+func (r *RecencyWeightedRanking) scoreFunc() func(database.TrendingLink) float64 {
+	halfLife := r.HalfLifeHours
+	if halfLife <= 0 {
+		halfLife = DefaultHalfLifeHours
+	}
+	now := clock(r.Now)
+	return func(link database.TrendingLink) float64 {
+		age := math.Max(now.Sub(link.LastSharedAt).Hours(), 0)
+		return baseScore(link) * math.Exp2(-age/halfLife)
+	}
+}
 
+// minVelocityHours keeps links first shared moments ago from dividing by
+// almost nothing
+const minVelocityHours = 1
+
+// VelocityRanking ranks links by how quickly they're gaining shares: base
+// score per hour since the link was first shared, counting at least an
+// hour. Links whose first share is unknown are timed from their last share.
+type VelocityRanking struct {
+	Now func() time.Time // Clock (nil = time.Now)
+}
+
+// Rank re-sorts links by shares per hour, keeping SQL order for ties
 func (r *VelocityRanking) Rank(links []database.TrendingLink) []database.TrendingLink {
-	// TODO: Implement velocity-based ranking
-	// Requires tracking share rate over time
-	return links
+	return sortByScore(links, r.scoreFunc())
+}
+
+func (r *VelocityRanking) scoreFunc() func(database.TrendingLink) float64 {
+	now := clock(r.Now)
+	return func(link database.TrendingLink) float64 {
+		first := link.LastSharedAt
+		if link.FirstSharedAt != nil {
+			first = *link.FirstSharedAt
+		}
+		return baseScore(link) / math.Max(now.Sub(first).Hours(), minVelocityHours)
+	}
+}
+
+// sortByScore returns links ordered by score, highest first; ties keep
+// their order
+func sortByScore(links []database.TrendingLink, score func(database.TrendingLink) float64) []database.TrendingLink {
+	scores := make(map[int]float64, len(links))
+	for _, link := range links {
+		scores[link.ID] = score(link)
+	}
+
+	ranked := append([]database.TrendingLink(nil), links...)
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return scores[ranked[i].ID] > scores[ranked[j].ID]
+	})
+	return ranked
+}
+
+// clock returns now(), or the current time if now is nil
+func clock(now func() time.Time) time.Time {
+	if now == nil {
+		return time.Now()
+	}
+	return now()
+}
+
+// ClickWeightedRanking boosts links readers open through the /out
+// redirect: score = base score × (1 + Weight × ln(1 + clicks)), keeping the
+// SQL weightings (replies, self-promotion, sharer types). Shares still
+// dominate, so a few clicks reorder close calls rather than lifting
+// barely-shared links.
+type ClickWeightedRanking struct {
+	Weight float64
+}
+
+// Rank re-sorts links by click-weighted score, keeping SQL order for ties
+func (r *ClickWeightedRanking) Rank(links []database.TrendingLink) []database.TrendingLink {
+	return sortByScore(links, r.scoreFunc())
+}
+
+func (r *ClickWeightedRanking) scoreFunc() func(database.TrendingLink) float64 {
+	return func(link database.TrendingLink) float64 {
+		return baseScore(link) * (1 + r.Weight*math.Log1p(float64(link.ClickCount)))
+	}
+}
+
+// Ranking strategies selectable in config
+const (
+	RankingShares   = "shares"
+	RankingClicks   = "clicks"
+	RankingRecency  = "recency"
+	RankingVelocity = "velocity"
+)
+
+// UndiscoveredRanking down-ranks or drops links from mainstream domains so
+// niche articles surface. Links keep their base score order otherwise.
+type UndiscoveredRanking struct {
+	Domains []string                            // Mainstream domains (subdomains match too)
+	Penalty float64                             // Score multiplier for mainstream links (0-1)
+	Exclude bool                                // Drop mainstream links instead of down-ranking them
+	Score   func(database.TrendingLink) float64 // Score the penalty applies to (nil = SQL score)
+}
+
+// IsMainstream reports whether a link's domain is on the mainstream list
+func (r *UndiscoveredRanking) IsMainstream(link database.TrendingLink) bool {
+	host := urlutil.Domain(link.NormalizedURL)
+	for _, domain := range r.Domains {
+		if urlutil.MatchesDomain(host, domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// Rank scores links by Score, times Penalty for mainstream links
+func (r *UndiscoveredRanking) Rank(links []database.TrendingLink) []database.TrendingLink {
+	type scored struct {
+		link  database.TrendingLink
+		score float64
+	}
+
+	base := r.Score
+	if base == nil {
+		base = baseScore
+	}
+
+	candidates := make([]scored, 0, len(links))
+	for _, link := range links {
+		score := base(link)
+		if r.IsMainstream(link) {
+			if r.Exclude {
+				continue
+			}
+			score *= r.Penalty
+		}
+		candidates = append(candidates, scored{link, score})
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].score > candidates[j].score
+	})
+
+	ranked := make([]database.TrendingLink, len(candidates))
+	for i, c := range candidates {
+		ranked[i] = c.link
+	}
+	return ranked
+}
+
+// baseScore is the score Go-side rankings start from: the SQL score, which
+// carries reply, self-promotion, pinned and cohort weights, or the share
+// count for links from rollups, which have no score
+func baseScore(link database.TrendingLink) float64 {
+	if link.Score == 0 {
+		return float64(link.ShareCount)
+	}
+	return link.Score
+}
+
+// Undiscovered modes
+const (
+	UndiscoveredOff      = "off"
+	UndiscoveredDownrank = "downrank"
+	UndiscoveredExclude  = "exclude"
+)
+
+// UndiscoveredOptions configures GetUndiscoveredLinks
+type UndiscoveredOptions struct {
+	Mode       string   // One of the Undiscovered* constants
+	Domains    []string // Configured mainstream domains
+	ShareRatio float64  // Domains with at least this fraction of all shares are also mainstream (0 = list only)
+	Penalty    float64  // Score multiplier for mainstream links when down-ranking
+}
+
+// rerankCandidates is how many more links than requested are fetched when
+// results are re-ranked in Go, so links outside the top N by SQL score can
+// rank in and down-ranked or excluded links can be replaced
+const rerankCandidates = 4
+
+// GetUndiscoveredLinks retrieves trending links with mainstream links
+// down-ranked or excluded, on top of the configured ranking. Mainstream
+// domains are the configured list plus domains that dominate the network's
+// shares in the window.
+func (a *Aggregator) GetUndiscoveredLinks(hoursBack, limit, degree int, opts database.TrendingOptions, undiscovered UndiscoveredOptions) ([]database.TrendingLink, error) {
+	domains := append([]string{}, undiscovered.Domains...)
+	if undiscovered.ShareRatio > 0 {
+		popular, err := a.db.GetDomainShares(hoursBack, undiscovered.ShareRatio)
+		if err != nil {
+			return nil, err
+		}
+		for _, d := range popular {
+			domains = append(domains, d.Domain)
+		}
+	}
+
+	links, err := a.db.GetTrendingLinksByDegree(hoursBack, limit*rerankCandidates, degree, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	ranker := &UndiscoveredRanking{
+		Domains: domains,
+		Penalty: undiscovered.Penalty,
+		Exclude: undiscovered.Mode == UndiscoveredExclude,
+	}
+	if s, ok := a.ranker.(scorer); ok {
+		ranker.Score = s.scoreFunc()
+	}
+	links = ranker.Rank(links)
+	if len(links) > limit {
+		links = links[:limit]
+	}
+	return links, nil
+}
+
+// LinkSource supplies the share data the aggregator ranks. *database.DB
+// implements it; MemorySource is an in-memory stand-in.
+type LinkSource interface {
+	// GetTrendingLinksByDegree returns the most-shared links of the last
+	// hoursBack hours (degree 0 = all sharers)
+	GetTrendingLinksByDegree(hoursBack, limit, degree int, opts database.TrendingOptions) ([]database.TrendingLink, error)
+
+	// GetDomainShares returns domains receiving at least minRatio of all shares
+	GetDomainShares(hoursBack int, minRatio float64) ([]database.DomainShare, error)
+
+	// GetTrendingLinksForDays returns the most-shared links of the calendar
+	// days first through last (dates at midnight UTC) from daily rollups,
+	// leaving out links with fewer than minShares shares and, if hideDead,
+	// links found dead
+	GetTrendingLinksForDays(first, last time.Time, limit, minShares int, hideDead bool) ([]database.TrendingLink, error)
 }
 
 // Aggregator handles link aggregation and ranking
 type Aggregator struct {
-	db      *database.DB
-	ranker  RankingStrategy
+	db     LinkSource
+	ranker RankingStrategy
+	shadow *ShadowConfig // Optional second strategy evaluated alongside ranker
 }
 
 // NewAggregator creates a new aggregator with the given ranking strategy
-func NewAggregator(db *database.DB, ranker RankingStrategy) *Aggregator {
+func NewAggregator(db LinkSource, ranker RankingStrategy) *Aggregator {
 	if ranker == nil {
 		ranker = &ShareCountRanking{} // Default
 	}
@@ -58,24 +299,34 @@ func NewAggregator(db *database.DB, ranker RankingStrategy) *Aggregator {
 }
 
 // GetTrendingLinks retrieves and ranks trending links
-func (a *Aggregator) GetTrendingLinks(hoursBack, limit int) ([]database.TrendingLink, error) {
-	links, err := a.db.GetTrendingLinks(hoursBack, limit)
-	if err != nil {
-		return nil, err
-	}
-
-	// Apply ranking strategy
-	return a.ranker.Rank(links), nil
+func (a *Aggregator) GetTrendingLinks(hoursBack, limit int, opts database.TrendingOptions) ([]database.TrendingLink, error) {
+	return a.GetTrendingLinksByDegree(hoursBack, limit, 0, opts)
 }
 
 // GetTrendingLinksByDegree retrieves and ranks trending links filtered by network degree
 // degree: 0 = all posts, 1 = 1st-degree only, 2 = 2nd-degree only
-func (a *Aggregator) GetTrendingLinksByDegree(hoursBack, limit, degree int) ([]database.TrendingLink, error) {
-	links, err := a.db.GetTrendingLinksByDegree(hoursBack, limit, degree)
+func (a *Aggregator) GetTrendingLinksByDegree(hoursBack, limit, degree int, opts database.TrendingOptions) ([]database.TrendingLink, error) {
+	// Rankings that re-sort choose from a larger pool than the SQL top N
+	candidates := limit
+	if reranks(a.ranker) || (a.shadow != nil && reranks(a.shadow.Ranker)) {
+		candidates = limit * rerankCandidates
+	}
+	links, err := a.db.GetTrendingLinksByDegree(hoursBack, candidates, degree, opts)
 	if err != nil {
 		return nil, err
 	}
 
 	// Apply ranking strategy
-	return a.ranker.Rank(links), nil
+	ranked := a.ranker.Rank(links)
+	if len(ranked) > limit {
+		ranked = ranked[:limit]
+	}
+	a.shadowRank(links, ranked)
+	return ranked, nil
+}
+
+// reranks reports whether a ranking strategy reorders the SQL results
+func reranks(ranker RankingStrategy) bool {
+	_, shares := ranker.(*ShareCountRanking)
+	return !shares
 }
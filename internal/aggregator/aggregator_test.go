@@ -0,0 +1,327 @@
+package aggregator
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/database"
+)
+
+var testNow = time.Date(2024, 5, 14, 12, 0, 0, 0, time.UTC)
+
+// trending builds a link with a score, share count and share times given
+// as hours before testNow (firstAgo < 0 = first share unknown)
+func trending(id int, score float64, shares int, lastAgo, firstAgo float64) database.TrendingLink {
+	link := database.TrendingLink{
+		ID:           id,
+		Score:        score,
+		ShareCount:   shares,
+		LastSharedAt: testNow.Add(-time.Duration(lastAgo * float64(time.Hour))),
+	}
+	if firstAgo >= 0 {
+		first := testNow.Add(-time.Duration(firstAgo * float64(time.Hour)))
+		link.FirstSharedAt = &first
+	}
+	return link
+}
+
+// clicked sets a link's click count
+func clicked(link database.TrendingLink, clicks int) database.TrendingLink {
+	link.ClickCount = clicks
+	return link
+}
+
+// ids returns the link IDs in order
+func ids(links []database.TrendingLink) []int {
+	out := make([]int, len(links))
+	for i, link := range links {
+		out[i] = link.ID
+	}
+	return out
+}
+
+func TestRankings(t *testing.T) {
+	now := func() time.Time { return testNow }
+
+	tests := []struct {
+		name    string
+		ranking RankingStrategy
+		links   []database.TrendingLink
+		want    []int
+	}{
+		{
+			name:    "shares keeps SQL order",
+			ranking: &ShareCountRanking{},
+			links:   []database.TrendingLink{trending(1, 2, 2, 5, 5), trending(2, 9, 9, 0, 0), trending(3, 4, 4, 1, 1)},
+			want:    []int{1, 2, 3},
+		},
+		{
+			name:    "shares empty",
+			ranking: &ShareCountRanking{},
+			links:   nil,
+			want:    []int{},
+		},
+		{
+			name:    "clicks reorder close calls",
+			ranking: &ClickWeightedRanking{Weight: 0.5},
+			links:   []database.TrendingLink{trending(1, 5, 5, 0, 0), clicked(trending(2, 4, 4, 0, 0), 20)},
+			want:    []int{2, 1},
+		},
+		{
+			name:    "clicks boost the weighted score, not share count",
+			ranking: &ClickWeightedRanking{Weight: 0.5},
+			// 2 × (1 + 0.5 ln 2) ≈ 2.7 vs 3 × 1
+			links: []database.TrendingLink{clicked(trending(1, 2, 6, 0, 0), 1), trending(2, 3, 3, 0, 0)},
+			want:  []int{2, 1},
+		},
+		{
+			name:    "recency fresh link beats a stale one with more shares",
+			ranking: &RecencyWeightedRanking{Now: now},
+			// 10 × 2^-2 = 2.5 vs 4 × 2^0 = 4
+			links: []database.TrendingLink{trending(1, 10, 10, 12, 20), trending(2, 4, 4, 0, 1)},
+			want:  []int{2, 1},
+		},
+		{
+			name:    "recency stale link still wins with enough shares",
+			ranking: &RecencyWeightedRanking{Now: now},
+			// 10 × 2^-1 = 5 vs 4
+			links: []database.TrendingLink{trending(1, 10, 10, 6, 20), trending(2, 4, 4, 0, 1)},
+			want:  []int{1, 2},
+		},
+		{
+			name:    "recency custom half-life",
+			ranking: &RecencyWeightedRanking{HalfLifeHours: 24, Now: now},
+			// 10 × 2^-0.5 ≈ 7.1 vs 4
+			links: []database.TrendingLink{trending(2, 4, 4, 0, 1), trending(1, 10, 10, 12, 20)},
+			want:  []int{1, 2},
+		},
+		{
+			name:    "recency ties keep SQL order",
+			ranking: &RecencyWeightedRanking{Now: now},
+			links:   []database.TrendingLink{trending(3, 5, 5, 2, 2), trending(1, 5, 5, 2, 4), trending(2, 5, 5, 2, 3)},
+			want:    []int{3, 1, 2},
+		},
+		{
+			name:    "recency clock skew doesn't boost future shares",
+			ranking: &RecencyWeightedRanking{Now: now},
+			links:   []database.TrendingLink{trending(1, 3, 3, 0, 0), trending(2, 3, 3, -2, 0)},
+			want:    []int{1, 2},
+		},
+		{
+			name:    "recency uses share count for rollups",
+			ranking: &RecencyWeightedRanking{Now: now},
+			links:   []database.TrendingLink{trending(1, 0, 2, 0, 0), trending(2, 0, 8, 6, 6)},
+			want:    []int{2, 1},
+		},
+		{
+			name:    "velocity young link beats an old one with more shares",
+			ranking: &VelocityRanking{Now: now},
+			// 20 / 10h = 2 vs 6 / 2h = 3
+			links: []database.TrendingLink{trending(1, 20, 20, 0, 10), trending(2, 6, 6, 0, 2)},
+			want:  []int{2, 1},
+		},
+		{
+			name:    "velocity counts at least an hour",
+			ranking: &VelocityRanking{Now: now},
+			// 3 / 1h vs 2 / 1h, not 3 / 0.5h vs 2 / 0.1h
+			links: []database.TrendingLink{trending(1, 2, 2, 0, 0.1), trending(2, 3, 3, 0, 0.5)},
+			want:  []int{2, 1},
+		},
+		{
+			name:    "velocity falls back to the last share",
+			ranking: &VelocityRanking{Now: now},
+			// 8 / 4h = 2 vs 5 / 1h = 5
+			links: []database.TrendingLink{trending(1, 8, 8, 4, -1), trending(2, 5, 5, 0, 24)},
+			want:  []int{1, 2},
+		},
+		{
+			name:    "velocity weighted score, not share count",
+			ranking: &VelocityRanking{Now: now},
+			// 1.5 / 1h vs 2 / 1h, though link 1 has more sharers
+			links: []database.TrendingLink{trending(1, 1.5, 3, 0, 1), trending(2, 2, 2, 0, 1)},
+			want:  []int{2, 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := append([]database.TrendingLink(nil), tt.links...)
+			got := ids(tt.ranking.Rank(input))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Rank = %v, want %v", got, tt.want)
+			}
+			if !reflect.DeepEqual(input, tt.links) && len(tt.links) > 0 {
+				t.Errorf("Rank reordered its input")
+			}
+		})
+	}
+}
+
+func TestNewRanking(t *testing.T) {
+	for _, name := range []string{RankingShares, RankingClicks, RankingRecency, RankingVelocity} {
+		if _, err := NewRanking(name, 0.5); err != nil {
+			t.Errorf("NewRanking(%q): %v", name, err)
+		}
+	}
+	if _, err := NewRanking("newest", 0.5); err == nil {
+		t.Error("NewRanking(newest) succeeded, want an error")
+	}
+}
+
+// TestMemorySourceDegrees covers the degree filter and reply handling that
+// MemorySource mirrors from the trending query
+func TestMemorySourceDegrees(t *testing.T) {
+	link := func(id int) database.Link {
+		return database.Link{ID: id, NormalizedURL: "https://example.com/" + strings.Repeat("a", id)}
+	}
+	at := func(minutesAgo int) time.Time { return testNow.Add(-time.Duration(minutesAgo) * time.Minute) }
+
+	src := NewMemorySource()
+	src.Now = func() time.Time { return testNow }
+	src.Add(
+		// Link 1: two 1st-degree sharers
+		Share{Link: link(1), Sharer: "did:plc:a", Degree: 1, SharedAt: at(10)},
+		Share{Link: link(1), Sharer: "did:plc:b", Degree: 1, SharedAt: at(20)},
+		// Link 2: three 2nd-degree sharers, one only in a reply
+		Share{Link: link(2), Sharer: "did:plc:c", Degree: 2, SharedAt: at(30)},
+		Share{Link: link(2), Sharer: "did:plc:d", Degree: 2, SharedAt: at(40)},
+		Share{Link: link(2), Sharer: "did:plc:e", Degree: 2, IsReply: true, SharedAt: at(5)},
+		// Link 3: one author twice, recorded at both degrees
+		Share{Link: link(3), Sharer: "did:plc:f", Degree: 1, SharedAt: at(50)},
+		Share{Link: link(3), Sharer: "did:plc:f", Degree: 2, SharedAt: at(55)},
+		// Outside the window
+		Share{Link: link(4), Sharer: "did:plc:g", Degree: 1, SharedAt: at(180)},
+	)
+
+	type result struct {
+		ID     int
+		Shares int
+		Score  float64
+	}
+	tests := []struct {
+		name   string
+		degree int
+		opts   database.TrendingOptions
+		want   []result
+	}{
+		{
+			name:   "all degrees",
+			degree: 0,
+			opts:   database.TrendingOptions{ReplyMode: database.ReplyModeInclude},
+			want:   []result{{2, 3, 3}, {1, 2, 2}, {3, 1, 1}},
+		},
+		{
+			name:   "1st degree",
+			degree: 1,
+			opts:   database.TrendingOptions{ReplyMode: database.ReplyModeInclude},
+			want:   []result{{1, 2, 2}, {3, 1, 1}},
+		},
+		{
+			name:   "2nd degree",
+			degree: 2,
+			opts:   database.TrendingOptions{ReplyMode: database.ReplyModeInclude},
+			want:   []result{{2, 3, 3}, {3, 1, 1}},
+		},
+		{
+			name:   "replies excluded",
+			degree: 0,
+			opts:   database.TrendingOptions{ReplyMode: database.ReplyModeExclude},
+			want:   []result{{1, 2, 2}, {2, 2, 2}, {3, 1, 1}},
+		},
+		{
+			name:   "replies downweighted",
+			degree: 2,
+			opts:   database.TrendingOptions{ReplyMode: database.ReplyModeDownweight, ReplyWeight: 0.5},
+			want:   []result{{2, 3, 2.5}, {3, 1, 1}},
+		},
+		{
+			name:   "minimum sharers",
+			degree: 0,
+			opts:   database.TrendingOptions{ReplyMode: database.ReplyModeInclude, MinShares: 2},
+			want:   []result{{2, 3, 3}, {1, 2, 2}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			links, err := src.GetTrendingLinksByDegree(1, 10, tt.degree, tt.opts)
+			if err != nil {
+				t.Fatalf("GetTrendingLinksByDegree: %v", err)
+			}
+			got := make([]result, len(links))
+			for i, link := range links {
+				got[i] = result{link.ID, link.ShareCount, link.Score}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	for _, degree := range []int{-1, 3} {
+		if _, err := src.GetTrendingLinksByDegree(1, 10, degree, database.TrendingOptions{}); err == nil {
+			t.Errorf("degree %d succeeded, want an error", degree)
+		}
+	}
+}
+
+// TestRerankingCandidatePool checks re-sorting rankings choose from more
+// than the SQL top N, in plain and undiscovered mode alike
+func TestRerankingCandidatePool(t *testing.T) {
+	at := func(hoursAgo float64) time.Time { return testNow.Add(-time.Duration(hoursAgo * float64(time.Hour))) }
+	stale := database.Link{ID: 1, NormalizedURL: "https://example.com/stale"}
+	fresh := database.Link{ID: 2, NormalizedURL: "https://example.com/fresh"}
+	mainstream := database.Link{ID: 3, NormalizedURL: "https://news.example.org/fresh"}
+
+	src := NewMemorySource()
+	src.Now = func() time.Time { return testNow }
+	src.Add(
+		// Most shares, but 12h ago: 4 × 2^-2 = 1 by recency
+		Share{Link: stale, Sharer: "did:plc:a", Degree: 1, SharedAt: at(12)},
+		Share{Link: stale, Sharer: "did:plc:b", Degree: 1, SharedAt: at(12)},
+		Share{Link: stale, Sharer: "did:plc:c", Degree: 1, SharedAt: at(12)},
+		Share{Link: stale, Sharer: "did:plc:g", Degree: 1, SharedAt: at(12)},
+		// Fewest shares, all just now: 2
+		Share{Link: fresh, Sharer: "did:plc:a", Degree: 1, SharedAt: at(0)},
+		Share{Link: fresh, Sharer: "did:plc:b", Degree: 1, SharedAt: at(0)},
+		// Mainstream, 6h ago: 3 × 2^-1 = 1.5 by recency, halved when down-ranked
+		Share{Link: mainstream, Sharer: "did:plc:d", Degree: 1, SharedAt: at(6)},
+		Share{Link: mainstream, Sharer: "did:plc:e", Degree: 1, SharedAt: at(6)},
+		Share{Link: mainstream, Sharer: "did:plc:f", Degree: 1, SharedAt: at(6)},
+	)
+	opts := database.TrendingOptions{ReplyMode: database.ReplyModeInclude}
+	undiscovered := UndiscoveredOptions{Mode: UndiscoveredDownrank, Domains: []string{"news.example.org"}, Penalty: 0.5}
+
+	tests := []struct {
+		name         string
+		ranking      RankingStrategy
+		undiscovered bool
+		want         []int
+	}{
+		{"shares", &ShareCountRanking{}, false, []int{1}},
+		{"recency ranks in a link outside the top 1", &RecencyWeightedRanking{Now: src.Now}, false, []int{2}},
+		{"undiscovered by shares", &ShareCountRanking{}, true, []int{1}},
+		{"undiscovered applies the configured ranking", &RecencyWeightedRanking{Now: src.Now}, true, []int{2}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			agg := NewAggregator(src, tt.ranking)
+			var links []database.TrendingLink
+			var err error
+			if tt.undiscovered {
+				links, err = agg.GetUndiscoveredLinks(24, 1, 0, opts, undiscovered)
+			} else {
+				links, err = agg.GetTrendingLinksByDegree(24, 1, 0, opts)
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := ids(links); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,288 @@
+package aggregator
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/calendar"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/database"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/urlutil"
+)
+
+// Share is one post sharing a link, as held by MemorySource
+type Share struct {
+	Link     database.Link
+	Sharer   string // Author DID
+	Handle   string // Author handle (defaults to Sharer)
+	Degree   int    // Author's network degree when the post was ingested
+	IsReply  bool
+	Langs    []string // Primary language subtags of the post
+	SharedAt time.Time
+}
+
+// MemorySource is an in-memory LinkSource for exercising ranking without
+// Postgres. It mirrors the trending query's counting (distinct sharers per
+// link, degree and reply handling, most-shared first) but ignores label,
+// cohort, self-promotion, pinned account, sharer type, keyword and cohort
+// boost options.
+type MemorySource struct {
+	Now      func() time.Time // Clock for the time window (defaults to time.Now)
+	Location *time.Location   // Timezone of calendar days (defaults to UTC)
+
+	mu     sync.Mutex
+	shares []Share
+}
+
+// NewMemorySource creates an empty in-memory source
+func NewMemorySource() *MemorySource {
+	return &MemorySource{Now: time.Now}
+}
+
+// Add records shares
+func (m *MemorySource) Add(shares ...Share) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.shares = append(m.shares, shares...)
+}
+
+// window returns the shares made in the last hoursBack hours
+func (m *MemorySource) window(hoursBack int) []Share {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now
+	if m.Now != nil {
+		now = m.Now
+	}
+	cutoff := now().Add(-time.Duration(hoursBack) * time.Hour)
+
+	var shares []Share
+	for _, share := range m.shares {
+		if share.SharedAt.After(cutoff) {
+			shares = append(shares, share)
+		}
+	}
+	return shares
+}
+
+// GetTrendingLinksByDegree implements LinkSource
+func (m *MemorySource) GetTrendingLinksByDegree(hoursBack, limit, degree int, opts database.TrendingOptions) ([]database.TrendingLink, error) {
+	if degree < 0 || degree > 2 {
+		return nil, fmt.Errorf("invalid degree %d (want 0, 1 or 2)", degree)
+	}
+
+	type tally struct {
+		link    database.TrendingLink
+		sharers map[string]bool // DID -> shared outside a reply
+		handles map[string]bool
+	}
+	tallies := make(map[int]*tally)
+
+	for _, share := range m.window(hoursBack) {
+		if degree != 0 && share.Degree != degree {
+			continue
+		}
+		if share.IsReply && opts.ReplyMode == database.ReplyModeExclude {
+			continue
+		}
+		if opts.HideDead && share.Link.DeadAt != nil {
+			continue
+		}
+		if len(opts.Langs) > 0 && !sharesLang(share.Langs, opts.Langs) {
+			continue
+		}
+		if !opts.Since.IsZero() && share.SharedAt.Before(opts.Since) ||
+			!opts.Until.IsZero() && !share.SharedAt.Before(opts.Until) {
+			continue
+		}
+
+		t, ok := tallies[share.Link.ID]
+		if !ok {
+			t = &tally{
+				link: database.TrendingLink{
+					ID:            share.Link.ID,
+					NormalizedURL: share.Link.NormalizedURL,
+					OriginalURL:   share.Link.OriginalURL,
+					Title:         share.Link.Title,
+					Description:   share.Link.Description,
+					OGImageURL:    share.Link.OGImageURL,
+					Sensitive:     share.Link.Sensitive,
+					DeadAt:        share.Link.DeadAt,
+					ImageMissing:  share.Link.ImageMissing,
+					FirstSharedAt: share.Link.FirstSharedAt,
+				},
+				sharers: make(map[string]bool),
+				handles: make(map[string]bool),
+			}
+			tallies[share.Link.ID] = t
+		}
+
+		t.sharers[share.Sharer] = t.sharers[share.Sharer] || !share.IsReply
+		handle := share.Handle
+		if handle == "" {
+			handle = share.Sharer
+		}
+		t.handles[handle] = true
+		if share.SharedAt.After(t.link.LastSharedAt) {
+			t.link.LastSharedAt = share.SharedAt
+		}
+	}
+
+	type scored struct {
+		link  database.TrendingLink
+		score float64
+	}
+	candidates := make([]scored, 0, len(tallies))
+	for _, t := range tallies {
+		score := 0.0
+		for _, nonReply := range t.sharers {
+			if !nonReply && opts.ReplyMode == database.ReplyModeDownweight {
+				score += opts.ReplyWeight
+			} else {
+				score++
+			}
+		}
+		t.link.ShareCount = len(t.sharers)
+		t.link.Score = score
+		if t.link.ShareCount < opts.MinShares {
+			continue
+		}
+		if opts.After != nil && !opts.After.Before(database.CursorFor(t.link)) {
+			continue
+		}
+		if !opts.OmitSharers {
+			for handle := range t.handles {
+				t.link.Sharers = append(t.link.Sharers, handle)
+			}
+			sort.Strings(t.link.Sharers)
+		}
+		candidates = append(candidates, scored{t.link, score})
+	}
+
+	// Same order as the SQL: score, share count, most recently shared, ID
+	sort.Slice(candidates, func(i, j int) bool {
+		return database.CursorFor(candidates[i].link).Before(database.CursorFor(candidates[j].link))
+	})
+
+	if len(candidates) > limit {
+		candidates = candidates[:limit]
+	}
+	links := make([]database.TrendingLink, len(candidates))
+	for i, c := range candidates {
+		links[i] = c.link
+	}
+	return links, nil
+}
+
+// GetTrendingLinksForDays implements LinkSource, counting distinct sharers
+// per day as the rollup job does
+func (m *MemorySource) GetTrendingLinksForDays(first, last time.Time, limit, minShares int, hideDead bool) ([]database.TrendingLink, error) {
+	loc := m.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	m.mu.Lock()
+	type dayShare struct {
+		day    time.Time
+		linkID int
+		sharer string
+	}
+	seen := make(map[dayShare]bool)
+	tallies := make(map[int]*database.TrendingLink)
+	for _, share := range m.shares {
+		day := calendar.Date(share.SharedAt, loc)
+		if day.Before(first) || day.After(last) || hideDead && share.Link.DeadAt != nil {
+			continue
+		}
+		t, ok := tallies[share.Link.ID]
+		if !ok {
+			t = &database.TrendingLink{
+				ID:            share.Link.ID,
+				NormalizedURL: share.Link.NormalizedURL,
+				OriginalURL:   share.Link.OriginalURL,
+				Title:         share.Link.Title,
+				Description:   share.Link.Description,
+				OGImageURL:    share.Link.OGImageURL,
+				Sensitive:     share.Link.Sensitive,
+				DeadAt:        share.Link.DeadAt,
+				ImageMissing:  share.Link.ImageMissing,
+				FirstSharedAt: share.Link.FirstSharedAt,
+				Sharers:       []string{},
+			}
+			tallies[share.Link.ID] = t
+		}
+		if key := (dayShare{day, share.Link.ID, share.Sharer}); !seen[key] {
+			seen[key] = true
+			t.ShareCount++
+		}
+		if share.SharedAt.After(t.LastSharedAt) {
+			t.LastSharedAt = share.SharedAt
+		}
+	}
+	m.mu.Unlock()
+
+	links := make([]database.TrendingLink, 0, len(tallies))
+	for _, t := range tallies {
+		if t.ShareCount >= minShares {
+			links = append(links, *t)
+		}
+	}
+	sort.Slice(links, func(i, j int) bool {
+		if links[i].ShareCount != links[j].ShareCount {
+			return links[i].ShareCount > links[j].ShareCount
+		}
+		if !links[i].LastSharedAt.Equal(links[j].LastSharedAt) {
+			return links[i].LastSharedAt.After(links[j].LastSharedAt)
+		}
+		return links[i].ID < links[j].ID
+	})
+	if len(links) > limit {
+		links = links[:limit]
+	}
+	return links, nil
+}
+
+// GetDomainShares implements LinkSource. Shares are counted per post, like
+// the SQL version.
+func (m *MemorySource) GetDomainShares(hoursBack int, minRatio float64) ([]database.DomainShare, error) {
+	counts := make(map[string]int)
+	total := 0
+	for _, share := range m.window(hoursBack) {
+		domain := urlutil.Domain(share.Link.NormalizedURL)
+		if domain == "" {
+			continue
+		}
+		counts[domain]++
+		total++
+	}
+
+	var domains []database.DomainShare
+	for domain, shares := range counts {
+		ratio := float64(shares) / float64(total)
+		if ratio >= minRatio {
+			domains = append(domains, database.DomainShare{Domain: domain, Shares: shares, Ratio: ratio})
+		}
+	}
+	sort.Slice(domains, func(i, j int) bool {
+		if domains[i].Shares != domains[j].Shares {
+			return domains[i].Shares > domains[j].Shares
+		}
+		return domains[i].Domain < domains[j].Domain
+	})
+	return domains, nil
+}
+
+// sharesLang reports whether a post's languages include any of langs
+func sharesLang(postLangs, langs []string) bool {
+	for _, lang := range postLangs {
+		for _, want := range langs {
+			if lang == want {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,321 @@
+package aggregator
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/calendar"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/config"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/database"
+)
+
+// Defaults and bounds for trending query parameters
+const (
+	DefaultTrendingHours = 24
+	DefaultTrendingLimit = 50
+	MaxTrendingHours     = 720
+	MaxTrendingLimit     = 100
+)
+
+// Calendar windows, measured in the query's timezone from daily rollups
+const (
+	WindowToday     = "today"
+	WindowYesterday = "yesterday"
+	WindowWeek      = "week" // Monday through today
+)
+
+// TrendingQuery is a trending request with every filter resolved: defaults
+// come from config, request parameters override them. Every handler serving
+// trending links parses its input into one, so filters behave the same in
+// each output format.
+type TrendingQuery struct {
+	Hours            int    // Time window
+	Window           string // Calendar window replacing Hours (see Window*); "" = rolling hours
+	Limit            int    // Maximum links returned
+	Degree           int    // 0 = all, 1 = 1st-degree only, 2 = 2nd-degree only
+	Cohort           string // Cohort name; callers resolve it to Options.CohortID
+	IncludeSensitive bool   // Return real preview images for sensitive links
+
+	CoordinationThreshold int // Copied shares at which a link is flagged as coordinated (-1 = never)
+
+	Location *time.Location // Timezone of calendar windows (nil = UTC)
+
+	Ranking string // Configured ranking strategy (see Ranking*); "" = shares
+
+	Options      database.TrendingOptions
+	Undiscovered UndiscoveredOptions
+}
+
+// QueryError reports an invalid trending parameter. Its message is suitable
+// for returning to clients.
+type QueryError struct {
+	Param string
+	Hint  string // Accepted values
+}
+
+func (e *QueryError) Error() string {
+	return fmt.Sprintf("Invalid %s parameter (%s)", e.Param, e.Hint)
+}
+
+// NewTrendingQuery returns a query with the default window and limit and
+// the configured trending defaults
+func NewTrendingQuery(cfg config.TrendingConfig) TrendingQuery {
+	return TrendingQuery{
+		Hours: DefaultTrendingHours,
+		Limit: DefaultTrendingLimit,
+		Options: database.TrendingOptions{
+			ReplyMode:       cfg.ReplyMode,
+			ReplyWeight:     cfg.ReplyWeight,
+			LabelMode:       cfg.LabelMode,
+			FlaggedLabels:   cfg.FlaggedLabels,
+			LabelThreshold:  cfg.LabelThreshold,
+			SelfPromoMode:   cfg.SelfPromoMode,
+			SelfPromoWeight: cfg.SelfPromoWeight,
+			PersonalDomains: cfg.PersonalDomains,
+			CollapseCopies:  cfg.CollapseCopies,
+			HideDead:        cfg.HideDead,
+			MinShares:       cfg.MinShares,
+			PinnedWeight:    cfg.PinnedWeight,
+		},
+		CoordinationThreshold: cfg.CoordinationThreshold,
+		Ranking:               cfg.Ranking,
+		Undiscovered: UndiscoveredOptions{
+			Mode:       cfg.UndiscoveredMode,
+			Domains:    cfg.MainstreamDomains,
+			ShareRatio: cfg.MainstreamShareRatio,
+			Penalty:    cfg.UndiscoveredPenalty,
+		},
+	}
+}
+
+// Parse overrides the query with request parameters (hours, window, limit,
+// degree, min_shares, replies, labels, self_promo, copies, dead,
+// undiscovered, cohort, lang, sharer_type, include_sensitive, cursor) and
+// validates the result
+func (q *TrendingQuery) Parse(values url.Values) error {
+	var err error
+	if v := values.Get("window"); v != "" {
+		q.Window = v
+		// Rollups only keep per-link counts, so per-share filters and
+		// weightings can't apply
+		for _, param := range []string{"hours", "degree", "cohort", "lang", "sharer_type", "replies", "labels", "self_promo", "copies"} {
+			if values.Get(param) != "" {
+				return &QueryError{param, "not available with window"}
+			}
+		}
+	}
+	if q.Hours, err = parseInt(values, "hours", q.Hours); err != nil {
+		return &QueryError{"hours", fmt.Sprintf("1-%d", MaxTrendingHours)}
+	}
+	if q.Limit, err = parseInt(values, "limit", q.Limit); err != nil {
+		return &QueryError{"limit", fmt.Sprintf("1-%d", MaxTrendingLimit)}
+	}
+	if q.Degree, err = parseInt(values, "degree", q.Degree); err != nil {
+		return &QueryError{"degree", "0=all, 1=1st-degree, 2=2nd-degree"}
+	}
+	if q.Options.MinShares, err = parseInt(values, "min_shares", q.Options.MinShares); err != nil {
+		return &QueryError{"min_shares", "0 or more"}
+	}
+
+	if v := values.Get("replies"); v != "" {
+		q.Options.ReplyMode = v
+	}
+	if v := values.Get("labels"); v != "" {
+		q.Options.LabelMode = v
+	}
+	if v := values.Get("self_promo"); v != "" {
+		q.Options.SelfPromoMode = v
+	}
+	switch values.Get("copies") {
+	case "":
+	case "collapse":
+		q.Options.CollapseCopies = true
+	case "count":
+		q.Options.CollapseCopies = false
+	default:
+		return &QueryError{"copies", "collapse, count"}
+	}
+	switch values.Get("dead") {
+	case "":
+	case "hide":
+		q.Options.HideDead = true
+	case "show":
+		q.Options.HideDead = false
+	default:
+		return &QueryError{"dead", "hide, show"}
+	}
+	if v := values.Get("undiscovered"); v != "" {
+		q.Undiscovered.Mode = v
+	}
+	if v := values.Get("cohort"); v != "" {
+		q.Cohort = v
+	}
+	if v := values.Get("lang"); v != "" {
+		// Comma-separated language tags; matching uses primary subtags
+		q.Options.Langs = database.NormalizeLangs(strings.Split(v, ","))
+		if len(q.Options.Langs) == 0 {
+			return &QueryError{"lang", "comma-separated language codes, e.g. en,de"}
+		}
+	}
+	if v := values.Get("sharer_type"); v != "" {
+		// Comma-separated account types
+		q.Options.SharerTypes = strings.Split(v, ",")
+		for _, t := range q.Options.SharerTypes {
+			if !database.IsAccountType(t) {
+				return &QueryError{"sharer_type", "comma-separated news_org, journalist, individual"}
+			}
+		}
+	}
+	if v := values.Get("include_sensitive"); v != "" {
+		q.IncludeSensitive = v == "true"
+	}
+	if v := values.Get("cursor"); v != "" {
+		if q.Options.After, err = database.ParseTrendingCursor(v); err != nil {
+			return &QueryError{"cursor", "a next_cursor from an earlier page"}
+		}
+	}
+
+	return q.Validate()
+}
+
+// Validate checks every field is in range
+func (q *TrendingQuery) Validate() error {
+	if q.Hours < 1 || q.Hours > MaxTrendingHours {
+		return &QueryError{"hours", fmt.Sprintf("1-%d", MaxTrendingHours)}
+	}
+	if q.Limit < 1 || q.Limit > MaxTrendingLimit {
+		return &QueryError{"limit", fmt.Sprintf("1-%d", MaxTrendingLimit)}
+	}
+	if q.Degree < 0 || q.Degree > 2 {
+		return &QueryError{"degree", "0=all, 1=1st-degree, 2=2nd-degree"}
+	}
+	if q.Options.MinShares < 0 {
+		return &QueryError{"min_shares", "0 or more"}
+	}
+	switch q.Window {
+	case "", WindowToday, WindowYesterday, WindowWeek:
+	default:
+		return &QueryError{"window", "today, yesterday, week"}
+	}
+	if !q.Pageable() && q.Options.After != nil {
+		return &QueryError{"cursor", "only available with the shares ranking, without window or undiscovered"}
+	}
+
+	switch q.Options.ReplyMode {
+	case database.ReplyModeInclude, database.ReplyModeExclude, database.ReplyModeDownweight:
+	default:
+		return &QueryError{"replies", "include, exclude, downweight"}
+	}
+	switch q.Options.LabelMode {
+	case database.LabelModeOff, database.LabelModeFlag, database.LabelModeExclude:
+	default:
+		return &QueryError{"labels", "off, flag, exclude"}
+	}
+	switch q.Options.SelfPromoMode {
+	case database.SelfPromoModeOff, database.SelfPromoModeExclude, database.SelfPromoModeDownweight:
+	default:
+		return &QueryError{"self_promo", "off, exclude, downweight"}
+	}
+	switch q.Undiscovered.Mode {
+	case UndiscoveredOff, UndiscoveredDownrank, UndiscoveredExclude:
+	default:
+		return &QueryError{"undiscovered", "off, downrank, exclude"}
+	}
+	return nil
+}
+
+// parseInt returns the integer parameter name, or def if it is absent
+func parseInt(values url.Values, name string, def int) (int, error) {
+	v := values.Get(name)
+	if v == "" {
+		return def, nil
+	}
+	return strconv.Atoi(v)
+}
+
+// ForTopic restricts the query to a topic, replacing Hours with the
+// topic's run so far. Calendar windows can't be combined with it.
+func (q *TrendingQuery) ForTopic(topic *database.Topic, now time.Time) error {
+	if q.Window != "" {
+		return &QueryError{"window", "not available for topics"}
+	}
+	q.Hours, q.Options = topic.TrendingOptions(q.Options, now)
+	return nil
+}
+
+// Flagged reports whether a link returned for this query should be flagged
+// as predominantly shared by labeled posts or accounts
+func (q *TrendingQuery) Flagged(link database.TrendingLink) bool {
+	return q.Options.LabelMode == database.LabelModeFlag &&
+		link.LabeledShareRatio >= q.Options.LabelThreshold && link.LabeledShareRatio > 0
+}
+
+// Coordinated reports whether a link returned for this query has enough
+// copied shares to suggest a coordinated campaign
+func (q *TrendingQuery) Coordinated(link database.TrendingLink) bool {
+	return q.CoordinationThreshold > 0 && link.CopiedShares >= q.CoordinationThreshold
+}
+
+// Pageable reports whether the query's results can be paged with cursors.
+// Calendar windows rank rollups, and undiscovered mode and the click,
+// recency and velocity rankings re-rank a larger candidate set in Go, so
+// none of them follow the SQL keyset.
+func (q *TrendingQuery) Pageable() bool {
+	return q.Window == "" && q.Undiscovered.Mode == UndiscoveredOff &&
+		(q.Ranking == "" || q.Ranking == RankingShares)
+}
+
+// NextCursor returns the cursor for the page after links, the results of
+// this query, or "" if they are the last page. Ranking strategies may
+// reorder a page, so the cursor is the lowest sort key rather than the last
+// link.
+func (q *TrendingQuery) NextCursor(links []database.TrendingLink) string {
+	if !q.Pageable() || len(links) < q.Limit {
+		return ""
+	}
+	last := database.CursorFor(links[0])
+	for _, link := range links[1:] {
+		if c := database.CursorFor(link); last.Before(c) {
+			last = c
+		}
+	}
+	return last.Encode()
+}
+
+// WindowDays returns the first and last calendar day of the query's window
+// as of now, as dates at midnight UTC
+func (q *TrendingQuery) WindowDays(now time.Time) (first, last time.Time) {
+	loc := q.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+	today := calendar.Date(now, loc)
+
+	switch q.Window {
+	case WindowYesterday:
+		yesterday := today.AddDate(0, 0, -1)
+		return yesterday, yesterday
+	case WindowWeek:
+		sinceMonday := (int(today.Weekday()) + 6) % 7
+		return today.AddDate(0, 0, -sinceMonday), today
+	default:
+		return today, today
+	}
+}
+
+// Trending returns the ranked trending links for a query. The cohort must
+// already be resolved into Options.CohortID. Calendar windows are served
+// from daily rollups, which carry no sharer details.
+func (a *Aggregator) Trending(q TrendingQuery) ([]database.TrendingLink, error) {
+	if q.Window != "" {
+		first, last := q.WindowDays(time.Now())
+		return a.db.GetTrendingLinksForDays(first, last, q.Limit, q.Options.MinShares, q.Options.HideDead)
+	}
+	if q.Undiscovered.Mode != UndiscoveredOff {
+		return a.GetUndiscoveredLinks(q.Hours, q.Limit, q.Degree, q.Options, q.Undiscovered)
+	}
+	return a.GetTrendingLinksByDegree(q.Hours, q.Limit, q.Degree, q.Options)
+}
@@ -0,0 +1,127 @@
+package aggregator
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/config"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/database"
+)
+
+// testTrendingConfig is the shipped trending defaults
+var testTrendingConfig = config.TrendingConfig{
+	ReplyMode:        database.ReplyModeInclude,
+	LabelMode:        "off",
+	SelfPromoMode:    "off",
+	UndiscoveredMode: UndiscoveredOff,
+}
+
+// TestParseWindowParams covers which parameters combine with window
+func TestParseWindowParams(t *testing.T) {
+	tests := []struct {
+		query   string
+		wantErr string // Rejected parameter, "" = accepted
+	}{
+		{"window=today", ""},
+		{"window=week&min_shares=3&dead=hide&limit=10", ""},
+		{"window=today&hours=6", "hours"},
+		{"window=today&degree=1", "degree"},
+		{"window=today&lang=en", "lang"},
+		{"window=today&replies=exclude", "replies"},
+		{"window=today&labels=exclude", "labels"},
+		{"window=yesterday&self_promo=exclude", "self_promo"},
+		{"window=week&copies=count", "copies"},
+		{"replies=exclude&copies=count", ""},
+	}
+
+	for _, tt := range tests {
+		values, err := url.ParseQuery(tt.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		q := NewTrendingQuery(testTrendingConfig)
+		err = q.Parse(values)
+		switch qerr, _ := err.(*QueryError); {
+		case tt.wantErr == "" && err != nil:
+			t.Errorf("Parse(%s): %v", tt.query, err)
+		case tt.wantErr != "" && (qerr == nil || qerr.Param != tt.wantErr):
+			t.Errorf("Parse(%s) = %v, want %s rejected", tt.query, err, tt.wantErr)
+		}
+	}
+}
+
+// TestTrendingWindowHideDead checks dead links are left out before the
+// limit, so a window page still fills up with live links
+func TestTrendingWindowHideDead(t *testing.T) {
+	now := time.Now().UTC()
+	dead := now.Add(-time.Hour)
+	src := NewMemorySource()
+	for i, sharers := range []int{5, 4, 3} {
+		link := database.Link{ID: i + 1, NormalizedURL: "https://example.com/" + string(rune('a'+i))}
+		if i == 0 {
+			link.DeadAt = &dead
+		}
+		for j := 0; j < sharers; j++ {
+			src.Add(Share{Link: link, Sharer: "did:plc:" + string(rune('a'+j)), SharedAt: now})
+		}
+	}
+
+	q := NewTrendingQuery(testTrendingConfig)
+	if err := q.Parse(url.Values{"window": {WindowToday}, "limit": {"2"}, "dead": {"hide"}}); err != nil {
+		t.Fatal(err)
+	}
+	links, err := NewAggregator(src, nil).Trending(q)
+	if err != nil {
+		t.Fatalf("Trending: %v", err)
+	}
+	if got := ids(links); len(got) != 2 || got[0] != 2 || got[1] != 3 {
+		t.Errorf("Trending = %v, want [2 3]", got)
+	}
+}
+
+// TestPageable checks cursors are only offered where pages follow the SQL
+// keyset
+func TestPageable(t *testing.T) {
+	tests := []struct {
+		name    string
+		ranking string
+		query   string
+		want    bool
+	}{
+		{"default", "", "", true},
+		{"shares", RankingShares, "", true},
+		{"clicks", RankingClicks, "", false},
+		{"recency", RankingRecency, "", false},
+		{"velocity", RankingVelocity, "", false},
+		{"window", RankingShares, "window=today", false},
+		{"undiscovered", RankingShares, "undiscovered=downrank", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testTrendingConfig
+			cfg.Ranking = tt.ranking
+			q := NewTrendingQuery(cfg)
+			values, _ := url.ParseQuery(tt.query)
+			if err := q.Parse(values); err != nil {
+				t.Fatal(err)
+			}
+			if got := q.Pageable(); got != tt.want {
+				t.Errorf("Pageable() = %v, want %v", got, tt.want)
+			}
+
+			q.Limit = 1
+			links := []database.TrendingLink{{ID: 1, Score: 2, ShareCount: 2, LastSharedAt: time.Now()}}
+			if cursor := q.NextCursor(links); (cursor != "") != tt.want {
+				t.Errorf("NextCursor() = %q, want a cursor only when pageable", cursor)
+			}
+
+			values.Set("cursor", database.CursorFor(links[0]).Encode())
+			q = NewTrendingQuery(cfg)
+			if err := q.Parse(values); (err == nil) != tt.want {
+				t.Errorf("Parse with cursor: %v", err)
+			}
+		})
+	}
+}
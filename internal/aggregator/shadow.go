@@ -0,0 +1,130 @@
+package aggregator
+
+import (
+	"fmt"
+	"math/rand"
+
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/database"
+)
+
+// shadowTopN is how many top links TopOverlap compares
+const shadowTopN = 10
+
+// NewRanking returns the named ranking strategy (see Ranking*)
+func NewRanking(name string, clickWeight float64) (RankingStrategy, error) {
+	switch name {
+	case RankingShares:
+		return &ShareCountRanking{}, nil
+	case RankingClicks:
+		return &ClickWeightedRanking{Weight: clickWeight}, nil
+	case RankingRecency:
+		return &RecencyWeightedRanking{}, nil
+	case RankingVelocity:
+		return &VelocityRanking{}, nil
+	default:
+		return nil, fmt.Errorf("unknown ranking %q (shares, clicks, recency or velocity)", name)
+	}
+}
+
+// RankingComparison measures how far a shadow strategy's order diverges
+// from the served one for the same links
+type RankingComparison struct {
+	Primary    string // Strategy names
+	Shadow     string
+	Links      int     // Links both strategies ranked
+	KendallTau float64 // 1 = same order, -1 = reversed
+	TopOverlap float64 // Fraction of the top 10 both strategies share
+	PrimaryIDs []int
+	ShadowIDs  []int
+}
+
+// ShadowConfig runs a second ranking strategy alongside the served one
+type ShadowConfig struct {
+	Primary    string // Name of the served strategy, for the record
+	Shadow     string // Name of the shadow strategy
+	Ranker     RankingStrategy
+	SampleRate float64                 // Fraction of requests also ranked in shadow (0-1)
+	Record     func(RankingComparison) // Called with each comparison; must not block
+}
+
+// SetShadow ranks a sample of trending requests with a second strategy
+// too, reporting how the orders differ without changing what is served.
+// nil turns shadow evaluation off.
+func (a *Aggregator) SetShadow(config *ShadowConfig) {
+	a.shadow = config
+}
+
+// shadowRank ranks candidates with the shadow strategy when the request
+// is sampled and records how its top len(ranked) compares to ranked
+func (a *Aggregator) shadowRank(candidates, ranked []database.TrendingLink) {
+	if a.shadow == nil || len(ranked) < 2 || rand.Float64() >= a.shadow.SampleRate {
+		return
+	}
+	shadowed := a.shadow.Ranker.Rank(append([]database.TrendingLink(nil), candidates...))
+	if len(shadowed) > len(ranked) {
+		shadowed = shadowed[:len(ranked)]
+	}
+
+	comparison := CompareRankings(linkIDs(ranked), linkIDs(shadowed))
+	comparison.Primary = a.shadow.Primary
+	comparison.Shadow = a.shadow.Shadow
+	a.shadow.Record(comparison)
+}
+
+// CompareRankings computes Kendall's tau over the links both orders contain
+// and the overlap of their top 10
+func CompareRankings(primary, shadow []int) RankingComparison {
+	comparison := RankingComparison{PrimaryIDs: primary, ShadowIDs: shadow}
+
+	position := make(map[int]int, len(shadow))
+	for i, id := range shadow {
+		position[id] = i
+	}
+	var common []int // Shadow positions, in primary order
+	for _, id := range primary {
+		if pos, ok := position[id]; ok {
+			common = append(common, pos)
+		}
+	}
+	comparison.Links = len(common)
+
+	if n := len(common); n >= 2 {
+		concordant, discordant := 0, 0
+		for i := 0; i < n; i++ {
+			for j := i + 1; j < n; j++ {
+				if common[i] < common[j] {
+					concordant++
+				} else {
+					discordant++
+				}
+			}
+		}
+		comparison.KendallTau = float64(concordant-discordant) / float64(n*(n-1)/2)
+	} else {
+		comparison.KendallTau = 1
+	}
+
+	top := min(shadowTopN, len(primary), len(shadow))
+	if top > 0 {
+		inShadowTop := make(map[int]bool, top)
+		for _, id := range shadow[:top] {
+			inShadowTop[id] = true
+		}
+		shared := 0
+		for _, id := range primary[:top] {
+			if inShadowTop[id] {
+				shared++
+			}
+		}
+		comparison.TopOverlap = float64(shared) / float64(top)
+	}
+	return comparison
+}
+
+func linkIDs(links []database.TrendingLink) []int {
+	ids := make([]int, len(links))
+	for i, link := range links {
+		ids[i] = link.ID
+	}
+	return ids
+}
@@ -0,0 +1,180 @@
+package anonymize
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/database"
+)
+
+// FormatVersion is bumped when the dataset layout changes incompatibly
+const FormatVersion = 1
+
+// Manifest describes an exported dataset
+type Manifest struct {
+	Version    int            `json:"version"`
+	CreatedAt  time.Time      `json:"created_at"`
+	Since      time.Time      `json:"since"`      // Shares posted after this are included
+	Resolution string         `json:"resolution"` // Precision of every timestamp
+	Files      map[string]int `json:"files"`      // Record count per file
+}
+
+// Account is an account in the network
+type Account struct {
+	Account     string `json:"account"`
+	Degree      int    `json:"degree"`       // 1 = followed directly, 2 = followed by a 1st-degree account
+	SourceCount int    `json:"source_count"` // 1st-degree accounts following it
+}
+
+// Follow is an edge of the network graph: follower follows followee
+type Follow struct {
+	Follower string `json:"follower"`
+	Followee string `json:"followee"`
+}
+
+// Link is a shared link, reduced to its domain
+type Link struct {
+	Link        string    `json:"link"`
+	Domain      string    `json:"domain"`
+	FirstSeenAt time.Time `json:"first_seen_at"`
+}
+
+// Share is one account sharing one link
+type Share struct {
+	Account  string    `json:"account"`
+	Link     string    `json:"link"`
+	SharedAt time.Time `json:"shared_at"`
+	Relation string    `json:"relation"`         // original, quote or repost
+	Degree   int       `json:"degree,omitempty"` // Sharer's degree when shared (0 = unknown)
+	Reply    bool      `json:"reply,omitempty"`
+}
+
+// Export writes an anonymized dataset of shares posted since the given time
+// to w: a gzipped tar holding manifest.json, accounts.jsonl, follows.jsonl,
+// links.jsonl and shares.jsonl
+func Export(db *database.DB, w io.Writer, a *Anonymizer, since time.Time) (*Manifest, error) {
+	manifest := &Manifest{
+		Version:    FormatVersion,
+		CreatedAt:  a.Time(time.Now()),
+		Since:      a.Time(since),
+		Resolution: a.Resolution().String(),
+		Files:      make(map[string]int),
+	}
+	files := make(map[string]*jsonlBuffer)
+	file := func(name string) *jsonlBuffer {
+		if files[name] == nil {
+			files[name] = &jsonlBuffer{}
+		}
+		return files[name]
+	}
+
+	// Network accounts and the 1st-to-2nd-degree follow edges
+	accounts, err := db.GetAllNetworkAccounts()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get network accounts: %w", err)
+	}
+	for _, account := range accounts {
+		id := a.Account(account.DID)
+		if err := file("accounts.jsonl").add(Account{id, account.Degree, account.SourceCount}); err != nil {
+			return nil, err
+		}
+		if account.SourceDIDs == nil {
+			continue
+		}
+		var sources []string
+		if err := json.Unmarshal([]byte(*account.SourceDIDs), &sources); err != nil {
+			continue // Malformed source list: the account is still exported
+		}
+		for _, source := range sources {
+			if err := file("follows.jsonl").add(Follow{a.Account(source), id}); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// Shares, and each link the first time it is shared
+	seen := make(map[int]bool)
+	err = db.StreamShares(since, func(share database.ShareRecord) error {
+		link := a.Link(share.LinkID)
+		if !seen[share.LinkID] {
+			seen[share.LinkID] = true
+			if err := file("links.jsonl").add(Link{link, a.Domain(share.NormalizedURL), a.Time(share.LinkFirstSeenAt)}); err != nil {
+				return err
+			}
+		}
+		record := Share{
+			Account:  a.Account(share.AuthorDID),
+			Link:     link,
+			SharedAt: a.Time(share.SharedAt),
+			Relation: share.Relation,
+			Reply:    share.IsReply,
+		}
+		if share.Degree != nil {
+			record.Degree = *share.Degree
+		}
+		return file("shares.jsonl").add(record)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	for _, name := range []string{"accounts.jsonl", "follows.jsonl", "links.jsonl", "shares.jsonl"} {
+		buf := file(name)
+		if err := writeFile(tw, name, buf.Bytes(), manifest.CreatedAt); err != nil {
+			return nil, err
+		}
+		manifest.Files[name] = buf.count
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode manifest: %w", err)
+	}
+	if err := writeFile(tw, "manifest.json", data, manifest.CreatedAt); err != nil {
+		return nil, err
+	}
+
+	if err := tw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to finish archive: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return nil, fmt.Errorf("failed to finish archive: %w", err)
+	}
+	return manifest, nil
+}
+
+// jsonlBuffer collects JSON lines, buffered because tar headers need the
+// size up front
+type jsonlBuffer struct {
+	bytes.Buffer
+	count int
+}
+
+func (b *jsonlBuffer) add(record interface{}) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to encode record: %w", err)
+	}
+	b.Write(data)
+	b.WriteByte('\n')
+	b.count++
+	return nil
+}
+
+func writeFile(tw *tar.Writer, name string, data []byte, modTime time.Time) error {
+	header := &tar.Header{Name: name, Mode: 0644, Size: int64(len(data)), ModTime: modTime}
+	if err := tw.WriteHeader(header); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	if _, err := tw.Write(data); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	return nil
+}
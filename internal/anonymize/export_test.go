@@ -0,0 +1,130 @@
+package anonymize_test
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/anonymize"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/testutil"
+)
+
+// readArchive returns each file in a gzipped tar by name
+func readArchive(t *testing.T, data []byte) map[string][]byte {
+	t.Helper()
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("gzip: %v", err)
+	}
+	files := make(map[string][]byte)
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return files
+		}
+		if err != nil {
+			t.Fatalf("tar: %v", err)
+		}
+		if files[header.Name], err = io.ReadAll(tr); err != nil {
+			t.Fatalf("reading %s: %v", header.Name, err)
+		}
+	}
+}
+
+// decodeLines decodes a JSON lines file into records
+func decodeLines[T any](t *testing.T, data []byte) []T {
+	t.Helper()
+	var records []T
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		var record T
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			t.Fatalf("decoding %s: %v", scanner.Text(), err)
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+// TestExport exports seeded shares and checks the dataset keeps timing,
+// degrees and follow edges without raw DIDs or URL paths
+func TestExport(t *testing.T) {
+	if testing.Short() {
+		t.Skip("integration test")
+	}
+	db := testutil.NewTestDB(t) // Skips without TEST_DATABASE_URL or docker
+
+	alice, bob := "did:plc:alice", "did:plc:bob"
+	if err := db.UpsertNetworkAccount(alice, "alice.test", nil, nil, 1, 1, []string{"did:plc:me"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.UpsertNetworkAccount(bob, "bob.test", nil, nil, 2, 1, []string{alice}); err != nil {
+		t.Fatal(err)
+	}
+	sharedAt := time.Now().Add(-30 * time.Minute)
+	testutil.AddShare(t, db, alice, 1, "https://www.example.com/private/report?id=7", sharedAt)
+	testutil.AddShare(t, db, bob, 2, "https://www.example.com/private/report?id=7", sharedAt.Add(time.Minute))
+	testutil.AddShare(t, db, bob, 2, "https://old.example.org/", time.Now().Add(-48*time.Hour)) // Before since
+
+	a := anonymize.New([]byte("test key"), time.Hour)
+	var out bytes.Buffer
+	manifest, err := anonymize.Export(db, &out, a, time.Now().Add(-24*time.Hour))
+	if err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	files := readArchive(t, out.Bytes())
+
+	for name, data := range files {
+		for _, secret := range []string{alice, bob, "alice.test", "private/report", "old.example.org"} {
+			if strings.Contains(string(data), secret) {
+				t.Errorf("%s contains %q", name, secret)
+			}
+		}
+	}
+
+	wantCounts := map[string]int{"accounts.jsonl": 2, "follows.jsonl": 2, "links.jsonl": 1, "shares.jsonl": 2}
+	for name, want := range wantCounts {
+		if got := manifest.Files[name]; got != want {
+			t.Errorf("manifest: %s has %d records, want %d", name, got, want)
+		}
+	}
+	if manifest.Resolution != "1h0m0s" {
+		t.Errorf("manifest resolution = %q, want 1h0m0s", manifest.Resolution)
+	}
+
+	follows := decodeLines[anonymize.Follow](t, files["follows.jsonl"])
+	foundEdge := false
+	for _, follow := range follows {
+		foundEdge = foundEdge || follow.Follower == a.Account(alice) && follow.Followee == a.Account(bob)
+	}
+	if !foundEdge {
+		t.Errorf("follows = %+v, want alice -> bob", follows)
+	}
+
+	links := decodeLines[anonymize.Link](t, files["links.jsonl"])
+	if len(links) != 1 || links[0].Domain != "example.com" {
+		t.Fatalf("links = %+v, want one example.com link", links)
+	}
+
+	shares := decodeLines[anonymize.Share](t, files["shares.jsonl"])
+	degrees := map[string]int{}
+	for _, share := range shares {
+		degrees[share.Account] = share.Degree
+		if !share.SharedAt.Equal(a.Time(share.SharedAt)) {
+			t.Errorf("shared_at %v is finer than the resolution", share.SharedAt)
+		}
+		if share.Link != links[0].Link {
+			t.Errorf("share of %s, want %s", share.Link, links[0].Link)
+		}
+	}
+	if degrees[a.Account(alice)] != 1 || degrees[a.Account(bob)] != 2 {
+		t.Errorf("share degrees = %v, want alice 1 and bob 2", degrees)
+	}
+}
@@ -0,0 +1,71 @@
+// Package atutil handles AT Protocol identifiers: at:// record URIs and
+// their bsky.app web URLs.
+package atutil
+
+import (
+	"fmt"
+	"strings"
+)
+
+// PostCollection is the collection of Bluesky posts
+const PostCollection = "app.bsky.feed.post"
+
+// webBase is the Bluesky web app that post and profile URLs point to
+const webBase = "https://bsky.app"
+
+// URI is a parsed at:// record URI (at://{authority}/{collection}/{rkey})
+type URI struct {
+	Authority  string // DID or handle of the repo
+	Collection string // NSID, e.g. app.bsky.feed.post
+	RKey       string // Record key
+}
+
+// ParseURI parses an at:// URI naming a record. Query strings and fragments
+// aren't part of record URIs and are rejected.
+func ParseURI(uri string) (URI, error) {
+	rest, ok := strings.CutPrefix(uri, "at://")
+	if !ok {
+		return URI{}, fmt.Errorf("not an at:// URI: %q", uri)
+	}
+	if strings.ContainsAny(rest, "?#") {
+		return URI{}, fmt.Errorf("at:// URI with query or fragment: %q", uri)
+	}
+
+	parts := strings.Split(rest, "/")
+	if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+		return URI{}, fmt.Errorf("at:// URI without authority, collection and record key: %q", uri)
+	}
+	return URI{Authority: parts[0], Collection: parts[1], RKey: parts[2]}, nil
+}
+
+// String formats the URI as at://{authority}/{collection}/{rkey}
+func (u URI) String() string {
+	return "at://" + u.Authority + "/" + u.Collection + "/" + u.RKey
+}
+
+// IsPost reports whether the URI names a Bluesky post
+func (u URI) IsPost() bool {
+	return u.Collection == PostCollection
+}
+
+// PostURL turns a post's at:// URI into its bsky.app URL, under handle when
+// it is known (more readable) or the URI's DID otherwise. It returns "" if
+// the URI isn't a post.
+func PostURL(uri, handle string) string {
+	u, err := ParseURI(uri)
+	if err != nil || !u.IsPost() {
+		return ""
+	}
+	return ProfileURL(handle, u.Authority) + "/post/" + u.RKey
+}
+
+// ProfileURL returns an account's bsky.app URL, under handle when it is a
+// handle or did otherwise. Stored handles fall back to the DID when the
+// account's handle was never resolved.
+func ProfileURL(handle, did string) string {
+	actor := did
+	if handle != "" && !strings.HasPrefix(handle, "did:") && !strings.Contains(handle, "/") {
+		actor = handle
+	}
+	return webBase + "/profile/" + actor
+}
@@ -0,0 +1,82 @@
+package atutil
+
+import "testing"
+
+func TestParseURI(t *testing.T) {
+	tests := []struct {
+		uri     string
+		want    URI
+		wantErr bool
+	}{
+		{uri: "at://did:plc:abc/app.bsky.feed.post/3kxyz", want: URI{"did:plc:abc", PostCollection, "3kxyz"}},
+		{uri: "at://alice.bsky.social/app.bsky.feed.post/3kxyz", want: URI{"alice.bsky.social", PostCollection, "3kxyz"}},
+		{uri: "at://did:plc:abc/app.bsky.feed.like/3kxyz", want: URI{"did:plc:abc", "app.bsky.feed.like", "3kxyz"}},
+		{uri: "https://bsky.app/profile/did:plc:abc/post/3kxyz", wantErr: true},
+		{uri: "at://did:plc:abc/app.bsky.feed.post", wantErr: true},         // No rkey
+		{uri: "at://did:plc:abc/app.bsky.feed.post/", wantErr: true},        // Empty rkey
+		{uri: "at://did:plc:abc/app.bsky.feed.post/3kxyz/x", wantErr: true}, // Extra segment
+		{uri: "at:///app.bsky.feed.post/3kxyz", wantErr: true},              // No authority
+		{uri: "at://did:plc:abc//3kxyz", wantErr: true},                     // No collection
+		{uri: "at://did:plc:abc/app.bsky.feed.post/3kxyz?x=1", wantErr: true},
+		{uri: "at://did:plc:abc/app.bsky.feed.post/3kxyz#frag", wantErr: true},
+		{uri: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseURI(tt.uri)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseURI(%q) error = %v, want error %v", tt.uri, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseURI(%q) = %+v, want %+v", tt.uri, got, tt.want)
+		}
+		if err == nil && got.String() != tt.uri {
+			t.Errorf("ParseURI(%q).String() = %q", tt.uri, got.String())
+		}
+	}
+}
+
+func TestPostURL(t *testing.T) {
+	const uri = "at://did:plc:abc/app.bsky.feed.post/3kxyz"
+
+	tests := []struct {
+		name   string
+		uri    string
+		handle string
+		want   string
+	}{
+		{"handle", uri, "alice.bsky.social", "https://bsky.app/profile/alice.bsky.social/post/3kxyz"},
+		{"no handle", uri, "", "https://bsky.app/profile/did:plc:abc/post/3kxyz"},
+		{"handle is a DID", uri, "did:plc:abc", "https://bsky.app/profile/did:plc:abc/post/3kxyz"},
+		{"handle with a slash", uri, "alice/evil", "https://bsky.app/profile/did:plc:abc/post/3kxyz"},
+		{"handle authority", "at://alice.bsky.social/app.bsky.feed.post/3kxyz", "", "https://bsky.app/profile/alice.bsky.social/post/3kxyz"},
+		{"not a post", "at://did:plc:abc/app.bsky.feed.like/3kxyz", "alice.bsky.social", ""},
+		{"missing rkey", "at://did:plc:abc/app.bsky.feed.post", "alice.bsky.social", ""},
+		{"malformed", "https://bsky.app/profile/alice.bsky.social/post/3kxyz", "alice.bsky.social", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := PostURL(tt.uri, tt.handle); got != tt.want {
+				t.Errorf("PostURL(%q, %q) = %q, want %q", tt.uri, tt.handle, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProfileURL(t *testing.T) {
+	tests := []struct {
+		handle, did, want string
+	}{
+		{"alice.bsky.social", "did:plc:abc", "https://bsky.app/profile/alice.bsky.social"},
+		{"", "did:plc:abc", "https://bsky.app/profile/did:plc:abc"},
+		{"did:plc:abc", "did:plc:abc", "https://bsky.app/profile/did:plc:abc"},
+	}
+
+	for _, tt := range tests {
+		if got := ProfileURL(tt.handle, tt.did); got != tt.want {
+			t.Errorf("ProfileURL(%q, %q) = %q, want %q", tt.handle, tt.did, got, tt.want)
+		}
+	}
+}
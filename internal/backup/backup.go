@@ -0,0 +1,204 @@
+// Package backup writes aggregator state to a portable archive and restores
+// it, so a deployment can move machines or recover without replaying weeks
+// of firehose.
+//
+// An archive is a gzipped tar holding manifest.json and one <table>.jsonl
+// file per table, each line a row as a JSON object keyed by column name.
+package backup
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/database"
+)
+
+// FormatVersion is bumped when the archive layout changes incompatibly
+const FormatVersion = 1
+
+const manifestName = "manifest.json"
+
+// Manifest describes an archive's contents
+type Manifest struct {
+	Version   int            `json:"version"`
+	CreatedAt time.Time      `json:"created_at"`
+	Tables    map[string]int `json:"tables"` // Row count per table
+}
+
+// Write exports every backup table to w as an archive
+func Write(db *database.DB, w io.Writer) (*Manifest, error) {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	manifest := &Manifest{
+		Version:   FormatVersion,
+		CreatedAt: time.Now().UTC(),
+		Tables:    make(map[string]int),
+	}
+
+	for _, table := range database.BackupTables {
+		// Rows are buffered per table because tar headers need the size up front
+		var buf bytes.Buffer
+		count := 0
+		err := db.ExportTableRows(table, func(row json.RawMessage) error {
+			buf.Write(row)
+			buf.WriteByte('\n')
+			count++
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := writeFile(tw, table.Name+".jsonl", buf.Bytes(), manifest.CreatedAt); err != nil {
+			return nil, err
+		}
+		manifest.Tables[table.Name] = count
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode manifest: %w", err)
+	}
+	if err := writeFile(tw, manifestName, data, manifest.CreatedAt); err != nil {
+		return nil, err
+	}
+
+	if err := tw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to finish archive: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return nil, fmt.Errorf("failed to finish archive: %w", err)
+	}
+	return manifest, nil
+}
+
+func writeFile(tw *tar.Writer, name string, data []byte, modTime time.Time) error {
+	header := &tar.Header{Name: name, Mode: 0644, Size: int64(len(data)), ModTime: modTime}
+	if err := tw.WriteHeader(header); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	if _, err := tw.Write(data); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	return nil
+}
+
+// RestoreOptions controls a restore
+type RestoreOptions struct {
+	// Merge allows restoring into tables that already hold rows; rows that
+	// conflict with existing ones are skipped
+	Merge bool
+}
+
+// RestoreResult reports rows inserted and skipped per table
+type RestoreResult struct {
+	Manifest *Manifest
+	Inserted map[string]int
+	Skipped  map[string]int
+}
+
+// Restore imports an archive read from r in a single transaction. Unless
+// opts.Merge is set, the target tables must be empty (a freshly migrated
+// database), since restored rows keep their IDs.
+func Restore(db *database.DB, r io.Reader, opts RestoreOptions) (*RestoreResult, error) {
+	files, err := readArchive(r)
+	if err != nil {
+		return nil, err
+	}
+
+	data, ok := files[manifestName]
+	if !ok {
+		return nil, errors.New("archive has no manifest.json")
+	}
+	var manifest Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("invalid manifest: %w", err)
+	}
+	if manifest.Version > FormatVersion {
+		return nil, fmt.Errorf("archive format %d is newer than supported (%d)", manifest.Version, FormatVersion)
+	}
+
+	if !opts.Merge {
+		for _, table := range database.BackupTables {
+			count, err := db.CountTableRows(table.Name)
+			if err != nil {
+				return nil, fmt.Errorf("failed to count %s: %w", table.Name, err)
+			}
+			if count > 0 {
+				return nil, fmt.Errorf("table %s is not empty (%d rows); restore into a fresh database or merge", table.Name, count)
+			}
+		}
+	}
+
+	restore, err := db.BeginRestore()
+	if err != nil {
+		return nil, err
+	}
+	for _, table := range database.BackupTables {
+		data, ok := files[table.Name+".jsonl"]
+		if !ok {
+			continue // Table added after the archive was made
+		}
+		scanner := bufio.NewScanner(bytes.NewReader(data))
+		scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+		for scanner.Scan() {
+			line := bytes.TrimSpace(scanner.Bytes())
+			if len(line) == 0 {
+				continue
+			}
+			if err := restore.InsertRow(table.Name, line); err != nil {
+				restore.Rollback()
+				return nil, err
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			restore.Rollback()
+			return nil, fmt.Errorf("failed to read %s: %w", table.Name, err)
+		}
+	}
+	if err := restore.Commit(); err != nil {
+		return nil, err
+	}
+
+	inserted, skipped := restore.Counts()
+	return &RestoreResult{Manifest: &manifest, Inserted: inserted, Skipped: skipped}, nil
+}
+
+// readArchive returns the contents of every regular file in the archive
+func readArchive(r io.Reader) (map[string][]byte, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("not a backup archive: %w", err)
+	}
+	defer gz.Close()
+
+	files := make(map[string][]byte)
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read archive: %w", err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", header.Name, err)
+		}
+		files[strings.TrimPrefix(path.Clean(header.Name), "./")] = data
+	}
+	return files, nil
+}
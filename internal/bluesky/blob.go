@@ -0,0 +1,62 @@
+package bluesky
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// cdnBaseURL serves images stored as blobs in users' repositories
+const cdnBaseURL = "https://cdn.bsky.app/img"
+
+var (
+	// cidV1Pattern matches a base32 CIDv1, the form blob refs use
+	cidV1Pattern = regexp.MustCompile(`^b[a-z2-7]{50,}$`)
+	// cidV0Pattern matches a legacy base58 CIDv0
+	cidV0Pattern = regexp.MustCompile(`^Qm[1-9A-HJ-NP-Za-km-z]{44}$`)
+	// didPattern matches a DID such as did:plc:abc123 or did:web:example.com
+	didPattern = regexp.MustCompile(`^did:[a-z]+:[A-Za-z0-9._:%-]+$`)
+)
+
+// IsValidCID reports whether cid looks like a CID, so it's safe to put in a URL
+func IsValidCID(cid string) bool {
+	return cidV1Pattern.MatchString(cid) || cidV0Pattern.MatchString(cid)
+}
+
+// BlobCDNURL returns the CDN URL of an image blob in did's repository, sized
+// by preset (e.g. "feed_thumbnail"). Returns "" if the DID or CID is malformed.
+func BlobCDNURL(preset, did, cid string) string {
+	if !didPattern.MatchString(did) || !IsValidCID(cid) {
+		return ""
+	}
+	return fmt.Sprintf("%s/%s/plain/%s/%s@jpeg", cdnBaseURL, preset, did, cid)
+}
+
+// ThumbURL returns the URL of an external embed's thumbnail. API views give
+// the thumb as a URL; raw records give a blob object, whose CID is turned into
+// a CDN URL under the author's DID. Returns "" for anything else.
+func ThumbURL(thumb interface{}, authorDID string) string {
+	switch t := thumb.(type) {
+	case string:
+		if strings.HasPrefix(t, "https://") || strings.HasPrefix(t, "http://") {
+			return t
+		}
+	case map[string]interface{}:
+		return BlobCDNURL("feed_thumbnail", authorDID, blobCID(t))
+	}
+	return ""
+}
+
+// blobCID extracts the CID from a blob object: {"ref": {"$link": cid}}, or
+// the legacy {"cid": cid}
+func blobCID(blob map[string]interface{}) string {
+	if ref, ok := blob["ref"].(map[string]interface{}); ok {
+		if cid, ok := ref["$link"].(string); ok {
+			return cid
+		}
+	}
+	if cid, ok := blob["cid"].(string); ok {
+		return cid
+	}
+	return ""
+}
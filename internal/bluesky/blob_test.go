@@ -0,0 +1,81 @@
+package bluesky
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+const (
+	testCIDv1 = "bafkreigh2akiscaildcqabsyg3dfr6chu3fgpregiymsck7e7aqa4s52zy"
+	testCIDv0 = "QmYwAPJzv5CZsnA625s3Xf2nemtYgPpHdWEz79ojWnPbdG"
+	testDID   = "did:plc:z72i7hdynmk6r22z27h6tvur"
+)
+
+func TestIsValidCID(t *testing.T) {
+	tests := []struct {
+		cid  string
+		want bool
+	}{
+		{testCIDv1, true},
+		{testCIDv0, true},
+		{"", false},
+		{"bafkrei", false}, // Too short
+		{"BAFKREIGH2AKISCAILDCQABSYG3DFR6CHU3FGPREGIYMSCK7E7AQA4S52ZY", false}, // Base32 CIDs are lowercase
+		{testCIDv1 + "/../../evil", false},
+		{"bafkreigh2akiscaildcqabsyg3dfr6chu3fgpregiymsck7e7aqa4s52z@", false},
+		{"QmYwAPJzv5CZsnA625s3Xf2nemtYgPpHdWEz79ojWnPbd0", false}, // 0 isn't base58
+	}
+	for _, tt := range tests {
+		if got := IsValidCID(tt.cid); got != tt.want {
+			t.Errorf("IsValidCID(%q) = %v, want %v", tt.cid, got, tt.want)
+		}
+	}
+}
+
+func TestBlobCDNURL(t *testing.T) {
+	tests := []struct {
+		name     string
+		did, cid string
+		want     string
+	}{
+		{"plc", testDID, testCIDv1, "https://cdn.bsky.app/img/feed_thumbnail/plain/" + testDID + "/" + testCIDv1 + "@jpeg"},
+		{"web", "did:web:example.com", testCIDv0, "https://cdn.bsky.app/img/feed_thumbnail/plain/did:web:example.com/" + testCIDv0 + "@jpeg"},
+		{"bad cid", testDID, "not-a-cid", ""},
+		{"bad did", "alice.bsky.social", testCIDv1, ""},
+		{"did with path", "did:plc:abc/../x", testCIDv1, ""},
+	}
+	for _, tt := range tests {
+		if got := BlobCDNURL("feed_thumbnail", tt.did, tt.cid); got != tt.want {
+			t.Errorf("%s: BlobCDNURL = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+// TestThumbURL decodes thumbs as they arrive, so the interface{} shapes
+// match what the poller, backfill and firehose see
+func TestThumbURL(t *testing.T) {
+	cdn := "https://cdn.bsky.app/img/feed_thumbnail/plain/" + testDID + "/" + testCIDv1 + "@jpeg"
+	tests := []struct {
+		name     string
+		external string // EmbedExternal JSON
+		want     string
+	}{
+		{"api view url", `{"uri": "https://example.com", "thumb": "https://cdn.bsky.app/img/x.jpg"}`, "https://cdn.bsky.app/img/x.jpg"},
+		{"blob ref", `{"uri": "https://example.com", "thumb": {"$type": "blob", "ref": {"$link": "` + testCIDv1 + `"}, "mimeType": "image/jpeg", "size": 1234}}`, cdn},
+		{"legacy blob", `{"uri": "https://example.com", "thumb": {"cid": "` + testCIDv1 + `", "mimeType": "image/jpeg"}}`, cdn},
+		{"blob with bad cid", `{"uri": "https://example.com", "thumb": {"ref": {"$link": "../../etc/passwd"}}}`, ""},
+		{"blob without ref", `{"uri": "https://example.com", "thumb": {"mimeType": "image/jpeg"}}`, ""},
+		{"non-http string", `{"uri": "https://example.com", "thumb": "javascript:alert(1)"}`, ""},
+		{"no thumb", `{"uri": "https://example.com"}`, ""},
+		{"number", `{"uri": "https://example.com", "thumb": 42}`, ""},
+	}
+	for _, tt := range tests {
+		var external EmbedExternal
+		if err := json.Unmarshal([]byte(tt.external), &external); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if got := ThumbURL(external.Thumb, testDID); got != tt.want {
+			t.Errorf("%s: ThumbURL = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
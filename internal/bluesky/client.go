@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"time"
 )
 
@@ -16,13 +17,23 @@ type Client struct {
 	handle     string
 	did        string
 	jwt        string
+	rateLimit  rateLimitState
 }
 
+// DefaultBaseURL is the XRPC endpoint used by NewClient
+const DefaultBaseURL = "https://bsky.social/xrpc"
+
 // NewClient creates a new Bluesky client and authenticates
 func NewClient(handle, password string) (*Client, error) {
+	return NewClientWithBaseURL(DefaultBaseURL, handle, password)
+}
+
+// NewClientWithBaseURL creates a client against a specific XRPC endpoint
+// (e.g. a self-hosted PDS or a fake server in tests) and authenticates
+func NewClientWithBaseURL(baseURL, handle, password string) (*Client, error) {
 	client := &Client{
 		httpClient: &http.Client{Timeout: 30 * time.Second},
-		baseURL:    "https://bsky.social/xrpc",
+		baseURL:    baseURL,
 		handle:     handle,
 	}
 
@@ -100,6 +111,7 @@ func (c *Client) GetAuthorFeed(handle string, cursor string, limit int) (*FeedRe
 		return nil, err
 	}
 	defer resp.Body.Close()
+	c.recordRateLimit(resp)
 
 	if resp.StatusCode != http.StatusOK {
 		return nil, fmt.Errorf("API error: %d", resp.StatusCode)
@@ -152,6 +164,7 @@ func (c *Client) GetFollowsWithMetadata(handle string) ([]Follow, error) {
 		if err != nil {
 			return nil, err
 		}
+		c.recordRateLimit(resp)
 
 		if resp.StatusCode != http.StatusOK {
 			// Read error response body for debugging
@@ -180,3 +193,90 @@ func (c *Client) GetFollowsWithMetadata(handle string) ([]Follow, error) {
 
 	return allFollows, nil
 }
+
+// MaxProfilesPerRequest is the getProfiles limit on actors per call
+const MaxProfilesPerRequest = 25
+
+// GetProfiles fetches profiles (including moderation labels) for up to
+// MaxProfilesPerRequest actors (DIDs or handles)
+func (c *Client) GetProfiles(actors []string) ([]Profile, error) {
+	if len(actors) == 0 {
+		return nil, nil
+	}
+	if len(actors) > MaxProfilesPerRequest {
+		return nil, fmt.Errorf("too many actors: %d (max %d)", len(actors), MaxProfilesPerRequest)
+	}
+
+	params := url.Values{}
+	for _, actor := range actors {
+		params.Add("actors", actor)
+	}
+	reqURL := fmt.Sprintf("%s/app.bsky.actor.getProfiles?%s", c.baseURL, params.Encode())
+
+	req, err := http.NewRequest("GET", reqURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Authorization", "Bearer "+c.jwt)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	c.recordRateLimit(resp)
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("API error: %d, body: %s", resp.StatusCode, string(bodyBytes))
+	}
+
+	var profilesResp ProfilesResponse
+	if err := json.NewDecoder(resp.Body).Decode(&profilesResp); err != nil {
+		return nil, err
+	}
+
+	return profilesResp.Profiles, nil
+}
+
+// PutRecord creates or replaces the record at collection/rkey in the
+// authenticated account's repo and returns its at:// URI
+func (c *Client) PutRecord(collection, rkey string, record interface{}) (string, error) {
+	body, err := json.Marshal(map[string]interface{}{
+		"repo":       c.did,
+		"collection": collection,
+		"rkey":       rkey,
+		"record":     record,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequest("POST", c.baseURL+"/com.atproto.repo.putRecord", bytes.NewBuffer(body))
+	if err != nil {
+		return "", err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+c.jwt)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	c.recordRateLimit(resp)
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return "", fmt.Errorf("API error: %d, body: %s", resp.StatusCode, string(bodyBytes))
+	}
+
+	var putResp PutRecordResponse
+	if err := json.NewDecoder(resp.Body).Decode(&putResp); err != nil {
+		return "", err
+	}
+
+	return putResp.URI, nil
+}
@@ -0,0 +1,153 @@
+package bluesky
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// MaxThumbSize is the largest image Bluesky accepts as an external embed thumb
+const MaxThumbSize = 1000000
+
+// Limits Bluesky's appview applies to external embed text
+const (
+	maxCardTitle       = 300
+	maxCardDescription = 1000
+)
+
+// BlobRef is a reference to an uploaded blob, as stored in records
+type BlobRef struct {
+	Type     string  `json:"$type"`
+	Ref      CIDLink `json:"ref"`
+	MimeType string  `json:"mimeType"`
+	Size     int     `json:"size"`
+}
+
+// CIDLink is the JSON form of a CID in a record
+type CIDLink struct {
+	Link string `json:"$link"`
+}
+
+// ExternalEmbed is an app.bsky.embed.external record: a link card
+type ExternalEmbed struct {
+	Type     string       `json:"$type"`
+	External ExternalCard `json:"external"`
+}
+
+// ExternalCard is the link a card shows. Thumb is nil for a card without
+// a preview image.
+type ExternalCard struct {
+	URI         string   `json:"uri"`
+	Title       string   `json:"title"`
+	Description string   `json:"description"`
+	Thumb       *BlobRef `json:"thumb,omitempty"`
+}
+
+// uploadBlobResponse is the response from com.atproto.repo.uploadBlob
+type uploadBlobResponse struct {
+	Blob BlobRef `json:"blob"`
+}
+
+// UploadBlob uploads data to the authenticated user's repository and returns
+// a reference to embed it in a record. The blob is only kept if a record
+// referencing it is created soon after.
+func (c *Client) UploadBlob(data []byte, mimeType string) (*BlobRef, error) {
+	url := fmt.Sprintf("%s/com.atproto.repo.uploadBlob", c.baseURL)
+
+	req, err := http.NewRequest("POST", url, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Authorization", "Bearer "+c.jwt)
+	req.Header.Set("Content-Type", mimeType)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("blob upload failed with status: %d", resp.StatusCode)
+	}
+
+	var uploaded uploadBlobResponse
+	if err := json.NewDecoder(resp.Body).Decode(&uploaded); err != nil {
+		return nil, err
+	}
+	if uploaded.Blob.Type == "" {
+		uploaded.Blob.Type = "blob"
+	}
+	return &uploaded.Blob, nil
+}
+
+// UploadImageFromURL fetches an image (a link's OG image) and uploads it as
+// a blob. Images that aren't images or are over MaxThumbSize are refused.
+func (c *Client) UploadImageFromURL(imageURL string) (*BlobRef, error) {
+	httpClient := &http.Client{Timeout: 15 * time.Second}
+	resp, err := httpClient.Get(imageURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch image: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("image fetch failed with status: %d", resp.StatusCode)
+	}
+
+	mimeType := strings.TrimSpace(strings.Split(resp.Header.Get("Content-Type"), ";")[0])
+	if !strings.HasPrefix(mimeType, "image/") {
+		return nil, fmt.Errorf("not an image: %q", mimeType)
+	}
+	if resp.ContentLength > MaxThumbSize {
+		return nil, fmt.Errorf("image too large: %d bytes", resp.ContentLength)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, MaxThumbSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read image: %w", err)
+	}
+	if len(data) > MaxThumbSize {
+		return nil, fmt.Errorf("image larger than %d bytes", MaxThumbSize)
+	}
+
+	return c.UploadBlob(data, mimeType)
+}
+
+// LinkCard builds a link card embed for uri. The image, if any, is uploaded
+// as the thumb; if that fails the card is returned without one, since a
+// card without a preview beats a post without a card.
+func (c *Client) LinkCard(uri, title, description, imageURL string) *ExternalEmbed {
+	card := ExternalCard{
+		URI:         uri,
+		Title:       truncateRunes(title, maxCardTitle),
+		Description: truncateRunes(description, maxCardDescription),
+	}
+
+	if imageURL != "" {
+		thumb, err := c.UploadImageFromURL(imageURL)
+		if err != nil {
+			log.Printf("[WARN] Link card for %s has no thumb: %v", uri, err)
+		} else {
+			card.Thumb = thumb
+		}
+	}
+
+	return &ExternalEmbed{Type: "app.bsky.embed.external", External: card}
+}
+
+// truncateRunes shortens s to at most n characters, marking the cut
+func truncateRunes(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	runes := []rune(s)
+	return strings.TrimSpace(string(runes[:n-1])) + "…"
+}
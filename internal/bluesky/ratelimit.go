@@ -0,0 +1,53 @@
+package bluesky
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RateLimit is the API quota reported by the last response's RateLimit-*
+// headers
+type RateLimit struct {
+	Limit     int       // Requests allowed per window
+	Remaining int       // Requests left in the current window
+	Reset     time.Time // When the window resets
+}
+
+// rateLimitState holds the latest RateLimit a client saw
+type rateLimitState struct {
+	mu    sync.Mutex
+	limit *RateLimit
+}
+
+// RateLimit returns the quota reported by the most recent API response, or
+// false if no response has carried rate limit headers yet
+func (c *Client) RateLimit() (RateLimit, bool) {
+	c.rateLimit.mu.Lock()
+	defer c.rateLimit.mu.Unlock()
+	if c.rateLimit.limit == nil {
+		return RateLimit{}, false
+	}
+	return *c.rateLimit.limit, true
+}
+
+// recordRateLimit keeps the quota from a response's headers. Responses
+// without them leave the last known quota in place.
+func (c *Client) recordRateLimit(resp *http.Response) {
+	remaining, err := strconv.Atoi(resp.Header.Get("RateLimit-Remaining"))
+	if err != nil {
+		return
+	}
+	limit, _ := strconv.Atoi(resp.Header.Get("RateLimit-Limit"))
+
+	// The reset is a Unix timestamp in seconds
+	var reset time.Time
+	if secs, err := strconv.ParseInt(resp.Header.Get("RateLimit-Reset"), 10, 64); err == nil {
+		reset = time.Unix(secs, 0)
+	}
+
+	c.rateLimit.mu.Lock()
+	c.rateLimit.limit = &RateLimit{Limit: limit, Remaining: remaining, Reset: reset}
+	c.rateLimit.mu.Unlock()
+}
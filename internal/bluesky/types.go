@@ -1,6 +1,9 @@
 package bluesky
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 // Post represents a Bluesky post
 type Post struct {
@@ -9,9 +12,44 @@ type Post struct {
 	Author    Author     `json:"author"`
 	Record    Record     `json:"record"`
 	Embed     *Embed     `json:"embed,omitempty"`
+	Labels    []Label    `json:"labels,omitempty"`
 	IndexedAt time.Time  `json:"indexedAt"`
 }
 
+// Label is a moderation label applied to a post or account, either by the
+// author (self-label) or by a labeler service
+type Label struct {
+	Src string `json:"src"` // DID of the labeler (or author, for self-labels)
+	URI string `json:"uri"` // Subject of the label
+	Val string `json:"val"` // Label value, e.g. "spam" or "porn"
+	Neg bool   `json:"neg,omitempty"`
+}
+
+// LabelValues returns the effective label values, ignoring negations
+func LabelValues(labels []Label) []string {
+	var values []string
+	for _, label := range labels {
+		if !label.Neg && label.Val != "" {
+			values = append(values, label.Val)
+		}
+	}
+	return values
+}
+
+// Profile represents an account profile from getProfiles
+type Profile struct {
+	DID         string  `json:"did"`
+	Handle      string  `json:"handle"`
+	DisplayName string  `json:"displayName"`
+	Avatar      string  `json:"avatar,omitempty"`
+	Labels      []Label `json:"labels,omitempty"`
+}
+
+// ProfilesResponse represents the response from getProfiles
+type ProfilesResponse struct {
+	Profiles []Profile `json:"profiles"`
+}
+
 // Author represents a post author
 type Author struct {
 	DID         string `json:"did"`
@@ -25,6 +63,34 @@ type Record struct {
 	Type      string    `json:"$type"`
 	Text      string    `json:"text"`
 	CreatedAt time.Time `json:"createdAt"`
+	Reply     *ReplyRef `json:"reply,omitempty"`
+	Langs     []string  `json:"langs,omitempty"` // Author-declared BCP 47 language tags
+
+	Raw json.RawMessage `json:"-"` // Original record JSON as returned by the API
+}
+
+// UnmarshalJSON decodes the record and keeps a copy of the original JSON
+func (r *Record) UnmarshalJSON(data []byte) error {
+	type recordAlias Record
+	var alias recordAlias
+	if err := json.Unmarshal(data, &alias); err != nil {
+		return err
+	}
+	*r = Record(alias)
+	r.Raw = append(json.RawMessage(nil), data...)
+	return nil
+}
+
+// ReplyRef identifies the parent and thread root of a reply post
+type ReplyRef struct {
+	Root   *StrongRef `json:"root,omitempty"`
+	Parent *StrongRef `json:"parent,omitempty"`
+}
+
+// StrongRef is an AT Protocol reference to a specific record version
+type StrongRef struct {
+	URI string `json:"uri"`
+	CID string `json:"cid"`
 }
 
 // FeedResponse represents the response from getAuthorFeed
@@ -63,6 +129,12 @@ type SessionResponse struct {
 	DID        string `json:"did"`
 }
 
+// PutRecordResponse is the response to com.atproto.repo.putRecord
+type PutRecordResponse struct {
+	URI string `json:"uri"`
+	CID string `json:"cid"`
+}
+
 // Reason represents why a post appears in the feed (e.g., repost)
 type Reason struct {
 	Type string `json:"$type"`
@@ -83,8 +155,8 @@ type EmbedRecord struct {
 
 // EmbedExternal represents an external link with metadata
 type EmbedExternal struct {
-	URI         string `json:"uri"`
-	Title       string `json:"title"`
-	Description string `json:"description"`
-	Thumb       string `json:"thumb,omitempty"`
+	URI         string      `json:"uri"`
+	Title       string      `json:"title"`
+	Description string      `json:"description"`
+	Thumb       interface{} `json:"thumb,omitempty"` // URL in API views, blob object in raw records; see ThumbURL
 }
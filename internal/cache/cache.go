@@ -0,0 +1,65 @@
+// Package cache provides the state API replicas must share: a response
+// cache, a rate limiter and pub/sub fan-out. Backends are in-memory (single
+// replica) or Redis (shared across replicas behind a load balancer).
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+// Store is a key/value cache with per-entry expiry
+type Store interface {
+	// Get returns the cached value and whether it was found
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+}
+
+// RateLimiter counts requests per key in fixed windows
+type RateLimiter interface {
+	// Allow records a request for key and reports whether it is within limit
+	// for the current window
+	Allow(ctx context.Context, key string, limit int, window time.Duration) (bool, error)
+}
+
+// PubSub fans messages out to every subscriber of a channel, across replicas
+// when backed by Redis
+type PubSub interface {
+	Publish(ctx context.Context, channel string, message []byte) error
+	// Subscribe returns a channel of messages and a function that unsubscribes
+	// and closes it
+	Subscribe(ctx context.Context, channel string) (<-chan []byte, func(), error)
+}
+
+// Backend bundles the shared-state implementations
+type Backend struct {
+	Store       Store
+	RateLimiter RateLimiter
+	PubSub      PubSub
+	Name        string // "memory" or "redis"
+
+	close func() error
+}
+
+// Close releases backend connections
+func (b *Backend) Close() error {
+	if b.close == nil {
+		return nil
+	}
+	return b.close()
+}
+
+// Config holds cache backend settings
+type Config struct {
+	RedisURL  string // redis://[:password@]host:port/db; empty = in-memory
+	KeyPrefix string // Prepended to every Redis key and channel
+}
+
+// NewWithConfig returns a Redis backend when RedisURL is set, otherwise an
+// in-memory backend
+func NewWithConfig(config *Config) (*Backend, error) {
+	if config == nil || config.RedisURL == "" {
+		return NewMemory(), nil
+	}
+	return NewRedis(config.RedisURL, config.KeyPrefix)
+}
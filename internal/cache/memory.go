@@ -0,0 +1,155 @@
+package cache
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// NewMemory returns an in-memory backend, suitable for a single replica
+func NewMemory() *Backend {
+	return &Backend{
+		Store:       newMemoryStore(),
+		RateLimiter: newMemoryRateLimiter(),
+		PubSub:      newMemoryPubSub(),
+		Name:        "memory",
+	}
+}
+
+type memoryEntry struct {
+	value     []byte
+	expiresAt time.Time
+}
+
+type memoryStore struct {
+	mu      sync.Mutex
+	entries map[string]memoryEntry
+}
+
+func newMemoryStore() *memoryStore {
+	s := &memoryStore{entries: make(map[string]memoryEntry)}
+
+	// Evict expired entries periodically
+	go func() {
+		for {
+			time.Sleep(time.Minute)
+			now := time.Now()
+			s.mu.Lock()
+			for key, e := range s.entries {
+				if now.After(e.expiresAt) {
+					delete(s.entries, key)
+				}
+			}
+			s.mu.Unlock()
+		}
+	}()
+
+	return s
+}
+
+func (s *memoryStore) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.entries[key]
+	if !ok || time.Now().After(e.expiresAt) {
+		return nil, false, nil
+	}
+	return e.value, true, nil
+}
+
+func (s *memoryStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.entries[key] = memoryEntry{value: value, expiresAt: time.Now().Add(ttl)}
+	return nil
+}
+
+type visitor struct {
+	count       int
+	windowStart time.Time
+}
+
+type memoryRateLimiter struct {
+	mu       sync.Mutex
+	visitors map[string]*visitor
+}
+
+func newMemoryRateLimiter() *memoryRateLimiter {
+	l := &memoryRateLimiter{visitors: make(map[string]*visitor)}
+
+	// Cleanup old entries periodically
+	go func() {
+		for {
+			time.Sleep(time.Minute)
+			l.mu.Lock()
+			for key, v := range l.visitors {
+				if time.Since(v.windowStart) > time.Hour {
+					delete(l.visitors, key)
+				}
+			}
+			l.mu.Unlock()
+		}
+	}()
+
+	return l
+}
+
+func (l *memoryRateLimiter) Allow(ctx context.Context, key string, limit int, window time.Duration) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	v, exists := l.visitors[key]
+	if !exists || time.Since(v.windowStart) > window {
+		l.visitors[key] = &visitor{count: 1, windowStart: time.Now()}
+		return true, nil
+	}
+
+	v.count++
+	return v.count <= limit, nil
+}
+
+type memoryPubSub struct {
+	mu          sync.Mutex
+	subscribers map[string]map[chan []byte]struct{}
+}
+
+func newMemoryPubSub() *memoryPubSub {
+	return &memoryPubSub{subscribers: make(map[string]map[chan []byte]struct{})}
+}
+
+func (p *memoryPubSub) Publish(ctx context.Context, channel string, message []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for ch := range p.subscribers[channel] {
+		select {
+		case ch <- message:
+		default: // Drop for slow subscribers rather than block publishers
+		}
+	}
+	return nil
+}
+
+func (p *memoryPubSub) Subscribe(ctx context.Context, channel string) (<-chan []byte, func(), error) {
+	ch := make(chan []byte, 16)
+
+	p.mu.Lock()
+	if p.subscribers[channel] == nil {
+		p.subscribers[channel] = make(map[chan []byte]struct{})
+	}
+	p.subscribers[channel][ch] = struct{}{}
+	p.mu.Unlock()
+
+	var once sync.Once
+	unsubscribe := func() {
+		once.Do(func() {
+			p.mu.Lock()
+			delete(p.subscribers[channel], ch)
+			p.mu.Unlock()
+			close(ch)
+		})
+	}
+	return ch, unsubscribe, nil
+}
@@ -0,0 +1,36 @@
+// Package calendar maps instants onto calendar days in the configured
+// timezone, so "today" and daily digests follow the reader's local day
+// rather than UTC.
+//
+// Timestamps in the database are UTC without a zone, so every boundary
+// returned here is in UTC, ready to compare against stored values.
+package calendar
+
+import (
+	"time"
+)
+
+// DayBounds returns the UTC start and end (exclusive) of the local day in
+// loc containing t. Days around DST changes are 23 or 25 hours long.
+func DayBounds(t time.Time, loc *time.Location) (start, end time.Time) {
+	local := t.In(loc)
+	startLocal := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, loc)
+	return startLocal.UTC(), startLocal.AddDate(0, 0, 1).UTC()
+}
+
+// ParseDay parses a YYYY-MM-DD date and returns the UTC bounds of that day
+// in loc
+func ParseDay(value string, loc *time.Location) (start, end time.Time, err error) {
+	day, err := time.ParseInLocation("2006-01-02", value, loc)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	return day.UTC(), day.AddDate(0, 0, 1).UTC(), nil
+}
+
+// Date returns the local date in loc containing t, as midnight UTC. Dates
+// without a time of day (all-day events, rollup days) are stored this way.
+func Date(t time.Time, loc *time.Location) time.Time {
+	local := t.In(loc)
+	return time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, time.UTC)
+}
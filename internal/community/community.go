@@ -0,0 +1,178 @@
+// Package community groups network accounts into communities from the
+// follow graph, so trending links can say whose corner of the network is
+// sharing them ("among the ML crowd" rather than "among local politics
+// folks").
+package community
+
+import (
+	"sort"
+)
+
+// maxRounds bounds label propagation on graphs that keep flipping
+const maxRounds = 50
+
+// Graph is an undirected graph of accounts, by DID
+type Graph struct {
+	index map[string]int
+	dids  []string
+	adj   [][]int
+}
+
+// NewGraph returns an empty graph
+func NewGraph() *Graph {
+	return &Graph{index: make(map[string]int)}
+}
+
+// node returns an account's index, adding it if new
+func (g *Graph) node(did string) int {
+	if i, ok := g.index[did]; ok {
+		return i
+	}
+	i := len(g.dids)
+	g.index[did] = i
+	g.dids = append(g.dids, did)
+	g.adj = append(g.adj, nil)
+	return i
+}
+
+// AddEdge connects two accounts, e.g. a follower and the account it follows
+func (g *Graph) AddEdge(a, b string) {
+	if a == b {
+		return
+	}
+	i, j := g.node(a), g.node(b)
+	g.adj[i] = append(g.adj[i], j)
+	g.adj[j] = append(g.adj[j], i)
+}
+
+// Len returns the number of accounts in the graph
+func (g *Graph) Len() int {
+	return len(g.dids)
+}
+
+// Community is a group of accounts found by Detect
+type Community struct {
+	Members []string // DIDs, best connected within the community first
+}
+
+// Detect finds communities by label propagation: every account starts in
+// a community of its own, then repeatedly joins the community most of its
+// neighbors are in until no account moves. Accounts are visited in DID
+// order and ties go to the current community, then the lowest label, so a
+// graph always gives the same result. Communities smaller than minSize are
+// dropped; the rest are returned largest first.
+func (g *Graph) Detect(minSize int) []Community {
+	n := len(g.dids)
+	order := make([]int, n)
+	labels := make([]int, n)
+	for i := range order {
+		order[i] = i
+		labels[i] = i
+	}
+	sort.Slice(order, func(a, b int) bool { return g.dids[order[a]] < g.dids[order[b]] })
+
+	counts := make(map[int]int)
+	for round := 0; round < maxRounds; round++ {
+		moved := false
+		for _, i := range order {
+			if len(g.adj[i]) == 0 {
+				continue
+			}
+			clear(counts)
+			for _, j := range g.adj[i] {
+				counts[labels[j]]++
+			}
+
+			current := labels[i]
+			best, bestCount := current, counts[current]
+			for label, count := range counts {
+				if count > bestCount || (count == bestCount && best != current && label < best) {
+					best, bestCount = label, count
+				}
+			}
+			if best != current {
+				labels[i] = best
+				moved = true
+			}
+		}
+		if !moved {
+			break
+		}
+	}
+
+	// Group members, ranked by neighbors in the same community
+	groups := make(map[int][]int)
+	for i, label := range labels {
+		groups[label] = append(groups[label], i)
+	}
+	var communities []Community
+	for label, members := range groups {
+		if len(members) < minSize {
+			continue
+		}
+		inside := make(map[int]int, len(members))
+		for _, i := range members {
+			for _, j := range g.adj[i] {
+				if labels[j] == label {
+					inside[i]++
+				}
+			}
+		}
+		sort.Slice(members, func(a, b int) bool {
+			if inside[members[a]] != inside[members[b]] {
+				return inside[members[a]] > inside[members[b]]
+			}
+			return g.dids[members[a]] < g.dids[members[b]]
+		})
+
+		community := Community{Members: make([]string, len(members))}
+		for k, i := range members {
+			community.Members[k] = g.dids[i]
+		}
+		communities = append(communities, community)
+	}
+	sort.Slice(communities, func(a, b int) bool {
+		if len(communities[a].Members) != len(communities[b].Members) {
+			return len(communities[a].Members) > len(communities[b].Members)
+		}
+		return communities[a].Members[0] < communities[b].Members[0]
+	})
+	return communities
+}
+
+// Match pairs detected communities with those of an earlier run, so a
+// community keeps its ID (and any name an admin gave it) as the graph
+// changes. previous maps DIDs to their earlier community. A community
+// takes the earlier one it shares the most members with, if they share
+// more than half of the smaller of the two and no larger community took it
+// first. Returns the earlier ID for each community, 0 for new ones.
+func Match(previous map[string]int, communities []Community) []int {
+	sizes := make(map[int]int)
+	for _, id := range previous {
+		sizes[id]++
+	}
+
+	ids := make([]int, len(communities))
+	taken := make(map[int]bool)
+	for k, community := range communities {
+		overlap := make(map[int]int)
+		for _, did := range community.Members {
+			if id, ok := previous[did]; ok && !taken[id] {
+				overlap[id]++
+			}
+		}
+
+		best, bestOverlap := 0, 0
+		for id, n := range overlap {
+			if n > bestOverlap || (n == bestOverlap && id < best) {
+				best, bestOverlap = id, n
+			}
+		}
+		if best == 0 || 2*bestOverlap <= min(len(community.Members), sizes[best]) {
+			continue
+		}
+		ids[k] = best
+		taken[best] = true
+	}
+	return ids
+}
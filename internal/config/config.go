@@ -6,7 +6,11 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"regexp"
+	"strconv"
 	"strings"
+	"time"
+	_ "time/tzdata" // Timezone names work on hosts without a zoneinfo database
 
 	"github.com/joho/godotenv"
 	"github.com/spf13/viper"
@@ -14,11 +18,38 @@ import (
 
 // Config holds all application configuration
 type Config struct {
-	Database DatabaseConfig
-	Bluesky  BlueskyConfig
-	Server   ServerConfig
-	Polling  PollingConfig
-	Cleanup  CleanupConfig
+	Database    DatabaseConfig
+	Bluesky     BlueskyConfig
+	Server      ServerConfig
+	Polling     PollingConfig
+	Cleanup     CleanupConfig
+	Snapshot    SnapshotConfig
+	Topics      TopicsConfig
+	Communities CommunitiesConfig
+	Digest      DigestConfig
+	FeedGen     FeedGenConfig
+	Redis       RedisConfig
+	Outbox      OutboxConfig
+	Ingest      IngestConfig
+	Scrape      ScrapeConfig
+	Trending    TrendingConfig
+	Firehose    FirehoseConfig
+	Moderation  ModerationConfig
+	Warehouse   WarehouseConfig
+
+	// Timezone for calendar days: digests, "today" in the API and daily
+	// rollups. Loaded from timezone / TIMEZONE (an IANA name, default UTC).
+	Timezone *time.Location
+
+	// Run migrations when cmd/api and cmd/firehose start, so a fresh
+	// deployment doesn't need cmd/migrate first. Loaded from
+	// migrate_on_start / MIGRATE_ON_START (default off).
+	MigrateOnStart bool
+
+	// Optional subsystems turned on or off, by name (see internal/features).
+	// Loaded from the features map / FEATURE_<NAME> variables; unset ones
+	// keep their defaults.
+	Features map[string]bool
 }
 
 // DatabaseConfig holds database connection settings
@@ -29,6 +60,7 @@ type DatabaseConfig struct {
 	Password string
 	DBName   string
 	SSLMode  string
+	Schema   string // Postgres schema holding this instance's tables (empty = public)
 }
 
 // BlueskyConfig holds Bluesky API credentials
@@ -44,7 +76,49 @@ type ServerConfig struct {
 	TLSCertFile     string
 	TLSKeyFile      string
 	CORSAllowOrigin string
-	RateLimitRPM    int // Requests per minute
+	RateLimitRPM    int    // Requests per minute
+	AdminToken      string // Bearer token for write endpoints (empty = writes disabled)
+
+	TrendingCacheSeconds  int // How long trending responses are cached (-1 = disabled)
+	StreamIntervalSeconds int // Minimum gap between live trending updates (-1 = stream disabled)
+
+	ClickTracking bool // Serve /out/{id} redirects that count clicks
+
+	PublicURL string // Base URL of the site in share previews (empty = the request's host)
+
+	DefaultLocale string // Locale of pages and API text when the request's languages aren't available
+
+	APIKeySignup     bool // Anyone can request an API key (admins still approve it)
+	APIKeyRPM        int  // Default requests per minute for new API keys
+	APIKeyDailyQuota int  // Default requests per UTC day for new API keys
+	RequireAPIKey    bool // The JSON API and feeds refuse requests without a key
+
+	// Hardened mode for public instances: nonce-based CSP, per-route rate
+	// limits, request body caps, abuser blocking, audit logging, and admin
+	// routes served only on AdminListen
+	PublicMode        bool
+	AdminListen       string   // host:port serving admin routes in public mode (empty = admin routes disabled)
+	MaxBodyBytes      int      // Largest request body accepted in public mode
+	BlockedIPs        []string // IPs or CIDRs refused in public mode
+	BlockAfterStrikes int      // Rate-limited requests in 10 minutes before an IP is blocked in public mode (-1 = never)
+	BlockMinutes      int      // How long a blocked IP stays blocked
+	AuditLogFile      string   // File audit events are appended to in public mode (empty = standard log)
+}
+
+// RedisConfig holds optional Redis settings for sharing state across API replicas
+type RedisConfig struct {
+	URL       string // redis://[:password@]host:port/db (empty = in-memory, single replica)
+	KeyPrefix string // Prepended to every key and channel
+}
+
+// OutboxConfig holds settings for streaming outbox events to downstream sinks
+type OutboxConfig struct {
+	WebhookURLs         []string // Each receives every event as batched JSON POSTs
+	WebhookSecret       string   // HMAC-SHA256 signing key for webhook bodies (empty = unsigned)
+	PublishEvents       bool     // Publish events to the Redis "events" channel (requires REDIS_URL)
+	DispatchIntervalSec int      // How often pending events are delivered
+	TrendingTop         int      // Links announced with link_trending events (-1 = disabled)
+	TrendingIntervalMin int      // How often the trending top list is checked
 }
 
 // PollingConfig holds polling settings
@@ -57,6 +131,18 @@ type PollingConfig struct {
 	MaxRetries           int
 	RetryBackoffMs       int
 	MaxPagesPerUser      int
+	QuotaReserve         int // Backfill pauses when this few API requests are left in the window (-1 = never)
+
+	Heartbeat HeartbeatConfig // Liveness of cmd/poller
+}
+
+// HeartbeatConfig holds how a background binary reports that its main loop
+// is making progress (see internal/heartbeat). systemd's watchdog is used
+// whenever the service runs with Type=notify.
+type HeartbeatConfig struct {
+	File         string // Touched while healthy (empty = no file)
+	Port         int    // Liveness probe port serving /healthz (0 = none)
+	StaleSeconds int    // Unhealthy after this long without progress
 }
 
 // CleanupConfig holds cleanup settings
@@ -65,8 +151,193 @@ type CleanupConfig struct {
 	CleanupIntervalMin   int
 	TrendingThreshold    int
 	CursorUpdateSeconds  int
+
+	MaxPosts       int // Posts kept before retention tightens (-1 = no cap)
+	MaxLinks       int // Links kept before retention tightens (-1 = no cap)
+	WarnDatabaseMB int // Database size that logs a warning (-1 = no check)
+
+	VacuumMinDeleted int // Rows a cleanup must delete to VACUUM (ANALYZE) afterwards (-1 = never)
+
+	PartitionAheadDays int // Days of posts partitions created ahead of today (partitioned posts only)
+
+	LinkExemptTables []string // Tables whose link_id references keep links from cleanup (missing tables are skipped)
 }
 
+// SnapshotConfig holds trending snapshot settings
+type SnapshotConfig struct {
+	IntervalMin int // How often to snapshot trending (-1 = disabled)
+	Hours       int // Trending window captured in each snapshot
+	Limit       int // Links per snapshot
+
+	Langs []string // Languages also snapshotted on their own, for per-language digests
+}
+
+// TopicsConfig holds settings for time-boxed topics (/topics/{slug})
+type TopicsConfig struct {
+	ArchiveIntervalMin int // How often the firehose archives ended topics (-1 = disabled)
+	ArchiveLimit       int // Links archived per topic
+}
+
+// CommunitiesConfig holds settings for communities detected from the
+// follow graph and shown on trending links
+type CommunitiesConfig struct {
+	IntervalHours int // How often the firehose re-detects communities (-1 = disabled)
+	MinSize       int // Smaller groups of accounts get no community
+	MinSharers    int // Sharers from a community before a link lists it
+}
+
+// DigestConfig holds settings for the email digest (cmd/digest)
+type DigestConfig struct {
+	Recipients []string // Addresses the digest is sent to (empty = nobody)
+	SendAt     string   // Local time of day to send, HH:MM in TIMEZONE
+	Hours      int      // Trending window covered
+	Limit      int      // Links included
+	From       string   // Sender address
+
+	SMTPHost     string
+	SMTPPort     int
+	SMTPUsername string // Empty = send without authentication
+	SMTPPassword string
+}
+
+// FeedGenConfig holds settings for serving trending posts as a Bluesky
+// custom feed
+type FeedGenConfig struct {
+	PublisherDID string // Account whose repo holds the feed record (empty = feed generator off)
+	ServiceDID   string // DID of this service (empty = did:web of PUBLIC_URL's host)
+	RecordName   string // Record key of the feed, the last part of its URL
+	DisplayName  string // Feed name shown in Bluesky
+	Description  string
+	Hours        int // Trending window the feed covers
+}
+
+// IngestConfig holds settings applied when posts are ingested
+type IngestConfig struct {
+	ExcludeReplies    bool // Store replies but don't extract their links
+	StoreRawRecord    bool // Keep the original record JSON on each post
+	RawRecordMaxBytes int  // Records larger than this are not stored (-1 = no limit)
+}
+
+// ScrapeConfig holds the link metadata scrape queue settings
+type ScrapeConfig struct {
+	QueueCapacity int // Jobs held in memory before overflowing to scrape_jobs (-1 = scrape inline)
+	Workers       int // Concurrent metadata fetches
+	ShedBacklog   int // Backlog size above which low-priority jobs are shed (-1 = never)
+	ShedPriority  int // Jobs with at most this many shares are shed
+
+	RecheckIntervalMin int // How often trending links are checked for missing/stale metadata (-1 = disabled)
+	RecheckTop         int // Links at the top of trending that are checked
+	RecheckStaleHours  int // Metadata older than this is refreshed for trending links
+
+	RefreshMinShares   int // Links with this many shares are re-scraped while young (-1 = disabled)
+	RefreshHours       int // Re-scrape interval for those links
+	RefreshMaxAgeHours int // How long after first being seen links keep being re-scraped
+
+	DeadCheckIntervalMin int // How often displayed links are checked for 404s (-1 = disabled)
+	DeadCheckTop         int // Top trending links checked each run
+	DeadCheckSample      int // Random recent links checked each run
+	DeadCheckHours       int // A link is checked at most once in this many hours
+
+	Enrichers []string // Enrichment stages run on links, in order (see internal/enrich)
+}
+
+// TrendingConfig holds defaults for trending queries (overridable per request)
+type TrendingConfig struct {
+	ReplyMode   string  // include, exclude, or downweight
+	ReplyWeight float64 // Weight of a reply share when ReplyMode is downweight
+
+	LabelMode      string   // off, flag, or exclude
+	FlaggedLabels  []string // Moderation label values that count against a share
+	LabelThreshold float64  // Labeled share ratio at which a link is flagged/excluded
+
+	UndiscoveredMode     string   // off, downrank, or exclude mainstream links
+	MainstreamDomains    []string // Domains treated as mainstream in undiscovered mode
+	MainstreamShareRatio float64  // Domains with at least this fraction of all shares are also mainstream (0 = list only)
+	UndiscoveredPenalty  float64  // Score multiplier for mainstream links when down-ranking
+
+	SelfPromoMode   string   // off, exclude, or downweight shares of the sharer's own domain
+	SelfPromoWeight float64  // Weight of a self-promotion share when SelfPromoMode is downweight
+	PersonalDomains []string // Domains whose links always count as self-promotion
+
+	CollapseCopies        bool // Count shares repeating another account's text only once
+	CoordinationThreshold int  // Copied shares at which a link is flagged as coordinated (-1 = never)
+
+	Ranking     string  // shares, or clicks to boost links readers open
+	ClickWeight float64 // Strength of the click boost when Ranking is clicks
+
+	ShadowRanking    string  // Strategy compared against Ranking without being served (empty = off)
+	ShadowSampleRate float64 // Fraction of trending requests also ranked by ShadowRanking
+
+	HideDead bool // Leave out links found dead (otherwise they are only marked)
+
+	MinShares int // Distinct sharers a link needs to appear in API results (independent of cleanup's trending threshold)
+
+	PinnedWeight float64 // Weight of a share by an account an admin pinned (1 = no boost)
+
+	NewsDomains      []string // Handles on these domains are typed news orgs, on their subdomains journalists
+	NewsOrgLabels    []string // Account labels that type an account as a news org
+	JournalistLabels []string // Account labels that type an account as a journalist
+}
+
+// ModerationConfig holds sensitive (adult/graphic) link detection settings
+type ModerationConfig struct {
+	SensitiveLabels    []string // Post self-label values that mark shared links sensitive
+	SensitiveDomains   []string // Domains whose links are always sensitive
+	ImageClassifierURL string   // Optional image classification endpoint
+}
+
+// WarehouseConfig holds settings for the daily export of aggregates to files
+// (a local directory or an S3 bucket) for long-term analysis
+type WarehouseConfig struct {
+	Dir          string // Local directory to export to
+	S3Bucket     string // S3 bucket to export to (instead of Dir)
+	S3Prefix     string // Key prefix within the bucket
+	S3Region     string
+	S3Endpoint   string // S3-compatible endpoint (R2, MinIO...); empty = AWS
+	S3AccessKey  string
+	S3SecretKey  string
+	BackfillDays int // Missed days to catch up on
+}
+
+// FirehoseConfig holds Jetstream consumer settings
+type FirehoseConfig struct {
+	WebsocketURL         string // Jetstream subscribe endpoint
+	RetryMaxAttempts     int // Attempts before a failed event is marked dead
+	RetryIntervalSeconds int // How often the retry queue checks for due events
+
+	ShardCount        int    // Split processing across this many instances by DID (1 = unsharded)
+	ShardLeaseSeconds int    // Shard lease length; an instance that stops renewing loses its shard
+	InstanceName      string // Unique name for shard leases (defaults to hostname-pid)
+
+	DormantDays     int // 2nd-degree accounts idle this many days leave the live filter (-1 = never)
+	FilterReloadMin int // How often each instance reloads its DID filter when DormantDays is set
+
+	ArchiveDir        string  // Directory for sampled raw events (empty = no archive)
+	ArchiveSampleRate float64 // Fraction of matched events archived
+	ArchiveErrors     bool    // Also archive every event that fails processing
+	ArchiveRotateMB   int     // Archive file size that starts a new file
+	ArchiveMaxFiles   int     // Archive files kept (-1 = keep all)
+
+	Heartbeat HeartbeatConfig // Liveness of cmd/firehose
+}
+
+// schemaNamePattern restricts schema names to plain identifiers, which need
+// no quoting in the connection string or in SQL
+var schemaNamePattern = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+
+// defaultNewsDomains are large outlets whose accounts are typed as news
+// orgs and journalists
+var defaultNewsDomains = []string{
+	"nytimes.com", "washingtonpost.com", "wsj.com", "cnn.com", "foxnews.com",
+	"nbcnews.com", "cbsnews.com", "abcnews.go.com", "bbc.com", "bbc.co.uk",
+	"theguardian.com", "reuters.com", "apnews.com", "bloomberg.com", "npr.org",
+	"politico.com", "axios.com", "theatlantic.com",
+}
+
+// defaultMainstreamDomains are large outlets whose links are down-ranked in
+// undiscovered mode
+var defaultMainstreamDomains = append(append([]string{}, defaultNewsDomains...), "youtube.com", "youtu.be")
+
 // Load reads configuration from file and environment variables.
 // Environment variables take precedence over config file values.
 // Sensitive values (passwords) should ONLY be set via environment variables in production.
@@ -108,6 +379,7 @@ func Load() (*Config, error) {
 			Password: getStringWithEnvFallback("database.password", "DB_PASSWORD", ""),
 			DBName:   getStringWithEnvFallback("database.dbname", "DB_NAME", "bluesky_news"),
 			SSLMode:  getStringWithEnvFallback("database.sslmode", "DB_SSLMODE", "disable"),
+			Schema:   getStringWithEnvFallback("database.schema", "DB_SCHEMA", ""),
 		},
 		Bluesky: BlueskyConfig{
 			Handle:   getStringWithEnvFallback("bluesky.handle", "BLUESKY_HANDLE", ""),
@@ -120,6 +392,41 @@ func Load() (*Config, error) {
 			TLSKeyFile:      getStringWithEnvFallback("server.tls_key", "TLS_KEY_FILE", ""),
 			CORSAllowOrigin: getStringWithEnvFallback("server.cors_origin", "CORS_ALLOW_ORIGIN", "*"),
 			RateLimitRPM:    getIntWithEnvFallback("server.rate_limit_rpm", "RATE_LIMIT_RPM", 100),
+			AdminToken:      getStringWithEnvFallback("server.admin_token", "ADMIN_TOKEN", ""),
+
+			TrendingCacheSeconds:  getIntWithEnvFallback("server.trending_cache_seconds", "TRENDING_CACHE_SEC", 30),
+			StreamIntervalSeconds: getIntWithEnvFallback("server.stream_interval_seconds", "STREAM_INTERVAL_SEC", 10),
+
+			ClickTracking: getBoolWithEnvFallback("server.click_tracking", "CLICK_TRACKING", false),
+
+			PublicURL: getStringWithEnvFallback("server.public_url", "PUBLIC_URL", ""),
+
+			DefaultLocale: getStringWithEnvFallback("server.default_locale", "DEFAULT_LOCALE", "en"),
+
+			APIKeySignup:     getBoolWithEnvFallback("server.api_key_signup", "API_KEY_SIGNUP", false),
+			APIKeyRPM:        getIntWithEnvFallback("server.api_key_rate_limit_rpm", "API_KEY_RATE_LIMIT_RPM", 60),
+			APIKeyDailyQuota: getIntWithEnvFallback("server.api_key_daily_quota", "API_KEY_DAILY_QUOTA", 10000),
+			RequireAPIKey:    getBoolWithEnvFallback("server.require_api_key", "REQUIRE_API_KEY", false),
+
+			PublicMode:        getBoolWithEnvFallback("server.public_mode", "PUBLIC_MODE", false),
+			AdminListen:       getStringWithEnvFallback("server.admin_listen", "ADMIN_LISTEN", ""),
+			MaxBodyBytes:      getIntWithEnvFallback("server.max_body_bytes", "MAX_BODY_BYTES", 65536),
+			BlockedIPs:        getStringListWithEnvFallback("server.blocked_ips", "BLOCKED_IPS", nil),
+			BlockAfterStrikes: getIntWithEnvFallback("server.block_after_strikes", "BLOCK_AFTER_STRIKES", 20),
+			BlockMinutes:      getIntWithEnvFallback("server.block_minutes", "BLOCK_MINUTES", 60),
+			AuditLogFile:      getStringWithEnvFallback("server.audit_log_file", "AUDIT_LOG_FILE", ""),
+		},
+		Redis: RedisConfig{
+			URL:       getStringWithEnvFallback("redis.url", "REDIS_URL", ""),
+			KeyPrefix: getStringWithEnvFallback("redis.key_prefix", "REDIS_KEY_PREFIX", "bna:"),
+		},
+		Outbox: OutboxConfig{
+			WebhookURLs:         getStringListWithEnvFallback("outbox.webhook_urls", "OUTBOX_WEBHOOK_URLS", nil),
+			WebhookSecret:       getStringWithEnvFallback("outbox.webhook_secret", "OUTBOX_WEBHOOK_SECRET", ""),
+			PublishEvents:       getBoolWithEnvFallback("outbox.publish_events", "OUTBOX_PUBLISH_EVENTS", false),
+			DispatchIntervalSec: getIntWithEnvFallback("outbox.dispatch_interval_seconds", "OUTBOX_DISPATCH_INTERVAL_SEC", 10),
+			TrendingTop:         getIntWithEnvFallback("outbox.trending_top", "OUTBOX_TRENDING_TOP", 20),
+			TrendingIntervalMin: getIntWithEnvFallback("outbox.trending_interval_minutes", "OUTBOX_TRENDING_INTERVAL_MIN", 5),
 		},
 		Polling: PollingConfig{
 			IntervalMinutes:      viper.GetInt("polling.interval_minutes"),
@@ -130,15 +437,180 @@ func Load() (*Config, error) {
 			MaxRetries:           viper.GetInt("polling.max_retries"),
 			RetryBackoffMs:       viper.GetInt("polling.retry_backoff_ms"),
 			MaxPagesPerUser:      viper.GetInt("polling.max_pages_per_user"),
+			QuotaReserve:         viper.GetInt("polling.quota_reserve"),
+
+			Heartbeat: HeartbeatConfig{
+				File:         getStringWithEnvFallback("polling.heartbeat_file", "POLLER_HEARTBEAT_FILE", ""),
+				Port:         getIntWithEnvFallback("polling.heartbeat_port", "POLLER_HEARTBEAT_PORT", 0),
+				StaleSeconds: getIntWithEnvFallback("polling.heartbeat_stale_seconds", "POLLER_HEARTBEAT_STALE_SEC", 0),
+			},
 		},
 		Cleanup: CleanupConfig{
 			RetentionHours:      getIntWithEnvFallback("cleanup.retention_hours", "CLEANUP_RETENTION_HOURS", 24),
 			CleanupIntervalMin:  getIntWithEnvFallback("cleanup.cleanup_interval_minutes", "CLEANUP_INTERVAL_MIN", 60),
 			TrendingThreshold:   getIntWithEnvFallback("cleanup.trending_threshold", "CLEANUP_TRENDING_THRESHOLD", 5),
 			CursorUpdateSeconds: getIntWithEnvFallback("cleanup.cursor_update_seconds", "CURSOR_UPDATE_SECONDS", 10),
+
+			MaxPosts:       getIntWithEnvFallback("cleanup.max_posts", "CLEANUP_MAX_POSTS", -1),
+			MaxLinks:       getIntWithEnvFallback("cleanup.max_links", "CLEANUP_MAX_LINKS", -1),
+			WarnDatabaseMB: getIntWithEnvFallback("cleanup.warn_database_mb", "CLEANUP_WARN_DATABASE_MB", -1),
+
+			VacuumMinDeleted: getIntWithEnvFallback("cleanup.vacuum_min_deleted", "CLEANUP_VACUUM_MIN_DELETED", -1),
+
+			PartitionAheadDays: getIntWithEnvFallback("cleanup.partition_ahead_days", "CLEANUP_PARTITION_AHEAD_DAYS", 3),
+
+			LinkExemptTables: getStringListWithEnvFallback("cleanup.link_exempt_tables", "CLEANUP_LINK_EXEMPT_TABLES", nil),
+		},
+		Snapshot: SnapshotConfig{
+			IntervalMin: getIntWithEnvFallback("snapshot.interval_minutes", "SNAPSHOT_INTERVAL_MIN", 60),
+			Hours:       getIntWithEnvFallback("snapshot.hours", "SNAPSHOT_HOURS", 24),
+			Limit:       getIntWithEnvFallback("snapshot.limit", "SNAPSHOT_LIMIT", 50),
+			Langs:       getStringListWithEnvFallback("snapshot.langs", "SNAPSHOT_LANGS", nil),
+		},
+		Topics: TopicsConfig{
+			ArchiveIntervalMin: getIntWithEnvFallback("topics.archive_interval_minutes", "TOPICS_ARCHIVE_INTERVAL_MIN", 5),
+			ArchiveLimit:       getIntWithEnvFallback("topics.archive_limit", "TOPICS_ARCHIVE_LIMIT", 50),
+		},
+		Communities: CommunitiesConfig{
+			IntervalHours: getIntWithEnvFallback("communities.interval_hours", "COMMUNITIES_INTERVAL_HOURS", 24),
+			MinSize:       getIntWithEnvFallback("communities.min_size", "COMMUNITIES_MIN_SIZE", 5),
+			MinSharers:    getIntWithEnvFallback("communities.min_sharers", "COMMUNITIES_MIN_SHARERS", 2),
+		},
+		Digest: DigestConfig{
+			Recipients:   getStringListWithEnvFallback("digest.recipients", "DIGEST_RECIPIENTS", nil),
+			SendAt:       getStringWithEnvFallback("digest.send_at", "DIGEST_SEND_AT", "07:00"),
+			Hours:        getIntWithEnvFallback("digest.hours", "DIGEST_HOURS", 24),
+			Limit:        getIntWithEnvFallback("digest.limit", "DIGEST_LIMIT", 10),
+			From:         getStringWithEnvFallback("digest.from", "DIGEST_FROM", ""),
+			SMTPHost:     getStringWithEnvFallback("digest.smtp_host", "SMTP_HOST", ""),
+			SMTPPort:     getIntWithEnvFallback("digest.smtp_port", "SMTP_PORT", 587),
+			SMTPUsername: getStringWithEnvFallback("digest.smtp_username", "SMTP_USERNAME", ""),
+			SMTPPassword: getStringWithEnvFallback("digest.smtp_password", "SMTP_PASSWORD", ""),
+		},
+		FeedGen: FeedGenConfig{
+			PublisherDID: getStringWithEnvFallback("feedgen.publisher_did", "FEEDGEN_PUBLISHER_DID", ""),
+			ServiceDID:   getStringWithEnvFallback("feedgen.service_did", "FEEDGEN_SERVICE_DID", ""),
+			RecordName:   getStringWithEnvFallback("feedgen.record_name", "FEEDGEN_RECORD_NAME", "trending"),
+			DisplayName:  getStringWithEnvFallback("feedgen.display_name", "FEEDGEN_DISPLAY_NAME", "News from my network"),
+			Description:  getStringWithEnvFallback("feedgen.description", "FEEDGEN_DESCRIPTION", "Links trending among the accounts I follow and the accounts they follow"),
+			Hours:        getIntWithEnvFallback("feedgen.hours", "FEEDGEN_HOURS", 24),
+		},
+		Ingest: IngestConfig{
+			ExcludeReplies:    getBoolWithEnvFallback("ingest.exclude_replies", "INGEST_EXCLUDE_REPLIES", false),
+			StoreRawRecord:    getBoolWithEnvFallback("ingest.store_raw_record", "INGEST_STORE_RAW_RECORD", true),
+			RawRecordMaxBytes: getIntWithEnvFallback("ingest.raw_record_max_bytes", "INGEST_RAW_RECORD_MAX_BYTES", 16384),
 		},
+		Scrape: ScrapeConfig{
+			QueueCapacity: getIntWithEnvFallback("scrape.queue_capacity", "SCRAPE_QUEUE_CAPACITY", 1000),
+			Workers:       getIntWithEnvFallback("scrape.workers", "SCRAPE_WORKERS", 4),
+			ShedBacklog:   getIntWithEnvFallback("scrape.shed_backlog", "SCRAPE_SHED_BACKLOG", 20000),
+			ShedPriority:  getIntWithEnvFallback("scrape.shed_priority", "SCRAPE_SHED_PRIORITY", 1),
+
+			RecheckIntervalMin: getIntWithEnvFallback("scrape.recheck_interval_minutes", "SCRAPE_RECHECK_INTERVAL_MIN", 5),
+			RecheckTop:         getIntWithEnvFallback("scrape.recheck_top", "SCRAPE_RECHECK_TOP", 20),
+			RecheckStaleHours:  getIntWithEnvFallback("scrape.recheck_stale_hours", "SCRAPE_RECHECK_STALE_HOURS", 6),
+
+			RefreshMinShares:   getIntWithEnvFallback("scrape.refresh_min_shares", "SCRAPE_REFRESH_MIN_SHARES", 5),
+			RefreshHours:       getIntWithEnvFallback("scrape.refresh_hours", "SCRAPE_REFRESH_HOURS", 2),
+			RefreshMaxAgeHours: getIntWithEnvFallback("scrape.refresh_max_age_hours", "SCRAPE_REFRESH_MAX_AGE_HOURS", 48),
+
+			DeadCheckIntervalMin: getIntWithEnvFallback("scrape.dead_check_interval_minutes", "SCRAPE_DEAD_CHECK_INTERVAL_MIN", 60),
+			DeadCheckTop:         getIntWithEnvFallback("scrape.dead_check_top", "SCRAPE_DEAD_CHECK_TOP", 50),
+			DeadCheckSample:      getIntWithEnvFallback("scrape.dead_check_sample", "SCRAPE_DEAD_CHECK_SAMPLE", 50),
+			DeadCheckHours:       getIntWithEnvFallback("scrape.dead_check_hours", "SCRAPE_DEAD_CHECK_HOURS", 24),
+
+			Enrichers: getStringListWithEnvFallback("scrape.enrichers", "SCRAPE_ENRICHERS", []string{"scrape", "events", "sensitive"}),
+		},
+		Trending: TrendingConfig{
+			ReplyMode:   getStringWithEnvFallback("trending.reply_mode", "TRENDING_REPLY_MODE", "include"),
+			ReplyWeight: getFloatWithEnvFallback("trending.reply_weight", "TRENDING_REPLY_WEIGHT", 0.5),
+
+			LabelMode:      getStringWithEnvFallback("trending.label_mode", "TRENDING_LABEL_MODE", "off"),
+			FlaggedLabels:  getStringListWithEnvFallback("trending.flagged_labels", "TRENDING_FLAGGED_LABELS", []string{"spam", "misleading", "impersonation", "scam"}),
+			LabelThreshold: getFloatWithEnvFallback("trending.label_threshold", "TRENDING_LABEL_THRESHOLD", 0.5),
+
+			UndiscoveredMode:     getStringWithEnvFallback("trending.undiscovered_mode", "TRENDING_UNDISCOVERED_MODE", "off"),
+			MainstreamDomains:    getStringListWithEnvFallback("trending.mainstream_domains", "TRENDING_MAINSTREAM_DOMAINS", defaultMainstreamDomains),
+			MainstreamShareRatio: getFloatWithEnvFallback("trending.mainstream_share_ratio", "TRENDING_MAINSTREAM_SHARE_RATIO", 0.05),
+			UndiscoveredPenalty:  getFloatWithEnvFallback("trending.undiscovered_penalty", "TRENDING_UNDISCOVERED_PENALTY", 0.25),
+
+			SelfPromoMode:   getStringWithEnvFallback("trending.self_promo_mode", "TRENDING_SELF_PROMO_MODE", "off"),
+			SelfPromoWeight: getFloatWithEnvFallback("trending.self_promo_weight", "TRENDING_SELF_PROMO_WEIGHT", 0.25),
+			PersonalDomains: getStringListWithEnvFallback("trending.personal_domains", "TRENDING_PERSONAL_DOMAINS", nil),
+
+			CollapseCopies:        getBoolWithEnvFallback("trending.collapse_copies", "TRENDING_COLLAPSE_COPIES", true),
+			CoordinationThreshold: getIntWithEnvFallback("trending.coordination_threshold", "TRENDING_COORDINATION_THRESHOLD", 3),
+
+			Ranking:     getStringWithEnvFallback("trending.ranking", "TRENDING_RANKING", "shares"),
+			ClickWeight: getFloatWithEnvFallback("trending.click_weight", "TRENDING_CLICK_WEIGHT", 0.5),
+
+			ShadowRanking:    getStringWithEnvFallback("trending.shadow_ranking", "TRENDING_SHADOW_RANKING", ""),
+			ShadowSampleRate: getFloatWithEnvFallback("trending.shadow_sample_rate", "TRENDING_SHADOW_SAMPLE_RATE", 0.1),
+
+			HideDead: getBoolWithEnvFallback("trending.hide_dead", "TRENDING_HIDE_DEAD", false),
+
+			MinShares: getIntWithEnvFallback("trending.min_shares", "TRENDING_MIN_SHARES", 1),
+
+			PinnedWeight: getFloatWithEnvFallback("trending.pinned_weight", "TRENDING_PINNED_WEIGHT", 2),
+
+			NewsDomains:      getStringListWithEnvFallback("trending.news_domains", "TRENDING_NEWS_DOMAINS", defaultNewsDomains),
+			NewsOrgLabels:    getStringListWithEnvFallback("trending.news_org_labels", "TRENDING_NEWS_ORG_LABELS", nil),
+			JournalistLabels: getStringListWithEnvFallback("trending.journalist_labels", "TRENDING_JOURNALIST_LABELS", nil),
+		},
+		Firehose: FirehoseConfig{
+			WebsocketURL:         getStringWithEnvFallback("firehose.websocket_url", "JETSTREAM_URL", "wss://jetstream2.us-west.bsky.network/subscribe"),
+			RetryMaxAttempts:     getIntWithEnvFallback("firehose.retry_max_attempts", "FIREHOSE_RETRY_MAX_ATTEMPTS", 5),
+			RetryIntervalSeconds: getIntWithEnvFallback("firehose.retry_interval_seconds", "FIREHOSE_RETRY_INTERVAL_SEC", 30),
+
+			ShardCount:        getIntWithEnvFallback("firehose.shard_count", "FIREHOSE_SHARD_COUNT", 1),
+			ShardLeaseSeconds: getIntWithEnvFallback("firehose.shard_lease_seconds", "FIREHOSE_SHARD_LEASE_SEC", 60),
+			InstanceName:      getStringWithEnvFallback("firehose.instance_name", "FIREHOSE_INSTANCE_NAME", ""),
+
+			DormantDays:     getIntWithEnvFallback("firehose.dormant_days", "FIREHOSE_DORMANT_DAYS", -1),
+			FilterReloadMin: getIntWithEnvFallback("firehose.filter_reload_min", "FIREHOSE_FILTER_RELOAD_MIN", 60),
+
+			ArchiveDir:        getStringWithEnvFallback("firehose.archive_dir", "FIREHOSE_ARCHIVE_DIR", ""),
+			ArchiveSampleRate: getFloatWithEnvFallback("firehose.archive_sample_rate", "FIREHOSE_ARCHIVE_SAMPLE_RATE", 0.01),
+			ArchiveErrors:     getBoolWithEnvFallback("firehose.archive_errors", "FIREHOSE_ARCHIVE_ERRORS", true),
+			ArchiveRotateMB:   getIntWithEnvFallback("firehose.archive_rotate_mb", "FIREHOSE_ARCHIVE_ROTATE_MB", 64),
+			ArchiveMaxFiles:   getIntWithEnvFallback("firehose.archive_max_files", "FIREHOSE_ARCHIVE_MAX_FILES", 20),
+
+			Heartbeat: HeartbeatConfig{
+				File:         getStringWithEnvFallback("firehose.heartbeat_file", "FIREHOSE_HEARTBEAT_FILE", ""),
+				Port:         getIntWithEnvFallback("firehose.heartbeat_port", "FIREHOSE_HEARTBEAT_PORT", 0),
+				StaleSeconds: getIntWithEnvFallback("firehose.heartbeat_stale_seconds", "FIREHOSE_HEARTBEAT_STALE_SEC", 120),
+			},
+		},
+		Moderation: ModerationConfig{
+			SensitiveLabels:    getStringListWithEnvFallback("moderation.sensitive_labels", "SENSITIVE_LABELS", []string{"porn", "sexual", "nudity", "graphic-media"}),
+			SensitiveDomains:   getStringListWithEnvFallback("moderation.sensitive_domains", "SENSITIVE_DOMAINS", nil),
+			ImageClassifierURL: getStringWithEnvFallback("moderation.image_classifier_url", "IMAGE_CLASSIFIER_URL", ""),
+		},
+		Warehouse: WarehouseConfig{
+			Dir:          getStringWithEnvFallback("warehouse.dir", "WAREHOUSE_DIR", ""),
+			S3Bucket:     getStringWithEnvFallback("warehouse.s3_bucket", "WAREHOUSE_S3_BUCKET", ""),
+			S3Prefix:     getStringWithEnvFallback("warehouse.s3_prefix", "WAREHOUSE_S3_PREFIX", ""),
+			S3Region:     getStringWithEnvFallback("warehouse.s3_region", "AWS_REGION", "us-east-1"),
+			S3Endpoint:   getStringWithEnvFallback("warehouse.s3_endpoint", "WAREHOUSE_S3_ENDPOINT", ""),
+			S3AccessKey:  getStringWithEnvFallback("warehouse.s3_access_key", "AWS_ACCESS_KEY_ID", ""),
+			S3SecretKey:  getStringWithEnvFallback("warehouse.s3_secret_key", "AWS_SECRET_ACCESS_KEY", ""),
+			BackfillDays: getIntWithEnvFallback("warehouse.backfill_days", "WAREHOUSE_BACKFILL_DAYS", 7),
+		},
+	}
+
+	if cfg.Database.Schema != "" && !schemaNamePattern.MatchString(cfg.Database.Schema) {
+		return nil, fmt.Errorf("invalid database schema %q: use lowercase letters, digits and underscores", cfg.Database.Schema)
 	}
 
+	timezone := getStringWithEnvFallback("timezone", "TIMEZONE", "UTC")
+	location, err := time.LoadLocation(timezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timezone %q: %w", timezone, err)
+	}
+	cfg.Timezone = location
+	cfg.MigrateOnStart = getBoolWithEnvFallback("migrate_on_start", "MIGRATE_ON_START", false)
+	cfg.Features = getFeatureFlags()
+
 	// Set defaults for polling if not configured
 	if cfg.Polling.IntervalMinutes == 0 {
 		cfg.Polling.IntervalMinutes = 15
@@ -164,6 +636,14 @@ func Load() (*Config, error) {
 	if cfg.Polling.MaxPagesPerUser == 0 {
 		cfg.Polling.MaxPagesPerUser = 100
 	}
+	if cfg.Polling.QuotaReserve == 0 {
+		cfg.Polling.QuotaReserve = 300
+	}
+	if cfg.Polling.Heartbeat.StaleSeconds == 0 {
+		// Two missed polls; accounts beat as they are polled, so a long
+		// poll still shows progress
+		cfg.Polling.Heartbeat.StaleSeconds = 2 * cfg.Polling.IntervalMinutes * 60
+	}
 
 	return cfg, nil
 }
@@ -172,15 +652,12 @@ func Load() (*Config, error) {
 // This method intentionally does NOT log the password.
 func (c *DatabaseConfig) DatabaseConnString() string {
 	if c.Password == "" {
-		return fmt.Sprintf(
-			"host=%s port=%d user=%s dbname=%s sslmode=%s",
-			c.Host, c.Port, c.User, c.DBName, c.SSLMode,
-		)
+		return c.DatabaseConnStringSafe()
 	}
 	return fmt.Sprintf(
 		"host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
 		c.Host, c.Port, c.User, c.Password, c.DBName, c.SSLMode,
-	)
+	) + c.searchPathParam()
 }
 
 // DatabaseConnStringSafe returns a connection string with password redacted for logging
@@ -188,7 +665,16 @@ func (c *DatabaseConfig) DatabaseConnStringSafe() string {
 	return fmt.Sprintf(
 		"host=%s port=%d user=%s dbname=%s sslmode=%s",
 		c.Host, c.Port, c.User, c.DBName, c.SSLMode,
-	)
+	) + c.searchPathParam()
+}
+
+// searchPathParam sets the session search_path so every unqualified table
+// name in queries and migrations resolves to the configured schema
+func (c *DatabaseConfig) searchPathParam() string {
+	if c.Schema == "" {
+		return ""
+	}
+	return " search_path=" + c.Schema
 }
 
 // IsTLSEnabled returns true if TLS certificate and key are configured
@@ -198,6 +684,9 @@ func (c *ServerConfig) IsTLSEnabled() bool {
 
 // bindEnvVars explicitly binds environment variables to viper keys
 func bindEnvVars() {
+	viper.BindEnv("timezone", "TIMEZONE")
+	viper.BindEnv("migrate_on_start", "MIGRATE_ON_START")
+
 	// Database
 	viper.BindEnv("database.host", "DB_HOST")
 	viper.BindEnv("database.port", "DB_PORT")
@@ -205,6 +694,7 @@ func bindEnvVars() {
 	viper.BindEnv("database.password", "DB_PASSWORD")
 	viper.BindEnv("database.dbname", "DB_NAME")
 	viper.BindEnv("database.sslmode", "DB_SSLMODE")
+	viper.BindEnv("database.schema", "DB_SCHEMA")
 
 	// Bluesky
 	viper.BindEnv("bluesky.handle", "BLUESKY_HANDLE")
@@ -217,6 +707,163 @@ func bindEnvVars() {
 	viper.BindEnv("server.tls_key", "TLS_KEY_FILE")
 	viper.BindEnv("server.cors_origin", "CORS_ALLOW_ORIGIN")
 	viper.BindEnv("server.rate_limit_rpm", "RATE_LIMIT_RPM")
+	viper.BindEnv("server.admin_token", "ADMIN_TOKEN")
+	viper.BindEnv("server.trending_cache_seconds", "TRENDING_CACHE_SEC")
+	viper.BindEnv("server.stream_interval_seconds", "STREAM_INTERVAL_SEC")
+	viper.BindEnv("server.click_tracking", "CLICK_TRACKING")
+	viper.BindEnv("server.public_url", "PUBLIC_URL")
+	viper.BindEnv("server.default_locale", "DEFAULT_LOCALE")
+	viper.BindEnv("server.api_key_signup", "API_KEY_SIGNUP")
+	viper.BindEnv("server.require_api_key", "REQUIRE_API_KEY")
+	viper.BindEnv("server.api_key_rate_limit_rpm", "API_KEY_RATE_LIMIT_RPM")
+	viper.BindEnv("server.api_key_daily_quota", "API_KEY_DAILY_QUOTA")
+	viper.BindEnv("server.public_mode", "PUBLIC_MODE")
+	viper.BindEnv("server.admin_listen", "ADMIN_LISTEN")
+	viper.BindEnv("server.max_body_bytes", "MAX_BODY_BYTES")
+	viper.BindEnv("server.blocked_ips", "BLOCKED_IPS")
+	viper.BindEnv("server.block_after_strikes", "BLOCK_AFTER_STRIKES")
+	viper.BindEnv("server.block_minutes", "BLOCK_MINUTES")
+	viper.BindEnv("server.audit_log_file", "AUDIT_LOG_FILE")
+
+	// Redis
+	viper.BindEnv("redis.url", "REDIS_URL")
+	viper.BindEnv("redis.key_prefix", "REDIS_KEY_PREFIX")
+
+	// Outbox
+	viper.BindEnv("outbox.webhook_urls", "OUTBOX_WEBHOOK_URLS")
+	viper.BindEnv("outbox.webhook_secret", "OUTBOX_WEBHOOK_SECRET")
+	viper.BindEnv("outbox.publish_events", "OUTBOX_PUBLISH_EVENTS")
+	viper.BindEnv("outbox.dispatch_interval_seconds", "OUTBOX_DISPATCH_INTERVAL_SEC")
+	viper.BindEnv("outbox.trending_top", "OUTBOX_TRENDING_TOP")
+	viper.BindEnv("outbox.trending_interval_minutes", "OUTBOX_TRENDING_INTERVAL_MIN")
+
+	// Cleanup
+	viper.BindEnv("cleanup.max_posts", "CLEANUP_MAX_POSTS")
+	viper.BindEnv("cleanup.max_links", "CLEANUP_MAX_LINKS")
+	viper.BindEnv("cleanup.warn_database_mb", "CLEANUP_WARN_DATABASE_MB")
+	viper.BindEnv("cleanup.vacuum_min_deleted", "CLEANUP_VACUUM_MIN_DELETED")
+	viper.BindEnv("cleanup.partition_ahead_days", "CLEANUP_PARTITION_AHEAD_DAYS")
+	viper.BindEnv("cleanup.link_exempt_tables", "CLEANUP_LINK_EXEMPT_TABLES")
+
+	// Snapshot
+	viper.BindEnv("snapshot.interval_minutes", "SNAPSHOT_INTERVAL_MIN")
+	viper.BindEnv("snapshot.hours", "SNAPSHOT_HOURS")
+	viper.BindEnv("snapshot.limit", "SNAPSHOT_LIMIT")
+	viper.BindEnv("snapshot.langs", "SNAPSHOT_LANGS")
+
+	// Topics
+	viper.BindEnv("topics.archive_interval_minutes", "TOPICS_ARCHIVE_INTERVAL_MIN")
+	viper.BindEnv("topics.archive_limit", "TOPICS_ARCHIVE_LIMIT")
+
+	// Communities
+	viper.BindEnv("communities.interval_hours", "COMMUNITIES_INTERVAL_HOURS")
+	viper.BindEnv("communities.min_size", "COMMUNITIES_MIN_SIZE")
+	viper.BindEnv("communities.min_sharers", "COMMUNITIES_MIN_SHARERS")
+
+	// Email digest
+	viper.BindEnv("digest.recipients", "DIGEST_RECIPIENTS")
+	viper.BindEnv("digest.send_at", "DIGEST_SEND_AT")
+	viper.BindEnv("digest.hours", "DIGEST_HOURS")
+	viper.BindEnv("digest.limit", "DIGEST_LIMIT")
+	viper.BindEnv("digest.from", "DIGEST_FROM")
+	viper.BindEnv("digest.smtp_host", "SMTP_HOST")
+	viper.BindEnv("digest.smtp_port", "SMTP_PORT")
+	viper.BindEnv("digest.smtp_username", "SMTP_USERNAME")
+	viper.BindEnv("digest.smtp_password", "SMTP_PASSWORD")
+
+	// Feed generator
+	viper.BindEnv("feedgen.publisher_did", "FEEDGEN_PUBLISHER_DID")
+	viper.BindEnv("feedgen.service_did", "FEEDGEN_SERVICE_DID")
+	viper.BindEnv("feedgen.record_name", "FEEDGEN_RECORD_NAME")
+	viper.BindEnv("feedgen.display_name", "FEEDGEN_DISPLAY_NAME")
+	viper.BindEnv("feedgen.description", "FEEDGEN_DESCRIPTION")
+	viper.BindEnv("feedgen.hours", "FEEDGEN_HOURS")
+
+	// Ingest
+	viper.BindEnv("ingest.exclude_replies", "INGEST_EXCLUDE_REPLIES")
+	viper.BindEnv("ingest.store_raw_record", "INGEST_STORE_RAW_RECORD")
+	viper.BindEnv("ingest.raw_record_max_bytes", "INGEST_RAW_RECORD_MAX_BYTES")
+
+	// Scrape
+	viper.BindEnv("scrape.queue_capacity", "SCRAPE_QUEUE_CAPACITY")
+	viper.BindEnv("scrape.workers", "SCRAPE_WORKERS")
+	viper.BindEnv("scrape.shed_backlog", "SCRAPE_SHED_BACKLOG")
+	viper.BindEnv("scrape.shed_priority", "SCRAPE_SHED_PRIORITY")
+	viper.BindEnv("scrape.recheck_interval_minutes", "SCRAPE_RECHECK_INTERVAL_MIN")
+	viper.BindEnv("scrape.recheck_top", "SCRAPE_RECHECK_TOP")
+	viper.BindEnv("scrape.recheck_stale_hours", "SCRAPE_RECHECK_STALE_HOURS")
+	viper.BindEnv("scrape.refresh_min_shares", "SCRAPE_REFRESH_MIN_SHARES")
+	viper.BindEnv("scrape.refresh_hours", "SCRAPE_REFRESH_HOURS")
+	viper.BindEnv("scrape.refresh_max_age_hours", "SCRAPE_REFRESH_MAX_AGE_HOURS")
+	viper.BindEnv("scrape.dead_check_interval_minutes", "SCRAPE_DEAD_CHECK_INTERVAL_MIN")
+	viper.BindEnv("scrape.dead_check_top", "SCRAPE_DEAD_CHECK_TOP")
+	viper.BindEnv("scrape.dead_check_sample", "SCRAPE_DEAD_CHECK_SAMPLE")
+	viper.BindEnv("scrape.dead_check_hours", "SCRAPE_DEAD_CHECK_HOURS")
+	viper.BindEnv("scrape.enrichers", "SCRAPE_ENRICHERS")
+
+	// Trending
+	viper.BindEnv("trending.reply_mode", "TRENDING_REPLY_MODE")
+	viper.BindEnv("trending.reply_weight", "TRENDING_REPLY_WEIGHT")
+	viper.BindEnv("trending.label_mode", "TRENDING_LABEL_MODE")
+	viper.BindEnv("trending.flagged_labels", "TRENDING_FLAGGED_LABELS")
+	viper.BindEnv("trending.label_threshold", "TRENDING_LABEL_THRESHOLD")
+	viper.BindEnv("trending.undiscovered_mode", "TRENDING_UNDISCOVERED_MODE")
+	viper.BindEnv("trending.mainstream_domains", "TRENDING_MAINSTREAM_DOMAINS")
+	viper.BindEnv("trending.mainstream_share_ratio", "TRENDING_MAINSTREAM_SHARE_RATIO")
+	viper.BindEnv("trending.undiscovered_penalty", "TRENDING_UNDISCOVERED_PENALTY")
+	viper.BindEnv("trending.self_promo_mode", "TRENDING_SELF_PROMO_MODE")
+	viper.BindEnv("trending.self_promo_weight", "TRENDING_SELF_PROMO_WEIGHT")
+	viper.BindEnv("trending.personal_domains", "TRENDING_PERSONAL_DOMAINS")
+	viper.BindEnv("trending.collapse_copies", "TRENDING_COLLAPSE_COPIES")
+	viper.BindEnv("trending.coordination_threshold", "TRENDING_COORDINATION_THRESHOLD")
+	viper.BindEnv("trending.ranking", "TRENDING_RANKING")
+	viper.BindEnv("trending.click_weight", "TRENDING_CLICK_WEIGHT")
+	viper.BindEnv("trending.shadow_ranking", "TRENDING_SHADOW_RANKING")
+	viper.BindEnv("trending.shadow_sample_rate", "TRENDING_SHADOW_SAMPLE_RATE")
+	viper.BindEnv("trending.hide_dead", "TRENDING_HIDE_DEAD")
+	viper.BindEnv("trending.min_shares", "TRENDING_MIN_SHARES")
+	viper.BindEnv("trending.pinned_weight", "TRENDING_PINNED_WEIGHT")
+	viper.BindEnv("trending.news_domains", "TRENDING_NEWS_DOMAINS")
+	viper.BindEnv("trending.news_org_labels", "TRENDING_NEWS_ORG_LABELS")
+	viper.BindEnv("trending.journalist_labels", "TRENDING_JOURNALIST_LABELS")
+
+	// Firehose
+	viper.BindEnv("firehose.websocket_url", "JETSTREAM_URL")
+	viper.BindEnv("firehose.retry_max_attempts", "FIREHOSE_RETRY_MAX_ATTEMPTS")
+	viper.BindEnv("firehose.retry_interval_seconds", "FIREHOSE_RETRY_INTERVAL_SEC")
+	viper.BindEnv("firehose.shard_count", "FIREHOSE_SHARD_COUNT")
+	viper.BindEnv("firehose.shard_lease_seconds", "FIREHOSE_SHARD_LEASE_SEC")
+	viper.BindEnv("firehose.instance_name", "FIREHOSE_INSTANCE_NAME")
+	viper.BindEnv("firehose.dormant_days", "FIREHOSE_DORMANT_DAYS")
+	viper.BindEnv("firehose.filter_reload_min", "FIREHOSE_FILTER_RELOAD_MIN")
+	viper.BindEnv("firehose.archive_dir", "FIREHOSE_ARCHIVE_DIR")
+	viper.BindEnv("firehose.archive_sample_rate", "FIREHOSE_ARCHIVE_SAMPLE_RATE")
+	viper.BindEnv("firehose.archive_errors", "FIREHOSE_ARCHIVE_ERRORS")
+	viper.BindEnv("firehose.archive_rotate_mb", "FIREHOSE_ARCHIVE_ROTATE_MB")
+	viper.BindEnv("firehose.archive_max_files", "FIREHOSE_ARCHIVE_MAX_FILES")
+	viper.BindEnv("firehose.heartbeat_file", "FIREHOSE_HEARTBEAT_FILE")
+	viper.BindEnv("firehose.heartbeat_port", "FIREHOSE_HEARTBEAT_PORT")
+	viper.BindEnv("firehose.heartbeat_stale_seconds", "FIREHOSE_HEARTBEAT_STALE_SEC")
+
+	// Poller heartbeat
+	viper.BindEnv("polling.heartbeat_file", "POLLER_HEARTBEAT_FILE")
+	viper.BindEnv("polling.heartbeat_port", "POLLER_HEARTBEAT_PORT")
+	viper.BindEnv("polling.heartbeat_stale_seconds", "POLLER_HEARTBEAT_STALE_SEC")
+
+	// Moderation
+	viper.BindEnv("moderation.sensitive_labels", "SENSITIVE_LABELS")
+	viper.BindEnv("moderation.sensitive_domains", "SENSITIVE_DOMAINS")
+	viper.BindEnv("moderation.image_classifier_url", "IMAGE_CLASSIFIER_URL")
+
+	// Warehouse export
+	viper.BindEnv("warehouse.dir", "WAREHOUSE_DIR")
+	viper.BindEnv("warehouse.s3_bucket", "WAREHOUSE_S3_BUCKET")
+	viper.BindEnv("warehouse.s3_prefix", "WAREHOUSE_S3_PREFIX")
+	viper.BindEnv("warehouse.s3_region", "AWS_REGION")
+	viper.BindEnv("warehouse.s3_endpoint", "WAREHOUSE_S3_ENDPOINT")
+	viper.BindEnv("warehouse.s3_access_key", "AWS_ACCESS_KEY_ID")
+	viper.BindEnv("warehouse.s3_secret_key", "AWS_SECRET_ACCESS_KEY")
+	viper.BindEnv("warehouse.backfill_days", "WAREHOUSE_BACKFILL_DAYS")
 }
 
 // getStringWithEnvFallback gets a string value, preferring env var over config file
@@ -248,3 +895,82 @@ func getIntWithEnvFallback(viperKey, envKey string, defaultVal int) int {
 	}
 	return defaultVal
 }
+
+// getBoolWithEnvFallback gets a bool value, preferring env var over config file
+func getBoolWithEnvFallback(viperKey, envKey string, defaultVal bool) bool {
+	// Check environment variable first
+	if val := os.Getenv(envKey); val != "" {
+		if boolVal, err := strconv.ParseBool(val); err == nil {
+			return boolVal
+		}
+	}
+	// Then check viper (config file)
+	if viper.IsSet(viperKey) {
+		return viper.GetBool(viperKey)
+	}
+	return defaultVal
+}
+
+// getFloatWithEnvFallback gets a float value, preferring env var over config file
+func getFloatWithEnvFallback(viperKey, envKey string, defaultVal float64) float64 {
+	// Check environment variable first
+	if val := os.Getenv(envKey); val != "" {
+		if floatVal, err := strconv.ParseFloat(val, 64); err == nil {
+			return floatVal
+		}
+	}
+	// Then check viper (config file)
+	if viper.IsSet(viperKey) {
+		return viper.GetFloat64(viperKey)
+	}
+	return defaultVal
+}
+
+// getStringListWithEnvFallback gets a list value, preferring a comma-separated
+// env var over the config file (YAML list or comma-separated string)
+func getStringListWithEnvFallback(viperKey, envKey string, defaultVal []string) []string {
+	// Check environment variable first
+	if val := os.Getenv(envKey); val != "" {
+		return splitList(val)
+	}
+	// Then check viper (config file)
+	if viper.IsSet(viperKey) {
+		if list := viper.GetStringSlice(viperKey); len(list) == 1 {
+			return splitList(list[0])
+		} else if len(list) > 0 {
+			return list
+		}
+	}
+	return defaultVal
+}
+
+// getFeatureFlags collects feature flags from the features map in the
+// config file and FEATURE_<NAME> environment variables, which win
+func getFeatureFlags() map[string]bool {
+	flags := map[string]bool{}
+	for name := range viper.GetStringMap("features") {
+		flags[name] = viper.GetBool("features." + name)
+	}
+	for _, env := range os.Environ() {
+		key, val, _ := strings.Cut(env, "=")
+		name, ok := strings.CutPrefix(key, "FEATURE_")
+		if !ok || name == "" {
+			continue
+		}
+		if enabled, err := strconv.ParseBool(val); err == nil {
+			flags[strings.ToLower(name)] = enabled
+		}
+	}
+	return flags
+}
+
+// splitList splits a comma-separated string, trimming spaces and dropping empties
+func splitList(val string) []string {
+	var items []string
+	for _, item := range strings.Split(val, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
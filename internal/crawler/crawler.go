@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"time"
 
 	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/bluesky"
 	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/database"
@@ -212,6 +213,55 @@ func (c *Crawler) SyncFirstDegree(ctx context.Context, myHandle string) error {
 	return nil
 }
 
+// CheckDormant looks up the newest post of up to limit dormant accounts and
+// returns those that posted after activeSince to the live filter. The
+// firehose never sees dormant accounts, so this is how they come back.
+// Returns the number reactivated.
+func (c *Crawler) CheckDormant(ctx context.Context, activeSince time.Time, limit int) (int, error) {
+	accounts, err := c.db.GetDormantAccounts(limit)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get dormant accounts: %w", err)
+	}
+
+	log.Printf("[INFO] Checking %d dormant accounts for new posts", len(accounts))
+
+	reactivated := 0
+	for _, account := range accounts {
+		if err := c.rateLimiter.Wait(ctx); err != nil {
+			return reactivated, err
+		}
+
+		feed, err := c.bskyClient.GetAuthorFeed(account.DID, "", 5)
+		if err != nil {
+			log.Printf("[WARN] Failed to get feed for %s: %v", account.Handle, err)
+			continue
+		}
+
+		// Newest post of the account's own (reposts carry the original's date)
+		var lastPost time.Time
+		for _, item := range feed.Feed {
+			if item.Reason == nil && item.Post.Author.DID == account.DID && item.Post.Record.CreatedAt.After(lastPost) {
+				lastPost = item.Post.Record.CreatedAt
+			}
+		}
+		if !lastPost.After(activeSince) {
+			if err := c.db.MarkDormantChecked(account.DID); err != nil {
+				log.Printf("[WARN] Failed to mark %s checked: %v", account.Handle, err)
+			}
+			continue
+		}
+
+		if err := c.db.TouchNetworkAccount(account.DID, lastPost); err != nil {
+			log.Printf("[WARN] Failed to reactivate %s: %v", account.Handle, err)
+			continue
+		}
+		log.Printf("[INFO] Reactivated %s (posted %s)", account.Handle, lastPost.Format(time.RFC3339))
+		reactivated++
+	}
+
+	return reactivated, nil
+}
+
 // GetStats returns network statistics
 func (c *Crawler) GetStats() (map[string]interface{}, error) {
 	return c.db.GetNetworkStats()
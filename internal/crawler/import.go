@@ -0,0 +1,116 @@
+package crawler
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/bluesky"
+)
+
+// MaxImportAccounts caps the accounts one import may list
+const MaxImportAccounts = 10000
+
+// ImportProgress reports a running import after each batch
+type ImportProgress struct {
+	Total     int      `json:"total"`
+	Processed int      `json:"processed"`
+	Imported  int      `json:"imported"`
+	NotFound  []string `json:"not_found"` // Resolved to no account
+	Failed    []string `json:"failed"`    // Resolved, but couldn't be saved
+}
+
+// ParseAccountList reads handles or DIDs from a newline-separated list or a
+// CSV whose first column holds them. Blank lines, # comments, a header row
+// and leading @s are skipped; duplicates are dropped.
+func ParseAccountList(r io.Reader) ([]string, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	reader.Comment = '#'
+
+	var accounts []string
+	seen := make(map[string]bool)
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		account := strings.ToLower(strings.TrimPrefix(strings.TrimSpace(record[0]), "@"))
+		if account == "" || account == "handle" || account == "did" || seen[account] {
+			continue
+		}
+		if !strings.HasPrefix(account, "did:") && !strings.Contains(account, ".") {
+			return nil, fmt.Errorf("invalid handle %q", record[0])
+		}
+		seen[account] = true
+		accounts = append(accounts, account)
+		if len(accounts) > MaxImportAccounts {
+			return nil, fmt.Errorf("too many accounts (max %d)", MaxImportAccounts)
+		}
+	}
+	return accounts, nil
+}
+
+// ImportAccounts adds listed accounts (handles or DIDs) as follows and
+// 1st-degree network accounts, as if followed from the authenticated
+// account. Handles are resolved to DIDs in getProfiles batches, which also
+// fill in display names and avatars. progress is called after each batch.
+func (c *Crawler) ImportAccounts(ctx context.Context, accounts []string, progress func(ImportProgress)) (ImportProgress, error) {
+	result := ImportProgress{Total: len(accounts), NotFound: []string{}, Failed: []string{}}
+
+	for start := 0; start < len(accounts); start += bluesky.MaxProfilesPerRequest {
+		batch := accounts[start:min(start+bluesky.MaxProfilesPerRequest, len(accounts))]
+		if err := c.rateLimiter.Wait(ctx); err != nil {
+			return result, err
+		}
+		profiles, err := c.bskyClient.GetProfiles(batch)
+		if err != nil {
+			return result, fmt.Errorf("failed to resolve accounts: %w", err)
+		}
+
+		resolved := make(map[string]bool, len(profiles))
+		for _, profile := range profiles {
+			resolved[strings.ToLower(profile.Handle)] = true
+			resolved[profile.DID] = true
+
+			var displayName, avatarURL *string
+			if profile.DisplayName != "" {
+				displayName = &profile.DisplayName
+			}
+			if profile.Avatar != "" {
+				avatarURL = &profile.Avatar
+			}
+			if err := c.db.AddFollow(profile.DID, profile.Handle, displayName, avatarURL); err != nil {
+				log.Printf("[WARN] Failed to import follow %s: %v", profile.Handle, err)
+				result.Failed = append(result.Failed, profile.Handle)
+				continue
+			}
+			if err := c.db.UpsertNetworkAccount(profile.DID, profile.Handle, displayName, avatarURL, 1, 1, []string{c.myDID}); err != nil {
+				log.Printf("[WARN] Failed to import 1st-degree account %s: %v", profile.Handle, err)
+				result.Failed = append(result.Failed, profile.Handle)
+				continue
+			}
+			result.Imported++
+		}
+		for _, account := range batch {
+			if !resolved[account] {
+				result.NotFound = append(result.NotFound, account)
+			}
+		}
+
+		result.Processed += len(batch)
+		if progress != nil {
+			progress(result)
+		}
+	}
+	return result, nil
+}
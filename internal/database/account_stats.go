@@ -0,0 +1,81 @@
+package database
+
+import (
+	"database/sql"
+	"time"
+)
+
+// AccountStats holds ingestion counters for one author DID
+type AccountStats struct {
+	DID           string     `db:"did" json:"did"`
+	PostsSeen     int64      `db:"posts_seen" json:"posts_seen"`
+	URLsExtracted int64      `db:"urls_extracted" json:"urls_extracted"`
+	TrendingLinks int64      `db:"trending_links" json:"trending_links"`
+	FirstPostAt   *time.Time `db:"first_post_at" json:"first_post_at"`
+	LastPostAt    *time.Time `db:"last_post_at" json:"last_post_at"`
+}
+
+// RecordAccountPost counts a post by did from which urls links were extracted
+func (db *DB) RecordAccountPost(did string, urls int, postedAt time.Time) error {
+	query := `
+		INSERT INTO account_stats (did, posts_seen, urls_extracted, first_post_at, last_post_at)
+		VALUES ($1, 1, $2, $3, $3)
+		ON CONFLICT (did) DO UPDATE SET
+			posts_seen = account_stats.posts_seen + 1,
+			urls_extracted = account_stats.urls_extracted + EXCLUDED.urls_extracted,
+			first_post_at = LEAST(account_stats.first_post_at, EXCLUDED.first_post_at),
+			last_post_at = GREATEST(account_stats.last_post_at, EXCLUDED.last_post_at)
+	`
+	_, err := db.Exec(query, did, urls, postedAt)
+	return err
+}
+
+// CreditTrendingSharers marks links that have reached threshold shares as
+// trended and adds one to trending_links for each of their sharers. A link is
+// only credited the first time. Returns the number of links credited.
+func (db *DB) CreditTrendingSharers(threshold int) (int, error) {
+	query := `
+		WITH trended AS (
+			UPDATE links
+			SET trended_at = NOW()
+			WHERE trended_at IS NULL
+			  AND id IN (
+				SELECT link_id FROM post_links
+				GROUP BY link_id
+				HAVING COUNT(*) >= $1
+			  )
+			RETURNING id
+		),
+		sharers AS (
+			SELECT COALESCE(p.author_did, p.author_handle) as did, COUNT(DISTINCT pl.link_id) as links
+			FROM trended t
+			JOIN post_links pl ON pl.link_id = t.id
+			JOIN posts p ON p.id = pl.post_id
+			GROUP BY 1
+		),
+		credited AS (
+			INSERT INTO account_stats (did, trending_links)
+			SELECT did, links FROM sharers
+			ON CONFLICT (did) DO UPDATE SET
+				trending_links = account_stats.trending_links + EXCLUDED.trending_links
+		)
+		SELECT COUNT(*) FROM trended
+	`
+
+	var count int
+	err := db.Get(&count, query, threshold)
+	return count, err
+}
+
+// GetAccountStats returns the counters for a DID, or nil if it has none
+func (db *DB) GetAccountStats(did string) (*AccountStats, error) {
+	var stats AccountStats
+	err := db.Get(&stats, `SELECT * FROM account_stats WHERE did = $1`, did)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
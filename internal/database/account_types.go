@@ -0,0 +1,55 @@
+package database
+
+import (
+	"github.com/lib/pq"
+)
+
+// Account types of network accounts
+const (
+	AccountNewsOrg    = "news_org"
+	AccountJournalist = "journalist"
+	AccountIndividual = "individual"
+)
+
+// IsAccountType reports whether t is a known account type
+func IsAccountType(t string) bool {
+	return t == AccountNewsOrg || t == AccountJournalist || t == AccountIndividual
+}
+
+// AccountTypeRules configures InferAccountTypes
+type AccountTypeRules struct {
+	NewsDomains      []string // Handles on these domains are news orgs; on their subdomains, journalists
+	NewsOrgLabels    []string // Account labels marking a news org
+	JournalistLabels []string // Account labels marking a journalist
+}
+
+// InferAccountTypes re-types network accounts an admin hasn't typed. An
+// account is a news org when its handle is a news domain (nytimes.com) or it
+// carries a news org label, a journalist when its handle is a subdomain of
+// one (jane.nytimes.com, a domain-verified staff handle) or it carries a
+// journalist label, and an individual otherwise. Returns how many accounts
+// changed type.
+func (db *DB) InferAccountTypes(rules AccountTypeRules) (int64, error) {
+	query := `
+		UPDATE network_accounts n SET account_type = c.inferred
+		FROM (
+			SELECT did, CASE
+				WHEN labels && $2::text[] OR EXISTS (
+					SELECT 1 FROM UNNEST($1::text[]) d WHERE LOWER(handle) = LOWER(d)
+				) THEN 'news_org'
+				WHEN labels && $3::text[] OR EXISTS (
+					SELECT 1 FROM UNNEST($1::text[]) d WHERE LOWER(handle) LIKE '%.' || LOWER(d)
+				) THEN 'journalist'
+				ELSE 'individual'
+			END AS inferred
+			FROM network_accounts
+			WHERE NOT account_type_manual
+		) c
+		WHERE n.did = c.did AND n.account_type <> c.inferred
+	`
+	result, err := db.Exec(query, pq.Array(rules.NewsDomains), pq.Array(rules.NewsOrgLabels), pq.Array(rules.JournalistLabels))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,71 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"hash/fnv"
+)
+
+// AdvisoryLock is a session-level Postgres advisory lock held on a dedicated
+// connection. The lock is released when the connection closes, so a crashed
+// holder loses it as soon as the server notices the dropped session.
+type AdvisoryLock struct {
+	conn *sql.Conn
+	key  int64
+}
+
+// TryAdvisoryLock attempts to take the advisory lock for key without
+// blocking. Returns nil if another session holds it.
+func (db *DB) TryAdvisoryLock(ctx context.Context, key int64) (*AdvisoryLock, error) {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get connection: %w", err)
+	}
+
+	var acquired bool
+	if err := conn.QueryRowContext(ctx, `SELECT pg_try_advisory_lock($1)`, key).Scan(&acquired); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to try advisory lock: %w", err)
+	}
+	if !acquired {
+		conn.Close()
+		return nil, nil
+	}
+	return &AdvisoryLock{conn: conn, key: key}, nil
+}
+
+// Check verifies the session holding the lock is still alive
+func (l *AdvisoryLock) Check(ctx context.Context) error {
+	return l.conn.PingContext(ctx)
+}
+
+// Release unlocks and returns the connection to the pool
+func (l *AdvisoryLock) Release() error {
+	_, err := l.conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, l.key)
+	if closeErr := l.conn.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// AcquireAdvisoryLock waits for the advisory lock for key, or until ctx is done
+func (db *DB) AcquireAdvisoryLock(ctx context.Context, key int64) (*AdvisoryLock, error) {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get connection: %w", err)
+	}
+
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, key); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to take advisory lock: %w", err)
+	}
+	return &AdvisoryLock{conn: conn, key: key}, nil
+}
+
+// LockKey maps a lock name onto the advisory lock key space
+func LockKey(name string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte(name))
+	return int64(h.Sum64())
+}
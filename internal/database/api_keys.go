@@ -0,0 +1,142 @@
+package database
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// API key statuses
+const (
+	APIKeyPending = "pending" // Requested, waiting for an admin
+	APIKeyActive  = "active"
+	APIKeyRevoked = "revoked"
+)
+
+// APIKey is a public API key. The key itself is never stored, only its hash.
+type APIKey struct {
+	ID            int            `db:"id" json:"id"`
+	KeyHash       string         `db:"key_hash" json:"-"`
+	KeyPrefix     string         `db:"key_prefix" json:"key_prefix"`
+	Name          string         `db:"name" json:"name"`
+	Contact       string         `db:"contact" json:"contact"`
+	Scopes        pq.StringArray `db:"scopes" json:"scopes"`
+	RatePerMinute int            `db:"rate_per_minute" json:"rate_per_minute"`
+	QuotaPerDay   int            `db:"quota_per_day" json:"quota_per_day"`
+	Status        string         `db:"status" json:"status"`
+	CreatedAt     time.Time      `db:"created_at" json:"created_at"`
+	ApprovedAt    *time.Time     `db:"approved_at" json:"approved_at"`
+	RevokedAt     *time.Time     `db:"revoked_at" json:"revoked_at"`
+	LastUsedAt    *time.Time     `db:"last_used_at" json:"last_used_at"`
+}
+
+// APIKeyUsage counts a key's requests on one UTC day
+type APIKeyUsage struct {
+	Day      time.Time `db:"day" json:"day"`
+	Requests int64     `db:"requests" json:"requests"`
+	Rejected int64     `db:"rejected" json:"rejected"` // Over quota or out of scope
+}
+
+// CreateAPIKey stores a pending key request and returns it
+func (db *DB) CreateAPIKey(keyHash, keyPrefix, name, contact string, scopes []string, ratePerMinute, quotaPerDay int) (*APIKey, error) {
+	query := `
+		INSERT INTO api_keys (key_hash, key_prefix, name, contact, scopes, rate_per_minute, quota_per_day)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING *
+	`
+
+	key := &APIKey{}
+	err := db.Get(key, query, keyHash, keyPrefix, name, contact, pq.Array(scopes), ratePerMinute, quotaPerDay)
+	return key, err
+}
+
+// GetAPIKeyByHash returns the key with a hash, or nil if there is none
+func (db *DB) GetAPIKeyByHash(keyHash string) (*APIKey, error) {
+	key := &APIKey{}
+	err := db.Get(key, `SELECT * FROM api_keys WHERE key_hash = $1`, keyHash)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return key, err
+}
+
+// GetAPIKeys returns keys with a status ("" = all), newest first
+func (db *DB) GetAPIKeys(status string) ([]APIKey, error) {
+	var keys []APIKey
+	err := db.Select(&keys, `
+		SELECT * FROM api_keys
+		WHERE $1 = '' OR status = $1
+		ORDER BY created_at DESC
+	`, status)
+	return keys, err
+}
+
+// ApproveAPIKey activates a pending or revoked key, optionally replacing its
+// scopes and limits (nil scopes or limits <= 0 keep the requested ones).
+// Returns nil if the key doesn't exist.
+func (db *DB) ApproveAPIKey(id int, scopes []string, ratePerMinute, quotaPerDay int) (*APIKey, error) {
+	query := `
+		UPDATE api_keys SET
+			status = 'active',
+			approved_at = NOW(),
+			revoked_at = NULL,
+			scopes = COALESCE($2, scopes),
+			rate_per_minute = CASE WHEN $3 > 0 THEN $3 ELSE rate_per_minute END,
+			quota_per_day = CASE WHEN $4 > 0 THEN $4 ELSE quota_per_day END
+		WHERE id = $1
+		RETURNING *
+	`
+
+	var scopesArg interface{}
+	if scopes != nil {
+		scopesArg = pq.Array(scopes)
+	}
+
+	key := &APIKey{}
+	err := db.Get(key, query, id, scopesArg, ratePerMinute, quotaPerDay)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return key, err
+}
+
+// RevokeAPIKey disables a key. Returns false if it doesn't exist.
+func (db *DB) RevokeAPIKey(id int) (bool, error) {
+	result, err := db.Exec(`UPDATE api_keys SET status = 'revoked', revoked_at = NOW() WHERE id = $1`, id)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+// RecordAPIKeyUsage counts a request by a key against today's usage, as
+// rejected when it wasn't served
+func (db *DB) RecordAPIKeyUsage(id int, rejected bool) error {
+	query := `
+		WITH used AS (
+			UPDATE api_keys SET last_used_at = NOW() WHERE id = $1
+		)
+		INSERT INTO api_key_usage (key_id, day, requests, rejected)
+		VALUES ($1, (NOW() AT TIME ZONE 'UTC')::date, 1, CASE WHEN $2 THEN 1 ELSE 0 END)
+		ON CONFLICT (key_id, day) DO UPDATE SET
+			requests = api_key_usage.requests + 1,
+			rejected = api_key_usage.rejected + EXCLUDED.rejected
+	`
+	_, err := db.Exec(query, id, rejected)
+	return err
+}
+
+// GetAPIKeyUsage returns a key's daily usage over the last days days, newest
+// first
+func (db *DB) GetAPIKeyUsage(id, days int) ([]APIKeyUsage, error) {
+	var usage []APIKeyUsage
+	err := db.Select(&usage, `
+		SELECT day, requests, rejected
+		FROM api_key_usage
+		WHERE key_id = $1 AND day > (NOW() AT TIME ZONE 'UTC')::date - $2::int
+		ORDER BY day DESC
+	`, id, days)
+	return usage, err
+}
@@ -0,0 +1,162 @@
+package database
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// BackupTable describes a table copied by backups
+type BackupTable struct {
+	Name   string
+	Query  string // Rows to export; defaults to the whole table
+	Serial string // SERIAL column whose sequence is advanced after a restore
+}
+
+// BackupTables lists the aggregator state worth keeping across machines, in
+// restore order (referenced tables first). Posts are left out: they age out
+// within a day and the firehose refills them. Shard leases are dropped so a
+// restored shard can be claimed straight away.
+var BackupTables = []BackupTable{
+	{Name: "follows"},
+	{Name: "network_accounts"},
+	{Name: "links", Serial: "id"},
+	{Name: "link_metadata_sources"},
+	{Name: "link_metadata_history", Serial: "id"},
+	{Name: "daily_link_shares"},
+	{Name: "link_clicks"},
+	{Name: "cohorts", Serial: "id"},
+	{Name: "cohort_members"},
+	{Name: "jetstream_state"},
+	{Name: "ingest_ledger"},
+	{Name: "account_stats"},
+	{Name: "poll_state"},
+	{Name: "firehose_shards", Query: `SELECT shard_count, shard_id, cursor_time_us FROM firehose_shards`},
+}
+
+// ExportTableRows streams every row of a backup table as a JSON object
+func (db *DB) ExportTableRows(table BackupTable, fn func(row json.RawMessage) error) error {
+	query := table.Query
+	if query == "" {
+		query = `SELECT * FROM ` + pq.QuoteIdentifier(table.Name)
+	}
+
+	rows, err := db.Query(`SELECT row_to_json(t) FROM (` + query + `) t`)
+	if err != nil {
+		return fmt.Errorf("failed to export %s: %w", table.Name, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var row []byte
+		if err := rows.Scan(&row); err != nil {
+			return fmt.Errorf("failed to scan %s row: %w", table.Name, err)
+		}
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// CountTableRows returns the number of rows in a table
+func (db *DB) CountTableRows(table string) (int, error) {
+	var count int
+	err := db.Get(&count, `SELECT COUNT(*) FROM `+pq.QuoteIdentifier(table))
+	return count, err
+}
+
+// Restore is a transaction importing backup rows
+type Restore struct {
+	tx       *sqlx.Tx
+	inserted map[string]int
+	skipped  map[string]int
+}
+
+// BeginRestore starts a restore transaction. The link_created outbox trigger
+// is disabled for its duration, so restored links aren't announced to
+// downstream sinks as new.
+func (db *DB) BeginRestore() (*Restore, error) {
+	tx, err := db.Beginx()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	if _, err := tx.Exec(`ALTER TABLE links DISABLE TRIGGER links_outbox_created_trigger`); err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to disable outbox trigger: %w", err)
+	}
+	return &Restore{tx: tx, inserted: make(map[string]int), skipped: make(map[string]int)}, nil
+}
+
+// InsertRow inserts one exported row. Only the columns present in the row
+// are written, so archives from older schemas get defaults for newer
+// columns. Rows conflicting with existing ones are skipped.
+func (r *Restore) InsertRow(table string, row json.RawMessage) error {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(row, &fields); err != nil {
+		return fmt.Errorf("invalid %s row: %w", table, err)
+	}
+	columns := make([]string, 0, len(fields))
+	for column := range fields {
+		columns = append(columns, pq.QuoteIdentifier(column))
+	}
+	sort.Strings(columns)
+	columnList := strings.Join(columns, ", ")
+
+	quoted := pq.QuoteIdentifier(table)
+	result, err := r.tx.Exec(`
+		INSERT INTO `+quoted+` (`+columnList+`)
+		SELECT `+columnList+` FROM json_populate_record(NULL::`+quoted+`, $1)
+		ON CONFLICT DO NOTHING
+	`, string(row))
+	if err != nil {
+		return fmt.Errorf("failed to restore %s row: %w", table, err)
+	}
+
+	if n, _ := result.RowsAffected(); n > 0 {
+		r.inserted[table]++
+	} else {
+		r.skipped[table]++
+	}
+	return nil
+}
+
+// Counts returns rows inserted and skipped (already present) per table
+func (r *Restore) Counts() (inserted, skipped map[string]int) {
+	return r.inserted, r.skipped
+}
+
+// Commit advances SERIAL sequences past the restored IDs, re-enables the
+// outbox trigger and commits
+func (r *Restore) Commit() error {
+	for _, table := range BackupTables {
+		if table.Serial == "" {
+			continue
+		}
+		quoted := pq.QuoteIdentifier(table.Name)
+		column := pq.QuoteIdentifier(table.Serial)
+		_, err := r.tx.Exec(`
+			SELECT setval(pg_get_serial_sequence($1, $2), COALESCE(MAX(`+column+`), 0) + 1, false)
+			FROM `+quoted,
+			table.Name, table.Serial)
+		if err != nil {
+			r.tx.Rollback()
+			return fmt.Errorf("failed to reset %s sequence: %w", table.Name, err)
+		}
+	}
+
+	if _, err := r.tx.Exec(`ALTER TABLE links ENABLE TRIGGER links_outbox_created_trigger`); err != nil {
+		r.tx.Rollback()
+		return fmt.Errorf("failed to re-enable outbox trigger: %w", err)
+	}
+	return r.tx.Commit()
+}
+
+// Rollback abandons the restore
+func (r *Restore) Rollback() error {
+	return r.tx.Rollback()
+}
@@ -0,0 +1,93 @@
+package database_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/testutil"
+)
+
+// exemptTables lists the tables the test creates, plus a table no migration
+// creates
+var exemptTables = []string{"story_articles", "saved_links", "annotations", "not_yet_migrated"}
+
+// TestDeleteUnsharedLinksExemptions checks cleanup keeps aged-out links that
+// stories, bookmarks or annotations still reference, across repeated runs,
+// and deletes them once the reference goes
+func TestDeleteUnsharedLinksExemptions(t *testing.T) {
+	if testing.Short() {
+		t.Skip("needs Postgres")
+	}
+	db := testutil.NewTestDB(t)
+
+	// The features owning these tables aren't migrated yet; cleanup only
+	// needs their link_id column
+	for _, table := range []string{"story_articles", "saved_links", "annotations"} {
+		if _, err := db.Exec(`CREATE TABLE ` + table + ` (id SERIAL PRIMARY KEY, link_id INTEGER NOT NULL)`); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	old := time.Now().Add(-10 * 24 * time.Hour)
+	story := testutil.AddShare(t, db, "did:plc:a", 1, "https://example.com/story", old)
+	saved := testutil.AddShare(t, db, "did:plc:a", 1, "https://example.com/saved", old)
+	annotated := testutil.AddShare(t, db, "did:plc:a", 1, "https://example.com/annotated", old)
+	stale := testutil.AddShare(t, db, "did:plc:a", 1, "https://example.com/stale", old)
+	recent := testutil.AddShare(t, db, "did:plc:a", 1, "https://example.com/recent", time.Now())
+
+	for table, linkID := range map[string]int{"story_articles": story, "saved_links": saved, "annotations": annotated} {
+		if _, err := db.Exec(`INSERT INTO `+table+` (link_id) VALUES ($1)`, linkID); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	exists := func(linkID int) bool {
+		t.Helper()
+		link, err := db.GetLinkByID(linkID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return link != nil
+	}
+	cutoff := time.Now().Add(-7 * 24 * time.Hour)
+
+	for run := 1; run <= 2; run++ {
+		deleted, err := db.DeleteUnsharedLinks(cutoff, 5, exemptTables)
+		if err != nil {
+			t.Fatalf("run %d: DeleteUnsharedLinks: %v", run, err)
+		}
+		if want := map[int]int{1: 1, 2: 0}[run]; deleted != want {
+			t.Errorf("run %d deleted %d links, want %d", run, deleted, want)
+		}
+		for name, linkID := range map[string]int{"story": story, "saved": saved, "annotated": annotated, "recent": recent} {
+			if !exists(linkID) {
+				t.Errorf("run %d deleted the %s link", run, name)
+			}
+		}
+		if exists(stale) {
+			t.Errorf("run %d kept the unreferenced stale link", run)
+		}
+	}
+
+	// Removing the bookmark frees its link
+	if _, err := db.Exec(`DELETE FROM saved_links WHERE link_id = $1`, saved); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.DeleteUnsharedLinks(cutoff, 5, exemptTables); err != nil {
+		t.Fatal(err)
+	}
+	if exists(saved) {
+		t.Error("link kept after its bookmark was removed")
+	}
+	if !exists(story) || !exists(annotated) {
+		t.Error("referenced links deleted with the unbookmarked one")
+	}
+
+	// With no exemptions, the remaining aged-out links go too
+	if _, err := db.DeleteUnsharedLinks(cutoff, 5, nil); err != nil {
+		t.Fatal(err)
+	}
+	if exists(story) || exists(annotated) || !exists(recent) {
+		t.Error("without exemptions, want only the recent link kept")
+	}
+}
@@ -0,0 +1,19 @@
+package database
+
+import (
+	"time"
+)
+
+// RecordClick counts a click on a link for day (a date at midnight UTC)
+func (db *DB) RecordClick(linkID int, day time.Time) error {
+	query := `
+		WITH daily AS (
+			INSERT INTO link_clicks (link_id, day, clicks)
+			VALUES ($1, $2::date, 1)
+			ON CONFLICT (link_id, day) DO UPDATE SET clicks = link_clicks.clicks + 1
+		)
+		UPDATE links SET click_count = click_count + 1 WHERE id = $1
+	`
+	_, err := db.Exec(query, linkID, day)
+	return err
+}
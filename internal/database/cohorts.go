@@ -0,0 +1,91 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Cohort is a named set of accounts that trending can be restricted to
+type Cohort struct {
+	ID          int            `db:"id" json:"id"`
+	Name        string         `db:"name" json:"name"`
+	Description *string        `db:"description" json:"description"`
+	Members     pq.StringArray `db:"members" json:"members"` // Member DIDs
+	CreatedAt   time.Time      `db:"created_at" json:"created_at"`
+	UpdatedAt   time.Time      `db:"updated_at" json:"updated_at"`
+}
+
+const cohortColumns = `
+		c.id, c.name, c.description, c.created_at, c.updated_at,
+		COALESCE(
+			(SELECT ARRAY_AGG(m.did ORDER BY m.did) FROM cohort_members m WHERE m.cohort_id = c.id),
+			'{}'
+		) AS members
+	`
+
+// GetCohorts returns all cohorts with their members, by name
+func (db *DB) GetCohorts() ([]Cohort, error) {
+	var cohorts []Cohort
+	err := db.Select(&cohorts, `SELECT `+cohortColumns+` FROM cohorts c ORDER BY c.name`)
+	return cohorts, err
+}
+
+// GetCohortByName returns a cohort with its members, or nil if it doesn't exist
+func (db *DB) GetCohortByName(name string) (*Cohort, error) {
+	cohort := &Cohort{}
+	err := db.Get(cohort, `SELECT `+cohortColumns+` FROM cohorts c WHERE c.name = $1`, name)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return cohort, err
+}
+
+// SaveCohort creates or replaces a cohort, setting its members to dids
+func (db *DB) SaveCohort(name string, description *string, dids []string) error {
+	tx, err := db.Beginx()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var cohortID int
+	err = tx.Get(&cohortID, `
+		INSERT INTO cohorts (name, description)
+		VALUES ($1, $2)
+		ON CONFLICT (name)
+		DO UPDATE SET description = $2, updated_at = NOW()
+		RETURNING id
+	`, name, description)
+	if err != nil {
+		return fmt.Errorf("failed to save cohort: %w", err)
+	}
+
+	if _, err := tx.Exec(`DELETE FROM cohort_members WHERE cohort_id = $1`, cohortID); err != nil {
+		return fmt.Errorf("failed to clear cohort members: %w", err)
+	}
+	if len(dids) > 0 {
+		_, err = tx.Exec(`
+			INSERT INTO cohort_members (cohort_id, did)
+			SELECT $1, UNNEST($2::text[])
+			ON CONFLICT DO NOTHING
+		`, cohortID, pq.StringArray(dids))
+		if err != nil {
+			return fmt.Errorf("failed to add cohort members: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// DeleteCohort removes a cohort and its members, reporting whether it existed
+func (db *DB) DeleteCohort(name string) (bool, error) {
+	result, err := db.Exec(`DELETE FROM cohorts WHERE name = $1`, name)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	return rows > 0, err
+}
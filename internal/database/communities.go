@@ -0,0 +1,149 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Community is a group of network accounts detected from the follow graph
+type Community struct {
+	ID         int       `db:"id" json:"id"`
+	Name       *string   `db:"name" json:"name,omitempty"` // Set by an admin
+	Label      string    `db:"label" json:"label"`         // Generated from members' handles
+	Size       int       `db:"size" json:"size"`
+	DetectedAt time.Time `db:"detected_at" json:"detected_at"`
+}
+
+// CommunityAssignment is one community found by a detection run
+type CommunityAssignment struct {
+	ID      int // Earlier community it continues (0 = new)
+	Label   string
+	Members []string // DIDs
+}
+
+// LinkCommunity is a community sharing a link
+type LinkCommunity struct {
+	ID      int    `db:"id" json:"id"`
+	Name    string `db:"name" json:"name"` // Admin-given name, or the generated label
+	Sharers int    `db:"sharers" json:"sharers"`
+}
+
+// GetCommunityMembership returns each network account's community, by DID
+func (db *DB) GetCommunityMembership() (map[string]int, error) {
+	var rows []struct {
+		DID         string `db:"did"`
+		CommunityID int    `db:"community_id"`
+	}
+	err := db.Select(&rows, `SELECT did, community_id FROM network_accounts WHERE community_id IS NOT NULL`)
+	if err != nil {
+		return nil, err
+	}
+
+	membership := make(map[string]int, len(rows))
+	for _, row := range rows {
+		membership[row.DID] = row.CommunityID
+	}
+	return membership, nil
+}
+
+// ReplaceCommunities stores the result of a detection run: continued
+// communities keep their ID and name, new ones are created, and
+// communities no longer found are deleted. Accounts in no community are
+// left without one.
+func (db *DB) ReplaceCommunities(communities []CommunityAssignment) error {
+	tx, err := db.Beginx()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`UPDATE network_accounts SET community_id = NULL WHERE community_id IS NOT NULL`); err != nil {
+		return fmt.Errorf("failed to clear communities: %w", err)
+	}
+
+	ids := make([]int, len(communities))
+	for i, community := range communities {
+		id := community.ID
+		if id != 0 {
+			_, err = tx.Exec(`
+				UPDATE communities SET label = $2, size = $3, detected_at = NOW()
+				WHERE id = $1
+			`, id, community.Label, len(community.Members))
+		} else {
+			err = tx.Get(&id, `
+				INSERT INTO communities (label, size)
+				VALUES ($1, $2)
+				RETURNING id
+			`, community.Label, len(community.Members))
+		}
+		if err != nil {
+			return fmt.Errorf("failed to save community: %w", err)
+		}
+
+		_, err = tx.Exec(`UPDATE network_accounts SET community_id = $1 WHERE did = ANY($2)`, id, pq.StringArray(community.Members))
+		if err != nil {
+			return fmt.Errorf("failed to assign community members: %w", err)
+		}
+		ids[i] = id
+	}
+
+	if _, err := tx.Exec(`DELETE FROM communities WHERE id <> ALL($1)`, pq.Array(ids)); err != nil {
+		return fmt.Errorf("failed to delete old communities: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// GetCommunities returns all communities, largest first
+func (db *DB) GetCommunities() ([]Community, error) {
+	var communities []Community
+	err := db.Select(&communities, `SELECT id, name, label, size, detected_at FROM communities ORDER BY size DESC, id`)
+	return communities, err
+}
+
+// RenameCommunity sets the name shown for a community (empty = go back to
+// its generated label). Returns nil if it doesn't exist.
+func (db *DB) RenameCommunity(id int, name string) (*Community, error) {
+	community := &Community{}
+	err := db.Get(community, `
+		UPDATE communities SET name = NULLIF($2, '')
+		WHERE id = $1
+		RETURNING id, name, label, size, detected_at
+	`, id, name)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return community, err
+}
+
+// GetLinkCommunities returns, for each of the given links, the communities
+// of its sharers with at least minSharers of them, most sharers first
+func (db *DB) GetLinkCommunities(linkIDs []int, minSharers int) (map[int][]LinkCommunity, error) {
+	var rows []struct {
+		LinkID int `db:"link_id"`
+		LinkCommunity
+	}
+	err := db.Select(&rows, `
+		SELECT pl.link_id, c.id, COALESCE(c.name, c.label) as name, COUNT(DISTINCT p.author_did) as sharers
+		FROM post_links pl
+		JOIN posts p ON p.id = pl.post_id
+		JOIN network_accounts n ON n.did = p.author_did
+		JOIN communities c ON c.id = n.community_id
+		WHERE pl.link_id = ANY($1)
+		GROUP BY pl.link_id, c.id
+		HAVING COUNT(DISTINCT p.author_did) >= $2
+		ORDER BY pl.link_id, sharers DESC, c.id
+	`, pq.Array(linkIDs), minSharers)
+	if err != nil {
+		return nil, err
+	}
+
+	communities := make(map[int][]LinkCommunity)
+	for _, row := range rows {
+		communities[row.LinkID] = append(communities[row.LinkID], row.LinkCommunity)
+	}
+	return communities, nil
+}
@@ -0,0 +1,45 @@
+package database
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// CoordinatedLink is a link shared with copied text, queued for review
+type CoordinatedLink struct {
+	ID            int            `db:"id" json:"id"`
+	NormalizedURL string         `db:"normalized_url" json:"url"`
+	Title         *string        `db:"title" json:"title,omitempty"`
+	ShareCount    int            `db:"share_count" json:"share_count"`
+	CopiedShares  int            `db:"copied_shares" json:"copied_shares"`
+	CopiedBy      pq.StringArray `db:"copied_by" json:"copied_by"` // Handles posting copied text
+	LastCopiedAt  time.Time      `db:"last_copied_at" json:"last_copied_at"`
+}
+
+// GetCoordinatedLinks returns links with at least minCopies copied shares
+// posted since the given time, most copied first
+func (db *DB) GetCoordinatedLinks(since time.Time, minCopies, limit int) ([]CoordinatedLink, error) {
+	query := `
+		SELECT
+			l.id,
+			l.normalized_url,
+			l.title,
+			(SELECT COUNT(*) FROM post_links a WHERE a.link_id = l.id) as share_count,
+			COUNT(*) as copied_shares,
+			ARRAY_AGG(DISTINCT p.author_handle) as copied_by,
+			MAX(p.created_at) as last_copied_at
+		FROM links l
+		JOIN post_links pl ON pl.link_id = l.id AND pl.copied
+		JOIN posts p ON p.id = pl.post_id
+		WHERE p.created_at > $1
+		GROUP BY l.id
+		HAVING COUNT(*) >= $2
+		ORDER BY copied_shares DESC, last_copied_at DESC
+		LIMIT $3
+	`
+
+	var links []CoordinatedLink
+	err := db.Select(&links, query, since, minCopies, limit)
+	return links, err
+}
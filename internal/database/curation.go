@@ -0,0 +1,88 @@
+package database
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Trust levels an admin can give a network account
+const (
+	TrustLow    = "low"
+	TrustNormal = "normal"
+	TrustHigh   = "high"
+)
+
+// IsTrustLevel reports whether level is a known trust level
+func IsTrustLevel(level string) bool {
+	return level == TrustLow || level == TrustNormal || level == TrustHigh
+}
+
+// AccountCuration is what an admin recorded about a network account
+type AccountCuration struct {
+	DID         string     `db:"did" json:"did"`
+	Handle      string     `db:"handle" json:"handle"`
+	DisplayName *string    `db:"display_name" json:"display_name"`
+	Degree      int        `db:"degree" json:"degree"`
+	Notes       *string    `db:"curator_notes" json:"notes"`
+	TrustLevel  string     `db:"trust_level" json:"trust_level"`
+	Pinned      bool       `db:"pinned" json:"pinned"` // Shares get a ranking boost and a badge
+	AccountType string     `db:"account_type" json:"account_type"`
+	TypeManual  bool       `db:"account_type_manual" json:"account_type_manual"` // Set by an admin rather than inferred
+	CuratedAt   *time.Time `db:"curated_at" json:"curated_at"`
+}
+
+const curationColumns = `did, handle, display_name, degree, curator_notes, trust_level, pinned, account_type, account_type_manual, curated_at`
+
+// GetCuratedAccounts returns the network accounts an admin pinned, took
+// notes on, typed or gave a trust level other than normal, pinned ones first
+func (db *DB) GetCuratedAccounts() ([]AccountCuration, error) {
+	var accounts []AccountCuration
+	err := db.Select(&accounts, `
+		SELECT `+curationColumns+`
+		FROM network_accounts
+		WHERE pinned OR curator_notes IS NOT NULL OR trust_level <> 'normal' OR account_type_manual
+		ORDER BY pinned DESC, handle
+	`)
+	return accounts, err
+}
+
+// GetAccountCuration returns a network account's curation, or nil if the
+// account isn't in the network
+func (db *DB) GetAccountCuration(did string) (*AccountCuration, error) {
+	var account AccountCuration
+	err := db.Get(&account, `SELECT `+curationColumns+` FROM network_accounts WHERE did = $1`, did)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
+
+// UpdateAccountCuration changes a network account's curation and returns
+// it. nil arguments are left as they are; empty notes clear them, and an
+// empty account type hands the type back to InferAccountTypes. Returns nil
+// if the account isn't in the network.
+func (db *DB) UpdateAccountCuration(did string, notes, trustLevel *string, pinned *bool, accountType *string) (*AccountCuration, error) {
+	query := `
+		UPDATE network_accounts SET
+			curator_notes = CASE WHEN $2::text IS NULL THEN curator_notes ELSE NULLIF($2, '') END,
+			trust_level = COALESCE($3, trust_level),
+			pinned = COALESCE($4, pinned),
+			account_type = COALESCE(NULLIF($5, ''), account_type),
+			account_type_manual = CASE WHEN $5::text IS NULL THEN account_type_manual ELSE $5 <> '' END,
+			curated_at = NOW()
+		WHERE did = $1
+		RETURNING ` + curationColumns
+
+	var account AccountCuration
+	err := db.Get(&account, query, did, notes, trustLevel, pinned, accountType)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
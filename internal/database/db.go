@@ -24,33 +24,96 @@ type DB struct {
 
 // Post represents a Bluesky post in the database
 type Post struct {
-	ID           string    `db:"id"`
-	AuthorHandle string    `db:"author_handle"`
-	AuthorDID    string    `db:"author_did"`
-	AuthorDegree int       `db:"author_degree"`
-	Content      string    `db:"content"`
-	CreatedAt    time.Time `db:"created_at"`
-	IndexedAt    time.Time `db:"indexed_at"`
+	ID           string         `db:"id"`
+	AuthorHandle string         `db:"author_handle"`
+	AuthorDID    string         `db:"author_did"`
+	AuthorDegree int            `db:"author_degree"`
+	Content      string         `db:"content"`
+	IsReply      bool           `db:"is_reply"`
+	RawRecord    []byte         `db:"raw_record"`       // Original record JSON (nil if not stored)
+	Labels       pq.StringArray `db:"labels"`           // Moderation label values (self-labels or labelers)
+	Fingerprint  string         `db:"text_fingerprint"` // Normalized text hash for copy detection ("" for short posts, stored as NULL)
+	Langs        pq.StringArray `db:"langs"`            // Primary language subtags from the record (e.g. "en")
+	Source       string         `db:"source"`           // One of the IngestSource* constants ("" = untagged, stored as NULL)
+	CreatedAt    time.Time      `db:"created_at"`
+	IndexedAt    time.Time      `db:"indexed_at"`
+}
+
+// Ingest sources recorded on posts
+const (
+	IngestSourcePoller   = "poller"
+	IngestSourceFirehose = "firehose"
+	IngestSourceBackfill = "backfill"
+	IngestSourceRSS      = "rss"
+	IngestSourceMastodon = "mastodon"
+)
+
+// NormalizeLangs returns the distinct primary subtags of BCP 47 language
+// tags, lowercased ("en-US" -> "en"), the form posts.langs stores and
+// language filters match against
+func NormalizeLangs(tags []string) []string {
+	var langs []string
+	for _, tag := range tags {
+		lang := strings.ToLower(strings.TrimSpace(strings.SplitN(tag, "-", 2)[0]))
+		if lang == "" || len(lang) > 8 {
+			continue
+		}
+		duplicate := false
+		for _, seen := range langs {
+			if seen == lang {
+				duplicate = true
+				break
+			}
+		}
+		if !duplicate {
+			langs = append(langs, lang)
+		}
+	}
+	return langs
 }
 
 // Link represents a URL shared in posts
 type Link struct {
-	ID            int       `db:"id"`
-	OriginalURL   string    `db:"original_url"`
-	NormalizedURL string    `db:"normalized_url"`
-	Title         *string   `db:"title"`
-	Description   *string   `db:"description"`
-	OGImageURL    *string   `db:"og_image_url"`
-	FirstSeenAt   time.Time `db:"first_seen_at"`
-	LastFetchedAt *time.Time `db:"last_fetched_at"`
+	ID              int        `db:"id"`
+	OriginalURL     string     `db:"original_url"`
+	NormalizedURL   string     `db:"normalized_url"`
+	Title           *string    `db:"title"`
+	Description     *string    `db:"description"`
+	OGImageURL      *string    `db:"og_image_url"`
+	FirstSeenAt     time.Time  `db:"first_seen_at"`
+	LastFetchedAt   *time.Time `db:"last_fetched_at"`
+	Sensitive       bool       `db:"sensitive"`
+	SensitiveReason *string    `db:"sensitive_reason"`
+	FirstSharedBy   *string    `db:"first_shared_by"` // DID of the earliest sharer
+	FirstSharedAt   *time.Time `db:"first_shared_at"`
+	ETag            *string    `db:"etag"` // HTTP validators from the last scrape, for conditional refreshes
+	LastModified    *string    `db:"last_modified"`
+	MetadataSource  *string    `db:"metadata_source"`     // Source of the displayed metadata (bluesky or scraped)
+	MetadataPref    *string    `db:"metadata_preference"` // Source pinned by an admin, if any
+	TrendedAt       *time.Time `db:"trended_at"`          // When the link first reached the trending threshold
+	ClickCount      int        `db:"click_count"`         // Clicks through the /out redirect
+	HTTPStatus      *int       `db:"http_status"`         // Status seen by the last dead-link check (0 = unreachable)
+	StatusCheckedAt *time.Time `db:"status_checked_at"`
+	DeadAt          *time.Time `db:"dead_at"`       // When the link was found gone (nil = live or unchecked)
+	ImageMissing    bool       `db:"image_missing"` // The last scrape found no usable preview image
 }
 
 // PostLink represents the relationship between posts and links
 type PostLink struct {
-	PostID string `db:"post_id"`
-	LinkID int    `db:"link_id"`
+	PostID       string `db:"post_id"`
+	LinkID       int    `db:"link_id"`
+	RelationType string `db:"relation_type"`
+	Degree       *int   `db:"degree"`
+	Copied       bool   `db:"copied"` // Same text as another account's share of the link
 }
 
+// Relation types: how a post came to share a link
+const (
+	RelationOriginal = "original" // Link in the author's own post text or embed
+	RelationQuote    = "quote"    // Link in a post the author quoted
+	RelationRepost   = "repost"   // Author reposted a post containing the link
+)
+
 // TrendingLink represents an aggregated link with share count
 type TrendingLink struct {
 	ID            int            `db:"id"`
@@ -61,7 +124,29 @@ type TrendingLink struct {
 	OGImageURL    *string        `db:"og_image_url"`
 	ShareCount    int            `db:"share_count"`
 	LastSharedAt  time.Time      `db:"last_shared_at"`
+	FirstSharedAt *time.Time     `db:"first_shared_at"` // First share ever, not just in the window (nil = unknown)
 	Sharers       pq.StringArray `db:"sharers"`
+	Sensitive     bool           `db:"sensitive"`
+
+	// Fraction of sharers whose post or account carries a flagged label
+	// (always 0 when LabelMode is off)
+	LabeledShareRatio float64 `db:"labeled_share_ratio"`
+
+	// Shares repeating another account's text (see CollapseCopies)
+	CopiedShares int `db:"copied_shares"`
+
+	// Clicks through the /out redirect
+	ClickCount int `db:"click_count"`
+
+	// When a dead-link check found the page gone (nil = live or unchecked)
+	DeadAt *time.Time `db:"dead_at"`
+
+	// The last scrape found no usable preview image
+	ImageMissing bool `db:"image_missing"`
+
+	// Ranking score: weighted sharers (equal to ShareCount without weights;
+	// 0 from rollups)
+	Score float64 `db:"score"`
 }
 
 // Follow represents a followed account (DID)
@@ -81,6 +166,8 @@ type SharerAvatar struct {
 	DisplayName *string `db:"display_name" json:"display_name"`
 	AvatarURL   *string `db:"avatar_url" json:"avatar_url"`
 	DID         string  `db:"did" json:"did"`
+	Pinned      bool    `db:"pinned" json:"pinned,omitempty"`   // Pinned by an admin
+	AccountType string  `db:"account_type" json:"account_type"` // news_org, journalist or individual
 }
 
 // LinkPost represents a post that shared a specific link
@@ -109,15 +196,81 @@ func NewDB(connectionString string) (*DB, error) {
 	return &DB{db}, nil
 }
 
-// InsertPost inserts a new post into the database
-func (db *DB) InsertPost(post *Post) error {
+// InsertPost inserts a new post into the database and reports whether it was
+// new. A post already stored through another ingest source records
+// post.Source as a duplicate source.
+func (db *DB) InsertPost(post *Post) (bool, error) {
+	query := `
+		INSERT INTO posts (id, author_handle, author_did, author_degree, content, is_reply, raw_record, labels, text_fingerprint, langs, source, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NULLIF($9, ''), $10, NULLIF($11, ''), $12)
+		ON CONFLICT DO NOTHING
+	`
+
+	// Columns are NOT NULL; a nil array encodes as NULL
+	labels := post.Labels
+	if labels == nil {
+		labels = pq.StringArray{}
+	}
+	langs := post.Langs
+	if langs == nil {
+		langs = pq.StringArray{}
+	}
+
+	result, err := db.Exec(query, post.ID, post.AuthorHandle, post.AuthorDID, post.AuthorDegree, post.Content, post.IsReply, post.RawRecord, labels, post.Fingerprint, langs, post.Source, post.CreatedAt)
+	if err != nil {
+		return false, err
+	}
+	inserted, err := result.RowsAffected()
+	if err != nil || inserted > 0 || post.Source == "" {
+		return inserted > 0, err
+	}
+
+	_, err = db.Exec(`
+		UPDATE posts
+		SET duplicate_sources = ARRAY_APPEND(duplicate_sources, $2)
+		WHERE id = $1
+		  AND source IS DISTINCT FROM $2
+		  AND NOT ($2 = ANY(duplicate_sources))
+	`, post.ID, post.Source)
+	return false, err
+}
+
+// GetPostsSince returns posts created at or after since, ordered by ID, for
+// batch reprocessing. Pass the last ID of the previous batch as afterID.
+func (db *DB) GetPostsSince(since time.Time, afterID string, limit int) ([]Post, error) {
+	query := `
+		SELECT id, author_handle, COALESCE(author_did, author_handle) AS author_did,
+		       COALESCE(author_degree, 0) AS author_degree, COALESCE(content, '') AS content,
+		       is_reply, raw_record, created_at, COALESCE(indexed_at, created_at) AS indexed_at
+		FROM posts
+		WHERE created_at >= $1 AND id > $2
+		ORDER BY id
+		LIMIT $3
+	`
+
+	var posts []Post
+	err := db.Select(&posts, query, since, afterID, limit)
+	return posts, err
+}
+
+// GetPostLinkURLs returns the normalized URLs currently linked to a post
+func (db *DB) GetPostLinkURLs(postID string) ([]string, error) {
 	query := `
-		INSERT INTO posts (id, author_handle, author_did, author_degree, content, created_at)
-		VALUES ($1, $2, $3, $4, $5, $6)
-		ON CONFLICT (id) DO NOTHING
+		SELECT l.normalized_url
+		FROM post_links pl
+		JOIN links l ON pl.link_id = l.id
+		WHERE pl.post_id = $1
+		ORDER BY l.normalized_url
 	`
 
-	_, err := db.Exec(query, post.ID, post.AuthorHandle, post.AuthorDID, post.AuthorDegree, post.Content, post.CreatedAt)
+	var urls []string
+	err := db.Select(&urls, query, postID)
+	return urls, err
+}
+
+// DeletePostLinks removes all link associations for a post
+func (db *DB) DeletePostLinks(postID string) error {
+	_, err := db.Exec(`DELETE FROM post_links WHERE post_id = $1`, postID)
 	return err
 }
 
@@ -138,37 +291,101 @@ func (db *DB) GetOrCreateLink(originalURL, normalizedURL string) (*Link, error)
 	return link, err
 }
 
-// UpdateLinkMetadata updates the OpenGraph metadata for a link
-func (db *DB) UpdateLinkMetadata(linkID int, title, description, imageURL string) error {
+// MarkLinkFetched marks a link as having been fetched (even if fetch failed)
+func (db *DB) MarkLinkFetched(linkID int) error {
+	query := `UPDATE links SET last_fetched_at = NOW() WHERE id = $1`
+	_, err := db.Exec(query, linkID)
+	return err
+}
+
+// MarkLinkSensitive flags a link's preview as sensitive. The first reason
+// recorded is kept.
+func (db *DB) MarkLinkSensitive(linkID int, reason string) error {
 	query := `
 		UPDATE links
-		SET title = $1, description = $2, og_image_url = $3, last_fetched_at = NOW()
-		WHERE id = $4
+		SET sensitive = TRUE, sensitive_reason = COALESCE(sensitive_reason, $2)
+		WHERE id = $1
 	`
-
-	_, err := db.Exec(query, title, description, imageURL, linkID)
+	_, err := db.Exec(query, linkID, reason)
 	return err
 }
 
-// MarkLinkFetched marks a link as having been fetched (even if fetch failed)
-func (db *DB) MarkLinkFetched(linkID int) error {
-	query := `UPDATE links SET last_fetched_at = NOW() WHERE id = $1`
-	_, err := db.Exec(query, linkID)
+// MarkPostLinksSensitive flags every link shared by a post as sensitive
+func (db *DB) MarkPostLinksSensitive(postID string, reason string) error {
+	query := `
+		UPDATE links
+		SET sensitive = TRUE, sensitive_reason = COALESCE(sensitive_reason, $2)
+		WHERE id IN (SELECT link_id FROM post_links WHERE post_id = $1)
+	`
+	_, err := db.Exec(query, postID, reason)
 	return err
 }
 
-// LinkPostToLink creates a relationship between a post and a link
+// LinkPostToLink creates a relationship between a post and a link, copying
+// the degree already stamped on the post
 func (db *DB) LinkPostToLink(postID string, linkID int) error {
 	query := `
-		INSERT INTO post_links (post_id, link_id)
-		VALUES ($1, $2)
-		ON CONFLICT DO NOTHING
-	`
+		WITH ins AS (
+			INSERT INTO post_links (post_id, link_id, relation_type, degree, copied, post_created_at)
+			SELECT $1, $2, $3, author_degree, ` + copiedShareSQL + `, created_at FROM posts WHERE id = $1
+			ON CONFLICT DO NOTHING
+			RETURNING post_id, link_id
+		)
+		` + updateFirstSharerSQL
+
+	_, err := db.Exec(query, postID, linkID, RelationOriginal)
+	return err
+}
+
+// LinkPostToLinkWithAttribution creates a post-link relationship recording how
+// the link was shared and the author's network degree. The post must already
+// be stored.
+func (db *DB) LinkPostToLinkWithAttribution(postID string, linkID int, relationType string, degree int) error {
+	query := `
+		WITH ins AS (
+			INSERT INTO post_links (post_id, link_id, relation_type, degree, copied, post_created_at)
+			SELECT $1, $2, $3, $4, ` + copiedShareSQL + `, created_at FROM posts WHERE id = $1
+			ON CONFLICT DO NOTHING
+			RETURNING post_id, link_id
+		)
+		` + updateFirstSharerSQL
 
-	_, err := db.Exec(query, postID, linkID)
+	_, err := db.Exec(query, postID, linkID, relationType, degree)
 	return err
 }
 
+// copiedShareSQL decides whether the post_links row being inserted for post
+// $1, link $2 and relation $3 is a copy: the post's text fingerprint matches
+// a non-repost share of the same link by another account within
+// CopiedShareWindow. Reposts are never copies.
+var copiedShareSQL = fmt.Sprintf(`($3 <> '%s' AND EXISTS (
+			SELECT 1
+			FROM posts np
+			JOIN posts op ON op.text_fingerprint = np.text_fingerprint
+			JOIN post_links opl ON opl.post_id = op.id
+			WHERE np.id = $1
+			  AND opl.link_id = $2
+			  AND opl.relation_type <> '%[1]s'
+			  AND COALESCE(op.author_did, op.author_handle) <> COALESCE(np.author_did, np.author_handle)
+			  AND op.created_at BETWEEN np.created_at - INTERVAL '%[2]d seconds' AND np.created_at + INTERVAL '%[2]d seconds'
+		))`, RelationRepost, int(CopiedShareWindow.Seconds()))
+
+// CopiedShareWindow is how far apart identical posts sharing a link can be
+// and still count as copies of each other
+const CopiedShareWindow = 6 * time.Hour
+
+// updateFirstSharerSQL completes an "ins" CTE of new post_links rows by
+// moving the link's first-sharer attribution to the new post if it is earlier
+const updateFirstSharerSQL = `
+		UPDATE links l
+		SET first_shared_by = COALESCE(p.author_did, p.author_handle),
+		    first_shared_at = p.created_at
+		FROM ins
+		JOIN posts p ON p.id = ins.post_id
+		WHERE l.id = ins.link_id
+		  AND (l.first_shared_at IS NULL OR p.created_at < l.first_shared_at)
+	`
+
 // buildDomainFilter generates SQL conditions to filter out blocked domains
 func buildDomainFilter() string {
 	var conditions []string
@@ -178,41 +395,336 @@ func buildDomainFilter() string {
 	return strings.Join(conditions, " AND ")
 }
 
+// Reply handling modes for trending queries
+const (
+	ReplyModeInclude    = "include"    // Count replies like any other share
+	ReplyModeExclude    = "exclude"    // Ignore shares made in replies
+	ReplyModeDownweight = "downweight" // Count replies at ReplyWeight when ranking
+)
+
+// Moderation label handling modes for trending queries
+const (
+	LabelModeOff     = "off"     // Ignore labels
+	LabelModeFlag    = "flag"    // Report LabeledShareRatio so callers can flag links
+	LabelModeExclude = "exclude" // Drop links at or above LabelThreshold
+)
+
+// TrendingOptions holds optional filters applied to trending queries
+type TrendingOptions struct {
+	ReplyMode   string  // One of the ReplyMode* constants (empty = include)
+	ReplyWeight float64 // Weight of a reply share when ReplyMode is downweight (0-1)
+
+	LabelMode      string   // One of the LabelMode* constants (empty = off)
+	FlaggedLabels  []string // Label values that count against a share (e.g. spam)
+	LabelThreshold float64  // Labeled share ratio at which a link is excluded (0-1)
+
+	CohortID int // Only count posts by members of this cohort (0 = everyone)
+
+	CollapseCopies bool // Don't count copied shares, so a copy-paste campaign counts once
+
+	SelfPromoMode   string   // One of the SelfPromoMode* constants (empty = off)
+	SelfPromoWeight float64  // Weight of a self-promotion share when SelfPromoMode is downweight (0-1)
+	PersonalDomains []string // Domains whose links always count as self-promotion (subdomains match too)
+
+	HideDead bool // Leave out links a dead-link check found gone
+
+	Langs []string // Only count posts tagged with one of these languages (nil = all posts)
+
+	SharerTypes []string // Only count shares by accounts of these types (nil = everyone; accounts outside the network are individuals)
+
+	MinShares int // Leave out links with fewer distinct sharers (<= 1 = none)
+
+	PinnedWeight float64 // Weight of a share by a pinned account (<= 1 = no boost)
+
+	Keywords      []string  // Only count posts whose text or link title contains one of these (nil = all posts)
+	Since         time.Time // Only count posts made at or after this (zero = the whole window)
+	Until         time.Time // Only count posts made before this (zero = up to now)
+	BoostCohortID int       // Boost shares by members of this cohort (0 = none)
+	BoostWeight   float64   // Weight of a share by a boosted cohort member (<= 1 = no boost)
+
+	After *TrendingCursor // Only links ranked after this position (nil = from the top)
+
+	OmitSharers bool // Leave Sharers empty, skipping the handle aggregation
+}
+
+// Self-promotion handling modes for trending queries
+const (
+	SelfPromoModeOff        = "off"        // Count self-promotion like any other share
+	SelfPromoModeExclude    = "exclude"    // Ignore self-promotion shares
+	SelfPromoModeDownweight = "downweight" // Count self-promotion at SelfPromoWeight when ranking
+)
+
+// linkHostSQL extracts a link's host without a leading www.
+const linkHostSQL = `REGEXP_REPLACE(SUBSTRING(l.normalized_url FROM '^[a-z]+://([^/:?#]+)'), '^www\.', '')`
+
+// weightedShare is a class of shares counted at a weight other than 1
+type weightedShare struct {
+	condition string // SQL condition matching the shares
+	weight    string // SQL expression for their weight
+}
+
+// buildReplyClauses returns the WHERE condition for the configured reply
+// mode, and the down-weighted share class when replies are down-weighted,
+// appending any bind parameters to args
+func buildReplyClauses(opts TrendingOptions, args *[]interface{}) (filter string, weighted *weightedShare) {
+	switch opts.ReplyMode {
+	case ReplyModeExclude:
+		return "AND NOT p.is_reply", nil
+	case ReplyModeDownweight:
+		*args = append(*args, opts.ReplyWeight)
+		return "", &weightedShare{"p.is_reply", fmt.Sprintf("$%d", len(*args))}
+	default:
+		return "", nil
+	}
+}
+
+// buildSelfPromoClauses is buildReplyClauses for self-promotion: shares of a
+// link on the sharer's own domain (a custom-domain handle matching the link's
+// host) or on one of the configured personal domains
+func buildSelfPromoClauses(opts TrendingOptions, args *[]interface{}) (filter string, weighted *weightedShare) {
+	if opts.SelfPromoMode != SelfPromoModeExclude && opts.SelfPromoMode != SelfPromoModeDownweight {
+		return "", nil
+	}
+
+	*args = append(*args, pq.Array(opts.PersonalDomains))
+	condition := fmt.Sprintf(`COALESCE(
+			%[1]s = LOWER(COALESCE(n.handle, p.author_handle))
+			OR %[1]s LIKE '%%.' || LOWER(COALESCE(n.handle, p.author_handle))
+			OR EXISTS (
+				SELECT 1 FROM UNNEST($%[2]d::text[]) d
+				WHERE %[1]s = LOWER(d) OR %[1]s LIKE '%%.' || LOWER(d)
+			), false)`, linkHostSQL, len(*args))
+
+	if opts.SelfPromoMode == SelfPromoModeExclude {
+		return "AND NOT " + condition, nil
+	}
+	*args = append(*args, opts.SelfPromoWeight)
+	return "", &weightedShare{condition, fmt.Sprintf("$%d", len(*args))}
+}
+
+// buildPinnedWeight returns the boosted share class for accounts an admin
+// pinned, or nil when there's no boost
+func buildPinnedWeight(opts TrendingOptions, args *[]interface{}) *weightedShare {
+	if opts.PinnedWeight <= 1 {
+		return nil
+	}
+	*args = append(*args, opts.PinnedWeight)
+	return &weightedShare{"COALESCE(n.pinned, false)", fmt.Sprintf("$%d", len(*args))}
+}
+
+// buildCohortBoost returns the boosted share class for members of
+// opts.BoostCohortID, or nil when there's no boost
+func buildCohortBoost(opts TrendingOptions, args *[]interface{}) *weightedShare {
+	if opts.BoostCohortID == 0 || opts.BoostWeight <= 1 {
+		return nil
+	}
+	*args = append(*args, opts.BoostCohortID, opts.BoostWeight)
+	n := len(*args)
+	return &weightedShare{
+		fmt.Sprintf("p.author_did IN (SELECT did FROM cohort_members WHERE cohort_id = $%d)", n-1),
+		fmt.Sprintf("$%d", n),
+	}
+}
+
+// buildScore returns the ranking score expression: distinct sharers, each
+// counted at the weight of its best share. A share's weight is the product
+// of the weights of the classes it falls in, so an author with a reply and
+// a top-level post counts once, at full weight, rather than once per class.
+func buildScore(classes ...*weightedShare) string {
+	var factors []string
+	for _, class := range classes {
+		if class != nil {
+			factors = append(factors, fmt.Sprintf("CASE WHEN %s THEN %s::float8 ELSE 1 END", class.condition, class.weight))
+		}
+	}
+	if len(factors) == 0 {
+		return "COUNT(DISTINCT p.author_did)"
+	}
+
+	// The aggregates belong to the outer query; the subquery reduces their
+	// per-share weights to one per author and sums those
+	return fmt.Sprintf(`(SELECT SUM(w) FROM (
+				SELECT MAX(s.w) AS w
+				FROM UNNEST(ARRAY_AGG(p.author_did), ARRAY_AGG((%s)::float8)) AS s(did, w)
+				GROUP BY s.did
+			) author_weights)`, strings.Join(factors, "\n\t\t\t\t\t* "))
+}
+
+// buildLabelClauses returns the labeled_share_ratio select expression and a
+// HAVING condition for the configured label mode, appending bind parameters to args
+func buildLabelClauses(opts TrendingOptions, args *[]interface{}) (ratio string, having string) {
+	if opts.LabelMode != LabelModeFlag && opts.LabelMode != LabelModeExclude || len(opts.FlaggedLabels) == 0 {
+		return "0::float8", ""
+	}
+
+	*args = append(*args, pq.Array(opts.FlaggedLabels))
+	labelsArg := len(*args)
+	ratio = fmt.Sprintf(`(COUNT(DISTINCT p.author_did) FILTER (
+			WHERE p.labels && $%[1]d::text[] OR COALESCE(n.labels, '{}') && $%[1]d::text[]
+		))::float8 / COUNT(DISTINCT p.author_did)`, labelsArg)
+
+	if opts.LabelMode == LabelModeExclude {
+		*args = append(*args, opts.LabelThreshold)
+		having = fmt.Sprintf("%s < $%d", ratio, len(*args))
+	}
+	return ratio, having
+}
+
+// buildMinSharesCondition returns a HAVING condition dropping links shared
+// by fewer than opts.MinShares accounts
+func buildMinSharesCondition(opts TrendingOptions, args *[]interface{}) string {
+	if opts.MinShares <= 1 {
+		return ""
+	}
+	*args = append(*args, opts.MinShares)
+	return fmt.Sprintf("COUNT(DISTINCT p.author_did) >= $%d", len(*args))
+}
+
+// buildHaving joins HAVING conditions, skipping empty ones
+func buildHaving(conditions ...string) string {
+	var parts []string
+	for _, condition := range conditions {
+		if condition != "" {
+			parts = append(parts, condition)
+		}
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return "HAVING " + strings.Join(parts, " AND ")
+}
+
+// buildCohortFilter returns a WHERE condition restricting posts to cohort
+// members, appending the cohort ID to args
+func buildCohortFilter(opts TrendingOptions, args *[]interface{}) string {
+	if opts.CohortID == 0 {
+		return ""
+	}
+	*args = append(*args, opts.CohortID)
+	return fmt.Sprintf("AND p.author_did IN (SELECT did FROM cohort_members WHERE cohort_id = $%d)", len(*args))
+}
+
+// buildLangFilter returns a WHERE condition restricting posts to languages.
+// Posts whose author set no language don't match any.
+func buildLangFilter(opts TrendingOptions, args *[]interface{}) string {
+	if len(opts.Langs) == 0 {
+		return ""
+	}
+	*args = append(*args, pq.Array(opts.Langs))
+	return fmt.Sprintf("AND p.langs && $%d", len(*args))
+}
+
+// buildKeywordFilter returns a WHERE condition restricting posts to those
+// whose text or link title contains one of opts.Keywords, ignoring case
+func buildKeywordFilter(opts TrendingOptions, args *[]interface{}) string {
+	if len(opts.Keywords) == 0 {
+		return ""
+	}
+	patterns := make([]string, len(opts.Keywords))
+	for i, keyword := range opts.Keywords {
+		patterns[i] = "%" + likeEscaper.Replace(keyword) + "%"
+	}
+	*args = append(*args, pq.Array(patterns))
+	return fmt.Sprintf("AND (p.content ILIKE ANY($%[1]d::text[]) OR l.title ILIKE ANY($%[1]d::text[]))", len(*args))
+}
+
+// likeEscaper escapes LIKE wildcards so keywords match literally
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// buildPeriodFilter returns a WHERE condition restricting posts to
+// opts.Since through opts.Until
+func buildPeriodFilter(opts TrendingOptions, args *[]interface{}) string {
+	var conditions []string
+	if !opts.Since.IsZero() {
+		*args = append(*args, opts.Since.UTC())
+		conditions = append(conditions, fmt.Sprintf("AND p.created_at >= $%d", len(*args)))
+	}
+	if !opts.Until.IsZero() {
+		*args = append(*args, opts.Until.UTC())
+		conditions = append(conditions, fmt.Sprintf("AND p.created_at < $%d", len(*args)))
+	}
+	return strings.Join(conditions, " ")
+}
+
+// buildSharerTypeFilter returns a WHERE condition restricting shares to
+// authors of the given account types
+func buildSharerTypeFilter(opts TrendingOptions, args *[]interface{}) string {
+	if len(opts.SharerTypes) == 0 {
+		return ""
+	}
+	*args = append(*args, pq.Array(opts.SharerTypes))
+	return fmt.Sprintf("AND COALESCE(n.account_type, 'individual') = ANY($%d::text[])", len(*args))
+}
+
+// buildDegreeFilter returns a WHERE condition restricting shares to authors
+// of a network degree (0 = all)
+func buildDegreeFilter(degree int, args *[]interface{}) string {
+	if degree == 0 {
+		return ""
+	}
+	*args = append(*args, degree)
+	return fmt.Sprintf("AND pl.degree = $%d", len(*args))
+}
+
+// buildCopiesFilter returns a WHERE condition dropping copied shares when
+// they are collapsed
+func buildCopiesFilter(opts TrendingOptions) string {
+	if !opts.CollapseCopies {
+		return ""
+	}
+	return "AND NOT pl.copied"
+}
+
+// buildDeadFilter returns a WHERE condition dropping dead links when they
+// are hidden
+func buildDeadFilter(opts TrendingOptions) string {
+	if !opts.HideDead {
+		return ""
+	}
+	return "AND l.dead_at IS NULL"
+}
+
 // GetTrendingLinks retrieves the most-shared links within a time window
-func (db *DB) GetTrendingLinks(hoursBack int, limit int) ([]TrendingLink, error) {
-	domainFilter := buildDomainFilter()
-	query := fmt.Sprintf(`
-		SELECT
-			l.id,
-			l.normalized_url,
-			l.original_url,
-			l.title,
-			l.description,
-			l.og_image_url,
-			COUNT(DISTINCT p.author_did) as share_count,
-			MAX(p.created_at) as last_shared_at,
-			ARRAY_AGG(DISTINCT COALESCE(n.handle, p.author_handle)) as sharers
-		FROM links l
-		JOIN post_links pl ON l.id = pl.link_id
-		JOIN posts p ON pl.post_id = p.id
-		LEFT JOIN network_accounts n ON p.author_did = n.did
-		WHERE p.created_at > NOW() - INTERVAL '1 hour' * $1
-		  AND l.normalized_url !~* '\.(gif|jpe?g|png|webp)(\?.*)?$'
-		  AND %s
-		GROUP BY l.id
-		ORDER BY share_count DESC, last_shared_at DESC
-		LIMIT $2
-	`, domainFilter)
+func (db *DB) GetTrendingLinks(hoursBack int, limit int, opts TrendingOptions) ([]TrendingLink, error) {
+	return db.GetTrendingLinksByDegree(hoursBack, limit, 0, opts)
+}
 
+// GetTrendingLinksByDegree retrieves trending links filtered by network degree
+// degree: 0 = all posts, 1 = 1st-degree only, 2 = 2nd-degree only
+// The degree filter uses the author degree stamped on post_links at ingest.
+func (db *DB) GetTrendingLinksByDegree(hoursBack int, limit int, degree int, opts TrendingOptions) ([]TrendingLink, error) {
+	if degree < 0 || degree > 2 {
+		return nil, fmt.Errorf("invalid degree %d (want 0, 1 or 2)", degree)
+	}
+
+	query, args := buildTrendingQuery(hoursBack, limit, degree, opts)
 	var links []TrendingLink
-	err := db.Select(&links, query, hoursBack, limit)
+	err := db.Select(&links, query, args...)
 	return links, err
 }
 
-// GetTrendingLinksByDegree retrieves trending links filtered by network degree
-// degree: 0 = all posts, 1 = 1st-degree only, 2 = 2nd-degree only
-func (db *DB) GetTrendingLinksByDegree(hoursBack int, limit int, degree int) ([]TrendingLink, error) {
+// buildTrendingQuery returns GetTrendingLinksByDegree's SQL and bind
+// parameters
+func buildTrendingQuery(hoursBack, limit, degree int, opts TrendingOptions) (string, []interface{}) {
+	args := []interface{}{hoursBack, limit}
+	degreeFilter := buildDegreeFilter(degree, &args)
 	domainFilter := buildDomainFilter()
+	replyFilter, replyWeight := buildReplyClauses(opts, &args)
+	selfPromoFilter, selfPromoWeight := buildSelfPromoClauses(opts, &args)
+	score := buildScore(replyWeight, selfPromoWeight, buildPinnedWeight(opts, &args), buildCohortBoost(opts, &args))
+	labelRatio, labelHaving := buildLabelClauses(opts, &args)
+	having := buildHaving(labelHaving, buildMinSharesCondition(opts, &args), buildCursorCondition(opts, score, &args))
+	cohortFilter := buildCohortFilter(opts, &args)
+	langFilter := buildLangFilter(opts, &args)
+	sharerTypeFilter := buildSharerTypeFilter(opts, &args)
+	keywordFilter := buildKeywordFilter(opts, &args)
+	periodFilter := buildPeriodFilter(opts, &args)
+	copiesFilter := buildCopiesFilter(opts)
+	deadFilter := buildDeadFilter(opts)
+	sharers := "ARRAY_AGG(DISTINCT COALESCE(n.handle, p.author_handle))"
+	if opts.OmitSharers {
+		sharers = "'{}'::text[]"
+	}
 	query := fmt.Sprintf(`
 		SELECT
 			l.id,
@@ -221,25 +733,40 @@ func (db *DB) GetTrendingLinksByDegree(hoursBack int, limit int, degree int) ([]
 			l.title,
 			l.description,
 			l.og_image_url,
+			l.sensitive,
 			COUNT(DISTINCT p.author_did) as share_count,
 			MAX(p.created_at) as last_shared_at,
-			ARRAY_AGG(DISTINCT COALESCE(n.handle, p.author_handle)) as sharers
+			l.first_shared_at,
+			%s as sharers,
+			%s as labeled_share_ratio,
+			%s::float8 as score,
+			(SELECT COUNT(*) FROM post_links c WHERE c.link_id = l.id AND c.copied) as copied_shares,
+			l.click_count,
+			l.dead_at,
+			l.image_missing
 		FROM links l
 		JOIN post_links pl ON l.id = pl.link_id
 		JOIN posts p ON pl.post_id = p.id
 		LEFT JOIN network_accounts n ON p.author_did = n.did
 		WHERE p.created_at > NOW() - INTERVAL '1 hour' * $1
-		  AND ($3 = 0 OR p.author_degree = $3)
 		  AND l.normalized_url !~* '\.(gif|jpe?g|png|webp)(\?.*)?$'
 		  AND %s
+		  %s
+		  %s
+		  %s
+		  %s
+		  %s
+		  %s
+		  %s
+		  %s
+		  %s
+		  %s
 		GROUP BY l.id
-		ORDER BY share_count DESC, last_shared_at DESC
+		%s
+		ORDER BY score DESC, share_count DESC, last_shared_at DESC, l.id DESC
 		LIMIT $2
-	`, domainFilter)
-
-	var links []TrendingLink
-	err := db.Select(&links, query, hoursBack, limit, degree)
-	return links, err
+	`, sharers, labelRatio, score, domainFilter, degreeFilter, replyFilter, selfPromoFilter, cohortFilter, langFilter, sharerTypeFilter, keywordFilter, periodFilter, copiesFilter, deadFilter, having)
+	return query, args
 }
 
 // GetLastCursor retrieves the last cursor for a user handle
@@ -354,12 +881,14 @@ func (db *DB) GetLinkSharers(linkID int) ([]SharerAvatar, error) {
 			COALESCE(n.handle, p.author_handle) as handle,
 			n.display_name,
 			n.avatar_url,
-			COALESCE(n.did, p.author_handle) as did
+			COALESCE(n.did, p.author_handle) as did,
+			COALESCE(n.pinned, false) as pinned,
+			COALESCE(n.account_type, 'individual') as account_type
 		FROM post_links pl
 		JOIN posts p ON pl.post_id = p.id
 		LEFT JOIN network_accounts n ON p.author_did = n.did
 		WHERE pl.link_id = $1
-		ORDER BY handle
+		ORDER BY pinned DESC, handle
 	`
 
 	var sharers []SharerAvatar
@@ -438,20 +967,27 @@ func (db *DB) DeleteOrphanedPostLinks() (int, error) {
 }
 
 // DeleteUnsharedLinks deletes links that have no shares since the cutoff time
-// EXCEPT: Keeps trending links (5+ total shares regardless of age)
-func (db *DB) DeleteUnsharedLinks(cutoff time.Time, trendingThreshold int) (int, error) {
-	query := `
+// EXCEPT: Keeps trending links (5+ total shares regardless of age) and links
+// referenced by any of the exemptTables
+func (db *DB) DeleteUnsharedLinks(cutoff time.Time, trendingThreshold int, exemptTables []string) (int, error) {
+	exempt, err := db.LinkExemptFilter(exemptTables)
+	if err != nil {
+		return 0, err
+	}
+
+	query := fmt.Sprintf(`
 		DELETE FROM links
 		WHERE id IN (
 			SELECT l.id
 			FROM links l
 			LEFT JOIN post_links pl ON l.id = pl.link_id
 			LEFT JOIN posts p ON pl.post_id = p.id
+			WHERE %s
 			GROUP BY l.id
 			HAVING COALESCE(MAX(p.created_at), '1970-01-01'::timestamp) < $1
 			   AND COUNT(pl.link_id) < $2
 		)
-	`
+	`, exempt)
 
 	result, err := db.Exec(query, cutoff, trendingThreshold)
 	if err != nil {
@@ -466,6 +1002,37 @@ func (db *DB) DeleteUnsharedLinks(cutoff time.Time, trendingThreshold int) (int,
 	return int(rowsAffected), nil
 }
 
+// LinkExemptFilter builds a condition on links l that spares links referenced
+// through a link_id column by any of tables. Tables that don't exist (or have
+// no link_id) are skipped, so features can be listed before their migration.
+func (db *DB) LinkExemptFilter(tables []string) (string, error) {
+	if len(tables) == 0 {
+		return "TRUE", nil
+	}
+
+	var existing []string
+	err := db.Select(&existing, `
+		SELECT table_name
+		FROM information_schema.columns
+		WHERE table_schema = current_schema()
+		  AND column_name = 'link_id'
+		  AND table_name = ANY($1)
+		ORDER BY table_name
+	`, pq.Array(tables))
+	if err != nil {
+		return "", err
+	}
+	if len(existing) == 0 {
+		return "TRUE", nil
+	}
+
+	conditions := make([]string, len(existing))
+	for i, table := range existing {
+		conditions[i] = fmt.Sprintf("NOT EXISTS (SELECT 1 FROM %s x WHERE x.link_id = l.id)", pq.QuoteIdentifier(table))
+	}
+	return strings.Join(conditions, " AND "), nil
+}
+
 // GetActiveFollows returns follows that have been seen within the specified duration
 func (db *DB) GetActiveFollows(maxAge time.Duration) ([]Follow, error) {
 	query := `
@@ -533,6 +1100,25 @@ func (db *DB) GetNetworkAccountsByDegree(degree, minSourceCount int) ([]NetworkA
 	return accounts, err
 }
 
+// GetNetworkAccount returns a network account by DID, or nil if not found
+func (db *DB) GetNetworkAccount(did string) (*NetworkAccount, error) {
+	query := `
+		SELECT did, handle, display_name, avatar_url, degree, source_count, source_dids, first_seen_at, last_updated_at
+		FROM network_accounts
+		WHERE did = $1
+	`
+
+	var account NetworkAccount
+	err := db.Get(&account, query, did)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
+
 // GetAllNetworkDIDs returns a map of all DIDs in the network for efficient lookup
 // Returns map[did] -> degree
 func (db *DB) GetAllNetworkDIDs() (map[string]int, error) {
@@ -587,3 +1173,87 @@ func (db *DB) GetNetworkStats() (map[string]interface{}, error) {
 		"second_degree_3plus":    stats.SecondDegreeStrong,
 	}, nil
 }
+
+// DeleteOldestPosts deletes up to count of the oldest posts, sparing posts
+// that share a link with at least trendingThreshold shares
+// Returns the number of posts deleted
+func (db *DB) DeleteOldestPosts(count, trendingThreshold int) (int, error) {
+	query := `
+		WITH trending AS (
+			SELECT link_id
+			FROM post_links
+			GROUP BY link_id
+			HAVING COUNT(*) >= $2
+		)
+		DELETE FROM posts
+		WHERE id IN (
+			SELECT p.id
+			FROM posts p
+			WHERE NOT EXISTS (
+				SELECT 1
+				FROM post_links pl
+				JOIN trending t ON t.link_id = pl.link_id
+				WHERE pl.post_id = p.id
+			)
+			ORDER BY p.created_at ASC
+			LIMIT $1
+		)
+	`
+
+	result, err := db.Exec(query, count, trendingThreshold)
+	if err != nil {
+		return 0, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(rowsAffected), nil
+}
+
+// DeleteOldestLinks deletes up to count links with fewer than
+// trendingThreshold shares that none of the exemptTables reference, least
+// recently shared first
+// Returns the number of links deleted
+func (db *DB) DeleteOldestLinks(count, trendingThreshold int, exemptTables []string) (int, error) {
+	exempt, err := db.LinkExemptFilter(exemptTables)
+	if err != nil {
+		return 0, err
+	}
+
+	query := fmt.Sprintf(`
+		DELETE FROM links
+		WHERE id IN (
+			SELECT l.id
+			FROM links l
+			LEFT JOIN post_links pl ON l.id = pl.link_id
+			LEFT JOIN posts p ON pl.post_id = p.id
+			WHERE %s
+			GROUP BY l.id
+			HAVING COUNT(pl.link_id) < $2
+			ORDER BY COALESCE(MAX(p.created_at), l.first_seen_at) ASC
+			LIMIT $1
+		)
+	`, exempt)
+
+	result, err := db.Exec(query, count, trendingThreshold)
+	if err != nil {
+		return 0, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(rowsAffected), nil
+}
+
+// GetDatabaseSize returns the on-disk size of the current database in bytes
+func (db *DB) GetDatabaseSize() (int64, error) {
+	var size int64
+	err := db.Get(&size, `SELECT pg_database_size(current_database())`)
+	return size, err
+}
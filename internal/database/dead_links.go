@@ -0,0 +1,43 @@
+package database
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// GetLinksForStatusCheck returns the links among ids plus up to sample links
+// picked at random from those first seen since recentSince, skipping links
+// whose status was checked after checkedBefore
+func (db *DB) GetLinksForStatusCheck(ids []int, sample int, recentSince, checkedBefore time.Time) ([]Link, error) {
+	query := `
+		(SELECT * FROM links
+		 WHERE id = ANY($1)
+		   AND (status_checked_at IS NULL OR status_checked_at < $4))
+		UNION ALL
+		(SELECT * FROM links
+		 WHERE first_seen_at > $3
+		   AND NOT id = ANY($1)
+		   AND (status_checked_at IS NULL OR status_checked_at < $4)
+		 ORDER BY random()
+		 LIMIT $2)
+	`
+	var links []Link
+	err := db.Select(&links, query, pq.Array(ids), sample, recentSince.UTC(), checkedBefore.UTC())
+	return links, err
+}
+
+// RecordLinkStatus stores the status code a link answered with (0 =
+// unreachable). Dead links keep the time they were first found dead; live
+// ones are cleared.
+func (db *DB) RecordLinkStatus(linkID, status int, dead bool) error {
+	query := `
+		UPDATE links
+		SET http_status = $2,
+		    status_checked_at = NOW(),
+		    dead_at = CASE WHEN $3 THEN COALESCE(dead_at, NOW()) END
+		WHERE id = $1
+	`
+	_, err := db.Exec(query, linkID, status, dead)
+	return err
+}
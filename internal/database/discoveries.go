@@ -0,0 +1,70 @@
+package database
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+)
+
+// Discovery is a link an account was first in the network to share
+type Discovery struct {
+	LinkID        int       `db:"link_id" json:"link_id"`
+	URL           string    `db:"normalized_url" json:"url"`
+	Title         *string   `db:"title" json:"title"`
+	Description   *string   `db:"description" json:"description"`
+	OGImageURL    *string   `db:"og_image_url" json:"image_url"`
+	Sensitive     bool      `db:"sensitive" json:"sensitive,omitempty"`
+	FirstSharedAt time.Time `db:"first_shared_at" json:"first_shared_at"`
+	ShareCount    int       `db:"share_count" json:"share_count"` // Distinct sharers still in the posts window
+}
+
+// ResolveAccountDID returns the DID for a handle (or DID) in the network,
+// or "" if the account isn't known
+func (db *DB) ResolveAccountDID(handleOrDID string) (string, error) {
+	if strings.HasPrefix(handleOrDID, "did:") {
+		return handleOrDID, nil
+	}
+
+	var did string
+	err := db.Get(&did, `SELECT did FROM network_accounts WHERE handle = $1 LIMIT 1`, handleOrDID)
+	if err == sql.ErrNoRows {
+		// Follows may not have been crawled into the network yet
+		err = db.Get(&did, `SELECT did FROM follows WHERE handle = $1 LIMIT 1`, handleOrDID)
+	}
+	if err == sql.ErrNoRows {
+		// Fall back to authors seen in posts
+		err = db.Get(&did, `SELECT author_did FROM posts WHERE author_handle = $1 AND author_did IS NOT NULL LIMIT 1`, handleOrDID)
+	}
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return did, err
+}
+
+// GetDiscoveries returns links the account was first to share, newest first
+func (db *DB) GetDiscoveries(did string, limit int) ([]Discovery, error) {
+	query := `
+		SELECT
+			l.id AS link_id,
+			l.normalized_url,
+			l.title,
+			l.description,
+			l.og_image_url,
+			l.sensitive,
+			l.first_shared_at,
+			(
+				SELECT COUNT(DISTINCT p.author_did)
+				FROM post_links pl
+				JOIN posts p ON pl.post_id = p.id
+				WHERE pl.link_id = l.id
+			) AS share_count
+		FROM links l
+		WHERE l.first_shared_by = $1
+		ORDER BY l.first_shared_at DESC
+		LIMIT $2
+	`
+
+	var discoveries []Discovery
+	err := db.Select(&discoveries, query, did, limit)
+	return discoveries, err
+}
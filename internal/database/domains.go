@@ -0,0 +1,147 @@
+package database
+
+import (
+	"fmt"
+
+	"github.com/lib/pq"
+)
+
+// DomainShare is a domain's share of all link shares in a time window
+type DomainShare struct {
+	Domain string  `db:"domain"`
+	Shares int     `db:"shares"`
+	Ratio  float64 `db:"ratio"`
+}
+
+// GetDomainShares returns domains receiving at least minRatio of all link
+// shares (distinct post/link pairs) in the last hoursBack hours, most popular first
+func (db *DB) GetDomainShares(hoursBack int, minRatio float64) ([]DomainShare, error) {
+	query := `
+		WITH shares AS (
+			SELECT REGEXP_REPLACE(
+				SUBSTRING(l.normalized_url FROM '^[a-z]+://([^/:?#]+)'),
+				'^www\.', ''
+			) AS domain
+			FROM post_links pl
+			JOIN posts p ON pl.post_id = p.id
+			JOIN links l ON pl.link_id = l.id
+			WHERE p.created_at > NOW() - INTERVAL '1 hour' * $1
+		),
+		counts AS (
+			SELECT domain, COUNT(*) AS shares, COUNT(*)::float8 / SUM(COUNT(*)) OVER () AS ratio
+			FROM shares
+			WHERE domain IS NOT NULL
+			GROUP BY domain
+		)
+		SELECT domain, shares, ratio
+		FROM counts
+		WHERE ratio >= $2
+		ORDER BY shares DESC
+	`
+
+	var domains []DomainShare
+	err := db.Select(&domains, query, hoursBack, minRatio)
+	return domains, err
+}
+
+// TopDomain is a host's share activity in a time window
+type TopDomain struct {
+	Domain   string       `db:"domain" json:"domain"`
+	Shares   int          `db:"shares" json:"shares"`   // Posts sharing the domain's links
+	Sharers  int          `db:"sharers" json:"sharers"` // Distinct accounts among them
+	Links    int          `db:"links" json:"links"`     // Distinct links shared
+	Ratio    float64      `db:"ratio" json:"ratio"`     // Fraction of all shares in the window
+	TopLinks []DomainLink `db:"-" json:"top_links"`
+}
+
+// DomainLink is one of a domain's most-shared links
+type DomainLink struct {
+	Domain     string  `db:"domain" json:"-"`
+	LinkID     int     `db:"link_id" json:"link_id"`
+	URL        string  `db:"normalized_url" json:"url"`
+	Title      *string `db:"title" json:"title"`
+	ShareCount int     `db:"share_count" json:"share_count"` // Distinct sharers in the window
+}
+
+// domainSharesCTE lists the shares of the last $1 hours with their link's
+// host, leaving out the links trending leaves out. degreeFilter restricts
+// sharers by network degree.
+const domainSharesCTE = `
+		WITH shares AS (
+			SELECT ` + linkHostSQL + ` AS domain, l.id AS link_id, l.normalized_url, l.title, p.author_did
+			FROM post_links pl
+			JOIN posts p ON pl.post_id = p.id
+			JOIN links l ON pl.link_id = l.id
+			WHERE p.created_at > NOW() - INTERVAL '1 hour' * $1
+			  AND l.normalized_url !~* '\.(gif|jpe?g|png|webp)(\?.*)?$'
+			  AND %s
+			  %s
+		)
+	`
+
+// GetTopDomains groups the shares of the last hoursBack hours by host, most
+// shared first. degree restricts sharers as in trending (0 = all). Each
+// domain includes up to linksPer of its most-shared links.
+func (db *DB) GetTopDomains(hoursBack, degree, limit, linksPer int) ([]TopDomain, error) {
+	args := []interface{}{hoursBack}
+	cte := fmt.Sprintf(domainSharesCTE, buildDomainFilter(), buildDegreeFilter(degree, &args))
+
+	query := cte + fmt.Sprintf(`
+		SELECT
+			domain,
+			COUNT(*) AS shares,
+			COUNT(DISTINCT author_did) AS sharers,
+			COUNT(DISTINCT link_id) AS links,
+			COUNT(*)::float8 / SUM(COUNT(*)) OVER () AS ratio
+		FROM shares
+		WHERE domain IS NOT NULL
+		GROUP BY domain
+		ORDER BY shares DESC, sharers DESC, domain
+		LIMIT $%d
+	`, len(args)+1)
+
+	var domains []TopDomain
+	if err := db.Select(&domains, query, append(args, limit)...); err != nil {
+		return nil, err
+	}
+	if len(domains) == 0 || linksPer <= 0 {
+		return domains, nil
+	}
+
+	names := make([]string, len(domains))
+	for i, d := range domains {
+		names[i] = d.Domain
+	}
+
+	linksQuery := cte + fmt.Sprintf(`
+		SELECT domain, link_id, normalized_url, title, share_count
+		FROM (
+			SELECT
+				domain, link_id, normalized_url, title,
+				COUNT(DISTINCT author_did) AS share_count,
+				ROW_NUMBER() OVER (PARTITION BY domain ORDER BY COUNT(DISTINCT author_did) DESC, link_id) AS rank
+			FROM shares
+			WHERE domain = ANY($%d)
+			GROUP BY domain, link_id, normalized_url, title
+		) ranked
+		WHERE rank <= $%d
+		ORDER BY rank
+	`, len(args)+1, len(args)+2)
+
+	var links []DomainLink
+	if err := db.Select(&links, linksQuery, append(args, pq.StringArray(names), linksPer)...); err != nil {
+		return nil, err
+	}
+
+	byDomain := make(map[string][]DomainLink)
+	for _, link := range links {
+		byDomain[link.Domain] = append(byDomain[link.Domain], link)
+	}
+	for i := range domains {
+		domains[i].TopLinks = byDomain[domains[i].Domain]
+		if domains[i].TopLinks == nil {
+			domains[i].TopLinks = []DomainLink{}
+		}
+	}
+	return domains, nil
+}
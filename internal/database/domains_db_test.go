@@ -0,0 +1,109 @@
+package database_test
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/database"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/testutil"
+)
+
+// seedDomainShares stores shares across three hosts: six posts on
+// example.com (two links, www. and port variants), three on example.org
+// and one image link, plus a share outside the window
+func seedDomainShares(t *testing.T, db *database.DB) (story, other int) {
+	t.Helper()
+	now := time.Now().Add(-10 * time.Minute)
+
+	story = testutil.AddShare(t, db, "did:plc:a", 1, "https://www.example.com/story", now)
+	testutil.AddShare(t, db, "did:plc:b", 1, "https://www.example.com/story", now)
+	testutil.AddShare(t, db, "did:plc:c", 2, "https://www.example.com/story", now)
+	testutil.AddShare(t, db, "did:plc:a", 1, "https://www.example.com/story", now) // Same sharer again
+	other = testutil.AddShare(t, db, "did:plc:d", 2, "https://example.com:8443/other", now)
+	testutil.AddShare(t, db, "did:plc:e", 2, "https://example.com:8443/other", now)
+
+	testutil.AddShare(t, db, "did:plc:a", 1, "https://example.org/one", now)
+	testutil.AddShare(t, db, "did:plc:d", 2, "https://example.org/two", now)
+	testutil.AddShare(t, db, "did:plc:e", 2, "https://example.org/three", now)
+
+	testutil.AddShare(t, db, "did:plc:a", 1, "https://images.example.net/photo.jpg", now)
+	testutil.AddShare(t, db, "did:plc:a", 1, "https://old.example.net/story", time.Now().Add(-3*time.Hour))
+	return story, other
+}
+
+func TestGetTopDomains(t *testing.T) {
+	if testing.Short() {
+		t.Skip("needs Postgres")
+	}
+	db := testutil.NewTestDB(t)
+	story, other := seedDomainShares(t, db)
+
+	domains, err := db.GetTopDomains(1, 0, 10, 1)
+	if err != nil {
+		t.Fatalf("GetTopDomains: %v", err)
+	}
+	if len(domains) != 2 {
+		t.Fatalf("domains = %+v, want example.com and example.org", domains)
+	}
+
+	com, org := domains[0], domains[1]
+	if com.Domain != "example.com" || com.Shares != 6 || com.Sharers != 5 || com.Links != 2 {
+		t.Errorf("first domain = %+v, want example.com with 6 shares by 5 sharers of 2 links", com)
+	}
+	if org.Domain != "example.org" || org.Shares != 3 || org.Sharers != 3 || org.Links != 3 {
+		t.Errorf("second domain = %+v, want example.org with 3 shares by 3 sharers of 3 links", org)
+	}
+	if math.Abs(com.Ratio-6.0/9) > 1e-9 || math.Abs(org.Ratio-3.0/9) > 1e-9 {
+		t.Errorf("ratios = %v, %v, want 2/3 and 1/3", com.Ratio, org.Ratio)
+	}
+	if len(com.TopLinks) != 1 || com.TopLinks[0].LinkID != story || com.TopLinks[0].ShareCount != 3 {
+		t.Errorf("example.com top links = %+v, want the story with 3 sharers", com.TopLinks)
+	}
+
+	// 2nd-degree sharers only
+	domains, err = db.GetTopDomains(1, 2, 10, 5)
+	if err != nil {
+		t.Fatalf("GetTopDomains degree 2: %v", err)
+	}
+	if len(domains) != 2 || domains[0].Domain != "example.com" || domains[0].Shares != 3 || domains[1].Shares != 2 {
+		t.Fatalf("degree 2 domains = %+v, want example.com (3) then example.org (2)", domains)
+	}
+	if links := domains[0].TopLinks; len(links) != 2 || links[0].LinkID != other || links[0].ShareCount != 2 {
+		t.Errorf("degree 2 example.com links = %+v, want other (2 sharers) first", links)
+	}
+
+	// The limit applies to domains
+	domains, err = db.GetTopDomains(1, 0, 1, 0)
+	if err != nil {
+		t.Fatalf("GetTopDomains limit 1: %v", err)
+	}
+	if len(domains) != 1 || domains[0].TopLinks != nil {
+		t.Errorf("limit 1 without links = %+v, want example.com alone", domains)
+	}
+
+	// No shares in the window
+	if _, err := db.Exec(`DELETE FROM post_links`); err != nil {
+		t.Fatal(err)
+	}
+	if domains, err = db.GetTopDomains(1, 0, 10, 3); err != nil || len(domains) != 0 {
+		t.Errorf("empty window = %+v, %v, want none", domains, err)
+	}
+}
+
+func TestGetDomainShares(t *testing.T) {
+	if testing.Short() {
+		t.Skip("needs Postgres")
+	}
+	db := testutil.NewTestDB(t)
+	seedDomainShares(t, db)
+
+	// Unlike GetTopDomains, image links count toward the total
+	domains, err := db.GetDomainShares(1, 0.5)
+	if err != nil {
+		t.Fatalf("GetDomainShares: %v", err)
+	}
+	if len(domains) != 1 || domains[0].Domain != "example.com" || domains[0].Shares != 6 || math.Abs(domains[0].Ratio-0.6) > 1e-9 {
+		t.Errorf("domains = %+v, want example.com with 6 of 10 shares", domains)
+	}
+}
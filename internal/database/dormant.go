@@ -0,0 +1,94 @@
+package database
+
+import (
+	"time"
+)
+
+// lastSeenResolution is how stale last_seen_at may get before a new post
+// rewrites it, so busy accounts don't cost a write per post
+const lastSeenResolution = time.Hour
+
+// DormantAccount is a network account left out of the live filter
+type DormantAccount struct {
+	DID          string     `db:"did"`
+	Handle       string     `db:"handle"`
+	LastSeenAt   *time.Time `db:"last_seen_at"`
+	DormantSince time.Time  `db:"dormant_since"`
+}
+
+// TouchNetworkAccount records that a network account posted at seenAt and
+// returns it to the live filter if it was dormant
+func (db *DB) TouchNetworkAccount(did string, seenAt time.Time) error {
+	query := `
+		UPDATE network_accounts
+		SET last_seen_at = GREATEST(last_seen_at, $2), dormant_since = NULL
+		WHERE did = $1
+		  AND (last_seen_at IS NULL OR last_seen_at < $3 OR dormant_since IS NOT NULL)
+	`
+	_, err := db.Exec(query, did, seenAt, seenAt.Add(-lastSeenResolution))
+	return err
+}
+
+// DemoteDormantAccounts marks 2nd-degree accounts that haven't posted since
+// idleSince as dormant. Accounts never seen posting are measured from when
+// they were first crawled. Returns the DIDs demoted.
+func (db *DB) DemoteDormantAccounts(idleSince time.Time) ([]string, error) {
+	query := `
+		UPDATE network_accounts
+		SET dormant_since = NOW()
+		WHERE degree = 2
+		  AND dormant_since IS NULL
+		  AND COALESCE(last_seen_at, first_seen_at) < $1
+		RETURNING did
+	`
+
+	var dids []string
+	err := db.Select(&dids, query, idleSince)
+	return dids, err
+}
+
+// GetDormantAccounts returns dormant accounts, least recently checked
+// first, so repeated checks work through all of them
+func (db *DB) GetDormantAccounts(limit int) ([]DormantAccount, error) {
+	query := `
+		SELECT did, handle, last_seen_at, dormant_since
+		FROM network_accounts
+		WHERE dormant_since IS NOT NULL
+		ORDER BY dormant_checked_at NULLS FIRST, dormant_since, did
+		LIMIT $1
+	`
+
+	var accounts []DormantAccount
+	err := db.Select(&accounts, query, limit)
+	return accounts, err
+}
+
+// MarkDormantChecked records that a dormant account was checked for new posts
+func (db *DB) MarkDormantChecked(did string) error {
+	_, err := db.Exec(`UPDATE network_accounts SET dormant_checked_at = NOW() WHERE did = $1`, did)
+	return err
+}
+
+// GetLiveNetworkDIDs returns the degree of every network account that isn't
+// dormant, keyed by DID
+func (db *DB) GetLiveNetworkDIDs() (map[string]int, error) {
+	query := `SELECT did, degree FROM network_accounts WHERE dormant_since IS NULL`
+
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	dids := make(map[string]int)
+	for rows.Next() {
+		var did string
+		var degree int
+		if err := rows.Scan(&did, &degree); err != nil {
+			return nil, err
+		}
+		dids[did] = degree
+	}
+
+	return dids, rows.Err()
+}
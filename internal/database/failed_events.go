@@ -0,0 +1,81 @@
+package database
+
+import (
+	"time"
+)
+
+// Failed event statuses
+const (
+	FailedEventPending = "pending"
+	FailedEventDead    = "dead"
+)
+
+// FailedEvent represents a firehose event awaiting retry
+type FailedEvent struct {
+	ID            int64     `db:"id"`
+	EventTimeUS   int64     `db:"event_time_us"`
+	DID           string    `db:"did"`
+	Payload       []byte    `db:"payload"`
+	Status        string    `db:"status"`
+	Attempts      int       `db:"attempts"`
+	LastError     *string   `db:"last_error"`
+	NextAttemptAt time.Time `db:"next_attempt_at"`
+	CreatedAt     time.Time `db:"created_at"`
+	UpdatedAt     time.Time `db:"updated_at"`
+}
+
+// EnqueueFailedEvent stores an event that failed processing so it can be retried
+func (db *DB) EnqueueFailedEvent(eventTimeUS int64, did string, payload []byte, lastError string, nextAttemptAt time.Time) error {
+	query := `
+		INSERT INTO failed_events (event_time_us, did, payload, attempts, last_error, next_attempt_at)
+		VALUES ($1, $2, $3, 1, $4, $5)
+	`
+	_, err := db.Exec(query, eventTimeUS, did, payload, lastError, nextAttemptAt)
+	return err
+}
+
+// GetDueFailedEvents returns pending events whose next attempt time has
+// passed, from DIDs in shard of shardCount (shardCount <= 1 = all)
+func (db *DB) GetDueFailedEvents(limit, shard, shardCount int) ([]FailedEvent, error) {
+	query := `
+		SELECT id, event_time_us, did, payload, status, attempts, last_error, next_attempt_at, created_at, updated_at
+		FROM failed_events
+		WHERE status = 'pending' AND next_attempt_at <= NOW()
+		  AND ($2 <= 1 OR shard_of(did, $2) = $3)
+		ORDER BY event_time_us
+		LIMIT $1
+	`
+
+	var events []FailedEvent
+	err := db.Select(&events, query, limit, shardCount, shard)
+	return events, err
+}
+
+// DeleteFailedEvent removes an event from the queue after it was processed
+func (db *DB) DeleteFailedEvent(id int64) error {
+	_, err := db.Exec(`DELETE FROM failed_events WHERE id = $1`, id)
+	return err
+}
+
+// RescheduleFailedEvent records another failed attempt and sets the next retry time
+func (db *DB) RescheduleFailedEvent(id int64, lastError string, nextAttemptAt time.Time) error {
+	query := `
+		UPDATE failed_events
+		SET attempts = attempts + 1, last_error = $2, next_attempt_at = $3, updated_at = NOW()
+		WHERE id = $1
+	`
+	_, err := db.Exec(query, id, lastError, nextAttemptAt)
+	return err
+}
+
+// MarkFailedEventDead gives up on an event after too many attempts
+// Dead events are kept for inspection rather than deleted
+func (db *DB) MarkFailedEventDead(id int64, lastError string) error {
+	query := `
+		UPDATE failed_events
+		SET status = 'dead', attempts = attempts + 1, last_error = $2, updated_at = NOW()
+		WHERE id = $1
+	`
+	_, err := db.Exec(query, id, lastError)
+	return err
+}
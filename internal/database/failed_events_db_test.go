@@ -0,0 +1,71 @@
+package database_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/sharding"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/testutil"
+)
+
+// TestGetDueFailedEventsShard checks shard_of agrees with sharding.ShardOf
+// and that the batch limit counts only the requested shard's events
+func TestGetDueFailedEventsShard(t *testing.T) {
+	if testing.Short() {
+		t.Skip("needs Postgres")
+	}
+	db := testutil.NewTestDB(t)
+
+	const shardCount = 4
+	dids := []string{"did:plc:ü", "did:web:example.com"}
+	for i := 0; i < 40; i++ {
+		dids = append(dids, fmt.Sprintf("did:plc:%024d", i))
+	}
+	for _, did := range dids {
+		var got int
+		if err := db.Get(&got, `SELECT shard_of($1, $2)`, did, shardCount); err != nil {
+			t.Fatal(err)
+		}
+		if want := sharding.ShardOf(did, shardCount); got != want {
+			t.Errorf("shard_of(%q) = %d, ShardOf = %d", did, got, want)
+		}
+	}
+
+	// The shard's last event is queued after other shards' events, so
+	// filtering after a LIMIT of the shard's size would come up short
+	due := time.Now().Add(-time.Minute)
+	for i, did := range dids {
+		if err := db.EnqueueFailedEvent(int64(i), did, []byte(`{}`), "boom", due); err != nil {
+			t.Fatal(err)
+		}
+	}
+	shard := sharding.ShardOf(dids[len(dids)-1], shardCount)
+	want := 0
+	for _, did := range dids {
+		if sharding.ShardOf(did, shardCount) == shard {
+			want++
+		}
+	}
+
+	events, err := db.GetDueFailedEvents(want, shard, shardCount)
+	if err != nil {
+		t.Fatalf("GetDueFailedEvents: %v", err)
+	}
+	if len(events) != want {
+		t.Errorf("got %d events, want %d", len(events), want)
+	}
+	for _, event := range events {
+		if got := sharding.ShardOf(event.DID, shardCount); got != shard {
+			t.Errorf("event from %s is in shard %d, want %d", event.DID, got, shard)
+		}
+	}
+
+	all, err := db.GetDueFailedEvents(len(dids), 0, 1)
+	if err != nil {
+		t.Fatalf("GetDueFailedEvents: %v", err)
+	}
+	if len(all) != len(dids) {
+		t.Errorf("unsharded: got %d events, want %d", len(all), len(dids))
+	}
+}
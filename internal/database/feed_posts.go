@@ -0,0 +1,39 @@
+package database
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// GetFeedPosts picks a post to show for each of the given links in a
+// Bluesky feed: the first post since the given time that shares the link in
+// its own text or embed, preferring top-level posts to replies. Reposts,
+// quotes and labeled posts are left out. Returns post URIs by link ID;
+// links without such a post are missing.
+func (db *DB) GetFeedPosts(linkIDs []int, since time.Time) (map[int]string, error) {
+	var rows []struct {
+		LinkID int    `db:"link_id"`
+		PostID string `db:"post_id"`
+	}
+	err := db.Select(&rows, `
+		SELECT DISTINCT ON (pl.link_id) pl.link_id, p.id as post_id
+		FROM post_links pl
+		JOIN posts p ON p.id = pl.post_id
+		WHERE pl.link_id = ANY($1)
+		  AND pl.relation_type = $2
+		  AND p.created_at > $3
+		  AND p.id LIKE 'at://%/app.bsky.feed.post/%'
+		  AND cardinality(p.labels) = 0
+		ORDER BY pl.link_id, p.is_reply, p.created_at
+	`, pq.Array(linkIDs), RelationOriginal, since)
+	if err != nil {
+		return nil, err
+	}
+
+	posts := make(map[int]string, len(rows))
+	for _, row := range rows {
+		posts[row.LinkID] = row.PostID
+	}
+	return posts, nil
+}
@@ -0,0 +1,43 @@
+package database
+
+import "github.com/lib/pq"
+
+// GetIngestLedger returns the newest processed event time_us per DID
+func (db *DB) GetIngestLedger() (map[string]int64, error) {
+	var rows []struct {
+		DID        string `db:"did"`
+		LastTimeUS int64  `db:"last_time_us"`
+	}
+	if err := db.Select(&rows, `SELECT did, last_time_us FROM ingest_ledger`); err != nil {
+		return nil, err
+	}
+
+	ledger := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		ledger[row.DID] = row.LastTimeUS
+	}
+	return ledger, nil
+}
+
+// RecordIngestedEvents advances DIDs' ledger entries to the given time_us
+// values (never backwards) in one statement
+func (db *DB) RecordIngestedEvents(latest map[string]int64) error {
+	if len(latest) == 0 {
+		return nil
+	}
+	dids := make([]string, 0, len(latest))
+	times := make([]int64, 0, len(latest))
+	for did, timeUS := range latest {
+		dids = append(dids, did)
+		times = append(times, timeUS)
+	}
+
+	_, err := db.Exec(`
+		INSERT INTO ingest_ledger (did, last_time_us)
+		SELECT * FROM UNNEST($1::text[], $2::bigint[])
+		ON CONFLICT (did) DO UPDATE
+		SET last_time_us = GREATEST(ingest_ledger.last_time_us, EXCLUDED.last_time_us),
+		    updated_at = NOW()
+	`, pq.Array(dids), pq.Array(times))
+	return err
+}
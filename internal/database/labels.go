@@ -0,0 +1,38 @@
+package database
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// UpdateAccountLabels replaces the moderation labels stored for a network account
+func (db *DB) UpdateAccountLabels(did string, labels []string) error {
+	if labels == nil {
+		labels = []string{}
+	}
+
+	query := `
+		UPDATE network_accounts
+		SET labels = $2, labels_updated_at = NOW()
+		WHERE did = $1
+	`
+	_, err := db.Exec(query, did, pq.Array(labels))
+	return err
+}
+
+// GetAccountsNeedingLabelRefresh returns DIDs of network accounts whose labels
+// were never fetched or were last fetched before staleBefore
+func (db *DB) GetAccountsNeedingLabelRefresh(staleBefore time.Time, limit int) ([]string, error) {
+	query := `
+		SELECT did
+		FROM network_accounts
+		WHERE labels_updated_at IS NULL OR labels_updated_at < $1
+		ORDER BY labels_updated_at NULLS FIRST, did
+		LIMIT $2
+	`
+
+	var dids []string
+	err := db.Select(&dids, query, staleBefore, limit)
+	return dids, err
+}
@@ -0,0 +1,109 @@
+package database
+
+import "github.com/lib/pq"
+
+// Curator is an account ranked by how many of its discoveries went on to trend
+type Curator struct {
+	DID         string        `db:"did" json:"did"`
+	Handle      string        `db:"handle" json:"handle"`
+	DisplayName *string       `db:"display_name" json:"display_name"`
+	AvatarURL   *string       `db:"avatar_url" json:"avatar_url"`
+	Degree      *int          `db:"degree" json:"degree"`
+	Discoveries int           `db:"discoveries" json:"discoveries"` // Links first shared in the window
+	Hits        int           `db:"hits" json:"hits"`               // Discoveries that reached the share threshold
+	HitRate     float64       `db:"hit_rate" json:"hit_rate"`
+	Examples    []CuratorLink `db:"-" json:"examples"`
+}
+
+// CuratorLink is an example hit on the leaderboard
+type CuratorLink struct {
+	DID        string  `db:"did" json:"-"`
+	LinkID     int     `db:"link_id" json:"link_id"`
+	URL        string  `db:"normalized_url" json:"url"`
+	Title      *string `db:"title" json:"title"`
+	ShareCount int     `db:"share_count" json:"share_count"`
+}
+
+// discoveredLinksCTE lists links first shared within the last $1 hours with
+// their distinct-sharer counts
+const discoveredLinksCTE = `
+		WITH discovered AS (
+			SELECT
+				l.id AS link_id,
+				l.first_shared_by AS did,
+				l.normalized_url,
+				l.title,
+				(
+					SELECT COUNT(DISTINCT p.author_did)
+					FROM post_links pl
+					JOIN posts p ON pl.post_id = p.id
+					WHERE pl.link_id = l.id
+				) AS share_count
+			FROM links l
+			WHERE l.first_shared_by IS NOT NULL
+			  AND l.first_shared_at > NOW() - INTERVAL '1 hour' * $1
+		)
+	`
+
+// GetCuratorLeaderboard ranks accounts by how many links they were first to
+// share in the last hoursBack hours that later reached minShares distinct
+// sharers. Each curator includes up to examplesPer of their biggest hits.
+func (db *DB) GetCuratorLeaderboard(hoursBack, minShares, limit, examplesPer int) ([]Curator, error) {
+	query := discoveredLinksCTE + `
+		SELECT
+			d.did,
+			COALESCE(n.handle, d.did) AS handle,
+			n.display_name,
+			n.avatar_url,
+			n.degree,
+			COUNT(*) AS discoveries,
+			COUNT(*) FILTER (WHERE d.share_count >= $2) AS hits,
+			COUNT(*) FILTER (WHERE d.share_count >= $2)::float / COUNT(*) AS hit_rate
+		FROM discovered d
+		LEFT JOIN network_accounts n ON n.did = d.did
+		GROUP BY d.did, n.handle, n.display_name, n.avatar_url, n.degree
+		HAVING COUNT(*) FILTER (WHERE d.share_count >= $2) > 0
+		ORDER BY hits DESC, hit_rate DESC, discoveries DESC
+		LIMIT $3
+	`
+
+	var curators []Curator
+	if err := db.Select(&curators, query, hoursBack, minShares, limit); err != nil {
+		return nil, err
+	}
+	if len(curators) == 0 || examplesPer <= 0 {
+		return curators, nil
+	}
+
+	dids := make([]string, len(curators))
+	for i, c := range curators {
+		dids[i] = c.DID
+	}
+
+	examplesQuery := discoveredLinksCTE + `
+		SELECT did, link_id, normalized_url, title, share_count
+		FROM (
+			SELECT d.*, ROW_NUMBER() OVER (PARTITION BY d.did ORDER BY d.share_count DESC, d.link_id) AS rank
+			FROM discovered d
+			WHERE d.did = ANY($3) AND d.share_count >= $2
+		) ranked
+		WHERE rank <= $4
+	`
+
+	var examples []CuratorLink
+	if err := db.Select(&examples, examplesQuery, hoursBack, minShares, pq.StringArray(dids), examplesPer); err != nil {
+		return nil, err
+	}
+
+	byDID := make(map[string][]CuratorLink)
+	for _, e := range examples {
+		byDID[e.DID] = append(byDID[e.DID], e)
+	}
+	for i := range curators {
+		curators[i].Examples = byDID[curators[i].DID]
+		if curators[i].Examples == nil {
+			curators[i].Examples = []CuratorLink{}
+		}
+	}
+	return curators, nil
+}
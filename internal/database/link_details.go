@@ -0,0 +1,91 @@
+package database
+
+import (
+	"database/sql"
+	"time"
+)
+
+// LinkBreakdown decomposes a link's shares by degree, author and relation type
+type LinkBreakdown struct {
+	TotalShares        int        `db:"total_shares" json:"total_shares"`
+	UniqueAuthors      int        `db:"unique_authors" json:"unique_authors"`
+	FirstDegreeShares  int        `db:"first_degree_shares" json:"first_degree_shares"`
+	SecondDegreeShares int        `db:"second_degree_shares" json:"second_degree_shares"`
+	OutOfNetworkShares int        `db:"out_of_network_shares" json:"out_of_network_shares"`
+	OriginalShares     int        `db:"original_shares" json:"original_shares"`
+	QuoteShares        int        `db:"quote_shares" json:"quote_shares"`
+	RepostShares       int        `db:"repost_shares" json:"repost_shares"`
+	FirstSharedAt      *time.Time `db:"first_shared_at" json:"first_shared_at"`
+	LastSharedAt       *time.Time `db:"last_shared_at" json:"last_shared_at"`
+}
+
+// LinkContributor is one account's contribution to a link's shares
+type LinkContributor struct {
+	DID           string    `db:"did" json:"did"`
+	Handle        string    `db:"handle" json:"handle"`
+	DisplayName   *string   `db:"display_name" json:"display_name"`
+	AvatarURL     *string   `db:"avatar_url" json:"avatar_url"`
+	Degree        int       `db:"degree" json:"degree"`
+	Shares        int       `db:"shares" json:"shares"`
+	FirstSharedAt time.Time `db:"first_shared_at" json:"first_shared_at"`
+}
+
+// GetLinkByID returns a link, or nil if it doesn't exist
+func (db *DB) GetLinkByID(linkID int) (*Link, error) {
+	link := &Link{}
+	err := db.Get(link, `SELECT * FROM links WHERE id = $1`, linkID)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return link, err
+}
+
+// GetLinkBreakdown aggregates how a link's shares decompose.
+// Degree counts are distinct authors, matching share_count in trending.
+func (db *DB) GetLinkBreakdown(linkID int) (*LinkBreakdown, error) {
+	query := `
+		SELECT
+			COUNT(*) AS total_shares,
+			COUNT(DISTINCT p.author_did) AS unique_authors,
+			COUNT(DISTINCT p.author_did) FILTER (WHERE COALESCE(pl.degree, p.author_degree) = 1) AS first_degree_shares,
+			COUNT(DISTINCT p.author_did) FILTER (WHERE COALESCE(pl.degree, p.author_degree) = 2) AS second_degree_shares,
+			COUNT(DISTINCT p.author_did) FILTER (WHERE COALESCE(pl.degree, p.author_degree, 0) NOT IN (1, 2)) AS out_of_network_shares,
+			COUNT(*) FILTER (WHERE pl.relation_type = 'original') AS original_shares,
+			COUNT(*) FILTER (WHERE pl.relation_type = 'quote') AS quote_shares,
+			COUNT(*) FILTER (WHERE pl.relation_type = 'repost') AS repost_shares,
+			MIN(p.created_at) AS first_shared_at,
+			MAX(p.created_at) AS last_shared_at
+		FROM post_links pl
+		JOIN posts p ON pl.post_id = p.id
+		WHERE pl.link_id = $1
+	`
+
+	breakdown := &LinkBreakdown{}
+	err := db.Get(breakdown, query, linkID)
+	return breakdown, err
+}
+
+// GetLinkContributors returns the accounts that shared a link, earliest first
+func (db *DB) GetLinkContributors(linkID int, limit int) ([]LinkContributor, error) {
+	query := `
+		SELECT
+			COALESCE(n.did, p.author_did, p.author_handle) AS did,
+			COALESCE(n.handle, p.author_handle) AS handle,
+			n.display_name,
+			n.avatar_url,
+			COALESCE(MAX(pl.degree), MAX(p.author_degree), 0) AS degree,
+			COUNT(*) AS shares,
+			MIN(p.created_at) AS first_shared_at
+		FROM post_links pl
+		JOIN posts p ON pl.post_id = p.id
+		LEFT JOIN network_accounts n ON p.author_did = n.did
+		WHERE pl.link_id = $1
+		GROUP BY 1, 2, 3, 4
+		ORDER BY first_shared_at ASC
+		LIMIT $2
+	`
+
+	var contributors []LinkContributor
+	err := db.Select(&contributors, query, linkID, limit)
+	return contributors, err
+}
@@ -0,0 +1,79 @@
+package database
+
+import (
+	"fmt"
+	"time"
+)
+
+// LinkEvent is an event mentioned by a link's article
+type LinkEvent struct {
+	ID       int        `db:"id" json:"id"`
+	LinkID   int        `db:"link_id" json:"link_id"`
+	Name     string     `db:"name" json:"name"`
+	StartAt  time.Time  `db:"start_at" json:"start_at"`
+	EndAt    *time.Time `db:"end_at" json:"end_at"`
+	AllDay   bool       `db:"all_day" json:"all_day"`
+	Location *string    `db:"location" json:"location"`
+	Source   string     `db:"source" json:"source"`
+}
+
+// UpcomingEvent is a future event mentioned by a trending link
+type UpcomingEvent struct {
+	LinkEvent
+	URL        string  `db:"normalized_url" json:"url"`
+	Title      *string `db:"title" json:"title"`
+	ShareCount int     `db:"share_count" json:"share_count"`
+}
+
+// ReplaceLinkEvents sets the events detected for a link
+func (db *DB) ReplaceLinkEvents(linkID int, events []LinkEvent) error {
+	tx, err := db.Beginx()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM link_events WHERE link_id = $1`, linkID); err != nil {
+		return fmt.Errorf("failed to clear link events: %w", err)
+	}
+	for _, e := range events {
+		_, err := tx.Exec(`
+			INSERT INTO link_events (link_id, name, start_at, end_at, all_day, location, source)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+		`, linkID, e.Name, e.StartAt, e.EndAt, e.AllDay, e.Location, e.Source)
+		if err != nil {
+			return fmt.Errorf("failed to insert link event: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetUpcomingEvents returns events starting at or after from that are
+// mentioned by links shared by at least minShares accounts in the last
+// hoursBack hours, soonest first
+func (db *DB) GetUpcomingEvents(from time.Time, hoursBack, minShares, limit int) ([]UpcomingEvent, error) {
+	query := `
+		WITH trending AS (
+			SELECT pl.link_id, COUNT(DISTINCT p.author_did) AS share_count
+			FROM post_links pl
+			JOIN posts p ON pl.post_id = p.id
+			WHERE p.created_at > NOW() - INTERVAL '1 hour' * $2
+			GROUP BY pl.link_id
+			HAVING COUNT(DISTINCT p.author_did) >= $3
+		)
+		SELECT
+			e.id, e.link_id, e.name, e.start_at, e.end_at, e.all_day, e.location, e.source,
+			l.normalized_url, l.title, t.share_count
+		FROM link_events e
+		JOIN trending t ON t.link_id = e.link_id
+		JOIN links l ON l.id = e.link_id
+		WHERE COALESCE(e.end_at, e.start_at) >= $1
+		ORDER BY e.start_at ASC, t.share_count DESC
+		LIMIT $4
+	`
+
+	var events []UpcomingEvent
+	err := db.Select(&events, query, from, hoursBack, minShares, limit)
+	return events, err
+}
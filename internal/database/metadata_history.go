@@ -0,0 +1,105 @@
+package database
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// MetadataRevision is a link's metadata as it was before a refresh changed it
+type MetadataRevision struct {
+	Title       *string   `db:"title" json:"title"`
+	Description *string   `db:"description" json:"description"`
+	OGImageURL  *string   `db:"og_image_url" json:"image_url"`
+	ReplacedAt  time.Time `db:"replaced_at" json:"replaced_at"`
+}
+
+// GetLinksDueForRefresh returns links first seen after seenAfter, with at
+// least minShares shares, whose metadata was last fetched before
+// fetchedBefore. Least recently fetched links come first.
+func (db *DB) GetLinksDueForRefresh(seenAfter, fetchedBefore time.Time, minShares, limit int) ([]Link, error) {
+	var links []Link
+	err := db.Select(&links, `
+		SELECT l.* FROM links l
+		WHERE l.first_seen_at > $1
+		  AND l.last_fetched_at < $2
+		  AND (SELECT COUNT(*) FROM post_links pl WHERE pl.link_id = l.id) >= $3
+		ORDER BY l.last_fetched_at
+		LIMIT $4
+	`, seenAfter, fetchedBefore, minShares, limit)
+	return links, err
+}
+
+// UpdateLinkValidators stores the HTTP cache validators from a scrape
+func (db *DB) UpdateLinkValidators(linkID int, etag, lastModified string) error {
+	_, err := db.Exec(`
+		UPDATE links SET etag = NULLIF($2, ''), last_modified = NULLIF($3, '')
+		WHERE id = $1
+	`, linkID, etag, lastModified)
+	return err
+}
+
+// RefreshLinkMetadata stores re-scraped metadata and the response's cache
+// validators. Empty values keep the current ones. When the displayed title,
+// description or image changes, the previous values are saved to
+// link_metadata_history. Reports whether anything changed.
+func (db *DB) RefreshLinkMetadata(linkID int, title, description, imageURL, etag, lastModified string) (bool, error) {
+	tx, err := db.Beginx()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	changed, err := saveLinkMetadata(tx, linkID, MetadataSourceScraped, title, description, imageURL)
+	if err != nil {
+		return false, err
+	}
+
+	if _, err := tx.Exec(`
+		UPDATE links SET etag = NULLIF($2, ''), last_modified = NULLIF($3, '')
+		WHERE id = $1
+	`, linkID, etag, lastModified); err != nil {
+		return false, err
+	}
+
+	return changed, tx.Commit()
+}
+
+// GetLinkMetadataHistory returns a link's replaced metadata, newest first
+func (db *DB) GetLinkMetadataHistory(linkID int) ([]MetadataRevision, error) {
+	var revisions []MetadataRevision
+	err := db.Select(&revisions, `
+		SELECT title, description, og_image_url, replaced_at
+		FROM link_metadata_history
+		WHERE link_id = $1
+		ORDER BY replaced_at DESC, id DESC
+	`, linkID)
+	return revisions, err
+}
+
+// GetPreviousTitles returns, for those of the given links whose headline has
+// changed, the most recent title that differs from the current one
+func (db *DB) GetPreviousTitles(linkIDs []int) (map[int]string, error) {
+	var rows []struct {
+		LinkID int    `db:"link_id"`
+		Title  string `db:"title"`
+	}
+	err := db.Select(&rows, `
+		SELECT DISTINCT ON (h.link_id) h.link_id, h.title
+		FROM link_metadata_history h
+		JOIN links l ON l.id = h.link_id
+		WHERE h.link_id = ANY($1)
+		  AND h.title IS NOT NULL AND h.title <> ''
+		  AND h.title IS DISTINCT FROM l.title
+		ORDER BY h.link_id, h.replaced_at DESC, h.id DESC
+	`, pq.Array(linkIDs))
+	if err != nil {
+		return nil, err
+	}
+
+	titles := make(map[int]string, len(rows))
+	for _, row := range rows {
+		titles[row.LinkID] = row.Title
+	}
+	return titles, nil
+}
@@ -0,0 +1,197 @@
+package database
+
+import (
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/metaquality"
+)
+
+// Metadata sources
+const (
+	MetadataSourceBluesky = "bluesky" // Link card embedded in the post by the Bluesky client
+	MetadataSourceScraped = "scraped" // Our own OpenGraph scrape
+)
+
+// LinkMetadataSource is one source's metadata for a link, with its quality score
+type LinkMetadataSource struct {
+	Source      string    `db:"source" json:"source"`
+	Title       *string   `db:"title" json:"title"`
+	Description *string   `db:"description" json:"description"`
+	ImageURL    *string   `db:"image_url" json:"image_url"`
+	Score       int       `db:"score" json:"score"`
+	UpdatedAt   time.Time `db:"updated_at" json:"updated_at"`
+}
+
+// IsMetadataSource reports whether source is a known metadata source
+func IsMetadataSource(source string) bool {
+	return source == MetadataSourceBluesky || source == MetadataSourceScraped
+}
+
+// SaveLinkMetadata stores a source's metadata for a link and displays the
+// better-scoring source (or the one an admin pinned). Empty values keep the
+// source's previous ones. Reports whether the displayed metadata changed.
+func (db *DB) SaveLinkMetadata(linkID int, source, title, description, imageURL string) (bool, error) {
+	tx, err := db.Beginx()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	changed, err := saveLinkMetadata(tx, linkID, source, title, description, imageURL)
+	if err != nil {
+		return false, err
+	}
+	return changed, tx.Commit()
+}
+
+// SetLinkImageMissing records whether the last scrape of a link found no
+// usable preview image
+func (db *DB) SetLinkImageMissing(linkID int, missing bool) error {
+	_, err := db.Exec(`UPDATE links SET image_missing = $2 WHERE id = $1`, linkID, missing)
+	return err
+}
+
+// SetLinkMetadataPreference pins the source a link displays, or with an
+// empty source goes back to the higher score. A pinned source with no
+// metadata stored yet takes effect once it has some. Returns false if the
+// link doesn't exist.
+func (db *DB) SetLinkMetadataPreference(linkID int, source string) (bool, error) {
+	tx, err := db.Beginx()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`UPDATE links SET metadata_preference = NULLIF($2, '') WHERE id = $1`, linkID, source)
+	if err != nil {
+		return false, err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return false, nil
+	}
+
+	if _, err := selectLinkMetadata(tx, linkID); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// GetLinkMetadataSources returns each source's metadata for a link
+func (db *DB) GetLinkMetadataSources(linkID int) ([]LinkMetadataSource, error) {
+	var sources []LinkMetadataSource
+	err := db.Select(&sources, `
+		SELECT source, title, description, image_url, score, updated_at
+		FROM link_metadata_sources
+		WHERE link_id = $1
+		ORDER BY score DESC, source
+	`, linkID)
+	return sources, err
+}
+
+// saveLinkMetadata upserts a source row, rescoring it with its merged
+// values, then reselects the displayed metadata
+func saveLinkMetadata(tx *sqlx.Tx, linkID int, source, title, description, imageURL string) (bool, error) {
+	var merged struct {
+		Title       string `db:"title"`
+		Description string `db:"description"`
+		ImageURL    string `db:"image_url"`
+	}
+	err := tx.Get(&merged, `
+		SELECT COALESCE(NULLIF($3, ''), title, '') AS title,
+		       COALESCE(NULLIF($4, ''), description, '') AS description,
+		       COALESCE(NULLIF($5, ''), image_url, '') AS image_url
+		FROM (SELECT NULL) AS one
+		LEFT JOIN link_metadata_sources ON link_id = $1 AND source = $2
+	`, linkID, source, title, description, imageURL)
+	if err != nil {
+		return false, err
+	}
+
+	score := metaquality.Score(merged.Title, merged.Description, merged.ImageURL)
+	if _, err := tx.Exec(`
+		INSERT INTO link_metadata_sources (link_id, source, title, description, image_url, score)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (link_id, source) DO UPDATE SET
+			title = EXCLUDED.title,
+			description = EXCLUDED.description,
+			image_url = EXCLUDED.image_url,
+			score = EXCLUDED.score,
+			updated_at = NOW()
+	`, linkID, source, merged.Title, merged.Description, merged.ImageURL, score); err != nil {
+		return false, err
+	}
+
+	if _, err := tx.Exec(`UPDATE links SET last_fetched_at = NOW() WHERE id = $1`, linkID); err != nil {
+		return false, err
+	}
+	return selectLinkMetadata(tx, linkID)
+}
+
+// selectLinkMetadata copies the winning source's metadata onto the link:
+// the pinned source if it has metadata, otherwise the highest score, with
+// ties kept on the source already displayed. Metadata replaced by a newer
+// version from the same source is saved to link_metadata_history. Reports
+// whether the displayed metadata changed.
+func selectLinkMetadata(tx *sqlx.Tx, linkID int) (bool, error) {
+	var current struct {
+		Title       *string `db:"title"`
+		Description *string `db:"description"`
+		OGImageURL  *string `db:"og_image_url"`
+		Source      *string `db:"metadata_source"`
+		Preference  *string `db:"metadata_preference"`
+	}
+	err := tx.Get(&current, `
+		SELECT title, description, og_image_url, metadata_source, metadata_preference
+		FROM links WHERE id = $1 FOR UPDATE
+	`, linkID)
+	if err != nil {
+		return false, err
+	}
+
+	var picks []LinkMetadataSource
+	err = tx.Select(&picks, `
+		SELECT source, title, description, image_url, score, updated_at
+		FROM link_metadata_sources
+		WHERE link_id = $1
+		ORDER BY source = $2 DESC, score DESC, source = $3 DESC, source
+		LIMIT 1
+	`, linkID, stringValue(current.Preference), stringValue(current.Source))
+	if err != nil || len(picks) == 0 {
+		return false, err
+	}
+	pick := picks[0]
+
+	changed := stringValue(current.Title) != stringValue(pick.Title) ||
+		stringValue(current.Description) != stringValue(pick.Description) ||
+		stringValue(current.OGImageURL) != stringValue(pick.ImageURL)
+
+	// Only a source revising its own metadata is history; switching sources
+	// isn't the story changing
+	sameSource := current.Source == nil || *current.Source == pick.Source
+	hadMetadata := current.Title != nil || current.Description != nil || current.OGImageURL != nil
+	if changed && sameSource && hadMetadata {
+		if _, err := tx.Exec(`
+			INSERT INTO link_metadata_history (link_id, title, description, og_image_url)
+			VALUES ($1, $2, $3, $4)
+		`, linkID, current.Title, current.Description, current.OGImageURL); err != nil {
+			return false, err
+		}
+	}
+
+	if _, err := tx.Exec(`
+		UPDATE links
+		SET title = $2, description = $3, og_image_url = $4, metadata_source = $5
+		WHERE id = $1
+	`, linkID, pick.Title, pick.Description, pick.ImageURL, pick.Source); err != nil {
+		return false, err
+	}
+	return changed, nil
+}
+
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
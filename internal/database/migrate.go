@@ -0,0 +1,137 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/lib/pq"
+)
+
+// MigrationsDir holds the migration files, relative to the working
+// directory services are started from
+const MigrationsDir = "migrations"
+
+// migrationLockName is the advisory lock held while migrating
+const migrationLockName = "bluesky-news:migrations"
+
+// migrationsTable records the migration files already applied, in the
+// schema the connection's search_path resolves to
+const migrationsTable = "schema_migrations"
+
+// Migrate runs the migration files in dir that haven't run yet, in name
+// order, recording each in schema_migrations. Each file runs in a
+// transaction with its record, so a failed file is retried next time. A
+// schema is created first so the connection's search_path can resolve to it.
+//
+// An advisory lock, scoped to the schema like the scheduler's, makes
+// services starting together (or cmd/migrate) take turns rather than run
+// the same DDL concurrently. before is called with each file's name before
+// it runs (nil = no progress reports).
+func (db *DB) Migrate(ctx context.Context, dir, schema string, before func(name string)) error {
+	return db.migrate(ctx, dir, schema, false, before)
+}
+
+// RerunMigrations runs every migration file in dir again, applied or not.
+// Migrations are written to be re-run (IF NOT EXISTS, CREATE OR REPLACE),
+// so this recreates indexes, views and functions lost with a replaced table
+// and leaves the rest as it is.
+func (db *DB) RerunMigrations(ctx context.Context, dir, schema string, before func(name string)) error {
+	return db.migrate(ctx, dir, schema, true, before)
+}
+
+// migrate runs the migration files in dir, all of them or only those not
+// yet recorded
+func (db *DB) migrate(ctx context.Context, dir, schema string, all bool, before func(name string)) error {
+	files, err := filepath.Glob(filepath.Join(dir, "*.sql"))
+	if err != nil {
+		return fmt.Errorf("failed to find migrations: %w", err)
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("no migrations found in %s", dir)
+	}
+
+	lockName := migrationLockName
+	if schema != "" {
+		lockName += ":" + schema
+	}
+	lock, err := db.AcquireAdvisoryLock(ctx, LockKey(lockName))
+	if err != nil {
+		return err
+	}
+	defer lock.Release()
+
+	if schema != "" {
+		if _, err := lock.conn.ExecContext(ctx, "CREATE SCHEMA IF NOT EXISTS "+pq.QuoteIdentifier(schema)); err != nil {
+			return fmt.Errorf("failed to create schema %s: %w", schema, err)
+		}
+	}
+
+	_, err = lock.conn.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS `+migrationsTable+` (
+			name TEXT PRIMARY KEY,
+			applied_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", migrationsTable, err)
+	}
+	applied := make(map[string]bool)
+	rows, err := lock.conn.QueryContext(ctx, `SELECT name FROM `+migrationsTable)
+	if err != nil {
+		return fmt.Errorf("failed to read applied migrations: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return fmt.Errorf("failed to read applied migrations: %w", err)
+		}
+		applied[name] = true
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read applied migrations: %w", err)
+	}
+
+	for _, file := range files {
+		name := filepath.Base(file)
+		if applied[name] && !all {
+			continue
+		}
+		if before != nil {
+			before(name)
+		}
+
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return fmt.Errorf("failed to read migration %s: %w", name, err)
+		}
+		if err := applyMigration(ctx, lock.conn, name, string(content)); err != nil {
+			return fmt.Errorf("failed to execute migration %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// applyMigration runs one migration file and records it, together
+func applyMigration(ctx context.Context, conn *sql.Conn, name, content string) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, content); err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO `+migrationsTable+` (name) VALUES ($1)
+		ON CONFLICT (name) DO UPDATE SET applied_at = NOW()
+	`, name)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,74 @@
+package database_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/testutil"
+)
+
+// TestMigrateRecordsApplied checks Migrate runs each file once, retries a
+// failed file from scratch, and RerunMigrations runs them all again
+func TestMigrateRecordsApplied(t *testing.T) {
+	if testing.Short() {
+		t.Skip("needs Postgres")
+	}
+	db := testutil.NewTestDB(t)
+	ctx := context.Background()
+
+	dir := t.TempDir()
+	write := func(name, sql string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(sql), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Inserts count how often each file ran
+	write("001_runs.sql", `CREATE TABLE IF NOT EXISTS migrate_runs (file TEXT); INSERT INTO migrate_runs VALUES ('001');`)
+	write("002_broken.sql", `INSERT INTO migrate_runs VALUES ('002'); SELECT * FROM no_such_table;`)
+
+	var ran []string
+	before := func(name string) { ran = append(ran, name) }
+	runs := func(file string) int {
+		t.Helper()
+		var n int
+		if err := db.Get(&n, `SELECT COUNT(*) FROM migrate_runs WHERE file = $1`, file); err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+
+	if err := db.Migrate(ctx, dir, "", before); err == nil {
+		t.Fatal("Migrate with a broken file succeeded")
+	}
+	if runs("001") != 1 || runs("002") != 0 {
+		t.Errorf("after a failure: 001 ran %d times, 002 left %d rows; want 1 and 0", runs("001"), runs("002"))
+	}
+
+	write("002_broken.sql", `INSERT INTO migrate_runs VALUES ('002');`)
+	ran = nil
+	if err := db.Migrate(ctx, dir, "", before); err != nil {
+		t.Fatalf("Migrate: %v", err)
+	}
+	if !slices.Equal(ran, []string{"002_broken.sql"}) {
+		t.Errorf("second Migrate ran %v, want only the fixed file", ran)
+	}
+
+	ran = nil
+	if err := db.Migrate(ctx, dir, "", before); err != nil {
+		t.Fatalf("Migrate: %v", err)
+	}
+	if len(ran) != 0 {
+		t.Errorf("Migrate with nothing pending ran %v", ran)
+	}
+
+	if err := db.RerunMigrations(ctx, dir, "", before); err != nil {
+		t.Fatalf("RerunMigrations: %v", err)
+	}
+	if len(ran) != 2 || runs("001") != 2 || runs("002") != 2 {
+		t.Errorf("RerunMigrations ran %v; 001 ran %d times, 002 %d; want both twice", ran, runs("001"), runs("002"))
+	}
+}
@@ -0,0 +1,57 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// LinkSharesChannel is notified by migration 039's trigger after posts share
+// links, with the inserting session's schema as payload
+const LinkSharesChannel = "link_shares"
+
+// ListenLinkShares opens a LISTEN connection on LinkSharesChannel and
+// returns a channel that receives a value whenever links are shared in this
+// database's schema. Bursts are coalesced: the channel holds at most one
+// pending signal. A reconnect also signals, since notifications may have
+// been missed while disconnected. The listener closes when ctx is done.
+func (db *DB) ListenLinkShares(ctx context.Context, connectionString string) (<-chan struct{}, error) {
+	var schema string
+	if err := db.GetContext(ctx, &schema, `SELECT current_schema()`); err != nil {
+		return nil, fmt.Errorf("failed to get current schema: %w", err)
+	}
+
+	listener := pq.NewListener(connectionString, time.Second, time.Minute, nil)
+	if err := listener.Listen(LinkSharesChannel); err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("failed to listen on %s: %w", LinkSharesChannel, err)
+	}
+
+	shared := make(chan struct{}, 1)
+	go func() {
+		defer listener.Close()
+		// Ping idle connections so a silently dropped one is noticed
+		ping := time.NewTicker(90 * time.Second)
+		defer ping.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case n := <-listener.Notify:
+				// nil = reconnected
+				if n != nil && n.Extra != schema {
+					continue
+				}
+				select {
+				case shared <- struct{}{}:
+				default:
+				}
+			case <-ping.C:
+				listener.Ping()
+			}
+		}
+	}()
+	return shared, nil
+}
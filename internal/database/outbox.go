@@ -0,0 +1,114 @@
+package database
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Outbox event types
+const (
+	OutboxLinkCreated  = "link_created"  // Written by a trigger when a link row is inserted
+	OutboxLinkTrending = "link_trending" // Written when a link enters the trending top list
+)
+
+// OutboxEvent is a domain event awaiting delivery to downstream sinks
+type OutboxEvent struct {
+	ID        int64           `db:"id" json:"id"`
+	EventType string          `db:"event_type" json:"type"`
+	LinkID    *int            `db:"link_id" json:"link_id,omitempty"`
+	Payload   json.RawMessage `db:"payload" json:"payload"`
+	CreatedAt time.Time       `db:"created_at" json:"created_at"`
+}
+
+// GetOutboxEventsAfter returns up to limit events with IDs above afterID, in
+// order. Events younger than a few seconds are held back: IDs are assigned
+// before commit, so a fresh event could still be joined by a lower ID that
+// would otherwise be skipped past.
+func (db *DB) GetOutboxEventsAfter(afterID int64, limit int) ([]OutboxEvent, error) {
+	var events []OutboxEvent
+	err := db.Select(&events, `
+		SELECT id, event_type, link_id, payload, created_at
+		FROM outbox_events
+		WHERE id > $1 AND created_at < NOW() - INTERVAL '5 seconds'
+		ORDER BY id
+		LIMIT $2
+	`, afterID, limit)
+	return events, err
+}
+
+// GetOutboxCursor returns the last event ID delivered to sink, creating the
+// cursor at the current end of the outbox so a new sink doesn't replay history
+func (db *DB) GetOutboxCursor(sink string) (int64, error) {
+	var id int64
+	err := db.Get(&id, `
+		WITH ins AS (
+			INSERT INTO outbox_sink_cursors (sink, last_event_id)
+			SELECT $1, COALESCE(MAX(id), 0) FROM outbox_events
+			ON CONFLICT (sink) DO NOTHING
+			RETURNING last_event_id
+		)
+		SELECT last_event_id FROM ins
+		UNION ALL
+		SELECT last_event_id FROM outbox_sink_cursors WHERE sink = $1
+		LIMIT 1
+	`, sink)
+	return id, err
+}
+
+// UpdateOutboxCursor records the last event ID delivered to sink
+func (db *DB) UpdateOutboxCursor(sink string, lastEventID int64) error {
+	_, err := db.Exec(`
+		UPDATE outbox_sink_cursors
+		SET last_event_id = $2, updated_at = NOW()
+		WHERE sink = $1
+	`, sink, lastEventID)
+	return err
+}
+
+// RecordTrendingEvents writes a link_trending event for each link that
+// hasn't had one within the past `within`, so a link that stays trending is
+// announced once. rank is the link's 1-based position. Returns the number
+// of events written.
+func (db *DB) RecordTrendingEvents(links []TrendingLink, hours int, within time.Duration) (int, error) {
+	recorded := 0
+	for i, link := range links {
+		payload, err := json.Marshal(map[string]interface{}{
+			"link_id":     link.ID,
+			"url":         link.NormalizedURL,
+			"title":       link.Title,
+			"rank":        i + 1,
+			"share_count": link.ShareCount,
+			"hours":       hours,
+		})
+		if err != nil {
+			return recorded, fmt.Errorf("failed to encode trending event: %w", err)
+		}
+
+		result, err := db.Exec(`
+			INSERT INTO outbox_events (event_type, link_id, payload)
+			SELECT $1, $2, $3
+			WHERE NOT EXISTS (
+				SELECT 1 FROM outbox_events
+				WHERE event_type = $1 AND link_id = $2
+				  AND created_at > NOW() - $4 * INTERVAL '1 second'
+			)
+		`, OutboxLinkTrending, link.ID, payload, int(within.Seconds()))
+		if err != nil {
+			return recorded, err
+		}
+		if n, _ := result.RowsAffected(); n > 0 {
+			recorded++
+		}
+	}
+	return recorded, nil
+}
+
+// DeleteOldOutboxEvents removes events created before cutoff
+func (db *DB) DeleteOldOutboxEvents(cutoff time.Time) (int64, error) {
+	result, err := db.Exec(`DELETE FROM outbox_events WHERE created_at < $1`, cutoff)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,211 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// partitionDayFormat is the day suffix of partition names (posts_p20260131)
+const partitionDayFormat = "20060102"
+
+// partitionedTables are partitioned by day together, each on its post time
+// column. post_links comes first: its partitions are dropped before the
+// posts partitions they reference.
+var partitionedTables = []struct {
+	Name   string
+	Column string
+}{
+	{"post_links", "post_created_at"},
+	{"posts", "created_at"},
+}
+
+// partitionName returns the name of a table's partition for a day
+func partitionName(table string, day time.Time) string {
+	return table + "_p" + day.Format(partitionDayFormat)
+}
+
+// IsPostsPartitioned reports whether posts has been converted to daily
+// partitions by PartitionPosts
+func (db *DB) IsPostsPartitioned() (bool, error) {
+	var partitioned bool
+	err := db.Get(&partitioned, `SELECT relkind = 'p' FROM pg_class WHERE oid = 'posts'::regclass`)
+	return partitioned, err
+}
+
+// GetPostPartitionDays returns the days posts has partitions for, oldest
+// first. The default partition isn't included.
+func (db *DB) GetPostPartitionDays() ([]time.Time, error) {
+	var names []string
+	err := db.Select(&names, `
+		SELECT c.relname
+		FROM pg_inherits i
+		JOIN pg_class c ON c.oid = i.inhrelid
+		WHERE i.inhparent = 'posts'::regclass
+		ORDER BY c.relname
+	`)
+	if err != nil {
+		return nil, err
+	}
+
+	var days []time.Time
+	for _, name := range names {
+		day, err := time.Parse(partitionDayFormat, strings.TrimPrefix(name, "posts_p"))
+		if err != nil {
+			continue // posts_default
+		}
+		days = append(days, day)
+	}
+	return days, nil
+}
+
+// CreatePostPartition adds the posts and post_links partitions for a day.
+// It returns false without creating them if rows for the day already landed
+// in a default partition, since Postgres won't carve a partition out of
+// rows it holds; those rows are left to row-by-row retention.
+func (db *DB) CreatePostPartition(day time.Time) (bool, error) {
+	tx, err := db.Beginx()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	created, err := createPostPartition(tx, day)
+	if err != nil || !created {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// createPostPartition creates a day's partitions in a transaction
+func createPostPartition(tx *sqlx.Tx, day time.Time) (bool, error) {
+	from, to := day.Format("2006-01-02"), day.AddDate(0, 0, 1).Format("2006-01-02")
+
+	for _, table := range partitionedTables {
+		var inDefault bool
+		err := tx.Get(&inDefault, fmt.Sprintf(
+			`SELECT EXISTS (SELECT 1 FROM %s WHERE %s >= $1 AND %[2]s < $2)`,
+			pq.QuoteIdentifier(table.Name+"_default"), table.Column,
+		), from, to)
+		if err != nil {
+			return false, fmt.Errorf("failed to check %s_default: %w", table.Name, err)
+		}
+		if inDefault {
+			return false, nil
+		}
+	}
+
+	for _, table := range partitionedTables {
+		_, err := tx.Exec(fmt.Sprintf(
+			`CREATE TABLE IF NOT EXISTS %s PARTITION OF %s FOR VALUES FROM ('%s') TO ('%s')`,
+			pq.QuoteIdentifier(partitionName(table.Name, day)), table.Name, from, to,
+		))
+		if err != nil {
+			return false, fmt.Errorf("failed to create %s partition: %w", table.Name, err)
+		}
+	}
+	return true, nil
+}
+
+// DropPostPartition drops the posts and post_links partitions for a day,
+// returning the number of posts they held
+func (db *DB) DropPostPartition(day time.Time) (int, error) {
+	tx, err := db.Beginx()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var posts int
+	err = tx.Get(&posts, `SELECT COUNT(*) FROM `+pq.QuoteIdentifier(partitionName("posts", day)))
+	if err != nil {
+		return 0, fmt.Errorf("failed to count posts partition: %w", err)
+	}
+
+	// A partition referenced by a foreign key can't be dropped while
+	// attached; detaching checks nothing still references it
+	for _, table := range partitionedTables {
+		partition := pq.QuoteIdentifier(partitionName(table.Name, day))
+		if _, err := tx.Exec(`ALTER TABLE ` + table.Name + ` DETACH PARTITION ` + partition); err != nil {
+			return 0, fmt.Errorf("failed to detach %s partition: %w", table.Name, err)
+		}
+		if _, err := tx.Exec(`DROP TABLE ` + partition); err != nil {
+			return 0, fmt.Errorf("failed to drop %s partition: %w", table.Name, err)
+		}
+	}
+
+	return posts, tx.Commit()
+}
+
+// PartitionPosts converts posts and post_links to tables partitioned by day
+// of the post, with partitions from firstDay through lastDay and default
+// partitions for anything outside them. Rows are copied in one transaction,
+// so writers block until it commits. Indexes and views are dropped with the
+// old tables; re-run the migrations afterwards to recreate them. Returns the
+// number of posts and post_links copied.
+func (db *DB) PartitionPosts(firstDay, lastDay time.Time) (posts, postLinks int64, err error) {
+	tx, err := db.Beginx()
+	if err != nil {
+		return 0, 0, err
+	}
+	defer tx.Rollback()
+
+	steps := []string{
+		// Shares of posts that are gone can't satisfy the new foreign key
+		`UPDATE post_links pl SET post_created_at = p.created_at
+		 FROM posts p WHERE pl.post_id = p.id AND pl.post_created_at IS NULL`,
+		`DELETE FROM post_links WHERE post_created_at IS NULL`,
+
+		`ALTER TABLE post_links RENAME TO post_links_unpartitioned`,
+		`ALTER TABLE posts RENAME TO posts_unpartitioned`,
+		`CREATE TABLE posts (LIKE posts_unpartitioned INCLUDING DEFAULTS INCLUDING CONSTRAINTS INCLUDING COMMENTS)
+		 PARTITION BY RANGE (created_at)`,
+		`CREATE TABLE post_links (LIKE post_links_unpartitioned INCLUDING DEFAULTS INCLUDING CONSTRAINTS INCLUDING COMMENTS)
+		 PARTITION BY RANGE (post_created_at)`,
+		`CREATE TABLE posts_default PARTITION OF posts DEFAULT`,
+		`CREATE TABLE post_links_default PARTITION OF post_links DEFAULT`,
+	}
+	for _, step := range steps {
+		if _, err := tx.Exec(step); err != nil {
+			return 0, 0, fmt.Errorf("failed to create partitioned tables: %w", err)
+		}
+	}
+
+	for day := firstDay; !day.After(lastDay); day = day.AddDate(0, 0, 1) {
+		if _, err := createPostPartition(tx, day); err != nil {
+			return 0, 0, err
+		}
+	}
+
+	result, err := tx.Exec(`INSERT INTO posts SELECT * FROM posts_unpartitioned`)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to copy posts: %w", err)
+	}
+	posts, _ = result.RowsAffected()
+	result, err = tx.Exec(`INSERT INTO post_links SELECT * FROM post_links_unpartitioned`)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to copy post_links: %w", err)
+	}
+	postLinks, _ = result.RowsAffected()
+
+	// Keys must include the partition column
+	steps = []string{
+		`DROP TABLE post_links_unpartitioned`,
+		`DROP TABLE posts_unpartitioned CASCADE`,
+		`ALTER TABLE posts ADD PRIMARY KEY (id, created_at)`,
+		`ALTER TABLE post_links ADD PRIMARY KEY (post_id, link_id, post_created_at)`,
+		`ALTER TABLE post_links ADD FOREIGN KEY (post_id, post_created_at)
+		 REFERENCES posts(id, created_at) ON DELETE CASCADE`,
+		`ALTER TABLE post_links ADD FOREIGN KEY (link_id) REFERENCES links(id) ON DELETE CASCADE`,
+	}
+	for _, step := range steps {
+		if _, err := tx.Exec(step); err != nil {
+			return 0, 0, fmt.Errorf("failed to replace unpartitioned tables: %w", err)
+		}
+	}
+
+	return posts, postLinks, tx.Commit()
+}
@@ -0,0 +1,39 @@
+package database_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/database"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/testutil"
+)
+
+// TestInsertPostReportsNew checks InsertPost only reports a post as new the
+// first time, whichever source delivers it again
+func TestInsertPostReportsNew(t *testing.T) {
+	if testing.Short() {
+		t.Skip("needs Postgres")
+	}
+	db := testutil.NewTestDB(t)
+
+	post := &database.Post{
+		ID:           "at://did:plc:a/app.bsky.feed.post/1",
+		AuthorHandle: "a.example.com",
+		AuthorDID:    "did:plc:a",
+		AuthorDegree: 1,
+		Content:      "https://example.com/story",
+		Source:       database.IngestSourceFirehose,
+		CreatedAt:    time.Now(),
+	}
+
+	for i, source := range []string{database.IngestSourceFirehose, database.IngestSourceFirehose, database.IngestSourcePoller, ""} {
+		post.Source = source
+		inserted, err := db.InsertPost(post)
+		if err != nil {
+			t.Fatalf("InsertPost #%d: %v", i+1, err)
+		}
+		if want := i == 0; inserted != want {
+			t.Errorf("InsertPost #%d (source %q) = %v, want %v", i+1, source, inserted, want)
+		}
+	}
+}
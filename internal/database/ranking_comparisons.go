@@ -0,0 +1,67 @@
+package database
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// RankingComparisonRetention is how long shadow ranking comparisons are kept
+const RankingComparisonRetention = 30 * 24 * time.Hour
+
+// RankingComparisonSummary averages the shadow comparisons of one pair of
+// ranking strategies
+type RankingComparisonSummary struct {
+	PrimaryStrategy string    `db:"primary_strategy" json:"primary_strategy"`
+	ShadowStrategy  string    `db:"shadow_strategy" json:"shadow_strategy"`
+	Comparisons     int       `db:"comparisons" json:"comparisons"`
+	AvgLinks        float64   `db:"avg_links" json:"avg_links"`
+	AvgKendallTau   float64   `db:"avg_kendall_tau" json:"avg_kendall_tau"`
+	MinKendallTau   float64   `db:"min_kendall_tau" json:"min_kendall_tau"`
+	AvgTopOverlap   float64   `db:"avg_top_overlap" json:"avg_top_overlap"`
+	LastComparedAt  time.Time `db:"last_compared_at" json:"last_compared_at"`
+}
+
+// SaveRankingComparison stores how a shadow strategy's order diverged from
+// the served one
+func (db *DB) SaveRankingComparison(primary, shadow string, links int, kendallTau, topOverlap float64, primaryIDs, shadowIDs []int) error {
+	query := `
+		INSERT INTO ranking_comparisons
+			(primary_strategy, shadow_strategy, links, kendall_tau, top_overlap, primary_ids, shadow_ids)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+	_, err := db.Exec(query, primary, shadow, links, kendallTau, topOverlap, pq.Array(primaryIDs), pq.Array(shadowIDs))
+	return err
+}
+
+// GetRankingComparisonSummaries averages the comparisons made since the
+// given time per strategy pair
+func (db *DB) GetRankingComparisonSummaries(since time.Time) ([]RankingComparisonSummary, error) {
+	query := `
+		SELECT
+			primary_strategy,
+			shadow_strategy,
+			COUNT(*) as comparisons,
+			AVG(links)::float8 as avg_links,
+			AVG(kendall_tau) as avg_kendall_tau,
+			MIN(kendall_tau) as min_kendall_tau,
+			AVG(top_overlap) as avg_top_overlap,
+			MAX(compared_at) as last_compared_at
+		FROM ranking_comparisons
+		WHERE compared_at > $1
+		GROUP BY primary_strategy, shadow_strategy
+		ORDER BY comparisons DESC
+	`
+	summaries := []RankingComparisonSummary{}
+	err := db.Select(&summaries, query, since)
+	return summaries, err
+}
+
+// DeleteOldRankingComparisons removes comparisons made before the cutoff
+func (db *DB) DeleteOldRankingComparisons(cutoff time.Time) (int64, error) {
+	result, err := db.Exec(`DELETE FROM ranking_comparisons WHERE compared_at < $1`, cutoff)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
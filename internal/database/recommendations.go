@@ -0,0 +1,64 @@
+package database
+
+// FollowRecommendation is a 2nd-degree account whose shares often trend
+type FollowRecommendation struct {
+	DID         string  `db:"did" json:"did"`
+	Handle      string  `db:"handle" json:"handle"`
+	DisplayName *string `db:"display_name" json:"display_name"`
+	AvatarURL   *string `db:"avatar_url" json:"avatar_url"`
+	SourceCount int     `db:"source_count" json:"source_count"` // 1st-degree follows who follow them
+	SharedLinks int     `db:"shared_links" json:"shared_links"` // Distinct links shared in the window
+	HitLinks    int     `db:"hit_links" json:"hit_links"`       // Of those, links that trended in the network
+	Score       float64 `db:"score" json:"score"`
+}
+
+// GetFollowRecommendations suggests 2nd-degree accounts not yet followed whose
+// links in the last hoursBack hours reached minShares distinct sharers across
+// the network. Accounts need at least minHits such links and are ranked by
+// hits weighted by log(1 + source_count).
+func (db *DB) GetFollowRecommendations(hoursBack, minShares, minHits, limit int) ([]FollowRecommendation, error) {
+	query := `
+		WITH recent AS (
+			SELECT pl.link_id, p.author_did, COALESCE(pl.degree, p.author_degree) AS degree
+			FROM post_links pl
+			JOIN posts p ON pl.post_id = p.id
+			WHERE p.created_at > NOW() - INTERVAL '1 hour' * $1
+			  AND p.author_did IS NOT NULL
+		),
+		hits AS (
+			SELECT link_id
+			FROM recent
+			GROUP BY link_id
+			HAVING COUNT(DISTINCT author_did) >= $2
+		),
+		candidates AS (
+			SELECT
+				r.author_did AS did,
+				COUNT(DISTINCT r.link_id) AS shared_links,
+				COUNT(DISTINCT h.link_id) AS hit_links
+			FROM recent r
+			LEFT JOIN hits h ON h.link_id = r.link_id
+			WHERE r.degree = 2
+			GROUP BY r.author_did
+		)
+		SELECT
+			n.did,
+			n.handle,
+			n.display_name,
+			n.avatar_url,
+			n.source_count,
+			c.shared_links,
+			c.hit_links,
+			c.hit_links * LN(1 + n.source_count) AS score
+		FROM candidates c
+		JOIN network_accounts n ON n.did = c.did AND n.degree = 2
+		WHERE c.hit_links >= $3
+		  AND NOT EXISTS (SELECT 1 FROM follows f WHERE f.did = n.did)
+		ORDER BY score DESC, c.hit_links DESC, n.source_count DESC
+		LIMIT $4
+	`
+
+	var recommendations []FollowRecommendation
+	err := db.Select(&recommendations, query, hoursBack, minShares, minHits, limit)
+	return recommendations, err
+}
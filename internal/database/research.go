@@ -0,0 +1,67 @@
+package database
+
+import (
+	"fmt"
+	"time"
+)
+
+// ShareRecord is one share of a link with the fields kept by research
+// exports
+type ShareRecord struct {
+	AuthorDID       string    `db:"author_did"`
+	LinkID          int       `db:"link_id"`
+	NormalizedURL   string    `db:"normalized_url"`
+	LinkFirstSeenAt time.Time `db:"first_seen_at"`
+	Relation        string    `db:"relation_type"`
+	Degree          *int      `db:"degree"`
+	IsReply         bool      `db:"is_reply"`
+	SharedAt        time.Time `db:"created_at"`
+}
+
+// StreamShares calls fn for every share posted since the given time, oldest
+// first
+func (db *DB) StreamShares(since time.Time, fn func(ShareRecord) error) error {
+	rows, err := db.Queryx(`
+		SELECT
+			COALESCE(p.author_did, p.author_handle) as author_did,
+			l.id as link_id,
+			l.normalized_url,
+			l.first_seen_at,
+			pl.relation_type,
+			pl.degree,
+			p.is_reply,
+			p.created_at
+		FROM post_links pl
+		JOIN posts p ON p.id = pl.post_id
+		JOIN links l ON l.id = pl.link_id
+		WHERE p.created_at > $1
+		ORDER BY p.created_at
+	`, since)
+	if err != nil {
+		return fmt.Errorf("failed to query shares: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var share ShareRecord
+		if err := rows.StructScan(&share); err != nil {
+			return fmt.Errorf("failed to scan share: %w", err)
+		}
+		if err := fn(share); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// GetAllNetworkAccounts returns every 1st- and 2nd-degree account
+func (db *DB) GetAllNetworkAccounts() ([]NetworkAccount, error) {
+	query := `
+		SELECT did, handle, display_name, avatar_url, degree, source_count, source_dids, first_seen_at, last_updated_at
+		FROM network_accounts
+		ORDER BY did
+	`
+	var accounts []NetworkAccount
+	err := db.Select(&accounts, query)
+	return accounts, err
+}
@@ -0,0 +1,121 @@
+package database
+
+import (
+	"fmt"
+	"time"
+)
+
+// RollupDailyShares recomputes the daily_link_shares rows for day (a date at
+// midnight UTC) from posts created in [start, end). Counts only ever grow,
+// so a recount after cleanup doesn't erase a day. Returns the links rolled up.
+func (db *DB) RollupDailyShares(day, start, end time.Time) (int, error) {
+	query := `
+		INSERT INTO daily_link_shares (day, link_id, share_count, last_shared_at)
+		SELECT $1::date, pl.link_id, COUNT(DISTINCT COALESCE(p.author_did, p.author_handle)), MAX(p.created_at)
+		FROM post_links pl
+		JOIN posts p ON p.id = pl.post_id
+		WHERE p.created_at >= $2 AND p.created_at < $3
+		GROUP BY pl.link_id
+		ON CONFLICT (day, link_id) DO UPDATE SET
+			share_count = GREATEST(daily_link_shares.share_count, EXCLUDED.share_count),
+			last_shared_at = GREATEST(daily_link_shares.last_shared_at, EXCLUDED.last_shared_at)
+	`
+
+	result, err := db.Exec(query, day, start.UTC(), end.UTC())
+	if err != nil {
+		return 0, err
+	}
+	n, err := result.RowsAffected()
+	return int(n), err
+}
+
+// GetTrendingLinksForDays ranks links by their rollups for the days first
+// through last (dates at midnight UTC). ShareCount sums the daily sharer
+// counts, so an account sharing a link on two days counts twice. Rollups
+// don't keep sharers, so Sharers is empty. Links with a ShareCount below
+// minShares, and dead links when hideDead is set, are left out.
+func (db *DB) GetTrendingLinksForDays(first, last time.Time, limit, minShares int, hideDead bool) ([]TrendingLink, error) {
+	query := fmt.Sprintf(`
+		SELECT
+			l.id,
+			l.normalized_url,
+			l.original_url,
+			l.title,
+			l.description,
+			l.og_image_url,
+			l.sensitive,
+			l.click_count,
+			l.dead_at,
+			l.image_missing,
+			SUM(d.share_count) as share_count,
+			MAX(d.last_shared_at) as last_shared_at,
+			l.first_shared_at,
+			'{}'::text[] as sharers
+		FROM daily_link_shares d
+		JOIN links l ON l.id = d.link_id
+		WHERE d.day BETWEEN $1::date AND $2::date
+		  AND l.normalized_url !~* '\.(gif|jpe?g|png|webp)(\?.*)?$'
+		  AND %s
+		  AND (NOT $5 OR l.dead_at IS NULL)
+		GROUP BY l.id
+		HAVING SUM(d.share_count) >= $4
+		ORDER BY share_count DESC, last_shared_at DESC
+		LIMIT $3
+	`, buildDomainFilter())
+
+	var links []TrendingLink
+	err := db.Select(&links, query, first, last, limit, minShares, hideDead)
+	return links, err
+}
+
+// RetrospectiveLink is a link's showing over past days, from the rollups
+type RetrospectiveLink struct {
+	ID            int        `db:"id" json:"id"`
+	NormalizedURL string     `db:"normalized_url" json:"url"`
+	Title         *string    `db:"title" json:"title"`
+	Description   *string    `db:"description" json:"description"`
+	OGImageURL    *string    `db:"og_image_url" json:"image_url"`
+	Sensitive     bool       `db:"sensitive" json:"sensitive,omitempty"`
+	ImageMissing  bool       `db:"image_missing" json:"image_missing,omitempty"`
+	TotalShares   int        `db:"total_shares" json:"total_shares"` // Daily sharer counts summed
+	PeakShares    int        `db:"peak_shares" json:"peak_shares"`   // Most sharers in one day
+	PeakDay       time.Time  `db:"peak_day" json:"peak_day"`
+	FirstSharedBy *string    `db:"first_shared_by" json:"first_shared_by"` // DID of the earliest sharer
+	FirstSharer   *string    `db:"first_sharer_handle" json:"first_sharer_handle"`
+	FirstSharedAt *time.Time `db:"first_shared_at" json:"first_shared_at"`
+}
+
+// GetRetrospective ranks links by their best day among the days first
+// through last (dates at midnight UTC), then by their total over the days.
+// Rollups outlive posts, so this reaches back as far as links are kept.
+func (db *DB) GetRetrospective(first, last time.Time, limit int) ([]RetrospectiveLink, error) {
+	query := fmt.Sprintf(`
+		SELECT
+			l.id,
+			l.normalized_url,
+			l.title,
+			l.description,
+			l.og_image_url,
+			l.sensitive,
+			l.image_missing,
+			SUM(d.share_count) AS total_shares,
+			MAX(d.share_count) AS peak_shares,
+			(ARRAY_AGG(d.day ORDER BY d.share_count DESC, d.day))[1] AS peak_day,
+			l.first_shared_by,
+			n.handle AS first_sharer_handle,
+			l.first_shared_at
+		FROM daily_link_shares d
+		JOIN links l ON l.id = d.link_id
+		LEFT JOIN network_accounts n ON n.did = l.first_shared_by
+		WHERE d.day BETWEEN $1::date AND $2::date
+		  AND l.normalized_url !~* '\.(gif|jpe?g|png|webp)(\?.*)?$'
+		  AND %s
+		GROUP BY l.id, n.handle
+		ORDER BY peak_shares DESC, total_shares DESC, l.id
+		LIMIT $3
+	`, buildDomainFilter())
+
+	var links []RetrospectiveLink
+	err := db.Select(&links, query, first, last, limit)
+	return links, err
+}
@@ -0,0 +1,157 @@
+package database
+
+import (
+	"database/sql"
+	"time"
+)
+
+// ScrapeFailure is a link whose last scrape failed
+type ScrapeFailure struct {
+	LinkID   int       `db:"link_id" json:"link_id"`
+	URL      string    `db:"url" json:"url"`
+	Error    string    `db:"error" json:"error"`
+	FailedAt time.Time `db:"failed_at" json:"failed_at"`
+}
+
+// PausedDomain is a domain the scrapers skip
+type PausedDomain struct {
+	Domain   string    `db:"domain" json:"domain"`
+	Reason   *string   `db:"reason" json:"reason,omitempty"`
+	PausedAt time.Time `db:"paused_at" json:"paused_at"`
+}
+
+// RecordScrapeError stores why the latest scrape of a link failed,
+// replacing any earlier error
+func (db *DB) RecordScrapeError(linkID int, errMsg string) error {
+	query := `
+		INSERT INTO link_scrape_errors (link_id, error)
+		VALUES ($1, $2)
+		ON CONFLICT (link_id) DO UPDATE SET error = EXCLUDED.error, failed_at = CURRENT_TIMESTAMP
+	`
+	_, err := db.Exec(query, linkID, errMsg)
+	return err
+}
+
+// ClearScrapeError forgets a link's scrape error after a successful scrape
+func (db *DB) ClearScrapeError(linkID int) error {
+	_, err := db.Exec(`DELETE FROM link_scrape_errors WHERE link_id = $1`, linkID)
+	return err
+}
+
+// GetScrapeError returns a link's last scrape error, or nil if its last
+// scrape didn't fail
+func (db *DB) GetScrapeError(linkID int) (*ScrapeFailure, error) {
+	failure := &ScrapeFailure{}
+	err := db.Get(failure, `
+		SELECT e.link_id, l.normalized_url as url, e.error, e.failed_at
+		FROM link_scrape_errors e
+		JOIN links l ON l.id = e.link_id
+		WHERE e.link_id = $1
+	`, linkID)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return failure, err
+}
+
+// GetScrapeFailures returns links whose last scrape failed, most recent
+// first, optionally only those on domain or its subdomains
+func (db *DB) GetScrapeFailures(domain string, limit int) ([]ScrapeFailure, error) {
+	var failures []ScrapeFailure
+	err := db.Select(&failures, `
+		SELECT e.link_id, l.normalized_url as url, e.error, e.failed_at
+		FROM link_scrape_errors e
+		JOIN links l ON l.id = e.link_id
+		WHERE $1 = '' OR `+linkHostSQL+` = $1 OR `+linkHostSQL+` LIKE '%.' || $1
+		ORDER BY e.failed_at DESC
+		LIMIT $2
+	`, domain, limit)
+	return failures, err
+}
+
+// GetScrapeJobs returns up to limit backlog jobs in the order they will be
+// served, without taking them
+func (db *DB) GetScrapeJobs(limit int) ([]ScrapeJob, error) {
+	var jobs []ScrapeJob
+	err := db.Select(&jobs, `
+		SELECT link_id, url, priority, enqueued_at
+		FROM scrape_jobs
+		ORDER BY priority DESC, enqueued_at
+		LIMIT $1
+	`, limit)
+	return jobs, err
+}
+
+// GetScrapeJob returns a link's backlog job, or nil if it isn't parked there
+func (db *DB) GetScrapeJob(linkID int) (*ScrapeJob, error) {
+	job := &ScrapeJob{}
+	err := db.Get(job, `SELECT link_id, url, priority, enqueued_at FROM scrape_jobs WHERE link_id = $1`, linkID)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return job, err
+}
+
+// RetryScrape parks a high-priority job for a link in the backlog, where the
+// firehose's scrape queue picks it up on its next refill, and marks the
+// link unfetched so cmd/metadata-fetcher retries it too
+func (db *DB) RetryScrape(linkID int, url string, priority int) error {
+	tx, err := db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`
+		INSERT INTO scrape_jobs (link_id, url, priority)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (link_id) DO UPDATE SET priority = GREATEST(scrape_jobs.priority, EXCLUDED.priority)
+	`, linkID, url, priority); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`UPDATE links SET last_fetched_at = NULL WHERE id = $1`, linkID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// PauseScrapeDomain stops scraping of a domain and its subdomains. Pausing
+// a paused domain updates the reason.
+func (db *DB) PauseScrapeDomain(domain, reason string) error {
+	query := `
+		INSERT INTO scrape_paused_domains (domain, reason)
+		VALUES ($1, NULLIF($2, ''))
+		ON CONFLICT (domain) DO UPDATE SET reason = EXCLUDED.reason
+	`
+	_, err := db.Exec(query, domain, reason)
+	return err
+}
+
+// ResumeScrapeDomain lifts a pause. Returns false if the domain wasn't paused.
+func (db *DB) ResumeScrapeDomain(domain string) (bool, error) {
+	result, err := db.Exec(`DELETE FROM scrape_paused_domains WHERE domain = $1`, domain)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+// GetPausedScrapeDomains lists paused domains, most recently paused first
+func (db *DB) GetPausedScrapeDomains() ([]PausedDomain, error) {
+	var domains []PausedDomain
+	err := db.Select(&domains, `SELECT domain, reason, paused_at FROM scrape_paused_domains ORDER BY paused_at DESC`)
+	return domains, err
+}
+
+// IsScrapePaused reports whether host, or a domain it is under, is paused
+func (db *DB) IsScrapePaused(host string) (bool, error) {
+	var paused bool
+	err := db.Get(&paused, `
+		SELECT EXISTS (
+			SELECT 1 FROM scrape_paused_domains
+			WHERE domain = $1 OR $1 LIKE '%.' || domain
+		)
+	`, host)
+	return paused, err
+}
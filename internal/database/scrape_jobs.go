@@ -0,0 +1,101 @@
+package database
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// ScrapeJob is a metadata fetch parked in the scrape backlog
+type ScrapeJob struct {
+	LinkID     int       `db:"link_id"`
+	URL        string    `db:"url"`
+	Priority   int       `db:"priority"`
+	EnqueuedAt time.Time `db:"enqueued_at"`
+}
+
+// UpsertScrapeJobs parks jobs in the backlog. A job already parked has the
+// new priority added to its own, so repeated shares raise it.
+func (db *DB) UpsertScrapeJobs(jobs []ScrapeJob) error {
+	tx, err := db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, job := range jobs {
+		if _, err := tx.Exec(`
+			INSERT INTO scrape_jobs (link_id, url, priority)
+			VALUES ($1, $2, $3)
+			ON CONFLICT (link_id) DO UPDATE SET priority = scrape_jobs.priority + EXCLUDED.priority
+		`, job.LinkID, job.URL, job.Priority); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// TakeScrapeJobs removes and returns up to limit of the highest-priority
+// jobs in the backlog (oldest first among equals)
+func (db *DB) TakeScrapeJobs(limit int) ([]ScrapeJob, error) {
+	var jobs []ScrapeJob
+	err := db.Select(&jobs, `
+		DELETE FROM scrape_jobs
+		WHERE link_id IN (
+			SELECT link_id FROM scrape_jobs
+			ORDER BY priority DESC, enqueued_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING link_id, url, priority, enqueued_at
+	`, limit)
+	return jobs, err
+}
+
+// CountScrapeJobs returns the size of the scrape backlog
+func (db *DB) CountScrapeJobs() (int, error) {
+	var count int
+	err := db.Get(&count, `SELECT COUNT(*) FROM scrape_jobs`)
+	return count, err
+}
+
+// ShedScrapeJobs drops backlog jobs at or below maxPriority, marking their
+// links fetched so they aren't picked up by the metadata fetcher either.
+// Returns the number of jobs shed.
+func (db *DB) ShedScrapeJobs(maxPriority int) (int, error) {
+	result, err := db.Exec(`
+		WITH shed AS (
+			DELETE FROM scrape_jobs WHERE priority <= $1
+			RETURNING link_id
+		)
+		UPDATE links SET last_fetched_at = NOW()
+		WHERE id IN (SELECT link_id FROM shed)
+	`, maxPriority)
+	if err != nil {
+		return 0, err
+	}
+	n, err := result.RowsAffected()
+	return int(n), err
+}
+
+// CountLinkShares returns the number of posts sharing a link
+func (db *DB) CountLinkShares(linkID int) (int, error) {
+	var count int
+	err := db.Get(&count, `SELECT COUNT(*) FROM post_links WHERE link_id = $1`, linkID)
+	return count, err
+}
+
+// GetLinksNeedingRefresh returns those of the given links whose metadata
+// should be re-fetched: never fetched, missing a title or image and last
+// tried before retryMissingBefore, or last fetched before staleBefore
+func (db *DB) GetLinksNeedingRefresh(linkIDs []int, retryMissingBefore, staleBefore time.Time) ([]Link, error) {
+	var links []Link
+	err := db.Select(&links, `
+		SELECT * FROM links
+		WHERE id = ANY($1)
+		  AND (last_fetched_at IS NULL
+		       OR ((title IS NULL OR og_image_url IS NULL) AND last_fetched_at < $2)
+		       OR last_fetched_at < $3)
+	`, pq.Array(linkIDs), retryMissingBefore, staleBefore)
+	return links, err
+}
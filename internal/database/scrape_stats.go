@@ -0,0 +1,69 @@
+package database
+
+import (
+	"time"
+)
+
+// ScrapeStatsRetention is how long scrape attempts are kept for domain stats
+const ScrapeStatsRetention = 7 * 24 * time.Hour
+
+// DomainStats summarizes scrape attempts against one domain
+type DomainStats struct {
+	Domain          string    `db:"domain" json:"domain"`
+	Attempts        int       `db:"attempts" json:"attempts"`
+	Successes       int       `db:"successes" json:"successes"` // 2xx and 304 responses
+	SuccessRate     float64   `db:"success_rate" json:"success_rate"`
+	Refusals        int       `db:"refusals" json:"refusals"` // 401, 403 and 451: bot walls and age gates
+	ClientErrors    int       `db:"client_errors" json:"client_errors"`
+	ServerErrors    int       `db:"server_errors" json:"server_errors"`
+	Failures        int       `db:"failures" json:"failures"` // No response (timeouts, DNS, TLS)
+	MedianLatencyMS int       `db:"median_latency_ms" json:"median_latency_ms"`
+	LastAttemptAt   time.Time `db:"last_attempt_at" json:"last_attempt_at"`
+}
+
+// RecordScrapeAttempt stores the outcome of one scraper request (status 0 =
+// no response, with errMsg describing why)
+func (db *DB) RecordScrapeAttempt(domain string, status int, latency time.Duration, errMsg string) error {
+	query := `
+		INSERT INTO scrape_stats (domain, status, latency_ms, error)
+		VALUES ($1, $2, $3, NULLIF($4, ''))
+	`
+	_, err := db.Exec(query, domain, status, latency.Milliseconds(), errMsg)
+	return err
+}
+
+// GetDomainStats aggregates scrape attempts since the given time per domain,
+// for domains with at least minAttempts, most attempted first
+func (db *DB) GetDomainStats(since time.Time, minAttempts, limit int) ([]DomainStats, error) {
+	query := `
+		SELECT
+			domain,
+			COUNT(*) as attempts,
+			COUNT(*) FILTER (WHERE status BETWEEN 200 AND 299 OR status = 304) as successes,
+			(COUNT(*) FILTER (WHERE status BETWEEN 200 AND 299 OR status = 304))::float8 / COUNT(*) as success_rate,
+			COUNT(*) FILTER (WHERE status IN (401, 403, 451)) as refusals,
+			COUNT(*) FILTER (WHERE status BETWEEN 400 AND 499 AND status NOT IN (401, 403, 451)) as client_errors,
+			COUNT(*) FILTER (WHERE status >= 500) as server_errors,
+			COUNT(*) FILTER (WHERE status = 0) as failures,
+			ROUND(PERCENTILE_CONT(0.5) WITHIN GROUP (ORDER BY latency_ms))::int as median_latency_ms,
+			MAX(attempted_at) as last_attempt_at
+		FROM scrape_stats
+		WHERE attempted_at > $1
+		GROUP BY domain
+		HAVING COUNT(*) >= $2
+		ORDER BY attempts DESC, domain
+		LIMIT $3
+	`
+	var stats []DomainStats
+	err := db.Select(&stats, query, since, minAttempts, limit)
+	return stats, err
+}
+
+// DeleteOldScrapeStats removes scrape attempts made before cutoff
+func (db *DB) DeleteOldScrapeStats(cutoff time.Time) (int64, error) {
+	result, err := db.Exec(`DELETE FROM scrape_stats WHERE attempted_at < $1`, cutoff)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
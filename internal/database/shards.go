@@ -0,0 +1,87 @@
+package database
+
+import (
+	"database/sql"
+	"time"
+)
+
+// FirehoseShard is a leased shard of firehose processing
+type FirehoseShard struct {
+	ShardCount   int    `db:"shard_count"`
+	ShardID      int    `db:"shard_id"`
+	CursorTimeUS *int64 `db:"cursor_time_us"`
+}
+
+// EnsureFirehoseShards creates the shard rows for a layout of count shards
+func (db *DB) EnsureFirehoseShards(count int) error {
+	_, err := db.Exec(`
+		INSERT INTO firehose_shards (shard_count, shard_id)
+		SELECT $1, s FROM generate_series(0, $1 - 1) s
+		ON CONFLICT DO NOTHING
+	`, count)
+	return err
+}
+
+// ClaimFirehoseShard leases the lowest shard that is unowned, expired, or
+// already held by owner. Returns nil if every shard is leased.
+func (db *DB) ClaimFirehoseShard(count int, owner string, lease time.Duration) (*FirehoseShard, error) {
+	query := `
+		UPDATE firehose_shards
+		SET owner = $2, lease_expires_at = NOW() + $3 * INTERVAL '1 second', updated_at = NOW()
+		WHERE (shard_count, shard_id) = (
+			SELECT shard_count, shard_id
+			FROM firehose_shards
+			WHERE shard_count = $1
+			  AND (owner IS NULL OR owner = $2 OR lease_expires_at < NOW())
+			ORDER BY (owner = $2) DESC NULLS LAST, shard_id
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING shard_count, shard_id, cursor_time_us
+	`
+
+	shard := &FirehoseShard{}
+	err := db.Get(shard, query, count, owner, lease.Seconds())
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return shard, err
+}
+
+// RenewFirehoseShard extends owner's lease, reporting false if it was lost
+func (db *DB) RenewFirehoseShard(count, shardID int, owner string, lease time.Duration) (bool, error) {
+	result, err := db.Exec(`
+		UPDATE firehose_shards
+		SET lease_expires_at = NOW() + $4 * INTERVAL '1 second', updated_at = NOW()
+		WHERE shard_count = $1 AND shard_id = $2 AND owner = $3
+	`, count, shardID, owner, lease.Seconds())
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	return rows > 0, err
+}
+
+// UpdateFirehoseShardCursor saves a shard's cursor if owner still holds it
+func (db *DB) UpdateFirehoseShardCursor(count, shardID int, owner string, cursorTimeUS int64) (bool, error) {
+	result, err := db.Exec(`
+		UPDATE firehose_shards
+		SET cursor_time_us = $4, updated_at = NOW()
+		WHERE shard_count = $1 AND shard_id = $2 AND owner = $3
+	`, count, shardID, owner, cursorTimeUS)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	return rows > 0, err
+}
+
+// ReleaseFirehoseShard gives up owner's lease so another instance can claim it
+func (db *DB) ReleaseFirehoseShard(count, shardID int, owner string) error {
+	_, err := db.Exec(`
+		UPDATE firehose_shards
+		SET owner = NULL, lease_expires_at = NULL, updated_at = NOW()
+		WHERE shard_count = $1 AND shard_id = $2 AND owner = $3
+	`, count, shardID, owner)
+	return err
+}
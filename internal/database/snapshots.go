@@ -0,0 +1,142 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// SnapshotLink is a trending link as captured in a snapshot
+type SnapshotLink struct {
+	ID           int       `json:"id"`
+	URL          string    `json:"url"`
+	Title        string    `json:"title"`
+	Description  string    `json:"description"`
+	ImageURL     string    `json:"image_url"`
+	ShareCount   int       `json:"share_count"`
+	LastSharedAt time.Time `json:"last_shared_at"`
+	Sharers      []string  `json:"sharers"`
+	Sensitive    bool      `json:"sensitive,omitempty"`
+}
+
+// SnapshotLinks converts trending links to the form stored in snapshots
+func SnapshotLinks(trending []TrendingLink) []SnapshotLink {
+	links := make([]SnapshotLink, len(trending))
+	for i, link := range trending {
+		links[i] = SnapshotLink{
+			ID:           link.ID,
+			URL:          link.NormalizedURL,
+			Title:        stringValue(link.Title),
+			Description:  stringValue(link.Description),
+			ImageURL:     stringValue(link.OGImageURL),
+			ShareCount:   link.ShareCount,
+			LastSharedAt: link.LastSharedAt,
+			Sharers:      []string(link.Sharers),
+			Sensitive:    link.Sensitive,
+		}
+	}
+	return links
+}
+
+// TrendingSnapshot is a stored copy of the trending list at a point in time
+type TrendingSnapshot struct {
+	ID      int            `db:"id" json:"id"`
+	TakenAt time.Time      `db:"taken_at" json:"taken_at"`
+	Hours   int            `db:"hours" json:"hours"`
+	Lang    string         `db:"lang" json:"lang,omitempty"` // Language counted ("" = all posts)
+	Links   []SnapshotLink `db:"-" json:"links"`
+}
+
+// snapshotRow is a trending_snapshots row with undecoded links
+type snapshotRow struct {
+	ID      int       `db:"id"`
+	TakenAt time.Time `db:"taken_at"`
+	Hours   int       `db:"hours"`
+	Lang    string    `db:"lang"`
+	Links   []byte    `db:"links"`
+}
+
+// InsertTrendingSnapshot stores a snapshot of trending links counting posts
+// in lang ("" = all posts) and returns its ID
+func (db *DB) InsertTrendingSnapshot(takenAt time.Time, hours int, lang string, links []SnapshotLink) (int, error) {
+	if links == nil {
+		links = []SnapshotLink{}
+	}
+	data, err := json.Marshal(links)
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode snapshot: %w", err)
+	}
+
+	var id int
+	err = db.Get(&id, `
+		INSERT INTO trending_snapshots (taken_at, hours, lang, links)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id
+	`, takenAt, hours, lang, data)
+	return id, err
+}
+
+// GetTrendingSnapshotAsOf returns the latest snapshot for lang ("" = all
+// posts) taken at or before t, or nil if there is none
+func (db *DB) GetTrendingSnapshotAsOf(t time.Time, lang string) (*TrendingSnapshot, error) {
+	return db.getTrendingSnapshot(`
+		SELECT id, taken_at, hours, lang, links
+		FROM trending_snapshots
+		WHERE taken_at <= $1 AND lang = $2
+		ORDER BY taken_at DESC
+		LIMIT 1
+	`, t, lang)
+}
+
+// GetTrendingSnapshotByID returns a snapshot, or nil if it doesn't exist
+func (db *DB) GetTrendingSnapshotByID(id int) (*TrendingSnapshot, error) {
+	return db.getTrendingSnapshot(`SELECT id, taken_at, hours, lang, links FROM trending_snapshots WHERE id = $1`, id)
+}
+
+func (db *DB) getTrendingSnapshot(query string, args ...interface{}) (*TrendingSnapshot, error) {
+	var row snapshotRow
+	if err := db.Get(&row, query, args...); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	snapshot := &TrendingSnapshot{ID: row.ID, TakenAt: row.TakenAt, Hours: row.Hours, Lang: row.Lang}
+	if err := json.Unmarshal(row.Links, &snapshot.Links); err != nil {
+		return nil, fmt.Errorf("failed to decode snapshot %d: %w", row.ID, err)
+	}
+	return snapshot, nil
+}
+
+// RankedSnapshotLink is a snapshot link with its place in the ranking
+type RankedSnapshotLink struct {
+	SnapshotLink
+	Rank         int `json:"rank"`                    // 1-based position in the later snapshot
+	PreviousRank int `json:"previous_rank,omitempty"` // Position in the earlier one (0 = not listed)
+}
+
+// CompareSnapshots returns the links of after that aren't in before
+// (appeared) and those ranked higher than in before (movedUp), both in
+// after's order. A nil before counts every link as new.
+func CompareSnapshots(before, after *TrendingSnapshot) (appeared, movedUp []RankedSnapshotLink) {
+	previous := make(map[int]int)
+	if before != nil {
+		for i, link := range before.Links {
+			previous[link.ID] = i + 1
+		}
+	}
+
+	appeared, movedUp = []RankedSnapshotLink{}, []RankedSnapshotLink{}
+	for i, link := range after.Links {
+		ranked := RankedSnapshotLink{SnapshotLink: link, Rank: i + 1, PreviousRank: previous[link.ID]}
+		switch {
+		case ranked.PreviousRank == 0:
+			appeared = append(appeared, ranked)
+		case ranked.Rank < ranked.PreviousRank:
+			movedUp = append(movedUp, ranked)
+		}
+	}
+	return appeared, movedUp
+}
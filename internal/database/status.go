@@ -0,0 +1,95 @@
+package database
+
+import (
+	"time"
+)
+
+// IngestStatus summarizes how far ingestion has got, for deployments that
+// are still filling a fresh database
+type IngestStatus struct {
+	Follows         int        `db:"follows" json:"follows"`
+	NetworkAccounts int        `db:"network_accounts" json:"network_accounts"`
+	Posts           int        `db:"posts" json:"posts"`
+	Links           int        `db:"links" json:"links"`
+	FirstPostAt     *time.Time `db:"first_post_at" json:"first_post_at,omitempty"`
+	LastPostAt      *time.Time `db:"last_post_at" json:"last_post_at,omitempty"`
+
+	Sources []SourceCount `db:"-" json:"sources"`
+}
+
+// SourceCount is how many stored posts an ingest source delivered first,
+// and how many more it delivered after another source stored them
+type SourceCount struct {
+	Source     string `db:"source" json:"source"` // "unknown" for untagged posts
+	Posts      int    `db:"posts" json:"posts"`
+	Duplicates int    `db:"duplicates" json:"duplicates"`
+}
+
+// GetIngestStatus counts what has been ingested so far
+func (db *DB) GetIngestStatus() (*IngestStatus, error) {
+	query := `
+		SELECT
+			(SELECT COUNT(*) FROM follows) as follows,
+			(SELECT COUNT(*) FROM network_accounts) as network_accounts,
+			(SELECT COUNT(*) FROM posts) as posts,
+			(SELECT COUNT(*) FROM links) as links,
+			(SELECT MIN(created_at) FROM posts) as first_post_at,
+			(SELECT MAX(created_at) FROM posts) as last_post_at
+	`
+	var status IngestStatus
+	if err := db.Get(&status, query); err != nil {
+		return nil, err
+	}
+
+	sources, err := db.GetIngestSourceCounts()
+	if err != nil {
+		return nil, err
+	}
+	status.Sources = sources
+	return &status, nil
+}
+
+// GetIngestSourceCounts breaks stored posts down by ingest source
+func (db *DB) GetIngestSourceCounts() ([]SourceCount, error) {
+	query := `
+		SELECT
+			source,
+			COUNT(*) FILTER (WHERE stored) as posts,
+			COUNT(*) FILTER (WHERE NOT stored) as duplicates
+		FROM (
+			SELECT COALESCE(source, 'unknown') as source, TRUE as stored FROM posts
+			UNION ALL
+			SELECT UNNEST(duplicate_sources), FALSE FROM posts WHERE duplicate_sources <> '{}'
+		) s
+		GROUP BY source
+		ORDER BY posts DESC, source
+	`
+	sources := []SourceCount{}
+	err := db.Select(&sources, query)
+	return sources, err
+}
+
+// Freshness reports when each kind of derived data was last updated
+type Freshness struct {
+	LastPostAt     *time.Time `db:"last_post_at" json:"last_post_at"`
+	LastSnapshotAt *time.Time `db:"last_snapshot_at" json:"last_snapshot_at"`
+	LastRollupDay  *string    `db:"last_rollup_day" json:"last_rollup_day"` // YYYY-MM-DD in the configured timezone
+	LastScrapeAt   *time.Time `db:"last_scrape_at" json:"last_scrape_at"`
+}
+
+// GetFreshness returns the newest post, trending snapshot, rollup day and
+// metadata scrape, each nil when there are none yet
+func (db *DB) GetFreshness() (*Freshness, error) {
+	query := `
+		SELECT
+			(SELECT MAX(created_at) FROM posts) as last_post_at,
+			(SELECT MAX(taken_at) FROM trending_snapshots) as last_snapshot_at,
+			(SELECT TO_CHAR(MAX(day), 'YYYY-MM-DD') FROM daily_link_shares) as last_rollup_day,
+			(SELECT MAX(attempted_at) FROM scrape_stats) as last_scrape_at
+	`
+	var freshness Freshness
+	if err := db.Get(&freshness, query); err != nil {
+		return nil, err
+	}
+	return &freshness, nil
+}
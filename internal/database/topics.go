@@ -0,0 +1,181 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Topic is a time-boxed trending view: links shared in posts matching its
+// keywords between StartsAt and EndsAt, with shares by its cohort boosted
+type Topic struct {
+	ID          int            `db:"id" json:"id"`
+	Slug        string         `db:"slug" json:"slug"`
+	Title       string         `db:"title" json:"title"`
+	Description *string        `db:"description" json:"description"`
+	Keywords    pq.StringArray `db:"keywords" json:"keywords"`
+	CohortID    *int           `db:"cohort_id" json:"-"`
+	Cohort      *string        `db:"cohort" json:"cohort"`             // Boosted cohort's name (nil = no boost)
+	BoostWeight float64        `db:"boost_weight" json:"boost_weight"` // Weight of a share by a cohort member
+	StartsAt    time.Time      `db:"starts_at" json:"starts_at"`
+	EndsAt      time.Time      `db:"ends_at" json:"ends_at"`
+	ArchivedAt  *time.Time     `db:"archived_at" json:"archived_at"` // When the final list was stored (nil = not yet)
+	CreatedAt   time.Time      `db:"created_at" json:"created_at"`
+	UpdatedAt   time.Time      `db:"updated_at" json:"updated_at"`
+}
+
+// Topic states, by time and archival
+const (
+	TopicScheduled = "scheduled" // Not started yet
+	TopicLive      = "live"      // Running
+	TopicEnded     = "ended"     // Ended, final list not archived yet
+	TopicArchived  = "archived"  // Ended, serving the archived list
+)
+
+// Status returns the topic's state as of now
+func (t *Topic) Status(now time.Time) string {
+	switch {
+	case t.ArchivedAt != nil:
+		return TopicArchived
+	case now.Before(t.StartsAt):
+		return TopicScheduled
+	case now.Before(t.EndsAt):
+		return TopicLive
+	default:
+		return TopicEnded
+	}
+}
+
+// TrendingOptions narrows opts to the topic: posts matching its keywords
+// made while it runs, with its cohort's shares boosted. It also returns the
+// window, in hours back from now, that reaches the topic's start.
+func (t *Topic) TrendingOptions(opts TrendingOptions, now time.Time) (int, TrendingOptions) {
+	opts.Keywords = t.Keywords
+	opts.Since = t.StartsAt
+	opts.Until = t.EndsAt
+	if t.CohortID != nil {
+		opts.BoostCohortID = *t.CohortID
+		opts.BoostWeight = t.BoostWeight
+	}
+	hours := int(math.Ceil(now.Sub(t.StartsAt).Hours()))
+	return max(hours, 1), opts
+}
+
+const topicColumns = `
+		t.id, t.slug, t.title, t.description, t.keywords, t.cohort_id,
+		c.name AS cohort, t.boost_weight, t.starts_at, t.ends_at,
+		t.archived_at, t.created_at, t.updated_at
+	`
+
+// GetTopics returns all topics, latest start first
+func (db *DB) GetTopics() ([]Topic, error) {
+	var topics []Topic
+	err := db.Select(&topics, `
+		SELECT `+topicColumns+`
+		FROM topics t
+		LEFT JOIN cohorts c ON c.id = t.cohort_id
+		ORDER BY t.starts_at DESC, t.slug
+	`)
+	return topics, err
+}
+
+// GetTopicBySlug returns a topic, or nil if it doesn't exist
+func (db *DB) GetTopicBySlug(slug string) (*Topic, error) {
+	topic := &Topic{}
+	err := db.Get(topic, `
+		SELECT `+topicColumns+`
+		FROM topics t
+		LEFT JOIN cohorts c ON c.id = t.cohort_id
+		WHERE t.slug = $1
+	`, slug)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return topic, err
+}
+
+// SaveTopic creates or replaces the topic with topic.Slug. Moving the end
+// of an archived topic into the future reopens it; otherwise its archive is
+// kept, as the posts it was computed from may be gone.
+func (db *DB) SaveTopic(topic Topic) error {
+	if topic.Keywords == nil {
+		topic.Keywords = pq.StringArray{}
+	}
+	_, err := db.Exec(`
+		INSERT INTO topics (slug, title, description, keywords, cohort_id, boost_weight, starts_at, ends_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (slug)
+		DO UPDATE SET
+			title = $2,
+			description = $3,
+			keywords = $4,
+			cohort_id = $5,
+			boost_weight = $6,
+			starts_at = $7,
+			ends_at = $8,
+			archived_at = CASE WHEN $8 > NOW() THEN NULL ELSE topics.archived_at END,
+			archived_links = CASE WHEN $8 > NOW() THEN NULL ELSE topics.archived_links END,
+			updated_at = NOW()
+	`, topic.Slug, topic.Title, topic.Description, topic.Keywords, topic.CohortID, topic.BoostWeight,
+		topic.StartsAt.UTC(), topic.EndsAt.UTC())
+	return err
+}
+
+// DeleteTopic removes a topic and its archive, reporting whether it existed
+func (db *DB) DeleteTopic(slug string) (bool, error) {
+	result, err := db.Exec(`DELETE FROM topics WHERE slug = $1`, slug)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	return rows > 0, err
+}
+
+// GetTopicsToArchive returns topics that have ended but aren't archived yet
+func (db *DB) GetTopicsToArchive() ([]Topic, error) {
+	var topics []Topic
+	err := db.Select(&topics, `
+		SELECT `+topicColumns+`
+		FROM topics t
+		LEFT JOIN cohorts c ON c.id = t.cohort_id
+		WHERE t.archived_at IS NULL AND t.ends_at <= NOW()
+		ORDER BY t.ends_at
+	`)
+	return topics, err
+}
+
+// ArchiveTopic stores a topic's final trending list
+func (db *DB) ArchiveTopic(id int, links []SnapshotLink) error {
+	if links == nil {
+		links = []SnapshotLink{}
+	}
+	data, err := json.Marshal(links)
+	if err != nil {
+		return fmt.Errorf("failed to encode topic archive: %w", err)
+	}
+	_, err = db.Exec(`UPDATE topics SET archived_at = NOW(), archived_links = $2 WHERE id = $1`, id, data)
+	return err
+}
+
+// GetArchivedTopicLinks returns a topic's archived trending list (nil if it
+// isn't archived)
+func (db *DB) GetArchivedTopicLinks(id int) ([]SnapshotLink, error) {
+	var data []byte
+	err := db.Get(&data, `SELECT archived_links FROM topics WHERE id = $1`, id)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil || data == nil {
+		return nil, err
+	}
+
+	var links []SnapshotLink
+	if err := json.Unmarshal(data, &links); err != nil {
+		return nil, fmt.Errorf("failed to decode archive of topic %d: %w", id, err)
+	}
+	return links, nil
+}
@@ -0,0 +1,48 @@
+package database
+
+import (
+	"testing"
+	"time"
+)
+
+// BenchmarkBuildTrendingQuery measures building the trending SQL, with no
+// options and with every filter and weight on
+func BenchmarkBuildTrendingQuery(b *testing.B) {
+	cases := []struct {
+		name string
+		opts TrendingOptions
+	}{
+		{"defaults", TrendingOptions{}},
+		{"all_options", TrendingOptions{
+			ReplyMode:       ReplyModeDownweight,
+			ReplyWeight:     0.5,
+			LabelMode:       LabelModeExclude,
+			FlaggedLabels:   []string{"spam"},
+			LabelThreshold:  0.5,
+			CohortID:        1,
+			CollapseCopies:  true,
+			SelfPromoMode:   SelfPromoModeDownweight,
+			SelfPromoWeight: 0.25,
+			PersonalDomains: []string{"example.com"},
+			HideDead:        true,
+			Langs:           []string{"en", "de"},
+			SharerTypes:     []string{AccountJournalist},
+			MinShares:       2,
+			PinnedWeight:    2,
+			Keywords:        []string{"election"},
+			Since:           time.Now().Add(-time.Hour),
+			BoostCohortID:   2,
+			BoostWeight:     1.5,
+			After:           &TrendingCursor{Score: 3, ShareCount: 3, LastSharedAt: time.Now(), ID: 100},
+		}},
+	}
+
+	for _, c := range cases {
+		b.Run(c.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				buildTrendingQuery(24, 50, 1, c.opts)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package database
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// TrendingCursor is a position in the trending ranking: the sort key of the
+// last link of a page. Ranking is by score, share count, last share and
+// then link ID, all descending, so the key is unique.
+type TrendingCursor struct {
+	Score        float64   `json:"s"`
+	ShareCount   int       `json:"n"`
+	LastSharedAt time.Time `json:"t"`
+	ID           int       `json:"id"`
+}
+
+// CursorFor returns the cursor positioned at link
+func CursorFor(link TrendingLink) TrendingCursor {
+	return TrendingCursor{
+		Score:        link.Score,
+		ShareCount:   link.ShareCount,
+		LastSharedAt: link.LastSharedAt,
+		ID:           link.ID,
+	}
+}
+
+// Before reports whether c ranks ahead of other
+func (c TrendingCursor) Before(other TrendingCursor) bool {
+	if c.Score != other.Score {
+		return c.Score > other.Score
+	}
+	if c.ShareCount != other.ShareCount {
+		return c.ShareCount > other.ShareCount
+	}
+	if !c.LastSharedAt.Equal(other.LastSharedAt) {
+		return c.LastSharedAt.After(other.LastSharedAt)
+	}
+	return c.ID > other.ID
+}
+
+// Encode returns the cursor as an opaque URL-safe string
+func (c TrendingCursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// ParseTrendingCursor decodes a cursor made by Encode
+func ParseTrendingCursor(s string) (*TrendingCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor: %w", err)
+	}
+	var c TrendingCursor
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("invalid cursor: %w", err)
+	}
+	if c.ID <= 0 {
+		return nil, errors.New("invalid cursor: no link")
+	}
+	return &c, nil
+}
+
+// buildCursorCondition returns a HAVING condition keeping links ranked
+// after opts.After, appending its sort key to args
+func buildCursorCondition(opts TrendingOptions, score string, args *[]interface{}) string {
+	if opts.After == nil {
+		return ""
+	}
+	c := opts.After
+	*args = append(*args, c.Score, c.ShareCount, c.LastSharedAt, c.ID)
+	n := len(*args)
+	return fmt.Sprintf("(%s::float8, COUNT(DISTINCT p.author_did), MAX(p.created_at), l.id) < ($%d::float8, $%d::bigint, $%d::timestamp, $%d::int)",
+		score, n-3, n-2, n-1, n)
+}
@@ -0,0 +1,50 @@
+package database_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/database"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/testutil"
+)
+
+// Seed size for the trending benchmark: benchShares shares of benchLinks
+// links by benchAuthors accounts over the last day. cmd/loadtest seeds
+// production-sized data for measuring the query at scale.
+const (
+	benchShares  = 5000
+	benchLinks   = 500
+	benchAuthors = 200
+)
+
+// BenchmarkGetTrendingLinksByDegree runs the trending query for each
+// degree mode against seeded shares
+func BenchmarkGetTrendingLinksByDegree(b *testing.B) {
+	if testing.Short() {
+		b.Skip("needs Postgres")
+	}
+	db := testutil.NewTestDB(b)
+
+	now := time.Now()
+	for i := 0; i < benchShares; i++ {
+		author := i % benchAuthors
+		testutil.AddShare(b, db,
+			fmt.Sprintf("did:plc:bench%d", author), 1+author%2,
+			fmt.Sprintf("https://news%d.example/story/%d", i%20, (i*7)%benchLinks),
+			now.Add(-time.Duration(i%1440)*time.Minute))
+	}
+	if _, err := db.Exec("ANALYZE"); err != nil {
+		b.Fatal(err)
+	}
+
+	for degree := 0; degree <= 2; degree++ {
+		b.Run(fmt.Sprintf("degree=%d", degree), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := db.GetTrendingLinksByDegree(24, 50, degree, database.TrendingOptions{}); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
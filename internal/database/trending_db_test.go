@@ -0,0 +1,63 @@
+package database_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/database"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/testutil"
+)
+
+// TestGetTrendingLinksByDegree runs the trending query in each degree mode
+// against seeded shares
+func TestGetTrendingLinksByDegree(t *testing.T) {
+	if testing.Short() {
+		t.Skip("needs Postgres")
+	}
+	db := testutil.NewTestDB(t)
+
+	now := time.Now()
+	at := func(minutes int) time.Time { return now.Add(-time.Duration(minutes) * time.Minute) }
+
+	// first: two 1st-degree sharers
+	first := testutil.AddShare(t, db, "did:plc:a", 1, "https://example.com/first", at(10))
+	testutil.AddShare(t, db, "did:plc:b", 1, "https://example.com/first", at(20))
+	// second: three 2nd-degree sharers
+	second := testutil.AddShare(t, db, "did:plc:c", 2, "https://example.com/second", at(30))
+	testutil.AddShare(t, db, "did:plc:d", 2, "https://example.com/second", at(40))
+	testutil.AddShare(t, db, "did:plc:e", 2, "https://example.com/second", at(50))
+	// mixed: one sharer at each degree, plus the 1st-degree one again
+	mixed := testutil.AddShare(t, db, "did:plc:a", 1, "https://example.com/mixed", at(5))
+	testutil.AddShare(t, db, "did:plc:a", 1, "https://example.com/mixed", at(6))
+	testutil.AddShare(t, db, "did:plc:c", 2, "https://example.com/mixed", at(7))
+	// Outside the window
+	testutil.AddShare(t, db, "did:plc:f", 1, "https://example.com/old", now.Add(-3*time.Hour))
+
+	type result struct {
+		ID     int
+		Shares int
+	}
+	tests := []struct {
+		degree int
+		want   []result
+	}{
+		{0, []result{{second, 3}, {mixed, 2}, {first, 2}}},
+		{1, []result{{first, 2}, {mixed, 1}}},
+		{2, []result{{second, 3}, {mixed, 1}}},
+	}
+
+	for _, tt := range tests {
+		links, err := db.GetTrendingLinksByDegree(1, 10, tt.degree, database.TrendingOptions{})
+		if err != nil {
+			t.Fatalf("degree %d: %v", tt.degree, err)
+		}
+		got := make([]result, len(links))
+		for i, link := range links {
+			got[i] = result{link.ID, link.ShareCount}
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("degree %d: got %+v, want %+v", tt.degree, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package database
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+var placeholder = regexp.MustCompile(`\$(\d+)`)
+
+// maxPlaceholder returns the highest $n bind parameter in query
+func maxPlaceholder(query string) int {
+	max := 0
+	for _, m := range placeholder.FindAllStringSubmatch(query, -1) {
+		if n, _ := strconv.Atoi(m[1]); n > max {
+			max = n
+		}
+	}
+	return max
+}
+
+// TestBuildTrendingQueryDegree checks each degree mode filters post_links
+// by degree only when asked, and binds every parameter it uses
+func TestBuildTrendingQueryDegree(t *testing.T) {
+	for _, opts := range []TrendingOptions{{}, {ReplyMode: ReplyModeDownweight, ReplyWeight: 0.5, CohortID: 3, Langs: []string{"en"}}} {
+		for degree := 0; degree <= 2; degree++ {
+			query, args := buildTrendingQuery(24, 50, degree, opts)
+
+			if n := maxPlaceholder(query); n != len(args) {
+				t.Errorf("degree %d: query uses $%d but binds %d args", degree, n, len(args))
+			}
+			if args[0] != 24 || args[1] != 50 {
+				t.Errorf("degree %d: args start %v, want hours then limit", degree, args[:2])
+			}
+
+			filter := regexp.MustCompile(`pl\.degree = \$(\d+)`).FindStringSubmatch(query)
+			switch {
+			case degree == 0 && filter != nil:
+				t.Errorf("degree 0 filters on %s, want all degrees", filter[0])
+			case degree != 0 && filter == nil:
+				t.Errorf("degree %d: no pl.degree filter", degree)
+			case degree != 0:
+				if n, _ := strconv.Atoi(filter[1]); args[n-1] != degree {
+					t.Errorf("degree %d: %s binds %v", degree, filter[0], args[n-1])
+				}
+			}
+			if strings.Contains(query, "%!") {
+				t.Errorf("degree %d: bad format verb in query", degree)
+			}
+		}
+	}
+}
+
+func TestGetTrendingLinksByDegreeInvalid(t *testing.T) {
+	var db *DB // The degree is checked before querying
+	for _, degree := range []int{-1, 3} {
+		if _, err := db.GetTrendingLinksByDegree(24, 50, degree, TrendingOptions{}); err == nil {
+			t.Errorf("degree %d succeeded, want an error", degree)
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package database
+
+import (
+	"database/sql"
+	"time"
+)
+
+// UserProfile is what the aggregator knows about an account
+type UserProfile struct {
+	DID         string  `db:"did" json:"did"`
+	Handle      string  `db:"handle" json:"handle"`
+	DisplayName *string `db:"display_name" json:"display_name"`
+	AvatarURL   *string `db:"avatar_url" json:"avatar_url"`
+	Degree      *int    `db:"degree" json:"degree"` // nil = outside the network
+	Followed    bool    `db:"followed" json:"followed"`
+}
+
+// UserShare is a link an account shared
+type UserShare struct {
+	LinkID     int       `db:"link_id" json:"link_id"`
+	URL        string    `db:"normalized_url" json:"url"`
+	Title      *string   `db:"title" json:"title"`
+	OGImageURL *string   `db:"og_image_url" json:"image_url"`
+	Sensitive  bool      `db:"sensitive" json:"sensitive,omitempty"`
+	PostID     string    `db:"post_id" json:"post_id"`
+	SharedAt   time.Time `db:"shared_at" json:"shared_at"`
+	ShareCount int       `db:"share_count" json:"share_count"` // Distinct sharers still in the posts window
+}
+
+// DiscoveryStats summarizes an account's discoveries: links it was first in
+// the network to share
+type DiscoveryStats struct {
+	Discoveries int     `db:"discoveries" json:"discoveries"`
+	Hits        int     `db:"hits" json:"hits"` // Discoveries that reached the share threshold
+	HitRate     float64 `db:"hit_rate" json:"hit_rate"`
+}
+
+// DomainCount is how many links an account shared from a domain
+type DomainCount struct {
+	Domain string `db:"domain" json:"domain"`
+	Shares int    `db:"shares" json:"shares"`
+}
+
+// GetUserProfile returns an account's handle and network position, from
+// network_accounts and follows, falling back to its latest post. Returns
+// nil if the account isn't known.
+func (db *DB) GetUserProfile(did string) (*UserProfile, error) {
+	query := `
+		SELECT
+			$1 AS did,
+			COALESCE(n.handle, f.handle, p.author_handle, $1) AS handle,
+			COALESCE(n.display_name, f.display_name) AS display_name,
+			n.avatar_url,
+			n.degree,
+			f.did IS NOT NULL AS followed
+		FROM (SELECT 1) one
+		LEFT JOIN network_accounts n ON n.did = $1
+		LEFT JOIN follows f ON f.did = $1
+		LEFT JOIN LATERAL (
+			SELECT author_handle FROM posts
+			WHERE author_did = $1
+			ORDER BY created_at DESC
+			LIMIT 1
+		) p ON true
+		WHERE n.did IS NOT NULL OR f.did IS NOT NULL OR p.author_handle IS NOT NULL
+	`
+
+	var profile UserProfile
+	err := db.Get(&profile, query, did)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &profile, nil
+}
+
+// GetUserShares returns the links an account shared, most recent share
+// first, one row per link
+func (db *DB) GetUserShares(did string, limit int) ([]UserShare, error) {
+	query := `
+		SELECT
+			s.link_id,
+			l.normalized_url,
+			l.title,
+			l.og_image_url,
+			l.sensitive,
+			s.post_id,
+			s.shared_at,
+			(
+				SELECT COUNT(DISTINCT p2.author_did)
+				FROM post_links pl2
+				JOIN posts p2 ON pl2.post_id = p2.id
+				WHERE pl2.link_id = s.link_id
+			) AS share_count
+		FROM (
+			SELECT DISTINCT ON (pl.link_id) pl.link_id, p.id AS post_id, p.created_at AS shared_at
+			FROM posts p
+			JOIN post_links pl ON pl.post_id = p.id
+			WHERE p.author_did = $1
+			ORDER BY pl.link_id, p.created_at DESC
+		) s
+		JOIN links l ON l.id = s.link_id
+		ORDER BY s.shared_at DESC
+		LIMIT $2
+	`
+
+	var shares []UserShare
+	err := db.Select(&shares, query, did, limit)
+	return shares, err
+}
+
+// GetFollowShares returns the links a followed account shared in the last
+// hoursBack hours, most recent share first, one row per link. Accounts that
+// aren't followed have none.
+func (db *DB) GetFollowShares(did string, hoursBack, limit int) ([]UserShare, error) {
+	query := `
+		SELECT
+			s.link_id,
+			l.normalized_url,
+			l.title,
+			l.og_image_url,
+			l.sensitive,
+			s.post_id,
+			s.shared_at,
+			(
+				SELECT COUNT(DISTINCT p2.author_did)
+				FROM post_links pl2
+				JOIN posts p2 ON pl2.post_id = p2.id
+				WHERE pl2.link_id = s.link_id
+			) AS share_count
+		FROM (
+			SELECT DISTINCT ON (pl.link_id) pl.link_id, p.id AS post_id, p.created_at AS shared_at
+			FROM follows f
+			JOIN posts p ON p.author_did = f.did
+			JOIN post_links pl ON pl.post_id = p.id
+			WHERE f.did = $1
+			  AND p.created_at > NOW() - INTERVAL '1 hour' * $2
+			ORDER BY pl.link_id, p.created_at DESC
+		) s
+		JOIN links l ON l.id = s.link_id
+		ORDER BY s.shared_at DESC
+		LIMIT $3
+	`
+
+	var shares []UserShare
+	err := db.Select(&shares, query, did, hoursBack, limit)
+	return shares, err
+}
+
+// GetDiscoveryStats counts an account's discoveries and the ones that went
+// on to reach minShares distinct sharers
+func (db *DB) GetDiscoveryStats(did string, minShares int) (*DiscoveryStats, error) {
+	query := `
+		WITH discovered AS (
+			SELECT (
+				SELECT COUNT(DISTINCT p.author_did)
+				FROM post_links pl
+				JOIN posts p ON pl.post_id = p.id
+				WHERE pl.link_id = l.id
+			) AS share_count
+			FROM links l
+			WHERE l.first_shared_by = $1
+		)
+		SELECT
+			COUNT(*) AS discoveries,
+			COUNT(*) FILTER (WHERE share_count >= $2) AS hits,
+			COALESCE(COUNT(*) FILTER (WHERE share_count >= $2)::float / NULLIF(COUNT(*), 0), 0) AS hit_rate
+		FROM discovered
+	`
+
+	var stats DiscoveryStats
+	if err := db.Get(&stats, query, did, minShares); err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
+
+// GetUserTopDomains returns the domains an account shared the most distinct
+// links from, still in the posts window
+func (db *DB) GetUserTopDomains(did string, limit int) ([]DomainCount, error) {
+	query := `
+		WITH shared AS (
+			SELECT DISTINCT pl.link_id, REGEXP_REPLACE(
+				SUBSTRING(l.normalized_url FROM '^[a-z]+://([^/:?#]+)'),
+				'^www\.', ''
+			) AS domain
+			FROM posts p
+			JOIN post_links pl ON pl.post_id = p.id
+			JOIN links l ON l.id = pl.link_id
+			WHERE p.author_did = $1
+		)
+		SELECT domain, COUNT(*) AS shares
+		FROM shared
+		WHERE domain IS NOT NULL
+		GROUP BY domain
+		ORDER BY shares DESC, domain
+		LIMIT $2
+	`
+
+	var domains []DomainCount
+	err := db.Select(&domains, query, did, limit)
+	return domains, err
+}
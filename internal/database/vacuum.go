@@ -0,0 +1,34 @@
+package database
+
+import (
+	"github.com/lib/pq"
+)
+
+// TableSize is the on-disk size of a table and its indexes
+type TableSize struct {
+	Table      string `db:"table_name"`
+	TableBytes int64  `db:"table_bytes"` // Heap and TOAST
+	IndexBytes int64  `db:"index_bytes"`
+}
+
+// GetTableSizes returns the sizes of tables, in the order given
+func (db *DB) GetTableSizes(tables []string) ([]TableSize, error) {
+	var sizes []TableSize
+	err := db.Select(&sizes, `
+		SELECT
+			t.name AS table_name,
+			pg_table_size(t.name::regclass) AS table_bytes,
+			pg_indexes_size(t.name::regclass) AS index_bytes
+		FROM UNNEST($1::text[]) WITH ORDINALITY AS t(name, ord)
+		ORDER BY t.ord
+	`, pq.Array(tables))
+	return sizes, err
+}
+
+// VacuumAnalyze runs VACUUM (ANALYZE) on a table, marking space left by
+// deleted rows for reuse and refreshing planner statistics. It can't run
+// inside a transaction.
+func (db *DB) VacuumAnalyze(table string) error {
+	_, err := db.Exec(`VACUUM (ANALYZE) ` + pq.QuoteIdentifier(table))
+	return err
+}
@@ -0,0 +1,135 @@
+package database
+
+import (
+	"time"
+)
+
+// WarehouseLink is a link's daily rollup with its metadata
+type WarehouseLink struct {
+	LinkID       int       `db:"link_id"`
+	URL          string    `db:"normalized_url"`
+	Domain       string    `db:"domain"`
+	Title        *string   `db:"title"`
+	FirstSeenAt  time.Time `db:"first_seen_at"`
+	Sharers      int       `db:"share_count"` // Distinct sharers during the day
+	LastSharedAt time.Time `db:"last_shared_at"`
+}
+
+// WarehouseShares counts a day's shares of a link by author degree
+type WarehouseShares struct {
+	LinkID  int  `db:"link_id"`
+	Degree  *int `db:"degree"` // nil = outside the network
+	Posts   int  `db:"posts"`
+	Sharers int  `db:"sharers"`
+	Replies int  `db:"replies"`
+}
+
+// WarehouseDomain counts a day's shares of links on a domain
+type WarehouseDomain struct {
+	Domain  string `db:"domain"`
+	Links   int    `db:"links"`
+	Posts   int    `db:"posts"`
+	Sharers int    `db:"sharers"`
+}
+
+// warehouseDomainExpr extracts a link's host without a leading www.
+const warehouseDomainExpr = `COALESCE(REGEXP_REPLACE(
+				SUBSTRING(l.normalized_url FROM '^[a-z]+://([^/:?#]+)'),
+				'^www\.', ''
+			), '')`
+
+// GetWarehouseLinks returns the daily rollups of day (a date at midnight UTC)
+func (db *DB) GetWarehouseLinks(day time.Time) ([]WarehouseLink, error) {
+	query := `
+		SELECT
+			d.link_id,
+			l.normalized_url,
+			` + warehouseDomainExpr + ` AS domain,
+			l.title,
+			l.first_seen_at,
+			d.share_count,
+			d.last_shared_at
+		FROM daily_link_shares d
+		JOIN links l ON l.id = d.link_id
+		WHERE d.day = $1::date
+		ORDER BY d.share_count DESC, d.link_id
+	`
+
+	var links []WarehouseLink
+	err := db.Select(&links, query, day)
+	return links, err
+}
+
+// GetWarehouseShares counts shares per link and author degree among posts
+// created in [start, end)
+func (db *DB) GetWarehouseShares(start, end time.Time) ([]WarehouseShares, error) {
+	query := `
+		SELECT
+			pl.link_id,
+			p.author_degree AS degree,
+			COUNT(*) AS posts,
+			COUNT(DISTINCT COALESCE(p.author_did, p.author_handle)) AS sharers,
+			COUNT(*) FILTER (WHERE p.is_reply) AS replies
+		FROM post_links pl
+		JOIN posts p ON p.id = pl.post_id
+		WHERE p.created_at >= $1 AND p.created_at < $2
+		GROUP BY pl.link_id, p.author_degree
+		ORDER BY pl.link_id, p.author_degree
+	`
+
+	var shares []WarehouseShares
+	err := db.Select(&shares, query, start.UTC(), end.UTC())
+	return shares, err
+}
+
+// GetWarehouseDomains counts shares per domain among posts created in
+// [start, end)
+func (db *DB) GetWarehouseDomains(start, end time.Time) ([]WarehouseDomain, error) {
+	query := `
+		SELECT
+			` + warehouseDomainExpr + ` AS domain,
+			COUNT(DISTINCT pl.link_id) AS links,
+			COUNT(*) AS posts,
+			COUNT(DISTINCT COALESCE(p.author_did, p.author_handle)) AS sharers
+		FROM post_links pl
+		JOIN posts p ON p.id = pl.post_id
+		JOIN links l ON l.id = pl.link_id
+		WHERE p.created_at >= $1 AND p.created_at < $2
+		GROUP BY 1
+		ORDER BY posts DESC, domain
+	`
+
+	var domains []WarehouseDomain
+	err := db.Select(&domains, query, start.UTC(), end.UTC())
+	return domains, err
+}
+
+// GetWarehouseExportedDays returns the days since the given date that have
+// been exported, as YYYY-MM-DD
+func (db *DB) GetWarehouseExportedDays(since time.Time) (map[string]bool, error) {
+	var days []time.Time
+	if err := db.Select(&days, `SELECT day FROM warehouse_exports WHERE day >= $1::date`, since); err != nil {
+		return nil, err
+	}
+
+	exported := make(map[string]bool, len(days))
+	for _, day := range days {
+		exported[day.Format("2006-01-02")] = true
+	}
+	return exported, nil
+}
+
+// MarkWarehouseDayExported records that day was written to the warehouse
+func (db *DB) MarkWarehouseDayExported(day time.Time, links, shares, domains int) error {
+	query := `
+		INSERT INTO warehouse_exports (day, links, shares, domains)
+		VALUES ($1::date, $2, $3, $4)
+		ON CONFLICT (day) DO UPDATE SET
+			links = EXCLUDED.links,
+			shares = EXCLUDED.shares,
+			domains = EXCLUDED.domains,
+			exported_at = NOW()
+	`
+	_, err := db.Exec(query, day, links, shares, domains)
+	return err
+}
@@ -44,13 +44,15 @@ func NewManagerWithConfig(db *database.DB, config *Config) *Manager {
 }
 
 // LoadFromDatabase loads followed DIDs from the database
-// This now uses the network_accounts table which supports both 1st and 2nd degree
+// This now uses the network_accounts table which supports both 1st and 2nd degree.
+// Dormant accounts are left out; calling it again picks up demotions and
+// reactivations.
 func (m *Manager) LoadFromDatabase() error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
 	// Try loading from network_accounts first (new schema)
-	networkDIDs, err := m.db.GetAllNetworkDIDs()
+	networkDIDs, err := m.db.GetLiveNetworkDIDs()
 	if err == nil && len(networkDIDs) > 0 {
 		// Clear existing and rebuild
 		m.dids = make(map[string]int)
@@ -0,0 +1,79 @@
+// Package digest renders the trending list as an email newsletter and sends
+// it over SMTP. cmd/digest runs it daily.
+package digest
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/database"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/urlutil"
+)
+
+//go:embed templates/*
+var templateFiles embed.FS
+
+var funcs = map[string]interface{}{
+	"domain": urlutil.Domain,
+	"inc":    func(i int) int { return i + 1 },
+}
+
+var (
+	htmlTemplate = htmltemplate.Must(htmltemplate.New("digest.html").Funcs(funcs).ParseFS(templateFiles, "templates/digest.html"))
+	textTemplate = texttemplate.Must(texttemplate.New("digest.txt").Funcs(funcs).ParseFS(templateFiles, "templates/digest.txt"))
+)
+
+// Digest is one issue of the newsletter
+type Digest struct {
+	Date    time.Time // Local day it was sent
+	Hours   int       // Trending window covered
+	SiteURL string    // Where readers can see the live list (empty = not linked)
+	Links   []database.SnapshotLink
+}
+
+// Subject returns the email subject
+func (d Digest) Subject() string {
+	return "Trending links for " + d.Date.Format("Monday, January 2")
+}
+
+// Message is a rendered digest
+type Message struct {
+	Subject string
+	HTML    []byte
+	Text    []byte // Plain-text alternative
+}
+
+// Render renders the digest as HTML and plain text
+func Render(d Digest) (*Message, error) {
+	d.SiteURL = strings.TrimSuffix(d.SiteURL, "/")
+
+	var html, text bytes.Buffer
+	if err := htmlTemplate.Execute(&html, d); err != nil {
+		return nil, fmt.Errorf("failed to render HTML digest: %w", err)
+	}
+	if err := textTemplate.Execute(&text, d); err != nil {
+		return nil, fmt.Errorf("failed to render text digest: %w", err)
+	}
+	return &Message{Subject: d.Subject(), HTML: html.Bytes(), Text: text.Bytes()}, nil
+}
+
+// NextSend returns the first time after now that the local clock in loc
+// reads sendAt (HH:MM)
+func NextSend(now time.Time, sendAt string, loc *time.Location) (time.Time, error) {
+	clock, err := time.Parse("15:04", sendAt)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid send time %q (want HH:MM)", sendAt)
+	}
+
+	local := now.In(loc)
+	next := time.Date(local.Year(), local.Month(), local.Day(), clock.Hour(), clock.Minute(), 0, 0, loc)
+	if !next.After(now) {
+		next = time.Date(local.Year(), local.Month(), local.Day()+1, clock.Hour(), clock.Minute(), 0, 0, loc)
+	}
+	return next, nil
+}
@@ -0,0 +1,88 @@
+package digest
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"time"
+)
+
+// SMTPConfig is the server digests are sent through
+type SMTPConfig struct {
+	Host     string
+	Port     int
+	Username string // Empty = no authentication
+	Password string
+}
+
+// Send emails the message from from to each recipient separately, so
+// recipients don't see each other's addresses. Delivery uses STARTTLS when
+// the server offers it. It returns the first error after trying everyone.
+func Send(server SMTPConfig, from string, to []string, msg *Message) error {
+	addr := net.JoinHostPort(server.Host, strconv.Itoa(server.Port))
+	var auth smtp.Auth
+	if server.Username != "" {
+		auth = smtp.PlainAuth("", server.Username, server.Password, server.Host)
+	}
+
+	var firstErr error
+	for _, recipient := range to {
+		data, err := compose(from, recipient, msg, time.Now())
+		if err == nil {
+			err = smtp.SendMail(addr, auth, from, []string{recipient}, data)
+		}
+		if err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("failed to send digest to %s: %w", recipient, err)
+		}
+	}
+	return firstErr
+}
+
+// compose builds a multipart/alternative message with the plain-text and
+// HTML versions
+func compose(from, to string, msg *Message, date time.Time) ([]byte, error) {
+	var body bytes.Buffer
+	parts := multipart.NewWriter(&body)
+	for _, alt := range []struct {
+		contentType string
+		content     []byte
+	}{
+		{"text/plain; charset=UTF-8", msg.Text}, // Least preferred first
+		{"text/html; charset=UTF-8", msg.HTML},
+	} {
+		part, err := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {alt.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		qp := quotedprintable.NewWriter(part)
+		if _, err := qp.Write(alt.content); err != nil {
+			return nil, err
+		}
+		if err := qp.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if err := parts.Close(); err != nil {
+		return nil, err
+	}
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "From: %s\r\n", from)
+	fmt.Fprintf(&out, "To: %s\r\n", to)
+	fmt.Fprintf(&out, "Subject: %s\r\n", mime.QEncoding.Encode("UTF-8", msg.Subject))
+	fmt.Fprintf(&out, "Date: %s\r\n", date.Format(time.RFC1123Z))
+	fmt.Fprintf(&out, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&out, "Content-Type: multipart/alternative; boundary=%s\r\n", parts.Boundary())
+	fmt.Fprintf(&out, "\r\n")
+	out.Write(body.Bytes())
+	return out.Bytes(), nil
+}
@@ -0,0 +1,142 @@
+// Package enrich adds information to links after they are shared: scraped
+// page metadata, detected events, sensitivity flags. Each kind of enrichment
+// is an Enricher, and a Pipeline runs the configured ones in order, so a new
+// one (oEmbed, favicons, language detection...) is a new stage registered
+// here and listed in config rather than another edit to every caller.
+package enrich
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/database"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/moderation"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/scraper"
+)
+
+// Item is a link being enriched and what earlier stages found out about it
+type Item struct {
+	Link *database.Link
+
+	// Page is the scraped page: set by the scrape stage, or up front by
+	// callers that already fetched it. nil when nothing was scraped.
+	Page *scraper.OGData
+
+	// SkipScrape leaves the page unfetched, e.g. when it is queued for a
+	// scrape worker or would refuse scrapers
+	SkipScrape bool
+
+	// NewImageURL is a preview image stored during this run, for stages
+	// that inspect images
+	NewImageURL string
+}
+
+// Enricher is one stage of a pipeline
+type Enricher interface {
+	Name() string
+	Enrich(item *Item) error
+}
+
+// Pipeline runs enrichers in order
+type Pipeline struct {
+	stages []Enricher
+}
+
+// New returns a pipeline running stages in the order given
+func New(stages ...Enricher) *Pipeline {
+	return &Pipeline{stages: stages}
+}
+
+// Names returns the pipeline's stage names in order
+func (p *Pipeline) Names() []string {
+	names := make([]string, len(p.stages))
+	for i, stage := range p.stages {
+		names[i] = stage.Name()
+	}
+	return names
+}
+
+// Run enriches a link with every stage. A failing stage doesn't stop the
+// ones after it, which check what they need is present (the sensitive
+// domain check, for one, works without a page). Returns the first error.
+func (p *Pipeline) Run(item *Item) error {
+	var first error
+	for _, stage := range p.stages {
+		if err := stage.Enrich(item); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// Deps are the services stages are built from
+type Deps struct {
+	DB       *database.DB
+	Scraper  *scraper.Scraper
+	Detector *moderation.Detector // nil disables the sensitive stage
+}
+
+// Factory builds a stage from its dependencies
+type Factory func(deps Deps) Enricher
+
+var registry = map[string]Factory{
+	StageScrape:    func(d Deps) Enricher { return &Scrape{DB: d.DB, Scraper: d.Scraper} },
+	StageEvents:    func(d Deps) Enricher { return &Events{DB: d.DB} },
+	StageSensitive: func(d Deps) Enricher { return &Sensitive{DB: d.DB, Detector: d.Detector} },
+}
+
+// DefaultStages is the pipeline used when none is configured
+var DefaultStages = []string{StageScrape, StageEvents, StageSensitive}
+
+// Register makes a stage available to Build under name. It is meant to be
+// called from init functions.
+func Register(name string, factory Factory) {
+	registry[name] = factory
+}
+
+// Build returns a pipeline of the named stages in order (nil names =
+// DefaultStages). Unknown names are left out and reported in the error, so
+// callers can warn and carry on with the rest.
+func Build(names []string, deps Deps) (*Pipeline, error) {
+	if names == nil {
+		names = DefaultStages
+	}
+
+	var stages []Enricher
+	var unknown []string
+	for _, name := range names {
+		factory, ok := registry[name]
+		if !ok {
+			unknown = append(unknown, name)
+			continue
+		}
+		stages = append(stages, factory(deps))
+	}
+
+	if len(unknown) > 0 {
+		known := make([]string, 0, len(registry))
+		for name := range registry {
+			known = append(known, name)
+		}
+		sort.Strings(known)
+		return New(stages...), fmt.Errorf("unknown enrichers %s (available: %s)",
+			strings.Join(unknown, ", "), strings.Join(known, ", "))
+	}
+	return New(stages...), nil
+}
+
+// RecordScrapes stores the outcome of every request sc makes in the
+// scrape_stats table, for per-domain reliability stats
+func RecordScrapes(sc *scraper.Scraper, db *database.DB) {
+	sc.Observe(func(a scraper.Attempt) {
+		errMsg := ""
+		if a.Err != nil {
+			errMsg = a.Err.Error()
+		}
+		if err := db.RecordScrapeAttempt(a.Domain, a.Status, a.Latency, errMsg); err != nil {
+			log.Printf("[WARN] Failed to record scrape of %s: %v", a.Domain, err)
+		}
+	})
+}
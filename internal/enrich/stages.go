@@ -0,0 +1,141 @@
+package enrich
+
+import (
+	"errors"
+	"log"
+
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/database"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/events"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/moderation"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/scraper"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/urlutil"
+)
+
+// Built-in stage names
+const (
+	StageScrape    = "scrape"    // OpenGraph title, description and image
+	StageEvents    = "events"    // Upcoming events mentioned by the page
+	StageSensitive = "sensitive" // Sensitive domains and preview images
+)
+
+// Scrape fetches the page's OpenGraph data and stores it. Links are marked
+// fetched even on failure to avoid retry storms, and the error is kept for
+// the admin API. Items that already carry a page, are marked SkipScrape, or
+// whose domain is paused are left alone; paused links stay unfetched, so
+// they are scraped on a later share or retry once resumed.
+type Scrape struct {
+	DB      *database.DB
+	Scraper *scraper.Scraper
+}
+
+func (s *Scrape) Name() string { return StageScrape }
+
+func (s *Scrape) Enrich(item *Item) error {
+	if item.Page != nil || item.SkipScrape {
+		return nil
+	}
+	link := item.Link
+
+	if paused, err := s.DB.IsScrapePaused(urlutil.Domain(link.NormalizedURL)); err != nil {
+		log.Printf("[WARN] Failed to check scrape pause for %s: %v", link.NormalizedURL, err)
+	} else if paused {
+		return nil
+	}
+
+	ogData, err := s.Scraper.FetchOGData(link.NormalizedURL)
+	if err != nil {
+		if !errors.Is(err, scraper.ErrBlocked) {
+			log.Printf("[WARN] Failed to fetch metadata for %s: %v", link.NormalizedURL, err)
+		}
+		if err := s.DB.MarkLinkFetched(link.ID); err != nil {
+			log.Printf("[WARN] Failed to mark link as fetched: %v", err)
+		}
+		if recordErr := s.DB.RecordScrapeError(link.ID, err.Error()); recordErr != nil {
+			log.Printf("[WARN] Failed to record scrape error for %s: %v", link.NormalizedURL, recordErr)
+		}
+		return err
+	}
+
+	if err := s.DB.ClearScrapeError(link.ID); err != nil {
+		log.Printf("[WARN] Failed to clear scrape error for %s: %v", link.NormalizedURL, err)
+	}
+
+	if err := s.DB.SetLinkImageMissing(link.ID, ogData.ImageMissing); err != nil {
+		log.Printf("[WARN] Failed to mark missing image for %s: %v", link.NormalizedURL, err)
+	}
+
+	if ogData.Title == "" && ogData.Description == "" && ogData.ImageURL == "" {
+		// No metadata found, mark as fetched
+		if err := s.DB.MarkLinkFetched(link.ID); err != nil {
+			log.Printf("[WARN] Failed to mark link as fetched: %v", err)
+		}
+		return nil
+	}
+
+	if _, err := s.DB.SaveLinkMetadata(link.ID, database.MetadataSourceScraped, ogData.Title, ogData.Description, ogData.ImageURL); err != nil {
+		log.Printf("[WARN] Failed to update link metadata: %v", err)
+		return err
+	}
+	if ogData.ETag != "" || ogData.LastModified != "" {
+		if err := s.DB.UpdateLinkValidators(link.ID, ogData.ETag, ogData.LastModified); err != nil {
+			log.Printf("[WARN] Failed to store validators for %s: %v", link.NormalizedURL, err)
+		}
+	}
+
+	item.Page = ogData
+	if ogData.ImageURL != "" {
+		item.NewImageURL = ogData.ImageURL
+	}
+	return nil
+}
+
+// Events stores events detected in the scraped page
+type Events struct {
+	DB *database.DB
+}
+
+func (e *Events) Name() string { return StageEvents }
+
+func (e *Events) Enrich(item *Item) error {
+	if item.Page == nil {
+		return nil
+	}
+	if n, err := events.Store(e.DB, item.Link.ID, item.Page); err != nil {
+		log.Printf("[WARN] Failed to store events for %s: %v", item.Link.NormalizedURL, err)
+	} else if n > 0 {
+		log.Printf("[INFO] Detected %d event(s) in %s", n, item.Link.NormalizedURL)
+	}
+	return nil
+}
+
+// Sensitive marks a link sensitive if its domain is listed or, when a new
+// preview image was just stored, the image classifier flags it
+type Sensitive struct {
+	DB       *database.DB
+	Detector *moderation.Detector
+}
+
+func (s *Sensitive) Name() string { return StageSensitive }
+
+func (s *Sensitive) Enrich(item *Item) error {
+	link := item.Link
+	if s.Detector == nil || link.Sensitive {
+		return nil
+	}
+
+	reason, sensitive := s.Detector.CheckURL(link.NormalizedURL)
+	if !sensitive && item.NewImageURL != "" {
+		var err error
+		reason, sensitive, err = s.Detector.CheckImage(item.NewImageURL)
+		if err != nil {
+			log.Printf("[WARN] Image classification failed for %s: %v", item.NewImageURL, err)
+		}
+	}
+
+	if sensitive {
+		if err := s.DB.MarkLinkSensitive(link.ID, reason); err != nil {
+			log.Printf("[WARN] Failed to mark link %d sensitive: %v", link.ID, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,208 @@
+// Package eventarchive writes a sample of raw firehose events to rotating
+// gzip files, so parsing bugs can be reproduced offline and events replayed
+// into a test server.
+package eventarchive
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// filePattern matches archive files; names sort in the order they were written
+const filePattern = "events-*.jsonl.gz"
+
+// Record is one archived event: a JSON line in an archive file
+type Record struct {
+	ArchivedAt time.Time       `json:"archived_at"`
+	Error      string          `json:"error,omitempty"` // Why processing failed (empty = sampled)
+	Event      json.RawMessage `json:"event"`           // The Jetstream event as received
+}
+
+// Config holds archiver settings
+type Config struct {
+	Dir        string  // Directory holding the archive files
+	SampleRate float64 // Fraction of events archived (0-1)
+	Errors     bool    // Also archive every event that fails processing
+	RotateMB   int     // Start a new file past this compressed size
+	MaxFiles   int     // Oldest files are deleted beyond this many (<= 0 = keep all)
+}
+
+// Archiver appends sampled events to the current archive file. It is safe
+// for concurrent use; a nil *Archiver archives nothing.
+type Archiver struct {
+	config Config
+
+	mu      sync.Mutex
+	file    *os.File
+	counter *countingWriter
+	gz      *gzip.Writer
+}
+
+// New creates the archive directory and opens a new file in it
+func New(config *Config) (*Archiver, error) {
+	if err := os.MkdirAll(config.Dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create archive directory: %w", err)
+	}
+	a := &Archiver{config: *config}
+	if err := a.rotate(); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// Archive writes event if it is sampled, or failed with processErr while
+// errors are archived
+func (a *Archiver) Archive(event interface{}, processErr error) error {
+	if a == nil {
+		return nil
+	}
+	failed := processErr != nil && a.config.Errors
+	if !failed && rand.Float64() >= a.config.SampleRate {
+		return nil
+	}
+
+	raw, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode event for archive: %w", err)
+	}
+	record := Record{ArchivedAt: time.Now().UTC(), Event: raw}
+	if processErr != nil {
+		record.Error = processErr.Error()
+	}
+	line, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to encode archive record: %w", err)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if _, err := a.gz.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write archive: %w", err)
+	}
+	// Flush each record so a crash loses at most the gzip trailer
+	if err := a.gz.Flush(); err != nil {
+		return fmt.Errorf("failed to write archive: %w", err)
+	}
+	if a.counter.n >= int64(a.config.RotateMB)*1024*1024 {
+		return a.rotate()
+	}
+	return nil
+}
+
+// Close finishes the current file
+func (a *Archiver) Close() error {
+	if a == nil {
+		return nil
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.closeFile()
+}
+
+// rotate closes the current file, opens the next one and deletes the oldest
+// beyond MaxFiles. The caller holds mu (or has the only reference).
+func (a *Archiver) rotate() error {
+	if err := a.closeFile(); err != nil {
+		return err
+	}
+
+	name := fmt.Sprintf("events-%s.jsonl.gz", time.Now().UTC().Format("20060102T150405.000000000Z"))
+	file, err := os.OpenFile(filepath.Join(a.config.Dir, name), os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to create archive file: %w", err)
+	}
+	a.file = file
+	a.counter = &countingWriter{w: file}
+	a.gz = gzip.NewWriter(a.counter)
+
+	if a.config.MaxFiles > 0 {
+		files, err := Files(a.config.Dir)
+		if err != nil {
+			return err
+		}
+		for len(files) > a.config.MaxFiles {
+			if err := os.Remove(files[0]); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return fmt.Errorf("failed to delete old archive file: %w", err)
+			}
+			files = files[1:]
+		}
+	}
+	return nil
+}
+
+func (a *Archiver) closeFile() error {
+	if a.file == nil {
+		return nil
+	}
+	gzErr := a.gz.Close()
+	fileErr := a.file.Close()
+	a.file, a.gz, a.counter = nil, nil, nil
+	if gzErr != nil {
+		return fmt.Errorf("failed to finish archive file: %w", gzErr)
+	}
+	return fileErr
+}
+
+// Files returns the archive files in dir, oldest first
+func Files(dir string) ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(dir, filePattern))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// ReadFile calls fn with each record in an archive file in order. A file
+// cut short by a crash is read up to the last complete record.
+func ReadFile(path string, fn func(Record) error) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	defer gz.Close()
+
+	scanner := bufio.NewScanner(gz)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var record Record
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return fmt.Errorf("failed to parse record in %s: %w", path, err)
+		}
+		if err := fn(record); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return nil
+}
+
+// countingWriter counts the bytes written through it
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
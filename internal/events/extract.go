@@ -0,0 +1,229 @@
+// Package events detects upcoming events mentioned by articles, from
+// schema.org Event markup and from dated phrases in titles and descriptions
+// (e.g. "election on Nov 5"), and renders them as an iCalendar feed.
+package events
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/scraper"
+)
+
+// Event sources
+const (
+	SourceSchema = "schema" // schema.org Event markup
+	SourceText   = "text"   // Dated phrase in the title or description
+)
+
+// Event is an event detected in an article
+type Event struct {
+	Name     string
+	StartAt  time.Time
+	EndAt    *time.Time
+	AllDay   bool // StartAt is a date without a time of day
+	Location string
+	Source   string
+}
+
+// eventKeywords are words that make a nearby date an event rather than, say,
+// a publication date
+var eventKeywords = []string{
+	"election", "primary", "vote", "referendum", "caucus", "debate",
+	"conference", "summit", "convention", "festival", "expo", "meetup",
+	"hearing", "trial", "sentencing", "ruling", "deadline", "launch",
+	"release", "premiere", "opening", "concert", "match", "game", "final",
+	"strike", "protest", "march", "rally", "meeting", "webinar", "keynote",
+	"eclipse", "inauguration",
+}
+
+var (
+	monthPattern = `(jan(?:uary)?|feb(?:ruary)?|mar(?:ch)?|apr(?:il)?|may|june?|july?|aug(?:ust)?|sept?(?:ember)?|oct(?:ober)?|nov(?:ember)?|dec(?:ember)?)\.?`
+
+	// "November 5", "Nov. 5th, 2026"
+	textDatePattern = regexp.MustCompile(`(?i)\b` + monthPattern + `\s+(\d{1,2})(?:st|nd|rd|th)?(?:,?\s+(\d{4}))?\b`)
+
+	// Sentence ends: terminal punctuation followed by a capitalized word, so
+	// abbreviations like "Nov. 5" stay in one sentence
+	sentenceEnd = regexp.MustCompile(`[.!?]\s+["“']?[A-Z]|\n`)
+)
+
+// Extract returns events declared in the page's schema.org markup plus dated
+// event phrases in its title and description. ref anchors dates without a
+// year (usually the article's publish time); mentions already in the past
+// relative to ref are dropped.
+func Extract(og *scraper.OGData, ref time.Time) []Event {
+	var events []Event
+	seen := make(map[string]bool)
+	add := func(e Event) {
+		key := strings.ToLower(e.Name) + "|" + e.StartAt.Format("2006-01-02")
+		if !seen[key] {
+			seen[key] = true
+			events = append(events, e)
+		}
+	}
+
+	for _, data := range og.Events {
+		start, allDay, ok := parseEventTime(data.StartDate)
+		if !ok {
+			continue
+		}
+		e := Event{Name: data.Name, StartAt: start, AllDay: allDay, Location: data.Location, Source: SourceSchema}
+		if end, _, ok := parseEventTime(data.EndDate); ok && !end.Before(start) {
+			e.EndAt = &end
+		}
+		add(e)
+	}
+
+	for _, text := range []string{og.Title, og.Description} {
+		for _, e := range ExtractFromText(text, ref) {
+			add(e)
+		}
+	}
+
+	return events
+}
+
+// ExtractFromText finds "<event keyword> ... <Month> <day>" mentions in text,
+// one per sentence, naming each event after its sentence
+func ExtractFromText(text string, ref time.Time) []Event {
+	var events []Event
+	for _, sentence := range splitSentences(text) {
+		sentence = strings.TrimSpace(sentence)
+		lower := strings.ToLower(sentence)
+		if sentence == "" || !containsKeyword(lower) {
+			continue
+		}
+
+		match := textDatePattern.FindStringSubmatch(sentence)
+		if match == nil {
+			continue
+		}
+		start, ok := resolveDate(match[1], match[2], match[3], ref)
+		if !ok {
+			continue
+		}
+
+		events = append(events, Event{
+			Name:    truncate(sentence, 200),
+			StartAt: start,
+			AllDay:  true,
+			Source:  SourceText,
+		})
+	}
+	return events
+}
+
+// splitSentences splits text into sentences, keeping terminal punctuation
+func splitSentences(text string) []string {
+	var sentences []string
+	start := 0
+	for _, loc := range sentenceEnd.FindAllStringIndex(text, -1) {
+		end := loc[0] + 1 // Keep the punctuation
+		sentences = append(sentences, text[start:end])
+		start = loc[1] - 1 // Next sentence begins at the capital letter
+		if text[loc[0]] == '\n' {
+			start = loc[1]
+		}
+	}
+	return append(sentences, text[start:])
+}
+
+func containsKeyword(lower string) bool {
+	for _, keyword := range eventKeywords {
+		if idx := strings.Index(lower, keyword); idx >= 0 {
+			// Whole-word match only ("primary" not "primarily")
+			end := idx + len(keyword)
+			if (idx == 0 || !isLetter(lower[idx-1])) && (end == len(lower) || !isLetter(lower[end]) || lower[end] == 's') {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func isLetter(b byte) bool {
+	return b >= 'a' && b <= 'z'
+}
+
+// resolveDate builds a date from a matched month, day and optional year.
+// Without a year, the next occurrence on or after ref is used.
+func resolveDate(monthStr, dayStr, yearStr string, ref time.Time) (time.Time, bool) {
+	month := parseMonth(monthStr)
+	day, err := strconv.Atoi(dayStr)
+	if month == 0 || err != nil || day < 1 || day > 31 {
+		return time.Time{}, false
+	}
+
+	refDay := time.Date(ref.Year(), ref.Month(), ref.Day(), 0, 0, 0, 0, time.UTC)
+	year := ref.Year()
+	if yearStr != "" {
+		year, _ = strconv.Atoi(yearStr)
+	}
+
+	t := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	if t.Day() != day {
+		return time.Time{}, false // e.g. February 30
+	}
+	if yearStr == "" && t.Before(refDay) {
+		t = t.AddDate(1, 0, 0)
+	}
+	if t.Before(refDay) {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+func parseMonth(s string) time.Month {
+	s = strings.ToLower(strings.TrimSuffix(s, "."))
+	for m := time.January; m <= time.December; m++ {
+		if strings.HasPrefix(strings.ToLower(m.String()), s[:3]) {
+			return m
+		}
+	}
+	return 0
+}
+
+// eventTimeLayouts are the date formats seen in schema.org startDate/endDate
+var eventTimeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05Z0700",
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+}
+
+// parseEventTime parses a schema.org date or date-time
+func parseEventTime(value string) (time.Time, bool, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, false, false
+	}
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return t, true, true
+	}
+	for _, layout := range eventTimeLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.UTC(), false, true
+		}
+	}
+	return time.Time{}, false, false
+}
+
+func truncate(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	cut := max
+	for cut > 0 && !utf8Start(s[cut]) {
+		cut--
+	}
+	return strings.TrimSpace(s[:cut]) + "…"
+}
+
+// utf8Start reports whether b begins a UTF-8 sequence
+func utf8Start(b byte) bool {
+	return b&0xC0 != 0x80
+}
@@ -0,0 +1,103 @@
+package events
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// CalendarEntry is one VEVENT in an iCalendar feed
+type CalendarEntry struct {
+	UID         string
+	Summary     string
+	Description string
+	URL         string
+	Location    string
+	StartAt     time.Time
+	EndAt       *time.Time
+	AllDay      bool
+}
+
+// WriteICS writes entries as an RFC 5545 iCalendar feed
+func WriteICS(w io.Writer, calendarName string, entries []CalendarEntry) error {
+	var b strings.Builder
+	line := func(format string, args ...interface{}) {
+		b.WriteString(foldLine(fmt.Sprintf(format, args...)))
+		b.WriteString("\r\n")
+	}
+
+	line("BEGIN:VCALENDAR")
+	line("VERSION:2.0")
+	line("PRODID:-//bluesky-news-aggregator//events//EN")
+	line("CALSCALE:GREGORIAN")
+	line("X-WR-CALNAME:%s", escapeText(calendarName))
+
+	stamp := time.Now().UTC().Format("20060102T150405Z")
+	for _, e := range entries {
+		line("BEGIN:VEVENT")
+		line("UID:%s", e.UID)
+		line("DTSTAMP:%s", stamp)
+		if e.AllDay {
+			line("DTSTART;VALUE=DATE:%s", e.StartAt.Format("20060102"))
+			end := e.StartAt.AddDate(0, 0, 1)
+			if e.EndAt != nil && e.EndAt.After(e.StartAt) {
+				end = e.EndAt.AddDate(0, 0, 1) // DTEND is exclusive for dates
+			}
+			line("DTEND;VALUE=DATE:%s", end.Format("20060102"))
+		} else {
+			line("DTSTART:%s", e.StartAt.UTC().Format("20060102T150405Z"))
+			if e.EndAt != nil {
+				line("DTEND:%s", e.EndAt.UTC().Format("20060102T150405Z"))
+			}
+		}
+		line("SUMMARY:%s", escapeText(e.Summary))
+		if e.Description != "" {
+			line("DESCRIPTION:%s", escapeText(e.Description))
+		}
+		if e.Location != "" {
+			line("LOCATION:%s", escapeText(e.Location))
+		}
+		if e.URL != "" {
+			line("URL:%s", e.URL)
+		}
+		line("END:VEVENT")
+	}
+
+	line("END:VCALENDAR")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// escapeText escapes an iCalendar TEXT value
+func escapeText(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, ";", `\;`)
+	s = strings.ReplaceAll(s, ",", `\,`)
+	s = strings.ReplaceAll(s, "\r\n", `\n`)
+	s = strings.ReplaceAll(s, "\n", `\n`)
+	return s
+}
+
+// foldLine splits content lines longer than 75 octets, as RFC 5545 requires,
+// without breaking UTF-8 sequences
+func foldLine(s string) string {
+	const limit = 75
+	if len(s) <= limit {
+		return s
+	}
+
+	var b strings.Builder
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && !utf8Start(s[cut]) {
+			cut--
+		}
+		b.WriteString(s[:cut])
+		b.WriteString("\r\n ")
+		s = s[cut:]
+	}
+	b.WriteString(s)
+	return b.String()
+}
@@ -0,0 +1,40 @@
+package events
+
+import (
+	"time"
+
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/database"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/scraper"
+)
+
+// Store detects events in freshly fetched link metadata and saves them.
+// Dates without a year are anchored to the article's publish time, or now.
+func Store(db *database.DB, linkID int, og *scraper.OGData) (int, error) {
+	ref := time.Now().UTC()
+	if og.PublishedAt != nil {
+		ref = og.PublishedAt.UTC()
+	}
+
+	detected := Extract(og, ref)
+	if len(detected) == 0 {
+		return 0, nil
+	}
+
+	linkEvents := make([]database.LinkEvent, len(detected))
+	for i, e := range detected {
+		linkEvents[i] = database.LinkEvent{
+			LinkID:  linkID,
+			Name:    e.Name,
+			StartAt: e.StartAt,
+			EndAt:   e.EndAt,
+			AllDay:  e.AllDay,
+			Source:  e.Source,
+		}
+		if e.Location != "" {
+			location := e.Location
+			linkEvents[i].Location = &location
+		}
+	}
+
+	return len(linkEvents), db.ReplaceLinkEvents(linkID, linkEvents)
+}
@@ -0,0 +1,91 @@
+// Package features keeps the on/off flags of optional subsystems, so a
+// deployment can leave some of them out and report which are running.
+package features
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Built-in features
+const (
+	SecondDegree   = "second_degree"  // 2nd-degree accounts in the ingest filter
+	Classification = "classification" // Image classifier for sensitive previews
+	Digests        = "digests"        // Trending snapshots, digest and snapshot pages
+	Retrospectives = "retrospectives" // Weekly/monthly and on-this-day lookbacks
+)
+
+// Feature is an optional subsystem and whether it is turned on
+type Feature struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Default     bool   `json:"default"`
+	Enabled     bool   `json:"enabled"`
+}
+
+var registry = map[string]*Feature{
+	SecondDegree:   {Description: "Ingest posts from 2nd-degree accounts (followed by several follows)", Default: true},
+	Classification: {Description: "Send preview images to IMAGE_CLASSIFIER_URL to detect sensitive links", Default: true},
+	Digests:        {Description: "Store trending snapshots and serve digest and snapshot pages", Default: true},
+	Retrospectives: {Description: "Serve weekly, monthly and on-this-day lookbacks", Default: true},
+}
+
+func init() {
+	for name, feature := range registry {
+		feature.Name = name
+		feature.Enabled = feature.Default
+	}
+}
+
+// Register adds an optional feature, on or off by default until Configure
+// says otherwise. It is meant to be called from init functions.
+func Register(name, description string, enabled bool) {
+	registry[name] = &Feature{Name: name, Description: description, Default: enabled, Enabled: enabled}
+}
+
+// Configure turns features on or off by name. Unknown names are left out
+// and reported in the error, so callers can warn and carry on with the rest.
+func Configure(flags map[string]bool) error {
+	var unknown []string
+	for name, enabled := range flags {
+		feature, ok := registry[name]
+		if !ok {
+			unknown = append(unknown, name)
+			continue
+		}
+		feature.Enabled = enabled
+	}
+
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("unknown features %s (available: %s)",
+			strings.Join(unknown, ", "), strings.Join(names(), ", "))
+	}
+	return nil
+}
+
+// Enabled reports whether the named feature is turned on. Unknown features
+// are off.
+func Enabled(name string) bool {
+	feature, ok := registry[name]
+	return ok && feature.Enabled
+}
+
+// All returns every registered feature, sorted by name
+func All() []Feature {
+	all := make([]Feature, 0, len(registry))
+	for _, name := range names() {
+		all = append(all, *registry[name])
+	}
+	return all
+}
+
+func names() []string {
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,211 @@
+// Package feed writes RSS 2.0 and Atom syndication feeds.
+package feed
+
+import (
+	"encoding/xml"
+	"io"
+	"mime"
+	"path"
+	"strings"
+	"time"
+)
+
+// Feed formats
+const (
+	FormatRSS  = "rss"
+	FormatAtom = "atom"
+)
+
+// Feed is a channel of items, independent of the output format
+type Feed struct {
+	Title       string
+	Description string
+	Link        string // Site the feed belongs to
+	SelfURL     string // The feed's own URL
+	Updated     time.Time
+	Items       []Item
+}
+
+// Item is one feed entry
+type Item struct {
+	ID          string // Permanent unique URL (RSS guid, Atom id)
+	Title       string
+	Description string
+	Link        string
+	ImageURL    string // Sent as an enclosure; empty = none
+	Published   time.Time
+}
+
+// Write writes f in format (FormatRSS or FormatAtom)
+func Write(w io.Writer, format string, f Feed) error {
+	if format == FormatAtom {
+		return WriteAtom(w, f)
+	}
+	return WriteRSS(w, f)
+}
+
+// Media types of the formats
+const (
+	rssType  = "application/rss+xml"
+	atomType = "application/atom+xml"
+)
+
+// ContentType is the Content-Type header for a format
+func ContentType(format string) string {
+	if format == FormatAtom {
+		return atomType + "; charset=utf-8"
+	}
+	return rssType + "; charset=utf-8"
+}
+
+type rss struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Atom    string     `xml:"xmlns:atom,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	LastBuildDate string    `xml:"lastBuildDate"`
+	Self          atomLink  `xml:"atom:link"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string        `xml:"title"`
+	Link        string        `xml:"link"`
+	Description string        `xml:"description,omitempty"`
+	GUID        rssGUID       `xml:"guid"`
+	PubDate     string        `xml:"pubDate"`
+	Enclosure   *rssEnclosure `xml:"enclosure"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+type rssEnclosure struct {
+	URL    string `xml:"url,attr"`
+	Length int    `xml:"length,attr"` // Unknown, so 0 as the spec's common practice allows
+	Type   string `xml:"type,attr"`
+}
+
+// WriteRSS writes f as an RSS 2.0 feed
+func WriteRSS(w io.Writer, f Feed) error {
+	doc := rss{
+		Version: "2.0",
+		Atom:    "http://www.w3.org/2005/Atom",
+		Channel: rssChannel{
+			Title:         f.Title,
+			Link:          f.Link,
+			Description:   f.Description,
+			LastBuildDate: f.Updated.UTC().Format(time.RFC1123Z),
+			Self:          atomLink{Href: f.SelfURL, Rel: "self", Type: rssType},
+		},
+	}
+	for _, item := range f.Items {
+		entry := rssItem{
+			Title:       item.Title,
+			Link:        item.Link,
+			Description: item.Description,
+			GUID:        rssGUID{IsPermaLink: true, Value: item.ID},
+			PubDate:     item.Published.UTC().Format(time.RFC1123Z),
+		}
+		if item.ImageURL != "" {
+			entry.Enclosure = &rssEnclosure{URL: item.ImageURL, Type: imageType(item.ImageURL)}
+		}
+		doc.Channel.Items = append(doc.Channel.Items, entry)
+	}
+	return writeXML(w, doc)
+}
+
+type atomFeed struct {
+	XMLName  xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title    string      `xml:"title"`
+	Subtitle string      `xml:"subtitle,omitempty"`
+	ID       string      `xml:"id"`
+	Updated  string      `xml:"updated"`
+	Links    []atomLink  `xml:"link"`
+	Author   atomAuthor  `xml:"author"`
+	Entries  []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomEntry struct {
+	Title     string     `xml:"title"`
+	ID        string     `xml:"id"`
+	Updated   string     `xml:"updated"`
+	Published string     `xml:"published"`
+	Links     []atomLink `xml:"link"`
+	Summary   string     `xml:"summary,omitempty"`
+}
+
+// WriteAtom writes f as an Atom (RFC 4287) feed
+func WriteAtom(w io.Writer, f Feed) error {
+	doc := atomFeed{
+		Title:    f.Title,
+		Subtitle: f.Description,
+		ID:       f.SelfURL,
+		Updated:  f.Updated.UTC().Format(time.RFC3339),
+		Links: []atomLink{
+			{Href: f.SelfURL, Rel: "self", Type: atomType},
+			{Href: f.Link, Rel: "alternate", Type: "text/html"},
+		},
+		Author: atomAuthor{Name: f.Title},
+	}
+	for _, item := range f.Items {
+		published := item.Published.UTC().Format(time.RFC3339)
+		entry := atomEntry{
+			Title:     item.Title,
+			ID:        item.ID,
+			Updated:   published,
+			Published: published,
+			Links:     []atomLink{{Href: item.Link, Rel: "alternate"}},
+			Summary:   item.Description,
+		}
+		if item.ImageURL != "" {
+			entry.Links = append(entry.Links, atomLink{Href: item.ImageURL, Rel: "enclosure", Type: imageType(item.ImageURL)})
+		}
+		doc.Entries = append(doc.Entries, entry)
+	}
+	return writeXML(w, doc)
+}
+
+func writeXML(w io.Writer, doc interface{}) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// imageType guesses an image's media type from its URL's extension,
+// falling back to image/jpeg, the most common preview format
+func imageType(imageURL string) string {
+	p := imageURL
+	if i := strings.IndexAny(p, "?#"); i >= 0 {
+		p = p[:i]
+	}
+	if t := mime.TypeByExtension(strings.ToLower(path.Ext(p))); strings.HasPrefix(t, "image/") {
+		return t
+	}
+	return "image/jpeg"
+}
@@ -0,0 +1,188 @@
+// Package feedgen publishes the trending list inside Bluesky as a custom
+// feed. It describes an AT Protocol feed generator: the did:web document
+// that points Bluesky at this service, the feed record the publisher's
+// account holds, and the skeleton (a list of post URIs) the App View asks
+// for when someone opens the feed.
+package feedgen
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/atutil"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/config"
+)
+
+// GeneratorCollection is the collection of feed generator records
+const GeneratorCollection = "app.bsky.feed.generator"
+
+// Skeleton page sizes allowed by app.bsky.feed.getFeedSkeleton
+const (
+	DefaultLimit = 50
+	MaxLimit     = 100
+)
+
+// serviceID is the fragment naming the feed generator service in the DID document
+const serviceID = "#bsky_fg"
+
+// Generator is this deployment's feed generator
+type Generator struct {
+	ServiceDID   string // DID the App View resolves to find this service
+	Endpoint     string // Service URL (scheme and host)
+	PublisherDID string // Account whose repo holds the feed record
+	RecordName   string // Record key of the feed
+	DisplayName  string
+	Description  string
+}
+
+// New returns the feed generator configured in cfg, served at publicURL,
+// or nil if none is configured
+func New(cfg config.FeedGenConfig, publicURL string) (*Generator, error) {
+	if cfg.PublisherDID == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(cfg.PublisherDID, "did:") {
+		return nil, fmt.Errorf("feedgen.publisher_did must be a DID, got %q", cfg.PublisherDID)
+	}
+	if cfg.RecordName == "" || strings.ContainsAny(cfg.RecordName, "/?#") {
+		return nil, fmt.Errorf("invalid feedgen.record_name %q", cfg.RecordName)
+	}
+
+	u, err := url.Parse(publicURL)
+	if err != nil || u.Host == "" || (u.Scheme != "https" && u.Scheme != "http") {
+		return nil, errors.New("PUBLIC_URL must be set to the site's http(s) URL to serve a feed")
+	}
+
+	serviceDID := cfg.ServiceDID
+	if serviceDID == "" {
+		// did:web percent-encodes the port separator
+		serviceDID = "did:web:" + strings.ReplaceAll(u.Host, ":", "%3A")
+	}
+	return &Generator{
+		ServiceDID:   serviceDID,
+		Endpoint:     u.Scheme + "://" + u.Host,
+		PublisherDID: cfg.PublisherDID,
+		RecordName:   cfg.RecordName,
+		DisplayName:  cfg.DisplayName,
+		Description:  cfg.Description,
+	}, nil
+}
+
+// FeedURI returns the at:// URI of the feed record
+func (g *Generator) FeedURI() string {
+	return atutil.URI{Authority: g.PublisherDID, Collection: GeneratorCollection, RKey: g.RecordName}.String()
+}
+
+// WebURL returns the feed's bsky.app URL, where people subscribe to it
+func (g *Generator) WebURL() string {
+	return atutil.ProfileURL("", g.PublisherDID) + "/feed/" + g.RecordName
+}
+
+// ServesDIDDocument reports whether this service hosts its own DID
+// document, i.e. its DID is did:web of its own host
+func (g *Generator) ServesDIDDocument() bool {
+	u, err := url.Parse(g.Endpoint)
+	return err == nil && g.ServiceDID == "did:web:"+strings.ReplaceAll(u.Host, ":", "%3A")
+}
+
+// DIDDocument is a did:web document served at /.well-known/did.json
+type DIDDocument struct {
+	Context []string     `json:"@context"`
+	ID      string       `json:"id"`
+	Service []DIDService `json:"service"`
+}
+
+// DIDService is a service entry of a DID document
+type DIDService struct {
+	ID              string `json:"id"`
+	Type            string `json:"type"`
+	ServiceEndpoint string `json:"serviceEndpoint"`
+}
+
+// DIDDocument returns the document declaring this service as a feed generator
+func (g *Generator) DIDDocument() DIDDocument {
+	return DIDDocument{
+		Context: []string{"https://www.w3.org/ns/did/v1"},
+		ID:      g.ServiceDID,
+		Service: []DIDService{{ID: serviceID, Type: "BskyFeedGenerator", ServiceEndpoint: g.Endpoint}},
+	}
+}
+
+// Description is the response to app.bsky.feed.describeFeedGenerator
+type Description struct {
+	DID   string     `json:"did"`
+	Feeds []FeedInfo `json:"feeds"`
+}
+
+// FeedInfo names one feed served by the generator
+type FeedInfo struct {
+	URI string `json:"uri"`
+}
+
+// Describe lists the feeds this generator serves
+func (g *Generator) Describe() Description {
+	return Description{DID: g.ServiceDID, Feeds: []FeedInfo{{URI: g.FeedURI()}}}
+}
+
+// Record returns the app.bsky.feed.generator record to publish in the
+// publisher's repo
+func (g *Generator) Record(createdAt time.Time) map[string]interface{} {
+	record := map[string]interface{}{
+		"$type":       GeneratorCollection,
+		"did":         g.ServiceDID,
+		"displayName": g.DisplayName,
+		"createdAt":   createdAt.UTC().Format(time.RFC3339),
+	}
+	if g.Description != "" {
+		record["description"] = g.Description
+	}
+	return record
+}
+
+// SkeletonRequest is a parsed app.bsky.feed.getFeedSkeleton request
+type SkeletonRequest struct {
+	Limit  int
+	Cursor string // Opaque; passed back from an earlier page
+}
+
+// Error is an XRPC error, written as {"error": Name, "message": Message}
+type Error struct {
+	Name    string `json:"error"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return e.Name + ": " + e.Message
+}
+
+// ParseSkeletonRequest checks a getFeedSkeleton request is for this
+// generator's feed and reads its page parameters
+func (g *Generator) ParseSkeletonRequest(values url.Values) (SkeletonRequest, error) {
+	req := SkeletonRequest{Limit: DefaultLimit, Cursor: values.Get("cursor")}
+	if feed := values.Get("feed"); feed != g.FeedURI() {
+		return req, &Error{Name: "UnknownFeed", Message: "Unknown feed " + strconv.Quote(feed)}
+	}
+	if v := values.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > MaxLimit {
+			return req, &Error{Name: "InvalidRequest", Message: fmt.Sprintf("Invalid limit parameter (1-%d)", MaxLimit)}
+		}
+		req.Limit = limit
+	}
+	return req, nil
+}
+
+// Skeleton is the response to app.bsky.feed.getFeedSkeleton
+type Skeleton struct {
+	Cursor string         `json:"cursor,omitempty"` // Empty = last page
+	Feed   []SkeletonPost `json:"feed"`
+}
+
+// SkeletonPost is one post in a skeleton; the App View hydrates it
+type SkeletonPost struct {
+	Post string `json:"post"` // at:// URI
+}
@@ -0,0 +1,400 @@
+// Package graphql executes GraphQL queries against a schema of Go
+// resolvers. It implements the query language (operations, variables,
+// aliases, fragments, @include and @skip) without type checking arguments
+// or introspection: resolvers read their own arguments, and the schema is
+// documented by hand.
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// DefaultMaxResolves caps the fields one query may resolve
+const DefaultMaxResolves = 10000
+
+// ResolveFunc returns a field's value on source, the value of the parent
+// field (nil for the query root). Objects are returned as any Go value for
+// the field type's resolvers; lists as slices.
+type ResolveFunc func(ctx context.Context, source interface{}, args Args) (interface{}, error)
+
+// Object is an object type: named fields with resolvers
+type Object struct {
+	Name   string
+	Fields map[string]*Field
+}
+
+// Field is a field of an object type
+type Field struct {
+	Type    *Object // Object type of the value or its list elements (nil = scalar)
+	Resolve ResolveFunc
+}
+
+// Schema is a query root and limits on queries
+type Schema struct {
+	Query       *Object
+	MaxResolves int // Fields one query may resolve (0 = DefaultMaxResolves)
+}
+
+// Request is a GraphQL request as POSTed in JSON
+type Request struct {
+	Query         string                 `json:"query"`
+	Variables     map[string]interface{} `json:"variables"`
+	OperationName string                 `json:"operationName"`
+}
+
+// Response is a GraphQL response. Data is nil when the query couldn't run.
+type Response struct {
+	Data   *OrderedMap `json:"data"`
+	Errors []*Error    `json:"errors,omitempty"`
+}
+
+// Error is a query or field error
+type Error struct {
+	Message string        `json:"message"`
+	Path    []interface{} `json:"path,omitempty"` // Response keys and list indexes to the failed field
+}
+
+// Args are a field's arguments with variables substituted. Numbers are ints
+// when written in the query and float64s when passed as JSON variables.
+type Args map[string]interface{}
+
+// Int returns an integer argument, or def if it is absent or null
+func (a Args) Int(name string, def int) (int, error) {
+	switch v := a[name].(type) {
+	case nil:
+		return def, nil
+	case int:
+		return v, nil
+	case float64:
+		if v != float64(int(v)) {
+			return 0, fmt.Errorf("argument %s must be an integer", name)
+		}
+		return int(v), nil
+	default:
+		return 0, fmt.Errorf("argument %s must be an integer", name)
+	}
+}
+
+// OrderedMap is a JSON object keeping its keys in query order
+type OrderedMap struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+func newOrderedMap() *OrderedMap {
+	return &OrderedMap{values: make(map[string]interface{})}
+}
+
+// Set adds or replaces a key
+func (m *OrderedMap) Set(key string, value interface{}) {
+	if _, ok := m.values[key]; !ok {
+		m.keys = append(m.keys, key)
+	}
+	m.values[key] = value
+}
+
+// Get returns a key's value
+func (m *OrderedMap) Get(key string) interface{} {
+	return m.values[key]
+}
+
+// MarshalJSON encodes the map with keys in insertion order
+func (m *OrderedMap) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, key := range m.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		k, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		v, err := json.Marshal(m.values[key])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(k)
+		buf.WriteByte(':')
+		buf.Write(v)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// errTooComplex stops a query past MaxResolves
+var errTooComplex = errors.New("query too complex")
+
+// Execute runs a request's query
+func (s *Schema) Execute(ctx context.Context, req Request) *Response {
+	doc, err := parse(req.Query)
+	if err != nil {
+		return &Response{Errors: []*Error{{Message: err.Error()}}}
+	}
+
+	op, err := doc.operation(req.OperationName)
+	if err != nil {
+		return &Response{Errors: []*Error{{Message: err.Error()}}}
+	}
+	variables, err := op.coerceVariables(req.Variables)
+	if err != nil {
+		return &Response{Errors: []*Error{{Message: err.Error()}}}
+	}
+
+	e := &executor{
+		ctx:         ctx,
+		doc:         doc,
+		variables:   variables,
+		maxResolves: s.MaxResolves,
+	}
+	if e.maxResolves == 0 {
+		e.maxResolves = DefaultMaxResolves
+	}
+	data := e.selectionSet(s.Query, nil, op.selection, nil)
+	if e.aborted != nil {
+		return &Response{Errors: []*Error{{Message: e.aborted.Error()}}}
+	}
+	return &Response{Data: data, Errors: e.errors}
+}
+
+// operation picks the operation to run
+func (d *document) operation(name string) (*operation, error) {
+	if name == "" {
+		if len(d.operations) > 1 {
+			return nil, errors.New("operationName is required with several operations")
+		}
+		return d.operations[0], nil
+	}
+	for _, op := range d.operations {
+		if op.name == name {
+			return op, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown operation %q", name)
+}
+
+// coerceVariables applies defaults and checks non-null variables are set
+func (op *operation) coerceVariables(values map[string]interface{}) (map[string]interface{}, error) {
+	variables := make(map[string]interface{}, len(op.variables))
+	for _, def := range op.variables {
+		value, ok := values[def.name]
+		if !ok {
+			value = substitute(def.defaults, nil)
+		}
+		if value == nil && def.nonNull {
+			return nil, fmt.Errorf("variable $%s is required", def.name)
+		}
+		variables[def.name] = value
+	}
+	return variables, nil
+}
+
+type executor struct {
+	ctx         context.Context
+	doc         *document
+	variables   map[string]interface{}
+	errors      []*Error
+	resolves    int
+	maxResolves int
+	aborted     error // Stops the whole query (nil = running)
+}
+
+// collectedField is a response key and the field selections merged into it
+type collectedField struct {
+	key        string
+	name       string
+	arguments  map[string]interface{}
+	selections []selection
+}
+
+// collectFields flattens fragments and skipped fields into the fields to
+// resolve on an object, merging fields selected under the same key
+func (e *executor) collectFields(object *Object, selections []selection, fields *[]*collectedField, visited map[string]bool) error {
+	for _, sel := range selections {
+		include, err := e.included(sel.directives)
+		if err != nil {
+			return err
+		}
+		if !include {
+			continue
+		}
+
+		switch {
+		case sel.fragment != "":
+			if visited[sel.fragment] {
+				continue
+			}
+			visited[sel.fragment] = true
+			f, ok := e.doc.fragments[sel.fragment]
+			if !ok {
+				return fmt.Errorf("unknown fragment %q", sel.fragment)
+			}
+			if f.typeCondition != object.Name {
+				continue
+			}
+			if err := e.collectFields(object, f.selection, fields, visited); err != nil {
+				return err
+			}
+		case sel.inline:
+			if sel.typeCondition != "" && sel.typeCondition != object.Name {
+				continue
+			}
+			if err := e.collectFields(object, sel.selection, fields, visited); err != nil {
+				return err
+			}
+		default:
+			key := sel.alias
+			if key == "" {
+				key = sel.name
+			}
+			merged := false
+			for _, f := range *fields {
+				if f.key == key {
+					f.selections = append(f.selections, sel.selection...)
+					merged = true
+					break
+				}
+			}
+			if !merged {
+				*fields = append(*fields, &collectedField{
+					key:        key,
+					name:       sel.name,
+					arguments:  sel.arguments,
+					selections: sel.selection,
+				})
+			}
+		}
+	}
+	return nil
+}
+
+// included evaluates @include and @skip
+func (e *executor) included(directives []directive) (bool, error) {
+	for _, d := range directives {
+		if d.name != "include" && d.name != "skip" {
+			return false, fmt.Errorf("unknown directive @%s", d.name)
+		}
+		cond, ok := substitute(d.arguments["if"], e.variables).(bool)
+		if !ok {
+			return false, fmt.Errorf("@%s needs a boolean if argument", d.name)
+		}
+		if cond == (d.name == "skip") {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// selectionSet resolves the selected fields of an object value
+func (e *executor) selectionSet(object *Object, source interface{}, selections []selection, path []interface{}) *OrderedMap {
+	var fields []*collectedField
+	if err := e.collectFields(object, selections, &fields, make(map[string]bool)); err != nil {
+		e.fail(err, path)
+		return nil
+	}
+
+	result := newOrderedMap()
+	for _, f := range fields {
+		if e.aborted != nil {
+			return nil
+		}
+		fieldPath := append(append([]interface{}{}, path...), f.key)
+		if f.name == "__typename" {
+			result.Set(f.key, object.Name)
+			continue
+		}
+
+		field, ok := object.Fields[f.name]
+		if !ok {
+			e.fail(fmt.Errorf("%s has no field %q", object.Name, f.name), fieldPath)
+			result.Set(f.key, nil)
+			continue
+		}
+		if field.Type != nil && len(f.selections) == 0 {
+			e.fail(fmt.Errorf("field %q of %s needs a selection of subfields", f.name, object.Name), fieldPath)
+			result.Set(f.key, nil)
+			continue
+		}
+		if field.Type == nil && len(f.selections) > 0 {
+			e.fail(fmt.Errorf("field %q of %s has no subfields", f.name, object.Name), fieldPath)
+			result.Set(f.key, nil)
+			continue
+		}
+
+		e.resolves++
+		if e.resolves > e.maxResolves {
+			e.aborted = errTooComplex
+			return nil
+		}
+		args := make(Args, len(f.arguments))
+		for name, value := range f.arguments {
+			args[name] = substitute(value, e.variables)
+		}
+		value, err := field.Resolve(e.ctx, source, args)
+		if err != nil {
+			e.fail(err, fieldPath)
+			result.Set(f.key, nil)
+			continue
+		}
+		result.Set(f.key, e.complete(field.Type, value, f.selections, fieldPath))
+	}
+	return result
+}
+
+// complete turns a resolved value into response data, resolving the
+// selected subfields of objects
+func (e *executor) complete(object *Object, value interface{}, selections []selection, path []interface{}) interface{} {
+	if value == nil {
+		return nil
+	}
+	v := reflect.ValueOf(value)
+	if v.Kind() == reflect.Ptr && v.IsNil() {
+		return nil
+	}
+	if object == nil {
+		return value
+	}
+
+	if v.Kind() == reflect.Slice {
+		list := make([]interface{}, v.Len())
+		for i := range list {
+			list[i] = e.complete(object, v.Index(i).Interface(), selections, append(append([]interface{}{}, path...), i))
+		}
+		return list
+	}
+	return e.selectionSet(object, value, selections, path)
+}
+
+// substitute turns an argument literal into its value, replacing variables
+// with their values and enum names with strings
+func substitute(value interface{}, variables map[string]interface{}) interface{} {
+	switch v := value.(type) {
+	case variable:
+		return variables[string(v)]
+	case enumValue:
+		return string(v)
+	case []interface{}:
+		list := make([]interface{}, len(v))
+		for i, item := range v {
+			list[i] = substitute(item, variables)
+		}
+		return list
+	case map[string]interface{}:
+		object := make(map[string]interface{}, len(v))
+		for name, item := range v {
+			object[name] = substitute(item, variables)
+		}
+		return object
+	default:
+		return value
+	}
+}
+
+func (e *executor) fail(err error, path []interface{}) {
+	e.errors = append(e.errors, &Error{Message: err.Error(), Path: path})
+}
@@ -0,0 +1,484 @@
+package graphql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// document is a parsed query document
+type document struct {
+	operations []*operation
+	fragments  map[string]*fragment
+}
+
+type operation struct {
+	name      string
+	variables []variableDefinition
+	selection []selection
+}
+
+type variableDefinition struct {
+	name     string
+	nonNull  bool
+	defaults interface{} // Default value literal (nil = none)
+}
+
+type fragment struct {
+	typeCondition string
+	selection     []selection
+}
+
+// selection is a field, a fragment spread or an inline fragment
+type selection struct {
+	// Field
+	alias, name string
+	arguments   map[string]interface{}
+	selection   []selection
+
+	// Fragment spread (fragment != "") or inline fragment (inline)
+	fragment      string
+	inline        bool
+	typeCondition string
+
+	directives []directive
+}
+
+type directive struct {
+	name      string
+	arguments map[string]interface{}
+}
+
+// variable is a $name reference in a literal, replaced when executing
+type variable string
+
+// enumValue is an unquoted name literal
+type enumValue string
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenPunct
+	tokenName
+	tokenInt
+	tokenFloat
+	tokenString
+)
+
+type token struct {
+	kind  tokenKind
+	value string
+	pos   int
+}
+
+// SyntaxError reports a query that can't be parsed
+type SyntaxError struct {
+	Pos     int // Byte offset in the query
+	Message string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("syntax error at offset %d: %s", e.Pos, e.Message)
+}
+
+type parser struct {
+	src string
+	pos int
+	tok token
+}
+
+func parse(src string) (doc *document, err error) {
+	p := &parser{src: strings.TrimPrefix(src, "\ufeff")}
+	defer func() {
+		if r := recover(); r != nil {
+			syntaxErr, ok := r.(*SyntaxError)
+			if !ok {
+				panic(r)
+			}
+			doc, err = nil, syntaxErr
+		}
+	}()
+
+	p.next()
+	doc = &document{fragments: make(map[string]*fragment)}
+	for p.tok.kind != tokenEOF {
+		switch {
+		case p.peek(tokenPunct, "{"):
+			doc.operations = append(doc.operations, &operation{selection: p.selectionSet()})
+		case p.peek(tokenName, "query"):
+			p.next()
+			op := &operation{}
+			if p.tok.kind == tokenName {
+				op.name = p.name()
+			}
+			if p.peek(tokenPunct, "(") {
+				op.variables = p.variableDefinitions()
+			}
+			p.directives()
+			op.selection = p.selectionSet()
+			doc.operations = append(doc.operations, op)
+		case p.peek(tokenName, "fragment"):
+			p.next()
+			name := p.name()
+			p.expectName("on")
+			f := &fragment{typeCondition: p.name()}
+			p.directives()
+			f.selection = p.selectionSet()
+			doc.fragments[name] = f
+		case p.peek(tokenName, "mutation"), p.peek(tokenName, "subscription"):
+			p.fail("only queries are supported")
+		default:
+			p.fail("expected an operation or fragment")
+		}
+	}
+	if len(doc.operations) == 0 {
+		return nil, &SyntaxError{0, "no operation"}
+	}
+	return doc, nil
+}
+
+func (p *parser) fail(format string, args ...interface{}) {
+	panic(&SyntaxError{p.tok.pos, fmt.Sprintf(format, args...)})
+}
+
+func (p *parser) peek(kind tokenKind, value string) bool {
+	return p.tok.kind == kind && p.tok.value == value
+}
+
+func (p *parser) expect(value string) {
+	if !p.peek(tokenPunct, value) {
+		p.fail("expected %q", value)
+	}
+	p.next()
+}
+
+func (p *parser) expectName(value string) {
+	if !p.peek(tokenName, value) {
+		p.fail("expected %q", value)
+	}
+	p.next()
+}
+
+func (p *parser) name() string {
+	if p.tok.kind != tokenName {
+		p.fail("expected a name")
+	}
+	name := p.tok.value
+	p.next()
+	return name
+}
+
+func (p *parser) variableDefinitions() []variableDefinition {
+	var defs []variableDefinition
+	p.expect("(")
+	for !p.peek(tokenPunct, ")") {
+		p.expect("$")
+		def := variableDefinition{name: p.name()}
+		p.expect(":")
+		def.nonNull = p.typeRef()
+		if p.peek(tokenPunct, "=") {
+			p.next()
+			def.defaults = p.value(true)
+		}
+		defs = append(defs, def)
+	}
+	p.next()
+	return defs
+}
+
+// typeRef skips a type reference, returning whether it is non-null.
+// Variables are coerced by the resolvers reading them.
+func (p *parser) typeRef() bool {
+	if p.peek(tokenPunct, "[") {
+		p.next()
+		p.typeRef()
+		p.expect("]")
+	} else {
+		p.name()
+	}
+	if p.peek(tokenPunct, "!") {
+		p.next()
+		return true
+	}
+	return false
+}
+
+func (p *parser) selectionSet() []selection {
+	var selections []selection
+	p.expect("{")
+	for !p.peek(tokenPunct, "}") {
+		selections = append(selections, p.selection())
+	}
+	p.next()
+	if len(selections) == 0 {
+		p.fail("empty selection set")
+	}
+	return selections
+}
+
+func (p *parser) selection() selection {
+	if p.peek(tokenPunct, "...") {
+		p.next()
+		if p.tok.kind == tokenName && p.tok.value != "on" {
+			return selection{fragment: p.name(), directives: p.directives()}
+		}
+		s := selection{inline: true}
+		if p.peek(tokenName, "on") {
+			p.next()
+			s.typeCondition = p.name()
+		}
+		s.directives = p.directives()
+		s.selection = p.selectionSet()
+		return s
+	}
+
+	s := selection{name: p.name()}
+	if p.peek(tokenPunct, ":") {
+		p.next()
+		s.alias, s.name = s.name, p.name()
+	}
+	if p.peek(tokenPunct, "(") {
+		s.arguments = p.arguments()
+	}
+	s.directives = p.directives()
+	if p.peek(tokenPunct, "{") {
+		s.selection = p.selectionSet()
+	}
+	return s
+}
+
+func (p *parser) arguments() map[string]interface{} {
+	args := make(map[string]interface{})
+	p.expect("(")
+	for !p.peek(tokenPunct, ")") {
+		name := p.name()
+		p.expect(":")
+		args[name] = p.value(false)
+	}
+	p.next()
+	return args
+}
+
+func (p *parser) directives() []directive {
+	var directives []directive
+	for p.peek(tokenPunct, "@") {
+		p.next()
+		d := directive{name: p.name()}
+		if p.peek(tokenPunct, "(") {
+			d.arguments = p.arguments()
+		}
+		directives = append(directives, d)
+	}
+	return directives
+}
+
+// value parses a literal; constant literals (defaults) can't hold variables
+func (p *parser) value(constant bool) interface{} {
+	tok := p.tok
+	switch tok.kind {
+	case tokenPunct:
+		switch tok.value {
+		case "$":
+			if constant {
+				p.fail("variables aren't allowed here")
+			}
+			p.next()
+			return variable(p.name())
+		case "[":
+			p.next()
+			list := []interface{}{}
+			for !p.peek(tokenPunct, "]") {
+				list = append(list, p.value(constant))
+			}
+			p.next()
+			return list
+		case "{":
+			p.next()
+			object := make(map[string]interface{})
+			for !p.peek(tokenPunct, "}") {
+				name := p.name()
+				p.expect(":")
+				object[name] = p.value(constant)
+			}
+			p.next()
+			return object
+		}
+	case tokenInt:
+		p.next()
+		n, err := strconv.Atoi(tok.value)
+		if err != nil {
+			panic(&SyntaxError{tok.pos, "integer out of range"})
+		}
+		return n
+	case tokenFloat:
+		p.next()
+		f, _ := strconv.ParseFloat(tok.value, 64)
+		return f
+	case tokenString:
+		p.next()
+		return tok.value
+	case tokenName:
+		p.next()
+		switch tok.value {
+		case "true":
+			return true
+		case "false":
+			return false
+		case "null":
+			return nil
+		}
+		return enumValue(tok.value)
+	}
+	p.fail("expected a value")
+	return nil
+}
+
+// next reads the next token, skipping whitespace, commas and comments
+func (p *parser) next() {
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		if c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ',' {
+			p.pos++
+			continue
+		}
+		if c == '#' {
+			for p.pos < len(p.src) && p.src[p.pos] != '\n' && p.src[p.pos] != '\r' {
+				p.pos++
+			}
+			continue
+		}
+		break
+	}
+
+	start := p.pos
+	if p.pos >= len(p.src) {
+		p.tok = token{tokenEOF, "", start}
+		return
+	}
+
+	c := p.src[p.pos]
+	switch {
+	case strings.HasPrefix(p.src[p.pos:], "..."):
+		p.pos += 3
+		p.tok = token{tokenPunct, "...", start}
+	case strings.IndexByte("!$():=@[]{}|", c) >= 0:
+		p.pos++
+		p.tok = token{tokenPunct, string(c), start}
+	case c == '_' || isLetter(c):
+		for p.pos < len(p.src) && (p.src[p.pos] == '_' || isLetter(p.src[p.pos]) || isDigit(p.src[p.pos])) {
+			p.pos++
+		}
+		p.tok = token{tokenName, p.src[start:p.pos], start}
+	case c == '-' || isDigit(c):
+		p.number()
+	case c == '"':
+		p.string()
+	default:
+		p.tok = token{tokenPunct, string(c), start}
+		p.fail("unexpected character %q", c)
+	}
+}
+
+func (p *parser) number() {
+	start := p.pos
+	kind := tokenInt
+	if p.src[p.pos] == '-' {
+		p.pos++
+	}
+	digits := func() {
+		from := p.pos
+		for p.pos < len(p.src) && isDigit(p.src[p.pos]) {
+			p.pos++
+		}
+		if p.pos == from {
+			panic(&SyntaxError{start, "malformed number"})
+		}
+	}
+	digits()
+	if p.pos < len(p.src) && p.src[p.pos] == '.' {
+		kind = tokenFloat
+		p.pos++
+		digits()
+	}
+	if p.pos < len(p.src) && (p.src[p.pos] == 'e' || p.src[p.pos] == 'E') {
+		kind = tokenFloat
+		p.pos++
+		if p.pos < len(p.src) && (p.src[p.pos] == '+' || p.src[p.pos] == '-') {
+			p.pos++
+		}
+		digits()
+	}
+	p.tok = token{kind, p.src[start:p.pos], start}
+}
+
+func (p *parser) string() {
+	start := p.pos
+	if strings.HasPrefix(p.src[p.pos:], `"""`) {
+		end := strings.Index(p.src[p.pos+3:], `"""`)
+		if end < 0 {
+			panic(&SyntaxError{start, "unterminated string"})
+		}
+		p.tok = token{tokenString, strings.TrimSpace(p.src[p.pos+3 : p.pos+3+end]), start}
+		p.pos += end + 6
+		return
+	}
+
+	var b strings.Builder
+	p.pos++
+	for {
+		if p.pos >= len(p.src) || p.src[p.pos] == '\n' {
+			panic(&SyntaxError{start, "unterminated string"})
+		}
+		c := p.src[p.pos]
+		if c == '"' {
+			p.pos++
+			break
+		}
+		if c != '\\' {
+			b.WriteByte(c)
+			p.pos++
+			continue
+		}
+		if p.pos+1 >= len(p.src) {
+			panic(&SyntaxError{start, "unterminated string"})
+		}
+		escape := p.src[p.pos+1]
+		p.pos += 2
+		switch escape {
+		case '"', '\\', '/':
+			b.WriteByte(escape)
+		case 'b':
+			b.WriteByte('\b')
+		case 'f':
+			b.WriteByte('\f')
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 't':
+			b.WriteByte('\t')
+		case 'u':
+			if p.pos+4 > len(p.src) {
+				panic(&SyntaxError{start, "malformed unicode escape"})
+			}
+			code, err := strconv.ParseUint(p.src[p.pos:p.pos+4], 16, 32)
+			if err != nil {
+				panic(&SyntaxError{start, "malformed unicode escape"})
+			}
+			b.WriteRune(rune(code))
+			p.pos += 4
+		default:
+			panic(&SyntaxError{start, fmt.Sprintf("unknown escape \\%c", escape)})
+		}
+	}
+	p.tok = token{tokenString, b.String(), start}
+}
+
+func isLetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
@@ -0,0 +1,202 @@
+// Package heartbeat tells supervisors whether a background binary is still
+// making progress, not just running. The binary's main loop calls Beat;
+// while the last beat is recent, a Monitor keeps touching a file, answers
+// an HTTP liveness probe with 200 and pings the systemd watchdog. Once it
+// goes stale the file ages, the probe answers 503 and the watchdog pings
+// stop, so the supervisor can restart the process.
+package heartbeat
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// Config holds where a Monitor reports liveness
+type Config struct {
+	File       string        // Touched while healthy (empty = no file)
+	Port       int           // Serves GET /healthz on this port (0 = no server)
+	StaleAfter time.Duration // Unhealthy once the last beat is this old
+}
+
+// Monitor tracks a binary's heartbeat
+type Monitor struct {
+	name string
+	cfg  Config
+	last atomic.Int64 // Unix nanoseconds of the last beat
+
+	stale bool // Last state reported, so only changes are logged
+
+	// systemd's notification socket and watchdog interval, when the
+	// service was started with Type=notify and WatchdogSec
+	notifySocket string
+	watchdog     time.Duration
+}
+
+// New returns a monitor for the named binary. Starting up counts as its
+// first beat.
+func New(name string, cfg Config) *Monitor {
+	m := &Monitor{
+		name:         name,
+		cfg:          cfg,
+		notifySocket: os.Getenv("NOTIFY_SOCKET"),
+	}
+	if usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64); err == nil && usec > 0 {
+		m.watchdog = time.Duration(usec) * time.Microsecond
+	}
+	m.Beat()
+	return m
+}
+
+// Beat records that the main loop made progress. It is cheap enough to
+// call for every event.
+func (m *Monitor) Beat() {
+	m.last.Store(time.Now().UnixNano())
+}
+
+// LastBeat returns when Beat was last called
+func (m *Monitor) LastBeat() time.Time {
+	return time.Unix(0, m.last.Load())
+}
+
+// Healthy reports whether the last beat is recent enough
+func (m *Monitor) Healthy() bool {
+	return time.Since(m.LastBeat()) < m.cfg.StaleAfter
+}
+
+// Start reports liveness until ctx is done. It fails only if the liveness
+// port can't be opened; with no file, port or systemd socket it does
+// nothing.
+func (m *Monitor) Start(ctx context.Context) error {
+	if m.cfg.File == "" && m.cfg.Port == 0 && m.notifySocket == "" {
+		return nil
+	}
+
+	if m.cfg.Port > 0 {
+		listener, err := net.Listen("tcp", fmt.Sprintf(":%d", m.cfg.Port))
+		if err != nil {
+			return fmt.Errorf("failed to open liveness port: %w", err)
+		}
+		mux := http.NewServeMux()
+		mux.HandleFunc("GET /healthz", m.handleHealthz)
+		server := &http.Server{Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+		go func() {
+			if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				log.Printf("[HEARTBEAT] Liveness server stopped: %v", err)
+			}
+		}()
+		go func() {
+			<-ctx.Done()
+			server.Close()
+		}()
+	}
+
+	log.Printf("[HEARTBEAT] %s is stale after %v (file: %q, port: %d, systemd: %v)",
+		m.name, m.cfg.StaleAfter, m.cfg.File, m.cfg.Port, m.notifySocket != "")
+	m.notify("READY=1")
+	m.report()
+	go m.run(ctx)
+	return nil
+}
+
+// run refreshes the file and watchdog several times per stale period, so
+// a healthy process never looks stale between refreshes
+func (m *Monitor) run(ctx context.Context) {
+	interval := m.cfg.StaleAfter / 4
+	if m.watchdog > 0 && m.watchdog/2 < interval {
+		interval = m.watchdog / 2
+	}
+	if interval < time.Second {
+		interval = time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			m.notify("STOPPING=1")
+			if m.cfg.File != "" {
+				os.Remove(m.cfg.File)
+			}
+			return
+		case <-ticker.C:
+			m.report()
+		}
+	}
+}
+
+// report touches the file and pings the watchdog if healthy
+func (m *Monitor) report() {
+	if !m.Healthy() {
+		if !m.stale {
+			log.Printf("[HEARTBEAT] %s stale: no progress since %s", m.name, m.LastBeat().Format(time.RFC3339))
+			m.notify("STATUS=No progress since " + m.LastBeat().UTC().Format(time.RFC3339))
+			m.stale = true
+		}
+		return
+	}
+	if m.stale {
+		log.Printf("[HEARTBEAT] %s making progress again", m.name)
+		m.notify("STATUS=")
+		m.stale = false
+	}
+	if m.cfg.File != "" {
+		if err := os.WriteFile(m.cfg.File, []byte(m.LastBeat().UTC().Format(time.RFC3339)+"\n"), 0644); err != nil {
+			log.Printf("[HEARTBEAT] Failed to write %s: %v", m.cfg.File, err)
+		}
+	}
+	m.notify("WATCHDOG=1")
+}
+
+// notify sends a state to systemd (sd_notify), if it is listening
+func (m *Monitor) notify(state string) {
+	if m.notifySocket == "" {
+		return
+	}
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: m.notifySocket, Net: "unixgram"})
+	if err != nil {
+		log.Printf("[HEARTBEAT] Failed to notify systemd: %v", err)
+		return
+	}
+	defer conn.Close()
+	if _, err := conn.Write([]byte(state)); err != nil {
+		log.Printf("[HEARTBEAT] Failed to notify systemd: %v", err)
+	}
+}
+
+// Status is the liveness probe's response
+type Status struct {
+	Status            string    `json:"status"` // "ok" or "stale"
+	LastBeat          time.Time `json:"last_beat"`
+	AgeSeconds        float64   `json:"age_seconds"`
+	StaleAfterSeconds float64   `json:"stale_after_seconds"`
+}
+
+// handleHealthz answers 200 while healthy and 503 once stale
+func (m *Monitor) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	last := m.LastBeat()
+	status := Status{
+		Status:            "ok",
+		LastBeat:          last.UTC(),
+		AgeSeconds:        time.Since(last).Seconds(),
+		StaleAfterSeconds: m.cfg.StaleAfter.Seconds(),
+	}
+	code := http.StatusOK
+	if !m.Healthy() {
+		status.Status = "stale"
+		code = http.StatusServiceUnavailable
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(status)
+}
@@ -0,0 +1,212 @@
+// Package i18n translates the human-readable strings of pages and API
+// responses. Message catalogs are embedded JSON files, one per locale, and
+// requests are matched to a locale by their Accept-Language header.
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/text/language"
+)
+
+// DefaultLocale is the source locale: every message id has an English text
+const DefaultLocale = "en"
+
+// jsPrefix marks messages used by the browser scripts
+const jsPrefix = "js."
+
+//go:embed locales/*.json
+var localeFiles embed.FS
+
+// message is a catalog entry: a plain text, or plural forms where {count}
+// stands for the number
+type message struct {
+	Text  string
+	One   string
+	Other string
+}
+
+func (m *message) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &m.Text); err == nil {
+		return nil
+	}
+	var forms struct {
+		One   string `json:"one"`
+		Other string `json:"other"`
+	}
+	if err := json.Unmarshal(data, &forms); err != nil {
+		return fmt.Errorf("message must be a string or {\"one\", \"other\"}: %w", err)
+	}
+	m.One, m.Other = forms.One, forms.Other
+	return nil
+}
+
+type catalog map[string]message
+
+// Bundle holds the catalogs of every available locale
+type Bundle struct {
+	catalogs map[string]catalog
+	locales  []string // Default first
+	matcher  language.Matcher
+	fallback string
+}
+
+// New loads the embedded catalogs. defaultLocale is served when nothing
+// better matches a request; it must be one of the embedded locales.
+func New(defaultLocale string) (*Bundle, error) {
+	files, err := localeFiles.ReadDir("locales")
+	if err != nil {
+		return nil, err
+	}
+
+	b := &Bundle{catalogs: make(map[string]catalog), fallback: defaultLocale}
+	for _, file := range files {
+		data, err := localeFiles.ReadFile(path.Join("locales", file.Name()))
+		if err != nil {
+			return nil, err
+		}
+		var c catalog
+		if err := json.Unmarshal(data, &c); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", file.Name(), err)
+		}
+		b.catalogs[strings.TrimSuffix(file.Name(), ".json")] = c
+	}
+	if _, ok := b.catalogs[defaultLocale]; !ok {
+		return nil, fmt.Errorf("unknown locale %q (available: %s)", defaultLocale, strings.Join(b.Locales(), ", "))
+	}
+
+	// The matcher falls back to its first tag
+	b.locales = []string{defaultLocale}
+	for _, locale := range b.Locales() {
+		if locale != defaultLocale {
+			b.locales = append(b.locales, locale)
+		}
+	}
+	tags := make([]language.Tag, len(b.locales))
+	for i, locale := range b.locales {
+		tags[i] = language.Make(locale)
+	}
+	b.matcher = language.NewMatcher(tags)
+	return b, nil
+}
+
+// Locales lists the available locales in alphabetical order
+func (b *Bundle) Locales() []string {
+	locales := make([]string, 0, len(b.catalogs))
+	for locale := range b.catalogs {
+		locales = append(locales, locale)
+	}
+	sort.Strings(locales)
+	return locales
+}
+
+// Match returns a localizer for the best available locale, or the default
+// locale if none is close. Each argument is a locale or an Accept-Language
+// header; earlier ones take precedence.
+func (b *Bundle) Match(preferences ...string) *Localizer {
+	_, index := language.MatchStrings(b.matcher, preferences...)
+	return b.Localizer(b.locales[index])
+}
+
+// Localizer returns the localizer for an available locale
+func (b *Bundle) Localizer(locale string) *Localizer {
+	return &Localizer{bundle: b, locale: locale}
+}
+
+// Localizer translates messages into one locale, falling back to the
+// bundle's default locale and then English for messages it lacks
+type Localizer struct {
+	bundle *Bundle
+	locale string
+}
+
+// Locale is the localizer's locale, for Content-Language and <html lang>
+func (l *Localizer) Locale() string {
+	return l.locale
+}
+
+func (l *Localizer) lookup(id string) (message, bool) {
+	for _, locale := range []string{l.locale, l.bundle.fallback, DefaultLocale} {
+		if m, ok := l.bundle.catalogs[locale][id]; ok {
+			return m, true
+		}
+	}
+	return message{}, false
+}
+
+// T translates a message, formatting args into it with fmt verbs. Unknown
+// ids are returned as they are, so a missing translation shows up on the
+// page rather than failing it.
+func (l *Localizer) T(id string, args ...interface{}) string {
+	m, ok := l.lookup(id)
+	if !ok {
+		return id
+	}
+	text := m.Text
+	if text == "" {
+		text = m.Other
+	}
+	if len(args) == 0 {
+		return text
+	}
+	return fmt.Sprintf(text, args...)
+}
+
+// N translates a message with plural forms for count, replacing {count}
+// and formatting args as T does
+func (l *Localizer) N(id string, count int, args ...interface{}) string {
+	m, ok := l.lookup(id)
+	if !ok {
+		return id
+	}
+	// The embedded locales all use one form for 1 and another for the rest
+	text := m.Other
+	if count == 1 && m.One != "" {
+		text = m.One
+	}
+	if text == "" {
+		text = m.Text
+	}
+	text = strings.ReplaceAll(text, "{count}", strconv.Itoa(count))
+	if len(args) == 0 {
+		return text
+	}
+	return fmt.Sprintf(text, args...)
+}
+
+// Ago describes how long before now t was ("5 minutes ago"), or gives the
+// date in t's location beyond a month
+func (l *Localizer) Ago(t, now time.Time) string {
+	elapsed := now.Sub(t)
+	switch {
+	case elapsed < time.Minute:
+		return l.T("ago_now")
+	case elapsed < time.Hour:
+		return l.N("ago_minutes", int(elapsed/time.Minute))
+	case elapsed < 24*time.Hour:
+		return l.N("ago_hours", int(elapsed/time.Hour))
+	case elapsed < 30*24*time.Hour:
+		return l.N("ago_days", int(elapsed/(24*time.Hour)))
+	default:
+		return t.Format("2006-01-02")
+	}
+}
+
+// JSMessages returns the messages used by the browser scripts, keyed
+// without their "js." prefix
+func (l *Localizer) JSMessages() map[string]string {
+	messages := make(map[string]string)
+	for id := range l.bundle.catalogs[DefaultLocale] {
+		if strings.HasPrefix(id, jsPrefix) {
+			messages[strings.TrimPrefix(id, jsPrefix)] = l.T(id)
+		}
+	}
+	return messages
+}
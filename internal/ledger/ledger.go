@@ -0,0 +1,115 @@
+// Package ledger tracks which firehose events have already been ingested so
+// replays from an older cursor are idempotent.
+//
+// Jetstream time_us values increase across the stream, so the newest
+// processed time_us per DID is enough to recognize a replayed event. The
+// ledger is loaded into memory at startup; the in-memory copy is
+// authoritative and changes are written in batches by Flush, alongside the
+// saved cursor, to keep database writes off the per-event path.
+package ledger
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/database"
+)
+
+// store is where the ledger is kept; *database.DB in production
+type store interface {
+	GetIngestLedger() (map[string]int64, error)
+	RecordIngestedEvents(latest map[string]int64) error
+}
+
+var _ store = (*database.DB)(nil)
+
+// Ledger is the per-DID high-water mark of ingested events
+type Ledger struct {
+	db store
+
+	mu     sync.RWMutex
+	latest map[string]int64
+	dirty  map[string]int64 // Entries changed since the last Flush
+
+	duplicates atomic.Int64
+}
+
+// Load reads the stored ledger
+func Load(db *database.DB) (*Ledger, error) {
+	return load(db)
+}
+
+func load(db store) (*Ledger, error) {
+	latest, err := db.GetIngestLedger()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load ingest ledger: %w", err)
+	}
+	return &Ledger{db: db, latest: latest, dirty: make(map[string]int64)}, nil
+}
+
+// Seen reports whether an event from did at timeUS was already ingested,
+// counting it as a duplicate if so
+func (l *Ledger) Seen(did string, timeUS int64) bool {
+	l.mu.RLock()
+	latest, ok := l.latest[did]
+	l.mu.RUnlock()
+
+	if ok && timeUS <= latest {
+		l.duplicates.Add(1)
+		return true
+	}
+	return false
+}
+
+// Record marks an event as ingested. Call it once the event is processed or
+// handed to the retry queue; it is stored by the next Flush.
+func (l *Ledger) Record(did string, timeUS int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if timeUS > l.latest[did] {
+		l.latest[did] = timeUS
+		l.dirty[did] = timeUS
+	}
+}
+
+// Flush stores the entries recorded since the last Flush. Call it before
+// saving the cursor: events recorded but not flushed when the process dies
+// are processed again on replay. On failure the entries are kept for the
+// next Flush.
+//
+// heldFrom is the time_us of the oldest event the cursor is held before
+// (0 if none). Entries at or after it stay unflushed: the held event is
+// replayed after a restart, and a stored high-water mark past it would make
+// Seen skip it.
+func (l *Ledger) Flush(heldFrom int64) error {
+	l.mu.Lock()
+	dirty := l.dirty
+	l.dirty = make(map[string]int64)
+	if heldFrom > 0 {
+		for did, timeUS := range dirty {
+			if timeUS >= heldFrom {
+				l.dirty[did] = timeUS
+				delete(dirty, did)
+			}
+		}
+	}
+	l.mu.Unlock()
+
+	if err := l.db.RecordIngestedEvents(dirty); err != nil {
+		l.mu.Lock()
+		for did, timeUS := range dirty {
+			if timeUS > l.dirty[did] {
+				l.dirty[did] = timeUS
+			}
+		}
+		l.mu.Unlock()
+		return fmt.Errorf("failed to store ingest ledger: %w", err)
+	}
+	return nil
+}
+
+// Duplicates returns how many replayed events have been skipped
+func (l *Ledger) Duplicates() int64 {
+	return l.duplicates.Load()
+}
@@ -0,0 +1,30 @@
+package maintenance
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/database"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/scheduler"
+)
+
+// ScheduleTrendingCredit registers a job crediting sharers of links that
+// reach threshold shares in their account stats, checking every 5 minutes
+func ScheduleTrendingCredit(sched *scheduler.Scheduler, db *database.DB, threshold int) {
+	if threshold <= 0 {
+		log.Println("[STATS] Trending credit for sharers disabled (threshold <= 0)")
+		return
+	}
+
+	sched.Every("trending-credit", 5*time.Minute, true, func(ctx context.Context) error {
+		n, err := db.CreditTrendingSharers(threshold)
+		if err != nil {
+			return err
+		}
+		if n > 0 {
+			log.Printf("[STATS] Credited sharers of %d newly trending links", n)
+		}
+		return nil
+	})
+}
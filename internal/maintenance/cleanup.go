@@ -2,11 +2,13 @@
 package maintenance
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"time"
 
 	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/database"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/scheduler"
 )
 
 // Config holds cleanup configuration
@@ -15,6 +17,16 @@ type Config struct {
 	TrendingThreshold    int // Minimum shares to keep a link regardless of age
 	CleanupIntervalMin   int // How often to run periodic cleanup
 	CursorUpdateInterval int // Seconds between cursor updates
+
+	MaxPosts       int // Hard cap on stored posts (<= 0 = no cap)
+	MaxLinks       int // Hard cap on stored links (<= 0 = no cap)
+	WarnDatabaseMB int // Database size that triggers a warning (<= 0 = no check)
+
+	VacuumMinDeleted int // Rows a cleanup must delete to VACUUM (ANALYZE) afterwards (< 0 = never)
+
+	PartitionAheadDays int // Days of posts partitions created ahead of today, when posts is partitioned
+
+	LinkExemptTables []string // Tables whose link_id references keep a link from being deleted
 }
 
 // StartupCleanup performs database cleanup on service startup
@@ -26,11 +38,17 @@ func StartupCleanup(db *database.DB, config Config) error {
 	cutoff := time.Now().Add(-time.Duration(config.RetentionHours) * time.Hour)
 	log.Printf("[STARTUP] Cutoff time: %v (%dh ago)", cutoff, config.RetentionHours)
 
-	// 1. Delete posts older than retention period
+	// 1. Delete posts older than retention period, whole days at a time
+	// when posts is partitioned
+	partitionDropped, err := ManagePostPartitions(db, cutoff, config.PartitionAheadDays)
+	if err != nil {
+		return fmt.Errorf("failed to manage posts partitions: %w", err)
+	}
 	postsDeleted, err := db.DeleteOldPosts(cutoff)
 	if err != nil {
 		return fmt.Errorf("failed to delete old posts: %w", err)
 	}
+	postsDeleted += partitionDropped
 	log.Printf("[STARTUP] ✓ Deleted %d old posts (>%dh)", postsDeleted, config.RetentionHours)
 
 	// 2. Delete orphaned post_links (safety cleanup)
@@ -43,13 +61,29 @@ func StartupCleanup(db *database.DB, config Config) error {
 	}
 
 	// 3. Delete links with no recent shares (except trending)
-	linksDeleted, err := db.DeleteUnsharedLinks(cutoff, config.TrendingThreshold)
+	linksDeleted, err := db.DeleteUnsharedLinks(cutoff, config.TrendingThreshold, config.LinkExemptTables)
 	if err != nil {
 		return fmt.Errorf("failed to delete unshared links: %w", err)
 	}
 	log.Printf("[STARTUP] ✓ Deleted %d unshared links (keeping trending with %d+ shares)",
 		linksDeleted, config.TrendingThreshold)
 
+	// 4. Trim tables still over their caps
+	quota, err := EnforceQuotas(db, config)
+	if err != nil {
+		return fmt.Errorf("failed to enforce quotas: %w", err)
+	}
+	if quota.PostsDeleted > 0 || quota.LinksDeleted > 0 {
+		log.Printf("[STARTUP] ✓ Deleted %d posts and %d links over quota", quota.PostsDeleted, quota.LinksDeleted)
+	}
+
+	// 5. Reclaim space and refresh statistics after large deletes (dropped
+	// partitions leave nothing to vacuum)
+	deleted := postsDeleted - partitionDropped + orphansDeleted + linksDeleted + quota.PostsDeleted + quota.LinksDeleted
+	if err := VacuumAfterCleanup(db, config.VacuumMinDeleted, deleted); err != nil {
+		return fmt.Errorf("failed to vacuum: %w", err)
+	}
+
 	duration := time.Since(startTime)
 	log.Printf("[STARTUP] Cleanup complete in %v", duration)
 	return nil
@@ -62,39 +96,67 @@ func PeriodicCleanup(db *database.DB, config Config) error {
 
 	cutoff := time.Now().Add(-time.Duration(config.RetentionHours) * time.Hour)
 
-	// 1. Delete old posts
+	// 1. Delete old posts, whole days at a time when posts is partitioned
+	partitionDropped, err := ManagePostPartitions(db, cutoff, config.PartitionAheadDays)
+	if err != nil {
+		return fmt.Errorf("failed to manage posts partitions: %w", err)
+	}
 	postsDeleted, err := db.DeleteOldPosts(cutoff)
 	if err != nil {
 		return fmt.Errorf("failed to delete old posts: %w", err)
 	}
+	postsDeleted += partitionDropped
 
 	// 2. Delete unshared links (except trending)
-	linksDeleted, err := db.DeleteUnsharedLinks(cutoff, config.TrendingThreshold)
+	linksDeleted, err := db.DeleteUnsharedLinks(cutoff, config.TrendingThreshold, config.LinkExemptTables)
 	if err != nil {
 		return fmt.Errorf("failed to delete unshared links: %w", err)
 	}
 
+	// 3. Delete delivered-or-expired outbox events
+	eventsDeleted, err := db.DeleteOldOutboxEvents(cutoff)
+	if err != nil {
+		return fmt.Errorf("failed to delete old outbox events: %w", err)
+	}
+
+	// 4. Delete scrape attempts and ranking comparisons past their windows
+	if _, err := db.DeleteOldScrapeStats(time.Now().Add(-database.ScrapeStatsRetention)); err != nil {
+		return fmt.Errorf("failed to delete old scrape stats: %w", err)
+	}
+	if _, err := db.DeleteOldRankingComparisons(time.Now().Add(-database.RankingComparisonRetention)); err != nil {
+		return fmt.Errorf("failed to delete old ranking comparisons: %w", err)
+	}
+
+	// 5. Trim tables still over their caps
+	quota, err := EnforceQuotas(db, config)
+	if err != nil {
+		return fmt.Errorf("failed to enforce quotas: %w", err)
+	}
+	postsDeleted += quota.PostsDeleted
+	linksDeleted += quota.LinksDeleted
+
+	// 6. Reclaim space and refresh statistics after large deletes (dropped
+	// partitions leave nothing to vacuum)
+	if err := VacuumAfterCleanup(db, config.VacuumMinDeleted, postsDeleted-partitionDropped+linksDeleted); err != nil {
+		return fmt.Errorf("failed to vacuum: %w", err)
+	}
+
 	duration := time.Since(startTime)
-	log.Printf("[CLEANUP] Deleted %d posts, %d links in %v", postsDeleted, linksDeleted, duration)
+	log.Printf("[CLEANUP] Deleted %d posts, %d links, %d outbox events in %v", postsDeleted, linksDeleted, eventsDeleted, duration)
 	return nil
 }
 
-// StartCleanupTicker starts a background goroutine that runs periodic cleanup
-func StartCleanupTicker(db *database.DB, config Config) {
+// ScheduleCleanup registers periodic cleanup with the scheduler, so it runs
+// on only one instance
+func ScheduleCleanup(sched *scheduler.Scheduler, db *database.DB, config Config) {
 	if config.CleanupIntervalMin <= 0 {
 		log.Println("[CLEANUP] Periodic cleanup disabled (interval <= 0)")
 		return
 	}
 
 	interval := time.Duration(config.CleanupIntervalMin) * time.Minute
-	ticker := time.NewTicker(interval)
-
-	go func() {
-		log.Printf("[CLEANUP] Started periodic cleanup (interval: %v)", interval)
-		for range ticker.C {
-			if err := PeriodicCleanup(db, config); err != nil {
-				log.Printf("[CLEANUP] Error: %v", err)
-			}
-		}
-	}()
+	log.Printf("[CLEANUP] Scheduled periodic cleanup (interval: %v)", interval)
+	sched.Every("cleanup", interval, false, func(ctx context.Context) error {
+		return PeriodicCleanup(db, config)
+	})
 }
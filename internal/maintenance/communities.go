@@ -0,0 +1,125 @@
+package maintenance
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/community"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/database"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/scheduler"
+)
+
+// hubShare is the fraction of 1st-degree accounts following a 2nd-degree
+// account above which its follows are left out of community detection:
+// an account nearly everyone follows would pull everyone into one community
+const hubShare = 0.5
+
+// labelMembers is how many handles a generated community label names
+const labelMembers = 3
+
+// CommunityConfig holds settings for community detection
+type CommunityConfig struct {
+	IntervalHours int // How often communities are re-detected (<= 0 disables)
+	MinSize       int // Smaller groups get no community
+}
+
+// DetectCommunities groups network accounts into communities from the
+// follow graph and stores them. Returns the number of communities.
+func DetectCommunities(db *database.DB, config CommunityConfig) (int, error) {
+	accounts, err := db.GetAllNetworkAccounts()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get network accounts: %w", err)
+	}
+
+	firstDegree := 0
+	handles := make(map[string]string, len(accounts))
+	for _, account := range accounts {
+		handles[account.DID] = account.Handle
+		if account.Degree == 1 {
+			firstDegree++
+		}
+	}
+
+	// Edges from 1st-degree accounts to the 2nd-degree accounts they follow
+	graph := community.NewGraph()
+	for _, account := range accounts {
+		if account.SourceDIDs == nil {
+			continue
+		}
+		var sources []string
+		if err := json.Unmarshal([]byte(*account.SourceDIDs), &sources); err != nil {
+			continue // Malformed source list: no edges
+		}
+		if float64(len(sources)) > hubShare*float64(firstDegree) {
+			continue
+		}
+		for _, source := range sources {
+			graph.AddEdge(source, account.DID)
+		}
+	}
+
+	detected := graph.Detect(config.MinSize)
+	previous, err := db.GetCommunityMembership()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get communities: %w", err)
+	}
+	ids := community.Match(previous, detected)
+
+	assignments := make([]database.CommunityAssignment, len(detected))
+	for i, c := range detected {
+		assignments[i] = database.CommunityAssignment{
+			ID:      ids[i],
+			Label:   communityLabel(c.Members, handles),
+			Members: c.Members,
+		}
+	}
+	if err := db.ReplaceCommunities(assignments); err != nil {
+		return 0, err
+	}
+	return len(detected), nil
+}
+
+// communityLabel names a community after its best connected members, e.g.
+// "Around @alice.bsky.social, @bob.example.com and @carol.bsky.social"
+func communityLabel(members []string, handles map[string]string) string {
+	var names []string
+	for _, did := range members {
+		if handle := handles[did]; handle != "" {
+			names = append(names, "@"+handle)
+		}
+		if len(names) == labelMembers {
+			break
+		}
+	}
+	switch len(names) {
+	case 0:
+		return fmt.Sprintf("%d accounts", len(members))
+	case 1:
+		return "Around " + names[0]
+	default:
+		return "Around " + strings.Join(names[:len(names)-1], ", ") + " and " + names[len(names)-1]
+	}
+}
+
+// ScheduleCommunityDetection registers periodic community detection with
+// the scheduler
+func ScheduleCommunityDetection(sched *scheduler.Scheduler, db *database.DB, config CommunityConfig) {
+	if config.IntervalHours <= 0 {
+		log.Println("[COMMUNITIES] Community detection disabled (interval <= 0)")
+		return
+	}
+
+	log.Printf("[COMMUNITIES] Scheduled community detection every %d hours", config.IntervalHours)
+	sched.Every("community-detection", time.Duration(config.IntervalHours)*time.Hour, true, func(ctx context.Context) error {
+		n, err := DetectCommunities(db, config)
+		if err != nil {
+			return err
+		}
+		log.Printf("[COMMUNITIES] Detected %d communities", n)
+		return nil
+	})
+}
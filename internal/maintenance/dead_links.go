@@ -0,0 +1,112 @@
+package maintenance
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/database"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/enrich"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/scheduler"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/scraper"
+)
+
+// DeadLinkConfig holds settings for the dead-link sweep
+type DeadLinkConfig struct {
+	IntervalMin  int           // How often links are checked (<= 0 disables)
+	Top          int           // Top trending links checked each run
+	Sample       int           // Random links first seen within Hours checked each run
+	Hours        int           // Trending window, and how recent sampled links are
+	RecheckAfter time.Duration // A link is checked at most once per this long
+	Options      database.TrendingOptions
+}
+
+// DeadLinkResult reports what a sweep found
+type DeadLinkResult struct {
+	Checked int
+	Dead    int
+}
+
+// CheckDeadLinks HEAD-checks the top trending links and a random sample of
+// recent ones, recording each status. Links answering 404 or 410, or
+// unreachable on two checks in a row, are marked dead; dead links that
+// answer again are revived. Refusals (bot walls) are not treated as dead.
+func CheckDeadLinks(db *database.DB, sc *scraper.Scraper, config DeadLinkConfig) (DeadLinkResult, error) {
+	var result DeadLinkResult
+
+	var ids []int
+	if config.Top > 0 {
+		opts := config.Options
+		opts.HideDead = false // Dead links are rechecked so they can come back
+		trending, err := db.GetTrendingLinks(config.Hours, config.Top, opts)
+		if err != nil {
+			return result, fmt.Errorf("failed to get trending links: %w", err)
+		}
+		for _, link := range trending {
+			ids = append(ids, link.ID)
+		}
+	}
+
+	now := time.Now()
+	links, err := db.GetLinksForStatusCheck(ids, config.Sample, now.Add(-time.Duration(config.Hours)*time.Hour), now.Add(-config.RecheckAfter))
+	if err != nil {
+		return result, fmt.Errorf("failed to get links to check: %w", err)
+	}
+
+	for _, link := range links {
+		status, err := sc.CheckStatus(link.NormalizedURL)
+		if errors.Is(err, scraper.ErrBlocked) {
+			continue
+		}
+		dead := status == http.StatusNotFound || status == http.StatusGone
+		if err != nil {
+			status = 0
+			dead = link.HTTPStatus != nil && *link.HTTPStatus == 0
+		}
+
+		if err := db.RecordLinkStatus(link.ID, status, dead); err != nil {
+			return result, fmt.Errorf("failed to record status of link %d: %w", link.ID, err)
+		}
+		result.Checked++
+		if dead {
+			result.Dead++
+			if link.DeadAt == nil {
+				log.Printf("[DEADLINKS] %s is gone (status %d)", link.NormalizedURL, status)
+			}
+		}
+	}
+	return result, nil
+}
+
+// ScheduleDeadLinkChecks registers the dead-link sweep with the scheduler
+func ScheduleDeadLinkChecks(sched *scheduler.Scheduler, db *database.DB, config DeadLinkConfig) {
+	if config.IntervalMin <= 0 || (config.Top <= 0 && config.Sample <= 0) {
+		log.Println("[DEADLINKS] Dead-link checks disabled")
+		return
+	}
+	if config.RecheckAfter <= 0 {
+		config.RecheckAfter = 24 * time.Hour
+	}
+	if config.Hours <= 0 {
+		config.Hours = 24
+	}
+
+	sc := scraper.NewScraper()
+	enrich.RecordScrapes(sc, db)
+	interval := time.Duration(config.IntervalMin) * time.Minute
+	log.Printf("[DEADLINKS] Scheduled dead-link checks of the top %d trending and %d sampled recent links (interval: %v)",
+		config.Top, config.Sample, interval)
+	sched.Every("dead-links", interval, false, func(ctx context.Context) error {
+		result, err := CheckDeadLinks(db, sc, config)
+		if err != nil {
+			return err
+		}
+		if result.Checked > 0 {
+			log.Printf("[DEADLINKS] Checked %d links, %d dead", result.Checked, result.Dead)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,50 @@
+package maintenance
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/database"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/scheduler"
+)
+
+// DormantConfig holds settings for pruning idle accounts from the live filter
+type DormantConfig struct {
+	Days int // 2nd-degree accounts idle this many days become dormant (<= 0 disables)
+}
+
+// DemoteDormantAccounts marks 2nd-degree accounts idle for config.Days as
+// dormant. Returns the number demoted.
+func DemoteDormantAccounts(db *database.DB, config DormantConfig) (int, error) {
+	idleSince := time.Now().Add(-time.Duration(config.Days) * 24 * time.Hour)
+	dids, err := db.DemoteDormantAccounts(idleSince)
+	if err != nil {
+		return 0, fmt.Errorf("failed to demote dormant accounts: %w", err)
+	}
+	return len(dids), nil
+}
+
+// ScheduleDormantPruning registers a daily dormant-account sweep with the
+// scheduler. reload is called after accounts are demoted so the leader's
+// filter drops them straight away.
+func ScheduleDormantPruning(sched *scheduler.Scheduler, db *database.DB, reload func() error, config DormantConfig) {
+	if config.Days <= 0 {
+		log.Println("[DORMANT] Dormant account pruning disabled")
+		return
+	}
+
+	log.Printf("[DORMANT] Scheduled daily pruning of 2nd-degree accounts idle for %d days", config.Days)
+	sched.Every("dormant-accounts", 24*time.Hour, true, func(ctx context.Context) error {
+		n, err := DemoteDormantAccounts(db, config)
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return nil
+		}
+		log.Printf("[DORMANT] Moved %d idle accounts out of the live filter", n)
+		return reload()
+	})
+}
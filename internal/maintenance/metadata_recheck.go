@@ -0,0 +1,135 @@
+package maintenance
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/database"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/scheduler"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/scrapequeue"
+)
+
+// RecheckConfig holds settings for refreshing metadata of trending links
+type RecheckConfig struct {
+	IntervalMin  int           // How often the trending top list is checked (<= 0 disables)
+	Top          int           // Links at the top of trending that are checked
+	Hours        int           // Trending window
+	RetryMissing time.Duration // Retry links missing a title or image after this long
+	StaleAfter   time.Duration // Refresh metadata older than this
+	Options      database.TrendingOptions
+}
+
+// RecheckTrendingMetadata pushes trending links with missing or stale
+// metadata to the front of the scrape queue. Returns the number pushed.
+func RecheckTrendingMetadata(db *database.DB, push func(scrapequeue.Job), config RecheckConfig) (int, error) {
+	trending, err := db.GetTrendingLinks(config.Hours, config.Top, config.Options)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get trending links: %w", err)
+	}
+	if len(trending) == 0 {
+		return 0, nil
+	}
+
+	ids := make([]int, len(trending))
+	for i, link := range trending {
+		ids[i] = link.ID
+	}
+
+	now := time.Now()
+	links, err := db.GetLinksNeedingRefresh(ids, now.Add(-config.RetryMissing), now.Add(-config.StaleAfter))
+	if err != nil {
+		return 0, fmt.Errorf("failed to check link metadata: %w", err)
+	}
+
+	for _, link := range links {
+		push(scrapequeue.Job{LinkID: link.ID, URL: link.NormalizedURL, Priority: scrapequeue.PriorityTrending})
+	}
+	return len(links), nil
+}
+
+// ScheduleMetadataRecheck registers the trending metadata re-check with the
+// scheduler
+func ScheduleMetadataRecheck(sched *scheduler.Scheduler, db *database.DB, push func(scrapequeue.Job), config RecheckConfig) {
+	if config.IntervalMin <= 0 || config.Top <= 0 {
+		log.Println("[RECHECK] Trending metadata re-check disabled")
+		return
+	}
+	if config.RetryMissing <= 0 {
+		config.RetryMissing = 30 * time.Minute
+	}
+	if config.StaleAfter <= 0 {
+		config.StaleAfter = 6 * time.Hour
+	}
+
+	interval := time.Duration(config.IntervalMin) * time.Minute
+	log.Printf("[RECHECK] Scheduled metadata re-check of the top %d trending links (interval: %v)", config.Top, interval)
+	sched.Every("metadata-recheck", interval, true, func(ctx context.Context) error {
+		n, err := RecheckTrendingMetadata(db, push, config)
+		if err != nil {
+			return err
+		}
+		if n > 0 {
+			log.Printf("[RECHECK] Queued metadata refresh for %d trending links", n)
+		}
+		return nil
+	})
+}
+
+// RefreshConfig holds settings for re-scraping young, popular links whose
+// metadata may still be changing
+type RefreshConfig struct {
+	MinShares int           // Links need at least this many shares (<= 0 disables)
+	Every     time.Duration // Re-scrape interval per link
+	MaxAge    time.Duration // Links are only refreshed this long after first being seen
+	BatchSize int           // Links refreshed per run
+}
+
+// RefreshEvolvingMetadata re-scrapes links due for a refresh. Returns the
+// number refreshed and how many of those changed.
+func RefreshEvolvingMetadata(db *database.DB, refresh func(*database.Link) (bool, error), config RefreshConfig) (int, int, error) {
+	now := time.Now()
+	links, err := db.GetLinksDueForRefresh(now.Add(-config.MaxAge), now.Add(-config.Every), config.MinShares, config.BatchSize)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to get links due for refresh: %w", err)
+	}
+
+	changed := 0
+	for i := range links {
+		ok, err := refresh(&links[i])
+		if err != nil {
+			log.Printf("[REFRESH] %s: %v", links[i].NormalizedURL, err)
+			continue
+		}
+		if ok {
+			changed++
+		}
+	}
+	return len(links), changed, nil
+}
+
+// ScheduleMetadataRefresh registers the evolving-story refresh with the
+// scheduler, checking for due links every 15 minutes
+func ScheduleMetadataRefresh(sched *scheduler.Scheduler, db *database.DB, refresh func(*database.Link) (bool, error), config RefreshConfig) {
+	if config.MinShares <= 0 || config.Every <= 0 || config.MaxAge <= 0 {
+		log.Println("[REFRESH] Metadata refresh disabled")
+		return
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = 100
+	}
+
+	log.Printf("[REFRESH] Scheduled metadata refresh every %v for links with %d+ shares during their first %v",
+		config.Every, config.MinShares, config.MaxAge)
+	sched.Every("metadata-refresh", 15*time.Minute, false, func(ctx context.Context) error {
+		n, changed, err := RefreshEvolvingMetadata(db, refresh, config)
+		if err != nil {
+			return err
+		}
+		if n > 0 {
+			log.Printf("[REFRESH] Refreshed %d links, %d changed", n, changed)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,66 @@
+package maintenance
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/database"
+)
+
+// ManagePostPartitions keeps daily posts partitions in step with retention
+// when posts is partitioned (cmd/migrate -partition-posts): partitions are
+// created for today and aheadDays days after, and days that ended before
+// the cutoff are dropped whole. Returns the number of posts dropped. Does
+// nothing when posts isn't partitioned.
+func ManagePostPartitions(db *database.DB, cutoff time.Time, aheadDays int) (int, error) {
+	partitioned, err := db.IsPostsPartitioned()
+	if err != nil {
+		return 0, fmt.Errorf("failed to check posts partitioning: %w", err)
+	}
+	if !partitioned {
+		return 0, nil
+	}
+
+	days, err := db.GetPostPartitionDays()
+	if err != nil {
+		return 0, fmt.Errorf("failed to list posts partitions: %w", err)
+	}
+	existing := make(map[time.Time]bool, len(days))
+	for _, day := range days {
+		existing[day] = true
+	}
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	for i := 0; i <= aheadDays; i++ {
+		day := today.AddDate(0, 0, i)
+		if existing[day] {
+			continue
+		}
+		created, err := db.CreatePostPartition(day)
+		if err != nil {
+			return 0, fmt.Errorf("failed to create partition for %s: %w", day.Format("2006-01-02"), err)
+		}
+		if created {
+			log.Printf("[PARTITION] Created posts partition for %s", day.Format("2006-01-02"))
+		} else {
+			log.Printf("[PARTITION] WARNING: posts for %s are already in the default partition; not partitioning that day",
+				day.Format("2006-01-02"))
+		}
+	}
+
+	dropped := 0
+	for _, day := range days {
+		if day.AddDate(0, 0, 1).After(cutoff) {
+			break
+		}
+		posts, err := db.DropPostPartition(day)
+		if err != nil {
+			return dropped, fmt.Errorf("failed to drop partition for %s: %w", day.Format("2006-01-02"), err)
+		}
+		log.Printf("[PARTITION] Dropped posts partition for %s (%d posts)", day.Format("2006-01-02"), posts)
+		dropped += posts
+	}
+
+	return dropped, nil
+}
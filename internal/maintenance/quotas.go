@@ -0,0 +1,78 @@
+package maintenance
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/database"
+)
+
+// QuotaResult reports what quota enforcement removed
+type QuotaResult struct {
+	PostsDeleted int
+	LinksDeleted int
+}
+
+// EnforceQuotas tightens retention when the posts or links tables grow past
+// their caps, deleting the oldest rows first while sparing trending links,
+// links the exempt tables reference and the posts that share trending links.
+// It only warns about database size, since deletes don't shrink the files on
+// disk.
+func EnforceQuotas(db *database.DB, config Config) (QuotaResult, error) {
+	var result QuotaResult
+
+	if config.MaxPosts > 0 {
+		deleted, err := enforceCap(db, "posts", config.MaxPosts, func(excess int) (int, error) {
+			return db.DeleteOldestPosts(excess, config.TrendingThreshold)
+		})
+		if err != nil {
+			return result, err
+		}
+		result.PostsDeleted = deleted
+	}
+
+	if config.MaxLinks > 0 {
+		deleted, err := enforceCap(db, "links", config.MaxLinks, func(excess int) (int, error) {
+			return db.DeleteOldestLinks(excess, config.TrendingThreshold, config.LinkExemptTables)
+		})
+		if err != nil {
+			return result, err
+		}
+		result.LinksDeleted = deleted
+	}
+
+	if config.WarnDatabaseMB > 0 {
+		size, err := db.GetDatabaseSize()
+		if err != nil {
+			return result, fmt.Errorf("failed to get database size: %w", err)
+		}
+		if sizeMB := size / (1024 * 1024); sizeMB > int64(config.WarnDatabaseMB) {
+			log.Printf("[QUOTA] WARNING: database is %d MB, over the %d MB limit; lower retention or caps and VACUUM to reclaim space",
+				sizeMB, config.WarnDatabaseMB)
+		}
+	}
+
+	return result, nil
+}
+
+// enforceCap deletes the rows a table holds beyond max through deleteOldest
+func enforceCap(db *database.DB, table string, max int, deleteOldest func(excess int) (int, error)) (int, error) {
+	count, err := db.CountTableRows(table)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count %s: %w", table, err)
+	}
+	if count <= max {
+		return 0, nil
+	}
+
+	excess := count - max
+	log.Printf("[QUOTA] WARNING: %s has %d rows, over the cap of %d; deleting the oldest %d", table, count, max, excess)
+	deleted, err := deleteOldest(excess)
+	if err != nil {
+		return 0, fmt.Errorf("failed to trim %s: %w", table, err)
+	}
+	if deleted < excess {
+		log.Printf("[QUOTA] WARNING: %s still %d rows over the cap; the rest belong to trending links", table, excess-deleted)
+	}
+	return deleted, nil
+}
@@ -0,0 +1,40 @@
+package maintenance
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/calendar"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/database"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/scheduler"
+)
+
+// rollupInterval is how often today's rollup is refreshed
+const rollupInterval = 15 * time.Minute
+
+// RollupDays refreshes the daily share rollups for today and yesterday in
+// loc. Yesterday is redone so shares made just before midnight are counted
+// once the day is over. Returns the number of link-day rows written.
+func RollupDays(db *database.DB, loc *time.Location, now time.Time) (int, error) {
+	total := 0
+	for _, t := range []time.Time{now.In(loc).AddDate(0, 0, -1), now} {
+		start, end := calendar.DayBounds(t, loc)
+		n, err := db.RollupDailyShares(calendar.Date(t, loc), start, end)
+		if err != nil {
+			return total, fmt.Errorf("failed to roll up %s: %w", calendar.Date(t, loc).Format("2006-01-02"), err)
+		}
+		total += n
+	}
+	return total, nil
+}
+
+// ScheduleDailyRollups registers the daily share rollup with the scheduler
+func ScheduleDailyRollups(sched *scheduler.Scheduler, db *database.DB, loc *time.Location) {
+	log.Printf("[ROLLUP] Scheduled daily share rollups (timezone: %s, interval: %v)", loc, rollupInterval)
+	sched.Every("daily-rollups", rollupInterval, true, func(ctx context.Context) error {
+		_, err := RollupDays(db, loc, time.Now())
+		return err
+	})
+}
@@ -0,0 +1,68 @@
+package maintenance
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/database"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/scheduler"
+)
+
+// SnapshotConfig holds trending snapshot settings
+type SnapshotConfig struct {
+	IntervalMin int // How often to snapshot trending (<= 0 disables)
+	Hours       int // Trending window captured in each snapshot
+	Limit       int // Links per snapshot
+	Options     database.TrendingOptions
+
+	Langs []string // Languages to also snapshot on their own, for language digests
+}
+
+// TakeSnapshot stores the current trending list in trending_snapshots,
+// counting only posts in lang ("" = all posts)
+func TakeSnapshot(db *database.DB, config SnapshotConfig, lang string) (int, error) {
+	takenAt := time.Now().UTC()
+
+	opts := config.Options
+	if lang != "" {
+		opts.Langs = []string{lang}
+	}
+	trending, err := db.GetTrendingLinks(config.Hours, config.Limit, opts)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get trending links: %w", err)
+	}
+
+	id, err := db.InsertTrendingSnapshot(takenAt, config.Hours, lang, database.SnapshotLinks(trending))
+	if err != nil {
+		return 0, fmt.Errorf("failed to store snapshot: %w", err)
+	}
+	return id, nil
+}
+
+// ScheduleSnapshots registers trending snapshots with the scheduler, taking
+// the first snapshot as soon as this instance becomes leader
+func ScheduleSnapshots(sched *scheduler.Scheduler, db *database.DB, config SnapshotConfig) {
+	if config.IntervalMin <= 0 {
+		log.Println("[SNAPSHOT] Trending snapshots disabled (interval <= 0)")
+		return
+	}
+
+	interval := time.Duration(config.IntervalMin) * time.Minute
+	log.Printf("[SNAPSHOT] Scheduled trending snapshots (interval: %v, window: %dh, languages: %v)", interval, config.Hours, config.Langs)
+	sched.Every("snapshot", interval, true, func(ctx context.Context) error {
+		for _, lang := range append([]string{""}, config.Langs...) {
+			id, err := TakeSnapshot(db, config, lang)
+			if err != nil {
+				return err
+			}
+			if lang == "" {
+				log.Printf("[SNAPSHOT] Stored snapshot %d", id)
+			} else {
+				log.Printf("[SNAPSHOT] Stored %s snapshot %d", lang, id)
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,56 @@
+package maintenance
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/database"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/scheduler"
+)
+
+// TopicConfig holds topic archival settings
+type TopicConfig struct {
+	IntervalMin int // How often to look for ended topics (<= 0 disables)
+	Limit       int // Links archived per topic
+	Options     database.TrendingOptions
+}
+
+// ArchiveTopics stores the final trending list of each ended topic not yet
+// archived, returning how many were archived
+func ArchiveTopics(db *database.DB, config TopicConfig) (int, error) {
+	topics, err := db.GetTopicsToArchive()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get ended topics: %w", err)
+	}
+
+	now := time.Now()
+	for i, topic := range topics {
+		hours, opts := topic.TrendingOptions(config.Options, now)
+		trending, err := db.GetTrendingLinks(hours, config.Limit, opts)
+		if err != nil {
+			return i, fmt.Errorf("failed to get trending links for topic %s: %w", topic.Slug, err)
+		}
+		if err := db.ArchiveTopic(topic.ID, database.SnapshotLinks(trending)); err != nil {
+			return i, fmt.Errorf("failed to archive topic %s: %w", topic.Slug, err)
+		}
+		log.Printf("[TOPICS] Archived topic %s (%d links)", topic.Slug, len(trending))
+	}
+	return len(topics), nil
+}
+
+// ScheduleTopicArchival registers topic archival with the scheduler
+func ScheduleTopicArchival(sched *scheduler.Scheduler, db *database.DB, config TopicConfig) {
+	if config.IntervalMin <= 0 {
+		log.Println("[TOPICS] Topic archival disabled (interval <= 0)")
+		return
+	}
+
+	interval := time.Duration(config.IntervalMin) * time.Minute
+	log.Printf("[TOPICS] Scheduled topic archival (interval: %v, links: %d)", interval, config.Limit)
+	sched.Every("topic-archival", interval, true, func(ctx context.Context) error {
+		_, err := ArchiveTopics(db, config)
+		return err
+	})
+}
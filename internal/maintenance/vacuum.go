@@ -0,0 +1,59 @@
+package maintenance
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/database"
+)
+
+// VacuumTables are the tables cleanup deletes from in bulk
+var VacuumTables = []string{"posts", "post_links", "links"}
+
+// VacuumAfterCleanup runs VACUUM (ANALYZE) on VacuumTables when a cleanup
+// deleted at least minDeleted rows (< 0 = never), logging table and index
+// sizes before and after. VACUUM doesn't shrink the files, but lets new rows
+// reuse the space and gives the planner fresh statistics for trending.
+func VacuumAfterCleanup(db *database.DB, minDeleted, deleted int) error {
+	if minDeleted < 0 || deleted < minDeleted {
+		return nil
+	}
+	return Vacuum(db)
+}
+
+// Vacuum runs VACUUM (ANALYZE) on VacuumTables, logging their sizes before
+// and after
+func Vacuum(db *database.DB) error {
+	startTime := time.Now()
+
+	before, err := db.GetTableSizes(VacuumTables)
+	if err != nil {
+		return fmt.Errorf("failed to get table sizes: %w", err)
+	}
+
+	for _, table := range VacuumTables {
+		if err := db.VacuumAnalyze(table); err != nil {
+			return fmt.Errorf("failed to vacuum %s: %w", table, err)
+		}
+	}
+
+	after, err := db.GetTableSizes(VacuumTables)
+	if err != nil {
+		return fmt.Errorf("failed to get table sizes: %w", err)
+	}
+
+	for i, size := range after {
+		log.Printf("[VACUUM] %s: table %s -> %s, indexes %s -> %s",
+			size.Table,
+			formatMB(before[i].TableBytes), formatMB(size.TableBytes),
+			formatMB(before[i].IndexBytes), formatMB(size.IndexBytes))
+	}
+	log.Printf("[VACUUM] Vacuumed %d tables in %v", len(VacuumTables), time.Since(startTime))
+	return nil
+}
+
+// formatMB formats a byte count in megabytes
+func formatMB(bytes int64) string {
+	return fmt.Sprintf("%.1f MB", float64(bytes)/(1024*1024))
+}
@@ -0,0 +1,77 @@
+package maintenance
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/calendar"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/database"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/scheduler"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/warehouse"
+)
+
+// warehouseSettle is how long after a day ends before it is exported, so
+// the daily rollup (which redoes yesterday every rollupInterval) has
+// counted its last shares
+const warehouseSettle = 2 * rollupInterval
+
+// WarehouseConfig holds settings for the daily warehouse export
+type WarehouseConfig struct {
+	Sink         warehouse.Sink // nil disables the export
+	Timezone     *time.Location // Calendar days to export
+	BackfillDays int            // Missed days to catch up on, back from yesterday
+}
+
+// ExportWarehouseDays exports every finished day in the backfill window
+// that hasn't been exported yet, oldest first. Returns the days exported.
+func ExportWarehouseDays(ctx context.Context, db *database.DB, config WarehouseConfig, now time.Time) (int, error) {
+	first := calendar.Date(now.In(config.Timezone).AddDate(0, 0, -config.BackfillDays), config.Timezone)
+	exported, err := db.GetWarehouseExportedDays(first)
+	if err != nil {
+		return 0, fmt.Errorf("failed to load exported days: %w", err)
+	}
+
+	n := 0
+	for day := first; ; day = day.AddDate(0, 0, 1) {
+		_, end, err := calendar.ParseDay(day.Format("2006-01-02"), config.Timezone)
+		if err != nil {
+			return n, err
+		}
+		if now.Before(end.Add(warehouseSettle)) {
+			break
+		}
+		if exported[day.Format("2006-01-02")] {
+			continue
+		}
+
+		counts, err := warehouse.ExportDay(ctx, db, config.Sink, day, config.Timezone)
+		if err != nil {
+			return n, fmt.Errorf("failed to export %s: %w", day.Format("2006-01-02"), err)
+		}
+		if err := db.MarkWarehouseDayExported(day, counts.Links, counts.Shares, counts.Domains); err != nil {
+			return n, fmt.Errorf("failed to record export of %s: %w", day.Format("2006-01-02"), err)
+		}
+		log.Printf("[WAREHOUSE] Exported %s: %d links, %d share rows, %d domains",
+			day.Format("2006-01-02"), counts.Links, counts.Shares, counts.Domains)
+		n++
+	}
+	return n, nil
+}
+
+// ScheduleWarehouseExport registers the warehouse export with the scheduler.
+// It checks hourly, so each day is exported shortly after it ends.
+func ScheduleWarehouseExport(sched *scheduler.Scheduler, db *database.DB, config WarehouseConfig) {
+	if config.Sink == nil {
+		log.Println("[WAREHOUSE] Warehouse export disabled (no directory or bucket configured)")
+		return
+	}
+
+	log.Printf("[WAREHOUSE] Scheduled daily export to %s (timezone: %s, backfill: %d days)",
+		config.Sink.Name(), config.Timezone, config.BackfillDays)
+	sched.Every("warehouse-export", time.Hour, true, func(ctx context.Context) error {
+		_, err := ExportWarehouseDays(ctx, db, config, time.Now())
+		return err
+	})
+}
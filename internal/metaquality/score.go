@@ -0,0 +1,117 @@
+// Package metaquality scores link metadata so the better of Bluesky's link
+// card and our own scrape can be shown.
+//
+// A score is 0-100: up to 50 for the title, 25 for the description and 25
+// for the preview image. Missing fields score nothing, and placeholder text
+// (bot challenges, cookie walls, "Page not found") or generic images (logos,
+// favicons) score little.
+package metaquality
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// Acceptable is the score below which metadata is worth replacing: links
+// whose Bluesky card scores lower are scraped as well
+const Acceptable = 60
+
+// junkTitlePrefixes start titles served in place of the article (lowercase)
+var junkTitlePrefixes = []string{
+	"just a moment", "attention required", "access denied", "are you a robot",
+	"403 forbidden", "404 not found", "page not found", "subscribe to read",
+	"please enable", "redirecting",
+}
+
+// junkTitles are whole titles that say nothing about the article (lowercase)
+var junkTitles = map[string]bool{
+	"404": true, "error": true, "not found": true, "sign in": true,
+	"log in": true, "login": true, "home": true, "untitled": true,
+}
+
+// junkDescriptions are phrases that mark a description as boilerplate
+var junkDescriptions = []string{
+	"enable javascript", "javascript is disabled", "we use cookies",
+	"checking your browser", "verify you are human", "subscribe to continue",
+}
+
+// junkImageHints are URL fragments of site-wide rather than article images
+var junkImageHints = []string{
+	"logo", "favicon", "default", "placeholder", "blank", "spacer", "avatar",
+}
+
+// Score rates a link's title, description and preview image
+func Score(title, description, imageURL string) int {
+	return scoreTitle(title) + scoreDescription(title, description) + scoreImage(imageURL)
+}
+
+func scoreTitle(title string) int {
+	title = strings.TrimSpace(title)
+	n := utf8.RuneCountInString(title)
+	if n == 0 {
+		return 0
+	}
+
+	lower := strings.ToLower(title)
+	if junkTitles[lower] {
+		return 5
+	}
+	for _, junk := range junkTitlePrefixes {
+		if strings.HasPrefix(lower, junk) {
+			return 5
+		}
+	}
+	if !strings.ContainsRune(title, ' ') {
+		// A bare site name or URL rather than a headline
+		return 15
+	}
+
+	switch {
+	case n < 15:
+		return 25
+	case n > 200:
+		return 40 // Probably the article's first paragraph
+	default:
+		return 50
+	}
+}
+
+func scoreDescription(title, description string) int {
+	description = strings.TrimSpace(description)
+	n := utf8.RuneCountInString(description)
+	if n == 0 {
+		return 0
+	}
+
+	lower := strings.ToLower(description)
+	for _, junk := range junkDescriptions {
+		if strings.Contains(lower, junk) {
+			return 0
+		}
+	}
+	if strings.EqualFold(description, strings.TrimSpace(title)) {
+		return 5
+	}
+	if n < 40 {
+		return 15
+	}
+	return 25
+}
+
+func scoreImage(imageURL string) int {
+	imageURL = strings.TrimSpace(imageURL)
+	if imageURL == "" {
+		return 0
+	}
+
+	lower := strings.ToLower(imageURL)
+	for _, hint := range junkImageHints {
+		if strings.Contains(lower, hint) {
+			return 5
+		}
+	}
+	if strings.HasSuffix(lower, ".ico") || strings.HasSuffix(lower, ".svg") {
+		return 5
+	}
+	return 25
+}
@@ -0,0 +1,38 @@
+package moderation
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// MinFingerprintWords is the fewest words a post needs to be fingerprinted.
+// Shorter texts ("wow", "this.") match by coincidence too often to say
+// anything about coordination.
+const MinFingerprintWords = 5
+
+var (
+	fingerprintURLPattern     = regexp.MustCompile(`(?i)\b(?:https?://|www\.)\S+`)
+	fingerprintMentionPattern = regexp.MustCompile(`@[\w.-]+`)
+)
+
+// TextFingerprint returns a hash of a post's text that is equal for
+// near-identical copies: case, punctuation, whitespace, links and @mentions
+// are ignored, since copy-paste campaigns vary those. Returns "" for texts
+// too short to compare.
+func TextFingerprint(text string) string {
+	text = fingerprintURLPattern.ReplaceAllString(text, " ")
+	text = fingerprintMentionPattern.ReplaceAllString(text, " ")
+
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	if len(words) < MinFingerprintWords {
+		return ""
+	}
+
+	sum := sha256.Sum256([]byte(strings.Join(words, " ")))
+	return hex.EncodeToString(sum[:16])
+}
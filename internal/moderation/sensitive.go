@@ -0,0 +1,162 @@
+// Package moderation detects links whose previews shouldn't be shown on a
+// public page (adult or graphic content).
+//
+// Detection combines three signals: self-labels on the sharing post, a
+// configured domain list, and an optional external image classifier.
+//
+// It also fingerprints post text so copy-paste campaigns sharing a link can
+// be collapsed into a single share.
+package moderation
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// DefaultSensitiveLabels are the Bluesky adult-content label values
+var DefaultSensitiveLabels = []string{"porn", "sexual", "nudity", "graphic-media"}
+
+// ImageClassifier decides whether an image is sensitive
+type ImageClassifier interface {
+	IsSensitive(imageURL string) (bool, error)
+}
+
+// Detector flags sensitive links
+type Detector struct {
+	labels     map[string]bool
+	domains    []string
+	classifier ImageClassifier
+}
+
+// NewDetector creates a detector. classifier may be nil to disable image checks.
+func NewDetector(labels, domains []string, classifier ImageClassifier) *Detector {
+	labelSet := make(map[string]bool, len(labels))
+	for _, label := range labels {
+		labelSet[strings.ToLower(label)] = true
+	}
+
+	normalized := make([]string, 0, len(domains))
+	for _, domain := range domains {
+		if domain = strings.ToLower(strings.TrimSpace(domain)); domain != "" {
+			normalized = append(normalized, strings.TrimPrefix(domain, "www."))
+		}
+	}
+
+	return &Detector{
+		labels:     labelSet,
+		domains:    normalized,
+		classifier: classifier,
+	}
+}
+
+// CheckLabels returns a reason if any post label marks its links as sensitive
+func (d *Detector) CheckLabels(labels []string) (string, bool) {
+	for _, label := range labels {
+		if d.labels[strings.ToLower(label)] {
+			return "label:" + label, true
+		}
+	}
+	return "", false
+}
+
+// CheckURL returns a reason if the URL's host is on the sensitive domain list
+// (subdomains match too)
+func (d *Detector) CheckURL(rawURL string) (string, bool) {
+	if len(d.domains) == 0 {
+		return "", false
+	}
+
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return "", false
+	}
+	host := strings.TrimPrefix(strings.ToLower(parsed.Hostname()), "www.")
+
+	for _, domain := range d.domains {
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return "domain:" + domain, true
+		}
+	}
+	return "", false
+}
+
+// CheckImage runs the image classifier, if configured
+func (d *Detector) CheckImage(imageURL string) (string, bool, error) {
+	if d.classifier == nil || imageURL == "" {
+		return "", false, nil
+	}
+
+	sensitive, err := d.classifier.IsSensitive(imageURL)
+	if err != nil || !sensitive {
+		return "", false, err
+	}
+	return "image", true, nil
+}
+
+// Config holds detector configuration
+type Config struct {
+	Labels             []string // Post label values that mark shared links sensitive
+	Domains            []string // Domains whose links are always sensitive
+	ImageClassifierURL string   // Optional HTTP classification endpoint (empty = disabled)
+}
+
+// NewDetectorWithConfig creates a detector, using an HTTPImageClassifier when
+// ImageClassifierURL is set
+func NewDetectorWithConfig(config *Config) *Detector {
+	var classifier ImageClassifier
+	if config.ImageClassifierURL != "" {
+		classifier = NewHTTPImageClassifier(config.ImageClassifierURL)
+	}
+	return NewDetector(config.Labels, config.Domains, classifier)
+}
+
+// HasImageClassifier reports whether image classification is enabled
+func (d *Detector) HasImageClassifier() bool {
+	return d.classifier != nil
+}
+
+// HTTPImageClassifier calls an external classification service.
+// It POSTs {"image_url": "..."} and expects {"sensitive": true|false}.
+type HTTPImageClassifier struct {
+	endpoint   string
+	httpClient *http.Client
+}
+
+// NewHTTPImageClassifier creates a classifier for the given endpoint
+func NewHTTPImageClassifier(endpoint string) *HTTPImageClassifier {
+	return &HTTPImageClassifier{
+		endpoint:   endpoint,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// IsSensitive asks the classification service about an image
+func (c *HTTPImageClassifier) IsSensitive(imageURL string) (bool, error) {
+	body, err := json.Marshal(map[string]string{"image_url": imageURL})
+	if err != nil {
+		return false, err
+	}
+
+	resp, err := c.httpClient.Post(c.endpoint, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("classifier returned status %d", resp.StatusCode)
+	}
+
+	var result struct {
+		Sensitive bool `json:"sensitive"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return false, fmt.Errorf("invalid classifier response: %w", err)
+	}
+	return result.Sensitive, nil
+}
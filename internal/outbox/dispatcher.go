@@ -0,0 +1,122 @@
+// Package outbox streams domain events to downstream integrations.
+//
+// Events are written to the outbox_events table in the same transaction as
+// the change they describe (links get a link_created event from a trigger).
+// The dispatcher reads the table in ID order and delivers batches to each
+// sink, keeping a per-sink cursor, so delivery is at-least-once and in order
+// and external systems never need to poll the aggregator's tables.
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/database"
+)
+
+// Config holds dispatcher settings
+type Config struct {
+	BatchSize int // Events per Send call
+
+	// Trending announcements: the top TrendingTop links over TrendingHours get
+	// a link_trending event, repeated at most once per TrendingCooldown
+	TrendingTop      int
+	TrendingHours    int
+	TrendingCooldown time.Duration
+	TrendingOptions  database.TrendingOptions
+}
+
+// Dispatcher delivers outbox events to sinks
+type Dispatcher struct {
+	db     *database.DB
+	sinks  []Sink
+	config Config
+}
+
+// NewDispatcherWithConfig creates a dispatcher for the given sinks
+func NewDispatcherWithConfig(db *database.DB, sinks []Sink, config *Config) *Dispatcher {
+	cfg := *config
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 100
+	}
+	if cfg.TrendingHours <= 0 {
+		cfg.TrendingHours = 24
+	}
+	if cfg.TrendingCooldown <= 0 {
+		cfg.TrendingCooldown = 24 * time.Hour
+	}
+	return &Dispatcher{db: db, sinks: sinks, config: cfg}
+}
+
+// Dispatch delivers pending events to every sink. A failing sink is left at
+// its last delivered event and retried on the next call; other sinks proceed.
+func (d *Dispatcher) Dispatch(ctx context.Context) error {
+	var firstErr error
+	for _, sink := range d.sinks {
+		sent, err := d.dispatchSink(ctx, sink)
+		if sent > 0 {
+			log.Printf("[OUTBOX] Delivered %d events to %s", sent, sink.Name())
+		}
+		if err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("%s: %w", sink.Name(), err)
+		}
+	}
+	return firstErr
+}
+
+func (d *Dispatcher) dispatchSink(ctx context.Context, sink Sink) (int, error) {
+	cursor, err := d.db.GetOutboxCursor(sink.Name())
+	if err != nil {
+		return 0, fmt.Errorf("failed to get cursor: %w", err)
+	}
+
+	sent := 0
+	for ctx.Err() == nil {
+		events, err := d.db.GetOutboxEventsAfter(cursor, d.config.BatchSize)
+		if err != nil {
+			return sent, fmt.Errorf("failed to load events: %w", err)
+		}
+		if len(events) == 0 {
+			return sent, nil
+		}
+
+		if err := sink.Send(ctx, events); err != nil {
+			return sent, err
+		}
+
+		cursor = events[len(events)-1].ID
+		if err := d.db.UpdateOutboxCursor(sink.Name(), cursor); err != nil {
+			return sent, fmt.Errorf("failed to update cursor: %w", err)
+		}
+		sent += len(events)
+
+		if len(events) < d.config.BatchSize {
+			return sent, nil
+		}
+	}
+	return sent, ctx.Err()
+}
+
+// RecordTrending writes link_trending events for links newly in the
+// trending top list
+func (d *Dispatcher) RecordTrending(ctx context.Context) error {
+	if d.config.TrendingTop <= 0 {
+		return nil
+	}
+
+	links, err := d.db.GetTrendingLinks(d.config.TrendingHours, d.config.TrendingTop, d.config.TrendingOptions)
+	if err != nil {
+		return fmt.Errorf("failed to get trending links: %w", err)
+	}
+
+	recorded, err := d.db.RecordTrendingEvents(links, d.config.TrendingHours, d.config.TrendingCooldown)
+	if err != nil {
+		return fmt.Errorf("failed to record trending events: %w", err)
+	}
+	if recorded > 0 {
+		log.Printf("[OUTBOX] Recorded %d link_trending events", recorded)
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/cache"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/database"
+)
+
+// EventsChannel is the pub/sub channel PubSubSink publishes to
+const EventsChannel = "events"
+
+// Sink delivers outbox events to a downstream system
+type Sink interface {
+	// Name identifies the sink's delivery cursor; it must be stable across restarts
+	Name() string
+	// Send delivers a batch of events in order. A batch that fails is retried
+	// in full, so sinks must tolerate duplicates.
+	Send(ctx context.Context, events []database.OutboxEvent) error
+}
+
+// WebhookSink POSTs batches as {"events": [...]} to a URL. When a secret is
+// set, the body's HMAC-SHA256 is sent in X-Signature-256 as "sha256=<hex>".
+type WebhookSink struct {
+	url        string
+	secret     string
+	httpClient *http.Client
+}
+
+// NewWebhookSink creates a webhook sink
+func NewWebhookSink(url, secret string) *WebhookSink {
+	return &WebhookSink{
+		url:        url,
+		secret:     secret,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Name returns the sink name
+func (s *WebhookSink) Name() string {
+	return "webhook:" + s.url
+}
+
+// Send POSTs the batch, failing on any non-2xx response
+func (s *WebhookSink) Send(ctx context.Context, events []database.OutboxEvent) error {
+	body, err := json.Marshal(map[string]interface{}{"events": events})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.secret != "" {
+		mac := hmac.New(sha256.New, []byte(s.secret))
+		mac.Write(body)
+		req.Header.Set("X-Signature-256", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// PubSubSink publishes each event as JSON to EventsChannel. With the Redis
+// cache backend, API replicas and external subscribers receive them.
+type PubSubSink struct {
+	pubsub cache.PubSub
+}
+
+// NewPubSubSink creates a pub/sub sink
+func NewPubSubSink(pubsub cache.PubSub) *PubSubSink {
+	return &PubSubSink{pubsub: pubsub}
+}
+
+// Name returns the sink name
+func (s *PubSubSink) Name() string {
+	return "pubsub:" + EventsChannel
+}
+
+// Send publishes the events one message at a time
+func (s *PubSubSink) Send(ctx context.Context, events []database.OutboxEvent) error {
+	for _, event := range events {
+		message, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		if err := s.pubsub.Publish(ctx, EventsChannel, message); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -20,13 +20,19 @@
 package processor
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"log"
 	"time"
 
 	"github.com/bluesky-social/jetstream/pkg/models"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/bluesky"
 	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/database"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/enrich"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/metaquality"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/moderation"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/scrapequeue"
 	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/scraper"
 	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/urlutil"
 )
@@ -45,6 +51,31 @@ type Processor struct {
 	db         *database.DB
 	scraper    *scraper.Scraper
 	didManager DIDManager
+	enrich     *enrich.Pipeline
+	config     Config
+}
+
+// Config holds processor configuration
+type Config struct {
+	ExcludeReplies    bool // Store replies but skip extracting their links
+	SkipMetadataFetch bool // Leave unfetched links to cmd/metadata-fetcher instead of scraping inline
+	StoreRawRecord    bool // Keep the original record JSON on the post
+	RawRecordMaxBytes int  // Larger records are not stored (<= 0 = no limit)
+
+	// Sensitive flags adult/graphic link previews (nil disables detection)
+	Sensitive *moderation.Detector
+
+	// ScrapeQueue defers metadata fetches to a bounded, prioritized queue
+	// (nil scrapes inline). The caller starts it with FetchMetadata.
+	ScrapeQueue *scrapequeue.Queue
+
+	// Enrichers names the enrichment stages run on links, in order
+	// (nil = enrich.DefaultStages)
+	Enrichers []string
+
+	// Source tags stored posts with their ingest path (a
+	// database.IngestSource* constant; "" leaves them untagged)
+	Source string
 }
 
 // PostRecord represents the post record from Jetstream (app.bsky.feed.post)
@@ -53,6 +84,47 @@ type PostRecord struct {
 	Text      string    `json:"text"`
 	CreatedAt time.Time `json:"createdAt"`
 	Embed     *Embed    `json:"embed,omitempty"`
+	Reply     *Reply    `json:"reply,omitempty"`
+	Labels    *SelfLabels `json:"labels,omitempty"`
+	Langs     []string    `json:"langs,omitempty"` // Author-declared BCP 47 language tags
+}
+
+// SelfLabels are moderation labels the author applied to their own post
+// (com.atproto.label.defs#selfLabels)
+type SelfLabels struct {
+	Type   string      `json:"$type"`
+	Values []SelfLabel `json:"values"`
+}
+
+// SelfLabel is a single self-applied label value (e.g. "porn", "graphic-media")
+type SelfLabel struct {
+	Val string `json:"val"`
+}
+
+// labelValues returns the self-label values on a post record
+func (r *PostRecord) labelValues() []string {
+	if r.Labels == nil {
+		return nil
+	}
+	values := make([]string, 0, len(r.Labels.Values))
+	for _, label := range r.Labels.Values {
+		if label.Val != "" {
+			values = append(values, label.Val)
+		}
+	}
+	return values
+}
+
+// Reply identifies the parent and thread root of a reply post
+type Reply struct {
+	Root   *StrongRef `json:"root,omitempty"`
+	Parent *StrongRef `json:"parent,omitempty"`
+}
+
+// StrongRef is an AT Protocol reference to a specific record version
+type StrongRef struct {
+	URI string `json:"uri"`
+	CID string `json:"cid"`
 }
 
 // Embed represents embedded content in a post
@@ -77,10 +149,23 @@ type EmbedRecord struct {
 
 // NewProcessor creates a new event processor
 func NewProcessor(db *database.DB, didManager DIDManager) *Processor {
+	return NewProcessorWithConfig(db, didManager, &Config{})
+}
+
+// NewProcessorWithConfig creates an event processor with custom configuration
+func NewProcessorWithConfig(db *database.DB, didManager DIDManager, config *Config) *Processor {
+	sc := scraper.NewScraper()
+	enrich.RecordScrapes(sc, db)
+	pipeline, err := enrich.Build(config.Enrichers, enrich.Deps{DB: db, Scraper: sc, Detector: config.Sensitive})
+	if err != nil {
+		log.Printf("[WARN] %v; running %v", err, pipeline.Names())
+	}
 	return &Processor{
 		db:         db,
-		scraper:    scraper.NewScraper(),
+		scraper:    sc,
 		didManager: didManager,
+		enrich:     pipeline,
+		config:     *config,
 	}
 }
 
@@ -115,33 +200,57 @@ func (p *Processor) ProcessEvent(event *models.Event) error {
 		AuthorDID:    event.Did,   // Store DID explicitly
 		AuthorDegree: degree,      // Store network degree (1, 2, or 0)
 		Content:      postRecord.Text,
+		IsReply:      postRecord.Reply != nil,
+		RawRecord:    p.RawRecordForStorage(event.Commit.Record),
+		Labels:       postRecord.labelValues(),
+		Fingerprint:  moderation.TextFingerprint(postRecord.Text),
+		Langs:        database.NormalizeLangs(postRecord.Langs),
+		Source:       p.config.Source,
 		CreatedAt:    postRecord.CreatedAt,
 	}
 
-	if err := p.db.InsertPost(dbPost); err != nil {
+	inserted, err := p.db.InsertPost(dbPost)
+	if err != nil {
 		return fmt.Errorf("failed to insert post: %w", err)
 	}
 
+	// Count a new post toward the author's stats once its links are stored;
+	// a replayed or retried one was counted the first time
+	urlCount := 0
+	defer func() {
+		if !inserted {
+			return
+		}
+		if err := p.db.RecordAccountPost(event.Did, urlCount, dbPost.CreatedAt); err != nil {
+			log.Printf("[WARN] Failed to update stats for %s: %v", event.Did, err)
+		}
+	}()
+
+	// Optionally keep reply links out of aggregation entirely
+	if dbPost.IsReply && p.config.ExcludeReplies {
+		return nil
+	}
+
 	// Skip reaction GIFs (image/video posts without actual links)
 	if p.isReactionGIF(&postRecord) {
 		log.Printf("[SKIP] Reaction GIF detected, skipping URL extraction: %s", event.Did)
 		return nil
 	}
 
-	// Process URLs
-	urlCount := 0
-
-	// Extract URLs from post text
-	urls := urlutil.ExtractURLs(postRecord.Text)
-	urlCount += p.processURLs(postURI, urls)
-
-	// Process embeds (quote posts, external links)
+	// Debug: Log embed data to see what Jetstream is sending
 	if postRecord.Embed != nil {
-		// Debug: Log embed data to see what Jetstream is sending
 		if embedJSON, err := json.Marshal(postRecord.Embed); err == nil {
 			log.Printf("[DEBUG-EMBED] %s: %s", event.Did, string(embedJSON))
 		}
-		urlCount += p.processEmbed(postURI, event.Did, postRecord.Embed)
+	}
+
+	// Process URLs from text, external embeds and quote posts
+	links := extractLinks(&postRecord, event.Did)
+	p.markGated(links, dbPost.Labels)
+	urlCount = p.storeLinks(postURI, degree, links)
+
+	if urlCount > 0 {
+		p.MarkLabeledLinksSensitive(postURI, dbPost.Labels)
 	}
 
 	if urlCount > 0 {
@@ -151,6 +260,22 @@ func (p *Processor) ProcessEvent(event *models.Event) error {
 	return nil
 }
 
+// RawRecordForStorage returns the record JSON to persist with a post, or nil
+// if raw records are disabled, the record exceeds the size cap, or it can't be
+// stored as JSONB (Postgres rejects \u0000 in jsonb strings)
+func (p *Processor) RawRecordForStorage(raw []byte) []byte {
+	if !p.config.StoreRawRecord || len(raw) == 0 {
+		return nil
+	}
+	if p.config.RawRecordMaxBytes > 0 && len(raw) > p.config.RawRecordMaxBytes {
+		return nil
+	}
+	if bytes.Contains(raw, []byte(`\u0000`)) {
+		return nil
+	}
+	return raw
+}
+
 // isReactionGIF checks if a post is a reaction GIF/image/video without actual links
 // Returns true if:
 // - Post has an image or video embed
@@ -194,8 +319,9 @@ func (p *Processor) isReactionGIF(post *PostRecord) bool {
 	return true
 }
 
-// processURLs processes a list of URLs and links them to a post
-func (p *Processor) processURLs(postURI string, urls []string) int {
+// processURLs processes a list of URLs and links them to a post, recording the
+// share's relation type and the author's degree. Gated URLs are not scraped.
+func (p *Processor) processURLs(postURI string, urls []string, relation string, degree int, gated bool) int {
 	urlCount := 0
 
 	for _, rawURL := range urls {
@@ -214,95 +340,188 @@ func (p *Processor) processURLs(postURI string, urls []string) int {
 		}
 
 		// Link post to link
-		if err := p.db.LinkPostToLink(postURI, link.ID); err != nil {
+		if err := p.db.LinkPostToLinkWithAttribution(postURI, link.ID, relation, degree); err != nil {
 			log.Printf("[WARN] Error linking post to link: %v", err)
 			continue
 		}
 
 		urlCount++
 
-		// Fetch OG data synchronously if not already fetched
-		if link.Title == nil {
-			ogData, err := p.scraper.FetchOGData(normalizedURL)
-			if err != nil {
-				log.Printf("[WARN] Failed to fetch metadata for %s: %v", normalizedURL, err)
-				// Mark as fetched to avoid retry storms
-				if err := p.db.MarkLinkFetched(link.ID); err != nil {
-					log.Printf("[WARN] Failed to mark link as fetched: %v", err)
-				}
-			} else if ogData.Title != "" || ogData.Description != "" || ogData.ImageURL != "" {
-				// Update with fetched metadata
-				if err := p.db.UpdateLinkMetadata(link.ID, ogData.Title, ogData.Description, ogData.ImageURL); err != nil {
-					log.Printf("[WARN] Failed to update link metadata: %v", err)
-				}
+		// Fetch OG data if not already fetched: queued when a scrape queue is
+		// configured, otherwise synchronously
+		item := &enrich.Item{Link: link, SkipScrape: true}
+		if link.Title == nil && !p.config.SkipMetadataFetch && !gated {
+			if p.config.ScrapeQueue != nil {
+				p.queueMetadataFetch(link)
 			} else {
-				// No metadata found, mark as fetched
-				if err := p.db.MarkLinkFetched(link.ID); err != nil {
-					log.Printf("[WARN] Failed to mark link as fetched: %v", err)
-				}
+				item.SkipScrape = false
 			}
 		}
+		p.enrich.Run(item)
 	}
 
 	return urlCount
 }
 
-// processEmbed extracts URLs from embeds (quote posts, external links, etc.)
-func (p *Processor) processEmbed(postURI string, authorDID string, embed *Embed) int {
-	urlCount := 0
+// queueMetadataFetch pushes a share of a link without metadata onto the
+// scrape queue. The 2nd and 3rd shares push at a much higher priority, and
+// also retry links whose earlier fetch failed or was shed, so links heading
+// for trending get a title and image before they are displayed.
+func (p *Processor) queueMetadataFetch(link *database.Link) {
+	shares, err := p.db.CountLinkShares(link.ID)
+	if err != nil {
+		log.Printf("[WARN] Failed to count shares of link %d: %v", link.ID, err)
+		shares = 1
+	}
 
-	// Handle external link embeds
-	if embed.External != nil {
-		// Extract thumb URL (can be string or blob object)
-		thumbURL := ""
-		if thumb, ok := embed.External.Thumb.(string); ok {
-			thumbURL = thumb
-		} else if thumbMap, ok := embed.External.Thumb.(map[string]interface{}); ok {
-			// Handle blob reference: extract CID and construct CDN URL
-			if ref, hasRef := thumbMap["ref"].(map[string]interface{}); hasRef {
-				if cid, hasCID := ref["$link"].(string); hasCID {
-					// Construct Bluesky CDN URL
-					thumbURL = fmt.Sprintf("https://cdn.bsky.app/img/feed_thumbnail/plain/%s/%s@jpeg", authorDID, cid)
-				}
+	priority := scrapequeue.PriorityShare
+	if shares == 2 || shares == 3 {
+		priority = scrapequeue.PriorityApproaching
+	} else if link.LastFetchedAt != nil {
+		return
+	}
+	p.config.ScrapeQueue.Push(scrapequeue.Job{LinkID: link.ID, URL: link.NormalizedURL, Priority: priority})
+}
+
+// FetchMetadata fetches and stores metadata for a queued scrape job.
+// It is the scrapequeue.Fetcher for the processor's scrape queue.
+func (p *Processor) FetchMetadata(job scrapequeue.Job) error {
+	return p.enrich.Run(&enrich.Item{Link: &database.Link{ID: job.LinkID, NormalizedURL: job.URL}})
+}
+
+// RefreshMetadata re-scrapes a link whose metadata was fetched before, using
+// a conditional request when validators were stored. Changed metadata is
+// recorded in the link's history and run through the later enrichment
+// stages. Links on paused domains are skipped. Reports whether anything
+// changed.
+func (p *Processor) RefreshMetadata(link *database.Link) (bool, error) {
+	if paused, err := p.db.IsScrapePaused(urlutil.Domain(link.NormalizedURL)); err != nil {
+		log.Printf("[WARN] Failed to check scrape pause for %s: %v", link.NormalizedURL, err)
+	} else if paused {
+		return false, nil
+	}
+
+	ogData, err := p.scraper.FetchOGDataIfModified(link.NormalizedURL, stringOrEmpty(link.ETag), stringOrEmpty(link.LastModified))
+	if err != nil {
+		// Unchanged or unreachable: either way, wait a full interval before trying again
+		if markErr := p.db.MarkLinkFetched(link.ID); markErr != nil {
+			log.Printf("[WARN] Failed to mark link as fetched: %v", markErr)
+		}
+		if err == scraper.ErrNotModified {
+			if clearErr := p.db.ClearScrapeError(link.ID); clearErr != nil {
+				log.Printf("[WARN] Failed to clear scrape error for %s: %v", link.NormalizedURL, clearErr)
 			}
+			return false, nil
 		}
-
-		// Use Bluesky's pre-fetched metadata if available
-		if embed.External.Title != "" {
-			urlCount += p.processExternalWithMetadata(
-				postURI,
-				embed.External.URI,
-				embed.External.Title,
-				embed.External.Description,
-				thumbURL,
-			)
-		} else {
-			// Fallback: scrape if Bluesky didn't fetch metadata
-			urls := []string{embed.External.URI}
-			urlCount += p.processURLs(postURI, urls)
+		if recordErr := p.db.RecordScrapeError(link.ID, err.Error()); recordErr != nil {
+			log.Printf("[WARN] Failed to record scrape error for %s: %v", link.NormalizedURL, recordErr)
 		}
+		return false, err
 	}
 
-	// Handle quote posts (embedded records)
-	if embed.Record != nil && embed.Record.Record != nil {
-		quotedPost := embed.Record.Record
+	if err := p.db.ClearScrapeError(link.ID); err != nil {
+		log.Printf("[WARN] Failed to clear scrape error for %s: %v", link.NormalizedURL, err)
+	}
+
+	if err := p.db.SetLinkImageMissing(link.ID, ogData.ImageMissing); err != nil {
+		log.Printf("[WARN] Failed to mark missing image for %s: %v", link.NormalizedURL, err)
+	}
+
+	changed, err := p.db.RefreshLinkMetadata(link.ID, ogData.Title, ogData.Description, ogData.ImageURL, ogData.ETag, ogData.LastModified)
+	if err != nil {
+		return false, err
+	}
+	if !changed {
+		return false, nil
+	}
+
+	item := &enrich.Item{Link: link, Page: ogData}
+	if ogData.ImageURL != "" && ogData.ImageURL != stringOrEmpty(link.OGImageURL) {
+		item.NewImageURL = ogData.ImageURL
+	}
+	p.enrich.Run(item)
+	return true, nil
+}
+
+// extractedLink is a URL found in a post, with Bluesky's link card metadata
+// when the post embedded it as an external link
+type extractedLink struct {
+	URL         string
+	Title       string
+	Description string
+	ImageURL    string
+	Relation    string // database.RelationOriginal or RelationQuote
+	Gated       bool   // Shared by an age-restricted post: sites answer with age gates, so don't scrape
+}
+
+// hasMetadata reports whether Bluesky provided any link card metadata. Cards
+// for restricted pages often carry only some fields.
+func (l extractedLink) hasMetadata() bool {
+	return l.Title != "" || l.Description != "" || l.ImageURL != ""
+}
+
+// extractLinks collects the URLs in a post: text URLs, external link embeds,
+// and (recursively) URLs in quoted posts
+func extractLinks(post *PostRecord, authorDID string) []extractedLink {
+	var links []extractedLink
+
+	for _, u := range urlutil.ExtractURLs(post.Text) {
+		links = append(links, extractedLink{URL: u, Relation: database.RelationOriginal})
+	}
+
+	if post.Embed != nil {
+		links = append(links, extractEmbedLinks(post.Embed, authorDID)...)
+	}
+
+	return links
+}
+
+// extractEmbedLinks extracts URLs from embeds (quote posts, external links, etc.)
+func extractEmbedLinks(embed *Embed, authorDID string) []extractedLink {
+	var links []extractedLink
 
-		// Extract URLs from quoted post text
-		urls := urlutil.ExtractURLs(quotedPost.Text)
-		urlCount += p.processURLs(postURI, urls)
+	// Handle external link embeds
+	if embed.External != nil {
+		// Use Bluesky's pre-fetched metadata, even partial, if available;
+		// otherwise the link is scraped like a text URL
+		links = append(links, extractedLink{
+			URL:         embed.External.URI,
+			Title:       embed.External.Title,
+			Description: embed.External.Description,
+			ImageURL:    bluesky.ThumbURL(embed.External.Thumb, authorDID),
+			Relation:    database.RelationOriginal,
+		})
+	}
 
-		// Recursively process embeds in the quoted post
+	// Handle quote posts (embedded records)
+	if embed.Record != nil && embed.Record.Record != nil {
 		// Note: quoted posts still use the same author DID for blob references
-		if quotedPost.Embed != nil {
-			urlCount += p.processEmbed(postURI, authorDID, quotedPost.Embed)
+		for _, link := range extractLinks(embed.Record.Record, authorDID) {
+			link.Relation = database.RelationQuote
+			links = append(links, link)
 		}
 	}
 
+	return links
+}
+
+// storeLinks links each extracted URL to the post, returning how many were stored
+func (p *Processor) storeLinks(postURI string, degree int, links []extractedLink) int {
+	urlCount := 0
+	for _, link := range links {
+		if link.hasMetadata() {
+			urlCount += p.processExternalWithMetadata(postURI, link, degree)
+		} else {
+			urlCount += p.processURLs(postURI, []string{link.URL}, link.Relation, degree, link.Gated)
+		}
+	}
 	return urlCount
 }
 
 // processExternalWithMetadata processes an external link with pre-fetched metadata from Bluesky
-func (p *Processor) processExternalWithMetadata(postURI, rawURL, title, description, imageURL string) int {
+func (p *Processor) processExternalWithMetadata(postURI string, external extractedLink, degree int) int {
+	rawURL := external.URL
+
 	// Normalize URL
 	normalizedURL, err := urlutil.Normalize(rawURL)
 	if err != nil {
@@ -318,17 +537,64 @@ func (p *Processor) processExternalWithMetadata(postURI, rawURL, title, descript
 	}
 
 	// Link post to link
-	if err := p.db.LinkPostToLink(postURI, link.ID); err != nil {
+	if err := p.db.LinkPostToLinkWithAttribution(postURI, link.ID, external.Relation, degree); err != nil {
 		log.Printf("[WARN] Error linking post to link: %v", err)
 		return 0
 	}
 
-	// Store Bluesky's metadata if we don't have any yet
+	// Store Bluesky's metadata if we don't have any yet. A poor link card
+	// (no image, a placeholder title) is also scraped, and the better of the
+	// two is displayed, unless the link is gated and the scrape would be refused.
+	item := &enrich.Item{Link: link, SkipScrape: true}
 	if link.Title == nil {
-		if err := p.db.UpdateLinkMetadata(link.ID, title, description, imageURL); err != nil {
+		if _, err := p.db.SaveLinkMetadata(link.ID, database.MetadataSourceBluesky, external.Title, external.Description, external.ImageURL); err != nil {
 			log.Printf("[WARN] Error updating link metadata: %v", err)
 		}
+		item.NewImageURL = external.ImageURL
+
+		if !p.config.SkipMetadataFetch && !external.Gated && metaquality.Score(external.Title, external.Description, external.ImageURL) < metaquality.Acceptable {
+			if p.config.ScrapeQueue != nil {
+				p.config.ScrapeQueue.Push(scrapequeue.Job{LinkID: link.ID, URL: link.NormalizedURL, Priority: scrapequeue.PriorityShare})
+			} else {
+				item.SkipScrape = false
+			}
+		}
 	}
+	p.enrich.Run(item)
 
 	return 1
 }
+
+// markGated flags links shared by a post with an adult/graphic label. Their
+// pages are typically behind age gates that refuse scrapers, so only
+// Bluesky's card metadata is kept.
+func (p *Processor) markGated(links []extractedLink, labels []string) {
+	if p.config.Sensitive == nil {
+		return
+	}
+	if _, gated := p.config.Sensitive.CheckLabels(labels); gated {
+		for i := range links {
+			links[i].Gated = true
+		}
+	}
+}
+
+// MarkLabeledLinksSensitive marks every link a post shares as sensitive when
+// the post carries an adult/graphic label
+func (p *Processor) MarkLabeledLinksSensitive(postURI string, labels []string) {
+	if p.config.Sensitive == nil {
+		return
+	}
+	if reason, sensitive := p.config.Sensitive.CheckLabels(labels); sensitive {
+		if err := p.db.MarkPostLinksSensitive(postURI, reason); err != nil {
+			log.Printf("[WARN] Failed to mark links sensitive for %s: %v", postURI, err)
+		}
+	}
+}
+
+func stringOrEmpty(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
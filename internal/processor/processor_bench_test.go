@@ -0,0 +1,44 @@
+package processor
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/testutil"
+)
+
+// fixedDegree places every author in the same degree
+type fixedDegree int
+
+func (d fixedDegree) GetDegree(did string) int { return int(d) }
+
+// BenchmarkProcessEvent measures ingesting a post with one external link,
+// metadata fetches left to cmd/metadata-fetcher
+func BenchmarkProcessEvent(b *testing.B) {
+	if testing.Short() {
+		b.Skip("needs Postgres")
+	}
+	db := testutil.NewTestDB(b)
+	proc := NewProcessorWithConfig(db, fixedDegree(1), &Config{SkipMetadataFetch: true})
+
+	createdAt := time.Now()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		event := testutil.PostEvent(fmt.Sprintf("did:plc:bench%d", i%100), fmt.Sprintf("bench%d", i), createdAt.UnixMicro(), PostRecord{
+			Type:      "app.bsky.feed.post",
+			Text:      "Worth a read",
+			CreatedAt: createdAt,
+			Embed: &Embed{
+				Type: "app.bsky.embed.external",
+				External: &EmbedExternal{
+					URI:   fmt.Sprintf("https://news.example/story/%d", i%1000),
+					Title: "Story",
+				},
+			},
+		})
+		if err := proc.ProcessEvent(&event); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package processor
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// TestExtractLinksThumb checks a raw record's blob thumb becomes a CDN URL
+// under the author's DID, and a malformed CID leaves the card without one
+func TestExtractLinksThumb(t *testing.T) {
+	const did = "did:plc:z72i7hdynmk6r22z27h6tvur"
+	const cid = "bafkreigh2akiscaildcqabsyg3dfr6chu3fgpregiymsck7e7aqa4s52zy"
+
+	tests := []struct {
+		name   string
+		record string
+		want   string
+	}{
+		{
+			"blob",
+			`{"text": "", "embed": {"$type": "app.bsky.embed.external", "external": {"uri": "https://example.com/a", "title": "A", "thumb": {"$type": "blob", "ref": {"$link": "` + cid + `"}}}}}`,
+			"https://cdn.bsky.app/img/feed_thumbnail/plain/" + did + "/" + cid + "@jpeg",
+		},
+		{
+			"bad cid",
+			`{"text": "", "embed": {"$type": "app.bsky.embed.external", "external": {"uri": "https://example.com/a", "title": "A", "thumb": {"ref": {"$link": "x/../y"}}}}}`,
+			"",
+		},
+	}
+	for _, tt := range tests {
+		var record PostRecord
+		if err := json.Unmarshal([]byte(tt.record), &record); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		links := extractLinks(&record, did)
+		if len(links) != 1 {
+			t.Fatalf("%s: got %d links, want 1", tt.name, len(links))
+		}
+		if links[0].ImageURL != tt.want {
+			t.Errorf("%s: ImageURL = %q, want %q", tt.name, links[0].ImageURL, tt.want)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package processor
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/database"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/urlutil"
+)
+
+// ReprocessResult describes how reprocessing a stored post changed its links
+type ReprocessResult struct {
+	Before  []string // Normalized URLs linked before reprocessing
+	After   []string // Normalized URLs the current pipeline extracts
+	Changed bool
+}
+
+// ReprocessPost re-runs a stored post through the current link extraction and
+// rebuilds its post_links. The raw record is used when stored; otherwise only
+// the post text is available. With dryRun, nothing is written.
+//
+// Reprocessing is idempotent: links are upserted by normalized URL and a post
+// whose link set is unchanged is left alone.
+func (p *Processor) ReprocessPost(post *database.Post, dryRun bool) (*ReprocessResult, error) {
+	record := PostRecord{Text: post.Content, CreatedAt: post.CreatedAt}
+	if len(post.RawRecord) > 0 {
+		if err := json.Unmarshal(post.RawRecord, &record); err != nil {
+			return nil, fmt.Errorf("failed to decode raw record: %w", err)
+		}
+	}
+
+	var links []extractedLink
+	skip := (post.IsReply && p.config.ExcludeReplies) || p.isReactionGIF(&record)
+	if !skip {
+		links = extractLinks(&record, post.AuthorDID)
+		p.markGated(links, post.Labels)
+	}
+
+	before, err := p.db.GetPostLinkURLs(post.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load current links: %w", err)
+	}
+	sort.Strings(before) // Compare in Go byte order, not the database collation
+
+	result := &ReprocessResult{
+		Before: before,
+		After:  normalizedURLs(links),
+	}
+	result.Changed = !equalStrings(result.Before, result.After)
+
+	if dryRun || !result.Changed {
+		return result, nil
+	}
+
+	if err := p.db.DeletePostLinks(post.ID); err != nil {
+		return nil, fmt.Errorf("failed to clear post links: %w", err)
+	}
+	p.storeLinks(post.ID, post.AuthorDegree, links)
+
+	return result, nil
+}
+
+// normalizedURLs returns the sorted, de-duplicated normalized URLs of links,
+// skipping any that fail normalization (as processURLs does)
+func normalizedURLs(links []extractedLink) []string {
+	seen := make(map[string]bool)
+	var urls []string
+	for _, link := range links {
+		normalized, err := urlutil.Normalize(link.URL)
+		if err != nil || seen[normalized] {
+			continue
+		}
+		seen[normalized] = true
+		urls = append(urls, normalized)
+	}
+	sort.Strings(urls)
+	return urls
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,165 @@
+// Package retryqueue provides a durable, database-backed retry queue for
+// firehose events that failed processing.
+//
+// Failed events are persisted to the failed_events table before the
+// Jetstream cursor moves past them, giving at-least-once processing:
+// every event is either processed, waiting in the queue, or marked dead
+// after exhausting its retries.
+package retryqueue
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/bluesky-social/jetstream/pkg/models"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/database"
+)
+
+// Handler processes a single event, returning an error if it should be retried
+type Handler func(event *models.Event) error
+
+// Config holds retry queue configuration
+type Config struct {
+	MaxAttempts  int           // Attempts before an event is marked dead
+	BaseBackoff  time.Duration // Delay before the first retry (doubles each attempt)
+	MaxBackoff   time.Duration // Upper bound on the retry delay
+	PollInterval time.Duration // How often to check for due events
+	BatchSize    int           // Max events retried per poll
+
+	// Shard and ShardCount limit retries to events from DIDs in this
+	// instance's shard (ShardCount <= 1 = all). Used in shard mode so two
+	// instances never retry the same event.
+	Shard      int
+	ShardCount int
+}
+
+// Queue persists failed events and retries them in the background
+type Queue struct {
+	db      *database.DB
+	handler Handler
+	config  Config
+}
+
+// NewQueue creates a retry queue that replays events through handler
+func NewQueue(db *database.DB, handler Handler, config Config) *Queue {
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = 5
+	}
+	if config.BaseBackoff <= 0 {
+		config.BaseBackoff = 30 * time.Second
+	}
+	if config.MaxBackoff <= 0 {
+		config.MaxBackoff = time.Hour
+	}
+	if config.PollInterval <= 0 {
+		config.PollInterval = 30 * time.Second
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = 100
+	}
+
+	return &Queue{
+		db:      db,
+		handler: handler,
+		config:  config,
+	}
+}
+
+// Enqueue durably stores a failed event for later retry.
+// Callers must not advance their cursor past the event if this returns an error.
+func (q *Queue) Enqueue(event *models.Event, cause error) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+
+	nextAttempt := time.Now().Add(q.backoff(1))
+	if err := q.db.EnqueueFailedEvent(event.TimeUS, event.Did, payload, cause.Error(), nextAttempt); err != nil {
+		return fmt.Errorf("failed to enqueue event: %w", err)
+	}
+
+	log.Printf("[RETRY] Queued failed event from %s (time_us: %d): %v", event.Did, event.TimeUS, cause)
+	return nil
+}
+
+// Start runs the retry loop in a background goroutine until ctx is cancelled
+func (q *Queue) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(q.config.PollInterval)
+		defer ticker.Stop()
+
+		log.Printf("[RETRY] Started retry queue (interval: %v, max attempts: %d)", q.config.PollInterval, q.config.MaxAttempts)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := q.processDue(); err != nil {
+					log.Printf("[RETRY] Error: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+// processDue retries every event whose next attempt time has passed
+func (q *Queue) processDue() error {
+	events, err := q.db.GetDueFailedEvents(q.config.BatchSize, q.config.Shard, q.config.ShardCount)
+	if err != nil {
+		return fmt.Errorf("failed to load due events: %w", err)
+	}
+
+	succeeded, rescheduled, dead := 0, 0, 0
+	for _, failed := range events {
+		var event models.Event
+		if err := json.Unmarshal(failed.Payload, &event); err != nil {
+			// A payload we can't decode will never succeed
+			if err := q.db.MarkFailedEventDead(failed.ID, fmt.Sprintf("invalid payload: %v", err)); err != nil {
+				return err
+			}
+			dead++
+			continue
+		}
+
+		procErr := q.handler(&event)
+		switch {
+		case procErr == nil:
+			if err := q.db.DeleteFailedEvent(failed.ID); err != nil {
+				return err
+			}
+			succeeded++
+		case failed.Attempts+1 >= q.config.MaxAttempts:
+			log.Printf("[RETRY] Giving up on event %d from %s after %d attempts: %v", failed.ID, failed.DID, failed.Attempts+1, procErr)
+			if err := q.db.MarkFailedEventDead(failed.ID, procErr.Error()); err != nil {
+				return err
+			}
+			dead++
+		default:
+			nextAttempt := time.Now().Add(q.backoff(failed.Attempts + 1))
+			if err := q.db.RescheduleFailedEvent(failed.ID, procErr.Error(), nextAttempt); err != nil {
+				return err
+			}
+			rescheduled++
+		}
+	}
+
+	if retried := succeeded + rescheduled + dead; retried > 0 {
+		log.Printf("[RETRY] Retried %d events: %d succeeded, %d rescheduled, %d dead", retried, succeeded, rescheduled, dead)
+	}
+	return nil
+}
+
+// backoff returns the delay before the given attempt number (exponential, capped)
+func (q *Queue) backoff(attempt int) time.Duration {
+	delay := q.config.BaseBackoff
+	for i := 1; i < attempt; i++ {
+		delay *= 2
+		if delay >= q.config.MaxBackoff {
+			return q.config.MaxBackoff
+		}
+	}
+	return delay
+}
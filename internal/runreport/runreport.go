@@ -0,0 +1,129 @@
+// Package runreport builds the machine-readable summary batch commands
+// write with -report-json, so cron wrappers and CI-style checks can act on
+// a run's results without parsing its logs.
+package runreport
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// Run statuses
+const (
+	StatusOK      = "ok"      // Finished; Failures may still count items that failed
+	StatusFailed  = "failed"  // Stopped early on Error
+	StatusNothing = "nothing" // Finished with no work to do
+)
+
+// Report summarizes one run of a command
+type Report struct {
+	Command    string           `json:"command"`
+	Status     string           `json:"status"`
+	Error      string           `json:"error,omitempty"`
+	StartedAt  time.Time        `json:"started_at"`
+	FinishedAt time.Time        `json:"finished_at"`
+	DurationMS int64            `json:"duration_ms"`
+	Counts     map[string]int64 `json:"counts"`
+	Failures   map[string]int64 `json:"failures"`  // By category
+	PhasesMS   map[string]int64 `json:"phases_ms"` // Time spent in each phase
+
+	dest string
+	mu   sync.Mutex
+}
+
+// New starts a report for command, written to dest by Write: a file path,
+// "-" for stdout, or "" to write nothing
+func New(command, dest string) *Report {
+	return &Report{
+		Command:   command,
+		Status:    StatusOK,
+		StartedAt: time.Now().UTC(),
+		Counts:    map[string]int64{},
+		Failures:  map[string]int64{},
+		PhasesMS:  map[string]int64{},
+		dest:      dest,
+	}
+}
+
+// Add adds n to a count
+func (r *Report) Add(name string, n int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Counts[name] += n
+}
+
+// Fail counts one failure in category
+func (r *Report) Fail(category string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Failures[category]++
+}
+
+// Phase starts timing a phase; call the returned func when it ends
+func (r *Report) Phase(name string) func() {
+	start := time.Now()
+	return func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.PhasesMS[name] += time.Since(start).Milliseconds()
+	}
+}
+
+// SetStatus overrides the status the report finishes with
+func (r *Report) SetStatus(status string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Status = status
+}
+
+// Write finishes the report and writes it as JSON to its destination
+func (r *Report) Write() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.FinishedAt = time.Now().UTC()
+	r.DurationMS = r.FinishedAt.Sub(r.StartedAt).Milliseconds()
+	if r.dest == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	if r.dest == "-" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	if err := os.WriteFile(r.dest, data, 0644); err != nil {
+		return fmt.Errorf("failed to write report: %w", err)
+	}
+	return nil
+}
+
+// Finish writes the report, logging rather than failing the run if it
+// can't be written
+func (r *Report) Finish() {
+	if err := r.Write(); err != nil {
+		log.Printf("[WARN] Failed to write run report: %v", err)
+	}
+}
+
+// Fatalf is log.Fatalf that first records the error in the report and
+// writes it, so a failed run still leaves a summary
+func (r *Report) Fatalf(format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	r.mu.Lock()
+	r.Status = StatusFailed
+	r.Error = msg
+	r.mu.Unlock()
+
+	r.Finish()
+	log.Fatal(msg)
+}
@@ -0,0 +1,201 @@
+// Package scheduler runs periodic jobs on exactly one instance.
+//
+// Instances elect a leader through a Postgres advisory lock held on a
+// dedicated connection. Every instance registers the same jobs and runs the
+// tickers, but a job only executes on the instance currently holding the
+// lock. If the leader exits or loses its connection the lock is released and
+// another instance takes over on its next election attempt.
+package scheduler
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/database"
+)
+
+// DefaultLockName identifies the leader lock shared by all instances
+const DefaultLockName = "bluesky-news-aggregator:scheduler"
+
+// Config holds leader election settings
+type Config struct {
+	LockName      string        // Instances using the same name compete for leadership
+	ElectInterval time.Duration // How often followers retry and the leader checks its lock
+}
+
+// Job is a periodic task
+type Job func(ctx context.Context) error
+
+type job struct {
+	name     string
+	interval time.Duration
+	runNow   bool
+	fn       Job
+}
+
+// Scheduler runs registered jobs while this instance is leader
+type Scheduler struct {
+	db     *database.DB
+	config Config
+	key    int64
+
+	mu   sync.Mutex
+	lock *database.AdvisoryLock
+	jobs []job
+}
+
+// New creates a scheduler with default settings
+func New(db *database.DB) *Scheduler {
+	return NewWithConfig(db, &Config{})
+}
+
+// NewWithConfig creates a scheduler with custom settings
+func NewWithConfig(db *database.DB, config *Config) *Scheduler {
+	cfg := *config
+	if cfg.LockName == "" {
+		cfg.LockName = DefaultLockName
+	}
+	if cfg.ElectInterval <= 0 {
+		cfg.ElectInterval = 15 * time.Second
+	}
+	return &Scheduler{db: db, config: cfg, key: database.LockKey(cfg.LockName)}
+}
+
+// Every registers fn to run every interval on the leader. With runNow, the
+// first run happens as soon as this instance becomes leader. A non-positive
+// interval disables the job. Jobs must be registered before Start.
+func (s *Scheduler) Every(name string, interval time.Duration, runNow bool, fn Job) {
+	if interval <= 0 {
+		log.Printf("[SCHEDULER] Job %s disabled (interval <= 0)", name)
+		return
+	}
+	s.jobs = append(s.jobs, job{name: name, interval: interval, runNow: runNow, fn: fn})
+}
+
+// IsLeader reports whether this instance currently holds the leader lock
+func (s *Scheduler) IsLeader() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lock != nil
+}
+
+// Start begins leader election and the job tickers. Everything stops and
+// leadership is released when ctx is cancelled.
+func (s *Scheduler) Start(ctx context.Context) {
+	if len(s.jobs) == 0 {
+		return
+	}
+
+	becameLeader := make(chan struct{}, 1)
+	s.elect(ctx, becameLeader)
+
+	go func() {
+		ticker := time.NewTicker(s.config.ElectInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				s.resign()
+				return
+			case <-ticker.C:
+				s.elect(ctx, becameLeader)
+			}
+		}
+	}()
+
+	runNow := make([]chan struct{}, len(s.jobs))
+	for i := range s.jobs {
+		runNow[i] = make(chan struct{}, 1)
+		go s.runJob(ctx, s.jobs[i], runNow[i])
+	}
+
+	// Fan leadership changes out to jobs that run immediately on election
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-becameLeader:
+				for i, j := range s.jobs {
+					if j.runNow {
+						select {
+						case runNow[i] <- struct{}{}:
+						default:
+						}
+					}
+				}
+			}
+		}
+	}()
+}
+
+// elect takes the leader lock if free, or verifies it is still held
+func (s *Scheduler) elect(ctx context.Context, becameLeader chan<- struct{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.lock != nil {
+		if err := s.lock.Check(ctx); err != nil {
+			log.Printf("[SCHEDULER] Lost leadership: %v", err)
+			s.lock.Release()
+			s.lock = nil
+		}
+		return
+	}
+
+	lock, err := s.db.TryAdvisoryLock(ctx, s.key)
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Printf("[SCHEDULER] Leader election failed: %v", err)
+		}
+		return
+	}
+	if lock == nil {
+		return
+	}
+
+	s.lock = lock
+	log.Printf("[SCHEDULER] Became leader; running %d periodic job(s)", len(s.jobs))
+	select {
+	case becameLeader <- struct{}{}:
+	default:
+	}
+}
+
+// resign releases the leader lock
+func (s *Scheduler) resign() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.lock == nil {
+		return
+	}
+	if err := s.lock.Release(); err != nil {
+		log.Printf("[SCHEDULER] Failed to release leader lock: %v", err)
+	}
+	s.lock = nil
+}
+
+func (s *Scheduler) runJob(ctx context.Context, j job, runNow <-chan struct{}) {
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-runNow:
+		case <-ticker.C:
+		}
+
+		if !s.IsLeader() {
+			continue
+		}
+
+		if err := j.fn(ctx); err != nil {
+			log.Printf("[SCHEDULER] Job %s failed: %v", j.name, err)
+		}
+	}
+}
@@ -0,0 +1,355 @@
+// Package scrapequeue schedules link metadata fetches with bounded memory.
+//
+// Jobs wait in an in-memory priority queue (most-shared links first) served
+// by a fixed pool of workers. When the queue is full, new jobs overflow to
+// the scrape_jobs table and are pulled back, highest priority first, as the
+// queue drains. When the persisted backlog grows past a threshold, the
+// lowest-priority jobs are shed so a burst (e.g. after downtime or a
+// backfill) can't grow memory or scrape traffic without bound.
+package scrapequeue
+
+import (
+	"container/heap"
+	"context"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/database"
+)
+
+// Priorities pushed for a share of a link. Pushes for a queued link add up,
+// so a waiting link's priority grows with its shares.
+const (
+	PriorityShare       = 1    // Any share of an unfetched link
+	PriorityApproaching = 10   // The 2nd or 3rd share: the link may be heading for trending
+	PriorityTrending    = 1000 // A link in the trending top list with missing or stale metadata
+)
+
+// Job is a pending metadata fetch for a link
+type Job struct {
+	LinkID   int
+	URL      string
+	Priority int // Shares seen while waiting; higher is fetched first
+}
+
+// Fetcher fetches and stores metadata for a job
+type Fetcher func(job Job) error
+
+// Config holds queue settings
+type Config struct {
+	Capacity       int           // Jobs held in memory before overflowing to the database
+	Workers        int           // Concurrent fetches
+	RefillInterval time.Duration // How often the backlog is checked while the queue has room
+	ShedBacklog    int           // Backlog size above which low-priority jobs are shed (<= 0 = never)
+	ShedPriority   int           // Jobs at or below this priority are shed
+}
+
+// Stats is a point-in-time view of queue activity
+type Stats struct {
+	Depth      int   // Jobs in memory
+	Backlog    int   // Jobs persisted in scrape_jobs (as of the last refill)
+	Enqueued   int64 // New jobs accepted
+	Bumped     int64 // Pushes that raised a queued job's priority
+	Overflowed int64 // Jobs persisted because the queue was full
+	Fetched    int64 // Successful fetches
+	Failed     int64 // Failed fetches
+	Shed       int64 // Jobs dropped by load shedding
+}
+
+// Queue is a bounded priority queue of scrape jobs
+type Queue struct {
+	db     *database.DB
+	config Config
+
+	mu      sync.Mutex
+	jobs    jobHeap
+	index   map[int]*item // link ID -> queued item
+	backlog int           // Persisted jobs, so new jobs queue behind them
+	ready   chan struct{} // Signalled when a job is pushed
+	drained chan struct{} // Signalled when the queue empties with a backlog waiting
+
+	enqueued, bumped, overflowed, fetched, failed, shed atomic.Int64
+
+	wg sync.WaitGroup
+}
+
+// NewWithConfig creates a scrape queue
+func NewWithConfig(db *database.DB, config *Config) *Queue {
+	cfg := *config
+	if cfg.Capacity <= 0 {
+		cfg.Capacity = 1000
+	}
+	if cfg.Workers <= 0 {
+		cfg.Workers = 4
+	}
+	if cfg.RefillInterval <= 0 {
+		cfg.RefillInterval = 10 * time.Second
+	}
+	if cfg.ShedPriority <= 0 {
+		cfg.ShedPriority = 1
+	}
+	return &Queue{
+		db:      db,
+		config:  cfg,
+		index:   make(map[int]*item),
+		ready:   make(chan struct{}, 1),
+		drained: make(chan struct{}, 1),
+	}
+}
+
+// Push queues a fetch, or raises the priority of one already queued. Jobs go
+// to the database backlog when the queue is full or already has a backlog,
+// so they are still served in priority order.
+func (q *Queue) Push(job Job) {
+	if job.Priority <= 0 {
+		job.Priority = 1
+	}
+
+	q.mu.Lock()
+	if it, ok := q.index[job.LinkID]; ok {
+		it.job.Priority += job.Priority
+		heap.Fix(&q.jobs, it.index)
+		q.mu.Unlock()
+		q.bumped.Add(1)
+		return
+	}
+	if len(q.jobs) >= q.config.Capacity || q.backlog > 0 {
+		q.backlog++
+		q.mu.Unlock()
+		q.overflow([]Job{job})
+		return
+	}
+	q.pushLocked(job)
+	q.mu.Unlock()
+	q.enqueued.Add(1)
+}
+
+// Start runs the workers and the backlog refill loop until ctx is
+// cancelled, then persists jobs still in memory to the backlog
+func (q *Queue) Start(ctx context.Context, fetch Fetcher) {
+	for i := 0; i < q.config.Workers; i++ {
+		q.wg.Add(1)
+		go func() {
+			defer q.wg.Done()
+			q.work(ctx, fetch)
+		}()
+	}
+
+	q.wg.Add(1)
+	go func() {
+		defer q.wg.Done()
+		q.refill()
+
+		ticker := time.NewTicker(q.config.RefillInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				q.flush()
+				return
+			case <-ticker.C:
+				q.refill()
+			case <-q.drained:
+				q.refill()
+			}
+		}
+	}()
+}
+
+// Wait blocks until the workers have stopped and remaining jobs are persisted
+func (q *Queue) Wait() {
+	q.wg.Wait()
+}
+
+// Stats returns current queue metrics
+func (q *Queue) Stats() Stats {
+	q.mu.Lock()
+	depth, backlog := len(q.jobs), q.backlog
+	q.mu.Unlock()
+
+	return Stats{
+		Depth:      depth,
+		Backlog:    backlog,
+		Enqueued:   q.enqueued.Load(),
+		Bumped:     q.bumped.Load(),
+		Overflowed: q.overflowed.Load(),
+		Fetched:    q.fetched.Load(),
+		Failed:     q.failed.Load(),
+		Shed:       q.shed.Load(),
+	}
+}
+
+func (q *Queue) work(ctx context.Context, fetch Fetcher) {
+	for {
+		job, ok := q.pop()
+		if !ok {
+			select {
+			case <-ctx.Done():
+				return
+			case <-q.ready:
+				continue
+			}
+		}
+
+		if err := fetch(job); err != nil {
+			q.failed.Add(1)
+		} else {
+			q.fetched.Add(1)
+		}
+
+		if ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+// refill sheds low-priority backlog when it is too large, then moves the
+// highest-priority backlog jobs into free queue slots
+func (q *Queue) refill() {
+	backlog, err := q.db.CountScrapeJobs()
+	if err != nil {
+		log.Printf("[SCRAPE] Failed to count backlog: %v", err)
+		return
+	}
+
+	if q.config.ShedBacklog > 0 && backlog > q.config.ShedBacklog {
+		n, err := q.db.ShedScrapeJobs(q.config.ShedPriority)
+		if err != nil {
+			log.Printf("[SCRAPE] Failed to shed backlog: %v", err)
+		} else if n > 0 {
+			q.shed.Add(int64(n))
+			backlog -= n
+			log.Printf("[SCRAPE] Backlog over %d; shed %d jobs with priority <= %d",
+				q.config.ShedBacklog, n, q.config.ShedPriority)
+		}
+	}
+
+	q.mu.Lock()
+	free := q.config.Capacity - len(q.jobs)
+	q.mu.Unlock()
+
+	var taken []database.ScrapeJob
+	if free > 0 && backlog > 0 {
+		taken, err = q.db.TakeScrapeJobs(free)
+		if err != nil {
+			log.Printf("[SCRAPE] Failed to take backlog jobs: %v", err)
+		}
+	}
+
+	q.mu.Lock()
+	for _, job := range taken {
+		if it, ok := q.index[job.LinkID]; ok {
+			it.job.Priority += job.Priority
+			heap.Fix(&q.jobs, it.index)
+			continue
+		}
+		q.pushLocked(Job{LinkID: job.LinkID, URL: job.URL, Priority: job.Priority})
+	}
+	q.backlog = backlog - len(taken)
+	if q.backlog < 0 {
+		q.backlog = 0
+	}
+	q.mu.Unlock()
+}
+
+// flush persists jobs still in memory so another process can serve them
+func (q *Queue) flush() {
+	q.mu.Lock()
+	jobs := make([]Job, 0, len(q.jobs))
+	for _, it := range q.jobs {
+		jobs = append(jobs, it.job)
+	}
+	q.jobs = nil
+	q.index = make(map[int]*item)
+	q.mu.Unlock()
+
+	if len(jobs) > 0 {
+		q.overflow(jobs)
+		log.Printf("[SCRAPE] Persisted %d queued jobs on shutdown", len(jobs))
+	}
+}
+
+// overflow parks jobs in the database backlog
+func (q *Queue) overflow(jobs []Job) {
+	rows := make([]database.ScrapeJob, len(jobs))
+	for i, job := range jobs {
+		rows[i] = database.ScrapeJob{LinkID: job.LinkID, URL: job.URL, Priority: job.Priority}
+	}
+	if err := q.db.UpsertScrapeJobs(rows); err != nil {
+		// The links stay unfetched, so cmd/metadata-fetcher can still pick them up
+		log.Printf("[SCRAPE] Failed to persist %d jobs: %v", len(jobs), err)
+		return
+	}
+	q.overflowed.Add(int64(len(jobs)))
+}
+
+func (q *Queue) pushLocked(job Job) {
+	it := &item{job: job}
+	heap.Push(&q.jobs, it)
+	q.index[job.LinkID] = it
+
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+}
+
+func (q *Queue) pop() (Job, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if len(q.jobs) == 0 {
+		if q.backlog > 0 {
+			select {
+			case q.drained <- struct{}{}:
+			default:
+			}
+		}
+		return Job{}, false
+	}
+	it := heap.Pop(&q.jobs).(*item)
+	delete(q.index, it.job.LinkID)
+
+	// Wake another worker if more jobs are waiting
+	if len(q.jobs) > 0 {
+		select {
+		case q.ready <- struct{}{}:
+		default:
+		}
+	}
+	return it.job, true
+}
+
+// item is a queued job with its heap position
+type item struct {
+	job   Job
+	index int
+}
+
+// jobHeap is a max-heap by priority
+type jobHeap []*item
+
+func (h jobHeap) Len() int           { return len(h) }
+func (h jobHeap) Less(i, j int) bool { return h[i].job.Priority > h[j].job.Priority }
+func (h jobHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *jobHeap) Push(x interface{}) {
+	it := x.(*item)
+	it.index = len(*h)
+	*h = append(*h, it)
+}
+
+func (h *jobHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	it := old[n-1]
+	old[n-1] = nil
+	*h = old[:n-1]
+	return it
+}
@@ -0,0 +1,181 @@
+package scraper
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	_ "image/gif" // Registered for image.DecodeConfig
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Preview image checks
+const (
+	imageProbeBytes = 64 * 1024 // Enough of an image for its dimensions
+	maxImageProbes  = 3         // Candidates checked per page
+	maxImageAspect  = 4         // Wider or taller than this is a banner or strip
+)
+
+// ImageInfo is what a partial download tells about a candidate image
+type ImageInfo struct {
+	URL         string
+	ContentType string
+	Width       int // 0 when the format's dimensions can't be read (e.g. AVIF)
+	Height      int
+}
+
+// uniqueImages trims candidates and drops empty and repeated ones, keeping
+// the first occurrence's position
+func uniqueImages(candidates []string) []string {
+	seen := make(map[string]bool)
+	var unique []string
+	for _, candidate := range candidates {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "" || seen[candidate] {
+			continue
+		}
+		seen[candidate] = true
+		unique = append(unique, candidate)
+	}
+	return unique
+}
+
+// resolveImages makes candidates absolute against the page URL, dropping
+// ones that aren't http(s) (data: URIs, mostly)
+func resolveImages(page *url.URL, candidates []string) []string {
+	var resolved []string
+	for _, candidate := range candidates {
+		u, err := page.Parse(candidate)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			continue
+		}
+		resolved = append(resolved, u.String())
+	}
+	return uniqueImages(resolved)
+}
+
+// selectImage sets the page's ImageURL to its largest usable candidate,
+// checking at most maxImageProbes of them. Images of unknown size rank below
+// any of known size. When none is usable ImageURL is cleared and
+// ImageMissing set.
+func (s *Scraper) selectImage(data *OGData) {
+	if s.minImageSize < 0 {
+		return
+	}
+
+	var best *ImageInfo
+	for i, candidate := range data.ImageCandidates {
+		if i == maxImageProbes {
+			break
+		}
+		info, err := s.probeImage(candidate)
+		if err != nil || s.checkImage(info) != nil {
+			continue
+		}
+		if best == nil || info.Width*info.Height > best.Width*best.Height {
+			best = info
+		}
+	}
+
+	if best == nil {
+		data.ImageURL = ""
+		data.ImageMissing = true
+		return
+	}
+	data.ImageURL = best.URL
+}
+
+// checkImage returns why an image can't be a link preview, or nil if it can
+func (s *Scraper) checkImage(info *ImageInfo) error {
+	switch {
+	case !strings.HasPrefix(info.ContentType, "image/"):
+		return fmt.Errorf("not an image: %q", info.ContentType)
+	case info.ContentType == "image/svg+xml":
+		return fmt.Errorf("SVG images render badly as previews")
+	case info.Width == 0:
+		return nil // Unknown size: give it the benefit of the doubt
+	case info.Width < s.minImageSize || info.Height < s.minImageSize:
+		return fmt.Errorf("too small: %dx%d", info.Width, info.Height)
+	case info.Width > maxImageAspect*info.Height || info.Height > maxImageAspect*info.Width:
+		return fmt.Errorf("odd aspect ratio: %dx%d", info.Width, info.Height)
+	}
+	return nil
+}
+
+// probeImage downloads the start of an image to read its type and
+// dimensions. Image hosts aren't the page's site, so probes skip the
+// per-domain rate limit and the scrape observer.
+func (s *Scraper) probeImage(imageURL string) (*ImageInfo, error) {
+	req, err := http.NewRequest("GET", imageURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("Accept", "image/avif,image/webp,image/apng,image/*,*/*;q=0.8")
+	req.Header.Set("Range", fmt.Sprintf("bytes=0-%d", imageProbeBytes-1))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusPartialContent {
+		return nil, fmt.Errorf("status code: %d", resp.StatusCode)
+	}
+
+	head, err := io.ReadAll(io.LimitReader(resp.Body, imageProbeBytes))
+	if err != nil {
+		return nil, err
+	}
+
+	info := &ImageInfo{URL: imageURL}
+	info.ContentType, _, _ = mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if info.ContentType == "" || info.ContentType == "application/octet-stream" {
+		info.ContentType = http.DetectContentType(head)
+	}
+	if bytes.Contains(head[:min(len(head), 512)], []byte("<svg")) {
+		info.ContentType = "image/svg+xml" // Often served as text/xml or text/plain
+	}
+
+	if config, _, err := image.DecodeConfig(bytes.NewReader(head)); err == nil {
+		info.Width, info.Height = config.Width, config.Height
+	} else {
+		info.Width, info.Height = webpSize(head)
+	}
+	return info, nil
+}
+
+// webpSize reads the dimensions from a WebP header, which the standard
+// library has no decoder for. Returns zeros if head isn't WebP.
+func webpSize(head []byte) (width, height int) {
+	if len(head) < 30 || string(head[0:4]) != "RIFF" || string(head[8:12]) != "WEBP" {
+		return 0, 0
+	}
+
+	switch string(head[12:16]) {
+	case "VP8 ": // Lossy: frame tag, start code, then 14-bit sizes
+		if !bytes.Equal(head[23:26], []byte{0x9d, 0x01, 0x2a}) {
+			return 0, 0
+		}
+		width = int(binary.LittleEndian.Uint16(head[26:28]) & 0x3fff)
+		height = int(binary.LittleEndian.Uint16(head[28:30]) & 0x3fff)
+	case "VP8L": // Lossless: signature, then 14-bit sizes minus one
+		if head[20] != 0x2f {
+			return 0, 0
+		}
+		bits := binary.LittleEndian.Uint32(head[21:25])
+		width = int(bits&0x3fff) + 1
+		height = int(bits>>14&0x3fff) + 1
+	case "VP8X": // Extended: flags, reserved, then 24-bit canvas sizes minus one
+		width = (int(head[24]) | int(head[25])<<8 | int(head[26])<<16) + 1
+		height = (int(head[27]) | int(head[28])<<8 | int(head[29])<<16) + 1
+	}
+	return width, height
+}
@@ -2,6 +2,8 @@ package scraper
 
 import (
 	"crypto/tls"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -11,6 +13,7 @@ import (
 	"time"
 
 	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html/charset"
 )
 
 // OGData holds OpenGraph metadata
@@ -18,6 +21,37 @@ type OGData struct {
 	Title       string
 	Description string
 	ImageURL    string
+	PublishedAt *time.Time  // Article publish time, if the page declares one
+	Events      []EventData // schema.org Event objects declared in JSON-LD
+
+	// ImageCandidates are the preview images the page declares, in order of
+	// preference: og:image, JSON-LD, twitter:image. A fetch picks ImageURL
+	// from them and sets ImageMissing when none is usable.
+	ImageCandidates []string
+	ImageMissing    bool
+
+	// HTTP cache validators from the response, for conditional re-fetches
+	ETag         string
+	LastModified string
+}
+
+// userAgent is a desktop browser's, since some sites refuse anything else
+const userAgent = "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36"
+
+// ErrNotModified is returned by FetchOGDataIfModified when the server
+// reports the page unchanged
+var ErrNotModified = errors.New("not modified")
+
+// ErrBlocked is returned when a site refuses the scraper (401, 403 or 451),
+// and without a request while its domain is on cooldown after repeated refusals
+var ErrBlocked = errors.New("blocked by site")
+
+// EventData holds the raw schema.org Event fields from a page
+type EventData struct {
+	Name      string
+	StartDate string
+	EndDate   string
+	Location  string
 }
 
 // DomainRateLimiter enforces per-domain rate limiting
@@ -49,13 +83,91 @@ func (d *DomainRateLimiter) Wait(domain string) {
 	d.lastRequest[domain] = time.Now()
 }
 
+// blockList tracks domains that keep refusing the scraper (age gates, bot
+// walls) so further attempts can be skipped for a while
+type blockList struct {
+	mu        sync.Mutex
+	domains   map[string]*blockState
+	threshold int           // Consecutive refusals before a domain is skipped
+	cooldown  time.Duration // How long a domain is skipped
+}
+
+type blockState struct {
+	refusals int
+	until    time.Time
+}
+
+func newBlockList(threshold int, cooldown time.Duration) *blockList {
+	return &blockList{domains: make(map[string]*blockState), threshold: threshold, cooldown: cooldown}
+}
+
+// blocked reports whether domain is on cooldown
+func (b *blockList) blocked(domain string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	state, ok := b.domains[domain]
+	return ok && time.Now().Before(state.until)
+}
+
+// record counts a refusal (or clears the domain's count after a success),
+// starting a cooldown once the threshold is reached
+func (b *blockList) record(domain string, refused bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !refused {
+		delete(b.domains, domain)
+		return
+	}
+	state, ok := b.domains[domain]
+	if !ok {
+		state = &blockState{}
+		b.domains[domain] = state
+	}
+	state.refusals++
+	if state.refusals >= b.threshold {
+		state.until = time.Now().Add(b.cooldown)
+		state.refusals = 0
+	}
+}
+
+// Attempt is the outcome of one request made by the scraper
+type Attempt struct {
+	Domain  string
+	Status  int // 0 when no response was received
+	Latency time.Duration
+	Err     error // Transport error, if no response was received
+}
+
 // Scraper fetches OpenGraph data from URLs
 type Scraper struct {
 	client       *http.Client
 	http1Client  *http.Client
 	rateLimiter  *DomainRateLimiter
+	blocks       *blockList
 	maxBodySize  int64
 	maxRetries   int
+	minImageSize int // Smallest usable preview image side in pixels (-1 = don't check images)
+	observe      func(Attempt)
+}
+
+// Observe sets a function called with the outcome of every request,
+// including retries. Set it before the scraper is used.
+func (s *Scraper) Observe(fn func(Attempt)) {
+	s.observe = fn
+}
+
+// do sends a request, reporting its outcome to the observer
+func (s *Scraper) do(client *http.Client, req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := client.Do(req)
+	if s.observe != nil {
+		attempt := Attempt{Domain: req.URL.Host, Latency: time.Since(start), Err: err}
+		if resp != nil {
+			attempt.Status = resp.StatusCode
+		}
+		s.observe(attempt)
+	}
+	return resp, err
 }
 
 // NewScraper creates a new scraper
@@ -85,22 +197,40 @@ func NewScraper() *Scraper {
 	}
 
 	return &Scraper{
-		client:      client,
-		http1Client: http1Client,
-		rateLimiter: NewDomainRateLimiter(1 * time.Second), // 1 req/sec per domain
-		maxBodySize: 1024 * 1024,                           // 1MB limit
-		maxRetries:  2,                                     // Retry transient errors twice
+		client:       client,
+		http1Client:  http1Client,
+		rateLimiter:  NewDomainRateLimiter(1 * time.Second), // 1 req/sec per domain
+		blocks:       newBlockList(3, time.Hour),            // Skip a domain for an hour after 3 refusals in a row
+		maxBodySize:  1024 * 1024,                           // 1MB limit
+		maxRetries:   2,                                     // Retry transient errors twice
+		minImageSize: 200,                                   // Smaller images are icons or tracking pixels
 	}
 }
 
 // FetchOGData fetches OpenGraph metadata from a URL with retry logic
 func (s *Scraper) FetchOGData(urlStr string) (*OGData, error) {
+	return s.fetch(urlStr, "", "")
+}
+
+// FetchOGDataIfModified re-fetches metadata with a conditional request using
+// validators from a previous fetch (either may be empty). Returns
+// ErrNotModified when the server answers 304.
+func (s *Scraper) FetchOGDataIfModified(urlStr, etag, lastModified string) (*OGData, error) {
+	return s.fetch(urlStr, etag, lastModified)
+}
+
+func (s *Scraper) fetch(urlStr, etag, lastModified string) (*OGData, error) {
 	// Extract domain for rate limiting
 	domain, err := extractDomain(urlStr)
 	if err != nil {
 		return nil, fmt.Errorf("invalid URL: %w", err)
 	}
 
+	// Don't retry sites that keep refusing us
+	if s.blocks.blocked(domain) {
+		return nil, fmt.Errorf("%w: %s on cooldown", ErrBlocked, domain)
+	}
+
 	// Rate limit per domain
 	s.rateLimiter.Wait(domain)
 
@@ -109,8 +239,12 @@ func (s *Scraper) FetchOGData(urlStr string) (*OGData, error) {
 	var lastErr error
 
 	for attempt := 0; attempt <= s.maxRetries; attempt++ {
-		data, err := s.fetchOnce(urlStr)
+		data, err := s.fetchOnce(urlStr, etag, lastModified)
+		if err == nil || err == ErrNotModified || errors.Is(err, ErrBlocked) {
+			s.blocks.record(domain, errors.Is(err, ErrBlocked))
+		}
 		if err == nil {
+			s.selectImage(data)
 			return data, nil
 		}
 
@@ -132,14 +266,14 @@ func (s *Scraper) FetchOGData(urlStr string) (*OGData, error) {
 }
 
 // fetchOnce attempts to fetch OG data once, with HTTP/2 fallback
-func (s *Scraper) fetchOnce(urlStr string) (*OGData, error) {
+func (s *Scraper) fetchOnce(urlStr, etag, lastModified string) (*OGData, error) {
 	// Try with default HTTP/2 client first
-	data, err := s.fetchWithClient(urlStr, s.client)
+	data, err := s.fetchWithClient(urlStr, s.client, etag, lastModified)
 	if err != nil {
 		// Check if it's an HTTP/2 stream error
 		if strings.Contains(err.Error(), "stream error") || strings.Contains(err.Error(), "INTERNAL_ERROR") {
 			// Retry with HTTP/1.1 client
-			return s.fetchWithClient(urlStr, s.http1Client)
+			return s.fetchWithClient(urlStr, s.http1Client, etag, lastModified)
 		}
 		return nil, err
 	}
@@ -188,26 +322,39 @@ func isRetryableError(err error) bool {
 }
 
 // fetchWithClient performs the actual HTTP request with the given client
-func (s *Scraper) fetchWithClient(urlStr string, client *http.Client) (*OGData, error) {
+func (s *Scraper) fetchWithClient(urlStr string, client *http.Client, etag, lastModified string) (*OGData, error) {
 	req, err := http.NewRequest("GET", urlStr, nil)
 	if err != nil {
 		return nil, err
 	}
 
 	// Set browser-like headers
-	req.Header.Set("User-Agent", "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36")
+	req.Header.Set("User-Agent", userAgent)
 	req.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,image/apng,*/*;q=0.8")
 	req.Header.Set("Accept-Language", "en-US,en;q=0.9")
 	req.Header.Set("Accept-Encoding", "gzip, deflate, br")
 	req.Header.Set("Cache-Control", "no-cache")
 	req.Header.Set("Pragma", "no-cache")
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+	if lastModified != "" {
+		req.Header.Set("If-Modified-Since", lastModified)
+	}
 
-	resp, err := client.Do(req)
+	resp, err := s.do(client, req)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusNotModified {
+		return nil, ErrNotModified
+	}
+	switch resp.StatusCode {
+	case http.StatusUnauthorized, http.StatusForbidden, http.StatusUnavailableForLegalReasons:
+		return nil, fmt.Errorf("%w (status code: %d)", ErrBlocked, resp.StatusCode)
+	}
 	if resp.StatusCode != http.StatusOK {
 		return nil, fmt.Errorf("status code: %d", resp.StatusCode)
 	}
@@ -215,12 +362,36 @@ func (s *Scraper) fetchWithClient(urlStr string, client *http.Client) (*OGData,
 	// Limit body size to prevent reading huge files
 	limitedReader := io.LimitReader(resp.Body, s.maxBodySize)
 
-	doc, err := goquery.NewDocumentFromReader(limitedReader)
+	data, err := ParseOGData(limitedReader, resp.Header.Get("Content-Type"))
+	if err != nil {
+		return nil, err
+	}
+	data.ETag = resp.Header.Get("ETag")
+	data.LastModified = resp.Header.Get("Last-Modified")
+	data.ImageCandidates = resolveImages(resp.Request.URL, data.ImageCandidates)
+	data.ImageURL = ""
+	if len(data.ImageCandidates) > 0 {
+		data.ImageURL = data.ImageCandidates[0]
+	}
+	return data, nil
+}
+
+// ParseOGData extracts metadata from an HTML document. contentType is the
+// response Content-Type header (may be empty); it and any <meta charset> are
+// used to decode non-UTF-8 pages.
+func ParseOGData(r io.Reader, contentType string) (*OGData, error) {
+	utf8Reader, err := charset.NewReader(r, contentType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode charset: %w", err)
+	}
+
+	doc, err := goquery.NewDocumentFromReader(utf8Reader)
 	if err != nil {
 		return nil, err
 	}
 
 	data := &OGData{}
+	var published string
 
 	// Extract OpenGraph tags
 	doc.Find("meta").Each(func(i int, s *goquery.Selection) {
@@ -233,10 +404,25 @@ func (s *Scraper) fetchWithClient(urlStr string, client *http.Client) (*OGData,
 		case "og:description":
 			data.Description = content
 		case "og:image":
-			data.ImageURL = content
+			data.ImageCandidates = append(data.ImageCandidates, content)
+		case "article:published_time":
+			published = content
 		}
 	})
 
+	// Fill gaps from schema.org JSON-LD (common on sites without OG tags)
+	ld := extractJSONLD(doc)
+	if data.Title == "" {
+		data.Title = ld.Headline
+	}
+	if data.Description == "" {
+		data.Description = ld.Description
+	}
+	if published == "" {
+		published = ld.DatePublished
+	}
+	data.ImageCandidates = append(data.ImageCandidates, ld.ImageURL)
+
 	// Fallback to standard HTML tags if OG tags not found
 	if data.Title == "" {
 		data.Title = strings.TrimSpace(doc.Find("title").First().Text())
@@ -249,13 +435,204 @@ func (s *Scraper) fetchWithClient(urlStr string, client *http.Client) (*OGData,
 		}
 	}
 
-	// Try Twitter card as fallback for image
-	if data.ImageURL == "" {
-		twitterImage, exists := doc.Find("meta[name='twitter:image']").Attr("content")
-		if exists {
-			data.ImageURL = twitterImage
+	// Twitter card images come last
+	doc.Find("meta[name='twitter:image'], meta[name='twitter:image:src']").Each(func(i int, s *goquery.Selection) {
+		data.ImageCandidates = append(data.ImageCandidates, s.AttrOr("content", ""))
+	})
+
+	data.Events = extractJSONLDEvents(doc)
+
+	if published == "" {
+		if t, exists := doc.Find("time[datetime]").First().Attr("datetime"); exists {
+			published = t
 		}
 	}
+	data.PublishedAt = parsePublishedTime(published)
+
+	data.Title = strings.TrimSpace(data.Title)
+	data.Description = strings.TrimSpace(data.Description)
+	data.ImageCandidates = uniqueImages(data.ImageCandidates)
+	if len(data.ImageCandidates) > 0 {
+		data.ImageURL = data.ImageCandidates[0]
+	}
 
 	return data, nil
 }
+
+// jsonLDArticle holds the schema.org fields we use from JSON-LD blocks
+type jsonLDArticle struct {
+	Headline      string
+	Description   string
+	ImageURL      string
+	DatePublished string
+}
+
+// extractJSONLD returns the first article-like object found in the page's
+// application/ld+json blocks, including objects nested in @graph arrays
+func extractJSONLD(doc *goquery.Document) jsonLDArticle {
+	var result jsonLDArticle
+
+	doc.Find(`script[type="application/ld+json"]`).EachWithBreak(func(i int, s *goquery.Selection) bool {
+		var raw interface{}
+		if err := json.Unmarshal([]byte(s.Text()), &raw); err != nil {
+			return true // Malformed JSON-LD is common; skip it
+		}
+
+		for _, obj := range flattenJSONLD(raw) {
+			headline := jsonLDString(obj["headline"])
+			if headline == "" {
+				headline = jsonLDString(obj["name"])
+			}
+			if headline == "" {
+				continue
+			}
+
+			result = jsonLDArticle{
+				Headline:      headline,
+				Description:   jsonLDString(obj["description"]),
+				ImageURL:      jsonLDString(obj["image"]),
+				DatePublished: jsonLDString(obj["datePublished"]),
+			}
+			if result.DatePublished != "" || isArticleType(obj["@type"]) {
+				return false
+			}
+		}
+		return true
+	})
+
+	return result
+}
+
+// extractJSONLDEvents returns every schema.org Event (or subtype, e.g.
+// SportsEvent) with a start date found in the page's JSON-LD blocks
+func extractJSONLDEvents(doc *goquery.Document) []EventData {
+	var events []EventData
+
+	doc.Find(`script[type="application/ld+json"]`).Each(func(i int, s *goquery.Selection) {
+		var raw interface{}
+		if err := json.Unmarshal([]byte(s.Text()), &raw); err != nil {
+			return
+		}
+
+		for _, obj := range flattenJSONLD(raw) {
+			if !isJSONLDType(obj["@type"], "Event") {
+				continue
+			}
+			event := EventData{
+				Name:      strings.TrimSpace(jsonLDString(obj["name"])),
+				StartDate: strings.TrimSpace(jsonLDString(obj["startDate"])),
+				EndDate:   strings.TrimSpace(jsonLDString(obj["endDate"])),
+				Location:  strings.TrimSpace(jsonLDName(obj["location"])),
+			}
+			if event.Name != "" && event.StartDate != "" {
+				events = append(events, event)
+			}
+		}
+	})
+
+	return events
+}
+
+// flattenJSONLD returns every object in a JSON-LD value (top-level arrays and @graph)
+func flattenJSONLD(v interface{}) []map[string]interface{} {
+	switch val := v.(type) {
+	case []interface{}:
+		var objs []map[string]interface{}
+		for _, item := range val {
+			objs = append(objs, flattenJSONLD(item)...)
+		}
+		return objs
+	case map[string]interface{}:
+		objs := []map[string]interface{}{val}
+		if graph, ok := val["@graph"]; ok {
+			objs = append(objs, flattenJSONLD(graph)...)
+		}
+		return objs
+	}
+	return nil
+}
+
+// jsonLDString reads a JSON-LD value that may be a string, an array, or an
+// object with a url field (the usual shape of "image")
+func jsonLDString(v interface{}) string {
+	switch val := v.(type) {
+	case string:
+		return val
+	case []interface{}:
+		if len(val) > 0 {
+			return jsonLDString(val[0])
+		}
+	case map[string]interface{}:
+		if u, ok := val["url"].(string); ok {
+			return u
+		}
+	}
+	return ""
+}
+
+// jsonLDName reads a JSON-LD value that may be a string or an object with a
+// name field (the usual shape of "location"), falling back to an address
+func jsonLDName(v interface{}) string {
+	switch val := v.(type) {
+	case string:
+		return val
+	case []interface{}:
+		if len(val) > 0 {
+			return jsonLDName(val[0])
+		}
+	case map[string]interface{}:
+		if name, ok := val["name"].(string); ok && name != "" {
+			return name
+		}
+		if address, ok := val["address"]; ok {
+			if addr, ok := address.(map[string]interface{}); ok {
+				return jsonLDString(addr["addressLocality"])
+			}
+			return jsonLDName(address)
+		}
+	}
+	return ""
+}
+
+// isArticleType reports whether a JSON-LD @type names an article
+func isArticleType(v interface{}) bool {
+	return isJSONLDType(v, "Article")
+}
+
+// isJSONLDType reports whether a JSON-LD @type (string or array) names a type
+// ending in suffix, e.g. NewsArticle for "Article"
+func isJSONLDType(v interface{}, suffix string) bool {
+	types := []interface{}{v}
+	if arr, ok := v.([]interface{}); ok {
+		types = arr
+	}
+	for _, t := range types {
+		if name, ok := t.(string); ok && strings.HasSuffix(name, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// publishedTimeLayouts are the date formats seen in published-time metadata
+var publishedTimeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05Z0700",
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02",
+}
+
+// parsePublishedTime parses a published timestamp, returning nil if unrecognized
+func parsePublishedTime(value string) *time.Time {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil
+	}
+	for _, layout := range publishedTimeLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return &t
+		}
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+package scraper
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// update rewrites the golden files from the current parser output:
+// go test ./internal/scraper -run TestParseOGDataGolden -update
+var update = flag.Bool("update", false, "rewrite testdata/*.golden.json")
+
+// goldenOGData is the part of OGData that ParseOGData fills
+type goldenOGData struct {
+	Title           string      `json:"title"`
+	Description     string      `json:"description"`
+	ImageURL        string      `json:"image_url"`
+	ImageCandidates []string    `json:"image_candidates"`
+	PublishedAt     *time.Time  `json:"published_at"`
+	Events          []EventData `json:"events"`
+}
+
+// TestParseOGDataGolden parses each saved page in testdata and compares the
+// extracted metadata with its .golden.json file
+func TestParseOGDataGolden(t *testing.T) {
+	tests := []struct {
+		page        string
+		contentType string // Response Content-Type header
+	}{
+		{"og_complete.html", ""},                                 // OG tags win over <title>, description and Twitter
+		{"og_missing.html", "text/html; charset=utf-8"},          // <title>, meta description and <time> fallbacks
+		{"twitter_only.html", ""},                                // Twitter card images only
+		{"jsonld_only.html", "text/html"},                        // NewsArticle JSON-LD, image object
+		{"jsonld_graph.html", ""},                                // Malformed block skipped, article found in @graph
+		{"og_with_jsonld_gaps.html", ""},                         // JSON-LD fills what OG lacks
+		{"paywalled.html", "text/html; charset=utf-8"},           // Metadata present despite the paywall
+		{"consent_wall.html", ""},                                // Consent page: only its own <title>
+		{"event.html", ""},                                       // schema.org events
+		{"non_utf8_meta.html", ""},                               // ISO-8859-1 declared in <meta>
+		{"non_utf8_header.html", "text/html; charset=Shift_JIS"}, // Shift_JIS declared only in the header
+	}
+
+	for _, tt := range tests {
+		t.Run(strings.TrimSuffix(tt.page, ".html"), func(t *testing.T) {
+			page, err := os.ReadFile(filepath.Join("testdata", tt.page))
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, err := ParseOGData(bytes.NewReader(page), tt.contentType)
+			if err != nil {
+				t.Fatalf("ParseOGData: %v", err)
+			}
+			got, err := json.MarshalIndent(goldenOGData{
+				Title:           data.Title,
+				Description:     data.Description,
+				ImageURL:        data.ImageURL,
+				ImageCandidates: data.ImageCandidates,
+				PublishedAt:     data.PublishedAt,
+				Events:          data.Events,
+			}, "", "  ")
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, '\n')
+
+			golden := filepath.Join("testdata", strings.TrimSuffix(tt.page, ".html")+".golden.json")
+			if *update {
+				if err := os.WriteFile(golden, got, 0644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("%v (run with -update to create it)", err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("ParseOGData(%s) =\n%s\nwant\n%s", tt.page, got, want)
+			}
+		})
+	}
+}
+
+// TestParsePublishedTime covers the date layouts sites declare
+func TestParsePublishedTime(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string // RFC 3339, "" = nil
+	}{
+		{"2024-05-14T09:30:00Z", "2024-05-14T09:30:00Z"},
+		{"2024-02-29T18:05:00+01:00", "2024-02-29T18:05:00+01:00"},
+		{"2024-06-01T05:00:00.000Z", "2024-06-01T05:00:00Z"},
+		{"2024-03-10T07:00:00+0200", "2024-03-10T07:00:00+02:00"},
+		{"2024-03-10T07:00:00", "2024-03-10T07:00:00Z"},
+		{"2024-03-10T07:00", "2024-03-10T07:00:00Z"},
+		{" 2023-11-02 ", "2023-11-02T00:00:00Z"},
+		{"November 2, 2023", ""},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		got := parsePublishedTime(tt.value)
+		switch {
+		case tt.want == "" && got != nil:
+			t.Errorf("parsePublishedTime(%q) = %v, want nil", tt.value, got)
+		case tt.want != "" && (got == nil || got.Format(time.RFC3339) != tt.want):
+			t.Errorf("parsePublishedTime(%q) = %v, want %s", tt.value, got, tt.want)
+		}
+	}
+}
+
+// BenchmarkParseOGData measures parsing each saved page in testdata
+func BenchmarkParseOGData(b *testing.B) {
+	pages, err := filepath.Glob(filepath.Join("testdata", "*.html"))
+	if err != nil || len(pages) == 0 {
+		b.Fatalf("no testdata pages: %v", err)
+	}
+	var corpus [][]byte
+	for _, page := range pages {
+		data, err := os.ReadFile(page)
+		if err != nil {
+			b.Fatal(err)
+		}
+		corpus = append(corpus, data)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		page := corpus[i%len(corpus)]
+		if _, err := ParseOGData(bytes.NewReader(page), ""); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package scraper
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// CheckStatus returns the HTTP status a URL answers with, following
+// redirects. It sends a HEAD request, falling back to GET for servers that
+// refuse HEAD. Domains on cooldown return ErrBlocked without a request.
+func (s *Scraper) CheckStatus(urlStr string) (int, error) {
+	domain, err := extractDomain(urlStr)
+	if err != nil {
+		return 0, fmt.Errorf("invalid URL: %w", err)
+	}
+	if s.blocks.blocked(domain) {
+		return 0, fmt.Errorf("%w: %s on cooldown", ErrBlocked, domain)
+	}
+	s.rateLimiter.Wait(domain)
+
+	status, err := s.statusWithMethod(http.MethodHead, urlStr)
+	if err != nil {
+		return 0, err
+	}
+	switch status {
+	case http.StatusMethodNotAllowed, http.StatusNotImplemented, http.StatusForbidden:
+		return s.statusWithMethod(http.MethodGet, urlStr)
+	}
+	return status, nil
+}
+
+func (s *Scraper) statusWithMethod(method, urlStr string) (int, error) {
+	req, err := http.NewRequest(method, urlStr, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("User-Agent", "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36")
+	req.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8")
+
+	resp, err := s.do(s.client, req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024)) // Let the connection be reused
+	return resp.StatusCode, nil
+}
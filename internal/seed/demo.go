@@ -0,0 +1,297 @@
+package seed
+
+import (
+	"fmt"
+	"log"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/database"
+)
+
+// DemoConfig controls a demo data set: a small, display-friendly network
+// for frontend work and ranking experiments, rather than load-test volume
+type DemoConfig struct {
+	RunID    string        // Tag for generated rows
+	Follows  int           // 1st-degree accounts, also stored as follows
+	Accounts int           // All authoring accounts (the rest are 2nd-degree)
+	Links    int           // Distinct links
+	Posts    int           // Posts, including the ones sharing pattern links
+	Window   time.Duration // Posts are spread over this window ending now
+	RandSeed int64         // Same seed, same data (timestamps are relative to now)
+}
+
+// DefaultDemoConfig returns a demo data set that fills a trending page
+func DefaultDemoConfig() DemoConfig {
+	return DemoConfig{
+		RunID:    "demo",
+		Follows:  60,
+		Accounts: 400,
+		Links:    150,
+		Posts:    3000,
+		Window:   24 * time.Hour,
+		RandSeed: 1,
+	}
+}
+
+// Trending patterns given to the first demo links, so ranking changes show
+// up against known shapes
+var demoPatterns = []struct {
+	name     string
+	shares   int
+	from, to float64 // Share times, as fractions of the window back from now
+	degree   int     // Only accounts of this degree share it (0 = any)
+}{
+	{"breaking", 60, 0, 0.08, 0}, // A burst in the last couple of hours
+	{"steady", 40, 0, 1, 0},      // Shared evenly all day
+	{"fading", 50, 0.75, 1, 0},   // Big early, quiet since
+	{"niche", 25, 0, 1, 2},       // Only the extended network cares
+}
+
+var (
+	demoDomains = []string{
+		"dailyplanet.example", "metroherald.example", "techwire.example",
+		"sciencenow.example", "civicpost.example", "sportsdesk.example",
+	}
+	demoSubjects = []string{
+		"City council", "Researchers", "Regulators", "The central bank",
+		"Local farmers", "A startup", "Voters", "Astronomers", "Transit officials",
+		"The league", "Health officials", "Open-source maintainers",
+	}
+	demoEvents = []string{
+		"approve new budget", "report surprising results", "propose stricter rules",
+		"hold rates steady", "brace for drought", "raise new funding",
+		"head to the polls", "spot a rare comet", "unveil expansion plans",
+		"announce schedule changes", "issue new guidance", "ship a major release",
+	}
+	demoComments = []string{
+		"Worth a read:", "This is big.", "Huh, didn't see this coming.",
+		"Important context here.", "Sharing for anyone following this.",
+		"Good explainer.", "Thread-worthy news.", "",
+	}
+)
+
+// Demo loads a demo data set: follows and their 2nd-degree network, links
+// with full metadata across a few news domains, and posts sharing them.
+// The first links follow fixed trending patterns (a breaking burst, a
+// steady riser, a fading story, a 2nd-degree niche); the rest share the
+// remaining posts with Zipf-distributed popularity. Rows are tagged with
+// the run ID, so Remove deletes them.
+func Demo(db *database.DB, config DemoConfig) (*Result, error) {
+	if config.RunID == "" {
+		config.RunID = "demo"
+	}
+	if config.Follows <= 0 || config.Accounts < config.Follows || config.Links <= len(demoPatterns) || config.Posts <= 0 {
+		return nil, fmt.Errorf("follows, links and posts must be positive, accounts at least follows, and links more than %d", len(demoPatterns))
+	}
+
+	start := time.Now()
+	rng := rand.New(rand.NewSource(config.RandSeed))
+	result := &Result{RunID: config.RunID}
+	now := time.Now().UTC()
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Accounts: the first Follows are followed directly
+	dids := AccountDIDs(config.RunID, config.Accounts)
+	handles := make([]string, config.Accounts)
+	degrees := make([]int, config.Accounts)
+	for i := range dids {
+		handles[i] = fmt.Sprintf("user%d.%s.test", i, config.RunID)
+		degrees[i] = 2
+		if i < config.Follows {
+			degrees[i] = 1
+		}
+	}
+
+	stmt, err := tx.Prepare(pq.CopyIn("follows", "did", "handle", "display_name", "added_at", "backfill_completed"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare follow copy: %w", err)
+	}
+	for i := 0; i < config.Follows; i++ {
+		if _, err := stmt.Exec(dids[i], handles[i], fmt.Sprintf("Demo User %d", i), now.Add(-30*24*time.Hour), true); err != nil {
+			return nil, fmt.Errorf("failed to copy follow: %w", err)
+		}
+	}
+	if err := closeCopy(stmt); err != nil {
+		return nil, err
+	}
+
+	stmt, err = tx.Prepare(pq.CopyIn("network_accounts", "did", "handle", "display_name", "degree", "source_count"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare account copy: %w", err)
+	}
+	for i := range dids {
+		if _, err := stmt.Exec(dids[i], handles[i], fmt.Sprintf("Demo User %d", i), degrees[i], 1+rng.Intn(5)); err != nil {
+			return nil, fmt.Errorf("failed to copy account: %w", err)
+		}
+	}
+	if err := closeCopy(stmt); err != nil {
+		return nil, err
+	}
+	result.Accounts = config.Accounts
+	log.Printf("[SEED] Loaded %d accounts (%d follows)", result.Accounts, config.Follows)
+
+	// Links with full metadata
+	stmt, err = tx.Prepare(pq.CopyIn("links", "original_url", "normalized_url", "title", "description",
+		"og_image_url", "first_seen_at", "last_fetched_at", "metadata_source"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare link copy: %w", err)
+	}
+	firstSeen := now.Add(-config.Window)
+	urls := make([]string, config.Links)
+	for i := range urls {
+		u := demoLinkURL(config.RunID, i, demoDomains[rng.Intn(len(demoDomains))])
+		urls[i] = u
+		subject := demoSubjects[rng.Intn(len(demoSubjects))]
+		event := demoEvents[rng.Intn(len(demoEvents))]
+		title := fmt.Sprintf("%s %s", subject, event)
+		description := fmt.Sprintf("%s %s, in a story that is still developing. Demo article %d.", subject, event, i)
+		image := fmt.Sprintf("https://picsum.photos/seed/%s-%d/1200/630", config.RunID, i)
+		if _, err := stmt.Exec(u, u, title, description, image, firstSeen, firstSeen, database.MetadataSourceScraped); err != nil {
+			return nil, fmt.Errorf("failed to copy link: %w", err)
+		}
+	}
+	if err := closeCopy(stmt); err != nil {
+		return nil, err
+	}
+	result.Links = config.Links
+
+	var linkIDs []int
+	rows, err := tx.Query(`SELECT id FROM links WHERE normalized_url LIKE $1 ORDER BY id`, demoLinkPattern(config.RunID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load link IDs: %w", err)
+	}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		linkIDs = append(linkIDs, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to load link IDs: %w", err)
+	}
+	log.Printf("[SEED] Loaded %d links", result.Links)
+	for p, pattern := range demoPatterns {
+		log.Printf("[SEED] %s pattern: %s", pattern.name, urls[p])
+	}
+
+	// Posts: pattern shares first, then a Zipf long tail
+	type share struct {
+		author    int
+		linkIndex int // -1 = no link
+		createdAt time.Time
+	}
+	var shares []share
+	window := float64(config.Window)
+	at := func(from, to float64) time.Time {
+		return now.Add(-time.Duration(window * (from + rng.Float64()*(to-from))))
+	}
+
+	for p, pattern := range demoPatterns {
+		authors := rng.Perm(config.Accounts)
+		n := 0
+		for _, author := range authors {
+			if n == pattern.shares {
+				break
+			}
+			if pattern.degree != 0 && degrees[author] != pattern.degree {
+				continue
+			}
+			shares = append(shares, share{author, p, at(pattern.from, pattern.to)})
+			n++
+		}
+	}
+
+	zipf := rand.NewZipf(rng, 1.2, 1, uint64(config.Links-len(demoPatterns)-1))
+	for len(shares) < config.Posts {
+		s := share{author: rng.Intn(config.Accounts), linkIndex: -1, createdAt: at(0, 1)}
+		if rng.Float64() < 0.6 {
+			s.linkIndex = len(demoPatterns) + int(zipf.Uint64())
+		}
+		shares = append(shares, s)
+	}
+
+	stmt, err = tx.Prepare(pq.CopyIn("posts", "id", "author_handle", "author_did", "author_degree", "content", "is_reply", "created_at"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare post copy: %w", err)
+	}
+	for i, s := range shares {
+		postID := fmt.Sprintf("at://%s/app.bsky.feed.post/%d", dids[s.author], i)
+		content := fmt.Sprintf("Just thinking out loud (demo post %d)", i)
+		if s.linkIndex >= 0 {
+			content = strings.TrimSpace(demoComments[rng.Intn(len(demoComments))] + " " + urls[s.linkIndex])
+		}
+		isReply := rng.Float64() < 0.15
+		if _, err := stmt.Exec(postID, handles[s.author], dids[s.author], degrees[s.author], content, isReply, s.createdAt); err != nil {
+			return nil, fmt.Errorf("failed to copy post: %w", err)
+		}
+	}
+	if err := closeCopy(stmt); err != nil {
+		return nil, err
+	}
+	result.Posts = len(shares)
+	log.Printf("[SEED] Loaded %d posts", result.Posts)
+
+	stmt, err = tx.Prepare(pq.CopyIn("post_links", "post_id", "link_id", "degree", "post_created_at"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare post_links copy: %w", err)
+	}
+	seen := make(map[[2]int]bool) // One share per account and link
+	for i, s := range shares {
+		if s.linkIndex < 0 || seen[[2]int{s.author, s.linkIndex}] {
+			continue
+		}
+		seen[[2]int{s.author, s.linkIndex}] = true
+		postID := fmt.Sprintf("at://%s/app.bsky.feed.post/%d", dids[s.author], i)
+		if _, err := stmt.Exec(postID, linkIDs[s.linkIndex], degrees[s.author], s.createdAt); err != nil {
+			return nil, fmt.Errorf("failed to copy post_link: %w", err)
+		}
+		result.PostLinks++
+	}
+	if err := closeCopy(stmt); err != nil {
+		return nil, err
+	}
+	log.Printf("[SEED] Loaded %d post_links", result.PostLinks)
+
+	// Credit each link's earliest sharer, as ingestion does
+	_, err = tx.Exec(`
+		UPDATE links l
+		SET first_shared_by = first.author_did, first_shared_at = first.created_at
+		FROM (
+			SELECT DISTINCT ON (pl.link_id) pl.link_id, p.author_did, p.created_at
+			FROM post_links pl
+			JOIN posts p ON p.id = pl.post_id
+			WHERE pl.link_id = ANY($1)
+			ORDER BY pl.link_id, p.created_at
+		) first
+		WHERE l.id = first.link_id
+	`, pq.Array(linkIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to set first sharers: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit demo data: %w", err)
+	}
+
+	result.Duration = time.Since(start)
+	return result, nil
+}
+
+func demoLinkURL(runID string, i int, domain string) string {
+	return fmt.Sprintf("https://%s/%s/story/%d", domain, runID, i)
+}
+
+func demoLinkPattern(runID string) string {
+	return fmt.Sprintf("https://%%.example/%s/story/%%", runID)
+}
@@ -0,0 +1,256 @@
+// Package seed generates large volumes of synthetic posts, links and network
+// accounts for load testing the trending queries and ingest pipeline.
+//
+// Rows are bulk-loaded with COPY. Every generated row is tagged with a run ID
+// (in DIDs, post URIs and link URLs) so a run can be removed with Remove.
+package seed
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"math/rand"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/database"
+)
+
+// Config controls the size and shape of generated data
+type Config struct {
+	RunID      string        // Tag for generated rows (defaults to a timestamp)
+	Posts      int           // Number of posts
+	Links      int           // Number of distinct links
+	Accounts   int           // Number of authoring accounts
+	Window     time.Duration // Posts are spread uniformly over this window ending now
+	LinkRatio  float64       // Fraction of posts that share a link
+	ReplyRatio float64       // Fraction of posts that are replies
+	Skew       float64       // Zipf exponent for link popularity (> 1; higher = more concentrated)
+	RandSeed   int64         // Seed for reproducible data
+}
+
+// Result summarizes a generation run
+type Result struct {
+	RunID     string
+	Accounts  int
+	Links     int
+	Posts     int
+	PostLinks int
+	Duration  time.Duration
+}
+
+// DefaultConfig returns a configuration sized like a busy production day
+func DefaultConfig() Config {
+	return Config{
+		Posts:      1000000,
+		Links:      200000,
+		Accounts:   20000,
+		Window:     24 * time.Hour,
+		LinkRatio:  0.6,
+		ReplyRatio: 0.3,
+		Skew:       1.1,
+		RandSeed:   1,
+	}
+}
+
+// Generate bulk-loads synthetic data into the database in a single transaction
+func Generate(db *database.DB, config Config) (*Result, error) {
+	if config.RunID == "" {
+		config.RunID = fmt.Sprintf("seed%d", time.Now().Unix())
+	}
+	if config.Accounts <= 0 || config.Links <= 0 || config.Posts <= 0 {
+		return nil, fmt.Errorf("posts, links and accounts must be positive")
+	}
+	if config.Skew <= 1 {
+		config.Skew = 1.1
+	}
+
+	start := time.Now()
+	rng := rand.New(rand.NewSource(config.RandSeed))
+	result := &Result{RunID: config.RunID}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Network accounts: roughly a third 1st-degree, the rest 2nd-degree
+	dids := AccountDIDs(config.RunID, config.Accounts)
+	degrees := make([]int, config.Accounts)
+	stmt, err := tx.Prepare(pq.CopyIn("network_accounts", "did", "handle", "degree", "source_count"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare account copy: %w", err)
+	}
+	for i := range dids {
+		degrees[i] = 2
+		if i%3 == 0 {
+			degrees[i] = 1
+		}
+		handle := fmt.Sprintf("user%d.%s.test", i, config.RunID)
+		if _, err := stmt.Exec(dids[i], handle, degrees[i], 1+rng.Intn(5)); err != nil {
+			return nil, fmt.Errorf("failed to copy account: %w", err)
+		}
+	}
+	if err := closeCopy(stmt); err != nil {
+		return nil, err
+	}
+	result.Accounts = config.Accounts
+	log.Printf("[SEED] Loaded %d accounts", result.Accounts)
+
+	// Links (half with metadata, like a partially fetched production table)
+	stmt, err = tx.Prepare(pq.CopyIn("links", "original_url", "normalized_url", "title", "og_image_url"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare link copy: %w", err)
+	}
+	for i := 0; i < config.Links; i++ {
+		u := linkURL(config.RunID, i)
+		var title, image interface{}
+		if i%2 == 0 {
+			title = fmt.Sprintf("Seed article %d", i)
+			image = fmt.Sprintf("https://loadtest.example.com/img/%d.jpg", i)
+		}
+		if _, err := stmt.Exec(u, u, title, image); err != nil {
+			return nil, fmt.Errorf("failed to copy link: %w", err)
+		}
+	}
+	if err := closeCopy(stmt); err != nil {
+		return nil, err
+	}
+	result.Links = config.Links
+
+	linkIDs := make([]int, 0, config.Links)
+	rows, err := tx.Query(`SELECT id FROM links WHERE normalized_url LIKE $1 ORDER BY id`, linkPattern(config.RunID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load link IDs: %w", err)
+	}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		linkIDs = append(linkIDs, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to load link IDs: %w", err)
+	}
+	log.Printf("[SEED] Loaded %d links", result.Links)
+
+	// Posts, with share counts following a Zipf distribution over links
+	zipf := rand.NewZipf(rng, config.Skew, 1, uint64(len(linkIDs)-1))
+	now := time.Now().UTC()
+	windowSec := int64(config.Window / time.Second)
+	if windowSec <= 0 {
+		windowSec = 1
+	}
+
+	type postLink struct {
+		postID    string
+		linkID    int
+		degree    int
+		createdAt time.Time
+	}
+	postLinks := make([]postLink, 0, int(float64(config.Posts)*config.LinkRatio))
+
+	stmt, err = tx.Prepare(pq.CopyIn("posts", "id", "author_handle", "author_did", "author_degree", "content", "is_reply", "created_at"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare post copy: %w", err)
+	}
+	for i := 0; i < config.Posts; i++ {
+		author := rng.Intn(config.Accounts)
+		postID := fmt.Sprintf("at://%s/app.bsky.feed.post/%d", dids[author], i)
+		createdAt := now.Add(-time.Duration(rng.Int63n(windowSec)) * time.Second)
+		isReply := rng.Float64() < config.ReplyRatio
+
+		content := fmt.Sprintf("Seed post %d", i)
+		if rng.Float64() < config.LinkRatio {
+			linkID := linkIDs[zipf.Uint64()]
+			postLinks = append(postLinks, postLink{postID, linkID, degrees[author], createdAt})
+		}
+
+		if _, err := stmt.Exec(postID, dids[author], dids[author], degrees[author], content, isReply, createdAt); err != nil {
+			return nil, fmt.Errorf("failed to copy post: %w", err)
+		}
+	}
+	if err := closeCopy(stmt); err != nil {
+		return nil, err
+	}
+	result.Posts = config.Posts
+	log.Printf("[SEED] Loaded %d posts", result.Posts)
+
+	stmt, err = tx.Prepare(pq.CopyIn("post_links", "post_id", "link_id", "degree", "post_created_at"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare post_links copy: %w", err)
+	}
+	for _, pl := range postLinks {
+		if _, err := stmt.Exec(pl.postID, pl.linkID, pl.degree, pl.createdAt); err != nil {
+			return nil, fmt.Errorf("failed to copy post_link: %w", err)
+		}
+	}
+	if err := closeCopy(stmt); err != nil {
+		return nil, err
+	}
+	result.PostLinks = len(postLinks)
+	log.Printf("[SEED] Loaded %d post_links", result.PostLinks)
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit seed data: %w", err)
+	}
+
+	// Fresh statistics so the planner sees the new data distribution
+	if _, err := db.Exec("ANALYZE posts; ANALYZE links; ANALYZE post_links; ANALYZE network_accounts"); err != nil {
+		log.Printf("[WARN] Failed to analyze seeded tables: %v", err)
+	}
+
+	result.Duration = time.Since(start)
+	return result, nil
+}
+
+// Remove deletes all rows generated by the given run (load test or demo)
+func Remove(db *database.DB, runID string) error {
+	didPattern := fmt.Sprintf("did:plc:%s-%%", runID)
+
+	// post_links rows cascade from posts and links
+	if _, err := db.Exec(`DELETE FROM posts WHERE author_did LIKE $1`, didPattern); err != nil {
+		return fmt.Errorf("failed to delete seed posts: %w", err)
+	}
+	if _, err := db.Exec(`DELETE FROM links WHERE normalized_url LIKE $1 OR normalized_url LIKE $2`,
+		linkPattern(runID), demoLinkPattern(runID)); err != nil {
+		return fmt.Errorf("failed to delete seed links: %w", err)
+	}
+	if _, err := db.Exec(`DELETE FROM follows WHERE did LIKE $1`, didPattern); err != nil {
+		return fmt.Errorf("failed to delete seed follows: %w", err)
+	}
+	if _, err := db.Exec(`DELETE FROM network_accounts WHERE did LIKE $1`, didPattern); err != nil {
+		return fmt.Errorf("failed to delete seed accounts: %w", err)
+	}
+	return nil
+}
+
+// AccountDIDs returns the DIDs generated for a run, for building synthetic events
+func AccountDIDs(runID string, accounts int) []string {
+	dids := make([]string, accounts)
+	for i := range dids {
+		dids[i] = fmt.Sprintf("did:plc:%s-%d", runID, i)
+	}
+	return dids
+}
+
+func linkURL(runID string, i int) string {
+	return fmt.Sprintf("https://loadtest.example.com/%s/article/%d", runID, i)
+}
+
+func linkPattern(runID string) string {
+	return fmt.Sprintf("https://loadtest.example.com/%s/%%", runID)
+}
+
+// closeCopy flushes buffered COPY rows and closes the statement
+func closeCopy(stmt *sql.Stmt) error {
+	if _, err := stmt.Exec(); err != nil {
+		stmt.Close()
+		return fmt.Errorf("failed to flush copy: %w", err)
+	}
+	return stmt.Close()
+}
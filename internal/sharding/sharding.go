@@ -0,0 +1,144 @@
+// Package sharding splits firehose processing across instances by DID.
+//
+// DIDs are hashed onto a 32-bit ring divided into equal contiguous ranges,
+// one per shard. Each instance leases a shard through the firehose_shards
+// table, renews the lease while running, and keeps a per-shard cursor, so
+// ingestion scales horizontally without two instances processing a DID.
+package sharding
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"os"
+	"time"
+
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/database"
+)
+
+// ShardOf returns the shard (0..count-1) owning a DID
+func ShardOf(did string, count int) int {
+	if count <= 1 {
+		return 0
+	}
+	h := fnv.New32a()
+	h.Write([]byte(did))
+	return int(uint64(h.Sum32()) * uint64(count) >> 32)
+}
+
+// Config holds shard lease settings
+type Config struct {
+	Count        int           // Total shards
+	Owner        string        // Unique instance name (defaults to hostname-pid)
+	LeaseTTL     time.Duration // Lease length; renewed every LeaseTTL/3
+	ClaimTimeout time.Duration // How long to wait for a free shard (0 = forever)
+}
+
+// Lease is a claimed shard
+type Lease struct {
+	db     *database.DB
+	config Config
+
+	ShardID int
+	Cursor  *int64 // Saved shard cursor, nil if the shard hasn't run yet
+}
+
+// Claim waits for a free shard and leases it
+func Claim(ctx context.Context, db *database.DB, config Config) (*Lease, error) {
+	if config.Owner == "" {
+		host, _ := os.Hostname()
+		config.Owner = fmt.Sprintf("%s-%d", host, os.Getpid())
+	}
+	if config.LeaseTTL <= 0 {
+		config.LeaseTTL = time.Minute
+	}
+
+	if err := db.EnsureFirehoseShards(config.Count); err != nil {
+		return nil, fmt.Errorf("failed to create shards: %w", err)
+	}
+
+	var deadline time.Time
+	if config.ClaimTimeout > 0 {
+		deadline = time.Now().Add(config.ClaimTimeout)
+	}
+
+	for {
+		shard, err := db.ClaimFirehoseShard(config.Count, config.Owner, config.LeaseTTL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to claim shard: %w", err)
+		}
+		if shard != nil {
+			return &Lease{db: db, config: config, ShardID: shard.ShardID, Cursor: shard.CursorTimeUS}, nil
+		}
+
+		if !deadline.IsZero() && time.Now().After(deadline) {
+			return nil, fmt.Errorf("all %d shards are leased", config.Count)
+		}
+		log.Printf("[SHARD] All %d shards leased, waiting...", config.Count)
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(config.LeaseTTL / 3):
+		}
+	}
+}
+
+// Owns reports whether this lease's shard owns a DID
+func (l *Lease) Owns(did string) bool {
+	return ShardOf(did, l.config.Count) == l.ShardID
+}
+
+// Owner returns the instance name holding the lease
+func (l *Lease) Owner() string {
+	return l.config.Owner
+}
+
+// Count returns the total number of shards
+func (l *Lease) Count() int {
+	return l.config.Count
+}
+
+// KeepAlive renews the lease until ctx is cancelled, calling onLost if the
+// lease can't be renewed (another instance may then claim the shard)
+func (l *Lease) KeepAlive(ctx context.Context, onLost func()) {
+	go func() {
+		ticker := time.NewTicker(l.config.LeaseTTL / 3)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				held, err := l.db.RenewFirehoseShard(l.config.Count, l.ShardID, l.config.Owner, l.config.LeaseTTL)
+				if err != nil {
+					log.Printf("[SHARD] Failed to renew lease on shard %d: %v", l.ShardID, err)
+					continue // Retry until the lease actually expires
+				}
+				if !held {
+					log.Printf("[SHARD] Lost lease on shard %d", l.ShardID)
+					onLost()
+					return
+				}
+			}
+		}
+	}()
+}
+
+// SaveCursor stores the shard's cursor
+func (l *Lease) SaveCursor(cursorTimeUS int64) error {
+	held, err := l.db.UpdateFirehoseShardCursor(l.config.Count, l.ShardID, l.config.Owner, cursorTimeUS)
+	if err != nil {
+		return err
+	}
+	if !held {
+		return fmt.Errorf("lease on shard %d lost", l.ShardID)
+	}
+	return nil
+}
+
+// Release gives up the lease
+func (l *Lease) Release() error {
+	return l.db.ReleaseFirehoseShard(l.config.Count, l.ShardID, l.config.Owner)
+}
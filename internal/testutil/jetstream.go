@@ -0,0 +1,175 @@
+package testutil
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/bluesky-social/jetstream/pkg/models"
+	"github.com/gorilla/websocket"
+	"github.com/klauspost/compress/zstd"
+)
+
+// FakeJetstream is a websocket server that replays recorded Jetstream events.
+// It honors the cursor, wantedDids and wantedCollections query parameters and
+// zstd compression (Socket-Encoding: zstd) like the real service, then holds
+// the connection open until the client disconnects or the server is closed.
+type FakeJetstream struct {
+	server *httptest.Server
+	events []models.Event
+
+	mu          sync.Mutex
+	connections int
+}
+
+// NewFakeJetstream starts a fake Jetstream server replaying events in order
+func NewFakeJetstream(t testing.TB, events []models.Event) *FakeJetstream {
+	t.Helper()
+
+	f := &FakeJetstream{events: events}
+	f.server = httptest.NewServer(http.HandlerFunc(f.handleSubscribe))
+	t.Cleanup(f.server.Close)
+	return f
+}
+
+// URL returns the websocket subscribe URL to use as the Jetstream endpoint
+func (f *FakeJetstream) URL() string {
+	return "ws" + strings.TrimPrefix(f.server.URL, "http") + "/subscribe"
+}
+
+// Connections returns how many clients have connected so far
+func (f *FakeJetstream) Connections() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.connections
+}
+
+// LoadEvents reads recorded events from a JSON-lines file (one event per line),
+// the format written by the firehose archiver
+func LoadEvents(t testing.TB, path string) []models.Event {
+	t.Helper()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("testutil: failed to read events: %v", err)
+	}
+
+	var events []models.Event
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var event models.Event
+		if err := json.Unmarshal(line, &event); err != nil {
+			t.Fatalf("testutil: invalid event in %s: %v", path, err)
+		}
+		events = append(events, event)
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatalf("testutil: failed to scan events: %v", err)
+	}
+
+	return events
+}
+
+// PostEvent builds a commit event creating an app.bsky.feed.post record
+func PostEvent(did, rkey string, timeUS int64, record interface{}) models.Event {
+	raw, err := json.Marshal(record)
+	if err != nil {
+		panic(fmt.Sprintf("testutil: failed to marshal record: %v", err))
+	}
+
+	return models.Event{
+		Did:    did,
+		TimeUS: timeUS,
+		Kind:   models.EventKindCommit,
+		Commit: &models.Commit{
+			Operation:  models.CommitOperationCreate,
+			Collection: "app.bsky.feed.post",
+			RKey:       rkey,
+			Record:     raw,
+		},
+	}
+}
+
+func (f *FakeJetstream) handleSubscribe(w http.ResponseWriter, r *http.Request) {
+	upgrader := websocket.Upgrader{CheckOrigin: func(*http.Request) bool { return true }}
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	f.mu.Lock()
+	f.connections++
+	f.mu.Unlock()
+
+	query := r.URL.Query()
+	var cursor int64
+	if c := query.Get("cursor"); c != "" {
+		cursor, _ = strconv.ParseInt(c, 10, 64)
+	}
+	wantedDIDs := toSet(query["wantedDids"])
+	wantedCollections := toSet(query["wantedCollections"])
+
+	var encoder *zstd.Encoder
+	if r.Header.Get("Socket-Encoding") == "zstd" {
+		encoder, err = zstd.NewWriter(nil, zstd.WithEncoderDict(models.ZSTDDictionary))
+		if err != nil {
+			return
+		}
+		defer encoder.Close()
+	}
+
+	for _, event := range f.events {
+		if event.TimeUS <= cursor {
+			continue
+		}
+		if len(wantedDIDs) > 0 && !wantedDIDs[event.Did] {
+			continue
+		}
+		if len(wantedCollections) > 0 && event.Commit != nil && !wantedCollections[event.Commit.Collection] {
+			continue
+		}
+
+		msg, err := json.Marshal(event)
+		if err != nil {
+			return
+		}
+
+		msgType := websocket.TextMessage
+		if encoder != nil {
+			msg = encoder.EncodeAll(msg, nil)
+			msgType = websocket.BinaryMessage
+		}
+		if err := conn.WriteMessage(msgType, msg); err != nil {
+			return
+		}
+	}
+
+	// Keep the connection open like the live firehose until the client leaves
+	for {
+		if _, _, err := conn.ReadMessage(); err != nil {
+			return
+		}
+	}
+}
+
+func toSet(values []string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, v := range values {
+		set[v] = true
+	}
+	return set
+}
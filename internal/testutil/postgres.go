@@ -0,0 +1,142 @@
+// Package testutil provides integration test infrastructure: a disposable
+// PostgreSQL database with migrations applied, a fake Jetstream server that
+// replays recorded events, and a fake Bluesky XRPC server.
+//
+// Integration tests using this package should be guarded by
+// testing.Short() so `go test -short ./...` stays fast and hermetic.
+package testutil
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	_ "github.com/lib/pq"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/database"
+)
+
+// postgresImage is the container image used when TEST_DATABASE_URL is unset
+const postgresImage = "postgres:16-alpine"
+
+// NewTestDB returns a connection to a fresh, fully migrated database that is
+// dropped when the test finishes.
+//
+// If TEST_DATABASE_URL is set (e.g. "host=localhost user=postgres sslmode=disable"),
+// a uniquely named database is created on that server. Otherwise a throwaway
+// Postgres container is started with the docker CLI.
+func NewTestDB(t testing.TB) *database.DB {
+	t.Helper()
+
+	serverConn := os.Getenv("TEST_DATABASE_URL")
+	if serverConn == "" {
+		serverConn = startPostgresContainer(t)
+	}
+
+	admin, err := sql.Open("postgres", serverConn)
+	if err != nil {
+		t.Fatalf("testutil: failed to open admin connection: %v", err)
+	}
+	t.Cleanup(func() { admin.Close() })
+
+	if err := waitForPostgres(admin, 30*time.Second); err != nil {
+		t.Fatalf("testutil: postgres not ready: %v", err)
+	}
+
+	dbName := fmt.Sprintf("bna_test_%d", time.Now().UnixNano())
+	if _, err := admin.Exec("CREATE DATABASE " + dbName); err != nil {
+		t.Fatalf("testutil: failed to create database: %v", err)
+	}
+
+	db, err := database.NewDB(serverConn + " dbname=" + dbName)
+	if err != nil {
+		t.Fatalf("testutil: failed to connect to test database: %v", err)
+	}
+	t.Cleanup(func() {
+		db.Close()
+		admin.Exec("DROP DATABASE IF EXISTS " + dbName + " WITH (FORCE)")
+	})
+
+	dir, err := migrationsDir()
+	if err != nil {
+		t.Fatalf("testutil: %v", err)
+	}
+	if err := db.Migrate(context.Background(), dir, "", nil); err != nil {
+		t.Fatalf("testutil: %v", err)
+	}
+
+	return db
+}
+
+// migrationsDir locates the repository's migrations directory by walking up
+// from the working directory (tests run from their package directory)
+func migrationsDir() (string, error) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+
+	for {
+		if _, err := os.Stat(filepath.Join(dir, "go.mod")); err == nil {
+			return filepath.Join(dir, "migrations"), nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", fmt.Errorf("could not find repository root (go.mod)")
+		}
+		dir = parent
+	}
+}
+
+// startPostgresContainer starts a disposable Postgres container and returns a
+// server-level connection string (without dbname)
+func startPostgresContainer(t testing.TB) string {
+	t.Helper()
+
+	if _, err := exec.LookPath("docker"); err != nil {
+		t.Skip("testutil: docker not available and TEST_DATABASE_URL not set")
+	}
+
+	out, err := exec.Command("docker", "run", "-d", "--rm",
+		"-e", "POSTGRES_PASSWORD=test",
+		"-p", "127.0.0.1::5432",
+		postgresImage,
+	).Output()
+	if err != nil {
+		t.Fatalf("testutil: failed to start postgres container: %v", err)
+	}
+	containerID := strings.TrimSpace(string(out))
+	t.Cleanup(func() {
+		exec.Command("docker", "rm", "-f", containerID).Run()
+	})
+
+	// Resolve the randomly assigned host port
+	out, err = exec.Command("docker", "port", containerID, "5432/tcp").Output()
+	if err != nil {
+		t.Fatalf("testutil: failed to resolve container port: %v", err)
+	}
+	hostPort := strings.TrimSpace(strings.Split(string(out), "\n")[0])
+	port := hostPort[strings.LastIndex(hostPort, ":")+1:]
+
+	return fmt.Sprintf("host=127.0.0.1 port=%s user=postgres password=test sslmode=disable", port)
+}
+
+// waitForPostgres pings until the server accepts connections or timeout expires
+func waitForPostgres(db *sql.DB, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		err := db.Ping()
+		if err == nil {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return err
+		}
+		time.Sleep(250 * time.Millisecond)
+	}
+}
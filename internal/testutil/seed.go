@@ -0,0 +1,44 @@
+package testutil
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/database"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/urlutil"
+)
+
+// postSeq keeps seeded post URIs unique
+var postSeq atomic.Int64
+
+// AddShare stores a post by did sharing rawURL at createdAt, the way ingest
+// does, and returns the link's ID
+func AddShare(t testing.TB, db *database.DB, did string, degree int, rawURL string, createdAt time.Time) int {
+	t.Helper()
+
+	normalized, err := urlutil.Normalize(rawURL)
+	if err != nil {
+		t.Fatalf("testutil: invalid URL %s: %v", rawURL, err)
+	}
+	post := &database.Post{
+		ID:           fmt.Sprintf("at://%s/app.bsky.feed.post/seed%d", did, postSeq.Add(1)),
+		AuthorHandle: did,
+		AuthorDID:    did,
+		AuthorDegree: degree,
+		Content:      rawURL,
+		CreatedAt:    createdAt,
+	}
+	if _, err := db.InsertPost(post); err != nil {
+		t.Fatalf("testutil: failed to insert post: %v", err)
+	}
+	link, err := db.GetOrCreateLink(rawURL, normalized)
+	if err != nil {
+		t.Fatalf("testutil: failed to create link: %v", err)
+	}
+	if err := db.LinkPostToLinkWithAttribution(post.ID, link.ID, database.RelationOriginal, degree); err != nil {
+		t.Fatalf("testutil: failed to link post: %v", err)
+	}
+	return link.ID
+}
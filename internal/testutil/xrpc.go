@@ -0,0 +1,145 @@
+package testutil
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/bluesky"
+)
+
+// FakeXRPC is an in-memory Bluesky XRPC server serving fixture follows and
+// author feeds. Point bluesky.NewClientWithBaseURL at URL() to use it.
+type FakeXRPC struct {
+	server *httptest.Server
+
+	mu      sync.Mutex
+	session bluesky.SessionResponse
+	follows map[string][]bluesky.Follow       // actor -> follows
+	feeds   map[string][]bluesky.FeedResponse // actor -> pages, in order
+}
+
+// NewFakeXRPC starts a fake XRPC server that accepts any credentials for did
+func NewFakeXRPC(t testing.TB, handle, did string) *FakeXRPC {
+	t.Helper()
+
+	f := &FakeXRPC{
+		session: bluesky.SessionResponse{
+			AccessJWT:  "test-access-jwt",
+			RefreshJWT: "test-refresh-jwt",
+			Handle:     handle,
+			DID:        did,
+		},
+		follows: make(map[string][]bluesky.Follow),
+		feeds:   make(map[string][]bluesky.FeedResponse),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/xrpc/com.atproto.server.createSession", f.handleCreateSession)
+	mux.HandleFunc("/xrpc/app.bsky.graph.getFollows", f.handleGetFollows)
+	mux.HandleFunc("/xrpc/app.bsky.feed.getAuthorFeed", f.handleGetAuthorFeed)
+
+	f.server = httptest.NewServer(mux)
+	t.Cleanup(f.server.Close)
+	return f
+}
+
+// URL returns the XRPC base URL (including the /xrpc path)
+func (f *FakeXRPC) URL() string {
+	return f.server.URL + "/xrpc"
+}
+
+// SetFollows sets the accounts returned by getFollows for actor
+func (f *FakeXRPC) SetFollows(actor string, follows []bluesky.Follow) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.follows[actor] = follows
+}
+
+// SetAuthorFeed sets the pages returned by getAuthorFeed for actor.
+// Page cursors are assigned automatically so clients paginate through them.
+func (f *FakeXRPC) SetAuthorFeed(actor string, pages ...[]bluesky.FeedItem) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	responses := make([]bluesky.FeedResponse, len(pages))
+	for i, items := range pages {
+		responses[i] = bluesky.FeedResponse{Feed: items}
+		if i < len(pages)-1 {
+			responses[i].Cursor = pageCursor(i + 1)
+		}
+	}
+	f.feeds[actor] = responses
+}
+
+func (f *FakeXRPC) handleCreateSession(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	writeJSON(w, f.session)
+}
+
+func (f *FakeXRPC) handleGetFollows(w http.ResponseWriter, r *http.Request) {
+	if !f.authorized(r) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	actor := r.URL.Query().Get("actor")
+
+	f.mu.Lock()
+	follows := f.follows[actor]
+	f.mu.Unlock()
+
+	writeJSON(w, bluesky.FollowsResponse{
+		Subject: bluesky.Author{Handle: actor},
+		Follows: follows,
+	})
+}
+
+func (f *FakeXRPC) handleGetAuthorFeed(w http.ResponseWriter, r *http.Request) {
+	if !f.authorized(r) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	query := r.URL.Query()
+	actor := query.Get("actor")
+
+	f.mu.Lock()
+	pages := f.feeds[actor]
+	f.mu.Unlock()
+
+	page := 0
+	if cursor := query.Get("cursor"); cursor != "" {
+		for i := range pages {
+			if pageCursor(i) == cursor {
+				page = i
+				break
+			}
+		}
+	}
+
+	if page >= len(pages) {
+		writeJSON(w, bluesky.FeedResponse{Feed: []bluesky.FeedItem{}})
+		return
+	}
+	writeJSON(w, pages[page])
+}
+
+func (f *FakeXRPC) authorized(r *http.Request) bool {
+	return r.Header.Get("Authorization") == "Bearer "+f.session.AccessJWT
+}
+
+func pageCursor(page int) string {
+	return "page-" + strconv.Itoa(page)
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
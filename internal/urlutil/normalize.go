@@ -78,3 +78,18 @@ func Normalize(rawURL string) (string, error) {
 
 	return u.String(), nil
 }
+
+// Domain returns the lowercased host of a URL without a leading "www.",
+// or "" if the URL can't be parsed
+func Domain(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+}
+
+// MatchesDomain reports whether host is domain or one of its subdomains
+func MatchesDomain(host, domain string) bool {
+	return host == domain || strings.HasSuffix(host, "."+domain)
+}
@@ -0,0 +1,124 @@
+// Package warehouse exports daily aggregates to files for long-term
+// analysis outside the operational database. Each local calendar day
+// becomes one gzipped CSV per table, partitioned Hive-style:
+//
+//	links/dt=2025-11-02/links.csv.gz
+//	shares/dt=2025-11-02/shares.csv.gz
+//	domains/dt=2025-11-02/domains.csv.gz
+//
+// so DuckDB (read_csv('links/*/*.csv.gz', hive_partitioning=true)) and
+// BigQuery external tables pick up new days without loading old ones.
+package warehouse
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/calendar"
+	"github.com/petroleumjelliffe/bluesky-news-aggregator/internal/database"
+)
+
+// Counts is the number of rows exported per table
+type Counts struct {
+	Links   int
+	Shares  int
+	Domains int
+}
+
+// ExportDay writes the aggregates of day (a date at midnight UTC) in loc to
+// the sink. Re-exporting a day replaces its files. Share and domain counts
+// come from posts, so they only cover days still in the posts window; link
+// rollups outlive cleanup.
+func ExportDay(ctx context.Context, db *database.DB, sink Sink, day time.Time, loc *time.Location) (Counts, error) {
+	var counts Counts
+	start, end, err := calendar.ParseDay(day.Format("2006-01-02"), loc)
+	if err != nil {
+		return counts, err
+	}
+	dt := day.Format("2006-01-02")
+
+	links, err := db.GetWarehouseLinks(day)
+	if err != nil {
+		return counts, fmt.Errorf("failed to load links: %w", err)
+	}
+	rows := make([][]string, len(links))
+	for i, l := range links {
+		rows[i] = []string{dt, strconv.Itoa(l.LinkID), l.URL, l.Domain, stringOrEmpty(l.Title),
+			formatTime(l.FirstSeenAt), strconv.Itoa(l.Sharers), formatTime(l.LastSharedAt)}
+	}
+	if err := putCSV(ctx, sink, "links", dt, []string{"day", "link_id", "url", "domain", "title",
+		"first_seen_at", "sharers", "last_shared_at"}, rows); err != nil {
+		return counts, err
+	}
+	counts.Links = len(rows)
+
+	shares, err := db.GetWarehouseShares(start, end)
+	if err != nil {
+		return counts, fmt.Errorf("failed to load shares: %w", err)
+	}
+	rows = make([][]string, len(shares))
+	for i, s := range shares {
+		degree := ""
+		if s.Degree != nil {
+			degree = strconv.Itoa(*s.Degree)
+		}
+		rows[i] = []string{dt, strconv.Itoa(s.LinkID), degree, strconv.Itoa(s.Posts),
+			strconv.Itoa(s.Sharers), strconv.Itoa(s.Replies)}
+	}
+	if err := putCSV(ctx, sink, "shares", dt, []string{"day", "link_id", "degree", "posts", "sharers", "replies"}, rows); err != nil {
+		return counts, err
+	}
+	counts.Shares = len(rows)
+
+	domains, err := db.GetWarehouseDomains(start, end)
+	if err != nil {
+		return counts, fmt.Errorf("failed to load domains: %w", err)
+	}
+	rows = make([][]string, len(domains))
+	for i, d := range domains {
+		rows[i] = []string{dt, d.Domain, strconv.Itoa(d.Links), strconv.Itoa(d.Posts), strconv.Itoa(d.Sharers)}
+	}
+	if err := putCSV(ctx, sink, "domains", dt, []string{"day", "domain", "links", "posts", "sharers"}, rows); err != nil {
+		return counts, err
+	}
+	counts.Domains = len(rows)
+
+	return counts, nil
+}
+
+// putCSV writes a table's partition for one day as gzipped CSV
+func putCSV(ctx context.Context, sink Sink, table, dt string, header []string, rows [][]string) error {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	w := csv.NewWriter(gz)
+	w.Write(header)
+	w.WriteAll(rows)
+	if err := w.Error(); err != nil {
+		return fmt.Errorf("failed to encode %s: %w", table, err)
+	}
+	if err := gz.Close(); err != nil {
+		return fmt.Errorf("failed to compress %s: %w", table, err)
+	}
+
+	key := fmt.Sprintf("%s/dt=%s/%s.csv.gz", table, dt, table)
+	if err := sink.Put(ctx, key, "application/gzip", buf.Bytes()); err != nil {
+		return fmt.Errorf("failed to write %s: %w", key, err)
+	}
+	return nil
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+func stringOrEmpty(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -0,0 +1,180 @@
+package warehouse
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Sink stores exported files under slash-separated keys
+type Sink interface {
+	// Name describes where files go, for logs
+	Name() string
+	// Put writes a file, replacing any earlier file under the same key
+	Put(ctx context.Context, key, contentType string, data []byte) error
+}
+
+// DirSink writes files under a local directory
+type DirSink struct {
+	Dir string
+}
+
+// Name returns the directory
+func (s *DirSink) Name() string {
+	return s.Dir
+}
+
+// Put writes the file atomically: readers never see a partial file
+func (s *DirSink) Put(ctx context.Context, key, contentType string, data []byte) error {
+	path := filepath.Join(s.Dir, filepath.FromSlash(key))
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// S3Config locates an S3 bucket (or an S3-compatible store such as R2 or
+// MinIO, via Endpoint)
+type S3Config struct {
+	Bucket    string
+	Prefix    string // Prepended to every key, e.g. "aggregator/"
+	Region    string
+	Endpoint  string // Empty = AWS; otherwise objects are addressed path-style
+	AccessKey string
+	SecretKey string
+}
+
+// S3Sink uploads files to an S3 bucket, signing requests with AWS
+// Signature Version 4
+type S3Sink struct {
+	config     S3Config
+	httpClient *http.Client
+}
+
+// NewS3Sink creates an S3 sink
+func NewS3Sink(config S3Config) *S3Sink {
+	if config.Region == "" {
+		config.Region = "us-east-1"
+	}
+	return &S3Sink{
+		config:     config,
+		httpClient: &http.Client{Timeout: 5 * time.Minute},
+	}
+}
+
+// Name returns the bucket URL
+func (s *S3Sink) Name() string {
+	return "s3://" + s.config.Bucket + "/" + s.config.Prefix
+}
+
+// Put uploads the file with a PUT Object request
+func (s *S3Sink) Put(ctx context.Context, key, contentType string, data []byte) error {
+	objectURL, host, path := s.objectURL(s.config.Prefix + key)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, objectURL, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	s.sign(req, host, path, data, time.Now().UTC())
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("S3 upload of %s failed with status %d: %s", key, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+// objectURL returns the URL, host and escaped path of an object
+func (s *S3Sink) objectURL(key string) (string, string, string) {
+	if s.config.Endpoint != "" {
+		endpoint := strings.TrimSuffix(s.config.Endpoint, "/")
+		host := strings.TrimPrefix(strings.TrimPrefix(endpoint, "https://"), "http://")
+		path := "/" + s.config.Bucket + "/" + escapePath(key)
+		return endpoint + path, host, path
+	}
+
+	host := fmt.Sprintf("%s.s3.%s.amazonaws.com", s.config.Bucket, s.config.Region)
+	path := "/" + escapePath(key)
+	return "https://" + host + path, host, path
+}
+
+// sign adds SigV4 headers for a request without a query string
+func (s *S3Sink) sign(req *http.Request, host, path string, payload []byte, now time.Time) {
+	payloadHash := sha256Hex(payload)
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	req.Header.Set("X-Amz-Date", amzDate)
+
+	signedHeaders := "host;x-amz-content-sha256;x-amz-date"
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		path,
+		"", // No query string
+		"host:" + host + "\nx-amz-content-sha256:" + payloadHash + "\nx-amz-date:" + amzDate + "\n",
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + s.config.Region + "/s3/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+
+	key := hmacSHA256([]byte("AWS4"+s.config.SecretKey), date)
+	key = hmacSHA256(key, s.config.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.config.AccessKey, scope, signedHeaders, signature))
+}
+
+// escapePath percent-encodes a key as SigV4 expects: everything but
+// unreserved characters and slashes
+func escapePath(key string) string {
+	var b strings.Builder
+	for i := 0; i < len(key); i++ {
+		c := key[i]
+		switch {
+		case c >= 'A' && c <= 'Z', c >= 'a' && c <= 'z', c >= '0' && c <= '9',
+			c == '-', c == '_', c == '.', c == '~', c == '/':
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,10 @@
+-- Migration 006: Track whether a post is a reply
+-- Replies often carry links that are only relevant to a conversation, so
+-- trending can exclude or down-weight them.
+
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS is_reply BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Partial index: most posts are not replies, so only index the replies
+CREATE INDEX IF NOT EXISTS idx_posts_is_reply ON posts(created_at) WHERE is_reply;
+
+COMMENT ON COLUMN posts.is_reply IS 'TRUE when the post record has a reply reference (parent/root)';
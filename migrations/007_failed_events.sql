@@ -0,0 +1,28 @@
+-- Migration 007: Durable retry queue for firehose events
+-- Events that fail processing (e.g. a transient DB error) are stored here and
+-- retried with backoff, so advancing the Jetstream cursor never loses them.
+
+CREATE TABLE IF NOT EXISTS failed_events (
+    id BIGSERIAL PRIMARY KEY,
+    event_time_us BIGINT NOT NULL,
+    did TEXT NOT NULL,
+    payload JSONB NOT NULL,               -- Full Jetstream event as received
+
+    -- Retry state
+    status TEXT NOT NULL DEFAULT 'pending',  -- pending = will retry, dead = gave up
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT,
+    next_attempt_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    -- Timestamps
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Index for the retry worker's "what is due" scan
+CREATE INDEX IF NOT EXISTS idx_failed_events_due
+ON failed_events(next_attempt_at)
+WHERE status = 'pending';
+
+COMMENT ON TABLE failed_events IS 'Firehose events that failed processing, retried with exponential backoff';
+COMMENT ON COLUMN failed_events.status IS 'pending = awaiting retry, dead = exceeded max attempts (kept for inspection)';
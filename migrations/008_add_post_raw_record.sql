@@ -0,0 +1,7 @@
+-- Migration 008: Keep the original post record JSON
+-- Lets cmd/reprocess re-run stored posts through the current pipeline
+-- (embed parsing, URL normalization) without re-fetching from the network.
+
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS raw_record JSONB;
+
+COMMENT ON COLUMN posts.raw_record IS 'Original app.bsky.feed.post record as received at ingest (NULL for posts stored before migration 008)';
@@ -0,0 +1,16 @@
+-- Migration 009: Moderation labels on posts and accounts
+-- Posts carry self-labels in their record (and labeler labels when fetched
+-- from the API); accounts carry labels returned by app.bsky.actor.getProfiles.
+-- Trending can flag or exclude links predominantly shared by labeled content.
+
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS labels TEXT[] NOT NULL DEFAULT '{}';
+
+ALTER TABLE network_accounts
+ADD COLUMN IF NOT EXISTS labels TEXT[] NOT NULL DEFAULT '{}',
+ADD COLUMN IF NOT EXISTS labels_updated_at TIMESTAMP;
+
+-- Partial index: the vast majority of posts are unlabeled
+CREATE INDEX IF NOT EXISTS idx_posts_labeled ON posts(created_at) WHERE labels <> '{}';
+
+COMMENT ON COLUMN posts.labels IS 'Label values (e.g. spam, porn) from record self-labels or labelers';
+COMMENT ON COLUMN network_accounts.labels IS 'Account label values from getProfiles, refreshed by cmd/sync-labels';
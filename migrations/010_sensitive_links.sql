@@ -0,0 +1,9 @@
+-- Migration 010: Mark links with adult/graphic previews
+-- Set from post self-labels, a domain list, or an image classifier. The API
+-- replaces the preview image of sensitive links unless ?include_sensitive=true.
+
+ALTER TABLE links
+ADD COLUMN IF NOT EXISTS sensitive BOOLEAN NOT NULL DEFAULT FALSE,
+ADD COLUMN IF NOT EXISTS sensitive_reason TEXT;
+
+COMMENT ON COLUMN links.sensitive_reason IS 'Why the link was marked: label:<val>, domain:<domain>, or image';
@@ -0,0 +1,17 @@
+-- Migration 011: How and by whom each link share happened
+-- relation_type records whether the link was in the author's own post, in a
+-- post they quoted, or in a post they reposted (backfill only); degree is the
+-- author's network degree at ingest time.
+
+ALTER TABLE post_links
+ADD COLUMN IF NOT EXISTS relation_type TEXT NOT NULL DEFAULT 'original',
+ADD COLUMN IF NOT EXISTS degree INTEGER;
+
+-- Existing rows: take the degree stamped on the post
+UPDATE post_links pl
+SET degree = p.author_degree
+FROM posts p
+WHERE pl.post_id = p.id AND pl.degree IS NULL;
+
+COMMENT ON COLUMN post_links.relation_type IS 'original, quote, or repost';
+COMMENT ON COLUMN post_links.degree IS 'Author network degree at ingest (1, 2, or 0 = outside network)';
@@ -0,0 +1,5 @@
+-- Migration 012: Index post_links.degree for degree-filtered trending
+-- The author's degree is stamped on post_links at ingest (migration 011), so
+-- filtering by degree no longer depends on the posts row.
+
+CREATE INDEX IF NOT EXISTS idx_post_links_degree_link ON post_links(degree, link_id);
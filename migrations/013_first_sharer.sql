@@ -0,0 +1,25 @@
+-- Migration 013: First-sharer attribution
+-- Records who in the network shared each link first. Maintained at ingest by
+-- LinkPostToLink; kept on the link even after cleanup deletes the posts.
+
+ALTER TABLE links
+ADD COLUMN IF NOT EXISTS first_shared_by TEXT,
+ADD COLUMN IF NOT EXISTS first_shared_at TIMESTAMP;
+
+-- Populate from existing shares
+UPDATE links l
+SET first_shared_by = first.author, first_shared_at = first.created_at
+FROM (
+    SELECT DISTINCT ON (pl.link_id)
+        pl.link_id,
+        COALESCE(p.author_did, p.author_handle) AS author,
+        p.created_at
+    FROM post_links pl
+    JOIN posts p ON pl.post_id = p.id
+    ORDER BY pl.link_id, p.created_at ASC
+) first
+WHERE l.id = first.link_id AND l.first_shared_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_links_first_shared_by ON links(first_shared_by, first_shared_at DESC);
+
+COMMENT ON COLUMN links.first_shared_by IS 'DID of the earliest known sharer';
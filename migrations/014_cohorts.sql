@@ -0,0 +1,21 @@
+-- Migration 014: Cohorts
+-- Named sets of accounts (e.g. "climate journalists") that trending can be
+-- restricted to with ?cohort=
+
+CREATE TABLE IF NOT EXISTS cohorts (
+    id SERIAL PRIMARY KEY,
+    name TEXT NOT NULL UNIQUE,
+    description TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS cohort_members (
+    cohort_id INTEGER NOT NULL REFERENCES cohorts(id) ON DELETE CASCADE,
+    did TEXT NOT NULL,
+    added_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (cohort_id, did)
+);
+
+-- Trending joins posts to members by author DID
+CREATE INDEX IF NOT EXISTS idx_cohort_members_did ON cohort_members(did);
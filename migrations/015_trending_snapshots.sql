@@ -0,0 +1,13 @@
+-- Migration 015: Trending snapshots
+-- Periodic copies of the trending list so historical states ("what was
+-- trending yesterday at 9am") remain available after cleanup deletes posts.
+-- Link data is denormalized into JSONB for the same reason.
+
+CREATE TABLE IF NOT EXISTS trending_snapshots (
+    id SERIAL PRIMARY KEY,
+    taken_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    hours INTEGER NOT NULL,   -- Trending window the snapshot was computed over
+    links JSONB NOT NULL      -- Ranked []SnapshotLink
+);
+
+CREATE INDEX IF NOT EXISTS idx_trending_snapshots_taken_at ON trending_snapshots(taken_at DESC);
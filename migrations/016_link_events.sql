@@ -0,0 +1,18 @@
+-- Migration 016: Events mentioned by articles
+-- Detected from schema.org Event markup and dated phrases in link metadata
+-- when metadata is fetched; served as a calendar at /api/events.ics
+
+CREATE TABLE IF NOT EXISTS link_events (
+    id SERIAL PRIMARY KEY,
+    link_id INTEGER NOT NULL REFERENCES links(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    start_at TIMESTAMP NOT NULL,
+    end_at TIMESTAMP,
+    all_day BOOLEAN NOT NULL DEFAULT FALSE,
+    location TEXT,
+    source TEXT NOT NULL,   -- 'schema' or 'text'
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_link_events_start ON link_events(start_at);
+CREATE INDEX IF NOT EXISTS idx_link_events_link ON link_events(link_id);
@@ -0,0 +1,13 @@
+-- Migration 017: Firehose shards
+-- In shard mode each firehose instance leases one shard (a DID hash range)
+-- and keeps its own cursor, so instances never process the same DID.
+
+CREATE TABLE IF NOT EXISTS firehose_shards (
+    shard_count INTEGER NOT NULL,      -- Total shards in this layout
+    shard_id INTEGER NOT NULL,         -- 0 .. shard_count-1
+    owner TEXT,                        -- Instance holding the lease
+    lease_expires_at TIMESTAMP,
+    cursor_time_us BIGINT,             -- Per-shard Jetstream cursor
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (shard_count, shard_id)
+);
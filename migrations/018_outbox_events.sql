@@ -0,0 +1,42 @@
+-- Migration 018: Outbox of domain events for downstream integrations
+-- Events are written in the same transaction as the change they describe
+-- and streamed to configured sinks by the firehose's outbox dispatcher
+
+CREATE TABLE IF NOT EXISTS outbox_events (
+    id BIGSERIAL PRIMARY KEY,
+    event_type TEXT NOT NULL,   -- 'link_created', 'link_trending'
+    link_id INTEGER,            -- Subject link, when the event is about one
+    payload JSONB NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_outbox_events_created ON outbox_events(created_at);
+CREATE INDEX IF NOT EXISTS idx_outbox_events_type_link ON outbox_events(event_type, link_id, created_at DESC);
+
+-- Delivery position of each sink (events are delivered at least once, in order)
+CREATE TABLE IF NOT EXISTS outbox_sink_cursors (
+    sink TEXT PRIMARY KEY,
+    last_event_id BIGINT NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Record link_created in the inserting transaction, whichever code path
+-- creates the link
+CREATE OR REPLACE FUNCTION outbox_link_created()
+RETURNS TRIGGER AS $$
+BEGIN
+    INSERT INTO outbox_events (event_type, link_id, payload)
+    VALUES ('link_created', NEW.id, jsonb_build_object(
+        'link_id', NEW.id,
+        'url', NEW.normalized_url,
+        'first_seen_at', NEW.first_seen_at
+    ));
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS links_outbox_created_trigger ON links;
+CREATE TRIGGER links_outbox_created_trigger
+    AFTER INSERT ON links
+    FOR EACH ROW
+    EXECUTE FUNCTION outbox_link_created();
@@ -0,0 +1,12 @@
+-- Migration 019: Persistent scrape backlog
+-- Metadata fetches that don't fit in a process's in-memory scrape queue are
+-- parked here and pulled back highest-priority first as the queue drains
+
+CREATE TABLE IF NOT EXISTS scrape_jobs (
+    link_id INTEGER PRIMARY KEY REFERENCES links(id) ON DELETE CASCADE,
+    url TEXT NOT NULL,
+    priority INTEGER NOT NULL DEFAULT 1,   -- Shares seen while waiting
+    enqueued_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_scrape_jobs_priority ON scrape_jobs(priority DESC, enqueued_at);
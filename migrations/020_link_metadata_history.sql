@@ -0,0 +1,19 @@
+-- Migration 020: Metadata refresh for evolving stories
+-- Popular links are re-scraped while they are young; the validators make
+-- re-fetches conditional, and replaced metadata is kept as history so
+-- clients can show that a headline changed
+
+ALTER TABLE links
+ADD COLUMN IF NOT EXISTS etag TEXT,
+ADD COLUMN IF NOT EXISTS last_modified TEXT;
+
+CREATE TABLE IF NOT EXISTS link_metadata_history (
+    id SERIAL PRIMARY KEY,
+    link_id INTEGER NOT NULL REFERENCES links(id) ON DELETE CASCADE,
+    title TEXT,                 -- Values before the change
+    description TEXT,
+    og_image_url TEXT,
+    replaced_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_link_metadata_history_link ON link_metadata_history(link_id, replaced_at DESC);
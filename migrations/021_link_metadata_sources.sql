@@ -0,0 +1,18 @@
+-- Migration 021: Metadata sources
+-- Bluesky's link card and our own scrape are stored side by side with a
+-- quality score; links show the better one unless an admin pins a source
+
+CREATE TABLE IF NOT EXISTS link_metadata_sources (
+    link_id INTEGER NOT NULL REFERENCES links(id) ON DELETE CASCADE,
+    source TEXT NOT NULL,       -- 'bluesky' or 'scraped'
+    title TEXT,
+    description TEXT,
+    image_url TEXT,
+    score INTEGER NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (link_id, source)
+);
+
+ALTER TABLE links
+ADD COLUMN IF NOT EXISTS metadata_source TEXT,      -- Source currently displayed (NULL for links stored before this migration)
+ADD COLUMN IF NOT EXISTS metadata_preference TEXT;  -- Source pinned by an admin; NULL picks the higher score
@@ -0,0 +1,11 @@
+-- Migration 022: Ingest ledger
+-- The time_us of the newest event processed per DID. After a crash the
+-- firehose replays from its last saved cursor; events at or before a DID's
+-- ledger entry were already handled and are skipped, so replays don't
+-- re-queue scrapes or bump last_seen_at and share-driven priorities again.
+
+CREATE TABLE IF NOT EXISTS ingest_ledger (
+    did TEXT PRIMARY KEY,
+    last_time_us BIGINT NOT NULL,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,14 @@
+-- Migration 023: Copied shares
+-- Coordinated accounts sometimes post the same text with the same link. Posts
+-- get a fingerprint of their normalized text; a share whose post matches
+-- another account's share of the same link within a few hours is marked
+-- copied, so trending can count the group once and flag the link for review.
+
+ALTER TABLE posts
+ADD COLUMN IF NOT EXISTS text_fingerprint TEXT;  -- NULL for short posts and posts stored before this migration
+
+ALTER TABLE post_links
+ADD COLUMN IF NOT EXISTS copied BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE INDEX IF NOT EXISTS idx_posts_text_fingerprint ON posts(text_fingerprint) WHERE text_fingerprint IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_post_links_copied ON post_links(link_id) WHERE copied;
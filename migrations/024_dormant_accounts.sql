@@ -0,0 +1,24 @@
+-- Migration 024: Dormant accounts
+-- 2nd-degree accounts that haven't posted in a long time still take up room
+-- in the firehose DID filter. The firehose records when each network account
+-- last posted; accounts idle past firehose.dormant_days are marked dormant
+-- and left out of the filter (but kept here), until crawl-network sees them
+-- post again.
+
+ALTER TABLE network_accounts
+ADD COLUMN IF NOT EXISTS last_seen_at TIMESTAMP,    -- Newest post seen from the account
+ADD COLUMN IF NOT EXISTS dormant_since TIMESTAMP,   -- NULL while the account is in the live filter
+ADD COLUMN IF NOT EXISTS dormant_checked_at TIMESTAMP; -- Last time crawl-network looked for new posts
+
+CREATE INDEX IF NOT EXISTS idx_network_dormant ON network_accounts(dormant_since) WHERE dormant_since IS NOT NULL;
+
+-- Seed last_seen_at from posts still in the database
+UPDATE network_accounts na
+SET last_seen_at = p.last_post_at
+FROM (
+    SELECT author_did, MAX(created_at) AS last_post_at
+    FROM posts
+    WHERE author_did IS NOT NULL
+    GROUP BY author_did
+) p
+WHERE na.did = p.author_did AND na.last_seen_at IS NULL;
@@ -0,0 +1,19 @@
+-- Migration 025: Per-account ingestion statistics
+-- Running counters per author DID, kept after their posts age out, showing
+-- which follows contribute links and which of those links go on to trend.
+-- links.trended_at records when a link first reached the trending threshold,
+-- so each sharer is credited once per link.
+
+CREATE TABLE IF NOT EXISTS account_stats (
+    did TEXT PRIMARY KEY,
+    posts_seen BIGINT NOT NULL DEFAULT 0,
+    urls_extracted BIGINT NOT NULL DEFAULT 0,
+    trending_links BIGINT NOT NULL DEFAULT 0,  -- Links shared by the account that reached the trending threshold
+    first_post_at TIMESTAMP,
+    last_post_at TIMESTAMP
+);
+
+ALTER TABLE links
+ADD COLUMN IF NOT EXISTS trended_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_links_not_trended ON links(id) WHERE trended_at IS NULL;
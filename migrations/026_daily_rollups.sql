@@ -0,0 +1,15 @@
+-- Migration 026: Daily link rollups
+-- Share counts per link per calendar day in the configured timezone, so
+-- "yesterday's top links" and weekly views survive post cleanup. The day
+-- is the local date; recomputing a day never lowers a count, since posts
+-- that fed it may since have been deleted.
+
+CREATE TABLE IF NOT EXISTS daily_link_shares (
+    day DATE NOT NULL,
+    link_id INTEGER NOT NULL REFERENCES links(id) ON DELETE CASCADE,
+    share_count INTEGER NOT NULL,   -- Distinct sharers during the day
+    last_shared_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (day, link_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_daily_link_shares_top ON daily_link_shares(day, share_count DESC);
@@ -0,0 +1,14 @@
+-- Migration 027: Click tracking
+-- Clicks through the optional /out/{id} redirect, so ranking can learn what
+-- readers actually open. Only counts are kept: a running total on the link
+-- and a per-day tally. Nothing identifying the reader is stored.
+
+ALTER TABLE links
+ADD COLUMN IF NOT EXISTS click_count INTEGER NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS link_clicks (
+    link_id INTEGER NOT NULL REFERENCES links(id) ON DELETE CASCADE,
+    day DATE NOT NULL,
+    clicks INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (link_id, day)
+);
@@ -0,0 +1,11 @@
+-- Migration 028: Dead link detection
+-- A periodic sweep checks displayed links and records the status code seen.
+-- dead_at is set when a link is first found gone (404/410, or unreachable on
+-- two checks in a row) and cleared if it comes back.
+
+ALTER TABLE links
+ADD COLUMN IF NOT EXISTS http_status SMALLINT,
+ADD COLUMN IF NOT EXISTS status_checked_at TIMESTAMP,
+ADD COLUMN IF NOT EXISTS dead_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_links_dead_at ON links(dead_at) WHERE dead_at IS NOT NULL;
@@ -0,0 +1,16 @@
+-- Migration 029: Scrape attempt history
+-- One row per request the scraper makes (retries included), so per-domain
+-- success rates and latency can guide blocklist and rate-limit decisions.
+-- Rows are pruned after a week by periodic cleanup.
+
+CREATE TABLE IF NOT EXISTS scrape_stats (
+    id BIGSERIAL PRIMARY KEY,
+    domain TEXT NOT NULL,
+    status SMALLINT NOT NULL,        -- HTTP status, 0 when no response was received
+    latency_ms INTEGER NOT NULL,
+    error TEXT,                      -- Transport error when status is 0
+    attempted_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_scrape_stats_attempted_at ON scrape_stats(attempted_at);
+CREATE INDEX IF NOT EXISTS idx_scrape_stats_domain ON scrape_stats(domain, attempted_at);
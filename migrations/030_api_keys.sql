@@ -0,0 +1,30 @@
+-- Migration 030: API keys for the public tier
+-- Anyone can request a key; it stays pending until an admin approves it.
+-- Keys are stored as SHA-256 hashes: the plaintext is only shown once, at
+-- issuance. Usage is counted per key and UTC day for quotas and owners.
+
+CREATE TABLE IF NOT EXISTS api_keys (
+    id SERIAL PRIMARY KEY,
+    key_hash TEXT NOT NULL UNIQUE,
+    key_prefix TEXT NOT NULL,            -- First characters, to tell keys apart
+    name TEXT NOT NULL,                  -- What the key is for
+    contact TEXT NOT NULL,               -- How to reach the owner
+    scopes TEXT[] NOT NULL DEFAULT '{}', -- Read-only API areas the key may call
+    rate_per_minute INTEGER NOT NULL,
+    quota_per_day INTEGER NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending', -- pending, active or revoked
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    approved_at TIMESTAMP,
+    revoked_at TIMESTAMP,
+    last_used_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_api_keys_status ON api_keys(status, created_at);
+
+CREATE TABLE IF NOT EXISTS api_key_usage (
+    key_id INTEGER NOT NULL REFERENCES api_keys(id) ON DELETE CASCADE,
+    day DATE NOT NULL,
+    requests BIGINT NOT NULL DEFAULT 0,
+    rejected BIGINT NOT NULL DEFAULT 0,  -- Over quota or out of scope
+    PRIMARY KEY (key_id, day)
+);
@@ -0,0 +1,11 @@
+-- Migration 031: Warehouse export progress
+-- One row per local calendar day whose aggregates have been written to the
+-- warehouse sink, so each day is exported once and missed days are caught up.
+
+CREATE TABLE IF NOT EXISTS warehouse_exports (
+    day DATE PRIMARY KEY,
+    links INTEGER NOT NULL,          -- Rows written to each table
+    shares INTEGER NOT NULL,
+    domains INTEGER NOT NULL,
+    exported_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
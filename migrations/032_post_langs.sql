@@ -0,0 +1,24 @@
+-- Migration 032: Post languages
+-- Post records carry the author's language tags (record.langs), which is
+-- much cheaper than detecting the language from the text. They are stored
+-- as lowercase primary subtags ("en-US" -> "en") for filtering trending by
+-- language, and snapshots can be taken per language for language digests.
+
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS langs TEXT[] NOT NULL DEFAULT '{}';
+
+-- Recover languages of posts stored with their raw record
+UPDATE posts p
+SET langs = ARRAY(
+    SELECT DISTINCT LOWER(SPLIT_PART(tag, '-', 1))
+    FROM jsonb_array_elements_text(p.raw_record->'langs') tag
+    WHERE tag <> ''
+)
+WHERE p.langs = '{}'
+  AND jsonb_typeof(p.raw_record->'langs') = 'array';
+
+ALTER TABLE trending_snapshots ADD COLUMN IF NOT EXISTS lang TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS idx_trending_snapshots_lang ON trending_snapshots(lang, taken_at DESC);
+
+COMMENT ON COLUMN posts.langs IS 'Primary language subtags from the post record (empty if the author set none)';
+COMMENT ON COLUMN trending_snapshots.lang IS 'Language the snapshot counts posts in (empty = all posts)';
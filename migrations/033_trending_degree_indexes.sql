@@ -0,0 +1,7 @@
+-- Migration 033: Composite indexes for degree-filtered trending
+-- Trending scans posts in the time window and joins their post_links,
+-- filtering on the degree stamped at ingest (migration 011). Covering both
+-- sides lets the window scan and the degree filter use indexes alone.
+
+CREATE INDEX IF NOT EXISTS idx_posts_created_author ON posts(created_at, author_did);
+CREATE INDEX IF NOT EXISTS idx_post_links_post_degree_link ON post_links(post_id, degree, link_id);
@@ -0,0 +1,14 @@
+-- Migration 034: Post creation time on post_links
+-- Partitioning posts by day (cmd/migrate -partition-posts) partitions
+-- post_links the same way, so each share carries its post's created_at and
+-- a day's shares are dropped together with its posts.
+
+ALTER TABLE post_links ADD COLUMN IF NOT EXISTS post_created_at TIMESTAMP;
+
+-- Existing rows: take the time from the post
+UPDATE post_links pl
+SET post_created_at = p.created_at
+FROM posts p
+WHERE pl.post_id = p.id AND pl.post_created_at IS NULL;
+
+COMMENT ON COLUMN post_links.post_created_at IS 'created_at of the post, the partition key when posts are partitioned';
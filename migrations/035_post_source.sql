@@ -0,0 +1,11 @@
+-- Migration 035: Ingest source of posts
+-- Each ingest path tags the posts it stores, so ingestion can be broken down
+-- by source and bugs in one path isolated. A post arriving again through a
+-- different path keeps its first source and records the other one in
+-- duplicate_sources, for auditing overlap between paths.
+
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS source TEXT;  -- NULL for posts stored before this migration
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS duplicate_sources TEXT[] NOT NULL DEFAULT '{}';
+
+COMMENT ON COLUMN posts.source IS 'Ingest path that stored the post: poller, firehose, backfill, rss or mastodon';
+COMMENT ON COLUMN posts.duplicate_sources IS 'Other ingest paths that delivered the post after it was stored';
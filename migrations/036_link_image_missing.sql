@@ -0,0 +1,9 @@
+-- Migration 036: Links without a usable preview image
+-- The scraper checks og:image, twitter:image and JSON-LD images before
+-- storing one, rejecting tracking pixels, tiny icons and SVG logos. Links
+-- whose page offered nothing usable are marked so the frontend can show a
+-- placeholder built from the domain instead of a broken or blank image.
+
+ALTER TABLE links ADD COLUMN IF NOT EXISTS image_missing BOOLEAN NOT NULL DEFAULT FALSE;
+
+COMMENT ON COLUMN links.image_missing IS 'The last scrape found no usable preview image';
@@ -0,0 +1,18 @@
+-- Migration 037: Manual curation of network accounts
+-- Admins can keep notes on an account, record how far they trust it, and pin
+-- it. Shares by pinned accounts count extra in trending (trending.pinned_weight)
+-- and are badged in sharer lists. The columns live on network_accounts, which
+-- is upserted by the network crawl and never rebuilt, so curation survives
+-- recrawls.
+
+ALTER TABLE network_accounts
+ADD COLUMN IF NOT EXISTS curator_notes TEXT,
+ADD COLUMN IF NOT EXISTS trust_level TEXT NOT NULL DEFAULT 'normal',  -- low, normal or high
+ADD COLUMN IF NOT EXISTS pinned BOOLEAN NOT NULL DEFAULT FALSE,
+ADD COLUMN IF NOT EXISTS curated_at TIMESTAMP;                       -- Last edit by an admin
+
+CREATE INDEX IF NOT EXISTS idx_network_pinned ON network_accounts(did) WHERE pinned;
+
+COMMENT ON COLUMN network_accounts.curator_notes IS 'Free-form admin notes on the account';
+COMMENT ON COLUMN network_accounts.trust_level IS 'Admin judgment of the account: low, normal or high';
+COMMENT ON COLUMN network_accounts.pinned IS 'Shares get a ranking boost and a badge';
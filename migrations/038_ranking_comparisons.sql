@@ -0,0 +1,20 @@
+-- Migration 038: Shadow ranking comparisons
+-- When a shadow ranking strategy is configured, a sample of trending
+-- requests is also ranked with it (without changing the served order) and
+-- the divergence stored here, so strategies can be compared before the
+-- default is switched. Both orders are kept for offline analysis. Rows are
+-- pruned after 30 days by periodic cleanup.
+
+CREATE TABLE IF NOT EXISTS ranking_comparisons (
+    id BIGSERIAL PRIMARY KEY,
+    primary_strategy TEXT NOT NULL,
+    shadow_strategy TEXT NOT NULL,
+    links INTEGER NOT NULL,                 -- Links ranked by both strategies
+    kendall_tau DOUBLE PRECISION NOT NULL,  -- 1 = same order, -1 = reversed
+    top_overlap DOUBLE PRECISION NOT NULL,  -- Share of the top 10 in common
+    primary_ids INTEGER[] NOT NULL,         -- Served order
+    shadow_ids INTEGER[] NOT NULL,          -- Shadow order
+    compared_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_ranking_comparisons_compared_at ON ranking_comparisons(compared_at);
@@ -0,0 +1,20 @@
+-- Migration 039: Notify listeners when links are shared
+-- The API's live trending stream LISTENs on link_shares and re-ranks when
+-- new shares arrive. Statement-level, so a batch of inserts sends a single
+-- notification; Postgres also folds identical notifications within a
+-- transaction. The payload is the schema, so instances sharing a database
+-- only react to their own shares.
+
+CREATE OR REPLACE FUNCTION notify_link_shares()
+RETURNS TRIGGER AS $$
+BEGIN
+    PERFORM pg_notify('link_shares', current_schema());
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS post_links_notify_trigger ON post_links;
+CREATE TRIGGER post_links_notify_trigger
+    AFTER INSERT ON post_links
+    FOR EACH STATEMENT
+    EXECUTE FUNCTION notify_link_shares();
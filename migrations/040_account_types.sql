@@ -0,0 +1,14 @@
+-- Migration 040: Account types
+-- Network accounts are typed as news organizations, journalists or
+-- individuals, so trending can be filtered by who shared (?sharer_type=).
+-- Types are inferred from handles and account labels by cmd/sync-labels
+-- unless an admin set one, which account_type_manual protects.
+
+ALTER TABLE network_accounts
+ADD COLUMN IF NOT EXISTS account_type TEXT NOT NULL DEFAULT 'individual',  -- news_org, journalist or individual
+ADD COLUMN IF NOT EXISTS account_type_manual BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE INDEX IF NOT EXISTS idx_network_account_type ON network_accounts(account_type) WHERE account_type <> 'individual';
+
+COMMENT ON COLUMN network_accounts.account_type IS 'news_org, journalist or individual';
+COMMENT ON COLUMN network_accounts.account_type_manual IS 'account_type was set by an admin and is not re-inferred';
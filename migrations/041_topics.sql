@@ -0,0 +1,30 @@
+-- Migration 041: Topics
+-- Time-boxed topics ("election mode"): a keyword set and an optional boosted
+-- cohort with their own trending page and feed at /topics/{slug} while they
+-- run. Once a topic ends, the firehose archives its final trending list in
+-- archived_links, which the page serves from then on (posts don't outlive
+-- cleanup, so the list couldn't be recomputed later).
+
+CREATE TABLE IF NOT EXISTS topics (
+    id SERIAL PRIMARY KEY,
+    slug TEXT NOT NULL UNIQUE,
+    title TEXT NOT NULL,
+    description TEXT,
+    keywords TEXT[] NOT NULL DEFAULT '{}',
+    cohort_id INTEGER REFERENCES cohorts(id) ON DELETE SET NULL,
+    boost_weight DOUBLE PRECISION NOT NULL DEFAULT 2,
+    starts_at TIMESTAMP NOT NULL,
+    ends_at TIMESTAMP NOT NULL,
+    archived_at TIMESTAMP,
+    archived_links JSONB,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CHECK (ends_at > starts_at)
+);
+
+-- The archival job looks for ended topics not yet archived
+CREATE INDEX IF NOT EXISTS idx_topics_unarchived ON topics(ends_at) WHERE archived_at IS NULL;
+
+COMMENT ON COLUMN topics.keywords IS 'Posts count toward the topic when their text or the link title contains one of these (case-insensitive)';
+COMMENT ON COLUMN topics.boost_weight IS 'Weight of a share by a member of the boosted cohort';
+COMMENT ON COLUMN topics.archived_links IS 'Final trending list (snapshot links) stored when the topic ended';
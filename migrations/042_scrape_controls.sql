@@ -0,0 +1,20 @@
+-- Migration 042: Scrape controls
+-- The last scrape error per link and domains whose scraping is paused, so
+-- admins can inspect failures, retry links and stop hammering a broken site
+-- through the admin API instead of SQL.
+
+-- Last failed scrape of a link; removed when a later scrape succeeds
+CREATE TABLE IF NOT EXISTS link_scrape_errors (
+    link_id INTEGER PRIMARY KEY REFERENCES links(id) ON DELETE CASCADE,
+    error TEXT NOT NULL,
+    failed_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_link_scrape_errors_failed_at ON link_scrape_errors(failed_at DESC);
+
+-- Domains (and their subdomains) the scrapers skip until resumed
+CREATE TABLE IF NOT EXISTS scrape_paused_domains (
+    domain TEXT PRIMARY KEY,
+    reason TEXT,
+    paused_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
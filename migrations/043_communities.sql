@@ -0,0 +1,22 @@
+-- Migration 043: Communities
+-- Network accounts are grouped into communities by label propagation over
+-- the follow graph (1st-degree accounts and the 2nd-degree accounts they
+-- follow), so trending links can say which communities are sharing them.
+-- Detection re-runs periodically; a community keeps its id, and any name an
+-- admin gave it, while most of its members stay together.
+
+CREATE TABLE IF NOT EXISTS communities (
+    id SERIAL PRIMARY KEY,
+    name TEXT,              -- Set by an admin (NULL = use label)
+    label TEXT NOT NULL,    -- Generated from the best connected members' handles
+    size INTEGER NOT NULL DEFAULT 0,
+    detected_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+ALTER TABLE network_accounts
+ADD COLUMN IF NOT EXISTS community_id INTEGER REFERENCES communities(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_network_community ON network_accounts(community_id) WHERE community_id IS NOT NULL;
+
+COMMENT ON COLUMN communities.name IS 'Admin-given name shown instead of the generated label';
+COMMENT ON COLUMN network_accounts.community_id IS 'Community detected from the follow graph (NULL = none, e.g. too small)';
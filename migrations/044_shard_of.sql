@@ -0,0 +1,19 @@
+-- Migration 044: Shard lookup in SQL
+-- shard_of mirrors sharding.ShardOf (FNV-1a 32-bit of the DID, scaled to
+-- shard_count), so queries can select one shard's rows before their LIMIT.
+
+CREATE OR REPLACE FUNCTION shard_of(did TEXT, shard_count INTEGER)
+RETURNS INTEGER AS $$
+DECLARE
+    bytes BYTEA := convert_to(did, 'UTF8');
+    h BIGINT := 2166136261;            -- FNV-1a 32-bit offset basis
+BEGIN
+    IF shard_count <= 1 THEN
+        RETURN 0;
+    END IF;
+    FOR i IN 0 .. length(bytes) - 1 LOOP
+        h := ((h # get_byte(bytes, i)::BIGINT) * 16777619) % 4294967296;
+    END LOOP;
+    RETURN ((h * shard_count) >> 32)::INTEGER;
+END;
+$$ LANGUAGE plpgsql IMMUTABLE;